@@ -0,0 +1,121 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/davidthor/arcctl/pkg/engine"
+	"github.com/davidthor/arcctl/pkg/engine/executor"
+	"github.com/davidthor/arcctl/pkg/engine/planner"
+	"github.com/spf13/cobra"
+)
+
+func newApplyCmd() *cobra.Command {
+	var (
+		readyTimeout  time.Duration
+		backendType   string
+		backendConfig []string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "apply <plan-file>",
+		Short: "Apply a saved execution plan",
+		Long: `Apply an execution plan previously saved with 'cldctl deploy component --out'.
+
+The plan is executed exactly as it was saved, without prompting. The apply is
+refused if the environment state, the datacenter configuration, or any of the
+planned components changed since the plan was created.
+
+Examples:
+  cldctl deploy component ./my-app -e production --out plan.json
+  cldctl apply plan.json`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			planFile := args[0]
+			ctx := context.Background()
+
+			saved, err := engine.ReadPlanFile(planFile)
+			if err != nil {
+				return err
+			}
+
+			// Create state manager
+			mgr, err := createStateManagerWithConfig(backendType, backendConfig)
+			if err != nil {
+				return fmt.Errorf("failed to create state manager: %w", err)
+			}
+
+			eng := createEngine(mgr)
+
+			fmt.Printf("Plan:        %s\n", planFile)
+			fmt.Printf("Environment: %s\n", saved.Environment)
+			fmt.Printf("Datacenter:  %s\n", saved.Datacenter)
+			fmt.Printf("Created:     %s\n", saved.CreatedAt.Format("2006-01-02 15:04:05"))
+			fmt.Println()
+
+			// Build progress table from the planned changes
+			progress := NewProgressTable(os.Stdout)
+			for _, change := range saved.Changes {
+				if change.Action == planner.ActionNoop {
+					continue
+				}
+				progress.AddResource(change.Node.ID, change.Node.Name, string(change.Node.Type), change.Node.Component, change.Node.DependsOn)
+			}
+
+			onProgress := func(event executor.ProgressEvent) {
+				var status ResourceStatus
+				switch event.Status {
+				case "running":
+					status = StatusInProgress
+				case "completed":
+					status = StatusCompleted
+				case "failed":
+					status = StatusFailed
+				case "skipped":
+					status = StatusSkipped
+				default:
+					status = StatusPending
+				}
+
+				if event.Error != nil {
+					progress.SetError(event.NodeID, event.Error)
+				} else {
+					progress.UpdateStatus(event.NodeID, status, event.Message)
+				}
+				progress.PrintUpdate(event.NodeID)
+			}
+
+			applyCtx, stopInterrupts := handleInterrupts(ctx)
+			defer stopInterrupts()
+			result, err := eng.ApplyPlan(applyCtx, saved, engine.ApplyPlanOptions{
+				Output:           os.Stdout,
+				Parallelism:      defaultParallelism,
+				OnProgress:       onProgress,
+				ReadinessTimeout: readyTimeout,
+			})
+			if err != nil {
+				return fmt.Errorf("apply failed: %w", err)
+			}
+
+			if !result.Success {
+				printInterrupted(applyCtx, "The plan can't be applied again; deploy the components with --resume to finish.")
+				if result.Execution != nil && len(result.Execution.Errors) > 0 {
+					return fmt.Errorf("apply failed with %d errors: %v", len(result.Execution.Errors), result.Execution.Errors[0])
+				}
+				return fmt.Errorf("apply failed")
+			}
+
+			progress.PrintFinalSummary()
+
+			return nil
+		},
+	}
+
+	cmd.Flags().DurationVar(&readyTimeout, "readiness-timeout", 5*time.Minute, "How long resources are given to pass their readiness checks")
+	cmd.Flags().StringVar(&backendType, "backend", "", "State backend type")
+	cmd.Flags().StringArrayVar(&backendConfig, "backend-config", nil, "Backend configuration (key=value)")
+
+	return cmd
+}
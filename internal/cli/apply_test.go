@@ -0,0 +1,21 @@
+package cli
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewApplyCmd(t *testing.T) {
+	cmd := newApplyCmd()
+
+	assert.Equal(t, "apply <plan-file>", cmd.Use)
+	assert.NotNil(t, cmd.RunE)
+
+	assert.NotNil(t, cmd.Flags().Lookup("backend"))
+	assert.NotNil(t, cmd.Flags().Lookup("backend-config"))
+
+	// Requires exactly one plan file
+	assert.Error(t, cmd.Args(cmd, []string{}))
+	assert.NoError(t, cmd.Args(cmd, []string{"plan.json"}))
+}
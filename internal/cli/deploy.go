@@ -6,12 +6,14 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"syscall"
 	"time"
 
 	"github.com/davidthor/arcctl/pkg/engine"
 	"github.com/davidthor/arcctl/pkg/engine/executor"
+	"github.com/davidthor/arcctl/pkg/engine/planner"
 	"github.com/davidthor/arcctl/pkg/oci"
 	"github.com/davidthor/arcctl/pkg/registry"
 	"github.com/davidthor/arcctl/pkg/schema/component"
@@ -42,7 +44,13 @@ func newDeployComponentCmd() *cobra.Command {
 		variables     []string
 		varFile       string
 		autoApprove   bool
+		detailedPlan  bool
+		planOut       string
 		targets       []string
+		targetDeps    bool
+		resume        bool
+		readyTimeout  time.Duration
+		rollback      bool
 		backendType   string
 		backendConfig []string
 	)
@@ -69,16 +77,46 @@ environments when needed as dependencies by other components.
 In interactive mode (when not running in CI), you will be prompted to enter
 values for any required variables that were not provided via --var or --var-file.
 
+Use --target to deploy only specific resources, such as "deployment/api" or
+"database/main", along with the resources they depend on. Add
+--target-dependents to also deploy the resources that depend on the targets.
+Everything else is left as it is, so the environment is partially applied
+until it is deployed without --target.
+
+Use --resume to continue a deployment that failed or was interrupted: resources
+that are already ready are reused as they are, and only the resources left
+failed or unfinished are applied again. When the last deployment stopped
+partway through, you are asked whether to resume it.
+
+Resources whose datacenter hook module outputs a ready_check are only marked
+ready, and their dependents started, once the check passes. Use
+--readiness-timeout to change how long they are given.
+
+Use --rollback-on-failure to restore the resources a failed deployment already
+updated to the inputs and state they had before it, rather than leaving the
+environment with a mix of old and new resources. Resources the deployment
+created are left in place.
+
 Examples:
   cldctl deploy component ./my-app -e production
   cldctl deploy component ./my-app -e staging -d my-dc
   cldctl deploy component ghcr.io/myorg/myapp:v1.0.0 -e production --var api_key=secret123
+  cldctl deploy component ./my-app -e production --detailed-plan
+  cldctl deploy component ./my-app -e production --out plan.json
+  cldctl deploy component ./my-app -e production --target deployment/api
+  cldctl deploy component ./my-app -e production --target database/main --target-dependents
+  cldctl deploy component ./my-app -e production --resume
+  cldctl deploy component ./my-app -e production --rollback-on-failure
   cldctl deploy component myorg/stripe:latest -d my-dc --var key=sk_live_xxx`,
 		Args: cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			source := args[0]
 			ctx := context.Background()
 
+			if targetDeps && len(targets) == 0 {
+				return fmt.Errorf("--target-dependents requires at least one --target")
+			}
+
 			// Resolve datacenter
 			dc, err := resolveDatacenter(datacenter)
 			if err != nil {
@@ -93,6 +131,9 @@ Examples:
 
 			// If no environment specified, register as datacenter-level component
 			if environment == "" {
+				if len(targets) > 0 {
+					return fmt.Errorf("--target requires --environment")
+				}
 				return deployDatacenterComponent(ctx, mgr, dc, source, variables, varFile)
 			}
 
@@ -254,10 +295,100 @@ Examples:
 			fmt.Printf("Source:      %s\n", source)
 			fmt.Println()
 
-			fmt.Println("Execution Plan:")
-			fmt.Println()
+			// Offer to resume when the last deployment of these components
+			// stopped partway through
+			if !resume {
+				compNames := make([]string, 0, len(componentsMap))
+				for name := range componentsMap {
+					compNames = append(compNames, name)
+				}
+				sort.Strings(compNames)
+
+				if unfinished := engine.UnfinishedResources(envState, compNames...); len(unfinished) > 0 {
+					fmt.Printf("The last deployment stopped with %d unfinished resources:\n", len(unfinished))
+					for _, res := range unfinished {
+						if res.Attempt > 0 {
+							fmt.Printf("  %s/%s/%s (%s, attempt %d)\n", res.Component, res.Type, res.Name, res.Status, res.Attempt)
+						} else {
+							fmt.Printf("  %s/%s/%s (%s)\n", res.Component, res.Type, res.Name, res.Status)
+						}
+					}
+					fmt.Println()
+
+					if !autoApprove && isInteractive() {
+						fmt.Print("Resume and retry only the unfinished resources? [Y/n]: ")
+						var response string
+						_, _ = fmt.Scanln(&response)
+						response = strings.ToLower(strings.TrimSpace(response))
+						resume = response == "" || response == "y" || response == "yes"
+					} else {
+						fmt.Println("Run with --resume to reuse the resources that are ready and retry only these.")
+					}
+					fmt.Println()
+				}
+			}
+
+			// Save the plan for a later `cldctl apply` instead of deploying
+			if planOut != "" {
+				planOpts := engine.DeployOptions{
+					Environment:      environment,
+					Datacenter:       dc,
+					Components:       componentsMap,
+					Variables:        variablesMap,
+					Output:           os.Stdout,
+					DryRun:           true,
+					DetailedPlan:     detailedPlan,
+					Targets:          targets,
+					TargetDependents: targetDeps,
+					Resume:           resume,
+				}
+				planResult, err := eng.Deploy(ctx, planOpts)
+				if err != nil {
+					return fmt.Errorf("failed to create plan: %w", err)
+				}
+				saved, err := eng.SavePlan(ctx, planOpts, planResult)
+				if err != nil {
+					return fmt.Errorf("failed to save plan: %w", err)
+				}
+				if err := engine.WritePlanFile(planOut, saved); err != nil {
+					return err
+				}
+				fmt.Println()
+				fmt.Printf("Plan saved to %s\n", planOut)
+				fmt.Printf("Apply it with: cldctl apply %s\n", planOut)
+				return nil
+			}
+
+			// Targeted and resumed plans only cover part of the components, so
+			// they are planned by the engine rather than listed from the component.
+			// A plan the engine made is the one that is executed.
+			var shownPlan, partialPlan *engine.DeployResult
+			if detailedPlan || len(targets) > 0 || resume {
+				// Ask the datacenter's IaC plugins what each module will actually change
+				planResult, err := eng.Deploy(ctx, engine.DeployOptions{
+					Environment:      environment,
+					Datacenter:       dc,
+					Components:       componentsMap,
+					Variables:        variablesMap,
+					Output:           os.Stdout,
+					DryRun:           true,
+					DetailedPlan:     detailedPlan,
+					Targets:          targets,
+					TargetDependents: targetDeps,
+					Resume:           resume,
+				})
+				if err != nil {
+					return fmt.Errorf("failed to create plan: %w", err)
+				}
+				shownPlan = planResult
+				if len(targets) > 0 || resume {
+					partialPlan = planResult
+				}
+			} else if comp != nil {
+				fmt.Println("Execution Plan:")
+				fmt.Println()
+
 
-			if comp != nil {
 				// Show resources that will be created
 				planCount := 0
 
@@ -287,15 +418,13 @@ Examples:
 
 				fmt.Printf("Plan: %d to create, 0 to update, 0 to destroy\n", planCount)
 			} else {
+				fmt.Println("Execution Plan:")
+				fmt.Println()
 				fmt.Println("  (resources will be determined from OCI artifact)")
 			}
 
 			fmt.Println()
 
-			// Handle targets filter
-			_ = targets
-			_ = envState
-
 			// Confirm unless --auto-approve is provided
 			if !autoApprove && isInteractive() {
 				fmt.Print("Proceed with deployment? [Y/n]: ")
@@ -313,18 +442,29 @@ Examples:
 			// Build progress table from component resources
 			progress := NewProgressTable(os.Stdout)
 
-			// Add dependency component resources to progress table first
-			for _, dep := range deps {
-				addComponentToProgressTable(progress, dep.Name, dep.Component)
-			}
+			if partialPlan != nil {
+				// Only the planned resources are deployed
+				for _, change := range partialPlan.Plan.Changes {
+					if change.Action == planner.ActionNoop || change.Node == nil {
+						continue
+					}
+					progress.AddResource(change.Node.ID, change.Node.Name, string(change.Node.Type), change.Node.Component, change.Node.DependsOn)
+				}
+				progress.PrintInitial()
+			} else {
+				// Add dependency component resources to progress table first
+				for _, dep := range deps {
+					addComponentToProgressTable(progress, dep.Name, dep.Component)
+				}
 
-			if comp != nil {
-				// Build dependency graph for progress display
-				addComponentToProgressTable(progress, componentName, comp)
+				if comp != nil {
+					// Build dependency graph for progress display
+					addComponentToProgressTable(progress, componentName, comp)
 
-				// Print initial progress table
-				progress.PrintInitial()
+					// Print initial progress table
+					progress.PrintInitial()
 
+				}
 			}
 
 			// Create progress callback
@@ -352,31 +492,47 @@ Examples:
 			}
 
 			// Execute deployment using the engine
-			result, err := eng.Deploy(ctx, engine.DeployOptions{
-				Environment: environment,
-				Datacenter:  dc,
-				Components:  componentsMap,
-				Variables:   variablesMap,
-				Output:      os.Stdout,
-				DryRun:      false,
-				AutoApprove: autoApprove,
-				Parallelism: defaultParallelism,
-				OnProgress:  onProgress,
+			deployCtx, stopInterrupts := handleInterrupts(ctx)
+			defer stopInterrupts()
+			result, err := eng.Deploy(deployCtx, engine.DeployOptions{
+				Environment:       environment,
+				Datacenter:        dc,
+				Components:        componentsMap,
+				Variables:         variablesMap,
+				Output:            os.Stdout,
+				DryRun:            false,
+				AutoApprove:       autoApprove,
+				Parallelism:       defaultParallelism,
+				OnProgress:        onProgress,
+				Targets:           targets,
+				TargetDependents:  targetDeps,
+				Resume:            resume,
+				ReadinessTimeout:  readyTimeout,
+				RollbackOnFailure: rollback,
+				Plan:              shownPlan,
 			})
 			if err != nil {
 				return fmt.Errorf("deployment failed: %w", err)
 			}
 
 			if !result.Success {
+				printInterrupted(deployCtx, fmt.Sprintf("Run 'cldctl deploy component %s -e %s --resume' to finish the deployment.", source, environment))
+				printRollback(result.Rollback)
 				if result.Execution != nil && len(result.Execution.Errors) > 0 {
-					return fmt.Errorf("deployment failed with %d errors: %v", len(result.Execution.Errors), result.Execution.Errors[0])
+					return fmt.Errorf("deployment failed with %d errors: %v%s", len(result.Execution.Errors), result.Execution.Errors[0], rollbackSummary(result.Rollback))
 				}
-				return fmt.Errorf("deployment failed")
+				return fmt.Errorf("deployment failed%s", rollbackSummary(result.Rollback))
 			}
 
 			// Print final summary
 			progress.PrintFinalSummary()
 
+			if len(targets) > 0 {
+				fmt.Println()
+				fmt.Printf("Warning: only the targeted resources were deployed. Environment %q is partially\n", environment)
+				fmt.Println("applied; deploy the component without --target to bring the rest up to date.")
+			}
+
 			return nil
 		},
 	}
@@ -386,13 +542,53 @@ Examples:
 	cmd.Flags().StringArrayVar(&variables, "var", nil, "Set variable (key=value)")
 	cmd.Flags().StringVar(&varFile, "var-file", "", "Load variables from file")
 	cmd.Flags().BoolVar(&autoApprove, "auto-approve", false, "Skip confirmation prompt")
-	cmd.Flags().StringArrayVar(&targets, "target", nil, "Target specific resource (repeatable)")
+	cmd.Flags().BoolVar(&detailedPlan, "detailed-plan", false, "Preview infrastructure changes from the datacenter's IaC modules before deploying")
+	cmd.Flags().StringVar(&planOut, "out", "", "Save the execution plan to a file instead of deploying (apply with 'cldctl apply')")
+	cmd.Flags().StringArrayVar(&targets, "target", nil, "Only deploy this resource and its dependencies, e.g. deployment/api (repeatable)")
+	cmd.Flags().BoolVar(&targetDeps, "target-dependents", false, "Also deploy the resources that depend on the targets")
+	cmd.Flags().BoolVar(&resume, "resume", false, "Resume a failed deployment, retrying only resources that aren't ready")
+	cmd.Flags().DurationVar(&readyTimeout, "readiness-timeout", 5*time.Minute, "How long resources are given to pass their readiness checks")
+	cmd.Flags().BoolVar(&rollback, "rollback-on-failure", false, "If the deployment fails, restore the resources it updated to their previous state")
 	cmd.Flags().StringVar(&backendType, "backend", "", "State backend type")
 	cmd.Flags().StringArrayVar(&backendConfig, "backend-config", nil, "Backend configuration (key=value)")
 
 	return cmd
 }
 
+// printRollback reports the outcome of rolling back a failed deployment. It
+// prints nothing if no rollback was attempted.
+func printRollback(rollback *executor.ExecutionResult) {
+	if rollback == nil {
+		return
+	}
+	fmt.Println()
+	if rollback.Success {
+		if rollback.Updated == 0 {
+			fmt.Println("Rollback: no resources had been updated, so there was nothing to restore.")
+		} else {
+			fmt.Printf("Rollback: restored %d resource(s) to their previous state.\n", rollback.Updated)
+		}
+		return
+	}
+	fmt.Printf("Rollback failed: restored %d resource(s); %d could not be restored:\n", rollback.Updated, rollback.Failed)
+	for _, err := range rollback.Errors {
+		fmt.Printf("  - %v\n", err)
+	}
+}
+
+// rollbackSummary describes the outcome of a rollback for the error returned
+// by a failed deployment.
+func rollbackSummary(rollback *executor.ExecutionResult) string {
+	switch {
+	case rollback == nil:
+		return ""
+	case rollback.Success:
+		return fmt.Sprintf(" (rolled back %d resource(s))", rollback.Updated)
+	default:
+		return " (rollback failed)"
+	}
+}
+
 // deployDatacenterComponent registers a component declaration at the datacenter level.
 // The component is not deployed immediately -- it is stored so the engine can
 // automatically deploy it into environments when needed as a dependency.
@@ -683,15 +879,33 @@ Examples:
 				}
 			}
 
+			existing, _ := mgr.GetDatacenter(ctx, dcName)
+
+			// Record sensitive variables before the first save so their values
+			// are never written unprotected. OCI datacenters are not loaded until
+			// deploy, so fall back to what the previous deployment recorded.
+			var sensitiveVars []string
+			if dc != nil {
+				sensitiveVars = engine.SensitiveDatacenterVariables(dc)
+			} else if existing != nil {
+				sensitiveVars = existing.SensitiveVariables
+			}
+
 			// Save datacenter state
 			dcState := &types.DatacenterState{
-				Name:      dcName,
-				Version:   tag,
-				Source:     source,
-				Variables: vars,
-				Modules:   make(map[string]*types.ModuleState),
-				CreatedAt: time.Now(),
-				UpdatedAt: time.Now(),
+				Name:               dcName,
+				Version:            tag,
+				Source:             source,
+				Variables:          vars,
+				SensitiveVariables: sensitiveVars,
+				Modules:            make(map[string]*types.ModuleState),
+				CreatedAt:          time.Now(),
+				UpdatedAt:          time.Now(),
+			}
+			// Replace the stored state rather than racing with it
+			if existing != nil {
+				dcState.Serial = existing.Serial
+				dcState.Lineage = existing.Lineage
 			}
 
 			if err := mgr.SaveDatacenter(ctx, dcState); err != nil {
@@ -44,7 +44,7 @@ func TestDeployComponentCmd_Flags(t *testing.T) {
 	}
 
 	// Check optional flags
-	optionalFlags := []string{"var", "var-file", "auto-approve", "target", "backend", "backend-config"}
+	optionalFlags := []string{"var", "var-file", "auto-approve", "detailed-plan", "out", "target", "backend", "backend-config"}
 	for _, flagName := range optionalFlags {
 		if cmd.Flags().Lookup(flagName) == nil {
 			t.Errorf("expected --%s flag", flagName)
@@ -122,7 +122,9 @@ Examples:
 			eng := createEngine(mgr)
 
 			// Execute destroy using the engine
-			result, err := eng.DestroyComponent(ctx, engine.DestroyComponentOptions{
+			destroyCtx, stopInterrupts := handleInterrupts(ctx)
+			defer stopInterrupts()
+			result, err := eng.DestroyComponent(destroyCtx, engine.DestroyComponentOptions{
 				Environment: environment,
 				Datacenter:  dc,
 				Component:   componentName,
@@ -136,6 +138,7 @@ Examples:
 			}
 
 			if !result.Success {
+				printInterrupted(destroyCtx, fmt.Sprintf("Run 'cldctl destroy component %s -e %s' again to finish.", componentName, environment))
 				if result.Execution != nil && len(result.Execution.Errors) > 0 {
 					return fmt.Errorf("destroy failed with %d errors: %v", len(result.Execution.Errors), result.Execution.Errors[0])
 				}
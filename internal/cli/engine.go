@@ -14,9 +14,12 @@ import (
 
 // createEngine creates a new deployment engine with the given state manager.
 // The IaC plugins are automatically registered via init() functions from the
-// blank imports above.
+// blank imports above. Secret references in variables are resolved using the
+// providers configured in ~/.cldctl/config.yaml.
 func createEngine(stateManager state.Manager) *engine.Engine {
-	return engine.NewEngine(stateManager, iac.DefaultRegistry)
+	eng := engine.NewEngine(stateManager, iac.DefaultRegistry)
+	eng.SetSecretsManager(createSecretsManager())
+	return eng
 }
 
 // defaultParallelism is the default number of parallel operations for deployments.
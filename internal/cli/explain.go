@@ -0,0 +1,318 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/davidthor/arcctl/pkg/engine"
+	"github.com/davidthor/arcctl/pkg/engine/executor"
+	"github.com/davidthor/arcctl/pkg/resolver"
+	"github.com/spf13/cobra"
+)
+
+// explainEnvironmentPlaceholder is used as the environment name in module
+// inputs when no environment is given.
+const explainEnvironmentPlaceholder = "<environment>"
+
+func newExplainCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "explain",
+		Short: "Explain how resources are matched to datacenter hooks",
+		Long: `Commands for explaining how a datacenter provisions resources, without
+applying anything.`,
+	}
+
+	cmd.AddCommand(newExplainComponentCmd())
+
+	return cmd
+}
+
+// hookExplanationReport is the JSON representation of a hook explanation.
+type hookExplanationReport struct {
+	ID         string                 `json:"id"`
+	Component  string                 `json:"component"`
+	Name       string                 `json:"name"`
+	Type       string                 `json:"type"`
+	Hooks      []hookCandidateReport  `json:"hooks"`
+	Selected   *int                   `json:"selected,omitempty"`
+	Module     string                 `json:"module,omitempty"`
+	Plugin     string                 `json:"plugin,omitempty"`
+	ModulePath string                 `json:"module_path,omitempty"`
+	Inputs     map[string]interface{} `json:"inputs,omitempty"`
+	Error      string                 `json:"error,omitempty"`
+}
+
+// hookCandidateReport is the JSON representation of a candidate hook.
+type hookCandidateReport struct {
+	When      string                 `json:"when,omitempty"`
+	Modules   []string               `json:"modules,omitempty"`
+	Evaluated bool                   `json:"evaluated"`
+	Matched   bool                   `json:"matched"`
+	Fallback  string                 `json:"fallback,omitempty"`
+	Values    map[string]interface{} `json:"values,omitempty"`
+	Error     string                 `json:"error,omitempty"`
+}
+
+func newExplainComponentCmd() *cobra.Command {
+	var (
+		file          string
+		datacenter    string
+		environment   string
+		variables     []string
+		varFile       string
+		outputFormat  string
+		backendType   string
+		backendConfig []string
+	)
+
+	cmd := &cobra.Command{
+		Use:     "component [path|image]",
+		Aliases: []string{"comp", "comps", "components"},
+		Short:   "Explain which datacenter hook provisions each resource",
+		Long: `Explain how each resource of a component is matched to the datacenter's
+hooks, without applying anything.
+
+For every resource, each hook defined for its type is listed in the order the
+hooks are tried, with the result of its 'when' condition and the node inputs
+and datacenter variables the condition references. Hooks after the first match
+are never evaluated. Conditions that can't be evaluated as HCL fall back to
+simplified string matching; when that happens the HCL error is shown.
+
+For the selected hook, the module, plugin, and module inputs the resource would
+be applied with are shown. Resources matched by an 'error' hook show the
+evaluated error message instead.
+
+Values of sensitive datacenter and component variables, and of variables
+resolved from secret references, are shown as (sensitive). Component
+expressions that reference outputs of other resources are shown unresolved.
+
+Examples:
+  cldctl explain component ./my-app -d my-dc
+  cldctl explain component ./my-app -d my-dc -e production --var tier=large
+  cldctl explain component ghcr.io/myorg/app:v1 -d my-dc -o json`,
+		Args:         cobra.MaximumNArgs(1),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := context.Background()
+
+			ref := "."
+			if len(args) > 0 {
+				ref = args[0]
+			}
+			if file != "" {
+				ref = file
+			}
+
+			dc, err := resolveDatacenter(datacenter)
+			if err != nil {
+				return err
+			}
+
+			res := resolver.NewResolver(resolver.Options{
+				AllowLocal:  true,
+				AllowRemote: true,
+			})
+			resolved, err := res.Resolve(ctx, ref)
+			if err != nil {
+				return formatResolveError(err)
+			}
+			componentName := extractComponentName(ref, resolved)
+
+			// Load variables from file if specified
+			vars := make(map[string]string)
+			if varFile != "" {
+				data, err := os.ReadFile(varFile)
+				if err != nil {
+					return fmt.Errorf("failed to read var file: %w", err)
+				}
+				if err := parseVarFile(data, vars); err != nil {
+					return fmt.Errorf("failed to parse var file: %w", err)
+				}
+			}
+
+			// Parse inline variables
+			for _, v := range variables {
+				parts := strings.SplitN(v, "=", 2)
+				if len(parts) == 2 {
+					vars[parts[0]] = parts[1]
+				}
+			}
+
+			compVars := make(map[string]interface{}, len(vars))
+			for k, v := range vars {
+				compVars[k] = v
+			}
+
+			if environment == "" {
+				environment = explainEnvironmentPlaceholder
+			}
+
+			mgr, err := createStateManagerWithConfig(backendType, backendConfig)
+			if err != nil {
+				return fmt.Errorf("failed to create state manager: %w", err)
+			}
+
+			explanations, err := createEngine(mgr).Explain(ctx, engine.ExplainOptions{
+				Datacenter:  dc,
+				Environment: environment,
+				Components:  map[string]string{componentName: resolved.Path},
+				Variables:   map[string]map[string]interface{}{componentName: compVars},
+			})
+			if err != nil {
+				return err
+			}
+
+			switch outputFormat {
+			case "json":
+				reports := make([]hookExplanationReport, 0, len(explanations))
+				for _, explanation := range explanations {
+					reports = append(reports, newHookExplanationReport(explanation))
+				}
+				return marshalJSON(reports)
+			case "table":
+				printExplanations(os.Stdout, explanations)
+				return nil
+			default:
+				return fmt.Errorf("unsupported output format %q (use table or json)", outputFormat)
+			}
+		},
+	}
+
+	cmd.Flags().StringVarP(&file, "file", "f", "", "Path to cloud.component.yml if not in default location")
+	cmd.Flags().StringVarP(&datacenter, "datacenter", "d", "", "Datacenter whose hooks to explain (uses default if not set)")
+	cmd.Flags().StringVarP(&environment, "environment", "e", "", "Environment name used in module inputs")
+	cmd.Flags().StringArrayVar(&variables, "var", nil, "Set variable (key=value)")
+	cmd.Flags().StringVar(&varFile, "var-file", "", "Load variables from file")
+	cmd.Flags().StringVarP(&outputFormat, "output", "o", "table", "Output format: table, json")
+	cmd.Flags().StringVar(&backendType, "backend", "", "State backend type")
+	cmd.Flags().StringArrayVar(&backendConfig, "backend-config", nil, "Backend configuration (key=value)")
+
+	return cmd
+}
+
+func newHookExplanationReport(explanation *executor.HookExplanation) hookExplanationReport {
+	report := hookExplanationReport{
+		ID:         explanation.NodeID,
+		Component:  explanation.Component,
+		Name:       explanation.Name,
+		Type:       string(explanation.Type),
+		Hooks:      []hookCandidateReport{},
+		Module:     explanation.Module,
+		Plugin:     explanation.Plugin,
+		ModulePath: explanation.ModulePath,
+		Inputs:     explanation.ModuleInputs,
+	}
+	if explanation.Selected >= 0 {
+		selected := explanation.Selected
+		report.Selected = &selected
+	}
+	if explanation.Error != nil {
+		report.Error = explanation.Error.Error()
+	}
+
+	for _, candidate := range explanation.Candidates {
+		hook := hookCandidateReport{
+			When:      candidate.When,
+			Modules:   candidate.Modules,
+			Evaluated: candidate.Evaluated,
+			Matched:   candidate.Matched,
+			Values:    candidate.Values,
+			Error:     candidate.Error,
+		}
+		if candidate.HCLError != nil {
+			hook.Fallback = candidate.HCLError.Error()
+		}
+		report.Hooks = append(report.Hooks, hook)
+	}
+
+	return report
+}
+
+// printExplanations prints hook explanations as a human-readable report.
+func printExplanations(w io.Writer, explanations []*executor.HookExplanation) {
+	for i, explanation := range explanations {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		fmt.Fprintf(w, "%s (%s)\n", explanation.NodeID, explanation.Type)
+
+		if len(explanation.Candidates) > 0 {
+			fmt.Fprintln(w, "  Hooks:")
+		}
+		for j, candidate := range explanation.Candidates {
+			when := candidate.When
+			if when == "" {
+				when = "(no condition)"
+			}
+			fmt.Fprintf(w, "    %d. when %s\n", j+1, when)
+			for _, key := range sortedInterfaceMapKeys(candidate.Values) {
+				fmt.Fprintf(w, "         %s = %s\n", key, formatWhenValue(candidate.Values[key]))
+			}
+			fmt.Fprintf(w, "       %s\n", candidateResult(candidate))
+		}
+
+		if explanation.Error != nil {
+			fmt.Fprintf(w, "  Error:   %v\n", explanation.Error)
+			continue
+		}
+		fmt.Fprintf(w, "  Module:  %s (plugin: %s)\n", explanation.Module, explanation.Plugin)
+		fmt.Fprintf(w, "  Path:    %s\n", explanation.ModulePath)
+		if len(explanation.ModuleInputs) > 0 {
+			fmt.Fprintln(w, "  Inputs:")
+			for _, key := range sortedInterfaceMapKeys(explanation.ModuleInputs) {
+				fmt.Fprintf(w, "    %-24s %s\n", key+":", formatModuleInput(explanation.ModuleInputs[key]))
+			}
+		}
+	}
+}
+
+// candidateResult describes the outcome of a candidate hook's condition.
+func candidateResult(candidate executor.HookCandidate) string {
+	var result string
+	switch {
+	case !candidate.Evaluated:
+		return "-> not evaluated (an earlier hook matched)"
+	case candidate.Matched && candidate.Error != "":
+		result = fmt.Sprintf("-> matched, error hook: %s", candidate.Error)
+	case candidate.Matched:
+		result = "-> matched"
+	default:
+		result = "-> no match"
+	}
+	if candidate.HCLError != nil {
+		result += fmt.Sprintf(" (string fallback, HCL evaluation failed: %v)", candidate.HCLError)
+	}
+	return result
+}
+
+// formatWhenValue formats a value referenced by a 'when' condition.
+func formatWhenValue(v interface{}) string {
+	switch val := v.(type) {
+	case nil:
+		return "null"
+	case string:
+		if val == engine.SensitiveValue {
+			return val
+		}
+		return fmt.Sprintf("%q", val)
+	default:
+		return formatModuleInput(v)
+	}
+}
+
+// formatModuleInput formats a module input for display. Values other than
+// strings are shown as JSON.
+func formatModuleInput(v interface{}) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	return string(data)
+}
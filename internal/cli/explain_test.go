@@ -0,0 +1,104 @@
+package cli
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/davidthor/arcctl/pkg/engine/executor"
+	"github.com/davidthor/arcctl/pkg/graph"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExplainComponentCmd_Flags(t *testing.T) {
+	cmd := newExplainComponentCmd()
+	assert.Equal(t, "component [path|image]", cmd.Use)
+
+	output := cmd.Flags().Lookup("output")
+	require.NotNil(t, output)
+	assert.Equal(t, "table", output.DefValue)
+
+	for _, name := range []string{"file", "datacenter", "environment", "var", "var-file", "backend", "backend-config"} {
+		assert.NotNil(t, cmd.Flags().Lookup(name), name)
+	}
+}
+
+func newTestExplanations() []*executor.HookExplanation {
+	return []*executor.HookExplanation{
+		{
+			NodeID: "api/database/main",
+			Type:   graph.NodeTypeDatabase,
+			Candidates: []executor.HookCandidate{
+				{
+					When:      `node.inputs.type == "mysql"`,
+					Evaluated: true,
+					Values:    map[string]interface{}{"node.inputs.type": "postgres:16"},
+				},
+				{
+					When:      `node.inputs.type != null`,
+					Modules:   []string{"postgres"},
+					Evaluated: true,
+					Matched:   true,
+					HCLError:  errors.New("parse error"),
+					Values:    map[string]interface{}{"node.inputs.type": "postgres:16"},
+				},
+				{Modules: []string{"fallback"}},
+			},
+			Selected:     1,
+			Module:       "postgres",
+			Plugin:       "opentofu",
+			ModulePath:   "/dc/modules/postgres",
+			ModuleInputs: map[string]interface{}{"password": "(sensitive)", "port": 5432},
+		},
+		{
+			NodeID: "api/bucket/files",
+			Type:   graph.NodeTypeBucket,
+			Candidates: []executor.HookCandidate{
+				{Evaluated: true, Matched: true, Error: "Buckets are not supported"},
+			},
+			Selected: 0,
+			Error:    errors.New("[DATACENTER_HOOK_ERROR] Buckets are not supported"),
+		},
+	}
+}
+
+func TestPrintExplanations(t *testing.T) {
+	var buf bytes.Buffer
+	printExplanations(&buf, newTestExplanations())
+	out := buf.String()
+
+	for _, want := range []string{
+		"api/database/main (database)",
+		`    1. when node.inputs.type == "mysql"`,
+		`         node.inputs.type = "postgres:16"`,
+		"       -> no match",
+		"       -> matched (string fallback, HCL evaluation failed: parse error)",
+		"    3. when (no condition)",
+		"       -> not evaluated (an earlier hook matched)",
+		"  Module:  postgres (plugin: opentofu)",
+		"  Path:    /dc/modules/postgres",
+		"    password:                (sensitive)",
+		"    port:                    5432",
+		"       -> matched, error hook: Buckets are not supported",
+		"  Error:   [DATACENTER_HOOK_ERROR] Buckets are not supported",
+	} {
+		assert.Contains(t, out, want)
+	}
+}
+
+func TestNewHookExplanationReport(t *testing.T) {
+	explanations := newTestExplanations()
+
+	report := newHookExplanationReport(explanations[0])
+	require.NotNil(t, report.Selected)
+	assert.Equal(t, 1, *report.Selected)
+	assert.Len(t, report.Hooks, 3)
+	assert.Equal(t, "parse error", report.Hooks[1].Fallback)
+	assert.Equal(t, "postgres", report.Module)
+	assert.Empty(t, report.Error)
+
+	report = newHookExplanationReport(explanations[1])
+	assert.Equal(t, "Buckets are not supported", report.Hooks[0].Error)
+	assert.Contains(t, report.Error, "DATACENTER_HOOK_ERROR")
+}
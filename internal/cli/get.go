@@ -64,6 +64,7 @@ Examples:
 			if err != nil {
 				return fmt.Errorf("failed to get component: %w", err)
 			}
+			comp = redactComponentState(comp)
 
 			// Handle output format
 			switch outputFormat {
@@ -97,7 +98,7 @@ Examples:
 						if strings.Contains(strings.ToLower(key), "secret") ||
 							strings.Contains(strings.ToLower(key), "password") ||
 							strings.Contains(strings.ToLower(key), "key") {
-							displayValue = "<sensitive>"
+							displayValue = sensitivePlaceholder
 						}
 						fmt.Printf("  %-16s = %q\n", key, displayValue)
 					}
@@ -171,6 +172,7 @@ Examples:
 			if err != nil {
 				return fmt.Errorf("datacenter %q not found: %w", dcName, err)
 			}
+			dc = redactDatacenterState(dc)
 
 			// Handle output format
 			switch outputFormat {
@@ -283,6 +285,7 @@ Examples:
 			if err != nil {
 				return fmt.Errorf("environment %q not found in datacenter %q: %w", envName, dc, err)
 			}
+			env = redactEnvironmentState(env)
 
 			// Handle output format
 			switch outputFormat {
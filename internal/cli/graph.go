@@ -0,0 +1,244 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/davidthor/arcctl/pkg/graph"
+	"github.com/davidthor/arcctl/pkg/resolver"
+	"github.com/davidthor/arcctl/pkg/schema/component"
+	"github.com/davidthor/arcctl/pkg/state/types"
+	"github.com/spf13/cobra"
+)
+
+func newGraphCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "graph",
+		Short: "Export dependency graphs as diagrams",
+		Long: `Commands for exporting the resource dependency graph of a component or
+environment as Graphviz DOT, Mermaid, or JSON.`,
+	}
+
+	cmd.AddCommand(newGraphComponentCmd())
+	cmd.AddCommand(newGraphEnvironmentCmd())
+
+	return cmd
+}
+
+func newGraphComponentCmd() *cobra.Command {
+	var (
+		file          string
+		expand        bool
+		outputFormat  string
+		datacenter    string
+		environment   string
+		backendType   string
+		backendConfig []string
+	)
+
+	cmd := &cobra.Command{
+		Use:     "component [path|image]",
+		Aliases: []string{"comp", "comps", "components"},
+		Short:   "Export a component's dependency graph",
+		Long: `Export the resource dependency graph of a component as Graphviz DOT, Mermaid,
+or JSON. Nodes are grouped by component and labelled with their type.
+
+With --datacenter, each node is also labelled with the datacenter hook module
+that provisions it. With --environment, nodes are coloured by the status of
+the component's resources in that environment.
+
+Examples:
+  cldctl graph component ./my-app > my-app.dot
+  cldctl graph component ./my-app -o mermaid
+  cldctl graph component ghcr.io/myorg/app:v1 --expand -o json
+  cldctl graph component ./my-app -d my-dc -e production | dot -Tsvg > my-app.svg`,
+		Args:         cobra.MaximumNArgs(1),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := context.Background()
+
+			ref := "."
+			if len(args) > 0 {
+				ref = args[0]
+			}
+			if file != "" {
+				ref = file
+			}
+
+			res := resolver.NewResolver(resolver.Options{
+				AllowLocal:  true,
+				AllowRemote: true,
+			})
+
+			var g *graph.Graph
+			if expand {
+				depGraph, err := resolver.NewDependencyResolver(res).Resolve(ctx, ref, nil)
+				if err != nil {
+					return formatResolveError(err)
+				}
+				g, err = buildExpandedGraph(depGraph)
+				if err != nil {
+					return err
+				}
+			} else {
+				resolved, err := res.Resolve(ctx, ref)
+				if err != nil {
+					return formatResolveError(err)
+				}
+				comp, err := component.NewLoader().Load(resolved.Path)
+				if err != nil {
+					return formatLoadError(err)
+				}
+				builder := graph.NewBuilder("", "")
+				if err := builder.AddComponent(extractComponentName(ref, resolved), comp); err != nil {
+					return fmt.Errorf("failed to build graph: %w", err)
+				}
+				g = builder.Build()
+			}
+
+			export := graph.NewExport(g)
+
+			// Annotations need the datacenter, so they are only added on request
+			if datacenter != "" || environment != "" {
+				dc, err := resolveDatacenter(datacenter)
+				if err != nil {
+					return err
+				}
+				mgr, err := createStateManagerWithConfig(backendType, backendConfig)
+				if err != nil {
+					return fmt.Errorf("failed to create state manager: %w", err)
+				}
+
+				modules, err := createEngine(mgr).HookModules(ctx, dc, g)
+				if err != nil {
+					return err
+				}
+				for id, module := range modules {
+					export.SetModule(id, module)
+				}
+				export.Datacenter = dc
+
+				if environment != "" {
+					envState, err := mgr.GetEnvironment(ctx, dc, environment)
+					if err != nil {
+						return fmt.Errorf("environment %q not found in datacenter %q: %w", environment, dc, err)
+					}
+					setExportStatuses(export, envState)
+					export.Environment = environment
+				}
+			}
+
+			return writeGraph(os.Stdout, export, outputFormat)
+		},
+	}
+
+	cmd.Flags().StringVarP(&file, "file", "f", "", "Path to cloud.component.yml if not in default location")
+	cmd.Flags().BoolVar(&expand, "expand", false, "Include the nodes of dependency components")
+	cmd.Flags().StringVarP(&outputFormat, "output", "o", "dot", "Output format: dot, mermaid, json")
+	cmd.Flags().StringVarP(&datacenter, "datacenter", "d", "", "Label nodes with this datacenter's hook modules")
+	cmd.Flags().StringVarP(&environment, "environment", "e", "", "Colour nodes by their status in this environment")
+	cmd.Flags().StringVar(&backendType, "backend", "", "State backend type")
+	cmd.Flags().StringArrayVar(&backendConfig, "backend-config", nil, "Backend configuration (key=value)")
+
+	return cmd
+}
+
+func newGraphEnvironmentCmd() *cobra.Command {
+	var (
+		datacenter    string
+		outputFormat  string
+		backendType   string
+		backendConfig []string
+	)
+
+	cmd := &cobra.Command{
+		Use:     "environment <name>",
+		Aliases: []string{"env", "envs", "environments"},
+		Short:   "Export an environment's dependency graph",
+		Long: `Export the resource dependency graph of every component deployed to an
+environment as Graphviz DOT, Mermaid, or JSON. Nodes are grouped by component,
+labelled with their type and the datacenter hook module that provisions them,
+and coloured by their status in state.
+
+The graph is rebuilt from the component sources recorded in state. Resources
+of components whose source can no longer be loaded are shown without their
+dependencies.
+
+Examples:
+  cldctl graph environment production > production.dot
+  cldctl graph environment staging -o mermaid
+  cldctl graph environment staging -d my-dc -o json`,
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			envName := args[0]
+			ctx := context.Background()
+
+			dc, err := resolveDatacenter(datacenter)
+			if err != nil {
+				return err
+			}
+
+			mgr, err := createStateManagerWithConfig(backendType, backendConfig)
+			if err != nil {
+				return fmt.Errorf("failed to create state manager: %w", err)
+			}
+			eng := createEngine(mgr)
+
+			g, envState, err := eng.EnvironmentGraph(ctx, dc, envName)
+			if err != nil {
+				return err
+			}
+
+			export := graph.NewExport(g)
+			setExportStatuses(export, envState)
+
+			modules, err := eng.HookModules(ctx, dc, g)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: hook modules not shown: %v\n", err)
+			}
+			for id, module := range modules {
+				export.SetModule(id, module)
+			}
+
+			return writeGraph(os.Stdout, export, outputFormat)
+		},
+	}
+
+	cmd.Flags().StringVarP(&datacenter, "datacenter", "d", "", "Datacenter of the environment (uses default if not set)")
+	cmd.Flags().StringVarP(&outputFormat, "output", "o", "dot", "Output format: dot, mermaid, json")
+	cmd.Flags().StringVar(&backendType, "backend", "", "State backend type")
+	cmd.Flags().StringArrayVar(&backendConfig, "backend-config", nil, "Backend configuration (key=value)")
+
+	return cmd
+}
+
+// setExportStatuses sets the status of each exported node that has a
+// resource in the environment's state.
+func setExportStatuses(export *graph.Export, envState *types.EnvironmentState) {
+	for _, node := range export.Nodes {
+		comp := envState.Components[node.Component]
+		if comp == nil {
+			continue
+		}
+		if res := comp.Resources[node.Type+"."+node.Name]; res != nil {
+			export.SetStatus(node.ID, string(res.Status))
+		}
+	}
+}
+
+// writeGraph writes an exported graph in the given format.
+func writeGraph(w io.Writer, export *graph.Export, format string) error {
+	switch format {
+	case "dot":
+		return export.WriteDOT(w)
+	case "mermaid":
+		return export.WriteMermaid(w)
+	case "json":
+		return export.WriteJSON(w)
+	default:
+		return fmt.Errorf("unsupported output format %q (use dot, mermaid, or json)", format)
+	}
+}
@@ -0,0 +1,72 @@
+package cli
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/davidthor/arcctl/pkg/graph"
+	"github.com/davidthor/arcctl/pkg/state/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewGraphCmd(t *testing.T) {
+	cmd := newGraphCmd()
+	assert.Equal(t, "graph", cmd.Use)
+
+	var uses []string
+	for _, sub := range cmd.Commands() {
+		uses = append(uses, sub.Use)
+	}
+	assert.ElementsMatch(t, []string{"component [path|image]", "environment <name>"}, uses)
+}
+
+func TestGraphComponentCmd_Flags(t *testing.T) {
+	cmd := newGraphComponentCmd()
+
+	output := cmd.Flags().Lookup("output")
+	require.NotNil(t, output)
+	assert.Equal(t, "dot", output.DefValue)
+	assert.Equal(t, "o", output.Shorthand)
+
+	for _, name := range []string{"file", "expand", "datacenter", "environment", "backend", "backend-config"} {
+		assert.NotNil(t, cmd.Flags().Lookup(name), name)
+	}
+}
+
+func TestSetExportStatuses(t *testing.T) {
+	g := graph.NewGraph("staging", "dc")
+	_ = g.AddNode(graph.NewNode(graph.NodeTypeDeployment, "api", "api"))
+	_ = g.AddNode(graph.NewNode(graph.NodeTypeService, "api", "api"))
+	_ = g.AddNode(graph.NewNode(graph.NodeTypeDatabase, "api", "main"))
+
+	export := graph.NewExport(g)
+	setExportStatuses(export, &types.EnvironmentState{
+		Components: map[string]*types.ComponentState{
+			"api": {Resources: map[string]*types.ResourceState{
+				"deployment.api": {Status: types.ResourceStatusFailed},
+				"service.api":    {Status: types.ResourceStatusReady},
+			}},
+		},
+	})
+
+	statuses := make(map[string]string)
+	for _, node := range export.Nodes {
+		statuses[node.ID] = node.Status
+	}
+	assert.Equal(t, "failed", statuses["api/deployment/api"])
+	assert.Equal(t, "ready", statuses["api/service/api"])
+	assert.Equal(t, "", statuses["api/database/main"])
+}
+
+func TestWriteGraph_UnsupportedFormat(t *testing.T) {
+	export := graph.NewExport(graph.NewGraph("", ""))
+
+	var buf bytes.Buffer
+	err := writeGraph(&buf, export, "png")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unsupported output format")
+
+	require.NoError(t, writeGraph(&buf, export, "mermaid"))
+	assert.Contains(t, buf.String(), "flowchart LR")
+}
@@ -0,0 +1,167 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/davidthor/arcctl/pkg/engine"
+	"github.com/davidthor/arcctl/pkg/resolver"
+	"github.com/spf13/cobra"
+)
+
+func newImportCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "import",
+		Short: "Adopt existing infrastructure into cldctl state",
+		Long: `Commands for bringing infrastructure that was created outside cldctl under
+its management, without recreating it.`,
+	}
+
+	cmd.AddCommand(newImportResourceCmd())
+
+	return cmd
+}
+
+func newImportResourceCmd() *cobra.Command {
+	var (
+		environment   string
+		datacenter    string
+		id            string
+		address       string
+		source        string
+		variables     []string
+		varFile       string
+		backendType   string
+		backendConfig []string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "resource <component/type/name>",
+		Short: "Adopt an existing resource as a component's resource",
+		Long: `Adopt existing infrastructure, such as a database or bucket, as a resource
+of a component, so that cldctl manages it from then on instead of creating a
+new one.
+
+The resource is matched to a datacenter hook exactly as it would be on deploy,
+and the hook's module is run in import mode against the infrastructure with
+the given --id:
+
+  - OpenTofu modules get an import block for the resource, and the import
+    fails if the plan would create or destroy anything.
+  - Pulumi modules run 'pulumi import', and the import fails if the following
+    preview would create or destroy anything.
+  - Native modules adopt Docker containers, volumes, and networks by name.
+
+The module's outputs and IaC state are recorded as a ready resource, so the
+next deploy of the component leaves it as it is.
+
+Use --address when the module declares more than one resource: an OpenTofu
+resource address (aws_db_instance.main), a Pulumi <type>::<name>
+(aws:s3/bucket:Bucket::bucket), or the name of a native resource. Pulumi
+modules always need one.
+
+For a component that isn't deployed to the environment yet, give its path or
+image with --source. The environment is locked while the import runs.
+
+Examples:
+  cldctl import resource my-app/database/main -e production --id my-app-prod-db
+  cldctl import resource my-app/bucket/uploads -e production --id my-app-uploads --source ./my-app
+  cldctl import resource my-app/database/main -e staging --id db-1 --address aws_db_instance.main`,
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := context.Background()
+
+			parts := strings.Split(args[0], "/")
+			if len(parts) != 3 || parts[0] == "" || parts[1] == "" || parts[2] == "" {
+				return fmt.Errorf("invalid resource %q: expected component/type/name", args[0])
+			}
+			componentName, resourceType, resourceName := parts[0], parts[1], parts[2]
+
+			dc, err := resolveDatacenter(datacenter)
+			if err != nil {
+				return err
+			}
+
+			if source != "" {
+				res := resolver.NewResolver(resolver.Options{
+					AllowLocal:  true,
+					AllowRemote: true,
+				})
+				resolved, err := res.Resolve(ctx, source)
+				if err != nil {
+					return formatResolveError(err)
+				}
+				source = resolved.Path
+			}
+
+			// Variables recorded in state are used unless any are given
+			var compVars map[string]interface{}
+			if varFile != "" || len(variables) > 0 {
+				vars := make(map[string]string)
+				if varFile != "" {
+					data, err := os.ReadFile(varFile)
+					if err != nil {
+						return fmt.Errorf("failed to read var file: %w", err)
+					}
+					if err := parseVarFile(data, vars); err != nil {
+						return fmt.Errorf("failed to parse var file: %w", err)
+					}
+				}
+				for _, v := range variables {
+					parts := strings.SplitN(v, "=", 2)
+					if len(parts) == 2 {
+						vars[parts[0]] = parts[1]
+					}
+				}
+				compVars = make(map[string]interface{}, len(vars))
+				for k, v := range vars {
+					compVars[k] = v
+				}
+			}
+
+			mgr, err := createStateManagerWithConfig(backendType, backendConfig)
+			if err != nil {
+				return fmt.Errorf("failed to create state manager: %w", err)
+			}
+
+			fmt.Printf("Importing %s into %s (environment %s, datacenter %s)...\n", id, args[0], environment, dc)
+
+			res, err := createEngine(mgr).ImportResource(ctx, engine.ImportResourceOptions{
+				Datacenter:  dc,
+				Environment: environment,
+				Component:   componentName,
+				Type:        resourceType,
+				Name:        resourceName,
+				ID:          id,
+				Address:     address,
+				Source:      source,
+				Variables:   compVars,
+				Output:      os.Stdout,
+			})
+			if err != nil {
+				return fmt.Errorf("failed to import %s: %w", args[0], err)
+			}
+
+			fmt.Printf("Imported %s with module %s.\n", args[0], res.Module)
+			fmt.Printf("Run 'cldctl state show %s/%s' to see its outputs.\n", environment, args[0])
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&environment, "environment", "e", "", "Environment to import into (required)")
+	cmd.Flags().StringVarP(&datacenter, "datacenter", "d", "", "Target datacenter (uses default if not set)")
+	cmd.Flags().StringVar(&id, "id", "", "Provider ID of the existing infrastructure (required)")
+	cmd.Flags().StringVar(&address, "address", "", "Resource within the hook module to import into")
+	cmd.Flags().StringVar(&source, "source", "", "Component path or image, for a component that isn't deployed yet")
+	cmd.Flags().StringArrayVar(&variables, "var", nil, "Set component variable (key=value)")
+	cmd.Flags().StringVar(&varFile, "var-file", "", "Load component variables from file")
+	cmd.Flags().StringVar(&backendType, "backend", "", "State backend type")
+	cmd.Flags().StringArrayVar(&backendConfig, "backend-config", nil, "Backend configuration (key=value)")
+	_ = cmd.MarkFlagRequired("environment")
+	_ = cmd.MarkFlagRequired("id")
+
+	return cmd
+}
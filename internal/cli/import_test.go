@@ -0,0 +1,28 @@
+package cli
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewImportCmd(t *testing.T) {
+	cmd := newImportCmd()
+	assert.Equal(t, "import", cmd.Use)
+
+	resource, _, err := cmd.Find([]string{"resource"})
+	require.NoError(t, err)
+	assert.Equal(t, "resource <component/type/name>", resource.Use)
+	for _, name := range []string{"environment", "datacenter", "id", "address", "source", "var", "var-file"} {
+		assert.NotNil(t, resource.Flags().Lookup(name), name)
+	}
+}
+
+func TestImportResourceCmd_InvalidResource(t *testing.T) {
+	cmd := newImportResourceCmd()
+	cmd.SetArgs([]string{"my-app/main", "-e", "staging", "--id", "db-1"})
+	err := cmd.Execute()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `invalid resource "my-app/main": expected component/type/name`)
+}
@@ -359,29 +359,11 @@ func getSortedNodes(g *graph.Graph) []*graph.Node {
 
 // printExpandedTopology prints the full expanded topology including dependencies
 func printExpandedTopology(depGraph *resolver.DependencyGraph) error {
-	// Build combined graph from all components
-	builder := graph.NewBuilder("", "")
-
-	// Process in deployment order (dependencies first)
-	for _, name := range depGraph.Order {
-		dep, ok := depGraph.All[name]
-		if !ok {
-			continue
-		}
-
-		compName := dep.Name
-		if compName == "root" {
-			// Use a better name for the root component
-			compName = extractComponentName(dep.Component.Reference, dep.Component)
-		}
-
-		if err := builder.AddComponent(compName, dep.LoadedComponent); err != nil {
-			return fmt.Errorf("failed to add component %s to graph: %w", compName, err)
-		}
+	g, err := buildExpandedGraph(depGraph)
+	if err != nil {
+		return err
 	}
 
-	g := builder.Build()
-
 	// Print header
 	fmt.Printf("\nExpanded Component Topology\n")
 	fmt.Println(strings.Repeat("=", 60))
@@ -423,6 +405,32 @@ func printExpandedTopology(depGraph *resolver.DependencyGraph) error {
 	return nil
 }
 
+// buildExpandedGraph builds a combined graph of a component and all of its
+// dependencies.
+func buildExpandedGraph(depGraph *resolver.DependencyGraph) (*graph.Graph, error) {
+	builder := graph.NewBuilder("", "")
+
+	// Process in deployment order (dependencies first)
+	for _, name := range depGraph.Order {
+		dep, ok := depGraph.All[name]
+		if !ok {
+			continue
+		}
+
+		compName := dep.Name
+		if compName == "root" {
+			// Use a better name for the root component
+			compName = extractComponentName(dep.Component.Reference, dep.Component)
+		}
+
+		if err := builder.AddComponent(compName, dep.LoadedComponent); err != nil {
+			return nil, fmt.Errorf("failed to add component %s to graph: %w", compName, err)
+		}
+	}
+
+	return builder.Build(), nil
+}
+
 // printNodesByType prints nodes organized by type
 func printNodesByType(nodesByType map[graph.NodeType][]*graph.Node) {
 	// Define display order for node types
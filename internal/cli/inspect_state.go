@@ -12,6 +12,8 @@ import (
 
 // inspectEnvironmentState displays the state of an environment.
 func inspectEnvironmentState(env *types.EnvironmentState, dc, outputFormat string) error {
+	env = redactEnvironmentState(env)
+
 	switch outputFormat {
 	case "json":
 		return marshalJSON(env)
@@ -90,6 +92,8 @@ func printEnvironmentStateTable(env *types.EnvironmentState, dc string) error {
 
 // inspectComponentState displays the state of a component.
 func inspectComponentState(comp *types.ComponentState, dc, envName, outputFormat string) error {
+	comp = redactComponentState(comp)
+
 	switch outputFormat {
 	case "json":
 		return marshalJSON(comp)
@@ -164,6 +168,8 @@ func printComponentStateTable(comp *types.ComponentState, dc, envName string) er
 
 // inspectResourceState displays the state of a single resource.
 func inspectResourceState(res *types.ResourceState, dc, envName, outputFormat string) error {
+	res = redactResourceState(res)
+
 	switch outputFormat {
 	case "json":
 		return marshalJSON(res)
@@ -185,6 +191,9 @@ func printResourceStateTable(res *types.ResourceState, dc, envName string) error
 	if res.StatusReason != "" {
 		fmt.Printf("Reason:      %s\n", res.StatusReason)
 	}
+	if res.Attempt > 1 {
+		fmt.Printf("Attempt:     %d\n", res.Attempt)
+	}
 
 	if res.Hook != "" {
 		fmt.Printf("Hook:        %s\n", res.Hook)
@@ -0,0 +1,68 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/davidthor/arcctl/pkg/engine/executor"
+)
+
+// handleInterrupts returns a context for executing a plan that handles Ctrl+C
+// in two stages. The first interrupt stops further resources from starting and
+// waits for those in progress to finish so that their state is saved. The
+// second cancels the context, interrupting the resources in progress, which
+// are marked failed. After that the default handling is restored, so a third
+// interrupt exits immediately.
+//
+// The returned function stops handling interrupts and must be called once the
+// execution returns.
+func handleInterrupts(ctx context.Context) (context.Context, func()) {
+	ctx, cancel := context.WithCancel(ctx)
+	stop := make(chan struct{})
+	done := make(chan struct{})
+
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+
+	go func() {
+		select {
+		case <-sigChan:
+		case <-done:
+			return
+		}
+		fmt.Println("\nInterrupted: waiting for in-progress resources to finish so their state is saved...")
+		fmt.Println("Press Ctrl+C again to stop them now; they will be marked as failed.")
+		close(stop)
+
+		select {
+		case <-sigChan:
+		case <-done:
+			return
+		}
+		signal.Stop(sigChan)
+		fmt.Println("\nStopping in-progress resources...")
+		cancel()
+	}()
+
+	return executor.WithStop(ctx, stop), func() {
+		signal.Stop(sigChan)
+		close(done)
+		cancel()
+	}
+}
+
+// printInterrupted explains how to recover from an execution that was
+// interrupted with Ctrl+C, ending with the next step. It prints nothing if
+// ctx wasn't interrupted.
+func printInterrupted(ctx context.Context, next string) {
+	if !executor.StopRequested(ctx) {
+		return
+	}
+	fmt.Println()
+	fmt.Println("The operation was interrupted. Resources that finished have been saved to state;")
+	fmt.Println("any that were stopped part-way are marked as failed.")
+	fmt.Println(next)
+}
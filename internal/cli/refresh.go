@@ -0,0 +1,218 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/davidthor/arcctl/pkg/engine"
+	"github.com/spf13/cobra"
+)
+
+func newRefreshCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "refresh",
+		Short: "Refresh state and detect drift",
+		Long:  `Commands for refreshing stored state against real infrastructure.`,
+	}
+
+	cmd.AddCommand(newRefreshEnvironmentCmd())
+
+	return cmd
+}
+
+// driftReport is the JSON representation of a refreshed resource.
+type driftReport struct {
+	ID        string        `json:"id"`
+	Component string        `json:"component,omitempty"`
+	Name      string        `json:"name"`
+	Type      string        `json:"type"`
+	Plugin    string        `json:"plugin,omitempty"`
+	Drifted   bool          `json:"drifted"`
+	Drifts    []driftDetail `json:"drifts,omitempty"`
+	Error     string        `json:"error,omitempty"`
+}
+
+// driftDetail is the JSON representation of a single drifted IaC resource.
+type driftDetail struct {
+	ResourceID   string           `json:"resource_id"`
+	ResourceType string           `json:"resource_type"`
+	Changes      []propertyDetail `json:"changes,omitempty"`
+}
+
+// propertyDetail is the JSON representation of a drifted property.
+type propertyDetail struct {
+	Path      string      `json:"path"`
+	Expected  interface{} `json:"expected,omitempty"`
+	Actual    interface{} `json:"actual,omitempty"`
+	Sensitive bool        `json:"sensitive,omitempty"`
+}
+
+func newRefreshEnvironmentCmd() *cobra.Command {
+	var (
+		datacenter    string
+		componentName string
+		outputFormat  string
+		backendType   string
+		backendConfig []string
+	)
+
+	cmd := &cobra.Command{
+		Use:     "environment <name>",
+		Aliases: []string{"env", "envs", "environments"},
+		Short:   "Detect drift between state and real infrastructure",
+		Long: `Refresh every resource and environment module recorded in an environment's
+state by running the IaC plugin's refresh against the stored IaC state.
+
+The refreshed IaC state is written back to the state backend, and any drift
+between the stored state and the real infrastructure is reported. Nothing is
+created, updated, or destroyed.
+
+The command exits with a non-zero status if any resource could not be refreshed.
+
+Examples:
+  cldctl refresh environment production
+  cldctl refresh environment staging --component my-app
+  cldctl refresh environment production -o json`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			envName := args[0]
+			ctx := context.Background()
+
+			// Resolve datacenter
+			dc, err := resolveDatacenter(datacenter)
+			if err != nil {
+				return err
+			}
+
+			// Create state manager
+			mgr, err := createStateManagerWithConfig(backendType, backendConfig)
+			if err != nil {
+				return fmt.Errorf("failed to create state manager: %w", err)
+			}
+
+			eng := createEngine(mgr)
+
+			result, err := eng.Refresh(ctx, engine.RefreshOptions{
+				Datacenter:  dc,
+				Environment: envName,
+				Component:   componentName,
+			})
+			if err != nil {
+				return fmt.Errorf("refresh failed: %w", err)
+			}
+
+			switch outputFormat {
+			case "json":
+				reports := make([]driftReport, 0, len(result.Resources))
+				for _, res := range result.Resources {
+					reports = append(reports, newDriftReport(res))
+				}
+				data, err := json.MarshalIndent(reports, "", "  ")
+				if err != nil {
+					return fmt.Errorf("failed to marshal JSON: %w", err)
+				}
+				fmt.Println(string(data))
+			default:
+				printRefreshTable(envName, dc, result)
+			}
+
+			if !result.Success {
+				return fmt.Errorf("one or more resources could not be refreshed")
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&datacenter, "datacenter", "d", "", "Target datacenter (uses default if not set)")
+	cmd.Flags().StringVar(&componentName, "component", "", "Only refresh resources of this component")
+	cmd.Flags().StringVarP(&outputFormat, "output", "o", "table", "Output format: table, json")
+	cmd.Flags().StringVar(&backendType, "backend", "", "State backend type")
+	cmd.Flags().StringArrayVar(&backendConfig, "backend-config", nil, "Backend configuration (key=value)")
+
+	return cmd
+}
+
+func newDriftReport(res engine.ResourceRefresh) driftReport {
+	report := driftReport{
+		ID:        res.ID,
+		Component: res.Component,
+		Name:      res.Name,
+		Type:      res.Type,
+		Plugin:    res.Plugin,
+		Drifted:   res.HasDrift(),
+	}
+	if res.Error != nil {
+		report.Error = res.Error.Error()
+	}
+	for _, d := range res.Drifts {
+		detail := driftDetail{
+			ResourceID:   d.ResourceID,
+			ResourceType: d.ResourceType,
+		}
+		for _, diff := range d.Diffs {
+			p := propertyDetail{
+				Path:      diff.Path,
+				Expected:  diff.OldValue,
+				Actual:    diff.NewValue,
+				Sensitive: diff.Sensitive,
+			}
+			if diff.Sensitive {
+				p.Expected = sensitivePlaceholder
+				p.Actual = sensitivePlaceholder
+			}
+			detail.Changes = append(detail.Changes, p)
+		}
+		report.Drifts = append(report.Drifts, detail)
+	}
+	return report
+}
+
+func printRefreshTable(envName, dc string, result *engine.RefreshResult) {
+	fmt.Printf("Environment: %s\n", envName)
+	fmt.Printf("Datacenter:  %s\n", dc)
+	fmt.Println()
+
+	if len(result.Resources) == 0 {
+		fmt.Println("No resources with IaC state to refresh.")
+		return
+	}
+
+	fmt.Printf("  %-44s %-12s %-10s %s\n", "RESOURCE", "PLUGIN", "STATUS", "DETAILS")
+	for _, res := range result.Resources {
+		status := "in-sync"
+		details := ""
+		switch {
+		case res.Error != nil:
+			status = "error"
+			details = res.Error.Error()
+		case res.HasDrift():
+			status = "drifted"
+			details = fmt.Sprintf("%d drifted resource(s)", len(res.Drifts))
+		}
+		fmt.Printf("  %-44s %-12s %-10s %s\n", truncateString(res.ID, 44), res.Plugin, status, details)
+
+		for _, d := range res.Drifts {
+			fmt.Printf("      ~ %s (%s)\n", d.ResourceID, d.ResourceType)
+			for _, diff := range d.Diffs {
+				if diff.Sensitive {
+					fmt.Printf("          %s: %s\n", diff.Path, sensitivePlaceholder)
+					continue
+				}
+				fmt.Printf("          %s: %v -> %v\n", diff.Path, diff.OldValue, diff.NewValue)
+			}
+		}
+	}
+
+	errored := 0
+	for _, res := range result.Resources {
+		if res.Error != nil {
+			errored++
+		}
+	}
+	drifted := result.DriftCount()
+
+	fmt.Println()
+	fmt.Printf("Refreshed %d resource(s): %d drifted, %d in sync, %d failed\n",
+		len(result.Resources), drifted, len(result.Resources)-drifted-errored, errored)
+}
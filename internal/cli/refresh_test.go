@@ -0,0 +1,69 @@
+package cli
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/davidthor/arcctl/pkg/engine"
+	"github.com/davidthor/arcctl/pkg/iac"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewRefreshCmd(t *testing.T) {
+	cmd := newRefreshCmd()
+
+	assert.Equal(t, "refresh", cmd.Use)
+
+	subcommands := cmd.Commands()
+	assert.Len(t, subcommands, 1)
+	assert.Equal(t, "environment <name>", subcommands[0].Use)
+}
+
+func TestRefreshEnvironmentCmd_Flags(t *testing.T) {
+	cmd := newRefreshEnvironmentCmd()
+
+	assert.Contains(t, cmd.Aliases, "env")
+
+	assert.NotNil(t, cmd.Flags().Lookup("datacenter"))
+	assert.NotNil(t, cmd.Flags().Lookup("component"))
+	assert.NotNil(t, cmd.Flags().Lookup("backend"))
+	assert.NotNil(t, cmd.Flags().Lookup("backend-config"))
+
+	outputFlag := cmd.Flags().Lookup("output")
+	assert.NotNil(t, outputFlag)
+	assert.Equal(t, "table", outputFlag.DefValue)
+
+	assert.NotNil(t, cmd.Flags().ShorthandLookup("d"))
+	assert.NotNil(t, cmd.Flags().ShorthandLookup("o"))
+}
+
+func TestNewDriftReport(t *testing.T) {
+	report := newDriftReport(engine.ResourceRefresh{
+		ID:        "api/database/main",
+		Component: "api",
+		Name:      "main",
+		Type:      "database",
+		Plugin:    "opentofu",
+		Drifts: []iac.ResourceDrift{
+			{
+				ResourceID:   "aws_db_instance.main",
+				ResourceType: "aws_db_instance",
+				Diffs: []iac.PropertyDiff{
+					{Path: "instance_class", OldValue: "db.t3.micro", NewValue: "db.t3.large"},
+					{Path: "password", OldValue: "old", NewValue: "new", Sensitive: true},
+				},
+			},
+		},
+	})
+
+	assert.True(t, report.Drifted)
+	assert.Len(t, report.Drifts, 1)
+	assert.Len(t, report.Drifts[0].Changes, 2)
+	assert.Equal(t, "db.t3.large", report.Drifts[0].Changes[0].Actual)
+	assert.Equal(t, "(sensitive)", report.Drifts[0].Changes[1].Expected)
+	assert.Equal(t, "(sensitive)", report.Drifts[0].Changes[1].Actual)
+
+	failed := newDriftReport(engine.ResourceRefresh{ID: "api/database/main", Error: errors.New("boom")})
+	assert.False(t, failed.Drifted)
+	assert.Equal(t, "boom", failed.Error)
+}
@@ -0,0 +1,173 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/davidthor/arcctl/pkg/engine"
+	"github.com/davidthor/arcctl/pkg/engine/executor"
+	"github.com/spf13/cobra"
+)
+
+func newRollbackCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "rollback",
+		Short: "Roll back to a previous state",
+		Long:  `Commands for rolling back deployments to a state recorded in the state history.`,
+	}
+
+	cmd.AddCommand(newRollbackEnvironmentCmd())
+
+	return cmd
+}
+
+func newRollbackEnvironmentCmd() *cobra.Command {
+	var (
+		datacenter    string
+		version       int
+		autoApprove   bool
+		backendType   string
+		backendConfig []string
+	)
+
+	cmd := &cobra.Command{
+		Use:     "environment <name>",
+		Aliases: []string{"env", "envs", "environments"},
+		Short:   "Redeploy an environment as recorded in a state snapshot",
+		Long: `Roll an environment back to a snapshot from its state history.
+
+Every component recorded in the snapshot is redeployed using the source and
+variables it was deployed with at that point. Components deployed since the
+snapshot was taken are destroyed.
+
+Components deployed from an OCI reference are restored to that exact version.
+Components deployed from a local path are redeployed from the current contents
+of that path with the recorded variables.
+
+List the available snapshots with 'cldctl state history environment'.
+
+Examples:
+  cldctl rollback environment production --to 12
+  cldctl rollback environment staging -d my-dc --to 3 --auto-approve`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			envName := args[0]
+			ctx := context.Background()
+
+			if version < 1 {
+				return fmt.Errorf("--to must be set to the version of a snapshot (see 'cldctl state history environment %s')", envName)
+			}
+
+			// Resolve datacenter
+			dc, err := resolveDatacenter(datacenter)
+			if err != nil {
+				return err
+			}
+
+			// Create state manager
+			mgr, err := createStateManagerWithConfig(backendType, backendConfig)
+			if err != nil {
+				return fmt.Errorf("failed to create state manager: %w", err)
+			}
+
+			eng := createEngine(mgr)
+
+			plan, err := eng.PlanRollback(ctx, dc, envName, version)
+			if err != nil {
+				return fmt.Errorf("failed to plan rollback: %w", err)
+			}
+
+			fmt.Printf("Environment: %s\n", envName)
+			fmt.Printf("Datacenter:  %s\n", dc)
+			fmt.Printf("Snapshot:    %d (%s by %s at %s)\n", plan.Snapshot.Version, plan.Snapshot.Operation,
+				plan.Snapshot.Who, plan.Snapshot.CreatedAt.Format("2006-01-02 15:04:05"))
+			fmt.Println()
+
+			names := make([]string, 0, len(plan.Components))
+			for name := range plan.Components {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+
+			fmt.Println("Rollback Plan:")
+			fmt.Println()
+			for _, name := range names {
+				fmt.Printf("  ~ %s (%s)\n", name, plan.Components[name])
+			}
+			for _, name := range plan.Remove {
+				fmt.Printf("  - %s\n", name)
+			}
+			fmt.Println()
+			fmt.Printf("Rollback: %d to redeploy, %d to destroy\n", len(names), len(plan.Remove))
+			fmt.Println()
+
+			// Confirm unless --auto-approve is provided
+			if !autoApprove && isInteractive() {
+				fmt.Print("Proceed with rollback? [y/N]: ")
+				var response string
+				_, _ = fmt.Scanln(&response)
+				response = strings.ToLower(strings.TrimSpace(response))
+				if response != "y" && response != "yes" {
+					fmt.Println("Rollback cancelled.")
+					return nil
+				}
+				fmt.Println()
+			}
+
+			onProgress := func(event executor.ProgressEvent) {
+				switch event.Status {
+				case "running":
+					fmt.Printf("  [%s] %s (%s): %s...\n", event.NodeType, event.NodeName, event.NodeID, event.Message)
+				case "completed":
+					fmt.Printf("  [%s] %s: ready\n", event.NodeType, event.NodeName)
+				case "failed":
+					errMsg := "unknown error"
+					if event.Error != nil {
+						errMsg = event.Error.Error()
+					}
+					fmt.Printf("  [%s] %s: failed (%s)\n", event.NodeType, event.NodeName, errMsg)
+				}
+			}
+
+			rollbackCtx, stopInterrupts := handleInterrupts(ctx)
+			defer stopInterrupts()
+			result, err := eng.Rollback(rollbackCtx, engine.RollbackOptions{
+				Environment: envName,
+				Datacenter:  dc,
+				Version:     version,
+				Output:      os.Stdout,
+				Parallelism: defaultParallelism,
+				OnProgress:  onProgress,
+			})
+			if err != nil {
+				return fmt.Errorf("rollback failed: %w", err)
+			}
+
+			if !result.Success {
+				printInterrupted(rollbackCtx, "Run the rollback again to finish.")
+				if result.Deploy != nil && result.Deploy.Execution != nil && len(result.Deploy.Execution.Errors) > 0 {
+					return fmt.Errorf("rollback failed with %d errors: %v", len(result.Deploy.Execution.Errors), result.Deploy.Execution.Errors[0])
+				}
+				return fmt.Errorf("rollback failed")
+			}
+
+			fmt.Println()
+			fmt.Printf("[success] Environment %q rolled back to snapshot %d in %s\n", envName, version, result.Duration.Round(time.Second))
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&datacenter, "datacenter", "d", "", "Target datacenter (uses default if not set)")
+	cmd.Flags().IntVar(&version, "to", 0, "Version of the snapshot to roll back to")
+	cmd.Flags().BoolVar(&autoApprove, "auto-approve", false, "Skip confirmation prompt")
+	cmd.Flags().StringVar(&backendType, "backend", "", "State backend type")
+	cmd.Flags().StringArrayVar(&backendConfig, "backend-config", nil, "Backend configuration (key=value)")
+	_ = cmd.MarkFlagRequired("to")
+
+	return cmd
+}
@@ -0,0 +1,28 @@
+package cli
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewRollbackCmd(t *testing.T) {
+	cmd := newRollbackCmd()
+
+	assert.Equal(t, "rollback", cmd.Use)
+
+	subcommands := cmd.Commands()
+	assert.Len(t, subcommands, 1)
+	assert.Equal(t, "environment <name>", subcommands[0].Use)
+}
+
+func TestRollbackEnvironmentCmd_Flags(t *testing.T) {
+	cmd := newRollbackEnvironmentCmd()
+
+	assert.Contains(t, cmd.Aliases, "env")
+
+	for _, name := range []string{"datacenter", "to", "auto-approve", "backend", "backend-config"} {
+		assert.NotNil(t, cmd.Flags().Lookup(name), "flag %q should exist", name)
+	}
+	assert.NotNil(t, cmd.Flags().ShorthandLookup("d"))
+}
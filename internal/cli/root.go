@@ -11,6 +11,7 @@ import (
 	_ "github.com/davidthor/arcctl/pkg/state/backend/azurerm"
 	_ "github.com/davidthor/arcctl/pkg/state/backend/gcs"
 	_ "github.com/davidthor/arcctl/pkg/state/backend/local"
+	_ "github.com/davidthor/arcctl/pkg/state/backend/postgres"
 	_ "github.com/davidthor/arcctl/pkg/state/backend/s3"
 
 	// Import log query adapters to register them via init()
@@ -64,6 +65,7 @@ func init() {
 	// Add action-based commands (new inverted syntax)
 	rootCmd.AddCommand(newBuildCmd())
 	rootCmd.AddCommand(newDeployCmd())
+	rootCmd.AddCommand(newApplyCmd())
 	rootCmd.AddCommand(newDestroyCmd())
 	rootCmd.AddCommand(newListCmd())
 	rootCmd.AddCommand(newGetCmd())
@@ -74,6 +76,8 @@ func init() {
 	rootCmd.AddCommand(newPullCmd())
 	rootCmd.AddCommand(newValidateCmd())
 	rootCmd.AddCommand(newInspectCmd())
+	rootCmd.AddCommand(newGraphCmd())
+	rootCmd.AddCommand(newExplainCmd())
 
 	// Keep the up command and version command
 	rootCmd.AddCommand(newUpCmd())
@@ -88,6 +92,14 @@ func init() {
 	// Migration commands
 	rootCmd.AddCommand(newMigrateCmd())
 
+	// Drift detection commands
+	rootCmd.AddCommand(newRefreshCmd())
+
+	// State management commands
+	rootCmd.AddCommand(newStateCmd())
+	rootCmd.AddCommand(newRollbackCmd())
+	rootCmd.AddCommand(newImportCmd())
+
 	// Observability commands
 	rootCmd.AddCommand(newLogsCmd())
 	rootCmd.AddCommand(newObservabilityCmd())
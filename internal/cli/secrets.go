@@ -0,0 +1,51 @@
+package cli
+
+import (
+	"github.com/davidthor/arcctl/pkg/secrets"
+	"github.com/spf13/viper"
+)
+
+// Config keys for secret provider settings in ~/.cldctl/config.yaml:
+//
+//	secrets:
+//	  vault:
+//	    address: https://vault.example.com
+//	    namespace: my-team
+//	    mount_path: secret
+//	  aws:
+//	    region: us-east-1
+//	    prefix: myapp/
+const (
+	ConfigKeySecretsVaultAddress   = "secrets.vault.address"
+	ConfigKeySecretsVaultToken     = "secrets.vault.token"
+	ConfigKeySecretsVaultNamespace = "secrets.vault.namespace"
+	ConfigKeySecretsVaultMountPath = "secrets.vault.mount_path"
+	ConfigKeySecretsAWSRegion      = "secrets.aws.region"
+	ConfigKeySecretsAWSPrefix      = "secrets.aws.prefix"
+	ConfigKeySecretsAWSEndpoint    = "secrets.aws.endpoint"
+)
+
+// secretsConfigFromViper reads secret provider settings from the CLI config.
+// Unset values fall back to each provider's defaults (e.g., VAULT_ADDR,
+// VAULT_TOKEN, and the default AWS credentials chain).
+func secretsConfigFromViper() secrets.Config {
+	return secrets.Config{
+		Vault: secrets.VaultConfig{
+			Address:   viper.GetString(ConfigKeySecretsVaultAddress),
+			Token:     viper.GetString(ConfigKeySecretsVaultToken),
+			Namespace: viper.GetString(ConfigKeySecretsVaultNamespace),
+			MountPath: viper.GetString(ConfigKeySecretsVaultMountPath),
+		},
+		AWS: secrets.AWSConfig{
+			Region:   viper.GetString(ConfigKeySecretsAWSRegion),
+			Prefix:   viper.GetString(ConfigKeySecretsAWSPrefix),
+			Endpoint: viper.GetString(ConfigKeySecretsAWSEndpoint),
+		},
+	}
+}
+
+// createSecretsManager creates the secrets manager used to resolve secret
+// references such as vault://path#field and aws-sm://name#key.
+func createSecretsManager() *secrets.Manager {
+	return secrets.NewManagerFromConfig(secretsConfigFromViper())
+}
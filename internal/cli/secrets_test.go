@@ -0,0 +1,26 @@
+package cli
+
+import (
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSecretsConfigFromViper(t *testing.T) {
+	viper.Set(ConfigKeySecretsVaultAddress, "https://vault.example.com")
+	viper.Set(ConfigKeySecretsVaultMountPath, "kv")
+	viper.Set(ConfigKeySecretsAWSRegion, "eu-west-1")
+	defer func() {
+		viper.Set(ConfigKeySecretsVaultAddress, "")
+		viper.Set(ConfigKeySecretsVaultMountPath, "")
+		viper.Set(ConfigKeySecretsAWSRegion, "")
+	}()
+
+	cfg := secretsConfigFromViper()
+
+	assert.Equal(t, "https://vault.example.com", cfg.Vault.Address)
+	assert.Equal(t, "kv", cfg.Vault.MountPath)
+	assert.Equal(t, "eu-west-1", cfg.AWS.Region)
+	assert.Empty(t, cfg.AWS.Prefix)
+}
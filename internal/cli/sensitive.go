@@ -0,0 +1,152 @@
+package cli
+
+import (
+	"strings"
+
+	"github.com/davidthor/arcctl/pkg/state/types"
+)
+
+// sensitivePlaceholder is displayed in place of sensitive state values.
+const sensitivePlaceholder = "(sensitive)"
+
+// The redact functions return copies of state with sensitive variables,
+// inputs, and outputs replaced by sensitivePlaceholder and raw IaC state removed, so that
+// state can be displayed in any output format without leaking secrets.
+
+func redactDatacenterState(dc *types.DatacenterState) *types.DatacenterState {
+	redacted := *dc
+	redacted.Variables = redactVariables(dc.Variables, dc.SensitiveVariables)
+	redacted.Modules = redactModules(dc.Modules)
+	return &redacted
+}
+
+func redactEnvironmentState(env *types.EnvironmentState) *types.EnvironmentState {
+	redacted := *env
+	if env.Components != nil {
+		redacted.Components = make(map[string]*types.ComponentState, len(env.Components))
+		for name, comp := range env.Components {
+			redacted.Components[name] = redactComponentState(comp)
+		}
+	}
+	redacted.Modules = redactModules(env.Modules)
+	return &redacted
+}
+
+func redactComponentState(comp *types.ComponentState) *types.ComponentState {
+	if comp == nil {
+		return nil
+	}
+	redacted := *comp
+	redacted.Variables = redactVariables(comp.Variables, comp.SensitiveVariables)
+	if comp.Resources != nil {
+		redacted.Resources = make(map[string]*types.ResourceState, len(comp.Resources))
+		for key, res := range comp.Resources {
+			redacted.Resources[key] = redactResourceState(res)
+		}
+	}
+	return &redacted
+}
+
+func redactResourceState(res *types.ResourceState) *types.ResourceState {
+	if res == nil {
+		return nil
+	}
+	redacted := *res
+	redacted.Inputs = redactInputs(res.Inputs, res.SensitiveInputs)
+	redacted.Outputs = redactOutputs(res.Outputs, res.SensitiveOutputs)
+	redacted.IaCState = nil
+	return &redacted
+}
+
+func redactModules(modules map[string]*types.ModuleState) map[string]*types.ModuleState {
+	if modules == nil {
+		return nil
+	}
+	redacted := make(map[string]*types.ModuleState, len(modules))
+	for name, mod := range modules {
+		if mod == nil {
+			continue
+		}
+		m := *mod
+		m.Inputs = redactInputs(mod.Inputs, mod.SensitiveInputs)
+		m.Outputs = redactOutputs(mod.Outputs, mod.SensitiveOutputs)
+		m.IaCState = nil
+		redacted[name] = &m
+	}
+	return redacted
+}
+
+func redactVariables(vars map[string]string, sensitive []string) map[string]string {
+	if len(vars) == 0 || len(sensitive) == 0 {
+		return vars
+	}
+	redacted := make(map[string]string, len(vars))
+	for k, v := range vars {
+		redacted[k] = v
+	}
+	for _, name := range sensitive {
+		if _, ok := redacted[name]; ok {
+			redacted[name] = sensitivePlaceholder
+		}
+	}
+	return redacted
+}
+
+// redactInputs redacts sensitive inputs, which name either a top-level input
+// or "<input>.<key>" for a key of an input that is a map.
+func redactInputs(inputs map[string]interface{}, sensitive []string) map[string]interface{} {
+	if len(inputs) == 0 || len(sensitive) == 0 {
+		return inputs
+	}
+	redacted := make(map[string]interface{}, len(inputs))
+	for k, v := range inputs {
+		redacted[k] = v
+	}
+	for _, name := range sensitive {
+		if _, ok := inputs[name]; ok {
+			redacted[name] = sensitivePlaceholder
+			continue
+		}
+		input, key, ok := strings.Cut(name, ".")
+		if !ok {
+			continue
+		}
+		switch values := redacted[input].(type) {
+		case map[string]interface{}:
+			if _, ok := values[key]; ok {
+				copied := make(map[string]interface{}, len(values))
+				for k, v := range values {
+					copied[k] = v
+				}
+				copied[key] = sensitivePlaceholder
+				redacted[input] = copied
+			}
+		case map[string]string:
+			if _, ok := values[key]; ok {
+				copied := make(map[string]string, len(values))
+				for k, v := range values {
+					copied[k] = v
+				}
+				copied[key] = sensitivePlaceholder
+				redacted[input] = copied
+			}
+		}
+	}
+	return redacted
+}
+
+func redactOutputs(outputs map[string]interface{}, sensitive []string) map[string]interface{} {
+	if len(outputs) == 0 || len(sensitive) == 0 {
+		return outputs
+	}
+	redacted := make(map[string]interface{}, len(outputs))
+	for k, v := range outputs {
+		redacted[k] = v
+	}
+	for _, name := range sensitive {
+		if _, ok := redacted[name]; ok {
+			redacted[name] = sensitivePlaceholder
+		}
+	}
+	return redacted
+}
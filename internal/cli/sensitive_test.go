@@ -0,0 +1,72 @@
+package cli
+
+import (
+	"testing"
+
+	"github.com/davidthor/arcctl/pkg/state/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedactEnvironmentState(t *testing.T) {
+	env := &types.EnvironmentState{
+		Name: "production",
+		Components: map[string]*types.ComponentState{
+			"api": {
+				Name:               "api",
+				Variables:          map[string]string{"log_level": "info", "api_key": "key-123"},
+				SensitiveVariables: []string{"api_key"},
+				Resources: map[string]*types.ResourceState{
+					"database.main": {
+						Name:             "main",
+						Type:             "database",
+						Inputs:           map[string]interface{}{"name": "main", "password": "hunter2", "environment": map[string]string{"DB_PASSWORD": "hunter2", "LOG_LEVEL": "info"}},
+						SensitiveInputs:  []string{"password", "environment.DB_PASSWORD"},
+						Outputs:          map[string]interface{}{"host": "db.internal", "url": "postgres://u:hunter2@db"},
+						SensitiveOutputs: []string{"url"},
+						IaCState:         []byte(`{"password":"hunter2"}`),
+					},
+				},
+			},
+		},
+	}
+
+	redacted := redactEnvironmentState(env)
+
+	comp := redacted.Components["api"]
+	assert.Equal(t, "info", comp.Variables["log_level"])
+	assert.Equal(t, sensitivePlaceholder, comp.Variables["api_key"])
+
+	res := comp.Resources["database.main"]
+	assert.Equal(t, "main", res.Inputs["name"])
+	assert.Equal(t, sensitivePlaceholder, res.Inputs["password"])
+	assert.Equal(t, map[string]string{"DB_PASSWORD": sensitivePlaceholder, "LOG_LEVEL": "info"}, res.Inputs["environment"])
+	assert.Equal(t, "db.internal", res.Outputs["host"])
+	assert.Equal(t, sensitivePlaceholder, res.Outputs["url"])
+	assert.Nil(t, res.IaCState)
+	assert.Equal(t, sensitivePlaceholder, resourceSummary(res))
+
+	// The original state is left untouched
+	assert.Equal(t, "key-123", env.Components["api"].Variables["api_key"])
+	assert.Equal(t, "postgres://u:hunter2@db", env.Components["api"].Resources["database.main"].Outputs["url"])
+	assert.Equal(t, "hunter2", env.Components["api"].Resources["database.main"].Inputs["environment"].(map[string]string)["DB_PASSWORD"])
+}
+
+func TestRedactDatacenterState_ModuleInputs(t *testing.T) {
+	dc := &types.DatacenterState{
+		Name: "aws-us-east",
+		Modules: map[string]*types.ModuleState{
+			"vpc": {
+				Name:            "vpc",
+				Inputs:          map[string]interface{}{"region": "us-east-1", "token": "hunter2"},
+				SensitiveInputs: []string{"token"},
+				IaCState:        []byte(`{"token":"hunter2"}`),
+			},
+		},
+	}
+
+	mod := redactDatacenterState(dc).Modules["vpc"]
+	assert.Equal(t, "us-east-1", mod.Inputs["region"])
+	assert.Equal(t, sensitivePlaceholder, mod.Inputs["token"])
+	assert.Nil(t, mod.IaCState)
+	assert.Equal(t, "hunter2", dc.Modules["vpc"].Inputs["token"])
+}
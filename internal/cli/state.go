@@ -1,24 +1,68 @@
 package cli
 
 import (
+	"fmt"
 	"os"
 	"strings"
 
 	"github.com/davidthor/arcctl/pkg/state"
 	"github.com/davidthor/arcctl/pkg/state/backend"
+	"github.com/davidthor/arcctl/pkg/state/encryption"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 )
 
 // Environment variable names for state backend configuration.
 const (
-	// EnvStateBackend sets the state backend type (local, s3, gcs, azurerm).
+	// EnvStateBackend sets the state backend type (local, s3, gcs, azurerm, postgres).
 	EnvStateBackend = "CLDCTL_STATE_BACKEND"
 
 	// EnvStatePrefix is the prefix for backend-specific config environment variables.
 	// For example, CLDCTL_STATE_PATH sets the "path" config for the local backend,
 	// CLDCTL_STATE_BUCKET sets the "bucket" config for S3/GCS backends.
 	EnvStatePrefix = "CLDCTL_STATE_"
+
+	// EnvStateEncryptionKey sets the base64-encoded key used to encrypt
+	// sensitive state values. It is not passed to the backend.
+	EnvStateEncryptionKey = "CLDCTL_STATE_ENCRYPTION_KEY"
+)
+
+// Config keys for state encryption settings in ~/.cldctl/config.yaml:
+//
+//	state:
+//	  encryption:
+//	    key: <base64 32-byte key>
+//	    vault_transit:
+//	      address: https://vault.example.com
+//	      key_name: cldctl-state
+const (
+	ConfigKeyStateEncryptionKey            = "state.encryption.key"
+	ConfigKeyStateEncryptionVaultAddress   = "state.encryption.vault_transit.address"
+	ConfigKeyStateEncryptionVaultToken     = "state.encryption.vault_transit.token"
+	ConfigKeyStateEncryptionVaultNamespace = "state.encryption.vault_transit.namespace"
+	ConfigKeyStateEncryptionVaultMountPath = "state.encryption.vault_transit.mount_path"
+	ConfigKeyStateEncryptionVaultKeyName   = "state.encryption.vault_transit.key_name"
 )
 
+func newStateCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "state",
+		Short: "Inspect and manage stored state",
+		Long:  `Commands for inspecting and managing state stored in the state backend.`,
+	}
+
+	cmd.AddCommand(newStateHistoryCmd())
+	cmd.AddCommand(newStateLockCmd())
+	cmd.AddCommand(newStateForceUnlockCmd())
+	cmd.AddCommand(newStateExportCmd())
+	cmd.AddCommand(newStateImportCmd())
+	cmd.AddCommand(newStateShowCmd())
+	cmd.AddCommand(newStateMvCmd())
+	cmd.AddCommand(newStateRmCmd())
+
+	return cmd
+}
+
 // createStateManagerWithConfig creates a state manager with the given backend type and config.
 //
 // Configuration precedence (highest to lowest):
@@ -37,7 +81,7 @@ func createStateManagerWithConfig(backendType string, backendConfig []string) (s
 
 	// Check for backend-specific env vars (CLDCTL_STATE_PATH, CLDCTL_STATE_BUCKET, etc.)
 	for _, env := range os.Environ() {
-		if strings.HasPrefix(env, EnvStatePrefix) && !strings.HasPrefix(env, EnvStateBackend) {
+		if strings.HasPrefix(env, EnvStatePrefix) && !strings.HasPrefix(env, EnvStateBackend) && !strings.HasPrefix(env, EnvStateEncryptionKey) {
 			parts := strings.SplitN(env, "=", 2)
 			if len(parts) == 2 {
 				// Convert CLDCTL_STATE_PATH to "path", CLDCTL_STATE_BUCKET to "bucket", etc.
@@ -64,5 +108,39 @@ func createStateManagerWithConfig(backendType string, backendConfig []string) (s
 		Config: effectiveConfig,
 	}
 
-	return state.NewManagerFromConfig(config)
+	enc, err := encryption.NewFromConfig(stateEncryptionConfigFromViper())
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure state encryption: %w", err)
+	}
+
+	// Without a key, sensitive state is kept in plain text only in local
+	// state, which stays on this machine; other backends require a key
+	var opts []state.Option
+	if enc != nil {
+		opts = append(opts, state.WithEncrypter(enc))
+	} else if effectiveBackend == "local" {
+		opts = append(opts, state.WithPlaintextState())
+	}
+
+	return state.NewManagerFromConfig(config, opts...)
+}
+
+// stateEncryptionConfigFromViper reads state encryption settings from the CLI
+// config. CLDCTL_STATE_ENCRYPTION_KEY takes precedence over the configured key.
+func stateEncryptionConfigFromViper() encryption.Config {
+	key := viper.GetString(ConfigKeyStateEncryptionKey)
+	if envKey := os.Getenv(EnvStateEncryptionKey); envKey != "" {
+		key = envKey
+	}
+
+	return encryption.Config{
+		Key: key,
+		VaultTransit: encryption.VaultTransitConfig{
+			Address:   viper.GetString(ConfigKeyStateEncryptionVaultAddress),
+			Token:     viper.GetString(ConfigKeyStateEncryptionVaultToken),
+			Namespace: viper.GetString(ConfigKeyStateEncryptionVaultNamespace),
+			MountPath: viper.GetString(ConfigKeyStateEncryptionVaultMountPath),
+			KeyName:   viper.GetString(ConfigKeyStateEncryptionVaultKeyName),
+		},
+	}
 }
@@ -0,0 +1,238 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/davidthor/arcctl/pkg/engine"
+	"github.com/spf13/cobra"
+)
+
+func newStateShowCmd() *cobra.Command {
+	var (
+		datacenter    string
+		outputFormat  string
+		backendType   string
+		backendConfig []string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "show <environment/component/[type/]name>",
+		Short: "Show the state of a single resource",
+		Long: `Show the state recorded for a single resource.
+
+Resources are addressed as environment/component/type/name. The type can be
+left out when no other resource in the component has the same name.
+
+Sensitive inputs and outputs are redacted and raw IaC state is never shown.
+
+Examples:
+  cldctl state show staging/my-app/database/main
+  cldctl state show production/my-app/api -d my-dc -o json`,
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := context.Background()
+
+			addr, err := engine.ParseStateAddress(args[0])
+			if err != nil {
+				return err
+			}
+
+			dc, err := resolveDatacenter(datacenter)
+			if err != nil {
+				return err
+			}
+
+			mgr, err := createStateManagerWithConfig(backendType, backendConfig)
+			if err != nil {
+				return fmt.Errorf("failed to create state manager: %w", err)
+			}
+
+			res, err := createEngine(mgr).ResourceState(ctx, dc, addr)
+			if err != nil {
+				return err
+			}
+
+			return inspectResourceState(res, dc, addr.Environment, outputFormat)
+		},
+	}
+
+	cmd.Flags().StringVarP(&datacenter, "datacenter", "d", "", "Target datacenter (uses default if not set)")
+	cmd.Flags().StringVarP(&outputFormat, "output", "o", "table", "Output format: table, json, yaml")
+	cmd.Flags().StringVar(&backendType, "backend", "", "State backend type")
+	cmd.Flags().StringArrayVar(&backendConfig, "backend-config", nil, "Backend configuration (key=value)")
+
+	return cmd
+}
+
+func newStateMvCmd() *cobra.Command {
+	var (
+		datacenter    string
+		backendType   string
+		backendConfig []string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "mv <source> <destination>",
+		Short: "Rename or move a component or resource in state",
+		Long: `Move a component or resource to a new address in state, without changing
+the infrastructure it tracks.
+
+Components are addressed as environment/component, and resources as
+environment/component/type/name. Use mv to:
+
+  - Rename a component:              staging/api staging/backend
+  - Rename a resource:               staging/api/database/main staging/api/database/primary
+  - Move a resource to a component:  staging/api/database/main staging/billing/database/main
+  - Move a component to another env: staging/api preview/api
+
+After renaming a resource in a component's cloud.component.yml, move its state
+to the new name before deploying, so the existing resource is updated in place
+instead of destroyed and recreated.
+
+A resource can only be moved to a component that declares it under the new
+name. The component is loaded from the source recorded in state; if it can't
+be loaded, the move goes ahead with a warning. The environments involved are
+locked during the move.
+
+Examples:
+  cldctl state mv staging/my-app/database/main staging/my-app/database/primary
+  cldctl state mv production/api production/backend -d my-dc`,
+		Args:         cobra.ExactArgs(2),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := context.Background()
+
+			from, err := engine.ParseStateAddress(args[0])
+			if err != nil {
+				return err
+			}
+			to, err := engine.ParseStateAddress(args[1])
+			if err != nil {
+				return err
+			}
+
+			dc, err := resolveDatacenter(datacenter)
+			if err != nil {
+				return err
+			}
+
+			mgr, err := createStateManagerWithConfig(backendType, backendConfig)
+			if err != nil {
+				return fmt.Errorf("failed to create state manager: %w", err)
+			}
+
+			err = createEngine(mgr).MoveState(ctx, engine.MoveStateOptions{
+				Datacenter: dc,
+				From:       from,
+				To:         to,
+				Output:     os.Stdout,
+			})
+			if err != nil {
+				return fmt.Errorf("failed to move state: %w", err)
+			}
+
+			fmt.Printf("Moved %s to %s.\n", from, to)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&datacenter, "datacenter", "d", "", "Target datacenter (uses default if not set)")
+	cmd.Flags().StringVar(&backendType, "backend", "", "State backend type")
+	cmd.Flags().StringArrayVar(&backendConfig, "backend-config", nil, "Backend configuration (key=value)")
+
+	return cmd
+}
+
+func newStateRmCmd() *cobra.Command {
+	var (
+		datacenter    string
+		autoApprove   bool
+		backendType   string
+		backendConfig []string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "rm <address>...",
+		Short: "Remove components or resources from state without destroying them",
+		Long: `Remove components or resources from state. The infrastructure they track is
+not destroyed; cldctl simply stops managing it.
+
+Components are addressed as environment/component, and resources as
+environment/component/type/name.
+
+If the component still declares a removed resource, the next deploy of the
+component creates it again. The environments involved are locked while state
+is updated.
+
+Examples:
+  cldctl state rm staging/my-app/database/legacy
+  cldctl state rm production/old-app -d my-dc --auto-approve`,
+		Args:         cobra.MinimumNArgs(1),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := context.Background()
+
+			var addrs []engine.StateAddress
+			for _, arg := range args {
+				addr, err := engine.ParseStateAddress(arg)
+				if err != nil {
+					return err
+				}
+				addrs = append(addrs, addr)
+			}
+
+			dc, err := resolveDatacenter(datacenter)
+			if err != nil {
+				return err
+			}
+
+			mgr, err := createStateManagerWithConfig(backendType, backendConfig)
+			if err != nil {
+				return fmt.Errorf("failed to create state manager: %w", err)
+			}
+
+			fmt.Printf("Datacenter: %s\n", dc)
+			fmt.Println()
+			fmt.Println("Remove from state:")
+			for _, addr := range addrs {
+				fmt.Printf("  - %s\n", addr)
+			}
+			fmt.Println()
+
+			// Confirm unless --auto-approve is provided
+			if !autoApprove && isInteractive() {
+				fmt.Print("The infrastructure will not be destroyed. Remove from state? [y/N]: ")
+				var response string
+				_, _ = fmt.Scanln(&response)
+				response = strings.ToLower(strings.TrimSpace(response))
+				if response != "y" && response != "yes" {
+					fmt.Println("State removal cancelled.")
+					return nil
+				}
+			}
+
+			err = createEngine(mgr).RemoveState(ctx, engine.RemoveStateOptions{
+				Datacenter: dc,
+				Addresses:  addrs,
+				Output:     os.Stdout,
+			})
+			if err != nil {
+				return fmt.Errorf("failed to remove state: %w", err)
+			}
+
+			fmt.Printf("Removed %d item(s) from state.\n", len(addrs))
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&datacenter, "datacenter", "d", "", "Target datacenter (uses default if not set)")
+	cmd.Flags().BoolVar(&autoApprove, "auto-approve", false, "Skip confirmation prompt")
+	cmd.Flags().StringVar(&backendType, "backend", "", "State backend type")
+	cmd.Flags().StringArrayVar(&backendConfig, "backend-config", nil, "Backend configuration (key=value)")
+
+	return cmd
+}
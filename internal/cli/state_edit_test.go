@@ -0,0 +1,41 @@
+package cli
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewStateEditCmds(t *testing.T) {
+	cmd := newStateCmd()
+
+	show, _, err := cmd.Find([]string{"show"})
+	require.NoError(t, err)
+	assert.Equal(t, "show <environment/component/[type/]name>", show.Use)
+	assert.NotNil(t, show.Flags().Lookup("output"))
+
+	mv, _, err := cmd.Find([]string{"mv"})
+	require.NoError(t, err)
+	assert.Equal(t, "mv <source> <destination>", mv.Use)
+	assert.NotNil(t, mv.Flags().Lookup("datacenter"))
+
+	rm, _, err := cmd.Find([]string{"rm"})
+	require.NoError(t, err)
+	assert.Equal(t, "rm <address>...", rm.Use)
+	assert.NotNil(t, rm.Flags().Lookup("auto-approve"))
+}
+
+func TestStateEditCmds_InvalidAddress(t *testing.T) {
+	mv := newStateMvCmd()
+	mv.SetArgs([]string{"staging", "staging/api"})
+	err := mv.Execute()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `invalid address "staging"`)
+
+	rm := newStateRmCmd()
+	rm.SetArgs([]string{"staging/api/database/main/extra"})
+	err = rm.Execute()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid address")
+}
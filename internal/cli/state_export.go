@@ -0,0 +1,241 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/user"
+	"strings"
+
+	"github.com/davidthor/arcctl/pkg/state"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+func newStateExportCmd() *cobra.Command {
+	var (
+		datacenter    string
+		outFile       string
+		backendType   string
+		backendConfig []string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "export",
+		Short: "Export a datacenter's state to an archive",
+		Long: `Export all state stored for a datacenter as a gzipped tar archive.
+
+The archive contains the datacenter, its components, and every environment
+with its history, components, and resources, along with a manifest recording
+the archive format version and a SHA-256 checksum for each file. State files
+are copied as stored, so encrypted values stay encrypted and can only be read
+with the same encryption key.
+
+The datacenter and its environments are locked while the state is read.
+
+Examples:
+  cldctl state export -d my-dc > state.tar.gz
+  cldctl state export -d my-dc --out backups/my-dc.tar.gz`,
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := context.Background()
+
+			var w io.Writer = os.Stdout
+			if outFile == "" || outFile == "-" {
+				if term.IsTerminal(int(os.Stdout.Fd())) {
+					return fmt.Errorf("refusing to write an archive to a terminal; redirect the output or use --out")
+				}
+			} else {
+				f, err := os.Create(outFile)
+				if err != nil {
+					return fmt.Errorf("failed to create %s: %w", outFile, err)
+				}
+				defer f.Close()
+				w = f
+			}
+
+			b, err := createBackend(backendType, backendConfig)
+			if err != nil {
+				return fmt.Errorf("failed to create backend: %w", err)
+			}
+
+			manifest, err := state.ExportDatacenter(ctx, b, datacenter, w, state.ArchiveOptions{Who: stateOperator()})
+			if err != nil {
+				return fmt.Errorf("failed to export state: %w", err)
+			}
+
+			fmt.Fprintf(os.Stderr, "Exported datacenter %s from %s backend: %s\n", datacenter, b.Type(), archiveSummary(manifest))
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&datacenter, "datacenter", "d", "", "Datacenter to export (required)")
+	cmd.Flags().StringVar(&outFile, "out", "", "File to write the archive to (default: stdout)")
+	cmd.Flags().StringVar(&backendType, "backend", "", "State backend type")
+	cmd.Flags().StringArrayVar(&backendConfig, "backend-config", nil, "Backend configuration (key=value)")
+	_ = cmd.MarkFlagRequired("datacenter")
+
+	return cmd
+}
+
+func newStateImportCmd() *cobra.Command {
+	var (
+		datacenter        string
+		force             bool
+		backendType       string
+		backendConfig     []string
+		fromBackendType   string
+		fromBackendConfig []string
+		toBackendType     string
+		toBackendConfig   []string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "import [archive]",
+		Short: "Import a datacenter's state from an archive or another backend",
+		Long: `Import datacenter state from an archive written by 'cldctl state export',
+or copy it directly from one backend to another.
+
+The archive is read from the given file, or from stdin if no file is given.
+Its format version and checksums are verified before anything is written.
+
+With --from-backend, state for the datacenter given by --datacenter is copied
+directly from the source backend to the backend set by --to-backend. The
+datacenter and its environments are locked in both backends during the copy.
+
+Importing into a backend that already has state for the datacenter fails
+unless --force is set. With --force, the datacenter's state is replaced:
+files that aren't in the archive are removed.
+
+Examples:
+  cldctl state import state.tar.gz --backend s3 --backend-config bucket=my-state
+  cldctl state export -d my-dc | cldctl state import --backend s3 --backend-config bucket=my-state
+  cldctl state import -d my-dc --from-backend local --to-backend s3 --to-backend-config bucket=my-state`,
+		Args:         cobra.MaximumNArgs(1),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := context.Background()
+			opts := state.ArchiveOptions{Who: stateOperator(), Overwrite: force}
+
+			var (
+				manifest *state.ArchiveManifest
+				target   string
+			)
+
+			if fromBackendType != "" {
+				if len(args) > 0 {
+					return fmt.Errorf("an archive can't be imported with --from-backend")
+				}
+				if datacenter == "" {
+					return fmt.Errorf("--datacenter is required with --from-backend")
+				}
+
+				from, err := createBackend(fromBackendType, fromBackendConfig)
+				if err != nil {
+					return fmt.Errorf("failed to create source backend: %w", err)
+				}
+				to, err := createBackend(toBackendType, toBackendConfig)
+				if err != nil {
+					return fmt.Errorf("failed to create target backend: %w", err)
+				}
+				target = to.Type()
+
+				manifest, err = state.CopyDatacenter(ctx, from, to, datacenter, opts)
+				if err != nil {
+					return importError(err)
+				}
+			} else {
+				if toBackendType != "" || len(toBackendConfig) > 0 {
+					return fmt.Errorf("--to-backend requires --from-backend; use --backend to choose where an archive is imported")
+				}
+
+				var r io.Reader = os.Stdin
+				if len(args) > 0 && args[0] != "-" {
+					f, err := os.Open(args[0])
+					if err != nil {
+						return fmt.Errorf("failed to open %s: %w", args[0], err)
+					}
+					defer f.Close()
+					r = f
+				} else if term.IsTerminal(int(os.Stdin.Fd())) {
+					return fmt.Errorf("no archive given; pass a file or pipe one to stdin")
+				}
+
+				b, err := createBackend(backendType, backendConfig)
+				if err != nil {
+					return fmt.Errorf("failed to create backend: %w", err)
+				}
+				target = b.Type()
+
+				manifest, err = state.ImportArchive(ctx, b, r, opts)
+				if err != nil {
+					return importError(err)
+				}
+				if datacenter != "" && manifest.Datacenter != datacenter {
+					fmt.Printf("Warning: archive contains datacenter %s, not %s\n", manifest.Datacenter, datacenter)
+				}
+			}
+
+			fmt.Printf("Imported datacenter %s into %s backend: %s\n", manifest.Datacenter, target, archiveSummary(manifest))
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&datacenter, "datacenter", "d", "", "Datacenter to copy (required with --from-backend)")
+	cmd.Flags().BoolVar(&force, "force", false, "Replace existing state for the datacenter")
+	cmd.Flags().StringVar(&backendType, "backend", "", "State backend type to import into")
+	cmd.Flags().StringArrayVar(&backendConfig, "backend-config", nil, "Backend configuration (key=value)")
+	cmd.Flags().StringVar(&fromBackendType, "from-backend", "", "Backend type to copy state from")
+	cmd.Flags().StringArrayVar(&fromBackendConfig, "from-backend-config", nil, "Source backend configuration (key=value)")
+	cmd.Flags().StringVar(&toBackendType, "to-backend", "", "Backend type to copy state to")
+	cmd.Flags().StringArrayVar(&toBackendConfig, "to-backend-config", nil, "Target backend configuration (key=value)")
+
+	return cmd
+}
+
+// importError adds a hint to errors caused by existing state in the target.
+func importError(err error) error {
+	if errors.Is(err, state.ErrDatacenterExists) {
+		return fmt.Errorf("failed to import state: %w (use --force to replace it)", err)
+	}
+	return fmt.Errorf("failed to import state: %w", err)
+}
+
+// archiveSummary describes the state in an archive, e.g.
+// "1 datacenter, 2 environments, 5 components, 12 resources".
+func archiveSummary(m *state.ArchiveManifest) string {
+	kinds := []struct {
+		kind, singular, plural string
+	}{
+		{state.ArchiveKindDatacenter, "datacenter", "datacenters"},
+		{state.ArchiveKindDatacenterComponent, "datacenter component", "datacenter components"},
+		{state.ArchiveKindEnvironment, "environment", "environments"},
+		{state.ArchiveKindComponent, "component", "components"},
+		{state.ArchiveKindResource, "resource", "resources"},
+		{state.ArchiveKindHistory, "history snapshot", "history snapshots"},
+		{state.ArchiveKindOther, "other file", "other files"},
+	}
+
+	var parts []string
+	for _, k := range kinds {
+		switch n := m.Count(k.kind); n {
+		case 0:
+		case 1:
+			parts = append(parts, "1 "+k.singular)
+		default:
+			parts = append(parts, fmt.Sprintf("%d %s", n, k.plural))
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// stateOperator returns the name recorded on locks taken by state commands.
+func stateOperator() string {
+	if u, err := user.Current(); err == nil && u.Username != "" {
+		return u.Username
+	}
+	return os.Getenv("USER")
+}
@@ -0,0 +1,101 @@
+package cli
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/davidthor/arcctl/pkg/state"
+	"github.com/davidthor/arcctl/pkg/state/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewStateExportImportCmds(t *testing.T) {
+	cmd := newStateCmd()
+
+	export, _, err := cmd.Find([]string{"export"})
+	require.NoError(t, err)
+	assert.Equal(t, "export", export.Use)
+	assert.NotNil(t, export.Flags().Lookup("datacenter"))
+	assert.NotNil(t, export.Flags().Lookup("out"))
+
+	imp, _, err := cmd.Find([]string{"import"})
+	require.NoError(t, err)
+	assert.Equal(t, "import [archive]", imp.Use)
+	for _, name := range []string{"force", "from-backend", "from-backend-config", "to-backend", "to-backend-config"} {
+		assert.NotNil(t, imp.Flags().Lookup(name), name)
+	}
+}
+
+func TestStateExportImportCmds(t *testing.T) {
+	ctx := context.Background()
+	tmpDir := t.TempDir()
+	srcPath := filepath.Join(tmpDir, "src")
+	dstPath := filepath.Join(tmpDir, "dst")
+	archive := filepath.Join(tmpDir, "my-dc.tar.gz")
+
+	src, err := createStateManagerWithConfig("local", []string{"path=" + srcPath})
+	require.NoError(t, err)
+	require.NoError(t, src.SaveDatacenter(ctx, &types.DatacenterState{Name: "my-dc"}))
+	require.NoError(t, src.SaveEnvironment(ctx, "my-dc", &types.EnvironmentState{Name: "staging", Datacenter: "my-dc"}))
+
+	export := newStateExportCmd()
+	export.SetArgs([]string{"-d", "my-dc", "--out", archive, "--backend", "local", "--backend-config", "path=" + srcPath})
+	require.NoError(t, export.Execute())
+
+	imp := newStateImportCmd()
+	imp.SetArgs([]string{archive, "--backend", "local", "--backend-config", "path=" + dstPath})
+	require.NoError(t, imp.Execute())
+
+	dst, err := createStateManagerWithConfig("local", []string{"path=" + dstPath})
+	require.NoError(t, err)
+	env, err := dst.GetEnvironment(ctx, "my-dc", "staging")
+	require.NoError(t, err)
+	assert.Equal(t, "staging", env.Name)
+
+	// Importing again requires --force
+	imp = newStateImportCmd()
+	imp.SetArgs([]string{archive, "--backend", "local", "--backend-config", "path=" + dstPath})
+	err = imp.Execute()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "use --force")
+
+	// Direct copy between backends
+	copyPath := filepath.Join(tmpDir, "copy")
+	imp = newStateImportCmd()
+	imp.SetArgs([]string{"-d", "my-dc",
+		"--from-backend", "local", "--from-backend-config", "path=" + srcPath,
+		"--to-backend", "local", "--to-backend-config", "path=" + copyPath,
+	})
+	require.NoError(t, imp.Execute())
+
+	copied, err := createStateManagerWithConfig("local", []string{"path=" + copyPath})
+	require.NoError(t, err)
+	_, err = copied.GetDatacenter(ctx, "my-dc")
+	assert.NoError(t, err)
+}
+
+func TestStateImportCmd_InvalidFlags(t *testing.T) {
+	imp := newStateImportCmd()
+	imp.SetArgs([]string{"--from-backend", "local"})
+	err := imp.Execute()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "--datacenter is required")
+
+	imp = newStateImportCmd()
+	imp.SetArgs([]string{"state.tar.gz", "--to-backend", "s3"})
+	err = imp.Execute()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "--to-backend requires --from-backend")
+}
+
+func TestArchiveSummary(t *testing.T) {
+	m := &state.ArchiveManifest{Files: []state.ArchiveFile{
+		{Kind: state.ArchiveKindDatacenter},
+		{Kind: state.ArchiveKindEnvironment},
+		{Kind: state.ArchiveKindEnvironment},
+		{Kind: state.ArchiveKindResource},
+	}}
+	assert.Equal(t, "1 datacenter, 2 environments, 1 resource", archiveSummary(m))
+}
@@ -0,0 +1,108 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+func newStateHistoryCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "history",
+		Short: "Show state history",
+		Long:  `Commands for listing the state snapshots recorded after each operation.`,
+	}
+
+	cmd.AddCommand(newStateHistoryEnvironmentCmd())
+
+	return cmd
+}
+
+func newStateHistoryEnvironmentCmd() *cobra.Command {
+	var (
+		datacenter    string
+		outputFormat  string
+		backendType   string
+		backendConfig []string
+	)
+
+	cmd := &cobra.Command{
+		Use:     "environment <name>",
+		Aliases: []string{"env", "envs", "environments"},
+		Short:   "List the state snapshots of an environment",
+		Long: `List the numbered state snapshots recorded for an environment.
+
+A snapshot of the environment's state is recorded each time an operation
+changes it, along with the operation, the user who ran it, and the component
+it targeted. Use the version with 'cldctl rollback environment --to' to
+redeploy the environment as it was at that point.
+
+Examples:
+  cldctl state history environment production
+  cldctl state history environment staging -d my-dc -o json`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			envName := args[0]
+			ctx := context.Background()
+
+			// Resolve datacenter
+			dc, err := resolveDatacenter(datacenter)
+			if err != nil {
+				return err
+			}
+
+			// Create state manager
+			mgr, err := createStateManagerWithConfig(backendType, backendConfig)
+			if err != nil {
+				return fmt.Errorf("failed to create state manager: %w", err)
+			}
+
+			if _, err := mgr.GetEnvironment(ctx, dc, envName); err != nil {
+				return fmt.Errorf("environment %q not found in datacenter %q: %w", envName, dc, err)
+			}
+
+			history, err := mgr.ListEnvironmentHistory(ctx, dc, envName)
+			if err != nil {
+				return fmt.Errorf("failed to list state history: %w", err)
+			}
+
+			switch outputFormat {
+			case "json":
+				data, err := json.MarshalIndent(history, "", "  ")
+				if err != nil {
+					return fmt.Errorf("failed to marshal JSON: %w", err)
+				}
+				fmt.Println(string(data))
+			default:
+				if len(history) == 0 {
+					fmt.Printf("No state history recorded for environment %q.\n", envName)
+					return nil
+				}
+
+				fmt.Printf("Environment: %s\n", envName)
+				fmt.Printf("Datacenter:  %s\n\n", dc)
+				fmt.Printf("%-8s %-20s %-16s %-12s %s\n", "VERSION", "CREATED", "OPERATION", "WHO", "COMPONENT")
+				for _, snapshot := range history {
+					fmt.Printf("%-8d %-20s %-16s %-12s %s\n",
+						snapshot.Version,
+						snapshot.CreatedAt.Format("2006-01-02 15:04:05"),
+						snapshot.Operation,
+						snapshot.Who,
+						snapshot.Component,
+					)
+				}
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&datacenter, "datacenter", "d", "", "Target datacenter (uses default if not set)")
+	cmd.Flags().StringVarP(&outputFormat, "output", "o", "table", "Output format: table, json")
+	cmd.Flags().StringVar(&backendType, "backend", "", "State backend type")
+	cmd.Flags().StringArrayVar(&backendConfig, "backend-config", nil, "Backend configuration (key=value)")
+
+	return cmd
+}
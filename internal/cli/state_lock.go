@@ -0,0 +1,301 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/davidthor/arcctl/pkg/state"
+	"github.com/davidthor/arcctl/pkg/state/backend"
+	"github.com/spf13/cobra"
+)
+
+func newStateLockCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "lock",
+		Short: "Inspect state locks",
+		Long: `Commands for inspecting the locks held on environment and component state.
+
+Locks that have passed their expiration are taken over automatically by the
+next operation. Use 'cldctl state force-unlock' to release a lock that is
+still held by a process that no longer exists.`,
+	}
+
+	cmd.AddCommand(newStateLockListCmd())
+	cmd.AddCommand(newStateLockShowCmd())
+
+	return cmd
+}
+
+func newStateLockListCmd() *cobra.Command {
+	var (
+		datacenter    string
+		outputFormat  string
+		backendType   string
+		backendConfig []string
+	)
+
+	cmd := &cobra.Command{
+		Use:     "list",
+		Aliases: []string{"ls"},
+		Short:   "List state locks",
+		Long: `List the locks held on environment and component state.
+
+Lists locks in all datacenters unless --datacenter is set.
+
+Examples:
+  cldctl state lock list
+  cldctl state lock list -d my-dc -o json`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := context.Background()
+
+			mgr, err := createStateManagerWithConfig(backendType, backendConfig)
+			if err != nil {
+				return fmt.Errorf("failed to create state manager: %w", err)
+			}
+
+			locks, err := mgr.ListLocks(ctx, datacenter)
+			if err != nil {
+				return fmt.Errorf("failed to list locks: %w", err)
+			}
+
+			switch outputFormat {
+			case "json":
+				return printLocksJSON(locks)
+			default:
+				if len(locks) == 0 {
+					fmt.Println("No state locks held.")
+					return nil
+				}
+
+				now := time.Now()
+				fmt.Printf("%-36s  %-32s  %-12s  %-10s  %-20s  %s\n", "LOCK ID", "SCOPE", "WHO", "OPERATION", "CREATED", "STATUS")
+				for _, info := range locks {
+					fmt.Printf("%-36s  %-32s  %-12s  %-10s  %-20s  %s\n",
+						info.ID,
+						lockScopeName(info),
+						info.Who,
+						info.Operation,
+						info.Created.Format("2006-01-02 15:04:05"),
+						lockStatus(info, now),
+					)
+				}
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&datacenter, "datacenter", "d", "", "Only list locks in this datacenter")
+	cmd.Flags().StringVarP(&outputFormat, "output", "o", "table", "Output format: table, json")
+	cmd.Flags().StringVar(&backendType, "backend", "", "State backend type")
+	cmd.Flags().StringArrayVar(&backendConfig, "backend-config", nil, "Backend configuration (key=value)")
+
+	return cmd
+}
+
+func newStateLockShowCmd() *cobra.Command {
+	var (
+		datacenter    string
+		outputFormat  string
+		backendType   string
+		backendConfig []string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "show <environment>",
+		Short: "Show the locks held on an environment",
+		Long: `Show the locks held on an environment and its components.
+
+Examples:
+  cldctl state lock show production
+  cldctl state lock show staging -d my-dc -o json`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			envName := args[0]
+			ctx := context.Background()
+
+			dc, err := resolveDatacenter(datacenter)
+			if err != nil {
+				return err
+			}
+
+			mgr, err := createStateManagerWithConfig(backendType, backendConfig)
+			if err != nil {
+				return fmt.Errorf("failed to create state manager: %w", err)
+			}
+
+			locks, err := mgr.ListEnvironmentLocks(ctx, dc, envName)
+			if err != nil {
+				return fmt.Errorf("failed to list locks: %w", err)
+			}
+
+			switch outputFormat {
+			case "json":
+				return printLocksJSON(locks)
+			default:
+				if len(locks) == 0 {
+					fmt.Printf("Environment %q is not locked.\n", envName)
+					return nil
+				}
+
+				fmt.Printf("Environment: %s\n", envName)
+				fmt.Printf("Datacenter:  %s\n", dc)
+
+				now := time.Now()
+				for _, info := range locks {
+					fmt.Println()
+					printLockInfo(info, now)
+				}
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&datacenter, "datacenter", "d", "", "Target datacenter (uses default if not set)")
+	cmd.Flags().StringVarP(&outputFormat, "output", "o", "table", "Output format: table, json")
+	cmd.Flags().StringVar(&backendType, "backend", "", "State backend type")
+	cmd.Flags().StringArrayVar(&backendConfig, "backend-config", nil, "Backend configuration (key=value)")
+
+	return cmd
+}
+
+func newStateForceUnlockCmd() *cobra.Command {
+	var (
+		autoApprove   bool
+		backendType   string
+		backendConfig []string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "force-unlock <lock-id>",
+		Short: "Release a state lock held by another process",
+		Long: `Release a state lock regardless of which process holds it.
+
+Use this to recover from an operation that was interrupted without releasing
+its lock, such as a cancelled CI job. Find the lock ID with
+'cldctl state lock list'.
+
+Only force-unlock when you're certain no other operation is running. Releasing
+a lock during an active operation allows concurrent changes to the same state.
+
+Examples:
+  cldctl state force-unlock 6f1c2e9a-8d4b-4a57-9e0f-2b7d3c1a5e84
+  cldctl state force-unlock 6f1c2e9a-8d4b-4a57-9e0f-2b7d3c1a5e84 --auto-approve`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			lockID := args[0]
+			ctx := context.Background()
+
+			mgr, err := createStateManagerWithConfig(backendType, backendConfig)
+			if err != nil {
+				return fmt.Errorf("failed to create state manager: %w", err)
+			}
+
+			// Show what is being unlocked before confirming
+			locks, err := mgr.ListLocks(ctx, "")
+			if err != nil {
+				return fmt.Errorf("failed to list locks: %w", err)
+			}
+			var found *backend.LockInfo
+			for i := range locks {
+				if locks[i].ID == lockID {
+					found = &locks[i]
+					break
+				}
+			}
+			if found == nil {
+				return fmt.Errorf("lock %q not found", lockID)
+			}
+
+			printLockInfo(*found, time.Now())
+			fmt.Println()
+
+			// Confirm unless --auto-approve is provided
+			if !autoApprove && isInteractive() {
+				fmt.Print("Release this lock? [y/N]: ")
+				var response string
+				_, _ = fmt.Scanln(&response)
+				response = strings.ToLower(strings.TrimSpace(response))
+				if response != "y" && response != "yes" {
+					fmt.Println("Force unlock cancelled.")
+					return nil
+				}
+			}
+
+			if _, err := mgr.ForceUnlock(ctx, lockID); err != nil {
+				if errors.Is(err, state.ErrLockNotFound) {
+					fmt.Printf("Lock %s was already released.\n", lockID)
+					return nil
+				}
+				return err
+			}
+
+			fmt.Printf("Lock %s released.\n", lockID)
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&autoApprove, "auto-approve", false, "Skip confirmation prompt")
+	cmd.Flags().StringVar(&backendType, "backend", "", "State backend type")
+	cmd.Flags().StringArrayVar(&backendConfig, "backend-config", nil, "Backend configuration (key=value)")
+
+	return cmd
+}
+
+// printLockInfo prints the details of a single lock.
+func printLockInfo(info backend.LockInfo, now time.Time) {
+	fmt.Printf("Lock ID:    %s\n", info.ID)
+	fmt.Printf("Scope:      %s\n", lockScopeName(info))
+	fmt.Printf("Locked by:  %s\n", info.Who)
+	fmt.Printf("Operation:  %s\n", info.Operation)
+	fmt.Printf("Created:    %s\n", info.Created.Format("2006-01-02 15:04:05"))
+	if !info.Expires.IsZero() {
+		fmt.Printf("Expires:    %s\n", info.Expires.Format("2006-01-02 15:04:05"))
+	}
+	fmt.Printf("Status:     %s\n", lockStatus(info, now))
+}
+
+func printLocksJSON(locks []backend.LockInfo) error {
+	if locks == nil {
+		locks = []backend.LockInfo{}
+	}
+	data, err := json.MarshalIndent(locks, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal JSON: %w", err)
+	}
+	fmt.Println(string(data))
+	return nil
+}
+
+// lockScopeName describes what a lock protects, e.g. "my-dc" for a
+// datacenter, "my-dc/production" for an environment, or "my-dc/production/api"
+// for a component.
+func lockScopeName(info backend.LockInfo) string {
+	scope, ok := state.ParseLockPath(info.Path)
+	if !ok {
+		return info.Path
+	}
+	if scope.Environment == "" {
+		return scope.Datacenter
+	}
+	name := scope.Datacenter + "/" + scope.Environment
+	if scope.Component != "" {
+		name += "/" + scope.Component
+	}
+	return name
+}
+
+// lockStatus returns "expired" for locks that the next operation will take
+// over, and "held" otherwise.
+func lockStatus(info backend.LockInfo, now time.Time) string {
+	if info.Expired(now) {
+		return "expired"
+	}
+	return "held"
+}
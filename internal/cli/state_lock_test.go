@@ -0,0 +1,42 @@
+package cli
+
+import (
+	"testing"
+	"time"
+
+	"github.com/davidthor/arcctl/pkg/state/backend"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewStateLockCmds(t *testing.T) {
+	cmd := newStateCmd()
+
+	list, _, err := cmd.Find([]string{"lock", "list"})
+	assert.NoError(t, err)
+	assert.Equal(t, "list", list.Use)
+	assert.NotNil(t, list.Flags().Lookup("datacenter"))
+
+	show, _, err := cmd.Find([]string{"lock", "show"})
+	assert.NoError(t, err)
+	assert.Equal(t, "show <environment>", show.Use)
+
+	unlock, _, err := cmd.Find([]string{"force-unlock"})
+	assert.NoError(t, err)
+	assert.Equal(t, "force-unlock <lock-id>", unlock.Use)
+	assert.NotNil(t, unlock.Flags().Lookup("auto-approve"))
+}
+
+func TestLockScopeName(t *testing.T) {
+	assert.Equal(t, "my-dc", lockScopeName(backend.LockInfo{Path: "datacenters/my-dc/datacenter"}))
+	assert.Equal(t, "my-dc/production", lockScopeName(backend.LockInfo{Path: "datacenters/my-dc/environments/production"}))
+	assert.Equal(t, "my-dc/production/api", lockScopeName(backend.LockInfo{Path: "datacenters/my-dc/environments/production/api"}))
+	assert.Equal(t, "other/path", lockScopeName(backend.LockInfo{Path: "other/path"}))
+}
+
+func TestLockStatus(t *testing.T) {
+	now := time.Now()
+	assert.Equal(t, "held", lockStatus(backend.LockInfo{Created: now.Add(-time.Minute)}, now))
+	assert.Equal(t, "expired", lockStatus(backend.LockInfo{Created: now.Add(-2 * time.Hour)}, now))
+	assert.Equal(t, "expired", lockStatus(backend.LockInfo{Created: now.Add(-time.Minute), Expires: now.Add(-time.Second)}, now))
+	assert.Equal(t, "held", lockStatus(backend.LockInfo{Created: now.Add(-2 * time.Hour), Expires: now.Add(time.Hour)}, now))
+}
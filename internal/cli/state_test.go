@@ -1,10 +1,12 @@
 package cli
 
 import (
+	"context"
 	"os"
 	"path/filepath"
 	"testing"
 
+	"github.com/davidthor/arcctl/pkg/state/types"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -88,3 +90,50 @@ func TestCreateStateManagerWithConfig_CLIOverridesEnvVar(t *testing.T) {
 	_, err = os.Stat(cliPath)
 	assert.NoError(t, err)
 }
+
+func TestCreateStateManagerWithConfig_EncryptionKey(t *testing.T) {
+	t.Setenv(EnvStateEncryptionKey, "MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY=")
+
+	cfg := stateEncryptionConfigFromViper()
+	assert.Equal(t, "MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY=", cfg.Key)
+
+	mgr, err := createStateManagerWithConfig("local", []string{"path=" + t.TempDir()})
+	require.NoError(t, err)
+	assert.NotNil(t, mgr)
+}
+
+func TestCreateStateManagerWithConfig_InvalidEncryptionKey(t *testing.T) {
+	t.Setenv(EnvStateEncryptionKey, "too-short")
+
+	_, err := createStateManagerWithConfig("local", []string{"path=" + t.TempDir()})
+	assert.Error(t, err)
+}
+
+func TestCreateStateManagerWithConfig_LocalWithoutKey(t *testing.T) {
+	t.Setenv(EnvStateEncryptionKey, "")
+
+	// Local state can hold sensitive values without a key
+	mgr, err := createStateManagerWithConfig("local", []string{"path=" + t.TempDir()})
+	require.NoError(t, err)
+	err = mgr.SaveDatacenter(context.Background(), &types.DatacenterState{
+		Name:               "dc",
+		Variables:          map[string]string{"db_password": "hunter2"},
+		SensitiveVariables: []string{"db_password"},
+	})
+	assert.NoError(t, err)
+}
+
+func TestNewStateCmd(t *testing.T) {
+	cmd := newStateCmd()
+
+	assert.Equal(t, "state", cmd.Use)
+
+	history, _, err := cmd.Find([]string{"history", "environment"})
+	assert.NoError(t, err)
+	assert.Equal(t, "environment <name>", history.Use)
+	assert.Contains(t, history.Aliases, "env")
+
+	outputFlag := history.Flags().Lookup("output")
+	assert.NotNil(t, outputFlag)
+	assert.Equal(t, "table", outputFlag.DefValue)
+}
@@ -427,7 +427,19 @@ Examples:
 				fmt.Printf("  cldctl destroy environment %s\n", envName)
 			} else {
 				fmt.Println()
-				fmt.Println("Press Ctrl+C to stop...")
+				fmt.Println("Watching for file changes. Press Ctrl+C to stop...")
+
+				// Redeploy changed sources until the context is cancelled
+				err := watchAndReload(ctx, eng, engine.DeployOptions{
+					Environment: envName,
+					Datacenter:  dc,
+					Components:  componentsMap,
+					Variables:   variablesMap,
+					Parallelism: defaultParallelism,
+				}, componentName, componentFile)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Warning: hot reload disabled: %v\n", err)
+				}
 
 				// Wait for context cancellation (already set up above)
 				<-ctx.Done()
@@ -33,6 +33,7 @@ func newUpdateEnvironmentCmd() *cobra.Command {
 		autoApprove   bool
 		variables     []string
 		varFile       string
+		rollback      bool
 		backendType   string
 		backendConfig []string
 	)
@@ -55,10 +56,14 @@ When providing a config file, the environment's components and configuration
 will be updated to match the file. Components not in the file will be removed,
 and new components will be deployed.
 
+Use --rollback-on-failure to restore the resources of a component whose
+deployment fails to the inputs and state they had before the update.
+
 Examples:
   cldctl update environment staging --datacenter new-dc
   cldctl update environment staging environment.yml
-  cldctl update environment staging ./envs/staging.yml --auto-approve`,
+  cldctl update environment staging ./envs/staging.yml --auto-approve
+  cldctl update environment production environment.yml --rollback-on-failure`,
 		Args: cobra.RangeArgs(1, 2),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			envName := args[0]
@@ -108,7 +113,7 @@ Examples:
 					}
 				}
 
-				return applyEnvironmentConfig(ctx, mgr, dc, env, configFile, autoApprove, cliVars)
+				return applyEnvironmentConfig(ctx, mgr, dc, env, configFile, autoApprove, rollback, cliVars)
 			}
 
 			// Otherwise, update individual settings
@@ -123,6 +128,7 @@ Examples:
 	cmd.Flags().BoolVar(&autoApprove, "auto-approve", false, "Skip confirmation prompt (when using config file)")
 	cmd.Flags().StringArrayVar(&variables, "var", nil, "Set an environment variable (key=value)")
 	cmd.Flags().StringVar(&varFile, "var-file", "", "Load variables from a file (KEY=value format)")
+	cmd.Flags().BoolVar(&rollback, "rollback-on-failure", false, "If a component fails to deploy, restore the resources it updated to their previous state")
 	cmd.Flags().StringVar(&backendType, "backend", "", "State backend type")
 	cmd.Flags().StringArrayVar(&backendConfig, "backend-config", nil, "Backend configuration (key=value)")
 
@@ -130,7 +136,7 @@ Examples:
 }
 
 // applyEnvironmentConfig applies an environment configuration file to an existing environment.
-func applyEnvironmentConfig(ctx context.Context, mgr state.Manager, dc string, env *types.EnvironmentState, configFile string, autoApprove bool, cliVars map[string]string) error {
+func applyEnvironmentConfig(ctx context.Context, mgr state.Manager, dc string, env *types.EnvironmentState, configFile string, autoApprove, rollback bool, cliVars map[string]string) error {
 	// Load and validate the environment file
 	loader := environment.NewLoader()
 	envConfig, err := loader.Load(configFile)
@@ -321,15 +327,16 @@ func applyEnvironmentConfig(ctx context.Context, mgr state.Manager, dc string, e
 
 		// Deploy the component
 		result, err := eng.Deploy(ctx, engine.DeployOptions{
-			Environment: env.Name,
-			Datacenter:  dc,
-			Components:  map[string]string{name: comp.Source()},
-			Variables:   map[string]map[string]interface{}{name: vars},
-			Output:      os.Stdout,
-			DryRun:      false,
-			AutoApprove: true, // Already confirmed above
-			Parallelism: defaultParallelism,
-			OnProgress:  onProgress,
+			Environment:       env.Name,
+			Datacenter:        dc,
+			Components:        map[string]string{name: comp.Source()},
+			Variables:         map[string]map[string]interface{}{name: vars},
+			Output:            os.Stdout,
+			DryRun:            false,
+			AutoApprove:       true, // Already confirmed above
+			Parallelism:       defaultParallelism,
+			OnProgress:        onProgress,
+			RollbackOnFailure: rollback,
 		})
 		if err != nil {
 			fmt.Printf("  Warning: failed to deploy component %q: %v\n", name, err)
@@ -337,6 +344,14 @@ func applyEnvironmentConfig(ctx context.Context, mgr state.Manager, dc string, e
 			continue
 		}
 
+		if !result.Success {
+			err := fmt.Errorf("failed to deploy component %q%s", name, rollbackSummary(result.Rollback))
+			fmt.Printf("  Warning: %v\n", err)
+			printRollback(result.Rollback)
+			updateErrors = append(updateErrors, err)
+			continue
+		}
+
 		if result.Success && result.Execution != nil {
 			fmt.Printf("  Created: %d, Updated: %d\n", result.Execution.Created, result.Execution.Updated)
 		}
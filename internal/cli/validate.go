@@ -1,12 +1,16 @@
 package cli
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"strings"
 
+	"github.com/davidthor/arcctl/pkg/engine/executor"
 	"github.com/davidthor/arcctl/pkg/errors"
+	"github.com/davidthor/arcctl/pkg/resolver"
 	"github.com/davidthor/arcctl/pkg/schema/component"
 	"github.com/davidthor/arcctl/pkg/schema/datacenter"
 	"github.com/davidthor/arcctl/pkg/schema/environment"
@@ -28,7 +32,10 @@ func newValidateCmd() *cobra.Command {
 }
 
 func newValidateComponentCmd() *cobra.Command {
-	var file string
+	var (
+		file       string
+		datacenter string
+	)
 
 	cmd := &cobra.Command{
 		Use:     "component [path]",
@@ -36,10 +43,20 @@ func newValidateComponentCmd() *cobra.Command {
 		Short:   "Validate a component configuration",
 		Long: `Validate a component configuration file without deploying.
 
+With --datacenter, also check that the datacenter can provision the component
+and its dependencies. Every resource is matched to the datacenter's hooks, and
+the command fails if any resource has no matching hook, matches an 'error'
+hook, or references an output (e.g. ${{ databases.main.url }}) that the hook
+provisioning the referenced resource doesn't declare. The datacenter is a
+local path or OCI reference; hook conditions see the defaults of its
+variables.
+
 Examples:
   cldctl validate component
   cldctl validate component ./my-app
-  cldctl validate component -f custom-component.yml`,
+  cldctl validate component -f custom-component.yml
+  cldctl validate component ./my-app --datacenter ./datacenters/aws
+  cldctl validate component ./my-app --datacenter ghcr.io/myorg/aws-dc:v1`,
 		Args:         cobra.MaximumNArgs(1),
 		SilenceUsage: true,
 		RunE: func(cmd *cobra.Command, args []string) error {
@@ -61,15 +78,72 @@ Examples:
 			}
 
 			fmt.Println("Component configuration is valid!")
+
+			if datacenter != "" {
+				return checkDatacenterCompatibility(path, datacenter)
+			}
 			return nil
 		},
 	}
 
 	cmd.Flags().StringVarP(&file, "file", "f", "", "Path to cloud.component.yml if not in default location")
+	cmd.Flags().StringVarP(&datacenter, "datacenter", "d", "", "Check that this datacenter (path or OCI reference) can provision the component")
 
 	return cmd
 }
 
+// checkDatacenterCompatibility builds the graph of a component and its
+// dependencies and checks that the datacenter at dcRef can provision it.
+func checkDatacenterCompatibility(path, dcRef string) error {
+	ctx := context.Background()
+
+	res := resolver.NewResolver(resolver.Options{
+		AllowLocal:  true,
+		AllowRemote: true,
+	})
+	depGraph, err := resolver.NewDependencyResolver(res).Resolve(ctx, path, nil)
+	if err != nil {
+		return formatResolveError(err)
+	}
+	g, err := buildExpandedGraph(depGraph)
+	if err != nil {
+		return err
+	}
+
+	// Matching hooks doesn't read or write state
+	issues, err := createEngine(nil).CheckCompatibility(dcRef, g)
+	if err != nil {
+		return err
+	}
+	if len(issues) == 0 {
+		fmt.Printf("Component is compatible with datacenter %s!\n", dcRef)
+		return nil
+	}
+
+	fmt.Println()
+	printCompatibilityIssues(os.Stdout, dcRef, issues)
+	return fmt.Errorf("component is not compatible with datacenter %s: %d issue(s)", dcRef, len(issues))
+}
+
+// printCompatibilityIssues prints the resources a datacenter can't provision.
+func printCompatibilityIssues(w io.Writer, dcRef string, issues []executor.CompatibilityIssue) {
+	fmt.Fprintf(w, "Datacenter %s can't provision:\n", dcRef)
+	for _, issue := range issues {
+		var kind string
+		switch issue.Kind {
+		case executor.IssueNoHook:
+			kind = "no matching hook"
+		case executor.IssueErrorHook:
+			kind = "rejected by error hook"
+		case executor.IssueMissingOutput:
+			kind = "missing output"
+		default:
+			kind = string(issue.Kind)
+		}
+		fmt.Fprintf(w, "  - %s (%s): %s\n", issue.NodeID, kind, issue.Message)
+	}
+}
+
 // formatValidationError extracts and displays validation error details
 func formatValidationError(err error) error {
 	// Try to extract cldctl error with details
@@ -6,6 +6,8 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+
+	"github.com/davidthor/arcctl/pkg/engine/executor"
 )
 
 func TestNewValidateCmd(t *testing.T) {
@@ -45,6 +47,10 @@ func TestValidateComponentCmd_Flags(t *testing.T) {
 		t.Error("expected --file flag")
 	}
 
+	if cmd.Flags().Lookup("datacenter") == nil {
+		t.Error("expected --datacenter flag")
+	}
+
 	// Check aliases
 	if len(cmd.Aliases) == 0 || cmd.Aliases[0] != "comp" {
 		t.Error("expected alias 'comp'")
@@ -72,6 +78,85 @@ deployments:
 	}
 }
 
+func TestValidateComponentCmd_Datacenter(t *testing.T) {
+	componentYAML := `
+name: test-app
+
+databases:
+  main:
+    type: postgres:^16
+
+deployments:
+  api:
+    image: nginx:latest
+    environment:
+      DATABASE_URL: ${{ databases.main.url }}
+`
+	dir := createTempComponent(t, componentYAML)
+
+	compatible := createTempDatacenter(t, `
+environment {
+  database {
+    module "db" {
+      build = "./modules/db"
+    }
+    outputs = {
+      url = module.db.url
+    }
+  }
+
+  deployment {
+    module "deployment" {
+      build = "./modules/deployment"
+    }
+  }
+}
+`)
+	cmd := newValidateComponentCmd()
+	cmd.SetArgs([]string{dir, "--datacenter", compatible})
+	if err := cmd.Execute(); err != nil {
+		t.Errorf("expected no error for compatible datacenter, got: %v", err)
+	}
+
+	incompatible := createTempDatacenter(t, `
+environment {
+  database {
+    module "db" {
+      build = "./modules/db"
+    }
+    outputs = {
+      host = module.db.host
+    }
+  }
+}
+`)
+	cmd = newValidateComponentCmd()
+	cmd.SetArgs([]string{dir, "--datacenter", incompatible})
+	err := cmd.Execute()
+	if err == nil || !strings.Contains(err.Error(), "2 issue(s)") {
+		t.Errorf("expected 2 compatibility issues, got: %v", err)
+	}
+}
+
+func TestPrintCompatibilityIssues(t *testing.T) {
+	var buf bytes.Buffer
+	printCompatibilityIssues(&buf, "./dc", []executor.CompatibilityIssue{
+		{NodeID: "app/bucket/files", Kind: executor.IssueNoHook, Message: "no matching hook found for bucket"},
+		{NodeID: "app/database/cache", Kind: executor.IssueErrorHook, Message: "Redis is not supported"},
+	})
+	out := buf.String()
+
+	for _, want := range []string{
+		"Datacenter ./dc can't provision:",
+		"  - app/bucket/files (no matching hook): no matching hook found for bucket",
+		"  - app/database/cache (rejected by error hook): Redis is not supported",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected output to contain %q, got:\n%s", want, out)
+		}
+	}
+}
+
 func TestValidateComponentCmd_InvalidComponent(t *testing.T) {
 	// Create an invalid component file
 	dir := t.TempDir()
@@ -0,0 +1,316 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/davidthor/arcctl/pkg/engine"
+	"github.com/davidthor/arcctl/pkg/engine/executor"
+	"github.com/davidthor/arcctl/pkg/engine/planner"
+	"github.com/davidthor/arcctl/pkg/graph"
+	"github.com/davidthor/arcctl/pkg/schema/component"
+	"github.com/fsnotify/fsnotify"
+)
+
+// watchDebounce is how long the watcher waits for file changes to settle
+// before triggering a reload.
+const watchDebounce = 500 * time.Millisecond
+
+// sourceWatcher watches directories recursively and reports batches of
+// changed files once changes have settled.
+type sourceWatcher struct {
+	watcher  *fsnotify.Watcher
+	debounce time.Duration
+	watched  map[string]bool
+}
+
+func newSourceWatcher(debounce time.Duration) (*sourceWatcher, error) {
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create file watcher: %w", err)
+	}
+	return &sourceWatcher{
+		watcher:  w,
+		debounce: debounce,
+		watched:  make(map[string]bool),
+	}, nil
+}
+
+// AddRecursive watches dir and all of its subdirectories, skipping ignored
+// directories such as .git and node_modules.
+func (w *sourceWatcher) AddRecursive(dir string) error {
+	return filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return nil // Skip unreadable entries
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		if path != dir && ignoredWatchPath(path) {
+			return filepath.SkipDir
+		}
+		return w.Add(path)
+	})
+}
+
+// Add watches a single directory (not its subdirectories).
+func (w *sourceWatcher) Add(dir string) error {
+	if w.watched[dir] {
+		return nil
+	}
+	if err := w.watcher.Add(dir); err != nil {
+		return fmt.Errorf("failed to watch %s: %w", dir, err)
+	}
+	w.watched[dir] = true
+	return nil
+}
+
+// Run delivers batches of changed paths to onChange until ctx is cancelled.
+// onChange is called synchronously; changes made while it runs are delivered
+// in the next batch.
+func (w *sourceWatcher) Run(ctx context.Context, onChange func(paths []string)) {
+	pending := make(map[string]bool)
+	timer := time.NewTimer(w.debounce)
+	timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+
+		case event, ok := <-w.watcher.Events:
+			if !ok {
+				return
+			}
+			if ignoredWatchPath(event.Name) || event.Op == fsnotify.Chmod {
+				continue
+			}
+			// Watch directories created inside a watched tree
+			if event.Has(fsnotify.Create) {
+				if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+					_ = w.AddRecursive(event.Name)
+				}
+			}
+			pending[event.Name] = true
+			timer.Reset(w.debounce)
+
+		case err, ok := <-w.watcher.Errors:
+			if !ok {
+				return
+			}
+			fmt.Fprintf(os.Stderr, "Warning: file watcher error: %v\n", err)
+
+		case <-timer.C:
+			paths := make([]string, 0, len(pending))
+			for p := range pending {
+				paths = append(paths, p)
+			}
+			sort.Strings(paths)
+			pending = make(map[string]bool)
+			onChange(paths)
+		}
+	}
+}
+
+// Close stops the watcher.
+func (w *sourceWatcher) Close() error {
+	return w.watcher.Close()
+}
+
+// watchAndReload watches the component's source files and redeploys the
+// environment whenever they change, until ctx is cancelled. Changes to source
+// directories force an update of the nodes built from them (and their
+// dependents); changes to the component file re-plan the whole component.
+func watchAndReload(ctx context.Context, eng *engine.Engine, opts engine.DeployOptions, componentName, componentFile string) error {
+	componentFile, err := filepath.Abs(componentFile)
+	if err != nil {
+		return fmt.Errorf("failed to resolve component file: %w", err)
+	}
+
+	comp, err := component.NewLoader().Load(componentFile)
+	if err != nil {
+		return fmt.Errorf("failed to load component: %w", err)
+	}
+	targets, err := componentWatchTargets(opts.Environment, opts.Datacenter, componentName, comp)
+	if err != nil {
+		return fmt.Errorf("failed to determine watch targets: %w", err)
+	}
+
+	w, err := newSourceWatcher(watchDebounce)
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+
+	watchAll := func() error {
+		if err := w.AddRecursive(filepath.Dir(componentFile)); err != nil {
+			return err
+		}
+		for _, dir := range targets {
+			if err := w.AddRecursive(dir); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := watchAll(); err != nil {
+		return err
+	}
+
+	w.Run(ctx, func(changed []string) {
+		componentChanged := false
+		for _, p := range changed {
+			if p == componentFile {
+				componentChanged = true
+				break
+			}
+		}
+
+		if componentChanged {
+			comp, err := component.NewLoader().Load(componentFile)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "[reload] Failed to load component, waiting for further changes: %v\n", err)
+				return
+			}
+			newTargets, err := componentWatchTargets(opts.Environment, opts.Datacenter, componentName, comp)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "[reload] Invalid component, waiting for further changes: %v\n", err)
+				return
+			}
+			targets = newTargets
+			if err := watchAll(); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+			}
+		}
+
+		affected := affectedNodes(targets, changed)
+		if !componentChanged && len(affected) == 0 {
+			return
+		}
+
+		fmt.Println()
+		if componentChanged {
+			fmt.Printf("[reload] %s changed, re-planning %s...\n", filepath.Base(componentFile), componentName)
+		} else {
+			fmt.Printf("[reload] Source changed, updating %s...\n", strings.Join(affected, ", "))
+		}
+
+		reloadOpts := opts
+		reloadOpts.ForceUpdateNodes = affected
+		reloadOpts.Output = nil
+		reloadOpts.DryRun = false
+		reloadOpts.AutoApprove = true
+		// Only report nodes that actually changed; unchanged nodes also
+		// complete but are noops. Progress is reported from parallel workers.
+		var mu sync.Mutex
+		applied := make(map[string]bool)
+		reloadOpts.OnProgress = func(event executor.ProgressEvent) {
+			mu.Lock()
+			defer mu.Unlock()
+			switch event.Status {
+			case "running":
+				if event.Action != planner.ActionNoop {
+					applied[event.NodeID] = true
+				}
+			case "completed":
+				if applied[event.NodeID] {
+					fmt.Printf("[reload]   %s updated\n", event.NodeID)
+				}
+			case "failed":
+				fmt.Printf("[reload]   %s failed: %v\n", event.NodeID, event.Error)
+			}
+		}
+
+		result, err := eng.Deploy(ctx, reloadOpts)
+		switch {
+		case ctx.Err() != nil:
+			return
+		case err != nil:
+			fmt.Fprintf(os.Stderr, "[reload] Deployment failed: %v\n", err)
+		case !result.Success:
+			fmt.Fprintln(os.Stderr, "[reload] Some resources failed to update. Fix the error and save again.")
+		default:
+			fmt.Println("[reload] Done. Watching for file changes...")
+		}
+	})
+
+	return nil
+}
+
+// ignoredWatchPath returns true for paths that should never trigger a reload:
+// VCS metadata, dependency directories, hidden files, and editor temporary
+// files. Only the final path element is checked since ignored directories are
+// never watched.
+func ignoredWatchPath(path string) bool {
+	base := filepath.Base(path)
+	return base == "node_modules" ||
+		strings.HasPrefix(base, ".") ||
+		strings.HasSuffix(base, "~") ||
+		strings.HasSuffix(base, ".swp") ||
+		strings.HasSuffix(base, ".tmp")
+}
+
+// componentWatchTargets returns the source directory of each node in the
+// component whose deployed artifact is derived from local files, keyed by
+// node ID: Docker build contexts, source-based functions, and runtime-based
+// deployments (which run from their working directory).
+func componentWatchTargets(envName, dcName, componentName string, comp component.Component) (map[string]string, error) {
+	builder := graph.NewBuilder(envName, dcName)
+	if err := builder.AddComponent(componentName, comp); err != nil {
+		return nil, err
+	}
+
+	targets := make(map[string]string)
+	for _, node := range builder.Build().Nodes {
+		if node.Component != componentName {
+			continue
+		}
+
+		var dir string
+		switch node.Type {
+		case graph.NodeTypeDockerBuild:
+			dir, _ = node.Inputs["context"].(string)
+		case graph.NodeTypeFunction:
+			dir, _ = node.Inputs["srcPath"].(string)
+		case graph.NodeTypeDeployment:
+			if _, ok := node.Inputs["runtime"]; ok {
+				dir, _ = node.Inputs["workingDirectory"].(string)
+			}
+		}
+		if dir != "" {
+			targets[node.ID] = filepath.Clean(dir)
+		}
+	}
+	return targets, nil
+}
+
+// affectedNodes returns the sorted IDs of the nodes whose source directory
+// contains at least one of the changed paths.
+func affectedNodes(targets map[string]string, changed []string) []string {
+	var ids []string
+	for id, dir := range targets {
+		for _, p := range changed {
+			if isWithinDir(p, dir) {
+				ids = append(ids, id)
+				break
+			}
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// isWithinDir returns true if path is dir or is inside dir.
+func isWithinDir(path, dir string) bool {
+	rel, err := filepath.Rel(dir, path)
+	if err != nil {
+		return false
+	}
+	return rel == "." || (rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)))
+}
@@ -0,0 +1,102 @@
+package cli
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/davidthor/arcctl/pkg/schema/component"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestComponentWatchTargets(t *testing.T) {
+	dir := t.TempDir()
+	comp, err := component.NewLoader().LoadFromBytes([]byte(`
+builds:
+  api:
+    context: ./api
+deployments:
+  api:
+    image: "${{ builds.api.image }}"
+  worker:
+    runtime: node:20
+    command: ["npm", "start"]
+  cache:
+    image: redis:7
+functions:
+  web:
+    src:
+      path: ./web
+`), filepath.Join(dir, "cloud.component.yml"))
+	require.NoError(t, err)
+
+	targets, err := componentWatchTargets("dev", "local", "my-app", comp)
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]string{
+		"my-app/dockerBuild/api":   filepath.Join(dir, "api"),
+		"my-app/deployment/worker": dir,
+		"my-app/function/web":      filepath.Join(dir, "web"),
+	}, targets)
+}
+
+func TestAffectedNodes(t *testing.T) {
+	targets := map[string]string{
+		"app/dockerBuild/api": "/src/app/api",
+		"app/function/web":    "/src/app/web",
+		"app/deployment/root": "/src/app",
+	}
+
+	assert.Equal(t, []string{"app/deployment/root", "app/dockerBuild/api"},
+		affectedNodes(targets, []string{"/src/app/api/main.go"}))
+	assert.Equal(t, []string{"app/deployment/root"},
+		affectedNodes(targets, []string{"/src/app/README.md"}))
+	assert.Empty(t, affectedNodes(targets, []string{"/src/app-other/main.go", "/src/apiary"}))
+}
+
+func TestIgnoredWatchPath(t *testing.T) {
+	assert.True(t, ignoredWatchPath("/src/app/.git"))
+	assert.True(t, ignoredWatchPath("/src/app/node_modules"))
+	assert.True(t, ignoredWatchPath("/src/app/main.go~"))
+	assert.True(t, ignoredWatchPath("/src/app/.main.go.swp"))
+	assert.False(t, ignoredWatchPath("/home/user/.projects/app/main.go"))
+}
+
+func TestSourceWatcher_Debounce(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "src"), 0755))
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "node_modules"), 0755))
+
+	w, err := newSourceWatcher(100 * time.Millisecond)
+	require.NoError(t, err)
+	defer w.Close()
+	require.NoError(t, w.AddRecursive(dir))
+	assert.False(t, w.watched[filepath.Join(dir, "node_modules")])
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	batches := make(chan []string, 10)
+	go w.Run(ctx, func(paths []string) { batches <- paths })
+
+	// Several writes in quick succession should arrive as a single batch
+	for _, name := range []string{"src/a.go", "src/b.go", "node_modules/x.js"} {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte("package main"), 0644))
+	}
+
+	select {
+	case paths := <-batches:
+		assert.Equal(t, []string{filepath.Join(dir, "src", "a.go"), filepath.Join(dir, "src", "b.go")}, paths)
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for changes")
+	}
+
+	select {
+	case paths := <-batches:
+		t.Fatalf("unexpected second batch: %v", paths)
+	case <-time.After(300 * time.Millisecond):
+	}
+}
@@ -0,0 +1,34 @@
+package engine
+
+import (
+	"fmt"
+
+	"github.com/davidthor/arcctl/pkg/engine/executor"
+	"github.com/davidthor/arcctl/pkg/graph"
+)
+
+// CheckCompatibility loads the datacenter at ref (a local path or OCI
+// reference) and reports the nodes in g that it can't provision: nodes that
+// no hook matches, nodes rejected by an error hook, and references to outputs
+// that the matching hook doesn't declare. Hook conditions see the defaults of
+// the datacenter's variables.
+func (e *Engine) CheckCompatibility(ref string, g *graph.Graph) ([]executor.CompatibilityIssue, error) {
+	dc, err := e.loadDatacenterConfig(ref)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load datacenter configuration: %w", err)
+	}
+
+	dcVars := make(map[string]interface{})
+	for _, v := range dc.Variables() {
+		if v.Default() != nil {
+			dcVars[v.Name()] = v.Default()
+		}
+	}
+
+	// The executor is only used to match nodes to hooks
+	exec := executor.NewExecutor(e.stateManager, e.iacRegistry, executor.Options{
+		Datacenter:          dc,
+		DatacenterVariables: dcVars,
+	})
+	return exec.CheckCompatibility(g), nil
+}
@@ -20,6 +20,7 @@ import (
 	"github.com/davidthor/arcctl/pkg/schema/component"
 	"github.com/davidthor/arcctl/pkg/schema/datacenter"
 	"github.com/davidthor/arcctl/pkg/schema/environment"
+	"github.com/davidthor/arcctl/pkg/secrets"
 	"github.com/davidthor/arcctl/pkg/state"
 	"github.com/davidthor/arcctl/pkg/state/types"
 )
@@ -29,6 +30,7 @@ type OCIClient interface {
 	Pull(ctx context.Context, reference string, destDir string) error
 	PullConfig(ctx context.Context, reference string) ([]byte, error)
 	Exists(ctx context.Context, reference string) (bool, error)
+	Digest(ctx context.Context, reference string) (string, error)
 }
 
 // Engine orchestrates component deployments.
@@ -39,6 +41,7 @@ type Engine struct {
 	envLoader    environment.Loader
 	dcLoader     datacenter.Loader
 	ociClient    OCIClient
+	secrets      *secrets.Manager
 }
 
 // NewEngine creates a new deployment engine.
@@ -50,6 +53,7 @@ func NewEngine(stateManager state.Manager, iacRegistry *iac.Registry) *Engine {
 		envLoader:    environment.NewLoader(),
 		dcLoader:     datacenter.NewLoader(),
 		ociClient:    oci.NewClient(),
+		secrets:      secrets.NewManagerFromConfig(secrets.Config{}),
 	}
 }
 
@@ -140,14 +144,61 @@ type DeployOptions struct {
 	// ForceUpdate converts Noop actions to Update, used when datacenter config
 	// changes and all resources need re-evaluation against new hooks.
 	ForceUpdate bool
+
+	// ForceUpdateNodes lists graph node IDs (e.g., "api/dockerBuild/api") to
+	// update even if their inputs are unchanged, along with their dependents.
+	// Used by `cldctl up` to redeploy nodes whose source files changed.
+	ForceUpdateNodes []string
+
+	// Targets restricts the deploy to the resources at these addresses (e.g.,
+	// "deployment/api") and the resources they depend on. Everything else in
+	// the components is left untouched.
+	Targets []string
+
+	// TargetDependents also deploys the resources that depend on the targets.
+	TargetDependents bool
+
+	// Resume continues a deployment that failed or was interrupted, reusing
+	// the resources that are already ready and retrying the rest.
+	Resume bool
+
+	// ReadinessTimeout is how long resources are given to pass their
+	// readiness checks (default 5 minutes)
+	ReadinessTimeout time.Duration
+
+	// RollbackOnFailure restores the resources that a failed deploy already
+	// updated to the inputs and IaC state they had before it.
+	RollbackOnFailure bool
+
+	// DetailedPlan runs the IaC plugin preview for every hook module touched by
+	// the plan and attaches the infrastructure-level changes to the plan.
+	DetailedPlan bool
+
+	// Operation is recorded in the environment's state history (default "deploy")
+	Operation string
+
+	// Plan is the result of a dry run with the same options, whose plan is
+	// executed instead of planning again so that what runs is the plan that
+	// was shown. It is refused if the environment state changed since.
+	Plan *DeployResult
 }
 
 // DeployResult contains the results of a deployment.
 type DeployResult struct {
 	Success   bool
 	Plan      *planner.Plan
+	Graph     *graph.Graph
 	Execution *executor.ExecutionResult
 	Duration  time.Duration
+
+	// Rollback is the result of restoring the updated resources after the
+	// deploy failed. Only set when RollbackOnFailure was requested.
+	Rollback *executor.ExecutionResult
+
+	// stateSerial and stateLineage identify the environment state the plan
+	// was made against
+	stateSerial  int64
+	stateLineage string
 }
 
 // Deploy deploys components to an environment.
@@ -175,6 +226,99 @@ func (e *Engine) Deploy(ctx context.Context, opts DeployOptions) (*DeployResult,
 		return nil, fmt.Errorf("failed to load datacenter configuration: %w", err)
 	}
 
+	// Execute the plan that was shown, or plan the deploy
+	var plan *planner.Plan
+	var g *graph.Graph
+	if opts.Plan != nil {
+		if err := e.verifyDeployPlan(ctx, opts); err != nil {
+			return nil, err
+		}
+		plan, g = opts.Plan.Plan, opts.Plan.Graph
+		result.stateSerial, result.stateLineage = opts.Plan.stateSerial, opts.Plan.stateLineage
+	} else {
+		plan, g, err = e.planDeploy(ctx, opts, dc, dcState, result)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	result.Plan = plan
+	result.Graph = g
+
+	// The executor resolves secret references, so it is only created when
+	// the plan will actually be previewed or executed
+	var exec *executor.Executor
+	if !plan.IsEmpty() && (opts.DetailedPlan || !opts.DryRun) {
+		exec, err = e.newDeployExecutor(ctx, dc, dcState, opts)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// A plan that was shown has already been previewed and printed
+	if opts.Plan == nil {
+		// Preview infrastructure-level changes for the planned resources
+		if opts.DetailedPlan && exec != nil {
+			if err := exec.Preview(ctx, plan, g); err != nil {
+				return nil, fmt.Errorf("failed to preview plan: %w", err)
+			}
+		}
+
+		// Print plan summary
+		if opts.Output != nil {
+			e.printPlanSummary(opts.Output, plan)
+		}
+	}
+
+	// If dry run or no changes, return here
+	if opts.DryRun || plan.IsEmpty() {
+		result.Success = plan.IsEmpty() || opts.DryRun
+		result.Duration = time.Since(startTime)
+		return result, nil
+	}
+
+	// Execute plan
+	var execResult *executor.ExecutionResult
+	if opts.Parallelism > 1 {
+		execResult, err = exec.ExecuteParallel(ctx, plan, g)
+	} else {
+		execResult, err = exec.Execute(ctx, plan, g)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("execution failed: %w", err)
+	}
+
+	// An interrupted deploy is left as it is, since it was stopped on purpose
+	interrupted := ctx.Err() != nil || executor.StopRequested(ctx)
+	if !execResult.Success && opts.RollbackOnFailure && !interrupted {
+		if opts.Output != nil {
+			fmt.Fprintln(opts.Output, "\nDeploy failed; rolling back the resources it updated...")
+		}
+		rollback, err := exec.Rollback(ctx, plan, execResult)
+		if err != nil {
+			rollback = &executor.ExecutionResult{Errors: []error{err}}
+		}
+		result.Rollback = rollback
+	}
+
+	operation := opts.Operation
+	if operation == "" {
+		operation = "deploy"
+	}
+	e.recordHistory(ctx, opts.Datacenter, opts.Environment, operation, componentNames(opts.Components), opts.Output)
+
+	result.Execution = execResult
+	result.Success = execResult.Success
+	result.Duration = time.Since(startTime)
+
+	return result, nil
+}
+
+// planDeploy builds the dependency graph of the components and plans the
+// deploy against the environment's current state, which it records in
+// result.
+func (e *Engine) planDeploy(ctx context.Context, opts DeployOptions, dc datacenter.Datacenter, dcState *types.DatacenterState, result *DeployResult) (*planner.Plan, *graph.Graph, error) {
 	// Build dependency graph
 	builder := graph.NewBuilder(opts.Environment, opts.Datacenter)
 
@@ -182,121 +326,119 @@ func (e *Engine) Deploy(ctx context.Context, opts DeployOptions) (*DeployResult,
 		// Load component
 		comp, err := e.compLoader.Load(compPath)
 		if err != nil {
-			return nil, fmt.Errorf("failed to load component %s: %w", compName, err)
+			return nil, nil, fmt.Errorf("failed to load component %s: %w", compName, err)
 		}
 
 		// Add to graph - component name comes from the deployment mapping
 		if err := builder.AddComponent(compName, comp); err != nil {
-			return nil, fmt.Errorf("failed to add component %s to graph: %w", compName, err)
+			return nil, nil, fmt.Errorf("failed to add component %s to graph: %w", compName, err)
 		}
 	}
 
 	g := builder.Build()
 
+	// Enforce datacenter policies before planning; warnings go in the plan
+	dcVars := make(map[string]interface{}, len(dcState.Variables))
+	for k, v := range dcState.Variables {
+		dcVars[k] = v
+	}
+	violations := checkPolicies(dc, g, policyContext{
+		Environment: opts.Environment,
+		Datacenter:  opts.Datacenter,
+		Variables:   dcVars,
+	})
+	if err := policyViolationError(violations); err != nil {
+		return nil, nil, err
+	}
+
 	// Get current state
 	currentState, _ := e.stateManager.GetEnvironment(ctx, opts.Datacenter, opts.Environment)
+	if currentState != nil {
+		result.stateSerial, result.stateLineage = currentState.Serial, currentState.Lineage
+	}
 
 	// Create plan
 	planOpts := planner.PlanOptions{
-		ForceUpdate: opts.ForceUpdate,
+		ForceUpdate:      opts.ForceUpdate,
+		ForceUpdateNodes: opts.ForceUpdateNodes,
+		Targets:          opts.Targets,
+		TargetDependents: opts.TargetDependents,
+		Resume:           opts.Resume,
 	}
 	p := planner.NewPlannerWithOptions(planOpts)
 	plan, err := p.Plan(g, currentState)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create plan: %w", err)
+		return nil, nil, fmt.Errorf("failed to create plan: %w", err)
 	}
+	plan.PolicyWarnings = policyWarnings(violations)
 
-	result.Plan = plan
+	return plan, g, nil
+}
 
-	// Print plan summary
-	if opts.Output != nil {
-		e.printPlanSummary(opts.Output, plan)
+// verifyDeployPlan checks that the environment state hasn't changed since
+// the plan in opts was made.
+func (e *Engine) verifyDeployPlan(ctx context.Context, opts DeployOptions) error {
+	envState, err := e.currentEnvironmentState(ctx, opts.Datacenter, opts.Environment)
+	if err != nil {
+		return err
 	}
-
-	// If dry run or no changes, return here
-	if opts.DryRun || plan.IsEmpty() {
-		result.Success = plan.IsEmpty() || opts.DryRun
-		result.Duration = time.Since(startTime)
-		return result, nil
+	var serial int64
+	var lineage string
+	if envState != nil {
+		serial, lineage = envState.Serial, envState.Lineage
+	}
+	if serial != opts.Plan.stateSerial || lineage != opts.Plan.stateLineage {
+		return fmt.Errorf("environment %q state has changed since the plan was shown (serial %d, planned against serial %d); run the command again",
+			opts.Environment, serial, opts.Plan.stateSerial)
 	}
+	return nil
+}
 
+// newDeployExecutor creates the executor for a component deployment. Secret
+// references in datacenter and component variables are resolved here; the
+// unresolved component variables are what gets recorded in state.
+func (e *Engine) newDeployExecutor(ctx context.Context, dc datacenter.Datacenter, dcState *types.DatacenterState, opts DeployOptions) (*executor.Executor, error) {
 	// Build datacenter variables map
 	dcVars := make(map[string]interface{})
 	for k, v := range dcState.Variables {
 		dcVars[k] = v
 	}
-
-	// Execute plan
-	execOpts := executor.Options{
-		Parallelism:         opts.Parallelism,
-		Output:              opts.Output,
-		DryRun:              false,
-		StopOnError:         true,
-		OnProgress:          opts.OnProgress,
-		Datacenter:          dc,
-		DatacenterVariables: dcVars,
-		ComponentSources:    opts.Components,
-		ComponentVariables:  opts.Variables,
-	}
-
-	exec := executor.NewExecutor(e.stateManager, e.iacRegistry, execOpts)
-
-	var execResult *executor.ExecutionResult
-	if opts.Parallelism > 1 {
-		execResult, err = exec.ExecuteParallel(ctx, plan, g)
-	} else {
-		execResult, err = exec.Execute(ctx, plan, g)
+	dcVars, err := e.resolveSecretVariables(ctx, dcVars)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve datacenter variables: %w", err)
 	}
 
+	compVars, err := e.resolveComponentSecretVariables(ctx, opts.Variables)
 	if err != nil {
-		return nil, fmt.Errorf("execution failed: %w", err)
+		return nil, fmt.Errorf("failed to resolve component variables: %w", err)
 	}
 
-	result.Execution = execResult
-	result.Success = execResult.Success
-	result.Duration = time.Since(startTime)
-
-	return result, nil
+	execOpts := executor.Options{
+		Parallelism:                 opts.Parallelism,
+		Output:                      opts.Output,
+		DryRun:                      false,
+		StopOnError:                 true,
+		OnProgress:                  opts.OnProgress,
+		Datacenter:                  dc,
+		DatacenterVariables:         dcVars,
+		ComponentSources:            opts.Components,
+		ComponentVariables:          compVars,
+		ComponentVariableSources:    opts.Variables,
+		SensitiveComponentVariables: e.sensitiveComponentVariables(opts.Components),
+		ReadinessTimeout:            opts.ReadinessTimeout,
+	}
+
+	return executor.NewExecutor(e.stateManager, e.iacRegistry, execOpts), nil
 }
 
 // loadDatacenterConfig loads a datacenter configuration from a path or OCI reference.
 // Resolution order: local filesystem path → unified artifact registry → remote OCI pull.
 func (e *Engine) loadDatacenterConfig(ref string) (datacenter.Datacenter, error) {
-	// Check if this is a local filesystem path
-	isLocalPath := strings.HasPrefix(ref, "./") || strings.HasPrefix(ref, "/") || strings.HasPrefix(ref, "../")
-	if !isLocalPath {
-		// Also treat paths without ":" as local (but not bare names like "mydc:latest")
-		if !strings.Contains(ref, ":") {
-			if _, err := os.Stat(ref); err == nil {
-				isLocalPath = true
-			}
-		}
+	dcFile, err := localDatacenterFile(ref)
+	if err != nil {
+		return nil, err
 	}
-
-	if isLocalPath {
-		// Resolve to absolute path
-		absPath := ref
-		if !filepath.IsAbs(ref) {
-			cwd, err := os.Getwd()
-			if err != nil {
-				return nil, fmt.Errorf("failed to get working directory: %w", err)
-			}
-			absPath = filepath.Join(cwd, ref)
-		}
-
-		info, err := os.Stat(absPath)
-		if err != nil {
-			return nil, fmt.Errorf("failed to access datacenter path: %w", err)
-		}
-
-		dcFile := absPath
-		if info.IsDir() {
-			dcFile = filepath.Join(absPath, "datacenter.dc")
-			if _, err := os.Stat(dcFile); os.IsNotExist(err) {
-				dcFile = filepath.Join(absPath, "datacenter.hcl")
-			}
-		}
-
+	if dcFile != "" {
 		return e.dcLoader.Load(dcFile)
 	}
 
@@ -318,6 +460,51 @@ func (e *Engine) loadDatacenterConfig(ref string) (datacenter.Datacenter, error)
 	return e.loadDatacenterFromOCI(context.Background(), ref)
 }
 
+// localDatacenterFile resolves a datacenter reference to a datacenter file on
+// the local filesystem. It returns an empty path if the reference is not a
+// local path (e.g., an OCI reference).
+func localDatacenterFile(ref string) (string, error) {
+	// Check if this is a local filesystem path
+	isLocalPath := strings.HasPrefix(ref, "./") || strings.HasPrefix(ref, "/") || strings.HasPrefix(ref, "../")
+	if !isLocalPath {
+		// Also treat paths without ":" as local (but not bare names like "mydc:latest")
+		if !strings.Contains(ref, ":") {
+			if _, err := os.Stat(ref); err == nil {
+				isLocalPath = true
+			}
+		}
+	}
+
+	if !isLocalPath {
+		return "", nil
+	}
+
+	// Resolve to absolute path
+	absPath := ref
+	if !filepath.IsAbs(ref) {
+		cwd, err := os.Getwd()
+		if err != nil {
+			return "", fmt.Errorf("failed to get working directory: %w", err)
+		}
+		absPath = filepath.Join(cwd, ref)
+	}
+
+	info, err := os.Stat(absPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to access datacenter path: %w", err)
+	}
+
+	dcFile := absPath
+	if info.IsDir() {
+		dcFile = filepath.Join(absPath, "datacenter.dc")
+		if _, err := os.Stat(dcFile); os.IsNotExist(err) {
+			dcFile = filepath.Join(absPath, "datacenter.hcl")
+		}
+	}
+
+	return dcFile, nil
+}
+
 // loadDatacenterFromOCI pulls a datacenter artifact from a remote OCI registry,
 // caches it locally, registers it in the unified artifact registry, and loads it.
 func (e *Engine) loadDatacenterFromOCI(ctx context.Context, ref string) (datacenter.Datacenter, error) {
@@ -527,6 +714,31 @@ type DestroyComponentOptions struct {
 	Force bool
 }
 
+// UnfinishedResources returns the resources of the given components that a
+// failed or interrupted deployment left failed, pending, or provisioning,
+// sorted by component and resource key.
+func UnfinishedResources(envState *types.EnvironmentState, components ...string) []*types.ResourceState {
+	var unfinished []*types.ResourceState
+	for _, compName := range components {
+		compState := envState.Components[compName]
+		if compState == nil {
+			continue
+		}
+		keys := make([]string, 0, len(compState.Resources))
+		for key := range compState.Resources {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			switch res := compState.Resources[key]; res.Status {
+			case types.ResourceStatusFailed, types.ResourceStatusPending, types.ResourceStatusProvisioning:
+				unfinished = append(unfinished, res)
+			}
+		}
+	}
+	return unfinished
+}
+
 // FindDependents returns the names of components in the environment that depend
 // on the given component. This is used to prevent destroying a component that
 // other components rely on.
@@ -635,6 +847,8 @@ func (e *Engine) DestroyComponent(ctx context.Context, opts DestroyComponentOpti
 		}
 	}
 
+	e.recordHistory(ctx, opts.Datacenter, opts.Environment, "destroy", []string{opts.Component}, opts.Output)
+
 	return result, nil
 }
 
@@ -679,6 +893,8 @@ func (e *Engine) printPlanSummary(w io.Writer, plan *planner.Plan) {
 	fmt.Fprintf(w, "  Datacenter:  %s\n", plan.Datacenter)
 	fmt.Fprintf(w, "\n")
 
+	printPolicyWarnings(w, plan.PolicyWarnings)
+
 	if plan.IsEmpty() {
 		fmt.Fprintf(w, "No changes required.\n")
 		return
@@ -708,10 +924,78 @@ func (e *Engine) printPlanSummary(w io.Writer, plan *planner.Plan) {
 		}
 
 		fmt.Fprintf(w, "  %s %s\n", actionSymbol, nodeID)
+		printIaCChanges(w, change)
 	}
 
 	fmt.Fprintf(w, "\nSummary: %d to create, %d to update, %d to delete, %d unchanged\n",
 		plan.ToCreate, plan.ToUpdate, plan.ToDelete, plan.NoChange)
+
+	printTargetWarning(w, plan.Targets)
+}
+
+// printPolicyWarnings lists the datacenter policies a plan's resources
+// violate with warn severity.
+func printPolicyWarnings(w io.Writer, warnings []planner.PolicyViolation) {
+	if len(warnings) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "Policy warnings:\n")
+	for _, v := range warnings {
+		fmt.Fprintf(w, "  ! %s\n", formatPolicyViolation(v))
+	}
+	fmt.Fprintf(w, "\n")
+}
+
+// printTargetWarning warns that a plan restricted with targets leaves the
+// rest of the environment as it is.
+func printTargetWarning(w io.Writer, targets []string) {
+	if len(targets) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "\nWarning: this plan is restricted to the targets %s.\n", strings.Join(targets, ", "))
+	fmt.Fprintf(w, "Resources outside the targets and their dependencies are not updated, so the\n")
+	fmt.Fprintf(w, "environment will be partially applied until it is deployed without --target.\n")
+}
+
+// printIaCChanges prints the infrastructure-level changes attached to a
+// resource change by a detailed plan.
+func printIaCChanges(w io.Writer, change *planner.ResourceChange) {
+	if change.PreviewError != nil {
+		fmt.Fprintf(w, "      (preview unavailable: %v)\n", change.PreviewError)
+		return
+	}
+
+	for _, ic := range change.IaCChanges {
+		if ic.Action == iac.ActionNoop {
+			continue
+		}
+
+		symbol := "?"
+		switch ic.Action {
+		case iac.ActionCreate:
+			symbol = "+"
+		case iac.ActionUpdate:
+			symbol = "~"
+		case iac.ActionDelete:
+			symbol = "-"
+		case iac.ActionReplace:
+			symbol = "±"
+		}
+
+		suffix := ""
+		if ic.Action == iac.ActionReplace {
+			suffix = " (must be replaced)"
+		}
+		fmt.Fprintf(w, "      %s %s%s\n", symbol, ic.ResourceID, suffix)
+
+		for _, diff := range ic.Diff {
+			if diff.Sensitive {
+				fmt.Fprintf(w, "          %s: (sensitive)\n", diff.Path)
+				continue
+			}
+			fmt.Fprintf(w, "          %s: %v -> %v\n", diff.Path, diff.OldValue, diff.NewValue)
+		}
+	}
 }
 
 func (e *Engine) printDestroyPlanSummary(w io.Writer, plan *planner.Plan) {
@@ -771,6 +1055,20 @@ func (e *Engine) DeployDatacenter(ctx context.Context, opts DeployDatacenterOpti
 			dcVars[v.Name()] = v.Default()
 		}
 	}
+	// Resolve secret references for the modules; the state keeps the
+	// references themselves
+	dcVars, err = e.resolveSecretVariables(ctx, dcVars)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve datacenter variables: %w", err)
+	}
+
+	// Record which variables are sensitive so they are protected at rest
+	dcState.SensitiveVariables = SensitiveDatacenterVariables(dc)
+	if len(dcState.SensitiveVariables) > 0 {
+		if err := e.stateManager.SaveDatacenter(ctx, dcState); err != nil {
+			return nil, fmt.Errorf("failed to save datacenter state: %w", err)
+		}
+	}
 
 	// Persist datacenter-level component declarations as individual state files.
 	// These are stored (not deployed) so the engine can reference them during
@@ -842,6 +1140,7 @@ func (e *Engine) DeployDatacenter(ctx context.Context, opts DeployDatacenterOpti
 			for inputName, exprStr := range mod.Inputs() {
 				inputs[inputName] = evaluateModuleExpression(exprStr, dcVars, nil, nil)
 			}
+			sensitiveInputs := sensitiveModuleInputs(dc, dcState.Variables, nil, mod.Inputs())
 
 			// Get IaC plugin
 			pluginName := mod.Plugin()
@@ -868,12 +1167,13 @@ func (e *Engine) DeployDatacenter(ctx context.Context, opts DeployDatacenterOpti
 
 			// Mark as applying
 			dcState.Modules[modName] = &types.ModuleState{
-				Name:      modName,
-				Plugin:    pluginName,
-				Source:    modulePath,
-				Inputs:    inputs,
-				Status:    types.ModuleStatusApplying,
-				UpdatedAt: time.Now(),
+				Name:            modName,
+				Plugin:          pluginName,
+				Source:          modulePath,
+				Inputs:          inputs,
+				SensitiveInputs: sensitiveInputs,
+				Status:          types.ModuleStatusApplying,
+				UpdatedAt:       time.Now(),
 			}
 			dcState.UpdatedAt = time.Now()
 			_ = e.stateManager.SaveDatacenter(ctx, dcState)
@@ -909,14 +1209,16 @@ func (e *Engine) DeployDatacenter(ctx context.Context, opts DeployDatacenterOpti
 			result.ModuleOutputs[modName] = outputs
 
 			dcState.Modules[modName] = &types.ModuleState{
-				Name:      modName,
-				Plugin:    pluginName,
-				Source:    modulePath,
-				Inputs:    inputs,
-				Outputs:   outputs,
-				IaCState:  applyResult.State,
-				Status:    types.ModuleStatusReady,
-				UpdatedAt: time.Now(),
+				Name:             modName,
+				Plugin:           pluginName,
+				Source:           modulePath,
+				Inputs:           inputs,
+				SensitiveInputs:  sensitiveInputs,
+				Outputs:          outputs,
+				SensitiveOutputs: applyResult.SensitiveOutputs(),
+				IaCState:         applyResult.State,
+				Status:           types.ModuleStatusReady,
+				UpdatedAt:        time.Now(),
 			}
 			dcState.UpdatedAt = time.Now()
 			_ = e.stateManager.SaveDatacenter(ctx, dcState)
@@ -1070,6 +1372,10 @@ func (e *Engine) DeployEnvironment(ctx context.Context, opts DeployEnvironmentOp
 			dcVars[v.Name()] = v.Default()
 		}
 	}
+	dcVars, err = e.resolveSecretVariables(ctx, dcVars)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve datacenter variables: %w", err)
+	}
 
 	// Collect root module outputs for cross-module references
 	rootOutputs := make(map[string]map[string]interface{})
@@ -1126,6 +1432,7 @@ func (e *Engine) DeployEnvironment(ctx context.Context, opts DeployEnvironmentOp
 				"environment.name": opts.Environment,
 			})
 		}
+		sensitiveInputs := sensitiveModuleInputs(dc, dcState.Variables, dcState.Modules, mod.Inputs())
 
 		// Get IaC plugin
 		pluginName := mod.Plugin()
@@ -1145,15 +1452,20 @@ func (e *Engine) DeployEnvironment(ctx context.Context, opts DeployEnvironmentOp
 
 		// Mark as applying
 		envState.Modules[modName] = &types.ModuleState{
-			Name:      modName,
-			Plugin:    pluginName,
-			Source:    modulePath,
-			Inputs:    inputs,
-			Status:    types.ModuleStatusApplying,
-			UpdatedAt: time.Now(),
+			Name:            modName,
+			Plugin:          pluginName,
+			Source:          modulePath,
+			Inputs:          inputs,
+			SensitiveInputs: sensitiveInputs,
+			Status:          types.ModuleStatusApplying,
+			UpdatedAt:       time.Now(),
 		}
 		envState.UpdatedAt = time.Now()
-		_ = e.stateManager.SaveEnvironment(ctx, opts.Datacenter, envState)
+		if err := e.stateManager.SaveEnvironment(ctx, opts.Datacenter, envState); err != nil {
+			result.Success = false
+			result.Duration = time.Since(startTime)
+			return result, fmt.Errorf("failed to save state before applying environment module %s: %w", modName, err)
+		}
 
 		// Apply
 		applyResult, err := plugin.Apply(ctx, runOpts)
@@ -1161,7 +1473,7 @@ func (e *Engine) DeployEnvironment(ctx context.Context, opts DeployEnvironmentOp
 			envState.Modules[modName].Status = types.ModuleStatusFailed
 			envState.Modules[modName].StatusReason = err.Error()
 			envState.UpdatedAt = time.Now()
-			_ = e.stateManager.SaveEnvironment(ctx, opts.Datacenter, envState)
+			saveErr := e.stateManager.SaveEnvironment(ctx, opts.Datacenter, envState)
 
 			if opts.OnProgress != nil {
 				opts.OnProgress(executor.ProgressEvent{
@@ -1175,6 +1487,9 @@ func (e *Engine) DeployEnvironment(ctx context.Context, opts DeployEnvironmentOp
 
 			result.Success = false
 			result.Duration = time.Since(startTime)
+			if saveErr != nil {
+				return result, fmt.Errorf("failed to apply environment module %s: %w (failed to save state: %v)", modName, err, saveErr)
+			}
 			return result, fmt.Errorf("failed to apply environment module %s: %w", modName, err)
 		}
 
@@ -1186,17 +1501,23 @@ func (e *Engine) DeployEnvironment(ctx context.Context, opts DeployEnvironmentOp
 		result.ModuleOutputs[modName] = outputs
 
 		envState.Modules[modName] = &types.ModuleState{
-			Name:      modName,
-			Plugin:    pluginName,
-			Source:    modulePath,
-			Inputs:    inputs,
-			Outputs:   outputs,
-			IaCState:  applyResult.State,
-			Status:    types.ModuleStatusReady,
-			UpdatedAt: time.Now(),
+			Name:             modName,
+			Plugin:           pluginName,
+			Source:           modulePath,
+			Inputs:           inputs,
+			SensitiveInputs:  sensitiveInputs,
+			Outputs:          outputs,
+			SensitiveOutputs: applyResult.SensitiveOutputs(),
+			IaCState:         applyResult.State,
+			Status:           types.ModuleStatusReady,
+			UpdatedAt:        time.Now(),
 		}
 		envState.UpdatedAt = time.Now()
-		_ = e.stateManager.SaveEnvironment(ctx, opts.Datacenter, envState)
+		if err := e.stateManager.SaveEnvironment(ctx, opts.Datacenter, envState); err != nil {
+			result.Success = false
+			result.Duration = time.Since(startTime)
+			return result, fmt.Errorf("environment module %s was applied, but its state wasn't recorded: %w", modName, err)
+		}
 
 		if opts.OnProgress != nil {
 			opts.OnProgress(executor.ProgressEvent{
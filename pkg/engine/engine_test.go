@@ -3,9 +3,12 @@ package engine
 import (
 	"bytes"
 	"context"
+	"encoding/base64"
+	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -20,6 +23,8 @@ import (
 // mockStateManager implements state.Manager for testing
 type mockStateManager struct {
 	environments map[string]*types.EnvironmentState
+	datacenters  map[string]*types.DatacenterState
+	snapshots    map[string][]*types.EnvironmentSnapshot
 	saveErr      error
 	getErr       error
 }
@@ -49,6 +54,11 @@ func (m *mockStateManager) SaveEnvironment(ctx context.Context, datacenter strin
 	if m.saveErr != nil {
 		return m.saveErr
 	}
+	// Saves advance the serial, as the state manager's do
+	s.Serial++
+	if s.Lineage == "" {
+		s.Lineage = "test-lineage"
+	}
 	key := datacenter + "/" + s.Name
 	m.environments[key] = s
 	// Also store by name for simpler test lookups
@@ -73,7 +83,40 @@ func (m *mockStateManager) DeleteEnvironment(ctx context.Context, datacenter, na
 	return nil
 }
 
+func (m *mockStateManager) SnapshotEnvironment(ctx context.Context, datacenter, name string, info types.EnvironmentSnapshotInfo) (*types.EnvironmentSnapshotInfo, error) {
+	env, err := m.GetEnvironment(ctx, datacenter, name)
+	if err != nil {
+		return nil, err
+	}
+	if m.snapshots == nil {
+		m.snapshots = make(map[string][]*types.EnvironmentSnapshot)
+	}
+	key := datacenter + "/" + name
+	info.Version = len(m.snapshots[key]) + 1
+	m.snapshots[key] = append(m.snapshots[key], &types.EnvironmentSnapshot{EnvironmentSnapshotInfo: info, State: env})
+	return &info, nil
+}
+
+func (m *mockStateManager) ListEnvironmentHistory(ctx context.Context, datacenter, name string) ([]types.EnvironmentSnapshotInfo, error) {
+	var history []types.EnvironmentSnapshotInfo
+	for _, snapshot := range m.snapshots[datacenter+"/"+name] {
+		history = append(history, snapshot.EnvironmentSnapshotInfo)
+	}
+	return history, nil
+}
+
+func (m *mockStateManager) GetEnvironmentSnapshot(ctx context.Context, datacenter, name string, version int) (*types.EnvironmentSnapshot, error) {
+	snapshots := m.snapshots[datacenter+"/"+name]
+	if version < 1 || version > len(snapshots) {
+		return nil, state.ErrSnapshotNotFound
+	}
+	return snapshots[version-1], nil
+}
+
 func (m *mockStateManager) GetDatacenter(ctx context.Context, name string) (*types.DatacenterState, error) {
+	if dc, ok := m.datacenters[name]; ok {
+		return dc, nil
+	}
 	return nil, nil
 }
 
@@ -133,6 +176,43 @@ func (m *mockStateManager) Lock(ctx context.Context, scope state.LockScope) (bac
 	return nil, nil
 }
 
+func (m *mockStateManager) ListLocks(ctx context.Context, datacenter string) ([]backend.LockInfo, error) {
+	return nil, nil
+}
+
+func (m *mockStateManager) ListEnvironmentLocks(ctx context.Context, datacenter, environment string) ([]backend.LockInfo, error) {
+	return nil, nil
+}
+
+func (m *mockStateManager) ForceUnlock(ctx context.Context, lockID string) (*backend.LockInfo, error) {
+	return nil, nil
+}
+
+// sealedPrefix marks the values sealed by mockStateManager.
+const sealedPrefix = "sealed:"
+
+func (m *mockStateManager) SealSensitiveValue(ctx context.Context, v interface{}) (interface{}, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return sealedPrefix + base64.StdEncoding.EncodeToString(data), nil
+}
+
+func (m *mockStateManager) OpenSensitiveValue(ctx context.Context, v interface{}) (interface{}, error) {
+	s, ok := v.(string)
+	if !ok || !strings.HasPrefix(s, sealedPrefix) {
+		return v, nil
+	}
+	data, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(s, sealedPrefix))
+	if err != nil {
+		return nil, err
+	}
+	var value interface{}
+	err = json.Unmarshal(data, &value)
+	return value, err
+}
+
 func (m *mockStateManager) Backend() backend.Backend {
 	return nil
 }
@@ -380,6 +460,7 @@ type mockOCIClient struct {
 	pullFn       func(ctx context.Context, reference string, destDir string) error
 	pullConfigFn func(ctx context.Context, reference string) ([]byte, error)
 	existsFn     func(ctx context.Context, reference string) (bool, error)
+	digestFn     func(ctx context.Context, reference string) (string, error)
 }
 
 func (m *mockOCIClient) Pull(ctx context.Context, reference string, destDir string) error {
@@ -403,6 +484,13 @@ func (m *mockOCIClient) Exists(ctx context.Context, reference string) (bool, err
 	return true, nil
 }
 
+func (m *mockOCIClient) Digest(ctx context.Context, reference string) (string, error) {
+	if m.digestFn != nil {
+		return m.digestFn(ctx, reference)
+	}
+	return "sha256:test", nil
+}
+
 // minimalDatacenterHCL is a minimal valid datacenter configuration for testing.
 const minimalDatacenterHCL = `
 environment {
@@ -699,6 +787,41 @@ func TestFindDependents_NoDependents(t *testing.T) {
 	}
 }
 
+func TestUnfinishedResources(t *testing.T) {
+	envState := &types.EnvironmentState{
+		Name: "staging",
+		Components: map[string]*types.ComponentState{
+			"api": {
+				Name: "api",
+				Resources: map[string]*types.ResourceState{
+					"database.main":   {Name: "main", Status: types.ResourceStatusReady},
+					"deployment.api":  {Name: "api", Status: types.ResourceStatusFailed, Attempt: 1},
+					"route.public":    {Name: "public", Status: types.ResourceStatusFailed},
+					"service.api":     {Name: "api", Status: types.ResourceStatusProvisioning, Attempt: 2},
+					"bucket.uploads":  {Name: "uploads", Status: types.ResourceStatusPending},
+					"function.resize": {Name: "resize", Status: types.ResourceStatusDeleted},
+				},
+			},
+			"web": {
+				Name: "web",
+				Resources: map[string]*types.ResourceState{
+					"deployment.web": {Name: "web", Status: types.ResourceStatusFailed},
+				},
+			},
+		},
+	}
+
+	unfinished := UnfinishedResources(envState, "api", "missing")
+	var names []string
+	for _, res := range unfinished {
+		names = append(names, res.Name)
+	}
+	expected := "uploads,api,public,api"
+	if strings.Join(names, ",") != expected {
+		t.Errorf("expected %s, got %v", expected, names)
+	}
+}
+
 func TestFindDependents_EmptyEnvironment(t *testing.T) {
 	envState := &types.EnvironmentState{
 		Name:       "staging",
@@ -815,3 +938,76 @@ func TestDestroyComponent_ForceOverridesDependents(t *testing.T) {
 		t.Error("expected success for forced dry run")
 	}
 }
+
+func TestDeploy_PlansOnlyDeployedComponents(t *testing.T) {
+	eng, sm, opts := newPlanTestEngine(t)
+
+	// The environment already holds the deployed component's database and
+	// another component, which deploying the first must not remove
+	sm.environments["test-dc/staging"] = &types.EnvironmentState{
+		Name:       "staging",
+		Datacenter: "test-dc",
+		Components: map[string]*types.ComponentState{
+			"api": {Name: "api", Resources: map[string]*types.ResourceState{
+				"database.main": {Name: "main", Type: "database", Component: "api", Status: types.ResourceStatusReady},
+			}},
+			"web": {Name: "web", Resources: map[string]*types.ResourceState{
+				"deployment.web": {Name: "web", Type: "deployment", Component: "web", Status: types.ResourceStatusReady},
+			}},
+		},
+	}
+
+	result, err := eng.Deploy(context.Background(), opts)
+	if err != nil {
+		t.Fatalf("Deploy (dry run) failed: %v", err)
+	}
+	if result.Plan.ToDelete != 0 {
+		t.Errorf("expected no deletions, got %d", result.Plan.ToDelete)
+	}
+	for _, c := range result.Plan.Changes {
+		if c.Node.Component != "api" {
+			t.Errorf("unexpected change to %s", c.Node.ID)
+		}
+		if c.Node.Name == "main" && (c.Action == planner.ActionCreate || c.CurrentState == nil) {
+			t.Errorf("expected the recorded database to be matched, got %s", c.Action)
+		}
+	}
+}
+
+func TestDeploy_ExecutesShownPlan(t *testing.T) {
+	eng, sm, opts := newPlanTestEngine(t)
+
+	shown, err := eng.Deploy(context.Background(), opts)
+	if err != nil {
+		t.Fatalf("Deploy (dry run) failed: %v", err)
+	}
+
+	// A component change after the plan was shown isn't picked up
+	componentYAML := planTestComponentYAML + `
+  cache:
+    type: postgres:16
+`
+	if err := os.WriteFile(opts.Components["api"], []byte(componentYAML), 0644); err != nil {
+		t.Fatalf("failed to write component: %v", err)
+	}
+
+	opts.DryRun = false
+	opts.Plan = shown
+	result, err := eng.Deploy(context.Background(), opts)
+	if err != nil {
+		t.Fatalf("Deploy failed: %v", err)
+	}
+	if !result.Success || result.Plan != shown.Plan {
+		t.Fatalf("expected the shown plan to be executed, got %+v", result)
+	}
+	resources := sm.environments["test-dc/staging"].Components["api"].Resources
+	if len(resources) != 1 || resources["database.main"] == nil {
+		t.Errorf("expected only database.main to be deployed, got %v", resources)
+	}
+
+	// The environment's state has changed since, so the plan is refused
+	_, err = eng.Deploy(context.Background(), opts)
+	if err == nil || !strings.Contains(err.Error(), "state has changed since the plan was shown") {
+		t.Errorf("expected stale plan error, got %v", err)
+	}
+}
@@ -0,0 +1,165 @@
+package executor
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	arcerrors "github.com/davidthor/arcctl/pkg/errors"
+	"github.com/davidthor/arcctl/pkg/graph"
+	"github.com/davidthor/arcctl/pkg/schema/datacenter"
+)
+
+// CompatibilityIssueKind identifies why a datacenter can't provision a resource.
+type CompatibilityIssueKind string
+
+const (
+	// IssueNoHook means no hook matches the resource
+	IssueNoHook CompatibilityIssueKind = "no_hook"
+
+	// IssueErrorHook means the resource matches a hook that rejects it
+	IssueErrorHook CompatibilityIssueKind = "error_hook"
+
+	// IssueMissingOutput means the resource references an output that the
+	// hook provisioning the referenced resource doesn't declare
+	IssueMissingOutput CompatibilityIssueKind = "missing_output"
+)
+
+// CompatibilityIssue describes a resource that a datacenter can't provision
+// as configured.
+type CompatibilityIssue struct {
+	NodeID  string
+	Kind    CompatibilityIssueKind
+	Message string
+}
+
+// referencePattern matches ${{ }} component expressions.
+var referencePattern = regexp.MustCompile(`\$\{\{\s*([^}]+?)\s*\}\}`)
+
+// referenceNodeTypes maps the first segment of a component expression to the
+// type of the node it references.
+var referenceNodeTypes = map[string]graph.NodeType{
+	"builds":         graph.NodeTypeDockerBuild,
+	"databases":      graph.NodeTypeDatabase,
+	"buckets":        graph.NodeTypeBucket,
+	"services":       graph.NodeTypeService,
+	"routes":         graph.NodeTypeRoute,
+	"functions":      graph.NodeTypeFunction,
+	"encryptionKeys": graph.NodeTypeEncryptionKey,
+	"smtp":           graph.NodeTypeSMTP,
+}
+
+// CheckCompatibility matches every node in g to the datacenter's hooks
+// without applying anything, and reports nodes that no hook matches, nodes
+// rejected by an error hook, and references to outputs that the hook
+// provisioning the referenced node doesn't declare. Issues are sorted by
+// node ID.
+func (e *Executor) CheckCompatibility(g *graph.Graph) []CompatibilityIssue {
+	var issues []CompatibilityIssue
+
+	hooks := make(map[string]datacenter.Hook)
+	for id, node := range g.Nodes {
+		hook, err := e.matchHook(node)
+		switch {
+		case err != nil && arcerrors.Is(err, arcerrors.ErrCodeDatacenterHook):
+			issues = append(issues, CompatibilityIssue{NodeID: id, Kind: IssueErrorHook, Message: err.(*arcerrors.Error).Message})
+		case err != nil:
+			issues = append(issues, CompatibilityIssue{NodeID: id, Kind: IssueNoHook, Message: err.Error()})
+		case len(hook.Modules()) == 0:
+			issues = append(issues, CompatibilityIssue{NodeID: id, Kind: IssueNoHook, Message: fmt.Sprintf("hook has no modules defined for %s", node.Type)})
+		default:
+			hooks[id] = hook
+		}
+	}
+
+	for id, node := range g.Nodes {
+		for _, ref := range componentReferences(node.Inputs) {
+			targetID, output := referencedOutput(node.Component, ref)
+			if targetID == "" || g.GetNode(targetID) == nil {
+				continue
+			}
+			// Targets without a usable hook are already reported
+			hook, ok := hooks[targetID]
+			if !ok {
+				continue
+			}
+			if _, ok := hook.Outputs()[output]; !ok {
+				issues = append(issues, CompatibilityIssue{
+					NodeID:  id,
+					Kind:    IssueMissingOutput,
+					Message: fmt.Sprintf("references ${{ %s }}, but the hook for %s doesn't output %q", ref, targetID, output),
+				})
+			}
+		}
+	}
+
+	sort.SliceStable(issues, func(i, j int) bool {
+		if issues[i].NodeID != issues[j].NodeID {
+			return issues[i].NodeID < issues[j].NodeID
+		}
+		return issues[i].Message < issues[j].Message
+	})
+	return issues
+}
+
+// referencedOutput returns the ID of the node a component expression refers
+// to and the name of the output it reads, or an empty ID if the expression
+// doesn't read a resource output.
+func referencedOutput(component, ref string) (nodeID, output string) {
+	// Pipe functions don't change which output is read
+	if idx := strings.Index(ref, "|"); idx != -1 {
+		ref = strings.TrimSpace(ref[:idx])
+	}
+	parts := strings.Split(ref, ".")
+
+	if parts[0] == "observability" && len(parts) >= 2 {
+		// observability is a singleton per component
+		return fmt.Sprintf("%s/%s/%s", component, graph.NodeTypeObservability, "observability"), parts[1]
+	}
+
+	nodeType, ok := referenceNodeTypes[parts[0]]
+	if !ok || len(parts) < 3 {
+		return "", ""
+	}
+	return fmt.Sprintf("%s/%s/%s", component, nodeType, parts[1]), parts[2]
+}
+
+// componentReferences returns the unique ${{ }} expressions in a node input
+// value, in sorted order.
+func componentReferences(value interface{}) []string {
+	seen := make(map[string]bool)
+	var walk func(v interface{})
+	walk = func(v interface{}) {
+		switch val := v.(type) {
+		case string:
+			for _, m := range referencePattern.FindAllStringSubmatch(val, -1) {
+				seen[m[1]] = true
+			}
+		case []string:
+			for _, item := range val {
+				walk(item)
+			}
+		case []interface{}:
+			for _, item := range val {
+				walk(item)
+			}
+		case map[string]string:
+			for _, item := range val {
+				walk(item)
+			}
+		case map[string]interface{}:
+			for _, item := range val {
+				walk(item)
+			}
+		}
+	}
+	walk(value)
+
+	refs := make([]string, 0, len(seen))
+	for ref := range seen {
+		refs = append(refs, ref)
+	}
+	sort.Strings(refs)
+	return refs
+}
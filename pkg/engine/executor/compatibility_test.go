@@ -0,0 +1,102 @@
+package executor
+
+import (
+	"testing"
+
+	"github.com/davidthor/arcctl/pkg/graph"
+)
+
+const compatibilityDatacenterHCL = `
+environment {
+  database {
+    when  = element(split(":", node.inputs.type), 0) == "redis"
+    error = "Redis is not supported: ${node.inputs.type}"
+  }
+
+  database {
+    module "db" {
+      build = "./modules/db"
+    }
+    outputs = {
+      host = module.db.host
+      port = module.db.port
+    }
+  }
+
+  deployment {
+    module "deployment" {
+      build = "./modules/deployment"
+    }
+  }
+}
+`
+
+func newCompatibilityTestExecutor(t *testing.T) *Executor {
+	t.Helper()
+
+	return NewExecutor(newMockStateManager(), newTestRegistry(), Options{
+		Datacenter: loadTestDatacenter(t, compatibilityDatacenterHCL),
+	})
+}
+
+func TestCheckCompatibility(t *testing.T) {
+	exec := newCompatibilityTestExecutor(t)
+
+	main := graph.NewNode(graph.NodeTypeDatabase, "api", "main")
+	main.Inputs["type"] = "postgres:16"
+	cache := graph.NewNode(graph.NodeTypeDatabase, "api", "cache")
+	cache.Inputs["type"] = "redis:7"
+	files := graph.NewNode(graph.NodeTypeBucket, "api", "files")
+	worker := graph.NewNode(graph.NodeTypeDeployment, "api", "worker")
+	worker.Inputs["environment"] = map[string]interface{}{
+		"DB_HOST": "${{ databases.main.host }}",
+		"DB_URL":  "${{ databases.main.url | urlencode }}",
+		"CACHE":   "${{ databases.cache.url }}",
+		"FILES":   "${{ buckets.files.endpoint }}",
+	}
+
+	g := graph.NewGraph("staging", "dc")
+	for _, node := range []*graph.Node{main, cache, files, worker} {
+		if err := g.AddNode(node); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	issues := exec.CheckCompatibility(g)
+	want := []CompatibilityIssue{
+		{NodeID: "api/bucket/files", Kind: IssueNoHook},
+		{NodeID: "api/database/cache", Kind: IssueErrorHook, Message: "Redis is not supported: redis:7"},
+		{NodeID: "api/deployment/worker", Kind: IssueMissingOutput, Message: `references ${{ databases.main.url | urlencode }}, but the hook for api/database/main doesn't output "url"`},
+	}
+	if len(issues) != len(want) {
+		t.Fatalf("expected %d issues, got %+v", len(want), issues)
+	}
+	for i, issue := range issues {
+		if issue.NodeID != want[i].NodeID || issue.Kind != want[i].Kind {
+			t.Errorf("issue %d = %+v, want %+v", i, issue, want[i])
+		}
+		if want[i].Message != "" && issue.Message != want[i].Message {
+			t.Errorf("issue %d message = %q, want %q", i, issue.Message, want[i].Message)
+		}
+	}
+}
+
+func TestReferencedOutput(t *testing.T) {
+	tests := []struct {
+		ref        string
+		wantNodeID string
+		wantOutput string
+	}{
+		{"databases.main.url", "api/database/main", "url"},
+		{"builds.api.image | lower", "api/dockerBuild/api", "image"},
+		{"observability.endpoint", "api/observability/observability", "endpoint"},
+		{"variables.region", "", ""},
+		{"dependencies.auth.services.api.url", "", ""},
+	}
+	for _, tt := range tests {
+		nodeID, output := referencedOutput("api", tt.ref)
+		if nodeID != tt.wantNodeID || output != tt.wantOutput {
+			t.Errorf("referencedOutput(%q) = (%q, %q), want (%q, %q)", tt.ref, nodeID, output, tt.wantNodeID, tt.wantOutput)
+		}
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -20,6 +21,7 @@ import (
 	"github.com/davidthor/arcctl/pkg/iac"
 	"github.com/davidthor/arcctl/pkg/schema/datacenter"
 	v1 "github.com/davidthor/arcctl/pkg/schema/datacenter/v1"
+	"github.com/davidthor/arcctl/pkg/secrets"
 	"github.com/davidthor/arcctl/pkg/state"
 	"github.com/davidthor/arcctl/pkg/state/types"
 	"github.com/hashicorp/hcl/v2"
@@ -53,9 +55,13 @@ type ProgressEvent struct {
 	NodeID   string
 	NodeName string
 	NodeType string
-	Status   string // "pending", "running", "completed", "failed", "skipped"
+	Action   planner.Action // Planned action, set on events for a planned change
+	Status   string         // "pending", "running", "completed", "failed", "skipped"
 	Message  string
 	Error    error
+
+	// Attempt is set when a failed hook module run is retried
+	Attempt int
 }
 
 // ProgressCallback is called when resource status changes.
@@ -91,6 +97,22 @@ type Options struct {
 	// ComponentVariables maps component name to its deployment variables.
 	// Used to populate ComponentState.Variables for re-deploy reconstruction.
 	ComponentVariables map[string]map[string]interface{}
+
+	// ComponentVariableSources, when set, is recorded in ComponentState.Variables
+	// instead of ComponentVariables. It holds the variables before secret
+	// references were resolved so that secret values are not written to state.
+	// Resource inputs that use a variable given as a secret reference record
+	// its ${{ variables.<name> }} expression rather than the resolved value.
+	ComponentVariableSources map[string]map[string]interface{}
+
+	// SensitiveComponentVariables maps component name to the names of its
+	// variables declared sensitive. Recorded in ComponentState.SensitiveVariables
+	// so the state manager can protect those values at rest.
+	SensitiveComponentVariables map[string][]string
+
+	// ReadinessTimeout is how long a resource whose hook module outputs a
+	// ready_check is given to pass it before it fails (default 5 minutes)
+	ReadinessTimeout time.Duration
 }
 
 // DefaultOptions returns default executor options.
@@ -109,15 +131,46 @@ type Executor struct {
 	graph          *graph.Graph // Store reference to graph for service port lookups
 	stateMu        sync.Mutex   // Protects concurrent access to environment state
 	datacenterName string       // Set at execution start for incremental state saves
+	restoring      bool         // Set during Rollback so applies start from the latest recorded IaC state
+	saveErr        error        // First state save that failed during the current execution; protected by stateMu
 }
 
 // saveStateLocked flushes the in-memory environment state to the backend so that
 // other processes (e.g., `cldctl inspect`) can observe progress in real time.
-// MUST be called while holding e.stateMu. Uses a background context so that
+// The state manager only writes the component and resource documents that
+// changed since the last save, so a status change doesn't rewrite the IaC state
+// of every resource. MUST be called while holding e.stateMu. Uses a background context so that
 // saves complete even when the deployment context has been cancelled.
-func (e *Executor) saveStateLocked(envState *types.EnvironmentState) {
+//
+// Once a save fails, e.g. because another process changed the environment
+// (state.ErrStateConflict), later saves would be rejected the same way, so
+// they aren't attempted and the first failure is returned instead.
+func (e *Executor) saveStateLocked(envState *types.EnvironmentState) error {
+	if e.saveErr != nil {
+		return e.saveErr
+	}
 	saveCtx := context.Background()
-	_ = e.stateManager.SaveEnvironment(saveCtx, e.datacenterName, envState)
+	if err := e.stateManager.SaveEnvironment(saveCtx, e.datacenterName, envState); err != nil {
+		e.saveErr = fmt.Errorf("failed to save state: %w", err)
+	}
+	return e.saveErr
+}
+
+// stateSaveErr returns the first state save that failed during the current
+// execution, if any. Nodes aren't started once a save has failed, since
+// nothing they create could be recorded.
+func (e *Executor) stateSaveErr() error {
+	e.stateMu.Lock()
+	defer e.stateMu.Unlock()
+	return e.saveErr
+}
+
+// beginExecution resets the per-execution state of the executor.
+func (e *Executor) beginExecution(datacenter string) {
+	e.stateMu.Lock()
+	defer e.stateMu.Unlock()
+	e.datacenterName = datacenter
+	e.saveErr = nil
 }
 
 // NewExecutor creates a new executor.
@@ -147,8 +200,12 @@ func (e *Executor) newComponentState(componentName string) *types.ComponentState
 	if e.options.ComponentSources != nil {
 		cs.Source = e.options.ComponentSources[componentName]
 	}
-	if e.options.ComponentVariables != nil {
-		if vars, ok := e.options.ComponentVariables[componentName]; ok {
+	stateVars := e.options.ComponentVariables
+	if e.options.ComponentVariableSources != nil {
+		stateVars = e.options.ComponentVariableSources
+	}
+	if stateVars != nil {
+		if vars, ok := stateVars[componentName]; ok {
 			strVars := make(map[string]string, len(vars))
 			for k, v := range vars {
 				strVars[k] = fmt.Sprintf("%v", v)
@@ -156,6 +213,9 @@ func (e *Executor) newComponentState(componentName string) *types.ComponentState
 			cs.Variables = strVars
 		}
 	}
+	if e.options.SensitiveComponentVariables != nil {
+		cs.SensitiveVariables = e.options.SensitiveComponentVariables[componentName]
+	}
 	return cs
 }
 
@@ -166,6 +226,17 @@ func resourceKey(node *graph.Node) string {
 	return string(node.Type) + "." + node.Name
 }
 
+// recordedOutputs returns the outputs recorded for node in envState, or nil
+// if the node's resource isn't recorded.
+func recordedOutputs(node *graph.Node, envState *types.EnvironmentState) map[string]interface{} {
+	if comp := envState.Components[node.Component]; comp != nil {
+		if res := comp.Resources[resourceKey(node)]; res != nil {
+			return res.Outputs
+		}
+	}
+	return nil
+}
+
 // computeComponentStatuses derives each component's status from its child resources.
 // Call this before the final state save so that component-level status is accurate.
 func computeComponentStatuses(envState *types.EnvironmentState) {
@@ -203,7 +274,7 @@ func (e *Executor) Execute(ctx context.Context, plan *planner.Plan, g *graph.Gra
 
 	// Store graph reference for service port lookups
 	e.graph = g
-	e.datacenterName = plan.Datacenter
+	e.beginExecution(plan.Datacenter)
 
 	result := &ExecutionResult{
 		Success:     true,
@@ -236,13 +307,25 @@ func (e *Executor) Execute(ctx context.Context, plan *planner.Plan, g *graph.Gra
 	// Mark as provisioning and flush so that inspect can see progress immediately
 	envState.Status = types.EnvironmentStatusProvisioning
 	envState.UpdatedAt = time.Now()
-	_ = e.stateManager.SaveEnvironment(ctx, plan.Datacenter, envState)
+	e.stateMu.Lock()
+	err = e.saveStateLocked(envState)
+	e.stateMu.Unlock()
+	if err != nil {
+		result.Success = false
+		result.Errors = append(result.Errors, err)
+		result.Duration = time.Since(startTime)
+		return result, nil
+	}
 
 	// Execute changes in order
 	for _, change := range plan.Changes {
-		if ctx.Err() != nil {
+		if ctx.Err() != nil || StopRequested(ctx) {
 			result.Success = false
-			result.Errors = append(result.Errors, ctx.Err())
+			result.Errors = append(result.Errors, interruptErr(ctx))
+			break
+		}
+		if e.stateSaveErr() != nil {
+			result.Success = false // Reported with the final save below
 			break
 		}
 
@@ -280,7 +363,7 @@ func (e *Executor) Execute(ctx context.Context, plan *planner.Plan, g *graph.Gra
 				Inputs:    change.Node.Inputs,
 				UpdatedAt: time.Now(),
 			}
-			e.saveStateLocked(envState)
+			_ = e.saveStateLocked(envState) // Checked before the next change
 			e.stateMu.Unlock()
 
 			// Fire progress event for the dependency failure
@@ -289,6 +372,7 @@ func (e *Executor) Execute(ctx context.Context, plan *planner.Plan, g *graph.Gra
 					NodeID:   change.Node.ID,
 					NodeName: change.Node.Name,
 					NodeType: string(change.Node.Type),
+					Action:   change.Action,
 					Status:   "failed",
 					Message:  depErr.Error(),
 					Error:    depErr,
@@ -361,9 +445,14 @@ func (e *Executor) Execute(ctx context.Context, plan *planner.Plan, g *graph.Gra
 	}
 	envState.UpdatedAt = time.Now()
 
-	// Save state
-	if err := e.stateManager.SaveEnvironment(ctx, plan.Datacenter, envState); err != nil {
-		result.Errors = append(result.Errors, fmt.Errorf("failed to save state: %w", err))
+	// Save state, even if the execution was interrupted. An execution whose
+	// state wasn't saved failed, since what it changed isn't recorded.
+	e.stateMu.Lock()
+	err = e.saveStateLocked(envState)
+	e.stateMu.Unlock()
+	if err != nil {
+		result.Success = false
+		result.Errors = append(result.Errors, err)
 	}
 
 	result.Duration = time.Since(startTime)
@@ -400,6 +489,7 @@ func (e *Executor) executeChange(ctx context.Context, change *planner.ResourceCh
 			NodeID:   change.Node.ID,
 			NodeName: change.Node.Name,
 			NodeType: string(change.Node.Type),
+			Action:   change.Action,
 			Status:   "running",
 			Message:  fmt.Sprintf("%s %s", change.Action, change.Node.Name),
 		})
@@ -419,6 +509,11 @@ func (e *Executor) executeChange(ctx context.Context, change *planner.ResourceCh
 		result = e.executeDestroy(ctx, change, envState)
 	case planner.ActionNoop:
 		result.Success = true
+		// Carry forward the recorded outputs so that updated dependents can
+		// still resolve references to this node
+		if change.CurrentState != nil {
+			result.Outputs = change.CurrentState.Outputs
+		}
 	}
 
 	result.Duration = time.Since(startTime)
@@ -437,6 +532,7 @@ func (e *Executor) executeChange(ctx context.Context, change *planner.ResourceCh
 			NodeID:   change.Node.ID,
 			NodeName: change.Node.Name,
 			NodeType: string(change.Node.Type),
+			Action:   change.Action,
 			Status:   status,
 			Message:  msg,
 			Error:    result.Error,
@@ -455,7 +551,7 @@ func (e *Executor) executeApply(ctx context.Context, change *planner.ResourceCha
 	// Resolve ${{ }} component expressions in node inputs (e.g., ${{ builds.api.image }},
 	// ${{ dependencies.*.outputs.* }}, ${{ variables.* }}) BEFORE saving state so that
 	// inspect shows resolved values even while the resource is still provisioning.
-	e.resolveComponentExpressions(change.Node, envState)
+	inputs, sensitiveInputs := e.resolveComponentExpressions(change.Node, envState)
 
 	// Lock for state initialization
 	e.stateMu.Lock()
@@ -477,21 +573,47 @@ func (e *Executor) executeApply(ctx context.Context, change *planner.ResourceCha
 		compState.Resources = make(map[string]*types.ResourceState)
 	}
 
+	prev := compState.Resources[resourceKey(change.Node)]
+	attempt := nextAttempt(prev)
+
+	// Infrastructure recorded by a previous apply stays in state until this
+	// one succeeds, so that it can still be destroyed if this apply fails
+	var prevIaCState []byte
+	if prev != nil {
+		var err error
+		if prevIaCState, err = prev.LoadIaCState(); err != nil {
+			e.stateMu.Unlock()
+			result.Error = err
+			result.Success = false
+			return result
+		}
+	}
+
 	// Save a "provisioning" entry immediately so that `cldctl inspect` can see
 	// in-progress resources before plugin.Apply returns (which may block for a
 	// long time, e.g. readiness checks on dev-server processes).
 	compState.Resources[resourceKey(change.Node)] = &types.ResourceState{
-		Component: change.Node.Component,
-		Name:      change.Node.Name,
-		Type:      string(change.Node.Type),
-		Status:    types.ResourceStatusProvisioning,
-		Inputs:    change.Node.Inputs,
-		UpdatedAt: time.Now(),
-	}
-	e.saveStateLocked(envState)
+		Component:       change.Node.Component,
+		Name:            change.Node.Name,
+		Type:            string(change.Node.Type),
+		Status:          types.ResourceStatusProvisioning,
+		Inputs:          inputs,
+		SensitiveInputs: sensitiveInputs,
+		IaCState:        prevIaCState,
+		Attempt:         attempt,
+		UpdatedAt:       time.Now(),
+	}
+	err := e.saveStateLocked(envState)
 
 	e.stateMu.Unlock()
 
+	// Nothing is applied unless it can be recorded
+	if err != nil {
+		result.Error = err
+		result.Success = false
+		return result
+	}
+
 	// Find the matching hook from datacenter
 	modulePath, moduleInputs, pluginName, err := e.findMatchingHook(change.Node, envState.Name)
 	if err != nil {
@@ -517,24 +639,55 @@ func (e *Executor) executeApply(ctx context.Context, change *planner.ResourceCha
 		Inputs:       moduleInputs,
 		Environment:  map[string]string{},
 	}
+	if e.restoring && len(prevIaCState) > 0 {
+		// Start from the latest recorded state, which includes anything the
+		// failed apply created, rather than the state from before it
+		runOpts.StateReader = bytes.NewReader(prevIaCState)
+	}
 
-	// Execute
-	applyResult, err := plugin.Apply(ctx, runOpts)
+	// Execute, retrying transient failures as the hook module allows
+	var policy hookPolicy
+	if module, err := e.matchHookModule(change.Node); err == nil {
+		policy = newHookPolicy(module)
+	}
+	var applyResult *iac.ApplyResult
+	err = e.runHookModule(ctx, change.Node, policy, "apply", func(ctx context.Context) error {
+		var applyErr error
+		applyResult, applyErr = plugin.Apply(ctx, runOpts)
+		return applyErr
+	})
 	if err != nil {
 		result.Error = fmt.Errorf("apply failed: %w", err)
 		result.Success = false
 
+		// Keep any state the plugin saved before failing, since it records
+		// infrastructure that was created
+		iacState := prevIaCState
+		if applyResult != nil && len(applyResult.State) > 0 {
+			iacState = applyResult.State
+		}
+		var reason string
+		if ctx.Err() != nil {
+			reason = interruptedReason
+		}
+
 		// Update resource state to failed (lock for state update)
 		e.stateMu.Lock()
 		compState.Resources[resourceKey(change.Node)] = &types.ResourceState{
-			Component: change.Node.Component,
-			Name:      change.Node.Name,
-			Type:      string(change.Node.Type),
-			Status:    types.ResourceStatusFailed,
-			Inputs:    change.Node.Inputs,
-			UpdatedAt: time.Now(),
-		}
-		e.saveStateLocked(envState)
+			Component:       change.Node.Component,
+			Name:            change.Node.Name,
+			Type:            string(change.Node.Type),
+			Status:          types.ResourceStatusFailed,
+			StatusReason:    reason,
+			Inputs:          inputs,
+			SensitiveInputs: sensitiveInputs,
+			IaCState:        iacState,
+			Attempt:         attempt,
+			UpdatedAt:       time.Now(),
+		}
+		if err := e.saveStateLocked(envState); err != nil {
+			result.Error = fmt.Errorf("%w; %v", result.Error, err)
+		}
 		e.stateMu.Unlock()
 
 		return result
@@ -545,27 +698,81 @@ func (e *Executor) executeApply(ctx context.Context, change *planner.ResourceCha
 	for name, out := range applyResult.Outputs {
 		outputs[name] = out.Value
 	}
+
+	// Hold back dependents until the resource passes its readiness check
+	if value, ok := outputs[readyCheckOutput]; ok && value != nil {
+		check, err := parseReadyCheck(value)
+		if err == nil {
+			err = e.waitForReady(ctx, change.Node, check)
+		}
+		if err != nil {
+			result.Error = fmt.Errorf("readiness check failed: %w", err)
+			result.Success = false
+
+			// The infrastructure was applied, so its IaC state is kept
+			e.stateMu.Lock()
+			compState.Resources[resourceKey(change.Node)] = &types.ResourceState{
+				Component:        change.Node.Component,
+				Name:             change.Node.Name,
+				Type:             string(change.Node.Type),
+				Status:           types.ResourceStatusFailed,
+				StatusReason:     "not ready: " + err.Error(),
+				Inputs:           inputs,
+				SensitiveInputs:  sensitiveInputs,
+				Outputs:          outputs,
+				SensitiveOutputs: applyResult.SensitiveOutputs(),
+				IaCState:         applyResult.State,
+				Attempt:          attempt,
+				UpdatedAt:        time.Now(),
+			}
+			if err := e.saveStateLocked(envState); err != nil {
+				result.Error = fmt.Errorf("%w; %v", result.Error, err)
+			}
+			e.stateMu.Unlock()
+
+			return result
+		}
+	}
+
 	result.Outputs = outputs
 	result.Success = true
 
 	// Update resource state including IaC state for cleanup (lock for state update)
 	e.stateMu.Lock()
 	compState.Resources[resourceKey(change.Node)] = &types.ResourceState{
-		Component: change.Node.Component,
-		Name:      change.Node.Name,
-		Type:      string(change.Node.Type),
-		Status:    types.ResourceStatusReady,
-		Inputs:    change.Node.Inputs,
-		Outputs:   outputs,
-		IaCState:  applyResult.State, // Store IaC state for destroy
-		UpdatedAt: time.Now(),
+		Component:        change.Node.Component,
+		Name:             change.Node.Name,
+		Type:             string(change.Node.Type),
+		Status:           types.ResourceStatusReady,
+		Inputs:           inputs,
+		SensitiveInputs:  sensitiveInputs,
+		Outputs:          outputs,
+		SensitiveOutputs: applyResult.SensitiveOutputs(),
+		IaCState:         applyResult.State, // Store IaC state for destroy
+		Attempt:          attempt,
+		UpdatedAt:        time.Now(),
+	}
+	if err := e.saveStateLocked(envState); err != nil {
+		// The infrastructure exists but isn't recorded, so nothing can
+		// depend on it
+		result.Error = fmt.Errorf("applied, but its state wasn't recorded: %w", err)
+		result.Success = false
 	}
-	e.saveStateLocked(envState)
 	e.stateMu.Unlock()
 
 	return result
 }
 
+// nextAttempt returns the attempt number for applying a resource given its
+// state before the apply. Applying a resource that didn't become ready is
+// another attempt at the same change.
+func nextAttempt(prev *types.ResourceState) int {
+	if prev == nil || prev.Status == types.ResourceStatusReady {
+		return 1
+	}
+	return prev.Attempt + 1
+}
+
 // findMatchingHook finds the matching datacenter hook for a node and returns the module path, inputs, and plugin name.
 func (e *Executor) findMatchingHook(node *graph.Node, envName string) (modulePath string, inputs map[string]interface{}, pluginName string, err error) {
 	dc := e.options.Datacenter
@@ -573,10 +780,68 @@ func (e *Executor) findMatchingHook(node *graph.Node, envName string) (modulePat
 		return "", nil, "", fmt.Errorf("no datacenter configuration provided")
 	}
 
+	module, err := e.matchHookModule(node)
+	if err != nil {
+		return "", nil, "", err
+	}
+
+	// Resolve module path relative to datacenter source
+	dcPath := dc.SourcePath()
+	dcDir := filepath.Dir(dcPath)
+	modulePath = module.Build()
+	if modulePath == "" {
+		modulePath = module.Source()
+	}
+
+	// Debug output for troubleshooting (only when env var is set)
+	if os.Getenv("CLDCTL_DEBUG") != "" && e.options.Output != nil {
+		fmt.Fprintf(e.options.Output, "  [debug] Node %s: dcPath=%s, dcDir=%s, moduleName=%s, moduleBuild=%q, moduleSource=%q\n",
+			node.ID, dcPath, dcDir, module.Name(), module.Build(), module.Source())
+	}
+
+	if modulePath != "" && !filepath.IsAbs(modulePath) {
+		modulePath = filepath.Join(dcDir, modulePath)
+	}
+
+	if modulePath == "" {
+		return "", nil, "", fmt.Errorf("module %s has no build or source path", module.Name())
+	}
+
+	// Build module inputs by evaluating expressions in the hook's module inputs
+	inputs = e.buildModuleInputs(module, node, envName)
+
+	return modulePath, inputs, module.Plugin(), nil
+}
+
+// matchHookModule finds the first datacenter hook whose 'when' condition
+// matches the node and returns the module that runs it.
+func (e *Executor) matchHookModule(node *graph.Node) (datacenter.Module, error) {
+	matchedHook, err := e.matchHook(node)
+	if err != nil {
+		return nil, err
+	}
+
+	// Get the first module from the hook
+	modules := matchedHook.Modules()
+	if len(modules) == 0 {
+		return nil, fmt.Errorf("hook has no modules defined for %s", node.Type)
+	}
+
+	return modules[0], nil
+}
+
+// matchHook finds the first datacenter hook whose 'when' condition matches
+// the node. An error hook that matches is returned as an error.
+func (e *Executor) matchHook(node *graph.Node) (datacenter.Hook, error) {
+	dc := e.options.Datacenter
+	if dc == nil {
+		return nil, fmt.Errorf("no datacenter configuration provided")
+	}
+
 	// Get hooks for this node type
 	hooks := e.getHooksForType(node.Type)
 	if len(hooks) == 0 {
-		return "", nil, "", fmt.Errorf("no hooks defined for resource type %s in datacenter (source: %s)", node.Type, dc.SourcePath())
+		return nil, fmt.Errorf("no hooks defined for resource type %s in datacenter (source: %s)", node.Type, dc.SourcePath())
 	}
 
 	// Find the first matching hook based on 'when' condition
@@ -591,13 +856,13 @@ func (e *Executor) findMatchingHook(node *graph.Node, envName string) (modulePat
 	}
 
 	if matchedHook == nil {
-		return "", nil, "", fmt.Errorf("no matching hook found for %s (inputs: %v)", node.Type, node.Inputs)
+		return nil, fmt.Errorf("no matching hook found for %s (inputs: %v)", node.Type, node.Inputs)
 	}
 
 	// Check if the matched hook is an error hook (rejects the resource)
 	if errMsg := matchedHook.Error(); errMsg != "" {
 		evaluatedMsg := e.evaluateErrorMessage(errMsg, node.Inputs)
-		return "", nil, "", arcerrors.DatacenterHookError(
+		return nil, arcerrors.DatacenterHookError(
 			string(node.Type),
 			node.Component,
 			node.Name,
@@ -605,40 +870,32 @@ func (e *Executor) findMatchingHook(node *graph.Node, envName string) (modulePat
 		)
 	}
 
-	// Get the first module from the hook
-	modules := matchedHook.Modules()
-	if len(modules) == 0 {
-		return "", nil, "", fmt.Errorf("hook has no modules defined for %s", node.Type)
-	}
-
-	module := modules[0]
-
-	// Resolve module path relative to datacenter source
-	dcPath := dc.SourcePath()
-	dcDir := filepath.Dir(dcPath)
-	modulePath = module.Build()
-	if modulePath == "" {
-		modulePath = module.Source()
-	}
+	return matchedHook, nil
+}
 
-	// Debug output for troubleshooting (only when env var is set)
-	if os.Getenv("CLDCTL_DEBUG") != "" && e.options.Output != nil {
-		fmt.Fprintf(e.options.Output, "  [debug] Node %s: dcPath=%s, dcDir=%s, moduleName=%s, moduleBuild=%q, moduleSource=%q\n",
-			node.ID, dcPath, dcDir, module.Name(), module.Build(), module.Source())
+// ResolveHook finds the datacenter hook module that handles the given node and
+// returns its resolved module path, module inputs, and plugin name. It is used
+// by operations that need to run a hook module outside of plan execution
+// (e.g., refresh).
+func (e *Executor) ResolveHook(node *graph.Node, envName string) (modulePath string, inputs map[string]interface{}, pluginName string, err error) {
+	modulePath, inputs, pluginName, err = e.findMatchingHook(node, envName)
+	if err != nil {
+		return "", nil, "", err
 	}
-
-	if modulePath != "" && !filepath.IsAbs(modulePath) {
-		modulePath = filepath.Join(dcDir, modulePath)
+	if pluginName == "" {
+		pluginName = "native"
 	}
+	return modulePath, inputs, pluginName, nil
+}
 
-	if modulePath == "" {
-		return "", nil, "", fmt.Errorf("module %s has no build or source path", module.Name())
+// HookModuleName returns the name of the datacenter hook module that handles
+// the given node, for display.
+func (e *Executor) HookModuleName(node *graph.Node) (string, error) {
+	module, err := e.matchHookModule(node)
+	if err != nil {
+		return "", err
 	}
-
-	// Build module inputs by evaluating expressions in the hook's module inputs
-	inputs = e.buildModuleInputs(module, node, envName)
-
-	return modulePath, inputs, module.Plugin(), nil
+	return module.Name(), nil
 }
 
 // getHooksForType returns the datacenter hooks for a given node type.
@@ -688,17 +945,32 @@ func (e *Executor) getHooksForType(nodeType graph.NodeType) []datacenter.Hook {
 // fails (e.g. due to an unparseable expression), it falls back to simplified
 // string-based matching for common patterns.
 func (e *Executor) evaluateWhenCondition(when string, inputs map[string]interface{}) bool {
+	return e.evaluateWhen(when, inputs).matched
+}
+
+// whenResult is the outcome of evaluating a 'when' condition.
+type whenResult struct {
+	matched bool
+
+	// hclErr is set when the condition couldn't be evaluated as HCL and the
+	// string fallback decided the result
+	hclErr error
+}
+
+// evaluateWhen evaluates a 'when' condition and reports how the result was reached.
+func (e *Executor) evaluateWhen(when string, inputs map[string]interface{}) whenResult {
 	if when == "" {
-		return true // No condition means always match
+		return whenResult{matched: true} // No condition means always match
 	}
 
 	// Try full HCL expression evaluation first
-	if result, err := e.evaluateWhenHCL(when, inputs); err == nil {
-		return result
+	result, err := e.evaluateWhenHCL(when, inputs)
+	if err == nil {
+		return whenResult{matched: result}
 	}
 
 	// Fall back to simplified string matching for patterns that can't be parsed as HCL
-	return e.evaluateWhenStringFallback(when, inputs)
+	return whenResult{matched: e.evaluateWhenStringFallback(when, inputs), hclErr: err}
 }
 
 // evaluateWhenHCL parses the when string as an HCL expression and evaluates it
@@ -1048,6 +1320,7 @@ func (e *Executor) buildModuleInputs(module datacenter.Module, node *graph.Node,
 		setIfMissing(inputs, "cpu", node.Inputs["cpu"])
 		setIfMissing(inputs, "memory", node.Inputs["memory"])
 		setIfMissing(inputs, "liveness_probe", node.Inputs["liveness_probe"])
+		setIfMissing(inputs, "readiness_probe", node.Inputs["readiness_probe"])
 
 		// Inject PORT into environment
 		// Priority: 1) node's own port property (for functions), 2) associated service's port
@@ -1283,9 +1556,16 @@ func hashCode(s string) int {
 //
 // Also recurses into nested maps (e.g., environment map) to resolve expressions there.
 // envState is used to look up cross-component dependency outputs (dependencies.<name>.outputs.<key>).
-func (e *Executor) resolveComponentExpressions(node *graph.Node, envState *types.EnvironmentState) {
+//
+// It returns the inputs to record in state: the resolved inputs, except that
+// variables given as secret references are left as ${{ variables.<name> }}
+// expressions, so that resolved secrets are never written to state and are
+// resolved again when the recorded inputs are re-applied. It also returns the
+// recorded inputs that hold sensitive values (sensitive variables and
+// dependencies' sensitive outputs), as listed in ResourceState.SensitiveInputs.
+func (e *Executor) resolveComponentExpressions(node *graph.Node, envState *types.EnvironmentState) (map[string]interface{}, []string) {
 	if e.graph == nil {
-		return
+		return node.Inputs, nil
 	}
 
 	// Resolve component variables from executor options
@@ -1298,11 +1578,25 @@ func (e *Executor) resolveComponentExpressions(node *graph.Node, envState *types
 
 	exprPattern := regexp.MustCompile(`\$\{\{\s*([^}]+)\s*\}\}`)
 
-	resolveStr := func(strVal string) string {
+	// resolve returns strVal with its expressions resolved, and whether any
+	// of them resolved to a sensitive value
+	resolve := func(strVal string, record bool) (string, bool) {
 		if !strings.Contains(strVal, "${{") {
-			return strVal
+			return strVal, false
+		}
+		sensitive := false
+		resolveOutput := func(depNode *graph.Node, name string) (string, bool) {
+			val, ok := depNode.Outputs[name]
+			if !ok {
+				return "", false
+			}
+			if e.isSensitiveOutput(envState, depNode, name) {
+				sensitive = true
+			}
+			return fmt.Sprintf("%v", val), true
 		}
-		return exprPattern.ReplaceAllStringFunc(strVal, func(match string) string {
+
+		resolved := exprPattern.ReplaceAllStringFunc(strVal, func(match string) string {
 			inner := match[3 : len(match)-2]
 			inner = strings.TrimSpace(inner)
 			parts := strings.Split(inner, ".")
@@ -1322,60 +1616,27 @@ func (e *Executor) resolveComponentExpressions(node *graph.Node, envState *types
 				if !ok || buildNode.Outputs == nil {
 					return match
 				}
-				if val, ok := buildNode.Outputs[parts[2]]; ok {
-					return fmt.Sprintf("%v", val)
-				}
-
-			case "databases":
-				if len(parts) < 3 {
-					return match
-				}
-				nodeID := fmt.Sprintf("%s/%s/%s", node.Component, graph.NodeTypeDatabase, parts[1])
-				depNode, ok := e.graph.Nodes[nodeID]
-				if !ok || depNode.Outputs == nil {
-					return match
-				}
-				if val, ok := depNode.Outputs[parts[2]]; ok {
-					return fmt.Sprintf("%v", val)
-				}
-
-			case "services":
-				if len(parts) < 3 {
-					return match
-				}
-				nodeID := fmt.Sprintf("%s/%s/%s", node.Component, graph.NodeTypeService, parts[1])
-				depNode, ok := e.graph.Nodes[nodeID]
-				if !ok || depNode.Outputs == nil {
-					return match
-				}
-				if val, ok := depNode.Outputs[parts[2]]; ok {
-					return fmt.Sprintf("%v", val)
-				}
-
-			case "buckets":
-				if len(parts) < 3 {
-					return match
-				}
-				nodeID := fmt.Sprintf("%s/%s/%s", node.Component, graph.NodeTypeBucket, parts[1])
-				depNode, ok := e.graph.Nodes[nodeID]
-				if !ok || depNode.Outputs == nil {
-					return match
-				}
-				if val, ok := depNode.Outputs[parts[2]]; ok {
-					return fmt.Sprintf("%v", val)
+				if val, ok := resolveOutput(buildNode, parts[2]); ok {
+					return val
 				}
 
-			case "routes":
+			case "databases", "services", "buckets", "routes":
 				if len(parts) < 3 {
 					return match
 				}
-				nodeID := fmt.Sprintf("%s/%s/%s", node.Component, graph.NodeTypeRoute, parts[1])
+				nodeType := map[string]graph.NodeType{
+					"databases": graph.NodeTypeDatabase,
+					"services":  graph.NodeTypeService,
+					"buckets":   graph.NodeTypeBucket,
+					"routes":    graph.NodeTypeRoute,
+				}[resourceType]
+				nodeID := fmt.Sprintf("%s/%s/%s", node.Component, nodeType, parts[1])
 				depNode, ok := e.graph.Nodes[nodeID]
 				if !ok || depNode.Outputs == nil {
 					return match
 				}
-				if val, ok := depNode.Outputs[parts[2]]; ok {
-					return fmt.Sprintf("%v", val)
+				if val, ok := resolveOutput(depNode, parts[2]); ok {
+					return val
 				}
 
 			case "observability":
@@ -1385,9 +1646,8 @@ func (e *Executor) resolveComponentExpressions(node *graph.Node, envState *types
 				if !ok || obsNode.Outputs == nil {
 					return "" // Resolve to empty string if no observability hook
 				}
-				prop := parts[1]
-				if val, ok := obsNode.Outputs[prop]; ok {
-					return fmt.Sprintf("%v", val)
+				if val, ok := resolveOutput(obsNode, parts[1]); ok {
+					return val
 				}
 				return "" // Unknown property resolves to empty
 
@@ -1397,11 +1657,18 @@ func (e *Executor) resolveComponentExpressions(node *graph.Node, envState *types
 					return match
 				}
 				varName := parts[1]
-				if val, ok := compVars[varName]; ok {
-					return fmt.Sprintf("%v", val)
+				if record && e.isSecretVariable(node.Component, varName) {
+					return match
+				}
+				val, ok := compVars[varName]
+				if !ok {
+					// Fallback: check if stored as variables_<name> in node inputs
+					val, ok = node.Inputs["variables_"+varName]
 				}
-				// Fallback: check if stored as variables_<name> in node inputs
-				if val, ok := node.Inputs["variables_"+varName]; ok {
+				if ok {
+					if e.isSensitiveVariable(node.Component, varName) {
+						sensitive = true
+					}
 					return fmt.Sprintf("%v", val)
 				}
 
@@ -1426,8 +1693,8 @@ func (e *Executor) resolveComponentExpressions(node *graph.Node, envState *types
 				// (for components deployed in the same session)
 				for _, graphNode := range e.graph.Nodes {
 					if graphNode.Component == depName && graphNode.Outputs != nil {
-						if val, ok := graphNode.Outputs[outputKey]; ok {
-							return fmt.Sprintf("%v", val)
+						if val, ok := resolveOutput(graphNode, outputKey); ok {
+							return val
 						}
 					}
 				}
@@ -1439,6 +1706,9 @@ func (e *Executor) resolveComponentExpressions(node *graph.Node, envState *types
 						for _, res := range depComp.Resources {
 							if res.Outputs != nil {
 								if val, ok := res.Outputs[outputKey]; ok {
+									if res.IsSensitiveOutput(outputKey) {
+										sensitive = true
+									}
 									return fmt.Sprintf("%v", val)
 								}
 							}
@@ -1449,30 +1719,92 @@ func (e *Executor) resolveComponentExpressions(node *graph.Node, envState *types
 
 			return match
 		})
+		return resolved, sensitive
 	}
 
+	recorded := make(map[string]interface{}, len(node.Inputs))
+	var sensitiveInputs []string
 	for key, value := range node.Inputs {
 		switch v := value.(type) {
 		case string:
-			node.Inputs[key] = resolveStr(v)
+			node.Inputs[key], _ = resolve(v, false)
+			val, sensitive := resolve(v, true)
+			recorded[key] = val
+			if sensitive {
+				sensitiveInputs = append(sensitiveInputs, key)
+			}
 		case map[string]string:
 			resolved := make(map[string]string, len(v))
+			record := make(map[string]string, len(v))
 			for k, val := range v {
-				resolved[k] = resolveStr(val)
+				resolved[k], _ = resolve(val, false)
+				recordedVal, sensitive := resolve(val, true)
+				record[k] = recordedVal
+				if sensitive {
+					sensitiveInputs = append(sensitiveInputs, key+"."+k)
+				}
 			}
 			node.Inputs[key] = resolved
+			recorded[key] = record
 		case map[string]interface{}:
 			resolved := make(map[string]interface{}, len(v))
+			record := make(map[string]interface{}, len(v))
 			for k, val := range v {
 				if s, ok := val.(string); ok {
-					resolved[k] = resolveStr(s)
+					resolved[k], _ = resolve(s, false)
+					recordedVal, sensitive := resolve(s, true)
+					record[k] = recordedVal
+					if sensitive {
+						sensitiveInputs = append(sensitiveInputs, key+"."+k)
+					}
 				} else {
 					resolved[k] = val
+					record[k] = val
 				}
 			}
 			node.Inputs[key] = resolved
+			recorded[key] = record
+		default:
+			recorded[key] = value
+		}
+	}
+	sort.Strings(sensitiveInputs)
+	return recorded, sensitiveInputs
+}
+
+// isSensitiveOutput reports whether the recorded resource for a node marks
+// the named output sensitive.
+func (e *Executor) isSensitiveOutput(envState *types.EnvironmentState, node *graph.Node, name string) bool {
+	if envState == nil {
+		return false
+	}
+	e.stateMu.Lock()
+	defer e.stateMu.Unlock()
+
+	comp := envState.Components[node.Component]
+	if comp == nil {
+		return false
+	}
+	res := comp.Resources[resourceKey(node)]
+	return res != nil && res.IsSensitiveOutput(name)
+}
+
+// isSensitiveVariable reports whether a component declares a variable
+// sensitive.
+func (e *Executor) isSensitiveVariable(component, name string) bool {
+	for _, v := range e.options.SensitiveComponentVariables[component] {
+		if v == name {
+			return true
 		}
 	}
+	return false
+}
+
+// isSecretVariable reports whether a component variable was given as a secret
+// reference, whose resolved value must not be recorded in state.
+func (e *Executor) isSecretVariable(component, name string) bool {
+	source, ok := e.options.ComponentVariableSources[component][name].(string)
+	return ok && secrets.IsReference(source)
 }
 
 // getBuildImageForNode looks up the built image from build dependencies.
@@ -1852,6 +2184,16 @@ func (e *Executor) executeDestroy(ctx context.Context, change *planner.ResourceC
 		// Backward compatibility: try legacy name-only key
 		resourceState = compState.Resources[change.Node.Name]
 	}
+	var iacState []byte
+	if resourceState != nil {
+		var err error
+		if iacState, err = resourceState.LoadIaCState(); err != nil {
+			e.stateMu.Unlock()
+			result.Error = err
+			result.Success = false
+			return result
+		}
+	}
 
 	e.stateMu.Unlock()
 
@@ -1869,15 +2211,44 @@ func (e *Executor) executeDestroy(ctx context.Context, change *planner.ResourceC
 		Inputs:     change.Node.Inputs,
 	}
 
-	// Pass the stored IaC state so the plugin knows what to destroy
-	if resourceState != nil && len(resourceState.IaCState) > 0 {
-		runOpts.StateReader = bytes.NewReader(resourceState.IaCState)
+	// The hook that created the resource is matched against its recorded
+	// inputs, since resources being removed have none in the graph
+	var policy hookPolicy
+	hookNode := change.Node
+	if len(hookNode.Inputs) == 0 && resourceState != nil {
+		nodeCopy := *change.Node
+		nodeCopy.Inputs = resourceState.Inputs
+		hookNode = &nodeCopy
+	}
+	if module, err := e.matchHookModule(hookNode); err == nil {
+		policy = newHookPolicy(module)
 	}
 
-	// Execute destroy
-	if err := plugin.Destroy(ctx, runOpts); err != nil {
+	// Execute destroy, retrying transient failures as the hook module allows.
+	// The stored IaC state tells the plugin what to destroy and is passed
+	// afresh on each attempt.
+	err = e.runHookModule(ctx, change.Node, policy, "destroy", func(ctx context.Context) error {
+		if len(iacState) > 0 {
+			runOpts.StateReader = bytes.NewReader(iacState)
+		}
+		return plugin.Destroy(ctx, runOpts)
+	})
+	if err != nil {
 		result.Error = fmt.Errorf("destroy failed: %w", err)
 		result.Success = false
+
+		// An interrupted destroy may have removed some of the infrastructure,
+		// so the resource is kept but marked failed
+		if ctx.Err() != nil && resourceState != nil {
+			e.stateMu.Lock()
+			resourceState.Status = types.ResourceStatusFailed
+			resourceState.StatusReason = interruptedReason
+			resourceState.UpdatedAt = time.Now()
+			if err := e.saveStateLocked(envState); err != nil {
+				result.Error = fmt.Errorf("%w; %v", result.Error, err)
+			}
+			e.stateMu.Unlock()
+		}
 		return result
 	}
 
@@ -1899,7 +2270,10 @@ func (e *Executor) executeDestroy(ctx context.Context, change *planner.ResourceC
 		delete(envState.Components, change.Node.Component)
 	}
 
-	e.saveStateLocked(envState)
+	if err := e.saveStateLocked(envState); err != nil {
+		result.Error = fmt.Errorf("destroyed, but its removal wasn't recorded: %w", err)
+		result.Success = false
+	}
 	e.stateMu.Unlock()
 
 	return result
@@ -1915,7 +2289,7 @@ func (e *Executor) ExecuteParallel(ctx context.Context, plan *planner.Plan, g *g
 
 	// Store graph reference for service port lookups
 	e.graph = g
-	e.datacenterName = plan.Datacenter
+	e.beginExecution(plan.Datacenter)
 
 	result := &ExecutionResult{
 		Success:     true,
@@ -1947,7 +2321,15 @@ func (e *Executor) ExecuteParallel(ctx context.Context, plan *planner.Plan, g *g
 	// Mark as provisioning and flush so that inspect can see progress immediately
 	envState.Status = types.EnvironmentStatusProvisioning
 	envState.UpdatedAt = time.Now()
-	_ = e.stateManager.SaveEnvironment(ctx, plan.Datacenter, envState)
+	e.stateMu.Lock()
+	err = e.saveStateLocked(envState)
+	e.stateMu.Unlock()
+	if err != nil {
+		result.Success = false
+		result.Errors = append(result.Errors, err)
+		result.Duration = time.Since(startTime)
+		return result, nil
+	}
 
 	// Concurrency control
 	var mu sync.Mutex
@@ -1984,8 +2366,9 @@ func (e *Executor) ExecuteParallel(ctx context.Context, plan *planner.Plan, g *g
 	// Must be called with mu held. Uses two-step declaration for recursive self-reference.
 	var findAndLaunchReady func()
 	findAndLaunchReady = func() {
-		// Don't launch new nodes if context is cancelled
-		if ctx.Err() != nil {
+		// Don't launch new nodes if context is cancelled, a stop was
+		// requested, or state can no longer be saved
+		if ctx.Err() != nil || StopRequested(ctx) || e.stateSaveErr() != nil {
 			cancelled = true
 			return
 		}
@@ -2033,7 +2416,7 @@ func (e *Executor) ExecuteParallel(ctx context.Context, plan *planner.Plan, g *g
 							Inputs:    change.Node.Inputs,
 							UpdatedAt: time.Now(),
 						}
-						e.saveStateLocked(envState)
+						_ = e.saveStateLocked(envState) // Checked before launching nodes
 						e.stateMu.Unlock()
 
 						// Notify progress callback about the failure
@@ -2042,6 +2425,7 @@ func (e *Executor) ExecuteParallel(ctx context.Context, plan *planner.Plan, g *g
 								NodeID:   change.Node.ID,
 								NodeName: change.Node.Name,
 								NodeType: string(change.Node.Type),
+								Action:   change.Action,
 								Status:   "failed",
 								Message:  depErr.Error(),
 								Error:    depErr,
@@ -2081,23 +2465,24 @@ func (e *Executor) ExecuteParallel(ctx context.Context, plan *planner.Plan, g *g
 
 				go func(c *planner.ResourceChange) {
 					// Acquire semaphore (limits concurrency)
+					acquired := false
 					select {
 					case sem <- struct{}{}:
-						// Got semaphore
+						acquired = true
 					case <-ctx.Done():
-						// Context cancelled while waiting for semaphore
+					case <-stopChannel(ctx):
+					}
+					if !acquired || ctx.Err() != nil || StopRequested(ctx) || e.stateSaveErr() != nil {
+						// Cancelled, stopped or unable to save state while
+						// waiting for semaphore; the node is left pending and
+						// reported as not started
+						if acquired {
+							<-sem
+						}
 						wg.Done()
 						mu.Lock()
 						delete(inFlight, c.Node.ID)
-						failed[c.Node.ID] = true
-						result.NodeResults[c.Node.ID] = &NodeResult{
-							NodeID:  c.Node.ID,
-							Action:  c.Action,
-							Success: false,
-							Error:   ctx.Err(),
-						}
-						result.Failed++
-						result.Success = false
+						cancelled = true
 						mu.Unlock()
 						nodeFinished <- struct{}{}
 						return
@@ -2178,28 +2563,41 @@ func (e *Executor) ExecuteParallel(ctx context.Context, plan *planner.Plan, g *g
 
 	// Check if context was cancelled
 	mu.Lock()
-	if cancelled || ctx.Err() != nil {
-		// Mark remaining pending nodes as cancelled
+	if cancelled || ctx.Err() != nil || StopRequested(ctx) {
+		// Mark remaining pending nodes as not started
+		stopErr := interruptErr(ctx)
+		saveErr := e.stateSaveErr()
+		if saveErr != nil {
+			stopErr = saveErr
+		}
 		for id, change := range pending {
 			if !inFlight[id] && !completed[id] && !failed[id] {
 				result.NodeResults[id] = &NodeResult{
 					NodeID:  id,
 					Action:  change.Action,
 					Success: false,
-					Error:   ctx.Err(),
+					Error:   stopErr,
 				}
 				result.Failed++
 			}
 		}
 		result.Success = false
-		result.Errors = append(result.Errors, ctx.Err())
+		if saveErr == nil {
+			result.Errors = append(result.Errors, stopErr)
+		}
 		mu.Unlock()
 
-		// Still save state and return
+		// Still save state and return. The save uses a background context,
+		// since this one may be cancelled.
 		computeComponentStatuses(envState)
 		envState.Status = types.EnvironmentStatusFailed
 		envState.UpdatedAt = time.Now()
-		_ = e.stateManager.SaveEnvironment(ctx, plan.Datacenter, envState)
+		e.stateMu.Lock()
+		err := e.saveStateLocked(envState)
+		e.stateMu.Unlock()
+		if err != nil {
+			result.Errors = append(result.Errors, err)
+		}
 		result.Duration = time.Since(startTime)
 		return result, nil
 	}
@@ -2241,9 +2639,14 @@ func (e *Executor) ExecuteParallel(ctx context.Context, plan *planner.Plan, g *g
 	}
 	envState.UpdatedAt = time.Now()
 
-	// Save state
-	if err := e.stateManager.SaveEnvironment(ctx, plan.Datacenter, envState); err != nil {
-		result.Errors = append(result.Errors, fmt.Errorf("failed to save state: %w", err))
+	// Save state, even if the execution was interrupted. An execution whose
+	// state wasn't saved failed, since what it changed isn't recorded.
+	e.stateMu.Lock()
+	err = e.saveStateLocked(envState)
+	e.stateMu.Unlock()
+	if err != nil {
+		result.Success = false
+		result.Errors = append(result.Errors, err)
 	}
 
 	result.Duration = time.Since(startTime)
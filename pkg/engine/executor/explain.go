@@ -0,0 +1,151 @@
+package executor
+
+import (
+	"regexp"
+
+	"github.com/davidthor/arcctl/pkg/graph"
+)
+
+// HookExplanation describes how a node is matched to a datacenter hook.
+type HookExplanation struct {
+	NodeID    string
+	Component string
+	Name      string
+	Type      graph.NodeType
+
+	// Candidates holds every hook defined for the node's type, in the order
+	// they are tried
+	Candidates []HookCandidate
+
+	// Selected is the index of the first candidate whose 'when' condition
+	// matched, or -1 if none did
+	Selected int
+
+	// Module, Plugin, ModulePath, and ModuleInputs describe the module that
+	// would run for the node. They are empty if Error is set.
+	Module       string
+	Plugin       string
+	ModulePath   string
+	ModuleInputs map[string]interface{}
+
+	// Error is why no module would run for the node, including the message
+	// of a matched error hook
+	Error error
+}
+
+// HookCandidate describes a datacenter hook considered for a node.
+type HookCandidate struct {
+	When    string
+	Modules []string
+
+	// Evaluated is false for hooks after the one that matched, since their
+	// conditions are never checked
+	Evaluated bool
+	Matched   bool
+
+	// HCLError is set when the condition couldn't be evaluated as HCL and
+	// simplified string matching decided Matched
+	HCLError error
+
+	// Values holds the node inputs and datacenter variables the condition
+	// references, keyed by the reference (e.g. node.inputs.type)
+	Values map[string]interface{}
+
+	// Error is the evaluated message of an error hook
+	Error string
+}
+
+// whenRefPattern matches node input and variable references in a 'when' condition.
+var whenRefPattern = regexp.MustCompile(`\b(node\.inputs|variable|var)\.([A-Za-z_][A-Za-z0-9_-]*)`)
+
+// ExplainHooks reports, for every node in g, each candidate datacenter hook,
+// how its 'when' condition evaluated, and the module and module inputs the
+// node would be applied with. Nothing is applied and no state is read or
+// written. Component expressions that reference outputs of other resources
+// are left unresolved, since those resources haven't been applied.
+func (e *Executor) ExplainHooks(g *graph.Graph, envName string) ([]*HookExplanation, error) {
+	nodes, err := g.TopologicalSort()
+	if err != nil {
+		return nil, err
+	}
+
+	e.graph = g
+	var explanations []*HookExplanation
+	for _, node := range nodes {
+		e.resolveComponentExpressions(node, nil)
+		explanations = append(explanations, e.explainNode(node, envName))
+	}
+	return explanations, nil
+}
+
+func (e *Executor) explainNode(node *graph.Node, envName string) *HookExplanation {
+	explanation := &HookExplanation{
+		NodeID:    node.ID,
+		Component: node.Component,
+		Name:      node.Name,
+		Type:      node.Type,
+		Selected:  -1,
+	}
+
+	for i, hook := range e.getHooksForType(node.Type) {
+		candidate := HookCandidate{
+			When:   hook.When(),
+			Values: e.whenValues(hook.When(), node.Inputs),
+		}
+		for _, module := range hook.Modules() {
+			candidate.Modules = append(candidate.Modules, module.Name())
+		}
+
+		// Only hooks up to the first match are evaluated, as in matchHookModule
+		if explanation.Selected < 0 {
+			result := e.evaluateWhen(hook.When(), node.Inputs)
+			candidate.Evaluated = true
+			candidate.Matched = result.matched
+			candidate.HCLError = result.hclErr
+			if result.matched {
+				explanation.Selected = i
+				if msg := hook.Error(); msg != "" {
+					candidate.Error = e.evaluateErrorMessage(msg, node.Inputs)
+				}
+			}
+		}
+
+		explanation.Candidates = append(explanation.Candidates, candidate)
+	}
+
+	module, err := e.matchHookModule(node)
+	if err != nil {
+		explanation.Error = err
+		return explanation
+	}
+	modulePath, inputs, pluginName, err := e.ResolveHook(node, envName)
+	if err != nil {
+		explanation.Error = err
+		return explanation
+	}
+
+	explanation.Module = module.Name()
+	explanation.Plugin = pluginName
+	explanation.ModulePath = modulePath
+	explanation.ModuleInputs = inputs
+	return explanation
+}
+
+// whenValues returns the values of the node inputs and datacenter variables
+// referenced by a 'when' condition. Unset references are included as nil.
+func (e *Executor) whenValues(when string, inputs map[string]interface{}) map[string]interface{} {
+	matches := whenRefPattern.FindAllStringSubmatch(when, -1)
+	if len(matches) == 0 {
+		return nil
+	}
+
+	values := make(map[string]interface{}, len(matches))
+	for _, m := range matches {
+		if m[1] == "node.inputs" {
+			values[m[0]] = inputs[m[2]]
+		} else {
+			values[m[0]] = e.options.DatacenterVariables[m[2]]
+		}
+	}
+	return values
+}
@@ -0,0 +1,140 @@
+package executor
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	arcerrors "github.com/davidthor/arcctl/pkg/errors"
+	"github.com/davidthor/arcctl/pkg/graph"
+)
+
+const explainDatacenterHCL = `
+variable "region" {
+  type    = string
+  default = "us-east-1"
+}
+
+environment {
+  database {
+    when = node.inputs.type == "mysql" && variable.region == "eu-west-1"
+    module "mysql" {
+      build = "./modules/mysql"
+    }
+  }
+
+  database {
+    when  = element(split(":", node.inputs.type), 0) == "redis"
+    error = "Redis is not supported: ${node.inputs.type}"
+  }
+
+  database {
+    when = node.inputs.type != "sqlite"
+    module "db" {
+      plugin = "opentofu"
+      build  = "./modules/db"
+      inputs = {
+        tier = "small"
+      }
+    }
+  }
+
+  database {
+    module "unused" {
+      build = "./modules/unused"
+    }
+  }
+}
+`
+
+func newExplainTestExecutor(t *testing.T) *Executor {
+	t.Helper()
+
+	return NewExecutor(newMockStateManager(), newTestRegistry(), Options{
+		Datacenter:          loadTestDatacenter(t, explainDatacenterHCL),
+		DatacenterVariables: map[string]interface{}{"region": "us-east-1"},
+	})
+}
+
+func TestExplainHooks(t *testing.T) {
+	exec := newExplainTestExecutor(t)
+
+	main := graph.NewNode(graph.NodeTypeDatabase, "api", "main")
+	main.Inputs["type"] = "mysql"
+	cache := graph.NewNode(graph.NodeTypeDatabase, "api", "cache")
+	cache.Inputs["type"] = "redis:7"
+	worker := graph.NewNode(graph.NodeTypeDeployment, "api", "worker")
+
+	g := graph.NewGraph("staging", "dc")
+	for _, node := range []*graph.Node{main, cache, worker} {
+		_ = g.AddNode(node)
+	}
+
+	explanations, err := exec.ExplainHooks(g, "staging")
+	if err != nil {
+		t.Fatalf("ExplainHooks returned error: %v", err)
+	}
+	byID := make(map[string]*HookExplanation)
+	for _, explanation := range explanations {
+		byID[explanation.NodeID] = explanation
+	}
+
+	got := byID[main.ID]
+	if got == nil || len(got.Candidates) != 4 {
+		t.Fatalf("expected 4 candidates for %s, got %+v", main.ID, got)
+	}
+	first := got.Candidates[0]
+	if !first.Evaluated || first.Matched || first.HCLError != nil {
+		t.Errorf("expected first hook to be evaluated as HCL without matching, got %+v", first)
+	}
+	if first.Values["node.inputs.type"] != "mysql" || first.Values["variable.region"] != "us-east-1" {
+		t.Errorf("expected referenced values to be reported, got %v", first.Values)
+	}
+	if got.Candidates[1].Matched || !got.Candidates[2].Matched {
+		t.Errorf("expected the third hook to match, got %+v", got.Candidates)
+	}
+	if got.Candidates[3].Evaluated {
+		t.Error("expected hooks after the match not to be evaluated")
+	}
+	if got.Selected != 2 || got.Module != "db" || got.Plugin != "opentofu" || got.Error != nil {
+		t.Errorf("expected module db to be selected, got %+v", got)
+	}
+	if got.ModulePath != filepath.Join(filepath.Dir(exec.options.Datacenter.SourcePath()), "modules/db") || got.ModuleInputs["tier"] != "small" {
+		t.Errorf("expected resolved module path and inputs, got %q %v", got.ModulePath, got.ModuleInputs)
+	}
+
+	got = byID[cache.ID]
+	if got.Selected != 1 || got.Candidates[1].Error != "Redis is not supported: redis:7" {
+		t.Errorf("expected error hook to match with its evaluated message, got %+v", got.Candidates[1])
+	}
+	var hookErr *arcerrors.Error
+	if !errors.As(got.Error, &hookErr) || got.Module != "" {
+		t.Errorf("expected datacenter hook error and no module, got %v", got.Error)
+	}
+
+	got = byID[worker.ID]
+	if got.Selected != -1 || len(got.Candidates) != 0 || got.Error == nil || !strings.Contains(got.Error.Error(), "no hooks defined") {
+		t.Errorf("expected no hooks for deployment, got %+v", got)
+	}
+}
+
+func TestExplainHooks_ResolvesComponentVariables(t *testing.T) {
+	exec := newExplainTestExecutor(t)
+	exec.options.ComponentVariables = map[string]map[string]interface{}{
+		"api": {"engine": "mysql"},
+	}
+
+	node := graph.NewNode(graph.NodeTypeDatabase, "api", "main")
+	node.Inputs["type"] = "${{ variables.engine }}"
+	g := graph.NewGraph("staging", "dc")
+	_ = g.AddNode(node)
+
+	explanations, err := exec.ExplainHooks(g, "staging")
+	if err != nil {
+		t.Fatalf("ExplainHooks returned error: %v", err)
+	}
+	if v := explanations[0].Candidates[0].Values["node.inputs.type"]; v != "mysql" {
+		t.Errorf("expected condition to see the resolved variable, got %v", v)
+	}
+}
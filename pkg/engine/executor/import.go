@@ -0,0 +1,99 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/davidthor/arcctl/pkg/graph"
+	"github.com/davidthor/arcctl/pkg/iac"
+	"github.com/davidthor/arcctl/pkg/state/types"
+)
+
+// Import adopts existing infrastructure as the resource for a node: it runs
+// the matching datacenter hook's module in import mode and records the
+// resulting outputs and IaC state in envState as a ready resource, with the
+// inputs a deploy would record, so the next deploy of the node is a no-op.
+// envState is updated in memory only; saving it is up to the caller.
+func (e *Executor) Import(ctx context.Context, g *graph.Graph, node *graph.Node, envState *types.EnvironmentState, opts iac.ImportOptions) (*types.ResourceState, error) {
+	e.graph = g
+
+	// Nothing in g has run, so dependencies resolve from their recorded outputs
+	for _, n := range g.Nodes {
+		if len(n.Outputs) == 0 {
+			if outputs := recordedOutputs(n, envState); outputs != nil {
+				n.Outputs = outputs
+			}
+		}
+	}
+
+	// Resolve ${{ }} component expressions the same way an apply does
+	inputs, sensitiveInputs := e.resolveComponentExpressions(node, envState)
+
+	module, err := e.matchHookModule(node)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find matching hook: %w", err)
+	}
+	modulePath, moduleInputs, pluginName, err := e.ResolveHook(node, envState.Name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find matching hook: %w", err)
+	}
+
+	plugin, err := e.iacRegistry.Get(pluginName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get IaC plugin %q: %w", pluginName, err)
+	}
+	importer, ok := plugin.(iac.Importer)
+	if !ok {
+		return nil, fmt.Errorf("IaC plugin %q used by module %s doesn't support importing existing resources", pluginName, module.Name())
+	}
+
+	runOpts := iac.RunOptions{
+		ModuleSource: modulePath,
+		Inputs:       moduleInputs,
+		Environment:  map[string]string{},
+	}
+	importResult, err := importer.Import(ctx, runOpts, opts)
+	if err != nil {
+		return nil, fmt.Errorf("import failed: %w", err)
+	}
+
+	outputs := make(map[string]interface{})
+	for name, out := range importResult.Outputs {
+		outputs[name] = out.Value
+	}
+
+	now := time.Now()
+	res := &types.ResourceState{
+		Component:        node.Component,
+		Name:             node.Name,
+		Type:             string(node.Type),
+		CreatedAt:        now,
+		UpdatedAt:        now,
+		Hook:             string(node.Type),
+		Module:           module.Name(),
+		Status:           types.ResourceStatusReady,
+		Inputs:           inputs,
+		SensitiveInputs:  sensitiveInputs,
+		Outputs:          outputs,
+		SensitiveOutputs: importResult.SensitiveOutputs(),
+		IaCState:         importResult.State,
+		Attempt:          1,
+	}
+
+	if envState.Components == nil {
+		envState.Components = make(map[string]*types.ComponentState)
+	}
+	compState := envState.Components[node.Component]
+	if compState == nil {
+		compState = e.newComponentState(node.Component)
+		envState.Components[node.Component] = compState
+	}
+	if compState.Resources == nil {
+		compState.Resources = make(map[string]*types.ResourceState)
+	}
+	compState.Resources[resourceKey(node)] = res
+	computeComponentStatuses(envState)
+
+	return res, nil
+}
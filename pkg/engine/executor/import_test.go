@@ -0,0 +1,166 @@
+package executor
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/davidthor/arcctl/pkg/graph"
+	"github.com/davidthor/arcctl/pkg/iac"
+	"github.com/davidthor/arcctl/pkg/state/types"
+)
+
+// importPlugin implements iac.Importer and records what it was asked to import.
+type importPlugin struct {
+	mockPlugin
+	imported iac.ImportOptions
+	source   string
+	inputs   map[string]interface{}
+}
+
+func (p *importPlugin) Import(ctx context.Context, opts iac.RunOptions, imp iac.ImportOptions) (*iac.ApplyResult, error) {
+	p.imported = imp
+	p.source = opts.ModuleSource
+	p.inputs = opts.Inputs
+	return &iac.ApplyResult{
+		Outputs: map[string]iac.OutputValue{"url": {Value: "postgres://db"}},
+		State:   []byte(`{"imported":true}`),
+	}, nil
+}
+
+const importDatacenterHCL = `
+environment {
+  database {
+    when = node.inputs.type == "postgres"
+    module "db" {
+      plugin = "import-test"
+      build  = "./modules/db"
+      inputs = {
+        name = node.name
+      }
+    }
+  }
+
+  deployment {
+    module "app" {
+      plugin = "import-test"
+      build  = "./modules/app"
+      inputs = {
+        image = node.inputs.image
+      }
+    }
+  }
+
+  bucket {
+    module "bucket" {
+      plugin = "import-test-plain"
+      build  = "./modules/bucket"
+    }
+  }
+}
+`
+
+func newImportExecutor(t *testing.T, plugin *importPlugin) *Executor {
+	t.Helper()
+
+	registry := newTestRegistry()
+	registry.Register("import-test", func() (iac.Plugin, error) {
+		return plugin, nil
+	})
+	registry.Register("import-test-plain", func() (iac.Plugin, error) {
+		return &mockPlugin{name: "import-test-plain"}, nil
+	})
+
+	return NewExecutor(newMockStateManager(), registry, Options{Datacenter: loadTestDatacenter(t, importDatacenterHCL)})
+}
+
+func TestImport(t *testing.T) {
+	plugin := &importPlugin{}
+	exec := newImportExecutor(t, plugin)
+
+	node := graph.NewNode(graph.NodeTypeDatabase, "api", "main")
+	node.Inputs = map[string]interface{}{"type": "postgres"}
+	g := graph.NewGraph("staging", "test-dc")
+	if err := g.AddNode(node); err != nil {
+		t.Fatalf("AddNode failed: %v", err)
+	}
+	envState := &types.EnvironmentState{Name: "staging"}
+
+	res, err := exec.Import(context.Background(), g, node, envState, iac.ImportOptions{ID: "prod-db", Address: "aws_db_instance.main"})
+	if err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+
+	if plugin.imported.ID != "prod-db" || plugin.imported.Address != "aws_db_instance.main" {
+		t.Errorf("unexpected import options: %+v", plugin.imported)
+	}
+	if !strings.HasSuffix(plugin.source, "modules/db") {
+		t.Errorf("expected the hook's module to be run, got %q", plugin.source)
+	}
+	if res.Status != types.ResourceStatusReady || res.Module != "db" || res.Outputs["url"] != "postgres://db" {
+		t.Errorf("unexpected resource state: %+v", res)
+	}
+	comp := envState.Components["api"]
+	if comp == nil || comp.Resources["database.main"] != res || comp.Status != types.ResourceStatusReady {
+		t.Errorf("expected the resource to be added to the environment, got %+v", comp)
+	}
+}
+
+func TestImport_PluginWithoutImport(t *testing.T) {
+	exec := newImportExecutor(t, &importPlugin{})
+
+	node := graph.NewNode(graph.NodeTypeBucket, "api", "uploads")
+	g := graph.NewGraph("staging", "test-dc")
+	if err := g.AddNode(node); err != nil {
+		t.Fatalf("AddNode failed: %v", err)
+	}
+	envState := &types.EnvironmentState{Name: "staging"}
+
+	_, err := exec.Import(context.Background(), g, node, envState, iac.ImportOptions{ID: "uploads"})
+	if err == nil || !strings.Contains(err.Error(), "doesn't support importing") {
+		t.Errorf("expected an unsupported plugin error, got %v", err)
+	}
+	if len(envState.Components) != 0 {
+		t.Error("expected state to be unchanged after a failed import")
+	}
+}
+
+func TestImport_ResolvesRecordedDependencyOutputs(t *testing.T) {
+	plugin := &importPlugin{}
+	exec := newImportExecutor(t, plugin)
+
+	// A freshly built graph, as on import, where no node has run
+	db := graph.NewNode(graph.NodeTypeDatabase, "api", "main")
+	build := graph.NewNode(graph.NodeTypeDockerBuild, "api", "web")
+	node := graph.NewNode(graph.NodeTypeDeployment, "api", "web")
+	node.SetInput("image", "${{ builds.web.image }}")
+	node.SetInput("environment", map[string]interface{}{"DATABASE_URL": "${{ databases.main.url }}"})
+	g := graph.NewGraph("staging", "test-dc")
+	for _, n := range []*graph.Node{db, build, node} {
+		if err := g.AddNode(n); err != nil {
+			t.Fatalf("AddNode failed: %v", err)
+		}
+	}
+	envState := &types.EnvironmentState{
+		Name: "staging",
+		Components: map[string]*types.ComponentState{
+			"api": {Resources: map[string]*types.ResourceState{
+				"database.main":   {Outputs: map[string]interface{}{"url": "postgres://db:5432/main"}},
+				"dockerBuild.web": {Outputs: map[string]interface{}{"image": "registry.example.com/web:abc123"}},
+			}},
+		},
+	}
+
+	res, err := exec.Import(context.Background(), g, node, envState, iac.ImportOptions{ID: "web"})
+	if err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+
+	if got := plugin.inputs["image"]; got != "registry.example.com/web:abc123" {
+		t.Errorf("module input image: got %v, want the recorded build output", got)
+	}
+	env, _ := res.Inputs["environment"].(map[string]interface{})
+	if env["DATABASE_URL"] != "postgres://db:5432/main" {
+		t.Errorf("recorded inputs should match a deploy's, got %+v", res.Inputs)
+	}
+}
@@ -0,0 +1,50 @@
+package executor
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrStopped is the error recorded for resources that weren't started because
+// the execution was stopped.
+var ErrStopped = errors.New("not started: execution was stopped")
+
+// interruptedReason is the status reason recorded for resources whose apply
+// or destroy was cancelled before it finished.
+const interruptedReason = "interrupted before the operation finished, so the infrastructure may be partially changed. " +
+	"Check the resource, then re-run the command to retry it (use --resume when deploying)"
+
+type stopKey struct{}
+
+// WithStop returns a context that stops an execution gracefully once stop is
+// closed: no further resources are started, but those already being applied
+// or destroyed run to completion and have their state saved. Cancelling the
+// context itself interrupts in-flight resources, which are marked failed.
+func WithStop(ctx context.Context, stop <-chan struct{}) context.Context {
+	return context.WithValue(ctx, stopKey{}, stop)
+}
+
+// StopRequested reports whether the execution using ctx has been asked to stop.
+func StopRequested(ctx context.Context) bool {
+	select {
+	case <-stopChannel(ctx):
+		return true
+	default:
+		return false
+	}
+}
+
+// stopChannel returns the channel closed when a stop is requested, or nil if
+// ctx has none.
+func stopChannel(ctx context.Context) <-chan struct{} {
+	stop, _ := ctx.Value(stopKey{}).(<-chan struct{})
+	return stop
+}
+
+// interruptErr returns why an execution ended early.
+func interruptErr(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return ErrStopped
+}
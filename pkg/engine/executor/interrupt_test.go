@@ -0,0 +1,199 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/davidthor/arcctl/pkg/engine/planner"
+	"github.com/davidthor/arcctl/pkg/graph"
+	"github.com/davidthor/arcctl/pkg/iac"
+	"github.com/davidthor/arcctl/pkg/schema/datacenter"
+	"github.com/davidthor/arcctl/pkg/state/types"
+)
+
+// blockingPlugin implements iac.Plugin with applies that block until released
+// or cancelled.
+type blockingPlugin struct {
+	mockPlugin
+	started chan struct{}
+	release chan struct{}
+}
+
+func (p *blockingPlugin) Apply(ctx context.Context, opts iac.RunOptions) (*iac.ApplyResult, error) {
+	p.started <- struct{}{}
+	select {
+	case <-p.release:
+		return &iac.ApplyResult{State: []byte(`{"applied": true}`)}, nil
+	case <-ctx.Done():
+		// Like OpenTofu, save what was created before stopping
+		return &iac.ApplyResult{State: []byte(`{"partial": true}`)}, ctx.Err()
+	}
+}
+
+const interruptDatacenterHCL = `
+environment {
+  database {
+    module "db" {
+      plugin = "interrupt-test"
+      build  = "./modules/db"
+    }
+  }
+}
+`
+
+// newInterruptTest returns an executor using plugin, and a plan creating the
+// databases "first" and "second", where second depends on first.
+func newInterruptTest(t *testing.T, plugin *blockingPlugin) (*Executor, *mockStateManager, *planner.Plan, *graph.Graph) {
+	t.Helper()
+
+	dc, err := datacenter.NewLoader().LoadFromBytes([]byte(interruptDatacenterHCL), "datacenter.hcl")
+	if err != nil {
+		t.Fatalf("failed to load datacenter: %v", err)
+	}
+
+	registry := newTestRegistry()
+	registry.Register("interrupt-test", func() (iac.Plugin, error) {
+		return plugin, nil
+	})
+	sm := newMockStateManager()
+	exec := NewExecutor(sm, registry, Options{Parallelism: 2, Datacenter: dc})
+
+	first := graph.NewNode(graph.NodeTypeDatabase, "api", "first")
+	second := graph.NewNode(graph.NodeTypeDatabase, "api", "second")
+	second.AddDependency(first.ID)
+
+	g := graph.NewGraph("staging", "dc")
+	_ = g.AddNode(first)
+	_ = g.AddNode(second)
+
+	plan := &planner.Plan{
+		Environment: "staging",
+		Datacenter:  "dc",
+		Changes: []*planner.ResourceChange{
+			{Node: first, Action: planner.ActionCreate},
+			{Node: second, Action: planner.ActionCreate},
+		},
+		ToCreate: 2,
+	}
+
+	return exec, sm, plan, g
+}
+
+func TestExecuteParallel_StopFinishesInFlightResources(t *testing.T) {
+	plugin := &blockingPlugin{started: make(chan struct{}, 2), release: make(chan struct{})}
+	exec, sm, plan, g := newInterruptTest(t, plugin)
+
+	stop := make(chan struct{})
+	ctx := WithStop(context.Background(), stop)
+
+	done := make(chan *ExecutionResult)
+	go func() {
+		result, _ := exec.ExecuteParallel(ctx, plan, g)
+		done <- result
+	}()
+
+	// Only first can start, since second depends on it
+	<-plugin.started
+	close(stop)
+	close(plugin.release)
+
+	var result *ExecutionResult
+	select {
+	case result = <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("execution did not finish after stop")
+	}
+
+	if result.Success {
+		t.Error("expected a stopped execution to be unsuccessful")
+	}
+	if r := result.NodeResults["api/database/first"]; r == nil || !r.Success {
+		t.Errorf("expected in-flight resource to finish, got %+v", r)
+	}
+	if r := result.NodeResults["api/database/second"]; r == nil || !errors.Is(r.Error, ErrStopped) {
+		t.Errorf("expected pending resource not to start, got %+v", r)
+	}
+	if len(plugin.started) != 0 {
+		t.Error("expected no resources to start after stop")
+	}
+
+	envState := sm.environments["dc/staging"]
+	res := envState.Components["api"].Resources["database.first"]
+	if res.Status != types.ResourceStatusReady || string(res.IaCState) != `{"applied": true}` {
+		t.Errorf("expected finished resource to be saved, got %+v", res)
+	}
+	if envState.Status != types.EnvironmentStatusFailed {
+		t.Errorf("expected environment to be failed, got %s", envState.Status)
+	}
+}
+
+func TestExecuteParallel_CancelMarksInFlightResourcesInterrupted(t *testing.T) {
+	plugin := &blockingPlugin{started: make(chan struct{}, 2), release: make(chan struct{})}
+	exec, sm, plan, g := newInterruptTest(t, plugin)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan *ExecutionResult)
+	go func() {
+		result, _ := exec.ExecuteParallel(ctx, plan, g)
+		done <- result
+	}()
+
+	<-plugin.started
+	cancel()
+
+	var result *ExecutionResult
+	select {
+	case result = <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("execution did not finish after cancel")
+	}
+
+	if r := result.NodeResults["api/database/first"]; r == nil || r.Success {
+		t.Errorf("expected in-flight resource to fail, got %+v", r)
+	}
+
+	res := sm.environments["dc/staging"].Components["api"].Resources["database.first"]
+	if res.Status != types.ResourceStatusFailed || res.StatusReason != interruptedReason {
+		t.Errorf("expected resource to be marked interrupted, got %s %q", res.Status, res.StatusReason)
+	}
+	if string(res.IaCState) != `{"partial": true}` {
+		t.Errorf("expected partial IaC state to be saved, got %s", res.IaCState)
+	}
+}
+
+func TestExecute_StopBeforeStart(t *testing.T) {
+	plugin := &blockingPlugin{started: make(chan struct{}, 2), release: make(chan struct{})}
+	exec, _, plan, g := newInterruptTest(t, plugin)
+
+	stop := make(chan struct{})
+	close(stop)
+
+	result, err := exec.Execute(WithStop(context.Background(), stop), plan, g)
+	if err != nil {
+		t.Fatalf("Execute returned error: %v", err)
+	}
+	if result.Success || len(result.Errors) == 0 || !errors.Is(result.Errors[0], ErrStopped) {
+		t.Errorf("expected execution to stop, got %+v", result)
+	}
+	if len(plugin.started) != 0 {
+		t.Error("expected no resources to start")
+	}
+}
+
+func TestStopRequested(t *testing.T) {
+	if StopRequested(context.Background()) {
+		t.Error("expected no stop without WithStop")
+	}
+
+	stop := make(chan struct{})
+	ctx := WithStop(context.Background(), stop)
+	if StopRequested(ctx) {
+		t.Error("expected no stop before the channel is closed")
+	}
+	close(stop)
+	if !StopRequested(ctx) {
+		t.Error("expected stop once the channel is closed")
+	}
+}
@@ -417,7 +417,7 @@ func TestSortStrings(t *testing.T) {
 // --- Datacenter hook tests ---
 
 func TestGetHooksForType_Observability(t *testing.T) {
-	dc := loadTestDatacenter(t)
+	dc := loadLocalTemplateDatacenter(t)
 	if dc == nil {
 		t.Skip("skipping: test datacenter not available")
 	}
@@ -472,7 +472,7 @@ func assertNotContains(t *testing.T, haystack, needle string) {
 	}
 }
 
-func loadTestDatacenter(t *testing.T) datacenter.Datacenter {
+func loadLocalTemplateDatacenter(t *testing.T) datacenter.Datacenter {
 	t.Helper()
 	loader := datacenter.NewLoader()
 	dc, err := loader.Load("../../../official-templates/local/datacenter.dc")
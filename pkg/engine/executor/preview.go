@@ -0,0 +1,134 @@
+package executor
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	"github.com/davidthor/arcctl/pkg/engine/planner"
+	"github.com/davidthor/arcctl/pkg/graph"
+	"github.com/davidthor/arcctl/pkg/iac"
+	"github.com/davidthor/arcctl/pkg/state/types"
+)
+
+// Preview runs iac.Plugin.Preview for the hook module behind every create,
+// update, and replace in the plan and attaches the reported infrastructure
+// changes to each planner.ResourceChange. Nothing is applied and no state is
+// written.
+//
+// Inputs are resolved as they are on apply, from the outputs of dependencies
+// recorded in the environment's state. References to dependencies that are
+// yet to be deployed can't be resolved until apply time. Failures to preview
+// an individual change are recorded on the change's PreviewError rather than
+// aborting.
+func (e *Executor) Preview(ctx context.Context, plan *planner.Plan, g *graph.Graph) error {
+	envState, err := e.stateManager.GetEnvironment(ctx, plan.Datacenter, plan.Environment)
+	if err != nil {
+		envState = &types.EnvironmentState{Name: plan.Environment, Datacenter: plan.Datacenter}
+	}
+
+	// Resolve against copies of the nodes, so that the graph executed
+	// afterwards still resolves its inputs from the outputs produced then
+	e.graph = previewGraph(g, envState)
+
+	for _, change := range plan.Changes {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if change.Node == nil {
+			continue
+		}
+
+		switch change.Action {
+		case planner.ActionCreate, planner.ActionUpdate, planner.ActionReplace:
+		default:
+			continue
+		}
+
+		changes, err := e.previewChange(ctx, change, envState)
+		if err != nil {
+			change.PreviewError = err
+			continue
+		}
+		change.IaCChanges = changes
+
+		// The plugin knows better than an input diff whether the change
+		// can be made in place
+		if change.Action == planner.ActionUpdate && change.RequiresReplacement() {
+			change.Action = planner.ActionReplace
+			change.Reason = "infrastructure requires replacement"
+		}
+	}
+
+	return nil
+}
+
+func (e *Executor) previewChange(ctx context.Context, change *planner.ResourceChange, envState *types.EnvironmentState) ([]iac.ResourceChange, error) {
+	node := e.graph.Nodes[change.Node.ID]
+	if node == nil {
+		node = previewNode(change.Node, envState)
+	}
+	e.resolveComponentExpressions(node, envState)
+
+	modulePath, moduleInputs, pluginName, err := e.ResolveHook(node, envState.Name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find matching hook: %w", err)
+	}
+
+	plugin, err := e.iacRegistry.Get(pluginName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get IaC plugin %q: %w", pluginName, err)
+	}
+
+	runOpts := iac.RunOptions{
+		ModuleSource: modulePath,
+		Inputs:       moduleInputs,
+		Environment:  map[string]string{},
+	}
+
+	// Pass the stored IaC state so the plugin can diff against what exists
+	if change.CurrentState != nil {
+		iacState, err := change.CurrentState.LoadIaCState()
+		if err != nil {
+			return nil, err
+		}
+		if len(iacState) > 0 {
+			runOpts.StateReader = bytes.NewReader(iacState)
+		}
+	}
+
+	previewResult, err := plugin.Preview(ctx, runOpts)
+	if err != nil {
+		return nil, fmt.Errorf("preview failed: %w", err)
+	}
+
+	return previewResult.Changes, nil
+}
+
+// previewGraph returns a copy of g to resolve inputs against for a preview.
+// Nodes that haven't run yet carry the outputs recorded for them in envState.
+func previewGraph(g *graph.Graph, envState *types.EnvironmentState) *graph.Graph {
+	if g == nil {
+		return graph.NewGraph(envState.Name, envState.Datacenter)
+	}
+	preview := graph.NewGraph(g.Environment, g.Datacenter)
+	preview.ComponentDependencies = g.ComponentDependencies
+	for id, node := range g.Nodes {
+		preview.Nodes[id] = previewNode(node, envState)
+	}
+	return preview
+}
+
+// previewNode copies a node, so that resolving its inputs leaves the original
+// untouched, and fills in the outputs recorded for it in envState.
+func previewNode(node *graph.Node, envState *types.EnvironmentState) *graph.Node {
+	clone := *node
+	clone.Inputs = make(map[string]interface{}, len(node.Inputs))
+	for k, v := range node.Inputs {
+		clone.Inputs[k] = v
+	}
+	if len(clone.Outputs) == 0 {
+		clone.Outputs = recordedOutputs(node, envState)
+	}
+	return &clone
+}
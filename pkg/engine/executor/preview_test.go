@@ -0,0 +1,202 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/davidthor/arcctl/pkg/engine/planner"
+	"github.com/davidthor/arcctl/pkg/graph"
+	"github.com/davidthor/arcctl/pkg/iac"
+	"github.com/davidthor/arcctl/pkg/state/types"
+)
+
+// previewPlugin implements iac.Plugin and returns canned preview results.
+type previewPlugin struct {
+	mockPlugin
+	changes    []iac.ResourceChange
+	previewErr error
+	sawState   bool
+	inputs     map[string]interface{}
+}
+
+func (p *previewPlugin) Preview(ctx context.Context, opts iac.RunOptions) (*iac.PreviewResult, error) {
+	p.sawState = opts.StateReader != nil
+	p.inputs = opts.Inputs
+	if p.previewErr != nil {
+		return nil, p.previewErr
+	}
+	return &iac.PreviewResult{Changes: p.changes}, nil
+}
+
+const previewDatacenterHCL = `
+environment {
+  database {
+    module "db" {
+      plugin = "preview-test"
+      build  = "./modules/db"
+      inputs = {
+        name = node.name
+      }
+    }
+
+    outputs = {
+      url = module.db.url
+    }
+  }
+
+  deployment {
+    module "app" {
+      plugin = "preview-test"
+      build  = "./modules/app"
+      inputs = {
+        image = node.inputs.image
+      }
+    }
+  }
+}
+`
+
+func newPreviewExecutor(t *testing.T, plugin *previewPlugin) *Executor {
+	t.Helper()
+
+	registry := newTestRegistry()
+	registry.Register("preview-test", func() (iac.Plugin, error) {
+		return plugin, nil
+	})
+
+	return NewExecutor(newMockStateManager(), registry, Options{Datacenter: loadTestDatacenter(t, previewDatacenterHCL)})
+}
+
+func TestPreview_AttachesChangesAndDetectsReplacement(t *testing.T) {
+	plugin := &previewPlugin{
+		changes: []iac.ResourceChange{
+			{
+				ResourceID:   "aws_db_instance.main",
+				ResourceType: "aws_db_instance",
+				Action:       iac.ActionReplace,
+				Diff: []iac.PropertyDiff{
+					{Path: "engine_version", OldValue: "15", NewValue: "16"},
+				},
+			},
+		},
+	}
+	exec := newPreviewExecutor(t, plugin)
+
+	node := graph.NewNode(graph.NodeTypeDatabase, "api", "main")
+	node.SetInput("type", "postgres:16")
+	plan := &planner.Plan{
+		Environment: "staging",
+		Datacenter:  "dc",
+		Changes: []*planner.ResourceChange{
+			{
+				Node:   node,
+				Action: planner.ActionUpdate,
+				CurrentState: &types.ResourceState{
+					Name:     "main",
+					Type:     "database",
+					IaCState: []byte(`{}`),
+				},
+			},
+		},
+		ToUpdate: 1,
+	}
+
+	if err := exec.Preview(context.Background(), plan, nil); err != nil {
+		t.Fatalf("Preview failed: %v", err)
+	}
+
+	change := plan.Changes[0]
+	if change.PreviewError != nil {
+		t.Fatalf("unexpected preview error: %v", change.PreviewError)
+	}
+	if len(change.IaCChanges) != 1 || len(change.IaCChanges[0].Diff) != 1 {
+		t.Fatalf("expected IaC changes to be attached, got %+v", change.IaCChanges)
+	}
+	if change.Action != planner.ActionReplace {
+		t.Errorf("Action: got %s, want %s", change.Action, planner.ActionReplace)
+	}
+	if !plugin.sawState {
+		t.Error("expected stored IaC state to be passed to the plugin")
+	}
+}
+
+func TestPreview_RecordsErrorsPerChange(t *testing.T) {
+	plugin := &previewPlugin{previewErr: errors.New("boom")}
+	exec := newPreviewExecutor(t, plugin)
+
+	plan := &planner.Plan{
+		Environment: "staging",
+		Changes: []*planner.ResourceChange{
+			{Node: graph.NewNode(graph.NodeTypeDatabase, "api", "main"), Action: planner.ActionCreate},
+			{Node: graph.NewNode(graph.NodeTypeDatabase, "api", "old"), Action: planner.ActionNoop},
+		},
+		ToCreate: 1,
+		NoChange: 1,
+	}
+
+	if err := exec.Preview(context.Background(), plan, nil); err != nil {
+		t.Fatalf("Preview failed: %v", err)
+	}
+
+	if plan.Changes[0].PreviewError == nil {
+		t.Error("expected preview error to be recorded on the change")
+	}
+	if plan.Changes[1].PreviewError != nil || plan.Changes[1].IaCChanges != nil {
+		t.Error("noop changes should not be previewed")
+	}
+}
+
+func TestPreview_ResolvesExpressionsFromRecordedOutputs(t *testing.T) {
+	plugin := &previewPlugin{}
+	exec := newPreviewExecutor(t, plugin)
+
+	// The build is unchanged, so it won't run before the deployment is
+	// applied; its image comes from the outputs recorded in state
+	build := graph.NewNode(graph.NodeTypeDockerBuild, "api", "web")
+	deployment := graph.NewNode(graph.NodeTypeDeployment, "api", "web")
+	deployment.SetInput("image", "${{ builds.web.image }}")
+	g := graph.NewGraph("staging", "dc")
+	for _, node := range []*graph.Node{build, deployment} {
+		if err := g.AddNode(node); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	exec.stateManager.(*mockStateManager).environments["dc/staging"] = &types.EnvironmentState{
+		Name: "staging",
+		Components: map[string]*types.ComponentState{
+			"api": {Resources: map[string]*types.ResourceState{
+				"dockerBuild.web": {Outputs: map[string]interface{}{"image": "registry.example.com/web:abc123"}},
+			}},
+		},
+	}
+
+	plan := &planner.Plan{
+		Environment: "staging",
+		Datacenter:  "dc",
+		Changes: []*planner.ResourceChange{
+			{Node: build, Action: planner.ActionNoop},
+			{Node: deployment, Action: planner.ActionCreate},
+		},
+		ToCreate: 1,
+		NoChange: 1,
+	}
+
+	if err := exec.Preview(context.Background(), plan, g); err != nil {
+		t.Fatalf("Preview failed: %v", err)
+	}
+	if err := plan.Changes[1].PreviewError; err != nil {
+		t.Fatalf("unexpected preview error: %v", err)
+	}
+
+	if got := plugin.inputs["image"]; got != "registry.example.com/web:abc123" {
+		t.Errorf("image input: got %v, want the recorded build output", got)
+	}
+	if got := deployment.Inputs["image"]; got != "${{ builds.web.image }}" {
+		t.Errorf("graph node inputs should be left for apply to resolve, got %v", got)
+	}
+	if len(build.Outputs) != 0 {
+		t.Error("graph node outputs should not be seeded by a preview")
+	}
+}
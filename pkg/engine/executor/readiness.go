@@ -0,0 +1,248 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/davidthor/arcctl/pkg/graph"
+)
+
+// readyCheckOutput is the hook module output that tells the executor how to
+// check that a resource is ready before its dependents are started.
+const readyCheckOutput = "ready_check"
+
+const (
+	// defaultReadinessTimeout is how long a resource is given to pass its
+	// readiness check when no timeout is configured.
+	defaultReadinessTimeout = 5 * time.Minute
+
+	// defaultReadinessInterval and defaultReadinessAttemptTimeout apply when
+	// the resource declares no readiness_probe timings.
+	defaultReadinessInterval       = 2 * time.Second
+	defaultReadinessAttemptTimeout = 5 * time.Second
+)
+
+// errReadinessStopped is returned when the execution is stopped while a
+// resource is waiting to pass its readiness check.
+var errReadinessStopped = errors.New("stopped waiting for the resource to be ready: execution was stopped")
+
+// readyCheck is a parsed ready_check output. Exactly one of url, tcp, and
+// command is set.
+type readyCheck struct {
+	url     string // http:// or https:// URL that must respond with 2xx or 3xx
+	tcp     string // host:port that must accept connections
+	command []string
+	timeout time.Duration // Overrides the executor's readiness timeout
+}
+
+// parseReadyCheck parses a ready_check output, which is a URL string
+// ("https://..." or "tcp://host:port"), a command as a list of strings, or an
+// object with one of url, tcp, or command and an optional timeout.
+func parseReadyCheck(value interface{}) (*readyCheck, error) {
+	switch v := value.(type) {
+	case string:
+		return parseReadyCheckURL(v)
+	case []interface{}, []string:
+		command := toStringSlice(v)
+		if len(command) == 0 {
+			return nil, fmt.Errorf("ready_check command is empty")
+		}
+		return &readyCheck{command: command}, nil
+	case map[string]interface{}:
+		var check *readyCheck
+		var err error
+		switch {
+		case v["url"] != nil:
+			check, err = parseReadyCheckURL(fmt.Sprintf("%v", v["url"]))
+		case v["tcp"] != nil:
+			check = &readyCheck{tcp: strings.TrimPrefix(fmt.Sprintf("%v", v["tcp"]), "tcp://")}
+		case v["command"] != nil:
+			check, err = parseReadyCheck(v["command"])
+		default:
+			return nil, fmt.Errorf("ready_check must set one of url, tcp, or command")
+		}
+		if err != nil {
+			return nil, err
+		}
+		if timeout, ok := v["timeout"].(string); ok && timeout != "" {
+			d, err := time.ParseDuration(timeout)
+			if err != nil || d <= 0 {
+				return nil, fmt.Errorf("invalid ready_check timeout %q", timeout)
+			}
+			check.timeout = d
+		}
+		return check, nil
+	default:
+		return nil, fmt.Errorf("unsupported ready_check value of type %T", value)
+	}
+}
+
+func parseReadyCheckURL(s string) (*readyCheck, error) {
+	switch {
+	case strings.HasPrefix(s, "http://"), strings.HasPrefix(s, "https://"):
+		return &readyCheck{url: s}, nil
+	case strings.HasPrefix(s, "tcp://"):
+		return &readyCheck{tcp: strings.TrimPrefix(s, "tcp://")}, nil
+	default:
+		return nil, fmt.Errorf("ready_check %q must be an http://, https://, or tcp:// URL", s)
+	}
+}
+
+// String describes the check for progress messages.
+func (c *readyCheck) String() string {
+	switch {
+	case c.url != "":
+		return c.url
+	case c.tcp != "":
+		return "tcp://" + c.tcp
+	default:
+		return strings.Join(c.command, " ")
+	}
+}
+
+// run performs the check once.
+func (c *readyCheck) run(ctx context.Context, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	switch {
+	case c.url != "":
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.url, nil)
+		if err != nil {
+			return err
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		if resp.StatusCode >= 400 {
+			return fmt.Errorf("GET %s returned %s", c.url, resp.Status)
+		}
+		return nil
+	case c.tcp != "":
+		var d net.Dialer
+		conn, err := d.DialContext(ctx, "tcp", c.tcp)
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	default:
+		out, err := exec.CommandContext(ctx, c.command[0], c.command[1:]...).CombinedOutput()
+		if err != nil {
+			if msg := strings.TrimSpace(string(out)); msg != "" {
+				return fmt.Errorf("%w: %s", err, msg)
+			}
+			return err
+		}
+		return nil
+	}
+}
+
+// waitForReady runs check until it passes, using the timings of the node's
+// readiness_probe, and fails once the readiness timeout passes or the
+// execution is stopped.
+func (e *Executor) waitForReady(ctx context.Context, node *graph.Node, check *readyCheck) error {
+	timeout := check.timeout
+	if timeout == 0 {
+		timeout = e.options.ReadinessTimeout
+	}
+	if timeout <= 0 {
+		timeout = defaultReadinessTimeout
+	}
+
+	probe, _ := node.Inputs["readiness_probe"].(map[string]interface{})
+	interval := secondsInput(probe, "period_seconds", defaultReadinessInterval)
+	attemptTimeout := secondsInput(probe, "timeout_seconds", defaultReadinessAttemptTimeout)
+	initialDelay := secondsInput(probe, "initial_delay_seconds", 0)
+	successes := intInput(probe, "success_threshold")
+
+	if e.options.OnProgress != nil {
+		e.options.OnProgress(ProgressEvent{
+			NodeID:   node.ID,
+			NodeName: node.Name,
+			NodeType: string(node.Type),
+			Status:   "running",
+			Message:  fmt.Sprintf("waiting for %s to be ready", check),
+		})
+	}
+
+	waitCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	wait := initialDelay
+	passed := 0
+	var lastErr error
+	for {
+		if StopRequested(ctx) {
+			return errReadinessStopped
+		}
+		select {
+		case <-time.After(wait):
+		case <-stopChannel(ctx):
+			return errReadinessStopped
+		case <-waitCtx.Done():
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if lastErr == nil {
+				lastErr = errors.New("no check completed")
+			}
+			return fmt.Errorf("%s not ready after %s: %w", check, timeout, lastErr)
+		}
+		wait = interval
+
+		if err := check.run(waitCtx, attemptTimeout); err != nil {
+			lastErr = err
+			passed = 0
+			continue
+		}
+		passed++
+		if passed >= successes {
+			return nil
+		}
+	}
+}
+
+// secondsInput reads a number of seconds from a probe input, returning def
+// if it is unset.
+func secondsInput(probe map[string]interface{}, key string, def time.Duration) time.Duration {
+	if seconds := intInput(probe, key); seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	return def
+}
+
+// intInput reads a whole number from a probe input, which is a float64 once
+// inputs have been round-tripped through state.
+func intInput(probe map[string]interface{}, key string) int {
+	switch v := probe[key].(type) {
+	case int:
+		return v
+	case int64:
+		return int(v)
+	case float64:
+		return int(v)
+	}
+	return 0
+}
+
+func toStringSlice(value interface{}) []string {
+	switch v := value.(type) {
+	case []string:
+		return v
+	case []interface{}:
+		result := make([]string, 0, len(v))
+		for _, item := range v {
+			result = append(result, fmt.Sprintf("%v", item))
+		}
+		return result
+	}
+	return nil
+}
@@ -0,0 +1,213 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/davidthor/arcctl/pkg/engine/planner"
+	"github.com/davidthor/arcctl/pkg/graph"
+	"github.com/davidthor/arcctl/pkg/iac"
+	"github.com/davidthor/arcctl/pkg/schema/datacenter"
+	"github.com/davidthor/arcctl/pkg/state/types"
+)
+
+func TestParseReadyCheck(t *testing.T) {
+	tests := []struct {
+		name  string
+		value interface{}
+		want  readyCheck
+	}{
+		{"url", "https://api.example.com/healthz", readyCheck{url: "https://api.example.com/healthz"}},
+		{"tcp", "tcp://db.internal:5432", readyCheck{tcp: "db.internal:5432"}},
+		{"command", []interface{}{"pg_isready", "-h", "db"}, readyCheck{command: []string{"pg_isready", "-h", "db"}}},
+		{
+			"object with timeout",
+			map[string]interface{}{"url": "http://api:8080/ready", "timeout": "10m"},
+			readyCheck{url: "http://api:8080/ready", timeout: 10 * time.Minute},
+		},
+		{
+			"object with tcp",
+			map[string]interface{}{"tcp": "db:5432"},
+			readyCheck{tcp: "db:5432"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			check, err := parseReadyCheck(tt.value)
+			if err != nil {
+				t.Fatalf("parseReadyCheck returned error: %v", err)
+			}
+			if check.url != tt.want.url || check.tcp != tt.want.tcp || check.timeout != tt.want.timeout ||
+				strings.Join(check.command, " ") != strings.Join(tt.want.command, " ") {
+				t.Errorf("got %+v, want %+v", *check, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseReadyCheck_Invalid(t *testing.T) {
+	for _, value := range []interface{}{
+		"api.example.com",
+		[]interface{}{},
+		map[string]interface{}{"timeout": "1m"},
+		map[string]interface{}{"url": "http://api", "timeout": "soon"},
+		42,
+	} {
+		if _, err := parseReadyCheck(value); err == nil {
+			t.Errorf("expected error for %#v", value)
+		}
+	}
+}
+
+func TestWaitForReady_PassesOnceHealthy(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests < 2 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	e := &Executor{}
+	node := graph.NewNode(graph.NodeTypeDeployment, "api", "api")
+	node.Inputs["readiness_probe"] = map[string]interface{}{"period_seconds": 1}
+
+	if err := e.waitForReady(context.Background(), node, &readyCheck{url: server.URL}); err != nil {
+		t.Fatalf("expected resource to become ready, got %v", err)
+	}
+	if requests != 2 {
+		t.Errorf("expected 2 checks, got %d", requests)
+	}
+}
+
+func TestWaitForReady_Timeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	e := &Executor{}
+	node := graph.NewNode(graph.NodeTypeDeployment, "api", "api")
+
+	err := e.waitForReady(context.Background(), node, &readyCheck{url: server.URL, timeout: 50 * time.Millisecond})
+	if err == nil {
+		t.Fatal("expected readiness check to time out")
+	}
+	if !strings.Contains(err.Error(), "not ready after 50ms") || !strings.Contains(err.Error(), "503") {
+		t.Errorf("expected timeout error with last failure, got %v", err)
+	}
+}
+
+func TestWaitForReady_Stop(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	e := &Executor{}
+	node := graph.NewNode(graph.NodeTypeDeployment, "api", "api")
+	node.Inputs["readiness_probe"] = map[string]interface{}{"period_seconds": 1}
+
+	stop := make(chan struct{})
+	ctx := WithStop(context.Background(), stop)
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		close(stop)
+	}()
+
+	start := time.Now()
+	err := e.waitForReady(ctx, node, &readyCheck{url: server.URL})
+	if !errors.Is(err, errReadinessStopped) {
+		t.Fatalf("expected the wait to end when the execution is stopped, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 900*time.Millisecond {
+		t.Errorf("expected the wait to end promptly, took %s", elapsed)
+	}
+}
+
+func TestExecuteApply_FailsWhenNotReady(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	dc, err := datacenter.NewLoader().LoadFromBytes([]byte(`
+environment {
+  deployment {
+    module "app" {
+      plugin = "readiness-test"
+      build  = "./modules/app"
+    }
+  }
+}
+`), "datacenter.hcl")
+	if err != nil {
+		t.Fatalf("failed to load datacenter: %v", err)
+	}
+
+	registry := newTestRegistry()
+	registry.Register("readiness-test", func() (iac.Plugin, error) {
+		return &statePlugin{outputs: map[string]iac.OutputValue{
+			"ready_check": {Value: map[string]interface{}{"url": server.URL, "timeout": "100ms"}},
+		}}, nil
+	})
+	sm := newMockStateManager()
+	exec := NewExecutor(sm, registry, Options{Parallelism: 1, Datacenter: dc})
+
+	api := graph.NewNode(graph.NodeTypeDeployment, "api", "api")
+	worker := graph.NewNode(graph.NodeTypeDeployment, "api", "worker")
+	worker.AddDependency(api.ID)
+
+	g := graph.NewGraph("staging", "dc")
+	_ = g.AddNode(api)
+	_ = g.AddNode(worker)
+
+	plan := &planner.Plan{
+		Environment: "staging",
+		Datacenter:  "dc",
+		Changes: []*planner.ResourceChange{
+			{Node: api, Action: planner.ActionCreate},
+			{Node: worker, Action: planner.ActionCreate},
+		},
+		ToCreate: 2,
+	}
+
+	result, err := exec.Execute(context.Background(), plan, g)
+	if err != nil {
+		t.Fatalf("Execute returned error: %v", err)
+	}
+	if result.Success {
+		t.Fatal("expected execution to fail")
+	}
+	if r := result.NodeResults[api.ID]; r == nil || r.Error == nil || !strings.Contains(r.Error.Error(), "readiness check failed") {
+		t.Errorf("expected readiness failure, got %+v", r)
+	}
+	if r := result.NodeResults[worker.ID]; r == nil || r.Error == nil || !strings.Contains(r.Error.Error(), "dependencies not satisfied") {
+		t.Errorf("expected dependent not to start before its dependency is ready, got %+v", r)
+	}
+
+	res := sm.environments["dc/staging"].Components["api"].Resources["deployment.api"]
+	if res.Status != types.ResourceStatusFailed || !strings.HasPrefix(res.StatusReason, "not ready: ") {
+		t.Errorf("expected resource to be marked not ready, got %s %q", res.Status, res.StatusReason)
+	}
+	if string(res.IaCState) != `{"applied": true}` {
+		t.Errorf("expected IaC state to be kept, got %s", res.IaCState)
+	}
+}
+
+// statePlugin implements iac.Plugin with applies that return state and outputs.
+type statePlugin struct {
+	mockPlugin
+	outputs map[string]iac.OutputValue
+}
+
+func (p *statePlugin) Apply(ctx context.Context, opts iac.RunOptions) (*iac.ApplyResult, error) {
+	return &iac.ApplyResult{Outputs: p.outputs, State: []byte(`{"applied": true}`)}, nil
+}
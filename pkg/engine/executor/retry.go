@@ -0,0 +1,128 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/davidthor/arcctl/pkg/graph"
+	"github.com/davidthor/arcctl/pkg/schema/datacenter"
+)
+
+// maxRetryBackoff caps the delay between retries of a hook module.
+const maxRetryBackoff = 5 * time.Minute
+
+// hookPolicy is the timeout and retry policy of the hook module that applies
+// or destroys a resource. The zero value runs the module once with no timeout.
+type hookPolicy struct {
+	timeout  time.Duration
+	attempts int
+	backoff  time.Duration
+	on       []*regexp.Regexp
+}
+
+// newHookPolicy returns the policy declared on a datacenter hook module.
+func newHookPolicy(module datacenter.Module) hookPolicy {
+	policy := hookPolicy{timeout: module.Timeout()}
+	if retry := module.Retry(); retry != nil {
+		policy.attempts = retry.Attempts()
+		policy.backoff = retry.Backoff()
+		for _, pattern := range retry.On() {
+			// Patterns are validated when the datacenter is parsed
+			if re, err := regexp.Compile(pattern); err == nil {
+				policy.on = append(policy.on, re)
+			}
+		}
+	}
+	return policy
+}
+
+// retryable reports whether the policy retries a run that failed with err.
+func (p hookPolicy) retryable(err error) bool {
+	if len(p.on) == 0 {
+		return true
+	}
+	for _, re := range p.on {
+		if re.MatchString(err.Error()) {
+			return true
+		}
+	}
+	return false
+}
+
+// delay returns how long to wait before the given retry (1 for the first).
+func (p hookPolicy) delay(retry int) time.Duration {
+	d := p.backoff
+	for i := 1; i < retry && d < maxRetryBackoff; i++ {
+		d *= 2
+	}
+	if d > maxRetryBackoff {
+		d = maxRetryBackoff
+	}
+	return d
+}
+
+// runHookModule runs a hook module operation for node under the policy: each
+// attempt is cancelled once the timeout passes, and failures are retried
+// with backoff while attempts remain and the error matches the policy, unless
+// the execution has been stopped. Retries are reported as "running" progress
+// events carrying the attempt.
+func (e *Executor) runHookModule(ctx context.Context, node *graph.Node, policy hookPolicy, operation string, run func(ctx context.Context) error) error {
+	attempts := policy.attempts
+	if attempts < 1 {
+		attempts = 1
+	}
+
+	for attempt := 1; ; attempt++ {
+		err := runWithTimeout(ctx, policy.timeout, run)
+		if err == nil {
+			return nil
+		}
+		// A stopped execution only waits for runs in progress, not retries
+		if attempt >= attempts || ctx.Err() != nil || StopRequested(ctx) || !policy.retryable(err) {
+			if attempt > 1 {
+				return fmt.Errorf("%w (after %d attempts)", err, attempt)
+			}
+			return err
+		}
+
+		delay := policy.delay(attempt)
+		if e.options.OnProgress != nil {
+			e.options.OnProgress(ProgressEvent{
+				NodeID:   node.ID,
+				NodeName: node.Name,
+				NodeType: string(node.Type),
+				Status:   "running",
+				Message:  fmt.Sprintf("%s failed, retrying in %s (attempt %d of %d): %v", operation, delay, attempt+1, attempts, err),
+				Attempt:  attempt + 1,
+			})
+		}
+
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return err
+		case <-stopChannel(ctx):
+			return err
+		}
+	}
+}
+
+// runWithTimeout runs fn with a context that is cancelled after timeout, or
+// with ctx itself if timeout is zero.
+func runWithTimeout(ctx context.Context, timeout time.Duration, fn func(ctx context.Context) error) error {
+	if timeout <= 0 {
+		return fn(ctx)
+	}
+
+	runCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	err := fn(runCtx)
+	if err != nil && errors.Is(runCtx.Err(), context.DeadlineExceeded) && ctx.Err() == nil {
+		return fmt.Errorf("timed out after %s: %w", timeout, err)
+	}
+	return err
+}
@@ -0,0 +1,158 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/davidthor/arcctl/pkg/graph"
+)
+
+func TestRunHookModule_RetriesUntilSuccess(t *testing.T) {
+	var events []ProgressEvent
+	e := &Executor{options: Options{OnProgress: func(event ProgressEvent) {
+		events = append(events, event)
+	}}}
+	node := graph.NewNode(graph.NodeTypeDatabase, "api", "main")
+	policy := hookPolicy{attempts: 3, backoff: time.Millisecond}
+
+	calls := 0
+	err := e.runHookModule(context.Background(), node, policy, "apply", func(ctx context.Context) error {
+		calls++
+		if calls < 3 {
+			return errors.New("Throttling: rate exceeded")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("expected success after retries, got %v", err)
+	}
+	if calls != 3 {
+		t.Errorf("expected 3 attempts, got %d", calls)
+	}
+
+	if len(events) != 2 {
+		t.Fatalf("expected a progress event per retry, got %d", len(events))
+	}
+	for i, event := range events {
+		if event.NodeID != node.ID || event.Status != "running" || event.Attempt != i+2 {
+			t.Errorf("unexpected retry event: %+v", event)
+		}
+	}
+	if !strings.Contains(events[0].Message, "attempt 2 of 3") {
+		t.Errorf("expected attempt in message, got %q", events[0].Message)
+	}
+}
+
+func TestRunHookModule_GivesUpAfterAttempts(t *testing.T) {
+	e := &Executor{}
+	node := graph.NewNode(graph.NodeTypeDatabase, "api", "main")
+	policy := hookPolicy{attempts: 2, backoff: time.Millisecond}
+
+	calls := 0
+	err := e.runHookModule(context.Background(), node, policy, "apply", func(ctx context.Context) error {
+		calls++
+		return errors.New("boom")
+	})
+	if err == nil || !strings.Contains(err.Error(), "after 2 attempts") {
+		t.Errorf("expected error after 2 attempts, got %v", err)
+	}
+	if calls != 2 {
+		t.Errorf("expected 2 attempts, got %d", calls)
+	}
+}
+
+func TestRunHookModule_OnlyRetriesMatchingErrors(t *testing.T) {
+	e := &Executor{}
+	node := graph.NewNode(graph.NodeTypeDatabase, "api", "main")
+	policy := hookPolicy{
+		attempts: 5,
+		backoff:  time.Millisecond,
+		on:       []*regexp.Regexp{regexp.MustCompile(`(?i)throttl`)},
+	}
+
+	calls := 0
+	err := e.runHookModule(context.Background(), node, policy, "apply", func(ctx context.Context) error {
+		calls++
+		return errors.New("invalid instance class")
+	})
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if calls != 1 {
+		t.Errorf("expected non-matching error not to be retried, got %d attempts", calls)
+	}
+}
+
+func TestRunHookModule_Timeout(t *testing.T) {
+	e := &Executor{}
+	node := graph.NewNode(graph.NodeTypeDeployment, "api", "api")
+	policy := hookPolicy{timeout: 10 * time.Millisecond}
+
+	err := e.runHookModule(context.Background(), node, policy, "apply", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	if err == nil || !strings.Contains(err.Error(), "timed out after 10ms") {
+		t.Errorf("expected timeout error, got %v", err)
+	}
+}
+
+func TestRunHookModule_StopsWhenCancelled(t *testing.T) {
+	e := &Executor{}
+	node := graph.NewNode(graph.NodeTypeDeployment, "api", "api")
+	policy := hookPolicy{attempts: 3, backoff: time.Hour}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	calls := 0
+	done := make(chan error)
+	go func() {
+		done <- e.runHookModule(ctx, node, policy, "apply", func(ctx context.Context) error {
+			calls++
+			return errors.New("boom")
+		})
+	}()
+	cancel()
+
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Error("expected error")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected retry backoff to stop when cancelled")
+	}
+}
+
+func TestRunHookModule_NoRetryAfterStop(t *testing.T) {
+	e := &Executor{}
+	node := graph.NewNode(graph.NodeTypeDeployment, "api", "api")
+	policy := hookPolicy{attempts: 3, backoff: time.Millisecond}
+
+	stop := make(chan struct{})
+	close(stop)
+
+	calls := 0
+	err := e.runHookModule(WithStop(context.Background(), stop), node, policy, "apply", func(ctx context.Context) error {
+		calls++
+		return errors.New("boom")
+	})
+	if err == nil {
+		t.Error("expected error")
+	}
+	if calls != 1 {
+		t.Errorf("expected a stopped execution not to retry, got %d attempts", calls)
+	}
+}
+
+func TestHookPolicy_Delay(t *testing.T) {
+	policy := hookPolicy{backoff: time.Second}
+	for retry, want := range map[int]time.Duration{1: time.Second, 2: 2 * time.Second, 3: 4 * time.Second, 20: maxRetryBackoff} {
+		if got := policy.delay(retry); got != want {
+			t.Errorf("delay(%d) = %s, want %s", retry, got, want)
+		}
+	}
+}
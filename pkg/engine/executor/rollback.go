@@ -0,0 +1,125 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/davidthor/arcctl/pkg/engine/planner"
+	"github.com/davidthor/arcctl/pkg/state/types"
+)
+
+// RollbackChanges returns the changes that restore the resources a failed
+// execution of plan updated, in the order they were applied. Each re-applies
+// the resource with the inputs it had before the execution. Resources that
+// were created, or that weren't ready beforehand, have nothing to restore and
+// are left as they are.
+func RollbackChanges(plan *planner.Plan, failed *ExecutionResult) []*planner.ResourceChange {
+	var changes []*planner.ResourceChange
+	for _, change := range plan.Changes {
+		if change.Node == nil || change.CurrentState == nil || change.CurrentState.Status != types.ResourceStatusReady {
+			continue
+		}
+		if change.Action != planner.ActionUpdate && change.Action != planner.ActionReplace {
+			continue
+		}
+
+		// Resources that were never started are unchanged
+		nodeResult := failed.NodeResults[change.Node.ID]
+		if nodeResult == nil || errors.Is(nodeResult.Error, ErrStopped) ||
+			errors.Is(nodeResult.Error, context.Canceled) {
+			continue
+		}
+
+		node := *change.Node
+		node.Inputs = make(map[string]interface{}, len(change.CurrentState.Inputs))
+		for k, v := range change.CurrentState.Inputs {
+			node.Inputs[k] = v
+		}
+		node.Outputs = nil
+
+		changes = append(changes, &planner.ResourceChange{
+			Node:         &node,
+			Action:       planner.ActionUpdate,
+			CurrentState: change.CurrentState,
+			Reason:       "rolling back failed deploy",
+		})
+	}
+	return changes
+}
+
+// Rollback restores the resources that a failed execution of plan updated to
+// the inputs they had before it. Each is re-applied on top of the IaC state the
+// failed execution recorded, so that infrastructure it created or replaced is
+// reconciled rather than orphaned. Every resource is attempted, even if
+// restoring an earlier one fails, and the environment's state is saved once
+// they have all run.
+func (e *Executor) Rollback(ctx context.Context, plan *planner.Plan, failed *ExecutionResult) (*ExecutionResult, error) {
+	startTime := time.Now()
+
+	result := &ExecutionResult{
+		Success:     true,
+		NodeResults: make(map[string]*NodeResult),
+	}
+
+	changes := RollbackChanges(plan, failed)
+	if len(changes) == 0 {
+		result.Duration = time.Since(startTime)
+		return result, nil
+	}
+
+	envState, err := e.stateManager.GetEnvironment(ctx, plan.Datacenter, plan.Environment)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load environment state: %w", err)
+	}
+	e.beginExecution(plan.Datacenter)
+
+	// Applies start from the IaC state the failed execution recorded
+	e.restoring = true
+	defer func() { e.restoring = false }()
+
+	for _, change := range changes {
+		if ctx.Err() != nil || StopRequested(ctx) {
+			result.Success = false
+			result.Errors = append(result.Errors, interruptErr(ctx))
+			break
+		}
+		if e.stateSaveErr() != nil {
+			result.Success = false // Reported with the final save below
+			break
+		}
+
+		nodeResult := e.executeChange(ctx, change, envState)
+		result.NodeResults[change.Node.ID] = nodeResult
+		if nodeResult.Success {
+			result.Updated++
+		} else {
+			result.Failed++
+			result.Success = false
+			result.Errors = append(result.Errors, fmt.Errorf("%s: %w", change.Node.ID, nodeResult.Error))
+		}
+	}
+
+	// Resources the failed execution created are left as they are, so the
+	// environment is only ready again if none of them failed
+	computeComponentStatuses(envState)
+	envState.Status = types.EnvironmentStatusReady
+	for _, comp := range envState.Components {
+		if comp.Status == types.ResourceStatusFailed {
+			envState.Status = types.EnvironmentStatusFailed
+		}
+	}
+	envState.UpdatedAt = time.Now()
+
+	e.stateMu.Lock()
+	err = e.saveStateLocked(envState)
+	e.stateMu.Unlock()
+	if err != nil {
+		result.Success = false
+		result.Errors = append(result.Errors, err)
+	}
+
+	result.Duration = time.Since(startTime)
+	return result, nil
+}
@@ -0,0 +1,209 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/davidthor/arcctl/pkg/engine/planner"
+	"github.com/davidthor/arcctl/pkg/graph"
+	"github.com/davidthor/arcctl/pkg/iac"
+	"github.com/davidthor/arcctl/pkg/schema/datacenter"
+	"github.com/davidthor/arcctl/pkg/state/types"
+)
+
+// failOncePlugin implements iac.Plugin with an apply that fails on the given
+// call after saving partial state, recording the IaC state each apply starts
+// from.
+type failOncePlugin struct {
+	mockPlugin
+	failOn int
+	calls  int
+	states []string
+}
+
+func (p *failOncePlugin) Apply(ctx context.Context, opts iac.RunOptions) (*iac.ApplyResult, error) {
+	p.calls++
+	state := ""
+	if opts.StateReader != nil {
+		data, _ := io.ReadAll(opts.StateReader)
+		state = string(data)
+	}
+	p.states = append(p.states, state)
+	if p.calls == p.failOn {
+		return &iac.ApplyResult{State: []byte(`{"version": "partial"}`)}, errors.New("boom")
+	}
+	return &iac.ApplyResult{State: []byte(`{"version": "new"}`)}, nil
+}
+
+func readyResource(node *graph.Node, inputs map[string]interface{}) *types.ResourceState {
+	return &types.ResourceState{
+		Component: node.Component,
+		Name:      node.Name,
+		Type:      string(node.Type),
+		Status:    types.ResourceStatusReady,
+		Inputs:    inputs,
+		IaCState:  []byte(`{"version": "old"}`),
+	}
+}
+
+func TestRollbackChanges(t *testing.T) {
+	updated := graph.NewNode(graph.NodeTypeDeployment, "api", "api")
+	created := graph.NewNode(graph.NodeTypeDeployment, "api", "worker")
+	stopped := graph.NewNode(graph.NodeTypeDeployment, "api", "cron")
+	retried := graph.NewNode(graph.NodeTypeDatabase, "api", "main")
+	updated.Inputs["image"] = "api:v2"
+
+	plan := &planner.Plan{Changes: []*planner.ResourceChange{
+		{Node: updated, Action: planner.ActionUpdate, CurrentState: readyResource(updated, map[string]interface{}{"image": "api:v1"})},
+		{Node: created, Action: planner.ActionCreate},
+		{Node: stopped, Action: planner.ActionUpdate, CurrentState: readyResource(stopped, nil)},
+		{Node: retried, Action: planner.ActionUpdate, CurrentState: &types.ResourceState{Status: types.ResourceStatusFailed}},
+	}}
+	failed := &ExecutionResult{NodeResults: map[string]*NodeResult{
+		updated.ID: {NodeID: updated.ID, Success: true},
+		created.ID: {NodeID: created.ID, Success: true},
+		stopped.ID: {NodeID: stopped.ID, Error: ErrStopped},
+		retried.ID: {NodeID: retried.ID, Error: errors.New("boom")},
+	}}
+
+	changes := RollbackChanges(plan, failed)
+	if len(changes) != 1 {
+		t.Fatalf("expected only the updated resource to be rolled back, got %d changes", len(changes))
+	}
+	if changes[0].Node.ID != updated.ID || changes[0].Node.Inputs["image"] != "api:v1" {
+		t.Errorf("expected rollback to previous inputs, got %+v", changes[0].Node)
+	}
+	if updated.Inputs["image"] != "api:v2" {
+		t.Error("expected the planned node to be left unchanged")
+	}
+}
+
+func TestExecutor_RollbackRestoresUpdatedResources(t *testing.T) {
+	dc, err := datacenter.NewLoader().LoadFromBytes([]byte(`
+environment {
+  deployment {
+    module "app" {
+      plugin = "rollback-test"
+      build  = "./modules/app"
+    }
+  }
+}
+`), "datacenter.hcl")
+	if err != nil {
+		t.Fatalf("failed to load datacenter: %v", err)
+	}
+
+	plugin := &failOncePlugin{failOn: 2}
+	registry := newTestRegistry()
+	registry.Register("rollback-test", func() (iac.Plugin, error) {
+		return plugin, nil
+	})
+
+	api := graph.NewNode(graph.NodeTypeDeployment, "api", "api")
+	worker := graph.NewNode(graph.NodeTypeDeployment, "api", "worker")
+	api.Inputs["image"] = "api:v2"
+	worker.Inputs["image"] = "worker:v2"
+
+	apiState := readyResource(api, map[string]interface{}{"image": "api:v1"})
+	workerState := readyResource(worker, map[string]interface{}{"image": "worker:v1"})
+
+	sm := newMockStateManager()
+	sm.environments["dc/staging"] = &types.EnvironmentState{
+		Name:       "staging",
+		Datacenter: "dc",
+		Status:     types.EnvironmentStatusReady,
+		Components: map[string]*types.ComponentState{
+			"api": {Name: "api", Resources: map[string]*types.ResourceState{
+				"deployment.api":    apiState,
+				"deployment.worker": workerState,
+			}},
+		},
+	}
+	exec := NewExecutor(sm, registry, Options{Parallelism: 1, Datacenter: dc})
+
+	g := graph.NewGraph("staging", "dc")
+	_ = g.AddNode(api)
+	_ = g.AddNode(worker)
+	plan := &planner.Plan{
+		Environment: "staging",
+		Datacenter:  "dc",
+		Changes: []*planner.ResourceChange{
+			{Node: api, Action: planner.ActionUpdate, CurrentState: apiState},
+			{Node: worker, Action: planner.ActionUpdate, CurrentState: workerState},
+		},
+		ToUpdate: 2,
+	}
+
+	failed, err := exec.Execute(context.Background(), plan, g)
+	if err != nil {
+		t.Fatalf("Execute returned error: %v", err)
+	}
+	if failed.Success {
+		t.Fatal("expected the deploy to fail")
+	}
+
+	result, err := exec.Rollback(context.Background(), plan, failed)
+	if err != nil {
+		t.Fatalf("Rollback returned error: %v", err)
+	}
+	if !result.Success || result.Updated != 2 {
+		t.Errorf("expected both resources to be restored, got %+v", result)
+	}
+
+	// Normal applies don't pass state; rollbacks start from the state the
+	// failed deploy recorded, including what the failed apply created
+	want := []string{"", "", `{"version": "new"}`, `{"version": "partial"}`}
+	for i, state := range want {
+		if i >= len(plugin.states) || plugin.states[i] != state {
+			t.Fatalf("expected applies to start from states %q, got %q", want, plugin.states)
+		}
+	}
+
+	envState := sm.environments["dc/staging"]
+	for key, image := range map[string]string{"deployment.api": "api:v1", "deployment.worker": "worker:v1"} {
+		res := envState.Components["api"].Resources[key]
+		if res.Status != types.ResourceStatusReady || res.Inputs["image"] != image {
+			t.Errorf("expected %s to be restored to %s, got %s %v", key, image, res.Status, res.Inputs)
+		}
+	}
+	if envState.Status != types.EnvironmentStatusReady {
+		t.Errorf("expected environment to be ready after rollback, got %s", envState.Status)
+	}
+}
+
+func TestExecutor_RollbackSaveFailure(t *testing.T) {
+	node := graph.NewNode(graph.NodeTypeDeployment, "api", "api")
+	node.Inputs["image"] = "api:v2"
+	current := readyResource(node, map[string]interface{}{"image": "api:v1"})
+
+	sm := newMockStateManager()
+	sm.environments["dc/staging"] = &types.EnvironmentState{
+		Name:       "staging",
+		Datacenter: "dc",
+		Components: map[string]*types.ComponentState{
+			"api": {Name: "api", Resources: map[string]*types.ResourceState{"deployment.api": current}},
+		},
+	}
+	sm.saveErr = errors.New("backend unavailable")
+	exec := NewExecutor(sm, newTestRegistry(), Options{})
+
+	plan := &planner.Plan{
+		Environment: "staging",
+		Datacenter:  "dc",
+		Changes:     []*planner.ResourceChange{{Node: node, Action: planner.ActionUpdate, CurrentState: current}},
+	}
+	failed := &ExecutionResult{NodeResults: map[string]*NodeResult{node.ID: {NodeID: node.ID, Error: errors.New("boom")}}}
+
+	result, err := exec.Rollback(context.Background(), plan, failed)
+	if err != nil {
+		t.Fatalf("Rollback returned error: %v", err)
+	}
+	if result.Success {
+		t.Error("expected a rollback whose state wasn't saved to fail")
+	}
+	if len(result.Errors) == 0 || !errors.Is(result.Errors[len(result.Errors)-1], sm.saveErr) {
+		t.Errorf("expected the save error to be reported, got %v", result.Errors)
+	}
+}
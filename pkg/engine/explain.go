@@ -0,0 +1,187 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/davidthor/arcctl/pkg/engine/executor"
+	"github.com/davidthor/arcctl/pkg/graph"
+)
+
+// SensitiveValue replaces sensitive values in hook explanations.
+const SensitiveValue = "(sensitive)"
+
+// ExplainOptions configures a hook resolution explanation.
+type ExplainOptions struct {
+	// Datacenter name
+	Datacenter string
+
+	// Environment name used in module inputs such as resource names
+	Environment string
+
+	// Components maps component name to its local path
+	Components map[string]string
+
+	// Variables maps component name to its variables
+	Variables map[string]map[string]interface{}
+}
+
+// Explain reports how every resource of the given components is matched to
+// the datacenter's hooks: each candidate hook, how its 'when' condition
+// evaluated, and the module and module inputs the resource would be applied
+// with. Nothing is applied and no state is written.
+//
+// Values of sensitive datacenter and component variables, and of variables
+// resolved from secret references, are replaced with SensitiveValue.
+func (e *Engine) Explain(ctx context.Context, opts ExplainOptions) ([]*executor.HookExplanation, error) {
+	dcState, err := e.stateManager.GetDatacenter(ctx, opts.Datacenter)
+	if err != nil {
+		return nil, fmt.Errorf("datacenter %q not found: %w", opts.Datacenter, err)
+	}
+	if dcState.Version == "" {
+		return nil, fmt.Errorf("datacenter %q has no source path configured", opts.Datacenter)
+	}
+	dc, err := e.loadDatacenterConfig(dcState.Version)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load datacenter configuration: %w", err)
+	}
+
+	builder := graph.NewBuilder(opts.Environment, opts.Datacenter)
+	for name, path := range opts.Components {
+		comp, err := e.compLoader.Load(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load component %s: %w", name, err)
+		}
+		if err := builder.AddComponent(name, comp); err != nil {
+			return nil, fmt.Errorf("failed to add component %s to graph: %w", name, err)
+		}
+	}
+	g := builder.Build()
+
+	// Build datacenter variables map
+	dcVarSources := make(map[string]interface{})
+	for k, v := range dcState.Variables {
+		dcVarSources[k] = v
+	}
+	for _, v := range dc.Variables() {
+		if _, ok := dcVarSources[v.Name()]; !ok && v.Default() != nil {
+			dcVarSources[v.Name()] = v.Default()
+		}
+	}
+	dcVars, err := e.resolveSecretVariables(ctx, dcVarSources)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve datacenter variables: %w", err)
+	}
+	compVars, err := e.resolveComponentSecretVariables(ctx, opts.Variables)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve component variables: %w", err)
+	}
+
+	// Collect the values to mask before anything is evaluated
+	var sensitive []string
+	sensitive = appendSensitiveValues(sensitive, dcVars, dcVarSources, SensitiveDatacenterVariables(dc))
+	sensitiveComp := e.sensitiveComponentVariables(opts.Components)
+	for name, vars := range compVars {
+		sensitive = appendSensitiveValues(sensitive, vars, opts.Variables[name], sensitiveComp[name])
+	}
+
+	exec := executor.NewExecutor(e.stateManager, e.iacRegistry, executor.Options{
+		Datacenter:          dc,
+		DatacenterVariables: dcVars,
+		ComponentVariables:  compVars,
+	})
+	explanations, err := exec.ExplainHooks(g, opts.Environment)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, explanation := range explanations {
+		explanation.ModuleInputs = maskValues(explanation.ModuleInputs, sensitive)
+		for i := range explanation.Candidates {
+			candidate := &explanation.Candidates[i]
+			candidate.Values = maskValues(candidate.Values, sensitive)
+			candidate.Error = maskString(candidate.Error, sensitive)
+		}
+		if explanation.Error != nil {
+			if msg := explanation.Error.Error(); maskString(msg, sensitive) != msg {
+				explanation.Error = fmt.Errorf("%s", maskString(msg, sensitive))
+			}
+		}
+	}
+
+	return explanations, nil
+}
+
+// appendSensitiveValues appends the string values of the named sensitive
+// variables, and of variables whose value was resolved from a secret
+// reference, to values.
+func appendSensitiveValues(values []string, resolved, sources map[string]interface{}, names []string) []string {
+	for name, value := range resolved {
+		s, ok := value.(string)
+		if !ok || s == "" {
+			continue
+		}
+		if containsString(names, name) || !reflect.DeepEqual(value, sources[name]) {
+			values = append(values, s)
+		}
+	}
+	return values
+}
+
+// maskValues returns a copy of m with every string containing a sensitive
+// value replaced with SensitiveValue.
+func maskValues(m map[string]interface{}, sensitive []string) map[string]interface{} {
+	if m == nil || len(sensitive) == 0 {
+		return m
+	}
+	masked := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		masked[k] = maskValue(v, sensitive)
+	}
+	return masked
+}
+
+func maskValue(v interface{}, sensitive []string) interface{} {
+	switch val := v.(type) {
+	case string:
+		if maskString(val, sensitive) != val {
+			return SensitiveValue
+		}
+		return val
+	case map[string]interface{}:
+		return maskValues(val, sensitive)
+	case map[string]string:
+		masked := make(map[string]string, len(val))
+		for k, item := range val {
+			masked[k] = maskValue(item, sensitive).(string)
+		}
+		return masked
+	case []interface{}:
+		masked := make([]interface{}, len(val))
+		for i, item := range val {
+			masked[i] = maskValue(item, sensitive)
+		}
+		return masked
+	default:
+		return v
+	}
+}
+
+// maskString replaces every sensitive value in s with SensitiveValue.
+func maskString(s string, sensitive []string) string {
+	for _, value := range sensitive {
+		s = strings.ReplaceAll(s, value, SensitiveValue)
+	}
+	return s
+}
+
+func containsString(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
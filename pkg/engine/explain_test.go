@@ -0,0 +1,85 @@
+package engine
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestExplain(t *testing.T) {
+	eng, _, opts := newPlanTestEngine(t)
+
+	explanations, err := eng.Explain(context.Background(), ExplainOptions{
+		Datacenter:  opts.Datacenter,
+		Environment: opts.Environment,
+		Components:  opts.Components,
+		Variables:   opts.Variables,
+	})
+	if err != nil {
+		t.Fatalf("Explain returned error: %v", err)
+	}
+
+	var found bool
+	for _, explanation := range explanations {
+		if explanation.NodeID != "api/database/main" {
+			continue
+		}
+		found = true
+		if explanation.Selected != 0 || len(explanation.Candidates) != 1 {
+			t.Errorf("expected the only database hook to be selected, got %+v", explanation)
+		}
+		if explanation.Module != "db" || explanation.Plugin != "refresh-test" || explanation.Error != nil {
+			t.Errorf("expected module db, got %+v", explanation)
+		}
+	}
+	if !found {
+		t.Fatal("expected an explanation for api/database/main")
+	}
+}
+
+func TestAppendSensitiveValues(t *testing.T) {
+	resolved := map[string]interface{}{
+		"password": "hunter2",
+		"token":    "resolved-token",
+		"region":   "us-east-1",
+		"replicas": 3,
+	}
+	sources := map[string]interface{}{
+		"password": "hunter2",
+		"token":    "vault://secret/api#token",
+		"region":   "us-east-1",
+		"replicas": 3,
+	}
+
+	got := appendSensitiveValues(nil, resolved, sources, []string{"password"})
+	if len(got) != 2 || !containsString(got, "hunter2") || !containsString(got, "resolved-token") {
+		t.Errorf("expected sensitive and secret values, got %v", got)
+	}
+}
+
+func TestMaskValues(t *testing.T) {
+	values := map[string]interface{}{
+		"url":      "postgres://admin:hunter2@db:5432/app",
+		"name":     "app",
+		"port":     5432,
+		"env":      map[string]string{"PASSWORD": "hunter2", "MODE": "prod"},
+		"args":     []interface{}{"--password", "hunter2"},
+		"settings": map[string]interface{}{"token": "hunter2"},
+	}
+
+	got := maskValues(values, []string{"hunter2"})
+	want := map[string]interface{}{
+		"url":      SensitiveValue,
+		"name":     "app",
+		"port":     5432,
+		"env":      map[string]string{"PASSWORD": SensitiveValue, "MODE": "prod"},
+		"args":     []interface{}{"--password", SensitiveValue},
+		"settings": map[string]interface{}{"token": SensitiveValue},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("maskValues() = %v, want %v", got, want)
+	}
+	if values["url"] == SensitiveValue {
+		t.Error("expected the input map not to be modified")
+	}
+}
@@ -0,0 +1,93 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/davidthor/arcctl/pkg/engine/executor"
+	"github.com/davidthor/arcctl/pkg/graph"
+	"github.com/davidthor/arcctl/pkg/schema/component"
+	"github.com/davidthor/arcctl/pkg/state/types"
+)
+
+// EnvironmentGraph rebuilds the dependency graph of the components deployed
+// to an environment from the sources recorded in its state, and returns it
+// with the environment's state. Resources of components whose source can't be
+// loaded are added from state without their dependencies.
+func (e *Engine) EnvironmentGraph(ctx context.Context, datacenter, environment string) (*graph.Graph, *types.EnvironmentState, error) {
+	envState, err := e.stateManager.GetEnvironment(ctx, datacenter, environment)
+	if err != nil {
+		return nil, nil, fmt.Errorf("environment %q not found in datacenter %q: %w", environment, datacenter, err)
+	}
+
+	builder := graph.NewBuilder(environment, datacenter)
+	var unloaded []string
+	for _, name := range sortedComponentNames(envState.Components) {
+		comp, err := e.loadRecordedComponent(ctx, envState.Components[name].Source)
+		if err != nil {
+			unloaded = append(unloaded, name)
+			continue
+		}
+		if err := builder.AddComponent(name, comp); err != nil {
+			return nil, nil, fmt.Errorf("failed to add component %s to graph: %w", name, err)
+		}
+	}
+	g := builder.Build()
+
+	for _, name := range unloaded {
+		compState := envState.Components[name]
+		for _, key := range sortedResourceKeys(compState.Resources) {
+			res := compState.Resources[key]
+			node := graph.NewNode(graph.NodeType(res.Type), name, res.Name)
+			if g.GetNode(node.ID) == nil {
+				_ = g.AddNode(node)
+			}
+		}
+	}
+
+	return g, envState, nil
+}
+
+// loadRecordedComponent loads a component from the source recorded in state,
+// which is either a local path or an OCI reference.
+func (e *Engine) loadRecordedComponent(ctx context.Context, source string) (component.Component, error) {
+	if source == "" {
+		return nil, fmt.Errorf("no source recorded")
+	}
+	path := source
+	if _, err := os.Stat(source); err != nil {
+		path, err = e.loadComponentConfig(ctx, source)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return e.compLoader.Load(path)
+}
+
+// HookModules returns the name of the datacenter hook module that provisions
+// each node in g, by node ID. Nodes that no hook handles are left out.
+func (e *Engine) HookModules(ctx context.Context, datacenter string, g *graph.Graph) (map[string]string, error) {
+	dcState, err := e.stateManager.GetDatacenter(ctx, datacenter)
+	if err != nil {
+		return nil, fmt.Errorf("datacenter %q not found: %w", datacenter, err)
+	}
+	if dcState.Version == "" {
+		return nil, fmt.Errorf("datacenter %q has no source path configured", datacenter)
+	}
+	dc, err := e.loadDatacenterConfig(dcState.Version)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load datacenter configuration: %w", err)
+	}
+
+	// The executor is only used to match nodes to hooks
+	exec := executor.NewExecutor(e.stateManager, e.iacRegistry, executor.Options{Datacenter: dc})
+
+	modules := make(map[string]string)
+	for id, node := range g.Nodes {
+		if name, err := exec.HookModuleName(node); err == nil {
+			modules[id] = name
+		}
+	}
+	return modules, nil
+}
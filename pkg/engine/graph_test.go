@@ -0,0 +1,58 @@
+package engine
+
+import (
+	"context"
+	"testing"
+
+	"github.com/davidthor/arcctl/pkg/state/types"
+)
+
+func TestEnvironmentGraph(t *testing.T) {
+	eng, sm, opts := newPlanTestEngine(t)
+	compPath := opts.Components["api"]
+
+	sm.environments["test-dc/staging"] = &types.EnvironmentState{
+		Name:       "staging",
+		Datacenter: "test-dc",
+		Components: map[string]*types.ComponentState{
+			"api": {
+				Name:   "api",
+				Source: compPath,
+				Resources: map[string]*types.ResourceState{
+					"database.main": {Name: "main", Type: "database", Component: "api", Status: types.ResourceStatusReady},
+				},
+			},
+			"legacy": {
+				Name: "legacy",
+				Resources: map[string]*types.ResourceState{
+					"deployment.worker": {Name: "worker", Type: "deployment", Component: "legacy"},
+				},
+			},
+		},
+	}
+
+	g, envState, err := eng.EnvironmentGraph(context.Background(), "test-dc", "staging")
+	if err != nil {
+		t.Fatalf("EnvironmentGraph returned error: %v", err)
+	}
+	if envState.Name != "staging" {
+		t.Errorf("expected environment state to be returned, got %q", envState.Name)
+	}
+	if g.GetNode("api/database/main") == nil {
+		t.Error("expected node from the component's source")
+	}
+	if g.GetNode("legacy/deployment/worker") == nil {
+		t.Error("expected node from state for component without a source")
+	}
+
+	modules, err := eng.HookModules(context.Background(), "test-dc", g)
+	if err != nil {
+		t.Fatalf("HookModules returned error: %v", err)
+	}
+	if modules["api/database/main"] != "db" {
+		t.Errorf("expected database to be provisioned by module db, got %q", modules["api/database/main"])
+	}
+	if _, ok := modules["legacy/deployment/worker"]; ok {
+		t.Error("expected no module for a resource type without hooks")
+	}
+}
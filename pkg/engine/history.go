@@ -0,0 +1,240 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/user"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/davidthor/arcctl/pkg/engine/executor"
+	"github.com/davidthor/arcctl/pkg/state/types"
+)
+
+// RollbackOptions configures a rollback to a previous state snapshot.
+type RollbackOptions struct {
+	// Environment name
+	Environment string
+
+	// Datacenter name
+	Datacenter string
+
+	// Version of the snapshot to roll back to
+	Version int
+
+	// Output writer for progress
+	Output io.Writer
+
+	// Parallelism for parallel execution
+	Parallelism int
+
+	// OnProgress is called when resource status changes
+	OnProgress executor.ProgressCallback
+}
+
+// RollbackResult contains the results of a rollback.
+type RollbackResult struct {
+	Success bool
+
+	// Snapshot that was rolled back to
+	Snapshot types.EnvironmentSnapshotInfo
+
+	// Removed lists components that were destroyed because they were not
+	// deployed at the time of the snapshot
+	Removed []string
+
+	// Deploy is the result of redeploying the snapshot's components
+	Deploy   *DeployResult
+	Duration time.Duration
+}
+
+// RollbackPlan describes what a rollback to a snapshot will do.
+type RollbackPlan struct {
+	Snapshot types.EnvironmentSnapshotInfo
+
+	// Components to redeploy, by name to the source recorded in the snapshot
+	Components map[string]string
+
+	// Variables recorded in the snapshot for each component
+	Variables map[string]map[string]interface{}
+
+	// Remove lists components that are deployed now but were not deployed at
+	// the time of the snapshot, in the order they will be destroyed
+	Remove []string
+}
+
+// PlanRollback determines which components a rollback to the given snapshot
+// will redeploy and which it will remove.
+func (e *Engine) PlanRollback(ctx context.Context, datacenter, environment string, version int) (*RollbackPlan, error) {
+	snapshot, err := e.stateManager.GetEnvironmentSnapshot(ctx, datacenter, environment, version)
+	if err != nil {
+		return nil, err
+	}
+
+	current, err := e.stateManager.GetEnvironment(ctx, datacenter, environment)
+	if err != nil {
+		return nil, fmt.Errorf("environment %s not found in datacenter %s", environment, datacenter)
+	}
+
+	plan := &RollbackPlan{
+		Snapshot:   snapshot.EnvironmentSnapshotInfo,
+		Components: make(map[string]string),
+		Variables:  make(map[string]map[string]interface{}),
+	}
+
+	for name, comp := range snapshot.State.Components {
+		if comp.Source == "" {
+			return nil, fmt.Errorf("component %q has no source recorded in snapshot %d and cannot be redeployed", name, version)
+		}
+		plan.Components[name] = comp.Source
+
+		vars := make(map[string]interface{}, len(comp.Variables))
+		for k, v := range comp.Variables {
+			vars[k] = v
+		}
+		plan.Variables[name] = vars
+	}
+
+	var remove []string
+	for name := range current.Components {
+		if _, ok := snapshot.State.Components[name]; !ok {
+			remove = append(remove, name)
+		}
+	}
+	plan.Remove = removalOrder(current, remove)
+
+	return plan, nil
+}
+
+// Rollback redeploys the components recorded in a state snapshot using the
+// sources and variables they were deployed with, and destroys components that
+// were added since the snapshot was taken.
+func (e *Engine) Rollback(ctx context.Context, opts RollbackOptions) (*RollbackResult, error) {
+	startTime := time.Now()
+
+	plan, err := e.PlanRollback(ctx, opts.Datacenter, opts.Environment, opts.Version)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &RollbackResult{Snapshot: plan.Snapshot}
+
+	for _, name := range plan.Remove {
+		destroyResult, err := e.DestroyComponent(ctx, DestroyComponentOptions{
+			Environment: opts.Environment,
+			Datacenter:  opts.Datacenter,
+			Component:   name,
+			Output:      opts.Output,
+			AutoApprove: true,
+			Force:       true, // Dependents are either removed first or rolled back
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to remove component %s: %w", name, err)
+		}
+		if !destroyResult.Success {
+			result.Duration = time.Since(startTime)
+			return result, nil
+		}
+		result.Removed = append(result.Removed, name)
+	}
+
+	if len(plan.Components) > 0 {
+		deployResult, err := e.Deploy(ctx, DeployOptions{
+			Environment: opts.Environment,
+			Datacenter:  opts.Datacenter,
+			Components:  plan.Components,
+			Variables:   plan.Variables,
+			Output:      opts.Output,
+			AutoApprove: true,
+			Parallelism: opts.Parallelism,
+			OnProgress:  opts.OnProgress,
+			Operation:   fmt.Sprintf("rollback to %d", opts.Version),
+		})
+		if err != nil {
+			return nil, err
+		}
+		result.Deploy = deployResult
+		result.Success = deployResult.Success
+	} else {
+		result.Success = true
+	}
+
+	result.Duration = time.Since(startTime)
+	return result, nil
+}
+
+// removalOrder orders components so that each is destroyed before the
+// components it depends on.
+func removalOrder(envState *types.EnvironmentState, names []string) []string {
+	remaining := make(map[string]bool, len(names))
+	for _, name := range names {
+		remaining[name] = true
+	}
+
+	var ordered []string
+	for len(remaining) > 0 {
+		var ready []string
+		for name := range remaining {
+			hasDependent := false
+			for _, dependent := range FindDependents(envState, name) {
+				if remaining[dependent] {
+					hasDependent = true
+					break
+				}
+			}
+			if !hasDependent {
+				ready = append(ready, name)
+			}
+		}
+		if len(ready) == 0 {
+			// Dependency cycle; remove the rest in name order
+			for name := range remaining {
+				ready = append(ready, name)
+			}
+		}
+		sort.Strings(ready)
+		for _, name := range ready {
+			delete(remaining, name)
+		}
+		ordered = append(ordered, ready...)
+	}
+	return ordered
+}
+
+// recordHistory snapshots the environment's state after an operation changed
+// it. Failures are reported as warnings since the operation itself succeeded
+// in updating state.
+func (e *Engine) recordHistory(ctx context.Context, datacenter, environment, operation string, components []string, output io.Writer) {
+	sorted := append([]string(nil), components...)
+	sort.Strings(sorted)
+
+	_, err := e.stateManager.SnapshotEnvironment(ctx, datacenter, environment, types.EnvironmentSnapshotInfo{
+		Operation: operation,
+		Who:       currentUser(),
+		Component: strings.Join(sorted, ", "),
+	})
+	if err != nil && output != nil {
+		fmt.Fprintf(output, "Warning: failed to record state history: %v\n", err)
+	}
+}
+
+// componentNames returns the names of the components in a deploy.
+func componentNames(components map[string]string) []string {
+	names := make([]string, 0, len(components))
+	for name := range components {
+		names = append(names, name)
+	}
+	return names
+}
+
+// currentUser returns the name of the user running cldctl, for recording in
+// state history.
+func currentUser() string {
+	if u, err := user.Current(); err == nil && u.Username != "" {
+		return u.Username
+	}
+	return os.Getenv("USER")
+}
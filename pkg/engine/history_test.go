@@ -0,0 +1,79 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/davidthor/arcctl/pkg/state"
+	"github.com/davidthor/arcctl/pkg/state/types"
+)
+
+func TestPlanRollback(t *testing.T) {
+	ctx := context.Background()
+	sm := newMockStateManager()
+	eng := NewEngine(sm, nil)
+
+	_ = sm.SaveEnvironment(ctx, "dc", &types.EnvironmentState{
+		Name:       "staging",
+		Datacenter: "dc",
+		Components: map[string]*types.ComponentState{
+			"api": {Name: "api", Source: "ghcr.io/org/api:v1", Variables: map[string]string{"log_level": "info"}},
+		},
+	})
+	if _, err := sm.SnapshotEnvironment(ctx, "dc", "staging", types.EnvironmentSnapshotInfo{Operation: "deploy"}); err != nil {
+		t.Fatalf("SnapshotEnvironment failed: %v", err)
+	}
+
+	// Later deploys upgrade api and add two components, one depending on the other
+	_ = sm.SaveEnvironment(ctx, "dc", &types.EnvironmentState{
+		Name:       "staging",
+		Datacenter: "dc",
+		Components: map[string]*types.ComponentState{
+			"api":    {Name: "api", Source: "ghcr.io/org/api:v2"},
+			"queue":  {Name: "queue", Source: "ghcr.io/org/queue:v1"},
+			"worker": {Name: "worker", Source: "ghcr.io/org/worker:v1", Dependencies: []string{"queue"}},
+		},
+	})
+
+	plan, err := eng.PlanRollback(ctx, "dc", "staging", 1)
+	if err != nil {
+		t.Fatalf("PlanRollback failed: %v", err)
+	}
+
+	if plan.Components["api"] != "ghcr.io/org/api:v1" {
+		t.Errorf("api source: got %q", plan.Components["api"])
+	}
+	if plan.Variables["api"]["log_level"] != "info" {
+		t.Errorf("api variables: got %v", plan.Variables["api"])
+	}
+	if len(plan.Remove) != 2 || plan.Remove[0] != "worker" || plan.Remove[1] != "queue" {
+		t.Errorf("expected [worker queue] to be removed, got %v", plan.Remove)
+	}
+}
+
+func TestPlanRollback_SnapshotNotFound(t *testing.T) {
+	eng := NewEngine(newMockStateManager(), nil)
+
+	_, err := eng.PlanRollback(context.Background(), "dc", "staging", 7)
+	if !errors.Is(err, state.ErrSnapshotNotFound) {
+		t.Errorf("expected ErrSnapshotNotFound, got %v", err)
+	}
+}
+
+func TestPlanRollback_MissingSource(t *testing.T) {
+	ctx := context.Background()
+	sm := newMockStateManager()
+	eng := NewEngine(sm, nil)
+
+	_ = sm.SaveEnvironment(ctx, "dc", &types.EnvironmentState{
+		Name:       "staging",
+		Datacenter: "dc",
+		Components: map[string]*types.ComponentState{"api": {Name: "api"}},
+	})
+	_, _ = sm.SnapshotEnvironment(ctx, "dc", "staging", types.EnvironmentSnapshotInfo{Operation: "deploy"})
+
+	if _, err := eng.PlanRollback(ctx, "dc", "staging", 1); err == nil {
+		t.Error("expected error for component without a recorded source")
+	}
+}
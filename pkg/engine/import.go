@@ -0,0 +1,139 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/davidthor/arcctl/pkg/graph"
+	"github.com/davidthor/arcctl/pkg/iac"
+	"github.com/davidthor/arcctl/pkg/state/types"
+)
+
+// ImportResourceOptions configures adopting existing infrastructure as a
+// component's resource.
+type ImportResourceOptions struct {
+	// Datacenter name
+	Datacenter string
+
+	// Environment name
+	Environment string
+
+	// Component, Type, and Name identify the resource to import into
+	Component string
+	Type      string
+	Name      string
+
+	// ID is the provider's identifier for the existing infrastructure
+	ID string
+
+	// Address is the resource within the hook module to import into. It can
+	// be left empty when the module declares a single importable resource.
+	Address string
+
+	// Source is the component's local path or OCI reference. Defaults to the
+	// source recorded in state when the component is already deployed.
+	Source string
+
+	// Variables for the component. Defaults to the variables recorded in
+	// state when the component is already deployed.
+	Variables map[string]interface{}
+
+	// Output writer for progress
+	Output io.Writer
+}
+
+// ImportResource adopts existing infrastructure as a resource of a component.
+// The resource is matched to a datacenter hook as it would be on deploy, and
+// the hook's module is run in import mode against the infrastructure with
+// the given ID. The resulting outputs and IaC state are recorded as a ready
+// resource, so the next deploy of the component leaves it as it is.
+//
+// The environment is locked while the import runs.
+func (e *Engine) ImportResource(ctx context.Context, opts ImportResourceOptions) (*types.ResourceState, error) {
+	dcState, err := e.stateManager.GetDatacenter(ctx, opts.Datacenter)
+	if err != nil {
+		return nil, fmt.Errorf("datacenter %q not found: %w", opts.Datacenter, err)
+	}
+	if dcState.Version == "" {
+		return nil, fmt.Errorf("datacenter %q has no source path configured", opts.Datacenter)
+	}
+	dc, err := e.loadDatacenterConfig(dcState.Version)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load datacenter configuration: %w", err)
+	}
+
+	unlock, err := e.lockEnvironments(ctx, opts.Datacenter, "import", opts.Environment)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	envState, err := e.stateManager.GetEnvironment(ctx, opts.Datacenter, opts.Environment)
+	if err != nil {
+		return nil, fmt.Errorf("environment %q not found in datacenter %q: %w", opts.Environment, opts.Datacenter, err)
+	}
+
+	node := graph.NewNode(graph.NodeType(opts.Type), opts.Component, opts.Name)
+	address := fmt.Sprintf("%s/%s/%s", opts.Component, opts.Type, opts.Name)
+
+	source, variables := opts.Source, opts.Variables
+	if comp := envState.Components[opts.Component]; comp != nil {
+		if existing := comp.Resources[string(node.Type)+"."+node.Name]; existing != nil {
+			return nil, fmt.Errorf("%s is already in state with status %s; remove it with 'cldctl state rm' to import it again", address, existing.Status)
+		}
+		if source == "" {
+			source = comp.Source
+		}
+		if variables == nil && comp.Variables != nil {
+			variables = make(map[string]interface{}, len(comp.Variables))
+			for k, v := range comp.Variables {
+				variables[k] = v
+			}
+		}
+	}
+	if source == "" {
+		return nil, fmt.Errorf("component %q isn't deployed to environment %q; give the component's source to import into it", opts.Component, opts.Environment)
+	}
+
+	comp, err := e.loadRecordedComponent(ctx, source)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load component %s: %w", opts.Component, err)
+	}
+
+	builder := graph.NewBuilder(opts.Environment, opts.Datacenter)
+	if err := builder.AddComponent(opts.Component, comp); err != nil {
+		return nil, fmt.Errorf("failed to add component %s to graph: %w", opts.Component, err)
+	}
+	g := builder.Build()
+	node = g.GetNode(node.ID)
+	if node == nil {
+		return nil, fmt.Errorf("component %q doesn't declare %s/%s", opts.Component, opts.Type, opts.Name)
+	}
+
+	deployOpts := DeployOptions{
+		Environment: opts.Environment,
+		Datacenter:  opts.Datacenter,
+		Components:  map[string]string{opts.Component: source},
+		Output:      opts.Output,
+	}
+	if variables != nil {
+		deployOpts.Variables = map[string]map[string]interface{}{opts.Component: variables}
+	}
+	exec, err := e.newDeployExecutor(ctx, dc, dcState, deployOpts)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := exec.Import(ctx, g, node, envState, iac.ImportOptions{ID: opts.ID, Address: opts.Address})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := e.saveEditedEnvironment(ctx, opts.Datacenter, envState); err != nil {
+		return nil, err
+	}
+	e.recordHistory(ctx, opts.Datacenter, opts.Environment, "import", []string{opts.Component}, opts.Output)
+
+	return res, nil
+}
@@ -0,0 +1,128 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/davidthor/arcctl/pkg/iac"
+	"github.com/davidthor/arcctl/pkg/state/types"
+)
+
+// Import makes refreshTestPlugin an iac.Importer. The ID "missing" is
+// treated as infrastructure that doesn't exist.
+func (p *refreshTestPlugin) Import(ctx context.Context, opts iac.RunOptions, imp iac.ImportOptions) (*iac.ApplyResult, error) {
+	if imp.ID == "missing" {
+		return nil, fmt.Errorf("no resource %q found", imp.ID)
+	}
+	return &iac.ApplyResult{
+		Outputs: map[string]iac.OutputValue{
+			"url":      {Value: "postgres://" + imp.ID},
+			"password": {Value: "secret", Sensitive: true},
+		},
+		State: []byte(`{"imported":"` + imp.ID + `"}`),
+	}, nil
+}
+
+func TestImportResource(t *testing.T) {
+	eng, sm, opts := newPlanTestEngine(t)
+	ctx := context.Background()
+	sm.environments["test-dc/staging"] = &types.EnvironmentState{Name: "staging", Datacenter: "test-dc"}
+
+	res, err := eng.ImportResource(ctx, ImportResourceOptions{
+		Datacenter:  "test-dc",
+		Environment: "staging",
+		Component:   "api",
+		Type:        "database",
+		Name:        "main",
+		ID:          "prod-db",
+		Source:      opts.Components["api"],
+		Variables:   opts.Variables["api"],
+	})
+	if err != nil {
+		t.Fatalf("ImportResource failed: %v", err)
+	}
+
+	if res.Status != types.ResourceStatusReady || res.Module != "db" || string(res.IaCState) != `{"imported":"prod-db"}` {
+		t.Errorf("unexpected resource state: %+v", res)
+	}
+	if res.Outputs["url"] != "postgres://prod-db" || len(res.SensitiveOutputs) != 1 {
+		t.Errorf("unexpected outputs: %v (sensitive %v)", res.Outputs, res.SensitiveOutputs)
+	}
+
+	comp := sm.environments["test-dc/staging"].Components["api"]
+	if comp == nil || comp.Resources["database.main"] != res {
+		t.Fatalf("expected the resource to be saved in the environment, got %+v", comp)
+	}
+	if comp.Source != opts.Components["api"] || comp.Status != types.ResourceStatusReady {
+		t.Errorf("expected the component's source and status to be recorded, got %+v", comp)
+	}
+	if len(sm.snapshots["test-dc/staging"]) == 0 {
+		t.Error("expected the import to be recorded in history")
+	}
+
+	// The next deploy leaves the imported resource as it is
+	result, err := eng.Deploy(ctx, opts)
+	if err != nil {
+		t.Fatalf("Deploy (dry run) failed: %v", err)
+	}
+	if !result.Plan.IsEmpty() {
+		t.Errorf("expected an empty plan after import, got %+v", result.Plan.Changes)
+	}
+
+	// Importing again requires removing the resource from state first
+	_, err = eng.ImportResource(ctx, ImportResourceOptions{
+		Datacenter:  "test-dc",
+		Environment: "staging",
+		Component:   "api",
+		Type:        "database",
+		Name:        "main",
+		ID:          "other-db",
+	})
+	if err == nil || !strings.Contains(err.Error(), "already in state") {
+		t.Errorf("expected an already in state error, got %v", err)
+	}
+}
+
+func TestImportResource_Errors(t *testing.T) {
+	eng, sm, opts := newPlanTestEngine(t)
+	ctx := context.Background()
+	sm.environments["test-dc/staging"] = &types.EnvironmentState{Name: "staging", Datacenter: "test-dc"}
+
+	tests := []struct {
+		name    string
+		opts    ImportResourceOptions
+		wantErr string
+	}{
+		{
+			name:    "component not deployed",
+			opts:    ImportResourceOptions{Component: "api", Type: "database", Name: "main", ID: "prod-db"},
+			wantErr: "give the component's source",
+		},
+		{
+			name:    "resource not declared",
+			opts:    ImportResourceOptions{Component: "api", Type: "database", Name: "other", ID: "prod-db", Source: opts.Components["api"]},
+			wantErr: `component "api" doesn't declare database/other`,
+		},
+		{
+			name:    "import fails",
+			opts:    ImportResourceOptions{Component: "api", Type: "database", Name: "main", ID: "missing", Source: opts.Components["api"]},
+			wantErr: `no resource "missing" found`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.opts.Datacenter = "test-dc"
+			tt.opts.Environment = "staging"
+			_, err := eng.ImportResource(ctx, tt.opts)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+			if len(sm.environments["test-dc/staging"].Components) != 0 {
+				t.Error("expected state to be unchanged after a failed import")
+			}
+		})
+	}
+}
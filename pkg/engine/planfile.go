@@ -0,0 +1,616 @@
+package engine
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/davidthor/arcctl/pkg/engine/executor"
+	"github.com/davidthor/arcctl/pkg/engine/planner"
+	"github.com/davidthor/arcctl/pkg/graph"
+	"github.com/davidthor/arcctl/pkg/state/backend"
+	"github.com/davidthor/arcctl/pkg/state/types"
+)
+
+// PlanFileFormatVersion is the current version of the saved plan file format.
+const PlanFileFormatVersion = 3
+
+// SavedPlan is a serialized execution plan that can be applied later with
+// ApplyPlan. It records everything needed to execute the plan exactly as it
+// was reviewed, plus digests of the configuration and the serial of the state
+// the plan was derived from so that a stale plan is refused instead of
+// applied.
+type SavedPlan struct {
+	FormatVersion int       `json:"format_version"`
+	CreatedAt     time.Time `json:"created_at"`
+
+	Environment string `json:"environment"`
+	Datacenter  string `json:"datacenter"`
+
+	// DatacenterSource is the datacenter source path or OCI reference
+	DatacenterSource string `json:"datacenter_source"`
+	// DatacenterDigest is a digest of the datacenter configuration
+	DatacenterDigest string `json:"datacenter_digest"`
+
+	// Components maps component name to its source, digest, and variables
+	Components map[string]SavedPlanComponent `json:"components"`
+
+	// StateSerial and StateLineage identify the environment state the plan
+	// was made against (zero and empty if the environment had no state)
+	StateSerial  int64  `json:"state_serial,omitempty"`
+	StateLineage string `json:"state_lineage,omitempty"`
+
+	// Changes in execution order
+	Changes []SavedPlanChange `json:"changes"`
+
+	// Targets the plan was restricted to, if any
+	Targets []string `json:"targets,omitempty"`
+
+	// PolicyWarnings are the datacenter policy violations with warn severity
+	PolicyWarnings []SavedPlanPolicyViolation `json:"policy_warnings,omitempty"`
+
+	Summary SavedPlanSummary `json:"summary"`
+}
+
+// SavedPlanComponent describes a component included in a saved plan.
+type SavedPlanComponent struct {
+	Source    string                 `json:"source"`
+	Digest    string                 `json:"digest"`
+	Variables map[string]interface{} `json:"variables,omitempty"`
+
+	// SensitiveVariables lists the variables the component declares
+	// sensitive, whose values are sealed by the state manager
+	SensitiveVariables []string `json:"sensitive_variables,omitempty"`
+}
+
+// SavedPlanChange is the serialized form of a planner.ResourceChange.
+type SavedPlanChange struct {
+	Node            SavedPlanNode             `json:"node"`
+	Action          planner.Action            `json:"action"`
+	Reason          string                    `json:"reason,omitempty"`
+	PropertyChanges []SavedPlanPropertyChange `json:"property_changes,omitempty"`
+}
+
+// SavedPlanNode is the serialized form of a graph node.
+type SavedPlanNode struct {
+	ID           string                 `json:"id"`
+	Type         graph.NodeType         `json:"type"`
+	Component    string                 `json:"component"`
+	Name         string                 `json:"name"`
+	Inputs       map[string]interface{} `json:"inputs,omitempty"`
+	DependsOn    []string               `json:"depends_on,omitempty"`
+	DependedOnBy []string               `json:"depended_on_by,omitempty"`
+}
+
+// SavedPlanPropertyChange is the serialized form of a planner.PropertyChange.
+type SavedPlanPropertyChange struct {
+	Path     string      `json:"path"`
+	OldValue interface{} `json:"old_value,omitempty"`
+	NewValue interface{} `json:"new_value,omitempty"`
+}
+
+// SavedPlanPolicyViolation is the serialized form of a planner.PolicyViolation.
+type SavedPlanPolicyViolation struct {
+	Policy   string `json:"policy"`
+	NodeID   string `json:"node_id"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+}
+
+// SavedPlanSummary holds the change counts of a saved plan.
+type SavedPlanSummary struct {
+	ToCreate int `json:"to_create"`
+	ToUpdate int `json:"to_update"`
+	ToDelete int `json:"to_delete"`
+	NoChange int `json:"no_change"`
+}
+
+// ApplyPlanOptions configures applying a saved plan.
+type ApplyPlanOptions struct {
+	// Output writer for progress
+	Output io.Writer
+
+	// Parallelism for parallel execution
+	Parallelism int
+
+	// OnProgress is called when resource status changes
+	OnProgress executor.ProgressCallback
+
+	// ReadinessTimeout is how long resources are given to pass their
+	// readiness checks (default 5 minutes)
+	ReadinessTimeout time.Duration
+}
+
+// SavePlan creates a SavedPlan from the result of a dry-run Deploy. The
+// options must be the same ones that produced the result.
+func (e *Engine) SavePlan(ctx context.Context, opts DeployOptions, result *DeployResult) (*SavedPlan, error) {
+	if result == nil || result.Plan == nil {
+		return nil, fmt.Errorf("no plan to save")
+	}
+
+	dcState, err := e.stateManager.GetDatacenter(ctx, opts.Datacenter)
+	if err != nil {
+		return nil, fmt.Errorf("datacenter %q not found: %w", opts.Datacenter, err)
+	}
+
+	dcDigest, err := e.datacenterDigest(ctx, dcState.Version)
+	if err != nil {
+		return nil, err
+	}
+
+	envState, err := e.currentEnvironmentState(ctx, opts.Datacenter, opts.Environment)
+	if err != nil {
+		return nil, err
+	}
+	var serial int64
+	var lineage string
+	if envState != nil {
+		serial, lineage = envState.Serial, envState.Lineage
+	}
+
+	saved := &SavedPlan{
+		FormatVersion:    PlanFileFormatVersion,
+		CreatedAt:        time.Now(),
+		Environment:      opts.Environment,
+		Datacenter:       opts.Datacenter,
+		DatacenterSource: dcState.Version,
+		DatacenterDigest: dcDigest,
+		Components:       make(map[string]SavedPlanComponent),
+		StateSerial:      serial,
+		StateLineage:     lineage,
+		Targets:          result.Plan.Targets,
+		Summary: SavedPlanSummary{
+			ToCreate: result.Plan.ToCreate,
+			ToUpdate: result.Plan.ToUpdate,
+			ToDelete: result.Plan.ToDelete,
+			NoChange: result.Plan.NoChange,
+		},
+	}
+
+	for name, source := range opts.Components {
+		digest, err := e.sourceDigest(ctx, source)
+		if err != nil {
+			return nil, fmt.Errorf("failed to compute digest for component %s: %w", name, err)
+		}
+		sensitive, err := e.sensitiveVariableNames(ctx, source)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load component %s: %w", name, err)
+		}
+		vars, err := e.sealPlanVariables(ctx, opts.Variables[name], sensitive)
+		if err != nil {
+			return nil, fmt.Errorf("failed to save the variables of component %s: %w", name, err)
+		}
+		saved.Components[name] = SavedPlanComponent{
+			Source:             source,
+			Digest:             digest,
+			Variables:          vars,
+			SensitiveVariables: sensitive,
+		}
+	}
+
+	for _, v := range result.Plan.PolicyWarnings {
+		saved.PolicyWarnings = append(saved.PolicyWarnings, SavedPlanPolicyViolation{
+			Policy:   v.Policy,
+			NodeID:   v.NodeID,
+			Severity: v.Severity,
+			Message:  v.Message,
+		})
+	}
+
+	for _, change := range result.Plan.Changes {
+		if change.Node == nil {
+			continue
+		}
+		sc := SavedPlanChange{
+			Node: SavedPlanNode{
+				ID:           change.Node.ID,
+				Type:         change.Node.Type,
+				Component:    change.Node.Component,
+				Name:         change.Node.Name,
+				Inputs:       change.Node.Inputs,
+				DependsOn:    change.Node.DependsOn,
+				DependedOnBy: change.Node.DependedOnBy,
+			},
+			Action: change.Action,
+			Reason: change.Reason,
+		}
+		for _, pc := range change.PropertyChanges {
+			sc.PropertyChanges = append(sc.PropertyChanges, SavedPlanPropertyChange{
+				Path:     pc.Path,
+				OldValue: pc.OldValue,
+				NewValue: pc.NewValue,
+			})
+		}
+		saved.Changes = append(saved.Changes, sc)
+	}
+
+	return saved, nil
+}
+
+// WritePlanFile writes a saved plan to disk as JSON.
+func WritePlanFile(path string, plan *SavedPlan) error {
+	data, err := json.MarshalIndent(plan, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal plan: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return fmt.Errorf("failed to write plan file: %w", err)
+	}
+	return nil
+}
+
+// ReadPlanFile reads a saved plan from disk.
+func ReadPlanFile(path string) (*SavedPlan, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read plan file: %w", err)
+	}
+
+	var plan SavedPlan
+	if err := json.Unmarshal(data, &plan); err != nil {
+		return nil, fmt.Errorf("failed to parse plan file: %w", err)
+	}
+
+	if plan.FormatVersion != PlanFileFormatVersion {
+		return nil, fmt.Errorf("unsupported plan file format version %d (expected %d)", plan.FormatVersion, PlanFileFormatVersion)
+	}
+
+	return &plan, nil
+}
+
+// ApplyPlan executes a saved plan exactly as it was created. It refuses to run
+// if the environment state, datacenter configuration, or any component
+// changed since the plan was made.
+func (e *Engine) ApplyPlan(ctx context.Context, saved *SavedPlan, opts ApplyPlanOptions) (*DeployResult, error) {
+	startTime := time.Now()
+
+	envState, err := e.verifySavedPlan(ctx, saved)
+	if err != nil {
+		return nil, err
+	}
+
+	dcState, err := e.stateManager.GetDatacenter(ctx, saved.Datacenter)
+	if err != nil {
+		return nil, fmt.Errorf("datacenter %q not found: %w", saved.Datacenter, err)
+	}
+
+	dc, err := e.loadDatacenterConfig(dcState.Version)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load datacenter configuration: %w", err)
+	}
+
+	g, plan, err := saved.toPlan(envState)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &DeployResult{
+		Plan:  plan,
+		Graph: g,
+	}
+
+	if opts.Output != nil {
+		e.printPlanSummary(opts.Output, plan)
+	}
+
+	if plan.IsEmpty() {
+		result.Success = true
+		result.Duration = time.Since(startTime)
+		return result, nil
+	}
+
+	componentSources := make(map[string]string)
+	componentVariables := make(map[string]map[string]interface{})
+	for name, comp := range saved.Components {
+		vars, err := e.openPlanVariables(ctx, comp.Variables, comp.SensitiveVariables)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the variables of component %s: %w", name, err)
+		}
+		componentSources[name] = comp.Source
+		componentVariables[name] = vars
+	}
+
+	exec, err := e.newDeployExecutor(ctx, dc, dcState, DeployOptions{
+		Components:       componentSources,
+		Variables:        componentVariables,
+		Parallelism:      opts.Parallelism,
+		Output:           opts.Output,
+		OnProgress:       opts.OnProgress,
+		ReadinessTimeout: opts.ReadinessTimeout,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	execResult, err := exec.ExecuteParallel(ctx, plan, g)
+	if err != nil {
+		return nil, fmt.Errorf("execution failed: %w", err)
+	}
+
+	e.recordHistory(ctx, saved.Datacenter, saved.Environment, "apply", componentNames(componentSources), opts.Output)
+
+	result.Execution = execResult
+	result.Success = execResult.Success
+	result.Duration = time.Since(startTime)
+
+	return result, nil
+}
+
+// verifySavedPlan checks that nothing the plan was derived from has changed,
+// and returns the environment state the plan was made against (nil if the
+// environment has no state).
+func (e *Engine) verifySavedPlan(ctx context.Context, saved *SavedPlan) (*types.EnvironmentState, error) {
+	envState, err := e.currentEnvironmentState(ctx, saved.Datacenter, saved.Environment)
+	if err != nil {
+		return nil, err
+	}
+	var serial int64
+	var lineage string
+	if envState != nil {
+		serial, lineage = envState.Serial, envState.Lineage
+	}
+	if serial != saved.StateSerial || lineage != saved.StateLineage {
+		return nil, fmt.Errorf("environment %q state has changed since the plan was created (serial %d, planned against serial %d); create a new plan",
+			saved.Environment, serial, saved.StateSerial)
+	}
+
+	dcState, err := e.stateManager.GetDatacenter(ctx, saved.Datacenter)
+	if err != nil {
+		return nil, fmt.Errorf("datacenter %q not found: %w", saved.Datacenter, err)
+	}
+	if dcState.Version != saved.DatacenterSource {
+		return nil, fmt.Errorf("datacenter %q source changed from %q to %q since the plan was created; create a new plan",
+			saved.Datacenter, saved.DatacenterSource, dcState.Version)
+	}
+	dcDigest, err := e.datacenterDigest(ctx, dcState.Version)
+	if err != nil {
+		return nil, err
+	}
+	if dcDigest != saved.DatacenterDigest {
+		return nil, fmt.Errorf("datacenter %q configuration has changed since the plan was created; create a new plan", saved.Datacenter)
+	}
+
+	for name, comp := range saved.Components {
+		digest, err := e.sourceDigest(ctx, comp.Source)
+		if err != nil {
+			return nil, fmt.Errorf("failed to compute digest for component %s: %w", name, err)
+		}
+		if digest != comp.Digest {
+			return nil, fmt.Errorf("component %q has changed since the plan was created; create a new plan", name)
+		}
+	}
+
+	return envState, nil
+}
+
+// toPlan rebuilds the dependency graph and execution plan from a saved plan.
+// The changes' current states are restored from envState, the state the plan
+// was made against, so that unchanged resources pass their recorded outputs
+// on to their dependents.
+func (s *SavedPlan) toPlan(envState *types.EnvironmentState) (*graph.Graph, *planner.Plan, error) {
+	g := graph.NewGraph(s.Environment, s.Datacenter)
+	plan := &planner.Plan{
+		Environment: s.Environment,
+		Datacenter:  s.Datacenter,
+		ToCreate:    s.Summary.ToCreate,
+		ToUpdate:    s.Summary.ToUpdate,
+		ToDelete:    s.Summary.ToDelete,
+		NoChange:    s.Summary.NoChange,
+		Targets:     s.Targets,
+	}
+
+	for _, v := range s.PolicyWarnings {
+		plan.PolicyWarnings = append(plan.PolicyWarnings, planner.PolicyViolation{
+			Policy:   v.Policy,
+			NodeID:   v.NodeID,
+			Severity: v.Severity,
+			Message:  v.Message,
+		})
+	}
+
+	for _, sc := range s.Changes {
+		node := graph.NewNode(sc.Node.Type, sc.Node.Component, sc.Node.Name)
+		node.ID = sc.Node.ID
+		if sc.Node.Inputs != nil {
+			node.Inputs = sc.Node.Inputs
+		}
+		if sc.Node.DependsOn != nil {
+			node.DependsOn = sc.Node.DependsOn
+		}
+		if sc.Node.DependedOnBy != nil {
+			node.DependedOnBy = sc.Node.DependedOnBy
+		}
+
+		// Deleted resources are not part of the desired graph
+		if sc.Action != planner.ActionDelete {
+			if err := g.AddNode(node); err != nil {
+				return nil, nil, fmt.Errorf("invalid plan file: %w", err)
+			}
+		}
+
+		change := &planner.ResourceChange{
+			Node:         node,
+			Action:       sc.Action,
+			Reason:       sc.Reason,
+			CurrentState: recordedResource(envState, node),
+		}
+		for _, pc := range sc.PropertyChanges {
+			change.PropertyChanges = append(change.PropertyChanges, planner.PropertyChange{
+				Path:     pc.Path,
+				OldValue: pc.OldValue,
+				NewValue: pc.NewValue,
+			})
+		}
+		plan.Changes = append(plan.Changes, change)
+	}
+
+	return g, plan, nil
+}
+
+// sensitiveVariableNames returns the sorted names of the variables the
+// component at source declares sensitive.
+func (e *Engine) sensitiveVariableNames(ctx context.Context, source string) ([]string, error) {
+	comp, err := e.loadRecordedComponent(ctx, source)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, v := range comp.Variables() {
+		if v.Sensitive() {
+			names = append(names, v.Name())
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// sealPlanVariables returns a copy of vars with the values of the sensitive
+// variables sealed by the state manager, so that a plan file doesn't hold
+// them in plain text.
+func (e *Engine) sealPlanVariables(ctx context.Context, vars map[string]interface{}, sensitive []string) (map[string]interface{}, error) {
+	if len(vars) == 0 || len(sensitive) == 0 {
+		return vars, nil
+	}
+	sealed := make(map[string]interface{}, len(vars))
+	for k, v := range vars {
+		sealed[k] = v
+	}
+	for _, name := range sensitive {
+		v, ok := vars[name]
+		if !ok {
+			continue
+		}
+		enc, err := e.stateManager.SealSensitiveValue(ctx, v)
+		if err != nil {
+			return nil, fmt.Errorf("variable %s: %w", name, err)
+		}
+		sealed[name] = enc
+	}
+	return sealed, nil
+}
+
+// openPlanVariables returns a copy of vars with the values sealed by
+// sealPlanVariables opened.
+func (e *Engine) openPlanVariables(ctx context.Context, vars map[string]interface{}, sensitive []string) (map[string]interface{}, error) {
+	if len(vars) == 0 || len(sensitive) == 0 {
+		return vars, nil
+	}
+	opened := make(map[string]interface{}, len(vars))
+	for k, v := range vars {
+		opened[k] = v
+	}
+	for _, name := range sensitive {
+		v, ok := vars[name]
+		if !ok {
+			continue
+		}
+		value, err := e.stateManager.OpenSensitiveValue(ctx, v)
+		if err != nil {
+			return nil, fmt.Errorf("variable %s: %w", name, err)
+		}
+		opened[name] = value
+	}
+	return opened, nil
+}
+
+// currentEnvironmentState returns the stored state of an environment, or nil
+// if the environment has no state.
+func (e *Engine) currentEnvironmentState(ctx context.Context, dc, env string) (*types.EnvironmentState, error) {
+	envState, err := e.stateManager.GetEnvironment(ctx, dc, env)
+	if errors.Is(err, backend.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read environment %q state: %w", env, err)
+	}
+	return envState, nil
+}
+
+// recordedResource returns the state recorded for a node's resource, checking
+// both the "type.name" key and the "type/name" key of older state.
+func recordedResource(envState *types.EnvironmentState, node *graph.Node) *types.ResourceState {
+	if envState == nil {
+		return nil
+	}
+	comp := envState.Components[node.Component]
+	if comp == nil {
+		return nil
+	}
+	if res, ok := comp.Resources[string(node.Type)+"."+node.Name]; ok {
+		return res
+	}
+	return comp.Resources[string(node.Type)+"/"+node.Name]
+}
+
+// datacenterDigest returns a digest of the datacenter configuration: of its
+// source tree for a local datacenter, and of the artifact an OCI reference
+// resolves to otherwise.
+func (e *Engine) datacenterDigest(ctx context.Context, ref string) (string, error) {
+	dcFile, err := localDatacenterFile(ref)
+	if err != nil {
+		return "", err
+	}
+	if dcFile == "" {
+		return e.ociClient.Digest(ctx, ref)
+	}
+	return treeDigest(filepath.Dir(dcFile))
+}
+
+// sourceDigest returns a digest of a component: of the source tree of a
+// local component file, which holds its build contexts and modules, and of
+// the artifact an OCI reference resolves to otherwise.
+func (e *Engine) sourceDigest(ctx context.Context, source string) (string, error) {
+	if _, err := os.Stat(source); err != nil {
+		if !os.IsNotExist(err) {
+			return "", err
+		}
+		return e.ociClient.Digest(ctx, source)
+	}
+	return treeDigest(filepath.Dir(source))
+}
+
+// treeDigest returns a digest of the files under dir, their paths and
+// content, leaving out version control metadata.
+func treeDigest(dir string) (string, error) {
+	h := sha256.New()
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if d.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+
+		var content []byte
+		if d.Type()&fs.ModeSymlink != 0 {
+			target, err := os.Readlink(p)
+			if err != nil {
+				return err
+			}
+			content = []byte(target)
+		} else if content, err = os.ReadFile(p); err != nil {
+			return err
+		}
+		fmt.Fprintf(h, "%s\x00%x\x00", filepath.ToSlash(rel), sha256.Sum256(content))
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to digest %s: %w", dir, err)
+	}
+	return fmt.Sprintf("sha256:%x", h.Sum(nil)), nil
+}
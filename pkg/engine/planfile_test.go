@@ -0,0 +1,291 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/davidthor/arcctl/pkg/engine/planner"
+	"github.com/davidthor/arcctl/pkg/iac"
+	"github.com/davidthor/arcctl/pkg/state/types"
+)
+
+const planTestComponentYAML = `
+databases:
+  main:
+    type: postgres:16
+`
+
+func newPlanTestEngine(t *testing.T) (*Engine, *mockStateManager, DeployOptions) {
+	t.Helper()
+
+	// The datacenter and component are digested by their source trees, so
+	// each has its own directory
+	dcFile := filepath.Join(t.TempDir(), "datacenter.hcl")
+	if err := os.WriteFile(dcFile, []byte(refreshDatacenterHCL), 0644); err != nil {
+		t.Fatalf("failed to write datacenter: %v", err)
+	}
+	compDir := t.TempDir()
+	compFile := filepath.Join(compDir, "cloud.component.yml")
+	if err := os.WriteFile(compFile, []byte(planTestComponentYAML), 0644); err != nil {
+		t.Fatalf("failed to write component: %v", err)
+	}
+
+	sm := newMockStateManager()
+	sm.datacenters = map[string]*types.DatacenterState{
+		"test-dc": {Name: "test-dc", Version: dcFile},
+	}
+
+	opts := DeployOptions{
+		Environment: "staging",
+		Datacenter:  "test-dc",
+		Components:  map[string]string{"api": compFile},
+		Variables:   map[string]map[string]interface{}{"api": {"region": "us-east-1"}},
+		DryRun:      true,
+	}
+
+	return NewEngine(sm, iac.DefaultRegistry), sm, opts
+}
+
+func savePlanForTest(t *testing.T, eng *Engine, opts DeployOptions) *SavedPlan {
+	t.Helper()
+
+	result, err := eng.Deploy(context.Background(), opts)
+	if err != nil {
+		t.Fatalf("Deploy (dry run) failed: %v", err)
+	}
+	saved, err := eng.SavePlan(context.Background(), opts, result)
+	if err != nil {
+		t.Fatalf("SavePlan failed: %v", err)
+	}
+
+	// Round-trip through a plan file
+	planFile := filepath.Join(t.TempDir(), "plan.json")
+	if err := WritePlanFile(planFile, saved); err != nil {
+		t.Fatalf("WritePlanFile failed: %v", err)
+	}
+	loaded, err := ReadPlanFile(planFile)
+	if err != nil {
+		t.Fatalf("ReadPlanFile failed: %v", err)
+	}
+	return loaded
+}
+
+func TestSavePlan_ApplyPlan(t *testing.T) {
+	eng, sm, opts := newPlanTestEngine(t)
+
+	saved := savePlanForTest(t, eng, opts)
+
+	if saved.Summary.ToCreate != 1 {
+		t.Fatalf("ToCreate: got %d, want 1", saved.Summary.ToCreate)
+	}
+	if len(saved.Changes) != 1 || saved.Changes[0].Action != planner.ActionCreate {
+		t.Fatalf("unexpected changes: %+v", saved.Changes)
+	}
+	if saved.Components["api"].Variables["region"] != "us-east-1" {
+		t.Errorf("expected component variables to be saved, got %+v", saved.Components["api"])
+	}
+
+	result, err := eng.ApplyPlan(context.Background(), saved, ApplyPlanOptions{Parallelism: 2})
+	if err != nil {
+		t.Fatalf("ApplyPlan failed: %v", err)
+	}
+	if !result.Success {
+		t.Fatalf("expected apply to succeed, got %+v", result.Execution)
+	}
+
+	envState := sm.environments["test-dc/staging"]
+	if envState == nil {
+		t.Fatal("expected environment state to be saved")
+	}
+	res := envState.Components["api"].Resources["database.main"]
+	if res == nil || res.Status != types.ResourceStatusReady {
+		t.Fatalf("expected database.main to be ready, got %+v", res)
+	}
+
+	// The same plan must not be applied twice
+	_, err = eng.ApplyPlan(context.Background(), saved, ApplyPlanOptions{})
+	if err == nil || !strings.Contains(err.Error(), "state has changed") {
+		t.Errorf("expected stale state error, got %v", err)
+	}
+}
+
+func TestSavePlan_SealsSensitiveVariables(t *testing.T) {
+	eng, sm, opts := newPlanTestEngine(t)
+	componentYAML := planTestComponentYAML + `
+variables:
+  region: {}
+  api_key:
+    sensitive: true
+`
+	if err := os.WriteFile(opts.Components["api"], []byte(componentYAML), 0644); err != nil {
+		t.Fatalf("failed to write component: %v", err)
+	}
+	opts.Variables["api"]["api_key"] = "hunter2"
+
+	result, err := eng.Deploy(context.Background(), opts)
+	if err != nil {
+		t.Fatalf("Deploy (dry run) failed: %v", err)
+	}
+	saved, err := eng.SavePlan(context.Background(), opts, result)
+	if err != nil {
+		t.Fatalf("SavePlan failed: %v", err)
+	}
+	planFile := filepath.Join(t.TempDir(), "plan.json")
+	if err := WritePlanFile(planFile, saved); err != nil {
+		t.Fatalf("WritePlanFile failed: %v", err)
+	}
+	raw, _ := os.ReadFile(planFile)
+	if strings.Contains(string(raw), "hunter2") {
+		t.Errorf("plan file contains a sensitive variable:\n%s", raw)
+	}
+	if !strings.Contains(string(raw), "us-east-1") {
+		t.Error("expected non-sensitive variables to be saved in plain text")
+	}
+	if opts.Variables["api"]["api_key"] != "hunter2" {
+		t.Error("SavePlan modified the deploy options")
+	}
+
+	loaded, err := ReadPlanFile(planFile)
+	if err != nil {
+		t.Fatalf("ReadPlanFile failed: %v", err)
+	}
+	result, err = eng.ApplyPlan(context.Background(), loaded, ApplyPlanOptions{Parallelism: 2})
+	if err != nil || !result.Success {
+		t.Fatalf("ApplyPlan failed: %v", err)
+	}
+	comp := sm.environments["test-dc/staging"].Components["api"]
+	if comp.Variables["api_key"] != "hunter2" {
+		t.Errorf("expected the sensitive variable to be applied, got %q", comp.Variables["api_key"])
+	}
+}
+
+func TestApplyPlan_RefusesChangedComponent(t *testing.T) {
+	eng, _, opts := newPlanTestEngine(t)
+
+	saved := savePlanForTest(t, eng, opts)
+
+	if err := os.WriteFile(opts.Components["api"], []byte(planTestComponentYAML+"\n# changed\n"), 0644); err != nil {
+		t.Fatalf("failed to modify component: %v", err)
+	}
+
+	_, err := eng.ApplyPlan(context.Background(), saved, ApplyPlanOptions{})
+	if err == nil || !strings.Contains(err.Error(), "component \"api\" has changed") {
+		t.Errorf("expected changed component error, got %v", err)
+	}
+}
+
+func TestApplyPlan_RefusesChangedSourceTree(t *testing.T) {
+	eng, _, opts := newPlanTestEngine(t)
+
+	saved := savePlanForTest(t, eng, opts)
+
+	// A change to a file the component builds from changes the component
+	dockerfile := filepath.Join(filepath.Dir(opts.Components["api"]), "Dockerfile")
+	if err := os.WriteFile(dockerfile, []byte("FROM scratch\n"), 0644); err != nil {
+		t.Fatalf("failed to write Dockerfile: %v", err)
+	}
+
+	_, err := eng.ApplyPlan(context.Background(), saved, ApplyPlanOptions{})
+	if err == nil || !strings.Contains(err.Error(), "component \"api\" has changed") {
+		t.Errorf("expected changed component error, got %v", err)
+	}
+}
+
+func TestApplyPlan_RefusesMovedOCIReference(t *testing.T) {
+	eng, _, _ := newPlanTestEngine(t)
+	digest := "sha256:aaa"
+	eng.ociClient = &mockOCIClient{
+		digestFn: func(ctx context.Context, reference string) (string, error) {
+			return digest, nil
+		},
+	}
+
+	ref := "ghcr.io/example/api:v1"
+	planDigest, err := eng.sourceDigest(context.Background(), ref)
+	if err != nil {
+		t.Fatalf("sourceDigest failed: %v", err)
+	}
+	saved := &SavedPlan{
+		Environment:      "staging",
+		Datacenter:       "test-dc",
+		DatacenterSource: eng.stateManager.(*mockStateManager).datacenters["test-dc"].Version,
+		Components:       map[string]SavedPlanComponent{"api": {Source: ref, Digest: planDigest}},
+	}
+	if saved.DatacenterDigest, err = eng.datacenterDigest(context.Background(), saved.DatacenterSource); err != nil {
+		t.Fatalf("datacenterDigest failed: %v", err)
+	}
+	if _, err := eng.verifySavedPlan(context.Background(), saved); err != nil {
+		t.Fatalf("verifySavedPlan failed: %v", err)
+	}
+
+	// The tag now points at another artifact
+	digest = "sha256:bbb"
+	_, err = eng.verifySavedPlan(context.Background(), saved)
+	if err == nil || !strings.Contains(err.Error(), "component \"api\" has changed") {
+		t.Errorf("expected changed component error, got %v", err)
+	}
+}
+
+func TestReadPlanFile_UnsupportedVersion(t *testing.T) {
+	planFile := filepath.Join(t.TempDir(), "plan.json")
+	if err := os.WriteFile(planFile, []byte(`{"format_version": 99}`), 0644); err != nil {
+		t.Fatalf("failed to write plan: %v", err)
+	}
+
+	if _, err := ReadPlanFile(planFile); err == nil {
+		t.Error("expected error for unsupported format version")
+	}
+}
+
+func TestApplyPlan_StateReadError(t *testing.T) {
+	eng, sm, opts := newPlanTestEngine(t)
+
+	saved := savePlanForTest(t, eng, opts)
+
+	// A plan is never applied against state that couldn't be read
+	sm.getErr = errors.New("backend unavailable")
+	_, err := eng.ApplyPlan(context.Background(), saved, ApplyPlanOptions{})
+	if err == nil || !strings.Contains(err.Error(), "backend unavailable") {
+		t.Errorf("expected state read error, got %v", err)
+	}
+	if _, ok := sm.environments["test-dc/staging"]; ok {
+		t.Error("expected nothing to be applied")
+	}
+}
+
+func TestSavedPlan_ToPlanRestoresCurrentState(t *testing.T) {
+	db := &types.ResourceState{
+		Name: "main", Type: "database", Component: "api",
+		Status:  types.ResourceStatusReady,
+		Outputs: map[string]interface{}{"url": "postgres://db.internal"},
+	}
+	envState := &types.EnvironmentState{
+		Name: "staging",
+		Components: map[string]*types.ComponentState{
+			"api": {Name: "api", Resources: map[string]*types.ResourceState{"database.main": db}},
+		},
+	}
+	saved := &SavedPlan{
+		Environment: "staging",
+		Datacenter:  "test-dc",
+		Changes: []SavedPlanChange{
+			{Node: SavedPlanNode{ID: "api/database/main", Type: "database", Component: "api", Name: "main"}, Action: planner.ActionNoop},
+			{Node: SavedPlanNode{ID: "api/deployment/api", Type: "deployment", Component: "api", Name: "api"}, Action: planner.ActionCreate},
+		},
+	}
+
+	_, plan, err := saved.toPlan(envState)
+	if err != nil {
+		t.Fatalf("toPlan failed: %v", err)
+	}
+	if plan.Changes[0].CurrentState != db {
+		t.Errorf("expected the unchanged database to carry its recorded state, got %+v", plan.Changes[0].CurrentState)
+	}
+	if plan.Changes[1].CurrentState != nil {
+		t.Errorf("expected no current state for a new resource, got %+v", plan.Changes[1].CurrentState)
+	}
+}
@@ -0,0 +1,98 @@
+package planner
+
+import (
+	"github.com/davidthor/arcctl/pkg/graph"
+	"github.com/davidthor/arcctl/pkg/state/types"
+)
+
+// recordedResources indexes the resources recorded in an environment's state
+// by a component-qualified key, e.g. "api/database.main".
+//
+// The executor records each resource in its component under a "type.name"
+// key, while older state used "type/name". Nodes are matched with either form,
+// so that an unchanged resource is planned as a noop instead of being created
+// again and its recorded entry deleted.
+//
+// Recorded resources that no node matches are deleted, but only within the
+// components in the graph. Deploying a component plans that component; the
+// other components of the environment aren't part of the deployment and are
+// left as they are. Removing a whole component is done by destroying it.
+type recordedResources struct {
+	resources map[string]*types.ResourceState
+
+	// components maps each key to the component the resource is recorded
+	// under, which older state doesn't always repeat on the resource
+	components map[string]string
+}
+
+func indexRecordedResources(currentState *types.EnvironmentState) *recordedResources {
+	r := &recordedResources{
+		resources:  make(map[string]*types.ResourceState),
+		components: make(map[string]string),
+	}
+	if currentState == nil {
+		return r
+	}
+	for compName, compState := range currentState.Components {
+		for resName, resState := range compState.Resources {
+			key := compName + "/" + resName
+			r.resources[key] = resState
+			r.components[key] = compName
+		}
+	}
+	return r
+}
+
+// find looks up the recorded state of a node. It returns the matched key so
+// the caller can exclude it from deletion.
+func (r *recordedResources) find(node *graph.Node) (string, *types.ResourceState) {
+	candidates := []string{
+		node.Component + "/" + string(node.Type) + "." + node.Name,
+		node.Component + "/" + string(node.Type) + "/" + node.Name,
+		node.ID,
+	}
+	for _, key := range candidates {
+		if existing, ok := r.resources[key]; ok {
+			return key, existing
+		}
+	}
+	return "", nil
+}
+
+// unmatched returns the keys of the resources recorded for the components of
+// nodes that aren't in matched.
+func (r *recordedResources) unmatched(matched map[string]bool, nodes []*graph.Node) []string {
+	planned := make(map[string]bool)
+	for _, node := range nodes {
+		planned[node.Component] = true
+	}
+
+	var keys []string
+	for key := range r.resources {
+		if !matched[key] && planned[r.components[key]] {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// matchesTarget reports whether a target address refers to a recorded
+// resource.
+func (r *recordedResources) matchesTarget(target string) bool {
+	for key, resState := range r.resources {
+		if matchesTarget(resourceNode(key, resState), target) {
+			return true
+		}
+	}
+	return false
+}
+
+// resourceNode returns a node describing a resource recorded in state.
+func resourceNode(key string, resState *types.ResourceState) *graph.Node {
+	return &graph.Node{
+		ID:        key,
+		Type:      graph.NodeType(resState.Type),
+		Component: resState.Component,
+		Name:      resState.Name,
+	}
+}
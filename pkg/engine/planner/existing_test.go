@@ -0,0 +1,151 @@
+package planner
+
+import (
+	"testing"
+
+	"github.com/davidthor/arcctl/pkg/graph"
+	"github.com/davidthor/arcctl/pkg/state/types"
+)
+
+func TestPlan_MatchesExecutorResourceKeys(t *testing.T) {
+	p := NewPlanner()
+
+	g := graph.NewGraph("test-env", "test-dc")
+	node := graph.NewNode(graph.NodeTypeDatabase, "api", "main")
+	node.SetInput("type", "postgres")
+	_ = g.AddNode(node)
+
+	// The executor stores resources under "type.name"
+	currentState := &types.EnvironmentState{
+		Name: "test-env",
+		Components: map[string]*types.ComponentState{
+			"api": {
+				Name: "api",
+				Resources: map[string]*types.ResourceState{
+					"database.main": {
+						Name:      "main",
+						Type:      string(graph.NodeTypeDatabase),
+						Component: "api",
+						Inputs:    map[string]interface{}{"type": "postgres"},
+					},
+				},
+			},
+		},
+	}
+
+	plan, err := p.Plan(g, currentState)
+	if err != nil {
+		t.Fatalf("Plan failed: %v", err)
+	}
+
+	if plan.NoChange != 1 {
+		t.Errorf("NoChange: got %d, want %d", plan.NoChange, 1)
+	}
+	if !plan.IsEmpty() {
+		t.Errorf("Plan should be empty, got %d create, %d update, %d delete", plan.ToCreate, plan.ToUpdate, plan.ToDelete)
+	}
+}
+
+func TestPlan_MatchesLegacyResourceKeys(t *testing.T) {
+	p := NewPlanner()
+
+	g := graph.NewGraph("test-env", "test-dc")
+	node := graph.NewNode(graph.NodeTypeDatabase, "api", "main")
+	node.SetInput("type", "postgres")
+	_ = g.AddNode(node)
+
+	// Older state stored resources under "type/name". They must be matched
+	// rather than created again and deleted under the old key.
+	currentState := &types.EnvironmentState{
+		Name: "test-env",
+		Components: map[string]*types.ComponentState{
+			"api": {
+				Name: "api",
+				Resources: map[string]*types.ResourceState{
+					"database/main": {
+						Name:      "main",
+						Type:      string(graph.NodeTypeDatabase),
+						Component: "api",
+						Inputs:    map[string]interface{}{"type": "postgres"},
+					},
+				},
+			},
+		},
+	}
+
+	plan, err := p.Plan(g, currentState)
+	if err != nil {
+		t.Fatalf("Plan failed: %v", err)
+	}
+	if len(plan.Changes) != 1 || plan.Changes[0].Action != ActionNoop || plan.Changes[0].CurrentState == nil {
+		t.Errorf("expected the legacy resource to be matched and unchanged, got %+v", plan.Changes)
+	}
+}
+
+func TestPlan_DeletionsUseRecordedComponent(t *testing.T) {
+	p := NewPlanner()
+
+	g := graph.NewGraph("test-env", "test-dc")
+	_ = g.AddNode(graph.NewNode(graph.NodeTypeDeployment, "api", "main"))
+
+	// Deletions are scoped by the component a resource is recorded under,
+	// even when the resource doesn't name its component
+	currentState := &types.EnvironmentState{
+		Name: "test-env",
+		Components: map[string]*types.ComponentState{
+			"api": {
+				Name: "api",
+				Resources: map[string]*types.ResourceState{
+					"deployment.main":   {Name: "main", Type: string(graph.NodeTypeDeployment), Component: "api"},
+					"deployment.legacy": {Name: "legacy", Type: string(graph.NodeTypeDeployment)},
+				},
+			},
+		},
+	}
+
+	plan, err := p.Plan(g, currentState)
+	if err != nil {
+		t.Fatalf("Plan failed: %v", err)
+	}
+	if plan.ToDelete != 1 {
+		t.Fatalf("ToDelete: got %d, want 1", plan.ToDelete)
+	}
+	for _, c := range plan.Changes {
+		if c.Action == ActionDelete && c.Node.Name != "legacy" {
+			t.Errorf("unexpected deletion of %s", c.Node.ID)
+		}
+	}
+}
+
+func TestPlan_DeletionsScopedToPlannedComponents(t *testing.T) {
+	p := NewPlanner()
+
+	g := graph.NewGraph("test-env", "test-dc")
+	_ = g.AddNode(graph.NewNode(graph.NodeTypeDeployment, "api", "main"))
+
+	// A resource of another component in the same environment must not be deleted
+	currentState := &types.EnvironmentState{
+		Name: "test-env",
+		Components: map[string]*types.ComponentState{
+			"web": {
+				Name: "web",
+				Resources: map[string]*types.ResourceState{
+					"deployment.main": {
+						Name:      "main",
+						Type:      string(graph.NodeTypeDeployment),
+						Component: "web",
+					},
+				},
+			},
+		},
+	}
+
+	plan, err := p.Plan(g, currentState)
+	if err != nil {
+		t.Fatalf("Plan failed: %v", err)
+	}
+
+	if plan.ToDelete != 0 {
+		t.Errorf("ToDelete: got %d, want %d", plan.ToDelete, 0)
+	}
+}
@@ -3,8 +3,10 @@ package planner
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/davidthor/arcctl/pkg/graph"
+	"github.com/davidthor/arcctl/pkg/iac"
 	"github.com/davidthor/arcctl/pkg/state/types"
 )
 
@@ -35,6 +37,24 @@ type ResourceChange struct {
 
 	// Property changes (for updates)
 	PropertyChanges []PropertyChange
+
+	// IaCChanges are the infrastructure changes reported by the hook module's
+	// IaC plugin preview. Only populated for detailed plans.
+	IaCChanges []iac.ResourceChange
+
+	// PreviewError is set if a detailed plan could not preview this change
+	PreviewError error
+}
+
+// RequiresReplacement returns true if the IaC preview reported that any
+// underlying infrastructure resource will be replaced.
+func (c *ResourceChange) RequiresReplacement() bool {
+	for _, ic := range c.IaCChanges {
+		if ic.Action == iac.ActionReplace {
+			return true
+		}
+	}
+	return false
 }
 
 // PropertyChange describes a change to a property.
@@ -55,6 +75,15 @@ type Plan struct {
 	// Changes to make, in execution order
 	Changes []*ResourceChange
 
+	// Targets the plan was restricted to. A targeted plan leaves out every
+	// resource that isn't a target or a dependency of one, so applying it
+	// leaves the environment partially applied.
+	Targets []string
+
+	// PolicyWarnings are the violations of datacenter policies with warn
+	// severity. Violations with error severity prevent a plan from being made.
+	PolicyWarnings []PolicyViolation
+
 	// Summary
 	ToCreate int
 	ToUpdate int
@@ -62,6 +91,21 @@ type Plan struct {
 	NoChange int
 }
 
+// PolicyViolation describes a resource that violates a datacenter policy.
+type PolicyViolation struct {
+	// Policy is the name of the violated policy
+	Policy string
+
+	// NodeID is the resource that violates it
+	NodeID string
+
+	// Severity is "error" or "warn"
+	Severity string
+
+	// Message explains the violation
+	Message string
+}
+
 // IsEmpty returns true if there are no changes.
 func (p *Plan) IsEmpty() bool {
 	return p.ToCreate == 0 && p.ToUpdate == 0 && p.ToDelete == 0
@@ -72,6 +116,26 @@ type PlanOptions struct {
 	// ForceUpdate converts Noop actions to Update, used when datacenter config
 	// changes and all resources need re-evaluation against new hooks.
 	ForceUpdate bool
+
+	// ForceUpdateNodes lists node IDs that are updated even if their inputs
+	// are unchanged, e.g. because their source files changed. Nodes that
+	// depend on them are updated as well.
+	ForceUpdateNodes []string
+
+	// Targets restricts the plan to the resources at these addresses and the
+	// resources they transitively depend on. An address is a node ID
+	// ("api/deployment/api") or a resource type and name ("deployment/api" or
+	// "deployment.api"), which matches the resource in every component.
+	Targets []string
+
+	// TargetDependents also plans the resources that transitively depend on
+	// the targets.
+	TargetDependents bool
+
+	// Resume continues a deployment that failed or was interrupted. Resources
+	// already ready in state are reused unless their inputs have changed, and
+	// resources left failed, pending, or provisioning are applied again.
+	Resume bool
 }
 
 // Planner generates execution plans.
@@ -90,6 +154,7 @@ func NewPlannerWithOptions(opts PlanOptions) *Planner {
 }
 
 // Plan creates an execution plan by comparing desired state (graph) with current state.
+// See recordedResources for how nodes are matched with the resources in state.
 func (p *Planner) Plan(g *graph.Graph, currentState *types.EnvironmentState) (*Plan, error) {
 	plan := &Plan{
 		Environment: g.Environment,
@@ -103,22 +168,46 @@ func (p *Planner) Plan(g *graph.Graph, currentState *types.EnvironmentState) (*P
 	}
 
 	// Track which resources exist in current state
-	existingResources := make(map[string]*types.ResourceState)
-	if currentState != nil {
-		for compName, compState := range currentState.Components {
-			for resName, resState := range compState.Resources {
-				key := compName + "/" + resName
-				existingResources[key] = resState
+	recorded := indexRecordedResources(currentState)
+
+	forced := forcedNodes(g, p.options.ForceUpdateNodes)
+
+	var targeted map[string]bool
+	if len(p.options.Targets) > 0 {
+		var unmatched []string
+		targeted, unmatched = targetedNodes(g, p.options.Targets, p.options.TargetDependents)
+		// Targets may also name resources that are no longer defined, to delete them
+		for _, target := range unmatched {
+			if !recorded.matchesTarget(target) {
+				return nil, fmt.Errorf("target %q does not match any resource", target)
 			}
 		}
+		plan.Targets = append([]string(nil), p.options.Targets...)
 	}
 
 	// Plan changes for each node
 	processedIDs := make(map[string]bool)
 	for _, node := range sortedNodes {
-		change := p.planNodeChange(node, existingResources)
+		existingKey, existing := recorded.find(node)
+		if targeted != nil && !targeted[node.ID] {
+			// Untargeted resources are left as they are, not deleted
+			processedIDs[node.ID] = true
+			if existingKey != "" {
+				processedIDs[existingKey] = true
+			}
+			continue
+		}
+
+		change := p.planNodeChange(node, existing)
+		if change.Action == ActionNoop && forced[node.ID] {
+			change.Action = ActionUpdate
+			change.Reason = "update requested"
+		}
 		plan.Changes = append(plan.Changes, change)
 		processedIDs[node.ID] = true
+		if existingKey != "" {
+			processedIDs[existingKey] = true
+		}
 
 		switch change.Action {
 		case ActionCreate:
@@ -133,21 +222,21 @@ func (p *Planner) Plan(g *graph.Graph, currentState *types.EnvironmentState) (*P
 	}
 
 	// Plan deletions for resources that exist but aren't in the graph
-	for key, resState := range existingResources {
-		if !processedIDs[key] {
-			change := &ResourceChange{
-				Node: &graph.Node{
-					ID:        key,
-					Component: resState.Component,
-					Name:      resState.Name,
-				},
-				Action:       ActionDelete,
-				CurrentState: resState,
-				Reason:       "resource no longer defined",
-			}
-			plan.Changes = append(plan.Changes, change)
-			plan.ToDelete++
+	for _, key := range recorded.unmatched(processedIDs, sortedNodes) {
+		resState := recorded.resources[key]
+		node := resourceNode(key, resState)
+		// A targeted plan only deletes resources that were targeted
+		if targeted != nil && !matchesAnyTarget(node, p.options.Targets) {
+			continue
+		}
+		change := &ResourceChange{
+			Node:         node,
+			Action:       ActionDelete,
+			CurrentState: resState,
+			Reason:       "resource no longer defined",
 		}
+		plan.Changes = append(plan.Changes, change)
+		plan.ToDelete++
 	}
 
 	return plan, nil
@@ -190,16 +279,90 @@ func (p *Planner) PlanDestroy(g *graph.Graph, currentState *types.EnvironmentSta
 	return plan, nil
 }
 
-func (p *Planner) planNodeChange(node *graph.Node, existingResources map[string]*types.ResourceState) *ResourceChange {
-	// Look for existing resource
-	existingKey := node.Component + "/" + string(node.Type) + "/" + node.Name
-	existing := existingResources[existingKey]
+// forcedNodes returns the given node IDs together with all of their
+// transitive dependents in the graph.
+func forcedNodes(g *graph.Graph, ids []string) map[string]bool {
+	forced := make(map[string]bool)
+	queue := append([]string(nil), ids...)
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		if forced[id] {
+			continue
+		}
+		forced[id] = true
+		if node := g.GetNode(id); node != nil {
+			queue = append(queue, node.DependedOnBy...)
+		}
+	}
+	return forced
+}
 
-	// Also check with just the node ID
-	if existing == nil {
-		existing = existingResources[node.ID]
+// targetedNodes returns the IDs of the nodes matching the target addresses,
+// optionally with their transitive dependents, together with everything they
+// transitively depend on so that their references can be resolved. Targets
+// that match no node are returned as unmatched.
+func targetedNodes(g *graph.Graph, targets []string, withDependents bool) (map[string]bool, []string) {
+	var roots, unmatched []string
+	for _, target := range targets {
+		matched := false
+		for id, node := range g.Nodes {
+			if matchesTarget(node, target) {
+				roots = append(roots, id)
+				matched = true
+			}
+		}
+		if !matched {
+			unmatched = append(unmatched, target)
+		}
+	}
+
+	if withDependents {
+		expanded := forcedNodes(g, roots)
+		roots = roots[:0]
+		for id := range expanded {
+			roots = append(roots, id)
+		}
 	}
 
+	targeted := make(map[string]bool)
+	queue := roots
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		if targeted[id] {
+			continue
+		}
+		targeted[id] = true
+		if node := g.GetNode(id); node != nil {
+			queue = append(queue, node.DependsOn...)
+		}
+	}
+	return targeted, unmatched
+}
+
+// matchesTarget reports whether a target address refers to node. Addresses
+// are either the node ID or the node's type and name, separated by "/" or ".".
+func matchesTarget(node *graph.Node, target string) bool {
+	target = strings.TrimSpace(target)
+	if target == node.ID {
+		return true
+	}
+	return target == string(node.Type)+"/"+node.Name ||
+		target == string(node.Type)+"."+node.Name ||
+		target == node.Component+"/"+string(node.Type)+"."+node.Name
+}
+
+func matchesAnyTarget(node *graph.Node, targets []string) bool {
+	for _, target := range targets {
+		if matchesTarget(node, target) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *Planner) planNodeChange(node *graph.Node, existing *types.ResourceState) *ResourceChange {
 	change := &ResourceChange{
 		Node:         node,
 		CurrentState: existing,
@@ -212,6 +375,21 @@ func (p *Planner) planNodeChange(node *graph.Node, existingResources map[string]
 		return change
 	}
 
+	if p.options.Resume {
+		switch existing.Status {
+		case types.ResourceStatusFailed, types.ResourceStatusPending, types.ResourceStatusProvisioning:
+			// Resources that never finished applying have no IaC state to update
+			change.Action = ActionUpdate
+			if existing.IaCState == nil && !existing.IaCStateDeferred() {
+				change.Action = ActionCreate
+			}
+			change.Reason = fmt.Sprintf("retrying %s resource", existing.Status)
+			return change
+		}
+		// Resources deployed before the deployment stopped are reused only
+		// if their configuration hasn't changed since
+	}
+
 	// Compare inputs to detect changes
 	changes := p.compareInputs(node.Inputs, existing.Inputs)
 	if len(changes) > 0 {
@@ -231,6 +409,9 @@ func (p *Planner) planNodeChange(node *graph.Node, existingResources map[string]
 	// No changes needed
 	change.Action = ActionNoop
 	change.Reason = "resource is up to date"
+	if p.options.Resume {
+		change.Reason = "resource was deployed before the deployment stopped"
+	}
 	return change
 }
 
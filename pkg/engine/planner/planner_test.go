@@ -244,8 +244,9 @@ func TestPlan_Updates(t *testing.T) {
 func TestPlan_Deletions(t *testing.T) {
 	p := NewPlanner()
 
-	// Create empty graph (no resources desired)
+	// Create graph where the component no longer defines the old resource
 	g := graph.NewGraph("test-env", "test-dc")
+	_ = g.AddNode(graph.NewNode(graph.NodeTypeDeployment, "api", "main"))
 
 	// Create current state with existing resource
 	currentState := &types.EnvironmentState{
@@ -254,6 +255,11 @@ func TestPlan_Deletions(t *testing.T) {
 			"api": {
 				Name: "api",
 				Resources: map[string]*types.ResourceState{
+					"deployment.main": {
+						Name:      "main",
+						Type:      string(graph.NodeTypeDeployment),
+						Component: "api",
+					},
 					"old-resource": {
 						Name:      "old-resource",
 						Type:      string(graph.NodeTypeDeployment),
@@ -290,6 +296,283 @@ func TestPlan_Deletions(t *testing.T) {
 	}
 }
 
+func TestPlan_ForceUpdateNodes(t *testing.T) {
+	g := graph.NewGraph("test-env", "test-dc")
+
+	build := graph.NewNode(graph.NodeTypeDockerBuild, "api", "api")
+	build.SetInput("context", "/src/api")
+	deploy := graph.NewNode(graph.NodeTypeDeployment, "api", "api")
+	deploy.AddDependency(build.ID)
+	build.AddDependent(deploy.ID)
+	db := graph.NewNode(graph.NodeTypeDatabase, "api", "main")
+	_ = g.AddNode(build)
+	_ = g.AddNode(deploy)
+	_ = g.AddNode(db)
+
+	// All nodes are unchanged in state
+	resources := make(map[string]*types.ResourceState)
+	for _, n := range []*graph.Node{build, deploy, db} {
+		resources[string(n.Type)+"."+n.Name] = &types.ResourceState{
+			Name:      n.Name,
+			Type:      string(n.Type),
+			Component: "api",
+			Inputs:    n.Inputs,
+		}
+	}
+	currentState := &types.EnvironmentState{
+		Name: "test-env",
+		Components: map[string]*types.ComponentState{
+			"api": {Name: "api", Resources: resources},
+		},
+	}
+
+	p := NewPlannerWithOptions(PlanOptions{ForceUpdateNodes: []string{build.ID}})
+	plan, err := p.Plan(g, currentState)
+	if err != nil {
+		t.Fatalf("Plan failed: %v", err)
+	}
+
+	actions := make(map[string]Action)
+	for _, c := range plan.Changes {
+		actions[c.Node.ID] = c.Action
+	}
+	if actions[build.ID] != ActionUpdate {
+		t.Errorf("forced node: got %s, want update", actions[build.ID])
+	}
+	if actions[deploy.ID] != ActionUpdate {
+		t.Errorf("dependent of forced node: got %s, want update", actions[deploy.ID])
+	}
+	if actions[db.ID] != ActionNoop {
+		t.Errorf("unrelated node: got %s, want noop", actions[db.ID])
+	}
+}
+
+// targetGraph builds a graph where a deployment depends on a database and
+// a route depends on the deployment's service, alongside an unrelated bucket.
+func targetGraph() (*graph.Graph, map[string]*graph.Node) {
+	g := graph.NewGraph("test-env", "test-dc")
+	nodes := map[string]*graph.Node{
+		"db":     graph.NewNode(graph.NodeTypeDatabase, "api", "main"),
+		"deploy": graph.NewNode(graph.NodeTypeDeployment, "api", "api"),
+		"svc":    graph.NewNode(graph.NodeTypeService, "api", "api"),
+		"route":  graph.NewNode(graph.NodeTypeRoute, "api", "public"),
+		"bucket": graph.NewNode(graph.NodeTypeBucket, "api", "uploads"),
+	}
+	for _, n := range nodes {
+		_ = g.AddNode(n)
+	}
+	for _, edge := range [][2]string{{"deploy", "db"}, {"svc", "deploy"}, {"route", "svc"}} {
+		_ = g.AddEdge(nodes[edge[0]].ID, nodes[edge[1]].ID)
+	}
+	return g, nodes
+}
+
+func plannedIDs(plan *Plan) map[string]Action {
+	ids := make(map[string]Action)
+	for _, c := range plan.Changes {
+		ids[c.Node.ID] = c.Action
+	}
+	return ids
+}
+
+func TestPlan_Targets(t *testing.T) {
+	g, nodes := targetGraph()
+
+	for _, target := range []string{"deployment/api", "deployment.api", "api/deployment/api"} {
+		p := NewPlannerWithOptions(PlanOptions{Targets: []string{target}})
+		plan, err := p.Plan(g, nil)
+		if err != nil {
+			t.Fatalf("Plan(%s) failed: %v", target, err)
+		}
+
+		planned := plannedIDs(plan)
+		if len(planned) != 2 {
+			t.Errorf("Plan(%s): expected target and its dependency, got %v", target, planned)
+		}
+		for _, key := range []string{"deploy", "db"} {
+			if _, ok := planned[nodes[key].ID]; !ok {
+				t.Errorf("Plan(%s): expected %s to be planned", target, nodes[key].ID)
+			}
+		}
+		if plan.ToCreate != 2 {
+			t.Errorf("Plan(%s): ToCreate: got %d, want 2", target, plan.ToCreate)
+		}
+		if len(plan.Targets) != 1 || plan.Targets[0] != target {
+			t.Errorf("Plan(%s): expected targets to be recorded, got %v", target, plan.Targets)
+		}
+	}
+}
+
+func TestPlan_TargetDependents(t *testing.T) {
+	g, nodes := targetGraph()
+
+	p := NewPlannerWithOptions(PlanOptions{Targets: []string{"database/main"}, TargetDependents: true})
+	plan, err := p.Plan(g, nil)
+	if err != nil {
+		t.Fatalf("Plan failed: %v", err)
+	}
+
+	planned := plannedIDs(plan)
+	for _, key := range []string{"db", "deploy", "svc", "route"} {
+		if _, ok := planned[nodes[key].ID]; !ok {
+			t.Errorf("expected %s to be planned", nodes[key].ID)
+		}
+	}
+	if _, ok := planned[nodes["bucket"].ID]; ok {
+		t.Error("expected unrelated bucket to be left out of the plan")
+	}
+}
+
+func TestPlan_TargetNotFound(t *testing.T) {
+	g, _ := targetGraph()
+
+	p := NewPlannerWithOptions(PlanOptions{Targets: []string{"deployment/missing"}})
+	if _, err := p.Plan(g, nil); err == nil {
+		t.Error("expected error for target that matches no resource")
+	}
+}
+
+func TestPlan_TargetsLeaveOtherResources(t *testing.T) {
+	g, nodes := targetGraph()
+
+	// The bucket exists with different inputs and a removed resource is still
+	// in state; neither is touched by a plan targeting the deployment
+	currentState := &types.EnvironmentState{
+		Name: "test-env",
+		Components: map[string]*types.ComponentState{
+			"api": {
+				Name: "api",
+				Resources: map[string]*types.ResourceState{
+					"bucket.uploads": {
+						Name:      "uploads",
+						Type:      string(graph.NodeTypeBucket),
+						Component: "api",
+						Inputs:    map[string]interface{}{"versioning": true},
+					},
+					"deployment.worker": {
+						Name:      "worker",
+						Type:      string(graph.NodeTypeDeployment),
+						Component: "api",
+					},
+				},
+			},
+		},
+	}
+
+	p := NewPlannerWithOptions(PlanOptions{Targets: []string{"deployment/api"}})
+	plan, err := p.Plan(g, currentState)
+	if err != nil {
+		t.Fatalf("Plan failed: %v", err)
+	}
+	if plan.ToUpdate != 0 || plan.ToDelete != 0 {
+		t.Errorf("expected untargeted resources to be left alone, got %d updates and %d deletes", plan.ToUpdate, plan.ToDelete)
+	}
+	if _, ok := plannedIDs(plan)[nodes["bucket"].ID]; ok {
+		t.Error("expected bucket to be left out of the plan")
+	}
+
+	// Targeting the removed resource deletes it
+	p = NewPlannerWithOptions(PlanOptions{Targets: []string{"deployment/worker"}})
+	plan, err = p.Plan(g, currentState)
+	if err != nil {
+		t.Fatalf("Plan failed: %v", err)
+	}
+	if len(plan.Changes) != 1 || plan.Changes[0].Action != ActionDelete || plan.Changes[0].Node.Name != "worker" {
+		t.Errorf("expected only the removed resource to be deleted, got %v", plannedIDs(plan))
+	}
+}
+
+func TestPlan_Resume(t *testing.T) {
+	g, nodes := targetGraph()
+
+	// The last deployment created the database, failed on the deployment
+	// (skipping the service behind it), and was interrupted applying the bucket
+	resources := map[string]*types.ResourceState{
+		"database.main": {
+			Name: "main", Type: "database", Component: "api",
+			Status: types.ResourceStatusReady,
+		},
+		"deployment.api": {
+			Name: "api", Type: "deployment", Component: "api",
+			Status: types.ResourceStatusFailed, Attempt: 1,
+		},
+		"service.api": {
+			Name: "api", Type: "service", Component: "api",
+			Status: types.ResourceStatusFailed,
+		},
+		"bucket.uploads": {
+			Name: "uploads", Type: "bucket", Component: "api",
+			Status:   types.ResourceStatusProvisioning,
+			IaCState: []byte(`{}`),
+		},
+	}
+	currentState := &types.EnvironmentState{
+		Name: "test-env",
+		Components: map[string]*types.ComponentState{
+			"api": {Name: "api", Resources: resources},
+		},
+	}
+
+	p := NewPlannerWithOptions(PlanOptions{Resume: true})
+	plan, err := p.Plan(g, currentState)
+	if err != nil {
+		t.Fatalf("Plan failed: %v", err)
+	}
+
+	planned := plannedIDs(plan)
+	expected := map[string]Action{
+		"db":     ActionNoop, // Ready and unchanged
+		"deploy": ActionCreate,
+		"svc":    ActionCreate,
+		"route":  ActionCreate, // Never reached
+		"bucket": ActionUpdate,
+	}
+	for key, action := range expected {
+		if planned[nodes[key].ID] != action {
+			t.Errorf("%s: got %s, want %s", nodes[key].ID, planned[nodes[key].ID], action)
+		}
+	}
+}
+
+func TestPlan_ResumeUpdatesChangedResources(t *testing.T) {
+	g, nodes := targetGraph()
+	nodes["db"].SetInput("type", "postgres:16")
+
+	// The database finished before the deployment stopped, but its
+	// configuration has changed since
+	currentState := &types.EnvironmentState{
+		Name: "test-env",
+		Components: map[string]*types.ComponentState{
+			"api": {Name: "api", Resources: map[string]*types.ResourceState{
+				"database.main": {
+					Name: "main", Type: "database", Component: "api",
+					Status: types.ResourceStatusReady,
+					Inputs: map[string]interface{}{"type": "postgres:15"},
+				},
+			}},
+		},
+	}
+
+	p := NewPlannerWithOptions(PlanOptions{Resume: true})
+	plan, err := p.Plan(g, currentState)
+	if err != nil {
+		t.Fatalf("Plan failed: %v", err)
+	}
+
+	var change *ResourceChange
+	for _, c := range plan.Changes {
+		if c.Node.ID == nodes["db"].ID {
+			change = c
+		}
+	}
+	if change == nil || change.Action != ActionUpdate {
+		t.Fatalf("expected the changed database to be updated, got %+v", change)
+	}
+	if len(change.PropertyChanges) != 1 || change.PropertyChanges[0].Path != "type" {
+		t.Errorf("expected a change to type, got %+v", change.PropertyChanges)
+	}
+}
+
 func TestPlanDestroy(t *testing.T) {
 	p := NewPlanner()
 
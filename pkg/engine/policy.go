@@ -0,0 +1,141 @@
+package engine
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/davidthor/arcctl/pkg/engine/planner"
+	arcerrors "github.com/davidthor/arcctl/pkg/errors"
+	"github.com/davidthor/arcctl/pkg/graph"
+	"github.com/davidthor/arcctl/pkg/schema/datacenter"
+	v1 "github.com/davidthor/arcctl/pkg/schema/datacenter/v1"
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+)
+
+// policyContext is the environment metadata policies are evaluated with.
+type policyContext struct {
+	Environment string
+	Datacenter  string
+	Variables   map[string]interface{}
+}
+
+// checkPolicies evaluates the datacenter's policies against every node in g.
+// A condition that can't be evaluated for a node counts as a violation, so a
+// broken policy never lets resources through unchecked. Violations are sorted
+// by node ID, in the order the policies are declared.
+func checkPolicies(dc datacenter.Datacenter, g *graph.Graph, pctx policyContext) []planner.PolicyViolation {
+	policies := dc.Policies()
+	if len(policies) == 0 {
+		return nil
+	}
+
+	ids := make([]string, 0, len(g.Nodes))
+	for id := range g.Nodes {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	var violations []planner.PolicyViolation
+	for _, id := range ids {
+		node := g.Nodes[id]
+		for _, policy := range policies {
+			if !policyApplies(policy, node) {
+				continue
+			}
+
+			eval := v1.NewEvaluator()
+			eval.SetNodeContext(string(node.Type), node.Name, node.Component, node.Inputs)
+			eval.SetEnvironmentContext(pctx.Environment, pctx.Datacenter, "", "")
+			eval.SetVariables(pctx.Variables)
+
+			var message string
+			passed, err := evaluatePolicyCondition(eval, policy.Condition())
+			switch {
+			case err != nil:
+				message = fmt.Sprintf("failed to evaluate policy condition: %v", err)
+			case passed:
+				continue
+			default:
+				message = evaluatePolicyMessage(eval, policy.Message())
+			}
+
+			violations = append(violations, planner.PolicyViolation{
+				Policy:   policy.Name(),
+				NodeID:   id,
+				Severity: policy.Severity(),
+				Message:  message,
+			})
+		}
+	}
+
+	return violations
+}
+
+// policyApplies reports whether a policy covers the node's resource type.
+func policyApplies(policy datacenter.Policy, node *graph.Node) bool {
+	resources := policy.Resources()
+	if len(resources) == 0 {
+		return true
+	}
+	for _, r := range resources {
+		if r == string(node.Type) {
+			return true
+		}
+	}
+	return false
+}
+
+// evaluatePolicyCondition parses and evaluates a policy condition.
+func evaluatePolicyCondition(eval *v1.Evaluator, condition string) (bool, error) {
+	expr, diags := hclsyntax.ParseExpression([]byte(condition), "policy.hcl", hcl.Pos{Line: 1, Column: 1})
+	if diags.HasErrors() {
+		return false, fmt.Errorf("failed to parse condition: %s", diags.Error())
+	}
+	return eval.EvaluateWhen(expr)
+}
+
+// evaluatePolicyMessage interpolates a policy message, falling back to the
+// raw template if it can't be evaluated.
+func evaluatePolicyMessage(eval *v1.Evaluator, message string) string {
+	expr, diags := hclsyntax.ParseTemplate([]byte(message), "policy.hcl", hcl.Pos{Line: 1, Column: 1})
+	if diags.HasErrors() {
+		return message
+	}
+	evaluated, err := eval.EvaluateErrorMessage(expr)
+	if err != nil {
+		return message
+	}
+	return evaluated
+}
+
+// policyViolationError returns an error listing the violations with error
+// severity, or nil if there are none.
+func policyViolationError(violations []planner.PolicyViolation) error {
+	var lines []string
+	for _, v := range violations {
+		if v.Severity == "error" {
+			lines = append(lines, formatPolicyViolation(v))
+		}
+	}
+	if len(lines) == 0 {
+		return nil
+	}
+	return arcerrors.PolicyViolationError(lines)
+}
+
+// policyWarnings returns the violations with warn severity.
+func policyWarnings(violations []planner.PolicyViolation) []planner.PolicyViolation {
+	var warnings []planner.PolicyViolation
+	for _, v := range violations {
+		if v.Severity == "warn" {
+			warnings = append(warnings, v)
+		}
+	}
+	return warnings
+}
+
+// formatPolicyViolation formats a violation as a single line.
+func formatPolicyViolation(v planner.PolicyViolation) string {
+	return fmt.Sprintf("%s: %s (policy %q)", v.NodeID, v.Message, v.Policy)
+}
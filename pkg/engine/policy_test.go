@@ -0,0 +1,205 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	arcerrors "github.com/davidthor/arcctl/pkg/errors"
+	"github.com/davidthor/arcctl/pkg/graph"
+	"github.com/davidthor/arcctl/pkg/iac"
+	"github.com/davidthor/arcctl/pkg/schema/datacenter"
+	"github.com/davidthor/arcctl/pkg/state/types"
+)
+
+const policyDatacenterHCL = `
+variable "max_replicas" {
+  type    = number
+  default = 10
+}
+
+policy "no-public-buckets" {
+  resources = ["bucket"]
+  condition = !(environment.name == "production" && node.inputs.public)
+  message   = "Bucket ${node.name} may not be public in production"
+}
+
+policy "memory-required" {
+  resources = ["deployment"]
+  condition = node.inputs.memory != ""
+  message   = "Deployments must set memory"
+  severity  = "warn"
+}
+
+policy "replica-limit" {
+  resources = ["deployment"]
+  condition = coalesce(node.inputs.replicas, 1) <= tonumber(variable.max_replicas)
+  message   = "${node.name} requests ${node.inputs.replicas} replicas; the limit is ${variable.max_replicas}"
+}
+`
+
+const policyComponentYAML = `
+buckets:
+  files:
+    type: s3
+    public: true
+
+deployments:
+  api:
+    image: nginx:latest
+    replicas: 20
+  worker:
+    image: nginx:latest
+    memory: 512Mi
+`
+
+func writePolicyTestFiles(t *testing.T, dcHCL string) (dcFile, compFile string) {
+	t.Helper()
+
+	tmpDir := t.TempDir()
+	dcFile = filepath.Join(tmpDir, "datacenter.hcl")
+	if err := os.WriteFile(dcFile, []byte(dcHCL), 0644); err != nil {
+		t.Fatalf("failed to write datacenter: %v", err)
+	}
+	compFile = filepath.Join(tmpDir, "cloud.component.yml")
+	if err := os.WriteFile(compFile, []byte(policyComponentYAML), 0644); err != nil {
+		t.Fatalf("failed to write component: %v", err)
+	}
+	return dcFile, compFile
+}
+
+func newPolicyTestGraph(t *testing.T, compFile string) *graph.Graph {
+	t.Helper()
+
+	comp, err := NewEngine(newMockStateManager(), iac.DefaultRegistry).compLoader.Load(compFile)
+	if err != nil {
+		t.Fatalf("failed to load component: %v", err)
+	}
+	builder := graph.NewBuilder("production", "test-dc")
+	if err := builder.AddComponent("app", comp); err != nil {
+		t.Fatalf("failed to build graph: %v", err)
+	}
+	return builder.Build()
+}
+
+func TestCheckPolicies(t *testing.T) {
+	dcFile, compFile := writePolicyTestFiles(t, policyDatacenterHCL)
+	dc, err := datacenter.NewLoader().Load(dcFile)
+	if err != nil {
+		t.Fatalf("failed to load datacenter: %v", err)
+	}
+	g := newPolicyTestGraph(t, compFile)
+
+	violations := checkPolicies(dc, g, policyContext{
+		Environment: "production",
+		Datacenter:  "test-dc",
+		Variables:   map[string]interface{}{"max_replicas": "10"},
+	})
+
+	// Violations are ordered by node ID, then policy declaration order
+	type violation struct{ policy, nodeID, severity, message string }
+	want := []violation{
+		{"no-public-buckets", "app/bucket/files", "error", "Bucket files may not be public in production"},
+		{"memory-required", "app/deployment/api", "warn", "Deployments must set memory"},
+		{"replica-limit", "app/deployment/api", "error", "api requests 20 replicas; the limit is 10"},
+	}
+	var got []violation
+	for _, v := range violations {
+		got = append(got, violation{v.Policy, v.NodeID, v.Severity, v.Message})
+	}
+	if len(got) != len(want) {
+		t.Fatalf("expected %d violations, got %+v", len(want), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("violation %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+
+	// The bucket policy only applies in production
+	violations = checkPolicies(dc, g, policyContext{
+		Environment: "staging",
+		Variables:   map[string]interface{}{"max_replicas": "25"},
+	})
+	if len(violations) != 1 || violations[0].Policy != "memory-required" {
+		t.Errorf("expected only the memory warning in staging, got %+v", violations)
+	}
+}
+
+func TestCheckPolicies_EvaluationErrorIsViolation(t *testing.T) {
+	dcFile, compFile := writePolicyTestFiles(t, `
+policy "broken" {
+  resources = ["bucket"]
+  condition = node.inputs.missing == "x"
+  message   = "never shown"
+}
+`)
+	dc, err := datacenter.NewLoader().Load(dcFile)
+	if err != nil {
+		t.Fatalf("failed to load datacenter: %v", err)
+	}
+
+	violations := checkPolicies(dc, newPolicyTestGraph(t, compFile), policyContext{Environment: "staging"})
+	if len(violations) != 1 || violations[0].Severity != "error" {
+		t.Fatalf("expected one error violation, got %+v", violations)
+	}
+	if !strings.Contains(violations[0].Message, "failed to evaluate policy condition") {
+		t.Errorf("expected an evaluation error message, got %q", violations[0].Message)
+	}
+}
+
+func TestDeploy_PolicyViolations(t *testing.T) {
+	dcFile, compFile := writePolicyTestFiles(t, policyDatacenterHCL)
+
+	sm := newMockStateManager()
+	sm.datacenters = map[string]*types.DatacenterState{
+		"test-dc": {Name: "test-dc", Version: dcFile, Variables: map[string]string{"max_replicas": "25"}},
+	}
+	eng := NewEngine(sm, iac.DefaultRegistry)
+	opts := DeployOptions{
+		Environment: "production",
+		Datacenter:  "test-dc",
+		Components:  map[string]string{"app": compFile},
+		DryRun:      true,
+	}
+
+	// Error violations block the deploy before anything is planned
+	_, err := eng.Deploy(context.Background(), opts)
+	if !arcerrors.Is(err, arcerrors.ErrCodePolicyViolation) {
+		t.Fatalf("expected a policy violation error, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "app/bucket/files: Bucket files may not be public in production") {
+		t.Errorf("expected the bucket violation in the error, got %v", err)
+	}
+
+	// Warnings are attached to the plan and printed with it
+	opts.Environment = "staging"
+	var out bytes.Buffer
+	opts.Output = &out
+	result, err := eng.Deploy(context.Background(), opts)
+	if err != nil {
+		t.Fatalf("Deploy failed: %v", err)
+	}
+	if len(result.Plan.PolicyWarnings) != 1 || result.Plan.PolicyWarnings[0].NodeID != "app/deployment/api" {
+		t.Errorf("expected a memory warning for api, got %+v", result.Plan.PolicyWarnings)
+	}
+	if !strings.Contains(out.String(), `! app/deployment/api: Deployments must set memory (policy "memory-required")`) {
+		t.Errorf("expected the warning in the plan output, got:\n%s", out.String())
+	}
+
+	// Saved plans keep their warnings
+	saved, err := eng.SavePlan(context.Background(), opts, result)
+	if err != nil {
+		t.Fatalf("SavePlan failed: %v", err)
+	}
+	_, plan, err := saved.toPlan(nil)
+	if err != nil {
+		t.Fatalf("toPlan failed: %v", err)
+	}
+	if len(plan.PolicyWarnings) != 1 || plan.PolicyWarnings[0].Policy != "memory-required" {
+		t.Errorf("expected the warning to survive saving, got %+v", plan.PolicyWarnings)
+	}
+}
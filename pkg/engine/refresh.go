@@ -0,0 +1,322 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/davidthor/arcctl/pkg/engine/executor"
+	"github.com/davidthor/arcctl/pkg/graph"
+	"github.com/davidthor/arcctl/pkg/iac"
+	"github.com/davidthor/arcctl/pkg/state/types"
+)
+
+// RefreshOptions configures a refresh (drift detection) operation.
+type RefreshOptions struct {
+	// Datacenter name
+	Datacenter string
+
+	// Environment name
+	Environment string
+
+	// Component limits the refresh to a single component. When empty, all
+	// components and environment-scoped modules are refreshed.
+	Component string
+
+	// Output writer for progress
+	Output io.Writer
+
+	// OnProgress is called as each resource is refreshed
+	OnProgress executor.ProgressCallback
+}
+
+// RefreshResult contains the results of a refresh operation.
+type RefreshResult struct {
+	// Success is false if any resource could not be refreshed
+	Success bool
+
+	Duration time.Duration
+
+	// Resources holds one entry per refreshed resource or module, sorted by ID
+	Resources []ResourceRefresh
+}
+
+// ResourceRefresh describes the outcome of refreshing a single resource or
+// environment-scoped module.
+type ResourceRefresh struct {
+	// ID is the graph node ID (component/type/name) or env/<env>/module/<name>
+	ID        string
+	Component string
+	Name      string
+	Type      string
+	Plugin    string
+
+	// Drifts reported by the IaC plugin
+	Drifts []iac.ResourceDrift
+
+	// Error is set if the refresh failed for this resource
+	Error error
+}
+
+// HasDrift returns true if the plugin reported any drift for the resource.
+func (r ResourceRefresh) HasDrift() bool {
+	return len(r.Drifts) > 0
+}
+
+// DriftCount returns the number of refreshed resources that reported drift.
+func (r *RefreshResult) DriftCount() int {
+	count := 0
+	for _, res := range r.Resources {
+		if res.HasDrift() {
+			count++
+		}
+	}
+	return count
+}
+
+// Refresh runs iac.Plugin.Refresh for every resource and module recorded in an
+// environment's state, persists the refreshed IaC state, and reports drift
+// between the stored state and the real infrastructure.
+func (e *Engine) Refresh(ctx context.Context, opts RefreshOptions) (*RefreshResult, error) {
+	startTime := time.Now()
+	result := &RefreshResult{Success: true}
+
+	// Load datacenter state
+	dcState, err := e.stateManager.GetDatacenter(ctx, opts.Datacenter)
+	if err != nil {
+		return nil, fmt.Errorf("datacenter %q not found: %w", opts.Datacenter, err)
+	}
+
+	dcPath := dcState.Version
+	if dcPath == "" {
+		return nil, fmt.Errorf("datacenter %q has no source path configured", opts.Datacenter)
+	}
+	dc, err := e.loadDatacenterConfig(dcPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load datacenter configuration: %w", err)
+	}
+
+	// Build datacenter variables map
+	dcVars := make(map[string]interface{})
+	for k, v := range dcState.Variables {
+		dcVars[k] = v
+	}
+	for _, v := range dc.Variables() {
+		if _, ok := dcVars[v.Name()]; !ok && v.Default() != nil {
+			dcVars[v.Name()] = v.Default()
+		}
+	}
+	dcVars, err = e.resolveSecretVariables(ctx, dcVars)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve datacenter variables: %w", err)
+	}
+
+	envState, err := e.stateManager.GetEnvironment(ctx, opts.Datacenter, opts.Environment)
+	if err != nil {
+		return nil, fmt.Errorf("environment %q not found in datacenter %q: %w", opts.Environment, opts.Datacenter, err)
+	}
+
+	if opts.Component != "" {
+		if _, ok := envState.Components[opts.Component]; !ok {
+			return nil, fmt.Errorf("component %s not found in environment %s", opts.Component, opts.Environment)
+		}
+	}
+
+	// The executor is only used to resolve which hook module manages each resource
+	exec := executor.NewExecutor(e.stateManager, e.iacRegistry, executor.Options{
+		Output:              opts.Output,
+		Datacenter:          dc,
+		DatacenterVariables: dcVars,
+	})
+
+	changed := false
+
+	// Phase 1: Environment-scoped modules (only when refreshing the whole environment)
+	if opts.Component == "" {
+		for _, modName := range sortedModuleNames(envState.Modules) {
+			modState := envState.Modules[modName]
+			if modState.Status != types.ModuleStatusReady || modState.Plugin == "" {
+				continue
+			}
+
+			refresh := ResourceRefresh{
+				ID:     fmt.Sprintf("env/%s/module/%s", opts.Environment, modName),
+				Name:   modName,
+				Type:   "module",
+				Plugin: modState.Plugin,
+			}
+
+			state, drifts, err := e.refreshModule(ctx, modState.Plugin, modState.Source, modState.Inputs, modState.IaCState)
+			if err != nil {
+				refresh.Error = err
+				result.Success = false
+			} else {
+				refresh.Drifts = drifts
+				if len(state) > 0 {
+					modState.IaCState = state
+					modState.UpdatedAt = time.Now()
+					changed = true
+				}
+			}
+
+			e.reportRefresh(opts.OnProgress, refresh)
+			result.Resources = append(result.Resources, refresh)
+		}
+	}
+
+	// Phase 2: Component resources
+	for _, compName := range sortedComponentNames(envState.Components) {
+		if opts.Component != "" && compName != opts.Component {
+			continue
+		}
+		compState := envState.Components[compName]
+
+		for _, resKey := range sortedResourceKeys(compState.Resources) {
+			resState := compState.Resources[resKey]
+			if resState.Status != types.ResourceStatusReady {
+				continue
+			}
+			iacState, err := resState.LoadIaCState()
+			if err != nil {
+				return nil, err
+			}
+			if len(iacState) == 0 {
+				continue
+			}
+
+			node := graph.NewNode(graph.NodeType(resState.Type), compName, resState.Name)
+			if resState.Inputs != nil {
+				node.Inputs = resState.Inputs
+			}
+
+			refresh := ResourceRefresh{
+				ID:        node.ID,
+				Component: compName,
+				Name:      resState.Name,
+				Type:      resState.Type,
+			}
+
+			modulePath, moduleInputs, pluginName, err := exec.ResolveHook(node, opts.Environment)
+			if err != nil {
+				refresh.Error = fmt.Errorf("failed to find matching hook: %w", err)
+				result.Success = false
+				e.reportRefresh(opts.OnProgress, refresh)
+				result.Resources = append(result.Resources, refresh)
+				continue
+			}
+			refresh.Plugin = pluginName
+
+			state, drifts, err := e.refreshModule(ctx, pluginName, modulePath, moduleInputs, iacState)
+			if err != nil {
+				refresh.Error = err
+				result.Success = false
+			} else {
+				refresh.Drifts = drifts
+				if len(state) > 0 {
+					resState.IaCState = state
+					resState.UpdatedAt = time.Now()
+					changed = true
+				}
+			}
+
+			e.reportRefresh(opts.OnProgress, refresh)
+			result.Resources = append(result.Resources, refresh)
+		}
+	}
+
+	// Persist refreshed IaC state
+	if changed {
+		envState.UpdatedAt = time.Now()
+		if err := e.stateManager.SaveEnvironment(ctx, opts.Datacenter, envState); err != nil {
+			return nil, fmt.Errorf("failed to save refreshed state: %w", err)
+		}
+		var components []string
+		if opts.Component != "" {
+			components = []string{opts.Component}
+		}
+		e.recordHistory(ctx, opts.Datacenter, opts.Environment, "refresh", components, nil)
+	}
+
+	result.Duration = time.Since(startTime)
+	return result, nil
+}
+
+// refreshModule runs a plugin refresh against previously stored IaC state and
+// returns the refreshed state and any reported drift.
+func (e *Engine) refreshModule(ctx context.Context, pluginName, source string, inputs map[string]interface{}, iacState []byte) ([]byte, []iac.ResourceDrift, error) {
+	plugin, err := e.iacRegistry.Get(pluginName)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get IaC plugin %q: %w", pluginName, err)
+	}
+
+	runOpts := iac.RunOptions{
+		ModuleSource: source,
+		Inputs:       inputs,
+		Environment:  map[string]string{},
+	}
+	if len(iacState) > 0 {
+		runOpts.StateReader = bytes.NewReader(iacState)
+	}
+
+	refreshResult, err := plugin.Refresh(ctx, runOpts)
+	if err != nil {
+		return nil, nil, fmt.Errorf("refresh failed: %w", err)
+	}
+
+	return refreshResult.State, refreshResult.Drifts, nil
+}
+
+// reportRefresh fires a progress event for a refreshed resource.
+func (e *Engine) reportRefresh(onProgress executor.ProgressCallback, refresh ResourceRefresh) {
+	if onProgress == nil {
+		return
+	}
+
+	event := executor.ProgressEvent{
+		NodeID:   refresh.ID,
+		NodeName: refresh.Name,
+		NodeType: refresh.Type,
+		Status:   "completed",
+	}
+	switch {
+	case refresh.Error != nil:
+		event.Status = "failed"
+		event.Message = refresh.Error.Error()
+		event.Error = refresh.Error
+	case refresh.HasDrift():
+		event.Message = fmt.Sprintf("%d drifted resource(s)", len(refresh.Drifts))
+	default:
+		event.Message = "no drift"
+	}
+	onProgress(event)
+}
+
+func sortedModuleNames(m map[string]*types.ModuleState) []string {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func sortedComponentNames(m map[string]*types.ComponentState) []string {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func sortedResourceKeys(m map[string]*types.ResourceState) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,157 @@
+package engine
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/davidthor/arcctl/pkg/iac"
+	"github.com/davidthor/arcctl/pkg/state/types"
+)
+
+// refreshTestPlugin is an IaC plugin that reports a fixed drift on refresh.
+type refreshTestPlugin struct{}
+
+func (p *refreshTestPlugin) Name() string { return "refresh-test" }
+
+func (p *refreshTestPlugin) Preview(ctx context.Context, opts iac.RunOptions) (*iac.PreviewResult, error) {
+	return &iac.PreviewResult{}, nil
+}
+
+func (p *refreshTestPlugin) Apply(ctx context.Context, opts iac.RunOptions) (*iac.ApplyResult, error) {
+	return &iac.ApplyResult{}, nil
+}
+
+func (p *refreshTestPlugin) Destroy(ctx context.Context, opts iac.RunOptions) error {
+	return nil
+}
+
+func (p *refreshTestPlugin) Refresh(ctx context.Context, opts iac.RunOptions) (*iac.RefreshResult, error) {
+	return &iac.RefreshResult{
+		State: []byte(`{"refreshed":true}`),
+		Drifts: []iac.ResourceDrift{
+			{
+				ResourceID:   "aws_db_instance.main",
+				ResourceType: "aws_db_instance",
+				Diffs: []iac.PropertyDiff{
+					{Path: "instance_class", OldValue: "db.t3.micro", NewValue: "db.t3.large"},
+				},
+			},
+		},
+	}, nil
+}
+
+func init() {
+	iac.Register("refresh-test", func() (iac.Plugin, error) {
+		return &refreshTestPlugin{}, nil
+	})
+}
+
+const refreshDatacenterHCL = `
+environment {
+  database {
+    module "db" {
+      plugin = "refresh-test"
+      build  = "./modules/db"
+      inputs = {
+        name = node.name
+      }
+    }
+
+    outputs = {
+      url = module.db.url
+    }
+  }
+}
+`
+
+func newRefreshTestEngine(t *testing.T) (*Engine, *mockStateManager) {
+	t.Helper()
+
+	tmpDir := t.TempDir()
+	dcFile := filepath.Join(tmpDir, "datacenter.hcl")
+	if err := os.WriteFile(dcFile, []byte(refreshDatacenterHCL), 0644); err != nil {
+		t.Fatalf("failed to write test file: %v", err)
+	}
+
+	sm := newMockStateManager()
+	sm.datacenters = map[string]*types.DatacenterState{
+		"test-dc": {Name: "test-dc", Version: dcFile},
+	}
+	sm.environments["test-dc/staging"] = &types.EnvironmentState{
+		Name:       "staging",
+		Datacenter: "test-dc",
+		Components: map[string]*types.ComponentState{
+			"api": {
+				Name: "api",
+				Resources: map[string]*types.ResourceState{
+					"database.main": {
+						Name:      "main",
+						Type:      "database",
+						Component: "api",
+						Status:    types.ResourceStatusReady,
+						Inputs:    map[string]interface{}{"type": "postgres"},
+						IaCState:  []byte(`{"refreshed":false}`),
+					},
+					"database.pending": {
+						Name:      "pending",
+						Type:      "database",
+						Component: "api",
+						Status:    types.ResourceStatusProvisioning,
+					},
+				},
+			},
+		},
+	}
+
+	return NewEngine(sm, iac.DefaultRegistry), sm
+}
+
+func TestRefresh_ReportsDriftAndPersistsState(t *testing.T) {
+	eng, sm := newRefreshTestEngine(t)
+
+	result, err := eng.Refresh(context.Background(), RefreshOptions{
+		Datacenter:  "test-dc",
+		Environment: "staging",
+	})
+	if err != nil {
+		t.Fatalf("Refresh failed: %v", err)
+	}
+
+	if !result.Success {
+		t.Errorf("expected success, got errors: %+v", result.Resources)
+	}
+	if len(result.Resources) != 1 {
+		t.Fatalf("expected 1 refreshed resource (pending resources are skipped), got %d", len(result.Resources))
+	}
+
+	res := result.Resources[0]
+	if res.ID != "api/database/main" {
+		t.Errorf("ID: got %q", res.ID)
+	}
+	if res.Plugin != "refresh-test" {
+		t.Errorf("Plugin: got %q", res.Plugin)
+	}
+	if !res.HasDrift() || result.DriftCount() != 1 {
+		t.Errorf("expected drift to be reported, got %+v", res.Drifts)
+	}
+
+	saved := sm.environments["test-dc/staging"].Components["api"].Resources["database.main"]
+	if string(saved.IaCState) != `{"refreshed":true}` {
+		t.Errorf("expected refreshed IaC state to be persisted, got %s", saved.IaCState)
+	}
+}
+
+func TestRefresh_ComponentNotFound(t *testing.T) {
+	eng, _ := newRefreshTestEngine(t)
+
+	_, err := eng.Refresh(context.Background(), RefreshOptions{
+		Datacenter:  "test-dc",
+		Environment: "staging",
+		Component:   "missing",
+	})
+	if err == nil {
+		t.Fatal("expected error for unknown component")
+	}
+}
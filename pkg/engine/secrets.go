@@ -0,0 +1,119 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+
+	"github.com/davidthor/arcctl/pkg/schema/datacenter"
+	"github.com/davidthor/arcctl/pkg/secrets"
+	"github.com/davidthor/arcctl/pkg/state/types"
+)
+
+// SetSecretsManager sets the secrets manager used to resolve secret
+// references (e.g. vault://path#field) in variables at deploy time.
+func (e *Engine) SetSecretsManager(m *secrets.Manager) {
+	e.secrets = m
+}
+
+// resolveSecretVariables returns a copy of vars with secret references
+// resolved. The input map is not modified so unresolved references can still
+// be persisted to state.
+func (e *Engine) resolveSecretVariables(ctx context.Context, vars map[string]interface{}) (map[string]interface{}, error) {
+	if vars == nil || e.secrets == nil {
+		return vars, nil
+	}
+	return e.secrets.ResolveSecrets(ctx, vars)
+}
+
+// resolveComponentSecretVariables resolves secret references in per-component
+// variables.
+func (e *Engine) resolveComponentSecretVariables(ctx context.Context, vars map[string]map[string]interface{}) (map[string]map[string]interface{}, error) {
+	if vars == nil {
+		return nil, nil
+	}
+
+	resolved := make(map[string]map[string]interface{}, len(vars))
+	for compName, compVars := range vars {
+		r, err := e.resolveSecretVariables(ctx, compVars)
+		if err != nil {
+			return nil, fmt.Errorf("component %s: %w", compName, err)
+		}
+		resolved[compName] = r
+	}
+	return resolved, nil
+}
+
+// sensitiveComponentVariables returns the names of the variables each
+// component declares sensitive. Components that fail to load are skipped;
+// they have already been loaded successfully when building the graph.
+func (e *Engine) sensitiveComponentVariables(components map[string]string) map[string][]string {
+	result := make(map[string][]string)
+	for compName, compPath := range components {
+		comp, err := e.compLoader.Load(compPath)
+		if err != nil {
+			continue
+		}
+		for _, v := range comp.Variables() {
+			if v.Sensitive() {
+				result[compName] = append(result[compName], v.Name())
+			}
+		}
+		sort.Strings(result[compName])
+	}
+	return result
+}
+
+// SensitiveDatacenterVariables returns the names of the variables the
+// datacenter declares sensitive.
+func SensitiveDatacenterVariables(dc datacenter.Datacenter) []string {
+	var names []string
+	for _, v := range dc.Variables() {
+		if v.Sensitive() {
+			names = append(names, v.Name())
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// moduleReferencePattern matches the variable and module output references in
+// a module input expression.
+var moduleReferencePattern = regexp.MustCompile(`\b(variable|module)\.([\w-]+)(?:\.([\w-]+))?`)
+
+// sensitiveModuleInputs returns the sorted names of a datacenter module's
+// inputs that hold sensitive values: those whose expressions reference a
+// variable the datacenter declares sensitive, a variable given as a secret
+// reference, or a sensitive output of one of modules.
+func sensitiveModuleInputs(dc datacenter.Datacenter, vars map[string]string, modules map[string]*types.ModuleState, inputs map[string]string) []string {
+	sensitiveVars := make(map[string]bool)
+	for _, name := range SensitiveDatacenterVariables(dc) {
+		sensitiveVars[name] = true
+	}
+	for name, value := range vars {
+		if secrets.IsReference(value) {
+			sensitiveVars[name] = true
+		}
+	}
+
+	var names []string
+	for name, expr := range inputs {
+		for _, ref := range moduleReferencePattern.FindAllStringSubmatch(expr, -1) {
+			sensitive := false
+			switch ref[1] {
+			case "variable":
+				sensitive = sensitiveVars[ref[2]]
+			case "module":
+				mod := modules[ref[2]]
+				sensitive = mod != nil && mod.IsSensitiveOutput(ref[3])
+			}
+			if sensitive {
+				names = append(names, name)
+				break
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,227 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/davidthor/arcctl/pkg/iac"
+	"github.com/davidthor/arcctl/pkg/schema/datacenter"
+	"github.com/davidthor/arcctl/pkg/secrets"
+	"github.com/davidthor/arcctl/pkg/state/types"
+)
+
+func newTestSecretsManager(values map[string]string) *secrets.Manager {
+	m := secrets.NewManager()
+	m.RegisterProvider(secrets.NewStaticProvider("vault", values))
+	return m
+}
+
+func TestSensitiveModuleInputs(t *testing.T) {
+	dc, err := datacenter.NewLoader().LoadFromBytes([]byte(`
+variable "region" {
+  type = string
+}
+
+variable "db_password" {
+  type      = string
+  sensitive = true
+}
+
+variable "api_token" {
+  type = string
+}
+`), "datacenter.dc")
+	if err != nil {
+		t.Fatalf("failed to load datacenter: %v", err)
+	}
+
+	vars := map[string]string{"region": "us-east-1", "db_password": "hunter2", "api_token": "vault://ci#token"}
+	modules := map[string]*types.ModuleState{
+		"network": {SensitiveOutputs: []string{"vpn_key"}},
+	}
+	got := sensitiveModuleInputs(dc, vars, modules, map[string]string{
+		"region":    "variable.region",
+		"password":  "variable.db_password",
+		"token":     "Bearer ${variable.api_token}",
+		"vpc_id":    "module.network.vpc_id",
+		"vpn_key":   "module.network.vpn_key",
+		"namespace": "${environment.name}",
+	})
+
+	want := []string{"password", "token", "vpn_key"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("sensitiveModuleInputs() = %v, want %v", got, want)
+	}
+}
+
+func TestResolveComponentSecretVariables(t *testing.T) {
+	eng := NewEngine(newMockStateManager(), nil)
+	eng.SetSecretsManager(newTestSecretsManager(map[string]string{"db#password": "s3cret"}))
+
+	vars := map[string]map[string]interface{}{
+		"api": {"password": "vault://db#password", "region": "us-east-1"},
+	}
+
+	resolved, err := eng.resolveComponentSecretVariables(context.Background(), vars)
+	if err != nil {
+		t.Fatalf("resolve failed: %v", err)
+	}
+
+	if resolved["api"]["password"] != "s3cret" {
+		t.Errorf("password: got %v", resolved["api"]["password"])
+	}
+	if resolved["api"]["region"] != "us-east-1" {
+		t.Errorf("region: got %v", resolved["api"]["region"])
+	}
+	if vars["api"]["password"] != "vault://db#password" {
+		t.Error("input variables should not be modified")
+	}
+
+	_, err = eng.resolveComponentSecretVariables(context.Background(), map[string]map[string]interface{}{
+		"api": {"password": "vault://missing#password"},
+	})
+	if err == nil {
+		t.Error("expected error for unresolvable secret reference")
+	}
+}
+
+// secretsTestDatacenterHCL provisions databases and deployments.
+const secretsTestDatacenterHCL = `
+environment {
+  database {
+    module "db" {
+      plugin = "refresh-test"
+      build  = "./modules/db"
+    }
+  }
+
+  deployment {
+    module "app" {
+      plugin = "refresh-test"
+      build  = "./modules/app"
+    }
+  }
+}
+`
+
+// secretsTestComponentYAML passes a variable to a deployment.
+const secretsTestComponentYAML = planTestComponentYAML + `
+deployments:
+  api:
+    image: nginx:latest
+    environment:
+      DB_PASSWORD: ${{ variables.password }}
+`
+
+func TestDeploy_SecretReferencesNotPersisted(t *testing.T) {
+	eng, sm, opts := newPlanTestEngine(t)
+	eng.SetSecretsManager(newTestSecretsManager(map[string]string{"db#password": "s3cret"}))
+	if err := os.WriteFile(sm.datacenters["test-dc"].Version, []byte(secretsTestDatacenterHCL), 0644); err != nil {
+		t.Fatalf("failed to write datacenter: %v", err)
+	}
+	if err := os.WriteFile(opts.Components["api"], []byte(secretsTestComponentYAML), 0644); err != nil {
+		t.Fatalf("failed to write component: %v", err)
+	}
+
+	opts.DryRun = false
+	opts.Variables = map[string]map[string]interface{}{
+		"api": {"password": "vault://db#password"},
+	}
+
+	result, err := eng.Deploy(context.Background(), opts)
+	if err != nil {
+		t.Fatalf("Deploy failed: %v", err)
+	}
+	if !result.Success {
+		t.Fatalf("expected deploy to succeed, got %+v", result.Execution)
+	}
+
+	envState := sm.environments["test-dc/staging"]
+	compState := envState.Components["api"]
+	if got := compState.Variables["password"]; got != "vault://db#password" {
+		t.Errorf("expected secret reference to be stored in state, got %q", got)
+	}
+
+	// Resource inputs record the expression rather than the resolved secret
+	res := compState.Resources["deployment.api"]
+	if res == nil {
+		t.Fatalf("expected the deployment to be recorded, got %v", compState.Resources)
+	}
+	env, _ := res.Inputs["environment"].(map[string]string)
+	if env["DB_PASSWORD"] != "${{ variables.password }}" {
+		t.Errorf("expected the secret variable's expression to be recorded, got %v", res.Inputs["environment"])
+	}
+
+	data, err := json.Marshal(envState)
+	if err != nil {
+		t.Fatalf("failed to encode state: %v", err)
+	}
+	if strings.Contains(string(data), "s3cret") {
+		t.Errorf("expected the resolved secret not to be stored in state, got:\n%s", data)
+	}
+}
+
+// inputsTestPlugin records the inputs each module is applied with.
+type inputsTestPlugin struct {
+	refreshTestPlugin
+	applied map[string]map[string]interface{}
+}
+
+func (p *inputsTestPlugin) Name() string { return "inputs-test" }
+
+func (p *inputsTestPlugin) Apply(ctx context.Context, opts iac.RunOptions) (*iac.ApplyResult, error) {
+	p.applied[filepath.Base(opts.ModuleSource)] = opts.Inputs
+	return &iac.ApplyResult{}, nil
+}
+
+// secretsTestRootModuleHCL passes a datacenter variable to a root module.
+const secretsTestRootModuleHCL = `
+variable "api_token" {
+  type = string
+}
+
+module "dns" {
+  plugin = "inputs-test"
+  build  = "./modules/dns"
+  inputs = {
+    token = "Bearer $${variable.api_token}"
+  }
+}
+`
+
+func TestDeployDatacenter_ResolvesSecretReferences(t *testing.T) {
+	dcFile := filepath.Join(t.TempDir(), "datacenter.hcl")
+	if err := os.WriteFile(dcFile, []byte(secretsTestRootModuleHCL), 0644); err != nil {
+		t.Fatalf("failed to write datacenter: %v", err)
+	}
+
+	sm := newMockStateManager()
+	sm.datacenters = map[string]*types.DatacenterState{
+		"test-dc": {Name: "test-dc", Version: dcFile, Variables: map[string]string{"api_token": "vault://dns#token"}},
+	}
+	plugin := &inputsTestPlugin{applied: make(map[string]map[string]interface{})}
+	iac.Register("inputs-test", func() (iac.Plugin, error) { return plugin, nil })
+
+	eng := NewEngine(sm, iac.DefaultRegistry)
+	eng.SetSecretsManager(newTestSecretsManager(map[string]string{"dns#token": "s3cret"}))
+
+	if _, err := eng.DeployDatacenter(context.Background(), DeployDatacenterOptions{Datacenter: "test-dc"}); err != nil {
+		t.Fatalf("DeployDatacenter failed: %v", err)
+	}
+
+	if got := plugin.applied["dns"]["token"]; got != "Bearer s3cret" {
+		t.Errorf("expected the root module to receive the resolved secret, got %v", got)
+	}
+
+	dcState := sm.datacenters["test-dc"]
+	if got := dcState.Variables["api_token"]; got != "vault://dns#token" {
+		t.Errorf("expected the secret reference to be kept in state, got %q", got)
+	}
+	if mod := dcState.Modules["dns"]; mod == nil || strings.Join(mod.SensitiveInputs, ",") != "token" {
+		t.Errorf("expected the resolved input to be recorded as sensitive, got %+v", mod)
+	}
+}
@@ -0,0 +1,418 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/davidthor/arcctl/pkg/graph"
+	"github.com/davidthor/arcctl/pkg/state"
+	"github.com/davidthor/arcctl/pkg/state/types"
+)
+
+// StateAddress identifies a component, or a resource within a component, in
+// an environment's state. It is written as environment/component for a
+// component, and environment/component/type/name for a resource. The type
+// may be left out when the name is unambiguous.
+type StateAddress struct {
+	Environment string
+	Component   string
+	Type        string
+	Name        string
+}
+
+// ParseStateAddress parses an address of the form
+// environment/component[/[type/]name].
+func ParseStateAddress(s string) (StateAddress, error) {
+	parts := strings.Split(strings.TrimSpace(s), "/")
+	for _, p := range parts {
+		if p == "" {
+			return StateAddress{}, fmt.Errorf("invalid address %q: expected environment/component[/type/name]", s)
+		}
+	}
+
+	switch len(parts) {
+	case 2:
+		return StateAddress{Environment: parts[0], Component: parts[1]}, nil
+	case 3:
+		return StateAddress{Environment: parts[0], Component: parts[1], Name: parts[2]}, nil
+	case 4:
+		return StateAddress{Environment: parts[0], Component: parts[1], Type: parts[2], Name: parts[3]}, nil
+	default:
+		return StateAddress{}, fmt.Errorf("invalid address %q: expected environment/component[/type/name]", s)
+	}
+}
+
+// IsComponent reports whether the address refers to a whole component.
+func (a StateAddress) IsComponent() bool {
+	return a.Name == ""
+}
+
+func (a StateAddress) String() string {
+	s := a.Environment + "/" + a.Component
+	if a.Type != "" {
+		s += "/" + a.Type
+	}
+	if a.Name != "" {
+		s += "/" + a.Name
+	}
+	return s
+}
+
+// MoveStateOptions configures a state move.
+type MoveStateOptions struct {
+	// Datacenter name
+	Datacenter string
+
+	// From is the component or resource to move
+	From StateAddress
+
+	// To is the new address. Components move to components and resources to
+	// resources of the same type.
+	To StateAddress
+
+	// Output writer for warnings
+	Output io.Writer
+}
+
+// RemoveStateOptions configures a state removal.
+type RemoveStateOptions struct {
+	// Datacenter name
+	Datacenter string
+
+	// Addresses of the components and resources to remove
+	Addresses []StateAddress
+
+	// Output writer for warnings
+	Output io.Writer
+}
+
+// ResourceState returns the state recorded for a single resource.
+func (e *Engine) ResourceState(ctx context.Context, datacenter string, addr StateAddress) (*types.ResourceState, error) {
+	if addr.IsComponent() {
+		return nil, fmt.Errorf("%s is a component; specify a resource as environment/component/type/name", addr)
+	}
+
+	envState, err := e.stateManager.GetEnvironment(ctx, datacenter, addr.Environment)
+	if err != nil {
+		return nil, fmt.Errorf("environment %q not found in datacenter %q: %w", addr.Environment, datacenter, err)
+	}
+	comp, ok := envState.Components[addr.Component]
+	if !ok {
+		return nil, fmt.Errorf("component %q not found in environment %q", addr.Component, addr.Environment)
+	}
+	_, res, err := findStateResource(comp, addr)
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// MoveState moves a component or resource to a new address without changing
+// the infrastructure it tracks. Renaming a component or resource in state
+// before deploying the renamed component updates the existing resources in
+// place instead of destroying and recreating them.
+//
+// The environments involved are locked for the duration of the move. The
+// destination of a resource move is validated against the schema of the
+// component it is moved into, when that component's source can be loaded.
+func (e *Engine) MoveState(ctx context.Context, opts MoveStateOptions) error {
+	from, to := opts.From, opts.To
+	if from.IsComponent() != to.IsComponent() {
+		return fmt.Errorf("can't move %s to %s: components can only be moved to components, and resources to resources", from, to)
+	}
+
+	release, err := e.lockEnvironments(ctx, opts.Datacenter, "state mv", from.Environment, to.Environment)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	srcEnv, err := e.stateManager.GetEnvironment(ctx, opts.Datacenter, from.Environment)
+	if err != nil {
+		return fmt.Errorf("environment %q not found in datacenter %q: %w", from.Environment, opts.Datacenter, err)
+	}
+	dstEnv := srcEnv
+	if to.Environment != from.Environment {
+		dstEnv, err = e.stateManager.GetEnvironment(ctx, opts.Datacenter, to.Environment)
+		if err != nil {
+			return fmt.Errorf("environment %q not found in datacenter %q: %w", to.Environment, opts.Datacenter, err)
+		}
+	}
+
+	srcComp, ok := srcEnv.Components[from.Component]
+	if !ok {
+		return fmt.Errorf("component %q not found in environment %q", from.Component, from.Environment)
+	}
+
+	if from.IsComponent() {
+		if _, exists := dstEnv.Components[to.Component]; exists {
+			return fmt.Errorf("component %q already exists in environment %q", to.Component, to.Environment)
+		}
+		moveComponent(srcEnv, dstEnv, from.Component, to.Component)
+	} else {
+		key, res, err := findStateResource(srcComp, from)
+		if err != nil {
+			return err
+		}
+		if to.Type == "" {
+			to.Type = res.Type
+		}
+		if to.Type != res.Type {
+			return fmt.Errorf("can't move %s to %s: resources can't change type", from, to)
+		}
+
+		dstComp, ok := dstEnv.Components[to.Component]
+		if !ok {
+			return fmt.Errorf("component %q not found in environment %q", to.Component, to.Environment)
+		}
+		newKey := to.Type + "." + to.Name
+		if _, exists := dstComp.Resources[newKey]; exists && (dstComp != srcComp || newKey != key) {
+			return fmt.Errorf("resource %s already exists", to)
+		}
+
+		if err := e.validateDeclared(ctx, opts.Datacenter, to, dstComp, opts.Output); err != nil {
+			return err
+		}
+
+		delete(srcComp.Resources, key)
+		res.Component = to.Component
+		res.Name = to.Name
+		res.UpdatedAt = time.Now()
+		if dstComp.Resources == nil {
+			dstComp.Resources = make(map[string]*types.ResourceState)
+		}
+		dstComp.Resources[newKey] = res
+	}
+
+	// Save the destination first, so a failure part way through a move
+	// between environments leaves the resource tracked in both rather than
+	// in neither
+	if dstEnv != srcEnv {
+		if err := e.saveEditedEnvironment(ctx, opts.Datacenter, dstEnv); err != nil {
+			return err
+		}
+	}
+	if err := e.saveEditedEnvironment(ctx, opts.Datacenter, srcEnv); err != nil {
+		if dstEnv != srcEnv {
+			return fmt.Errorf("%s was added to environment %q but not removed from %q: %w", to, to.Environment, from.Environment, err)
+		}
+		return err
+	}
+
+	e.recordHistory(ctx, opts.Datacenter, to.Environment, "state mv", []string{to.Component}, opts.Output)
+	if dstEnv != srcEnv {
+		e.recordHistory(ctx, opts.Datacenter, from.Environment, "state mv", []string{from.Component}, opts.Output)
+	}
+	return nil
+}
+
+// RemoveState removes components and resources from state without destroying
+// them. A warning is written for each removed resource that its component
+// still declares, since the next deploy of the component will create it again.
+func (e *Engine) RemoveState(ctx context.Context, opts RemoveStateOptions) error {
+	byEnv := make(map[string][]StateAddress)
+	var envNames []string
+	for _, addr := range opts.Addresses {
+		if _, ok := byEnv[addr.Environment]; !ok {
+			envNames = append(envNames, addr.Environment)
+		}
+		byEnv[addr.Environment] = append(byEnv[addr.Environment], addr)
+	}
+
+	release, err := e.lockEnvironments(ctx, opts.Datacenter, "state rm", envNames...)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	// Resolve every address before changing anything, so a typo doesn't
+	// leave a removal half done
+	envStates := make(map[string]*types.EnvironmentState)
+	for _, envName := range envNames {
+		envState, err := e.stateManager.GetEnvironment(ctx, opts.Datacenter, envName)
+		if err != nil {
+			return fmt.Errorf("environment %q not found in datacenter %q: %w", envName, opts.Datacenter, err)
+		}
+		envStates[envName] = envState
+
+		for _, addr := range byEnv[envName] {
+			comp, ok := envState.Components[addr.Component]
+			if !ok {
+				return fmt.Errorf("component %q not found in environment %q", addr.Component, envName)
+			}
+			if !addr.IsComponent() {
+				if _, _, err := findStateResource(comp, addr); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	for _, envName := range envNames {
+		envState := envStates[envName]
+		var components []string
+		for _, addr := range byEnv[envName] {
+			comp, ok := envState.Components[addr.Component]
+			if !ok {
+				continue // Component already removed by an earlier address
+			}
+			components = append(components, addr.Component)
+
+			if addr.IsComponent() {
+				delete(envState.Components, addr.Component)
+				continue
+			}
+
+			key, res, err := findStateResource(comp, addr)
+			if err != nil {
+				continue // Resource already removed by an earlier address
+			}
+			delete(comp.Resources, key)
+
+			addr.Type = res.Type
+			if declared, err := e.declaresResource(ctx, opts.Datacenter, addr, comp); err == nil && declared && opts.Output != nil {
+				fmt.Fprintf(opts.Output, "Warning: component %q still declares %s/%s; the next deploy of the component will create it again\n",
+					addr.Component, res.Type, res.Name)
+			}
+		}
+
+		if err := e.saveEditedEnvironment(ctx, opts.Datacenter, envState); err != nil {
+			return err
+		}
+		e.recordHistory(ctx, opts.Datacenter, envName, "state rm", uniqueStrings(components), opts.Output)
+	}
+
+	return nil
+}
+
+// moveComponent moves a component's state from one environment to another,
+// or renames it within an environment, and updates the dependencies that
+// other components in the source environment record on it.
+func moveComponent(srcEnv, dstEnv *types.EnvironmentState, from, to string) {
+	comp := srcEnv.Components[from]
+	delete(srcEnv.Components, from)
+
+	comp.Name = to
+	comp.UpdatedAt = time.Now()
+	for _, res := range comp.Resources {
+		res.Component = to
+	}
+
+	if dstEnv.Components == nil {
+		dstEnv.Components = make(map[string]*types.ComponentState)
+	}
+	dstEnv.Components[to] = comp
+
+	if srcEnv != dstEnv {
+		return
+	}
+	for _, other := range srcEnv.Components {
+		for i, dep := range other.Dependencies {
+			if dep == from {
+				other.Dependencies[i] = to
+			}
+		}
+	}
+}
+
+// findStateResource finds the resource an address refers to within a
+// component's state, returning its key in the component's resource map.
+func findStateResource(comp *types.ComponentState, addr StateAddress) (string, *types.ResourceState, error) {
+	var keys []string
+	for key, res := range comp.Resources {
+		if res.Name == addr.Name && (addr.Type == "" || res.Type == addr.Type) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	switch len(keys) {
+	case 0:
+		return "", nil, fmt.Errorf("resource %s not found", addr)
+	case 1:
+		return keys[0], comp.Resources[keys[0]], nil
+	default:
+		var qualified []string
+		for _, key := range keys {
+			res := comp.Resources[key]
+			qualified = append(qualified, addr.Environment+"/"+addr.Component+"/"+res.Type+"/"+res.Name)
+		}
+		return "", nil, fmt.Errorf("resource %s is ambiguous; qualify it with its type:\n  %s", addr, strings.Join(qualified, "\n  "))
+	}
+}
+
+// validateDeclared checks that the component a resource is moved into
+// declares it. If the component's source can't be loaded, a warning is
+// written instead.
+func (e *Engine) validateDeclared(ctx context.Context, datacenter string, addr StateAddress, comp *types.ComponentState, output io.Writer) error {
+	declared, err := e.declaresResource(ctx, datacenter, addr, comp)
+	if err != nil {
+		if output != nil {
+			fmt.Fprintf(output, "Warning: can't validate %s against component %q: %v\n", addr, addr.Component, err)
+		}
+		return nil
+	}
+	if !declared {
+		return fmt.Errorf("component %q doesn't declare %s/%s; rename the resource in the component first", addr.Component, addr.Type, addr.Name)
+	}
+	return nil
+}
+
+// declaresResource reports whether the component deployed at addr declares
+// the resource, by loading the component from the source recorded in state.
+func (e *Engine) declaresResource(ctx context.Context, datacenter string, addr StateAddress, comp *types.ComponentState) (bool, error) {
+	loaded, err := e.loadRecordedComponent(ctx, comp.Source)
+	if err != nil {
+		return false, err
+	}
+
+	builder := graph.NewBuilder(addr.Environment, datacenter)
+	if err := builder.AddComponent(addr.Component, loaded); err != nil {
+		return false, err
+	}
+	node := graph.NewNode(graph.NodeType(addr.Type), addr.Component, addr.Name)
+	return builder.Build().GetNode(node.ID) != nil, nil
+}
+
+// lockEnvironments locks each named environment for a state edit, in name
+// order so concurrent edits can't deadlock. The returned function releases
+// the locks.
+func (e *Engine) lockEnvironments(ctx context.Context, datacenter, operation string, environments ...string) (func(), error) {
+	var scopes []state.LockScope
+	for _, env := range uniqueStrings(environments) {
+		scopes = append(scopes, state.LockScope{
+			Datacenter:  datacenter,
+			Environment: env,
+			Operation:   operation,
+			Who:         currentUser(),
+		})
+	}
+	return state.LockAll(ctx, e.stateManager.Lock, scopes...)
+}
+
+// saveEditedEnvironment saves an environment after a state edit.
+func (e *Engine) saveEditedEnvironment(ctx context.Context, datacenter string, envState *types.EnvironmentState) error {
+	envState.UpdatedAt = time.Now()
+	if err := e.stateManager.SaveEnvironment(ctx, datacenter, envState); err != nil {
+		return fmt.Errorf("failed to save environment %q: %w", envState.Name, err)
+	}
+	return nil
+}
+
+// uniqueStrings returns the distinct values in s, sorted.
+func uniqueStrings(s []string) []string {
+	seen := make(map[string]bool, len(s))
+	var result []string
+	for _, v := range s {
+		if !seen[v] {
+			seen[v] = true
+			result = append(result, v)
+		}
+	}
+	sort.Strings(result)
+	return result
+}
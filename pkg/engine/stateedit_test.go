@@ -0,0 +1,284 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/davidthor/arcctl/pkg/state/types"
+)
+
+func TestParseStateAddress(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    StateAddress
+		wantErr bool
+	}{
+		{input: "staging/api", want: StateAddress{Environment: "staging", Component: "api"}},
+		{input: "staging/api/main", want: StateAddress{Environment: "staging", Component: "api", Name: "main"}},
+		{input: "staging/api/database/main", want: StateAddress{Environment: "staging", Component: "api", Type: "database", Name: "main"}},
+		{input: "staging", wantErr: true},
+		{input: "staging//main", wantErr: true},
+		{input: "a/b/c/d/e", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseStateAddress(tt.input)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("expected an error, got %+v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseStateAddress failed: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+			if got.String() != tt.input {
+				t.Errorf("String() = %q, want %q", got.String(), tt.input)
+			}
+		})
+	}
+}
+
+// newStateEditTestEngine returns an engine whose staging environment has an
+// "api" component, deployed from a source that declares database "main", with
+// a database recorded under its old name "legacy".
+func newStateEditTestEngine(t *testing.T) (*Engine, *mockStateManager) {
+	t.Helper()
+	eng, sm, opts := newPlanTestEngine(t)
+
+	sm.environments["test-dc/staging"] = &types.EnvironmentState{
+		Name:       "staging",
+		Datacenter: "test-dc",
+		Components: map[string]*types.ComponentState{
+			"api": {
+				Name:   "api",
+				Source: opts.Components["api"],
+				Resources: map[string]*types.ResourceState{
+					"database.legacy": {Name: "legacy", Type: "database", Component: "api", Status: types.ResourceStatusReady},
+					"deployment.web":  {Name: "web", Type: "deployment", Component: "api"},
+				},
+			},
+			"worker": {
+				Name:         "worker",
+				Dependencies: []string{"api"},
+			},
+		},
+	}
+	sm.environments["test-dc/preview"] = &types.EnvironmentState{Name: "preview", Datacenter: "test-dc"}
+	return eng, sm
+}
+
+func TestMoveState_Resource(t *testing.T) {
+	eng, sm := newStateEditTestEngine(t)
+	ctx := context.Background()
+
+	err := eng.MoveState(ctx, MoveStateOptions{
+		Datacenter: "test-dc",
+		From:       StateAddress{Environment: "staging", Component: "api", Name: "legacy"},
+		To:         StateAddress{Environment: "staging", Component: "api", Name: "main"},
+	})
+	if err != nil {
+		t.Fatalf("MoveState failed: %v", err)
+	}
+
+	resources := sm.environments["test-dc/staging"].Components["api"].Resources
+	if _, ok := resources["database.legacy"]; ok {
+		t.Error("expected the old key to be removed")
+	}
+	res := resources["database.main"]
+	if res == nil || res.Name != "main" || res.Status != types.ResourceStatusReady {
+		t.Errorf("expected the resource under its new name, got %+v", res)
+	}
+	if len(sm.snapshots) == 0 {
+		t.Error("expected the move to be recorded in history")
+	}
+}
+
+func TestMoveState_ValidatesAgainstComponent(t *testing.T) {
+	eng, sm := newStateEditTestEngine(t)
+
+	err := eng.MoveState(context.Background(), MoveStateOptions{
+		Datacenter: "test-dc",
+		From:       StateAddress{Environment: "staging", Component: "api", Type: "database", Name: "legacy"},
+		To:         StateAddress{Environment: "staging", Component: "api", Type: "database", Name: "other"},
+	})
+	if err == nil || !strings.Contains(err.Error(), `component "api" doesn't declare database/other`) {
+		t.Fatalf("expected a schema validation error, got %v", err)
+	}
+	if _, ok := sm.environments["test-dc/staging"].Components["api"].Resources["database.legacy"]; !ok {
+		t.Error("expected state to be unchanged after a failed move")
+	}
+
+	err = eng.MoveState(context.Background(), MoveStateOptions{
+		Datacenter: "test-dc",
+		From:       StateAddress{Environment: "staging", Component: "api", Name: "legacy"},
+		To:         StateAddress{Environment: "staging", Component: "api", Type: "deployment", Name: "main"},
+	})
+	if err == nil || !strings.Contains(err.Error(), "can't change type") {
+		t.Errorf("expected a type change error, got %v", err)
+	}
+}
+
+func TestMoveState_UnloadableSourceWarns(t *testing.T) {
+	eng, sm := newStateEditTestEngine(t)
+	sm.environments["test-dc/staging"].Components["worker"].Resources = map[string]*types.ResourceState{
+		"deployment.old": {Name: "old", Type: "deployment", Component: "worker"},
+	}
+
+	var out bytes.Buffer
+	err := eng.MoveState(context.Background(), MoveStateOptions{
+		Datacenter: "test-dc",
+		From:       StateAddress{Environment: "staging", Component: "worker", Name: "old"},
+		To:         StateAddress{Environment: "staging", Component: "worker", Name: "new"},
+		Output:     &out,
+	})
+	if err != nil {
+		t.Fatalf("MoveState failed: %v", err)
+	}
+	if !strings.Contains(out.String(), "Warning: can't validate staging/worker/deployment/new") {
+		t.Errorf("expected a validation warning, got %q", out.String())
+	}
+}
+
+func TestMoveState_Component(t *testing.T) {
+	eng, sm := newStateEditTestEngine(t)
+	ctx := context.Background()
+
+	err := eng.MoveState(ctx, MoveStateOptions{
+		Datacenter: "test-dc",
+		From:       StateAddress{Environment: "staging", Component: "api"},
+		To:         StateAddress{Environment: "staging", Component: "backend"},
+	})
+	if err != nil {
+		t.Fatalf("MoveState failed: %v", err)
+	}
+
+	env := sm.environments["test-dc/staging"]
+	backend := env.Components["backend"]
+	if _, ok := env.Components["api"]; ok || backend == nil {
+		t.Fatalf("expected api to be renamed to backend, got %v", sortedComponentNames(env.Components))
+	}
+	if backend.Name != "backend" || backend.Resources["database.legacy"].Component != "backend" {
+		t.Errorf("expected the component and its resources to be renamed, got %+v", backend)
+	}
+	if deps := env.Components["worker"].Dependencies; len(deps) != 1 || deps[0] != "backend" {
+		t.Errorf("expected worker's dependency to be renamed, got %v", deps)
+	}
+
+	// Moving into an environment that already has the component fails
+	sm.environments["test-dc/preview"].Components = map[string]*types.ComponentState{"backend": {Name: "backend"}}
+	err = eng.MoveState(ctx, MoveStateOptions{
+		Datacenter: "test-dc",
+		From:       StateAddress{Environment: "staging", Component: "backend"},
+		To:         StateAddress{Environment: "preview", Component: "backend"},
+	})
+	if err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Errorf("expected an already exists error, got %v", err)
+	}
+}
+
+func TestMoveState_BetweenEnvironments(t *testing.T) {
+	eng, sm := newStateEditTestEngine(t)
+
+	err := eng.MoveState(context.Background(), MoveStateOptions{
+		Datacenter: "test-dc",
+		From:       StateAddress{Environment: "staging", Component: "api"},
+		To:         StateAddress{Environment: "preview", Component: "api"},
+	})
+	if err != nil {
+		t.Fatalf("MoveState failed: %v", err)
+	}
+
+	if _, ok := sm.environments["test-dc/staging"].Components["api"]; ok {
+		t.Error("expected api to be removed from staging")
+	}
+	if _, ok := sm.environments["test-dc/preview"].Components["api"]; !ok {
+		t.Error("expected api to be added to preview")
+	}
+}
+
+func TestRemoveState(t *testing.T) {
+	eng, sm := newStateEditTestEngine(t)
+	ctx := context.Background()
+
+	// An unknown address fails before anything is removed
+	err := eng.RemoveState(ctx, RemoveStateOptions{
+		Datacenter: "test-dc",
+		Addresses: []StateAddress{
+			{Environment: "staging", Component: "api", Name: "web"},
+			{Environment: "staging", Component: "api", Name: "missing"},
+		},
+	})
+	if err == nil || !strings.Contains(err.Error(), "resource staging/api/missing not found") {
+		t.Fatalf("expected a not found error, got %v", err)
+	}
+	if _, ok := sm.environments["test-dc/staging"].Components["api"].Resources["deployment.web"]; !ok {
+		t.Fatal("expected state to be unchanged after a failed removal")
+	}
+
+	var out bytes.Buffer
+	err = eng.RemoveState(ctx, RemoveStateOptions{
+		Datacenter: "test-dc",
+		Addresses: []StateAddress{
+			{Environment: "staging", Component: "api", Name: "web"},
+			{Environment: "staging", Component: "worker"},
+		},
+		Output: &out,
+	})
+	if err != nil {
+		t.Fatalf("RemoveState failed: %v", err)
+	}
+
+	env := sm.environments["test-dc/staging"]
+	if _, ok := env.Components["api"].Resources["deployment.web"]; ok {
+		t.Error("expected deployment web to be removed")
+	}
+	if _, ok := env.Components["worker"]; ok {
+		t.Error("expected component worker to be removed")
+	}
+	if out.Len() != 0 {
+		t.Errorf("expected no warnings for resources the component doesn't declare, got %q", out.String())
+	}
+
+	// Removing a resource the component still declares warns that it will
+	// be created again
+	env.Components["api"].Resources["database.main"] = &types.ResourceState{Name: "main", Type: "database", Component: "api"}
+	err = eng.RemoveState(ctx, RemoveStateOptions{
+		Datacenter: "test-dc",
+		Addresses:  []StateAddress{{Environment: "staging", Component: "api", Type: "database", Name: "main"}},
+		Output:     &out,
+	})
+	if err != nil {
+		t.Fatalf("RemoveState failed: %v", err)
+	}
+	if !strings.Contains(out.String(), `component "api" still declares database/main`) {
+		t.Errorf("expected a warning, got %q", out.String())
+	}
+}
+
+func TestResourceState(t *testing.T) {
+	eng, sm := newStateEditTestEngine(t)
+	ctx := context.Background()
+
+	res, err := eng.ResourceState(ctx, "test-dc", StateAddress{Environment: "staging", Component: "api", Name: "legacy"})
+	if err != nil || res.Type != "database" {
+		t.Fatalf("expected the legacy database, got %+v, %v", res, err)
+	}
+
+	sm.environments["test-dc/staging"].Components["api"].Resources["deployment.legacy"] = &types.ResourceState{Name: "legacy", Type: "deployment", Component: "api"}
+	_, err = eng.ResourceState(ctx, "test-dc", StateAddress{Environment: "staging", Component: "api", Name: "legacy"})
+	if err == nil || !strings.Contains(err.Error(), "ambiguous") {
+		t.Errorf("expected an ambiguity error, got %v", err)
+	}
+
+	_, err = eng.ResourceState(ctx, "test-dc", StateAddress{Environment: "staging", Component: "api"})
+	if err == nil || !strings.Contains(err.Error(), "is a component") {
+		t.Errorf("expected a component address error, got %v", err)
+	}
+}
@@ -3,6 +3,7 @@ package errors
 
 import (
 	"fmt"
+	"strings"
 	"time"
 )
 
@@ -24,6 +25,7 @@ const (
 	ErrCodeDocker          ErrorCode = "DOCKER_ERROR"
 	ErrCodePlugin          ErrorCode = "PLUGIN_ERROR"
 	ErrCodeDatacenterHook  ErrorCode = "DATACENTER_HOOK_ERROR"
+	ErrCodePolicyViolation ErrorCode = "POLICY_VIOLATION"
 )
 
 // Error is the base error type for cldctl
@@ -186,6 +188,18 @@ func DatacenterHookError(hookType, component, resource, message string) *Error {
 	}
 }
 
+// PolicyViolationError creates an error for resources that violate datacenter
+// policies with error severity. Each violation is a line of the message.
+func PolicyViolationError(violations []string) *Error {
+	return &Error{
+		Code:    ErrCodePolicyViolation,
+		Message: fmt.Sprintf("%d datacenter policy violation(s):\n  - %s", len(violations), strings.Join(violations, "\n  - ")),
+		Details: map[string]interface{}{
+			"violations": violations,
+		},
+	}
+}
+
 // Is checks if the error matches the given code
 func Is(err error, code ErrorCode) bool {
 	if e, ok := err.(*Error); ok {
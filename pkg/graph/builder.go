@@ -144,6 +144,9 @@ func (b *Builder) AddComponent(componentName string, comp component.Component) e
 		node.SetInput("memory", deploy.Memory())
 		node.SetInput("replicas", deploy.Replicas())
 		node.SetInput("liveness_probe", deploy.LivenessProbe())
+		if probe := deploy.ReadinessProbe(); probe != nil {
+			node.SetInput("readiness_probe", probeInput(probe))
+		}
 
 		// Set working directory: explicit value or default to component directory
 		if deploy.WorkingDirectory() != "" {
@@ -468,3 +471,22 @@ func resolveBuildContext(compDir, path string) string {
 	// Resolve relative path against component directory
 	return filepath.Join(compDir, path)
 }
+
+// probeInput converts a probe into the map passed to hook modules, using the
+// field names from the component spec.
+func probeInput(probe component.Probe) map[string]interface{} {
+	input := map[string]interface{}{
+		"path":                  probe.Path(),
+		"port":                  probe.Port(),
+		"tcp_port":              probe.TCPPort(),
+		"initial_delay_seconds": probe.InitialDelaySeconds(),
+		"period_seconds":        probe.PeriodSeconds(),
+		"timeout_seconds":       probe.TimeoutSeconds(),
+		"success_threshold":     probe.SuccessThreshold(),
+		"failure_threshold":     probe.FailureThreshold(),
+	}
+	if len(probe.Command()) > 0 {
+		input["command"] = probe.Command()
+	}
+	return input
+}
@@ -271,3 +271,36 @@ cronjobs:
 		t.Error("expected cronjob to depend on task node")
 	}
 }
+
+func TestBuilder_DeploymentReadinessProbe(t *testing.T) {
+	builder := NewBuilder("test-env", "test-dc")
+
+	comp := loadComponent(t, `
+deployments:
+  api:
+    image: my-app:latest
+    readiness_probe:
+      path: /ready
+      port: 8080
+      period_seconds: 5
+  worker:
+    image: my-worker:latest
+`)
+
+	if err := builder.AddComponent("my-app", comp); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	g := builder.Build()
+
+	probe, ok := g.GetNode("my-app/deployment/api").Inputs["readiness_probe"].(map[string]interface{})
+	if !ok {
+		t.Fatal("expected readiness_probe input")
+	}
+	if probe["path"] != "/ready" || probe["port"] != 8080 || probe["period_seconds"] != 5 {
+		t.Errorf("unexpected readiness_probe input: %v", probe)
+	}
+
+	if _, ok := g.GetNode("my-app/deployment/worker").Inputs["readiness_probe"]; ok {
+		t.Error("expected no readiness_probe input for a deployment without one")
+	}
+}
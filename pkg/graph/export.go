@@ -0,0 +1,227 @@
+package graph
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Export is a serializable view of a graph for rendering as a diagram. Nodes
+// are sorted by ID and edges by dependent then dependency, so the output of
+// the same graph is always the same.
+type Export struct {
+	Environment string       `json:"environment,omitempty"`
+	Datacenter  string       `json:"datacenter,omitempty"`
+	Components  []string     `json:"components"`
+	Nodes       []ExportNode `json:"nodes"`
+	Edges       []ExportEdge `json:"edges"`
+}
+
+// ExportNode describes a node in an exported graph.
+type ExportNode struct {
+	ID        string `json:"id"`
+	Type      string `json:"type"`
+	Component string `json:"component"`
+	Name      string `json:"name"`
+
+	// Status is the resource's status from state, if it has been deployed
+	Status string `json:"status,omitempty"`
+
+	// Module is the datacenter hook module that provisions the node
+	Module string `json:"module,omitempty"`
+}
+
+// ExportEdge is a dependency from one node on another.
+type ExportEdge struct {
+	From string `json:"from"` // ID of the dependent node
+	To   string `json:"to"`   // ID of the node it depends on
+}
+
+// NewExport returns an export of the graph's nodes and edges. Status and
+// module annotations are left for the caller to fill in.
+func NewExport(g *Graph) *Export {
+	export := &Export{
+		Environment: g.Environment,
+		Datacenter:  g.Datacenter,
+		Components:  []string{},
+		Nodes:       []ExportNode{},
+		Edges:       []ExportEdge{},
+	}
+
+	ids := make([]string, 0, len(g.Nodes))
+	for id := range g.Nodes {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	components := make(map[string]bool)
+	for _, id := range ids {
+		node := g.Nodes[id]
+		export.Nodes = append(export.Nodes, ExportNode{
+			ID:        node.ID,
+			Type:      string(node.Type),
+			Component: node.Component,
+			Name:      node.Name,
+		})
+		if !components[node.Component] {
+			components[node.Component] = true
+			export.Components = append(export.Components, node.Component)
+		}
+
+		deps := append([]string(nil), node.DependsOn...)
+		sort.Strings(deps)
+		for _, dep := range deps {
+			if _, ok := g.Nodes[dep]; ok {
+				export.Edges = append(export.Edges, ExportEdge{From: node.ID, To: dep})
+			}
+		}
+	}
+	sort.Strings(export.Components)
+
+	return export
+}
+
+// SetStatus sets the status of the node with the given ID.
+func (x *Export) SetStatus(id, status string) {
+	for i := range x.Nodes {
+		if x.Nodes[i].ID == id {
+			x.Nodes[i].Status = status
+		}
+	}
+}
+
+// SetModule sets the hook module of the node with the given ID.
+func (x *Export) SetModule(id, module string) {
+	for i := range x.Nodes {
+		if x.Nodes[i].ID == id {
+			x.Nodes[i].Module = module
+		}
+	}
+}
+
+// WriteJSON writes the export as indented JSON.
+func (x *Export) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(x)
+}
+
+// WriteDOT writes the export as a Graphviz digraph, with a cluster for each
+// component and nodes filled by status.
+func (x *Export) WriteDOT(w io.Writer) error {
+	var b strings.Builder
+
+	b.WriteString("digraph {\n")
+	b.WriteString("  rankdir=LR;\n")
+	b.WriteString("  node [shape=box, style=\"rounded,filled\", fillcolor=\"#ffffff\", fontname=\"Helvetica\"];\n")
+	b.WriteString("  edge [fontname=\"Helvetica\"];\n")
+
+	for i, comp := range x.Components {
+		fmt.Fprintf(&b, "\n  subgraph cluster_%d {\n", i)
+		fmt.Fprintf(&b, "    label=%s;\n", dotQuote(comp))
+		b.WriteString("    style=dashed;\n")
+		for _, node := range x.Nodes {
+			if node.Component != comp {
+				continue
+			}
+			fmt.Fprintf(&b, "    %s [label=%s", dotQuote(node.ID), dotQuote(strings.Join(node.labelLines(), "\n")))
+			if color, ok := statusColors[node.Status]; ok {
+				fmt.Fprintf(&b, ", fillcolor=%s", dotQuote(color))
+			}
+			b.WriteString("];\n")
+		}
+		b.WriteString("  }\n")
+	}
+
+	if len(x.Edges) > 0 {
+		b.WriteString("\n")
+	}
+	for _, edge := range x.Edges {
+		fmt.Fprintf(&b, "  %s -> %s;\n", dotQuote(edge.From), dotQuote(edge.To))
+	}
+	b.WriteString("}\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// WriteMermaid writes the export as a Mermaid flowchart, with a subgraph for
+// each component and nodes styled by status.
+func (x *Export) WriteMermaid(w io.Writer) error {
+	var b strings.Builder
+
+	// Mermaid IDs can't contain "/", so nodes are numbered in order
+	ids := make(map[string]string, len(x.Nodes))
+	for i, node := range x.Nodes {
+		ids[node.ID] = fmt.Sprintf("n%d", i)
+	}
+
+	b.WriteString("flowchart LR\n")
+
+	for i, comp := range x.Components {
+		fmt.Fprintf(&b, "  subgraph c%d[%s]\n", i, mermaidQuote(comp))
+		for _, node := range x.Nodes {
+			if node.Component != comp {
+				continue
+			}
+			fmt.Fprintf(&b, "    %s[%s]\n", ids[node.ID], mermaidQuote(strings.Join(node.labelLines(), "<br/>")))
+		}
+		b.WriteString("  end\n")
+	}
+
+	for _, edge := range x.Edges {
+		fmt.Fprintf(&b, "  %s --> %s\n", ids[edge.From], ids[edge.To])
+	}
+
+	statuses := make(map[string][]string)
+	for _, node := range x.Nodes {
+		if _, ok := statusColors[node.Status]; ok {
+			statuses[node.Status] = append(statuses[node.Status], ids[node.ID])
+		}
+	}
+	names := make([]string, 0, len(statuses))
+	for status := range statuses {
+		names = append(names, status)
+	}
+	sort.Strings(names)
+	for _, status := range names {
+		fmt.Fprintf(&b, "  classDef %s fill:%s\n", status, statusColors[status])
+		fmt.Fprintf(&b, "  class %s %s\n", strings.Join(statuses[status], ","), status)
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// statusColors are the fill colours of nodes by resource status.
+var statusColors = map[string]string{
+	"pending":      "#e0e0e0",
+	"provisioning": "#fff3b0",
+	"ready":        "#c8e6c9",
+	"failed":       "#ffcdd2",
+	"deleting":     "#ffe0b2",
+	"deleted":      "#f5f5f5",
+}
+
+// labelLines returns the lines of a node's label: its name, type, and hook
+// module if known.
+func (n ExportNode) labelLines() []string {
+	lines := []string{n.Name, "(" + n.Type + ")"}
+	if n.Module != "" {
+		lines = append(lines, "module: "+n.Module)
+	}
+	return lines
+}
+
+func dotQuote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	s = strings.ReplaceAll(s, "\n", `\n`)
+	return `"` + s + `"`
+}
+
+func mermaidQuote(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, "#quot;") + `"`
+}
@@ -0,0 +1,120 @@
+package graph
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func newExportTestGraph(t *testing.T) *Graph {
+	t.Helper()
+
+	g := NewGraph("staging", "dc")
+	db := NewNode(NodeTypeDatabase, "api", "main")
+	api := NewNode(NodeTypeDeployment, "api", "api")
+	web := NewNode(NodeTypeDeployment, "web", "web")
+	for _, node := range []*Node{db, api, web} {
+		if err := g.AddNode(node); err != nil {
+			t.Fatal(err)
+		}
+	}
+	_ = g.AddEdge(api.ID, db.ID)
+	_ = g.AddEdge(web.ID, api.ID)
+	return g
+}
+
+func TestNewExport(t *testing.T) {
+	export := NewExport(newExportTestGraph(t))
+
+	if len(export.Components) != 2 || export.Components[0] != "api" || export.Components[1] != "web" {
+		t.Errorf("expected sorted components, got %v", export.Components)
+	}
+	if len(export.Nodes) != 3 || export.Nodes[0].ID != "api/database/main" {
+		t.Errorf("expected nodes sorted by ID, got %+v", export.Nodes)
+	}
+	want := []ExportEdge{
+		{From: "api/deployment/api", To: "api/database/main"},
+		{From: "web/deployment/web", To: "api/deployment/api"},
+	}
+	if len(export.Edges) != len(want) {
+		t.Fatalf("expected %d edges, got %+v", len(want), export.Edges)
+	}
+	for i := range want {
+		if export.Edges[i] != want[i] {
+			t.Errorf("edge %d = %+v, want %+v", i, export.Edges[i], want[i])
+		}
+	}
+}
+
+func TestExport_WriteDOT(t *testing.T) {
+	export := NewExport(newExportTestGraph(t))
+	export.SetStatus("api/database/main", "ready")
+	export.SetModule("api/database/main", "postgres")
+
+	var buf bytes.Buffer
+	if err := export.WriteDOT(&buf); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+
+	for _, want := range []string{
+		"digraph {",
+		"subgraph cluster_0 {",
+		`label="api";`,
+		`"api/database/main" [label="main\n(database)\nmodule: postgres", fillcolor="#c8e6c9"];`,
+		`"api/deployment/api" [label="api\n(deployment)"];`,
+		`"web/deployment/web" -> "api/deployment/api";`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected DOT output to contain %q, got:\n%s", want, out)
+		}
+	}
+}
+
+func TestExport_WriteMermaid(t *testing.T) {
+	export := NewExport(newExportTestGraph(t))
+	export.SetStatus("api/database/main", "failed")
+	export.SetStatus("api/deployment/api", "failed")
+
+	var buf bytes.Buffer
+	if err := export.WriteMermaid(&buf); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+
+	for _, want := range []string{
+		"flowchart LR",
+		`subgraph c0["api"]`,
+		`n0["main<br/>(database)"]`,
+		"n1 --> n0",
+		"n2 --> n1",
+		"classDef failed fill:#ffcdd2",
+		"class n0,n1 failed",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected Mermaid output to contain %q, got:\n%s", want, out)
+		}
+	}
+}
+
+func TestExport_WriteJSON(t *testing.T) {
+	export := NewExport(newExportTestGraph(t))
+	export.SetModule("web/deployment/web", "kubernetes")
+
+	var buf bytes.Buffer
+	if err := export.WriteJSON(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	var decoded Export
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if decoded.Environment != "staging" || len(decoded.Nodes) != 3 || len(decoded.Edges) != 2 {
+		t.Errorf("unexpected export: %+v", decoded)
+	}
+	if decoded.Nodes[2].Module != "kubernetes" || decoded.Nodes[2].Status != "" {
+		t.Errorf("expected module annotation without status, got %+v", decoded.Nodes[2])
+	}
+}
@@ -0,0 +1,22 @@
+package iac
+
+import (
+	"os/exec"
+	"time"
+)
+
+// InterruptGracePeriod is how long an IaC tool is given to save its state
+// after being interrupted before it is killed.
+const InterruptGracePeriod = 30 * time.Second
+
+// PrepareCommand configures cmd, created with exec.CommandContext, to run an
+// IaC tool that must be allowed to save its state when it is stopped.
+//
+// The tool runs in its own process group so that a Ctrl+C in the terminal
+// doesn't reach it directly; cldctl decides whether it runs to completion.
+// Cancelling the command's context interrupts the tool, which is only killed
+// if it hasn't exited after InterruptGracePeriod.
+func PrepareCommand(cmd *exec.Cmd) {
+	cmd.WaitDelay = InterruptGracePeriod
+	prepareCommand(cmd)
+}
@@ -0,0 +1,49 @@
+//go:build !windows
+
+package iac
+
+import (
+	"context"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPrepareCommand_InterruptsOnCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	// The script saves its "state" when interrupted, as OpenTofu does
+	cmd := exec.CommandContext(ctx, "sh", "-c", `trap 'echo saved; exit 1' INT; echo started; while true; do sleep 0.1; done`)
+	PrepareCommand(cmd)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatalf("failed to get stdout: %v", err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Skipf("sh not available: %v", err)
+	}
+
+	buf := make([]byte, len("started\n"))
+	if _, err := stdout.Read(buf); err != nil {
+		t.Fatalf("failed to read output: %v", err)
+	}
+	cancel()
+
+	done := make(chan []byte)
+	go func() {
+		rest := make([]byte, 64)
+		n, _ := stdout.Read(rest)
+		done <- rest[:n]
+		_ = cmd.Wait()
+	}()
+
+	select {
+	case out := <-done:
+		if !strings.Contains(string(out), "saved") {
+			t.Errorf("expected the command to handle the interrupt, got %q", out)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the command to exit after being interrupted")
+	}
+}
@@ -0,0 +1,9 @@
+//go:build windows
+
+package iac
+
+import "os/exec"
+
+// Interrupts can't be delivered to a single process on Windows, so the tool
+// is killed when its context is cancelled.
+func prepareCommand(cmd *exec.Cmd) {}
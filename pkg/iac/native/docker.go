@@ -6,6 +6,7 @@ import (
 	"io"
 	"os/exec"
 	"strings"
+	"time"
 
 	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/api/types/filters"
@@ -53,10 +54,11 @@ type VolumeMount struct {
 
 // Healthcheck defines a health check.
 type Healthcheck struct {
-	Command  []string
-	Interval string
-	Timeout  string
-	Retries  int
+	Command     []string
+	Interval    string
+	Timeout     string
+	StartPeriod string
+	Retries     int
 }
 
 // ContainerInfo contains container information.
@@ -138,6 +140,20 @@ func (d *DockerClient) RunContainer(ctx context.Context, opts ContainerOptions)
 		ExposedPorts: exposedPorts,
 	}
 
+	if hc := opts.Healthcheck; hc != nil {
+		test := hc.Command
+		if len(test) > 0 && test[0] != "CMD" && test[0] != "CMD-SHELL" && test[0] != "NONE" {
+			test = append([]string{"CMD"}, test...)
+		}
+		config.Healthcheck = &container.HealthConfig{
+			Test:        test,
+			Interval:    parseDuration(hc.Interval, 0),
+			Timeout:     parseDuration(hc.Timeout, 0),
+			StartPeriod: parseDuration(hc.StartPeriod, 0),
+			Retries:     hc.Retries,
+		}
+	}
+
 	hostConfig := &container.HostConfig{
 		PortBindings: portBindings,
 		Binds:        binds,
@@ -208,6 +224,43 @@ func (d *DockerClient) IsContainerRunning(ctx context.Context, containerID strin
 	return info.State.Running, nil
 }
 
+// WaitForHealthy waits for a container with a health check, from its image
+// or its options, to report healthy. It returns immediately for containers
+// without one, and fails if the container becomes unhealthy or exits.
+func (d *DockerClient) WaitForHealthy(ctx context.Context, containerID string) error {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		info, err := d.client.ContainerInspect(ctx, containerID)
+		if err != nil {
+			return err
+		}
+		if !info.State.Running {
+			return fmt.Errorf("container exited with code %d before becoming healthy", info.State.ExitCode)
+		}
+		health := info.State.Health
+		if health == nil || health.Status == container.NoHealthcheck {
+			return nil
+		}
+		switch health.Status {
+		case container.Healthy:
+			return nil
+		case container.Unhealthy:
+			if n := len(health.Log); n > 0 {
+				return fmt.Errorf("container is unhealthy: %s", strings.TrimSpace(health.Log[n-1].Output))
+			}
+			return fmt.Errorf("container is unhealthy")
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
 // GetContainerByName finds a container by name and returns its ID.
 // Returns empty string if not found.
 func (d *DockerClient) GetContainerByName(ctx context.Context, name string) (string, error) {
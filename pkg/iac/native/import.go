@@ -0,0 +1,261 @@
+package native
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/davidthor/arcctl/pkg/iac"
+)
+
+// adoptableTypes are the resource types that Import can adopt.
+var adoptableTypes = map[string]bool{
+	"docker:container": true,
+	"docker:volume":    true,
+	"docker:network":   true,
+}
+
+// Import adopts existing Docker containers, volumes, and networks instead of
+// creating them. The resource at imp.Address is adopted by imp.ID, a name or
+// ID; every other resource in the module is adopted by its configured name.
+// Nothing is created, and the import fails if a resource doesn't exist, or
+// is a container that isn't running or doesn't match the module's
+// configuration, since the next apply would replace it.
+func (p *Plugin) Import(ctx context.Context, opts iac.RunOptions, imp iac.ImportOptions) (*iac.ApplyResult, error) {
+	// Load module definition
+	module, err := LoadModule(opts.ModuleSource)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load module: %w", err)
+	}
+
+	target := imp.Address
+	if target == "" {
+		target, err = adoptTarget(module)
+		if err != nil {
+			return nil, err
+		}
+	} else if _, ok := module.Resources[target]; !ok {
+		return nil, fmt.Errorf("module has no resource %q", target)
+	}
+
+	// Resolve inputs
+	resolvedInputs, err := p.resolveInputs(module.Inputs, opts.Inputs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve inputs: %w", err)
+	}
+
+	state := &State{
+		ModulePath: opts.ModuleSource,
+		Inputs:     resolvedInputs,
+		Resources:  make(map[string]*ResourceState),
+		Outputs:    make(map[string]interface{}),
+	}
+
+	// Build evaluation context
+	evalCtx := &EvalContext{
+		Inputs:    resolvedInputs,
+		Resources: state.Resources,
+	}
+
+	for _, name := range adoptOrder(module) {
+		resource := module.Resources[name]
+		props, err := resolveProperties(resource.Properties, evalCtx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve properties of %s: %w", name, err)
+		}
+
+		ref := getString(props, "name")
+		if name == target {
+			ref = imp.ID
+		}
+		if ref == "" {
+			return nil, fmt.Errorf("resource %s has no name to adopt it by", name)
+		}
+
+		resourceState, err := p.adoptResource(ctx, resource.Type, ref, props)
+		if err != nil {
+			return nil, fmt.Errorf("failed to adopt resource %s: %w", name, err)
+		}
+		state.Resources[name] = resourceState
+		evalCtx.Resources = state.Resources
+	}
+
+	// Resolve outputs
+	outputs := make(map[string]iac.OutputValue)
+	for name, outputDef := range module.Outputs {
+		value, err := evaluateExpression(outputDef.Value, evalCtx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to evaluate output %s: %w", name, err)
+		}
+		state.Outputs[name] = value
+		outputs[name] = iac.OutputValue{
+			Value:     value,
+			Sensitive: outputDef.Sensitive,
+		}
+	}
+
+	// Serialize state
+	stateBytes, err := json.Marshal(state)
+	if err != nil {
+		return nil, fmt.Errorf("failed to serialize state: %w", err)
+	}
+
+	return &iac.ApplyResult{
+		Outputs: outputs,
+		State:   stateBytes,
+	}, nil
+}
+
+// adoptResource looks up an existing resource by name or ID and returns the
+// state an apply of the resource would have recorded.
+func (p *Plugin) adoptResource(ctx context.Context, resourceType, ref string, props map[string]interface{}) (*ResourceState, error) {
+	switch resourceType {
+	case "docker:container":
+		containerID, err := p.docker.GetContainerByName(ctx, ref)
+		if err != nil {
+			return nil, err
+		}
+		if containerID == "" {
+			containerID = ref
+		}
+		running, err := p.docker.IsContainerRunning(ctx, containerID)
+		if err != nil {
+			return nil, fmt.Errorf("no container %q found: %w", ref, err)
+		}
+		if !running {
+			return nil, fmt.Errorf("container %q isn't running", ref)
+		}
+		opts := containerOptions(props)
+		if !p.docker.ContainerMatchesConfig(ctx, containerID, opts) {
+			return nil, fmt.Errorf("container %q doesn't match the module's configuration, so the next deploy would replace it", ref)
+		}
+		info, err := p.docker.InspectContainer(ctx, containerID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to inspect container: %w", err)
+		}
+		return &ResourceState{
+			Type:       resourceType,
+			ID:         info.ID,
+			Properties: props,
+			Outputs: map[string]interface{}{
+				"container_id": info.ID,
+				"ports":        info.Ports,
+				"environment":  opts.Environment,
+				"name":         strings.TrimPrefix(info.Name, "/"),
+			},
+		}, nil
+
+	case "docker:volume":
+		exists, err := p.docker.VolumeExists(ctx, ref)
+		if err != nil {
+			return nil, err
+		}
+		if !exists {
+			return nil, fmt.Errorf("no volume %q found", ref)
+		}
+		return &ResourceState{
+			Type:       resourceType,
+			ID:         ref,
+			Properties: props,
+			Outputs: map[string]interface{}{
+				"volume_id": ref,
+				"name":      ref,
+			},
+		}, nil
+
+	case "docker:network":
+		exists, err := p.docker.NetworkExists(ctx, ref)
+		if err != nil {
+			return nil, err
+		}
+		if !exists {
+			return nil, fmt.Errorf("no network %q found", ref)
+		}
+		name := getString(props, "name")
+		if name == "" {
+			name = ref
+		}
+		return &ResourceState{
+			Type:       resourceType,
+			ID:         ref,
+			Properties: props,
+			Outputs: map[string]interface{}{
+				"network_id": ref,
+				"name":       name,
+			},
+		}, nil
+
+	default:
+		return nil, fmt.Errorf("resources of type %s can't be adopted", resourceType)
+	}
+}
+
+// adoptTarget returns the only adoptable resource in a module.
+func adoptTarget(module *Module) (string, error) {
+	var names []string
+	for name, resource := range module.Resources {
+		if adoptableTypes[resource.Type] {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	switch len(names) {
+	case 0:
+		return "", fmt.Errorf("module has no containers, volumes, or networks to import into")
+	case 1:
+		return names[0], nil
+	default:
+		return "", fmt.Errorf("module has %d resources that can be imported into (%s); choose one with an address", len(names), strings.Join(names, ", "))
+	}
+}
+
+// adoptOrder returns the names of a module's resources with each resource
+// after the resources it depends on, so that properties referring to them
+// can be resolved.
+func adoptOrder(module *Module) []string {
+	names := make([]string, 0, len(module.Resources))
+	for name := range module.Resources {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var order []string
+	done := make(map[string]bool, len(names))
+	for len(order) < len(names) {
+		progress := false
+		for _, name := range names {
+			if done[name] {
+				continue
+			}
+			ready := true
+			for _, dep := range module.Resources[name].DependsOn {
+				if _, ok := module.Resources[dep]; ok && !done[dep] {
+					ready = false
+					break
+				}
+			}
+			if ready {
+				order = append(order, name)
+				done[name] = true
+				progress = true
+			}
+		}
+
+		// Dependency cycles are left to fail when properties are resolved
+		if !progress {
+			for _, name := range names {
+				if !done[name] {
+					order = append(order, name)
+					done[name] = true
+				}
+			}
+		}
+	}
+	return order
+}
+
+// Ensure we implement the Importer interface
+var _ iac.Importer = (*Plugin)(nil)
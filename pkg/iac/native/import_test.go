@@ -0,0 +1,55 @@
+package native
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestAdoptTarget(t *testing.T) {
+	module := &Module{Resources: map[string]Resource{
+		"image":     {Type: "docker:build"},
+		"container": {Type: "docker:container"},
+	}}
+	target, err := adoptTarget(module)
+	if err != nil {
+		t.Fatalf("adoptTarget failed: %v", err)
+	}
+	if target != "container" {
+		t.Errorf("expected container, got %q", target)
+	}
+
+	module.Resources["data"] = Resource{Type: "docker:volume"}
+	_, err = adoptTarget(module)
+	if err == nil || !strings.Contains(err.Error(), "(container, data)") {
+		t.Errorf("expected an error listing both resources, got %v", err)
+	}
+
+	_, err = adoptTarget(&Module{Resources: map[string]Resource{"migrate": {Type: "exec"}}})
+	if err == nil {
+		t.Error("expected an error for a module without adoptable resources")
+	}
+}
+
+func TestAdoptOrder(t *testing.T) {
+	module := &Module{Resources: map[string]Resource{
+		"container": {Type: "docker:container", DependsOn: []string{"network", "volume"}},
+		"network":   {Type: "docker:network"},
+		"volume":    {Type: "docker:volume", DependsOn: []string{"missing"}},
+	}}
+
+	got := adoptOrder(module)
+	want := []string{"network", "volume", "container"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("adoptOrder() = %v, want %v", got, want)
+	}
+
+	// Cycles don't loop forever
+	cycle := &Module{Resources: map[string]Resource{
+		"a": {DependsOn: []string{"b"}},
+		"b": {DependsOn: []string{"a"}},
+	}}
+	if got := adoptOrder(cycle); len(got) != 2 {
+		t.Errorf("expected both resources, got %v", got)
+	}
+}
@@ -245,22 +245,9 @@ func (p *Plugin) rollback(ctx context.Context, state *State) {
 
 func (p *Plugin) applyDockerContainer(ctx context.Context, name string, props map[string]interface{}, existing *State) (*ResourceState, error) {
 	containerName := getString(props, "name")
-	desiredImage := getString(props, "image")
 
 	// Build desired options for comparison
-	opts := ContainerOptions{
-		Image:       desiredImage,
-		Name:        containerName,
-		Command:     getStringSlice(props, "command"),
-		Entrypoint:  getStringSlice(props, "entrypoint"),
-		Environment: getStringMap(props, "environment"),
-		Ports:       getPortMappings(props, "ports"),
-		Volumes:     getVolumeMounts(props, "volumes"),
-		Network:     getString(props, "network"),
-		Restart:     getString(props, "restart"),
-		LogDriver:   getString(props, "log_driver"),
-		LogOptions:  getStringMap(props, "log_options"),
-	}
+	opts := containerOptions(props)
 
 	// Check if container already exists and is running (from state)
 	if existing != nil {
@@ -311,6 +298,12 @@ func (p *Plugin) applyDockerContainer(ctx context.Context, name string, props ma
 		return nil, err
 	}
 
+	// Dependents shouldn't start against a container that isn't serving yet
+	if err := p.docker.WaitForHealthy(ctx, containerID); err != nil {
+		_ = p.docker.RemoveContainer(context.Background(), containerID)
+		return nil, fmt.Errorf("container %s did not become healthy: %w", containerName, err)
+	}
+
 	// Get container info
 	info, err := p.docker.InspectContainer(ctx, containerID)
 	if err != nil {
@@ -331,6 +324,25 @@ func (p *Plugin) applyDockerContainer(ctx context.Context, name string, props ma
 	}, nil
 }
 
+// containerOptions returns the options for the container a docker:container
+// resource describes.
+func containerOptions(props map[string]interface{}) ContainerOptions {
+	return ContainerOptions{
+		Image:       getString(props, "image"),
+		Name:        getString(props, "name"),
+		Command:     getStringSlice(props, "command"),
+		Entrypoint:  getStringSlice(props, "entrypoint"),
+		Environment: getStringMap(props, "environment"),
+		Ports:       getPortMappings(props, "ports"),
+		Volumes:     getVolumeMounts(props, "volumes"),
+		Network:     getString(props, "network"),
+		Restart:     getString(props, "restart"),
+		LogDriver:   getString(props, "log_driver"),
+		LogOptions:  getStringMap(props, "log_options"),
+		Healthcheck: getHealthcheck(props, "healthcheck"),
+	}
+}
+
 func (p *Plugin) applyDockerNetwork(ctx context.Context, name string, props map[string]interface{}, existing *State) (*ResourceState, error) {
 	networkName := getString(props, "name")
 
@@ -697,6 +709,28 @@ func getVolumeMounts(props map[string]interface{}, key string) []VolumeMount {
 	return nil
 }
 
+func getHealthcheck(props map[string]interface{}, key string) *Healthcheck {
+	m, ok := props[key].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	hc := &Healthcheck{
+		Interval:    getString(m, "interval"),
+		Timeout:     getString(m, "timeout"),
+		StartPeriod: getString(m, "start_period"),
+	}
+	// A command string runs in the container's shell
+	if command, ok := m["command"].(string); ok {
+		hc.Command = []string{"CMD-SHELL", command}
+	} else {
+		hc.Command = getStringSlice(m, "command")
+	}
+	if retries, ok := m["retries"].(int); ok {
+		hc.Retries = retries
+	}
+	return hc
+}
+
 func getString2(m map[string]interface{}, key string) string {
 	if v, ok := m[key]; ok {
 		if s, ok := v.(string); ok {
@@ -221,6 +221,40 @@ func TestGetVolumeMounts(t *testing.T) {
 	}
 }
 
+func TestGetHealthcheck(t *testing.T) {
+	props := map[string]interface{}{
+		"shell": map[string]interface{}{
+			"command":      "curl -f http://localhost:8080/health",
+			"interval":     "5s",
+			"start_period": "30s",
+			"retries":      3,
+		},
+		"exec": map[string]interface{}{
+			"command": []interface{}{"pg_isready", "-U", "postgres"},
+		},
+	}
+
+	hc := getHealthcheck(props, "shell")
+	if hc == nil {
+		t.Fatal("expected healthcheck")
+	}
+	if len(hc.Command) != 2 || hc.Command[0] != "CMD-SHELL" || hc.Command[1] != "curl -f http://localhost:8080/health" {
+		t.Errorf("expected command string to run in the shell, got %v", hc.Command)
+	}
+	if hc.Interval != "5s" || hc.StartPeriod != "30s" || hc.Retries != 3 {
+		t.Errorf("unexpected healthcheck: %+v", hc)
+	}
+
+	hc = getHealthcheck(props, "exec")
+	if hc == nil || len(hc.Command) != 3 || hc.Command[0] != "pg_isready" {
+		t.Errorf("expected command list to be kept, got %+v", hc)
+	}
+
+	if getHealthcheck(props, "nonexistent") != nil {
+		t.Error("expected nil for missing healthcheck")
+	}
+}
+
 func TestGetString2(t *testing.T) {
 	m := map[string]interface{}{
 		"name":   "test-name",
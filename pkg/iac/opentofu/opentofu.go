@@ -10,6 +10,9 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/davidthor/arcctl/pkg/iac"
@@ -133,7 +136,13 @@ func (p *Plugin) Apply(ctx context.Context, opts iac.RunOptions) (*iac.ApplyResu
 
 	output, err := p.runTF(ctx, workDir, args, opts)
 	if err != nil {
-		return nil, fmt.Errorf("apply failed: %w\nOutput: %s", err, output)
+		// Resources created before the failure are recorded in the state
+		// file, so return it to be saved
+		var partial *iac.ApplyResult
+		if stateBytes, readErr := p.readState(workDir); readErr == nil {
+			partial = &iac.ApplyResult{State: stateBytes}
+		}
+		return partial, fmt.Errorf("apply failed: %w\nOutput: %s", err, output)
 	}
 
 	// Read outputs
@@ -263,6 +272,175 @@ func (p *Plugin) Refresh(ctx context.Context, opts iac.RunOptions) (*iac.Refresh
 	}, nil
 }
 
+// Files written to the module directory for the duration of an import
+const (
+	importFile     = "cldctl_import.tf"
+	importPlanFile = "cldctl_import.tfplan"
+)
+
+// Import adopts an existing resource by adding an import block for it to the
+// module and applying the module. The plan is checked first, and the import
+// fails if it would create or destroy anything; in-place updates that bring
+// the resource in line with the module are applied.
+func (p *Plugin) Import(ctx context.Context, opts iac.RunOptions, imp iac.ImportOptions) (*iac.ApplyResult, error) {
+	workDir := opts.WorkDir
+	if workDir == "" {
+		workDir = opts.ModuleSource
+	}
+
+	address := imp.Address
+	if address == "" {
+		var err error
+		address, err = findResourceAddress(workDir)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Write tfvars file from inputs
+	if err := p.writeTFVars(workDir, opts.Inputs); err != nil {
+		return nil, fmt.Errorf("failed to write tfvars: %w", err)
+	}
+
+	// Initialize if needed
+	if err := p.init(ctx, workDir, opts); err != nil {
+		return nil, fmt.Errorf("init failed: %w", err)
+	}
+
+	// The import block is removed afterwards so that later applies don't
+	// repeat the import
+	if err := os.WriteFile(filepath.Join(workDir, importFile), []byte(importBlock(address, imp.ID)), 0644); err != nil {
+		return nil, fmt.Errorf("failed to write import block: %w", err)
+	}
+	defer os.Remove(filepath.Join(workDir, importFile))
+	defer os.Remove(filepath.Join(workDir, importPlanFile))
+
+	args := []string{
+		"plan",
+		"-input=false",
+		"-out=" + importPlanFile,
+	}
+
+	// Add var file if exists
+	varFile := filepath.Join(workDir, "terraform.tfvars.json")
+	if _, err := os.Stat(varFile); err == nil {
+		args = append(args, "-var-file=terraform.tfvars.json")
+	}
+
+	output, err := p.runTF(ctx, workDir, args, opts)
+	if err != nil {
+		return nil, fmt.Errorf("import plan failed: %w\nOutput: %s", err, output)
+	}
+
+	planJSON, err := p.runTF(ctx, workDir, []string{"show", "-json", importPlanFile}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read import plan: %w", err)
+	}
+	if err := checkImportPlan(planJSON); err != nil {
+		return nil, err
+	}
+
+	output, err = p.runTF(ctx, workDir, []string{"apply", "-input=false", "-auto-approve", importPlanFile}, opts)
+	if err != nil {
+		var partial *iac.ApplyResult
+		if stateBytes, readErr := p.readState(workDir); readErr == nil {
+			partial = &iac.ApplyResult{State: stateBytes}
+		}
+		return partial, fmt.Errorf("import failed: %w\nOutput: %s", err, output)
+	}
+
+	// Read outputs
+	outputs, err := p.getOutputs(ctx, workDir, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get outputs: %w", err)
+	}
+
+	// Read state
+	stateBytes, err := p.readState(workDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read state: %w", err)
+	}
+
+	return &iac.ApplyResult{
+		Outputs: outputs,
+		State:   stateBytes,
+	}, nil
+}
+
+// resourcePattern matches managed resource declarations in .tf files.
+var resourcePattern = regexp.MustCompile(`(?m)^\s*resource\s+"([^"]+)"\s+"([^"]+)"`)
+
+// findResourceAddress returns the address of the only resource the module in
+// workDir declares.
+func findResourceAddress(workDir string) (string, error) {
+	files, err := filepath.Glob(filepath.Join(workDir, "*.tf"))
+	if err != nil {
+		return "", err
+	}
+
+	var addresses []string
+	for _, file := range files {
+		if filepath.Base(file) == importFile {
+			continue
+		}
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return "", err
+		}
+		for _, m := range resourcePattern.FindAllStringSubmatch(string(data), -1) {
+			addresses = append(addresses, m[1]+"."+m[2])
+		}
+	}
+
+	switch len(addresses) {
+	case 0:
+		return "", fmt.Errorf("module declares no resources to import into")
+	case 1:
+		return addresses[0], nil
+	default:
+		sort.Strings(addresses)
+		return "", fmt.Errorf("module declares %d resources (%s); choose one with an address", len(addresses), strings.Join(addresses, ", "))
+	}
+}
+
+// importBlock returns an import block binding address to the existing
+// resource with the given ID.
+func importBlock(address, id string) string {
+	// Escape template sequences so the ID is used literally
+	quoted := strconv.Quote(id)
+	quoted = strings.ReplaceAll(quoted, "${", "$${")
+	quoted = strings.ReplaceAll(quoted, "%{", "%%{")
+	return fmt.Sprintf("import {\n  to = %s\n  id = %s\n}\n", address, quoted)
+}
+
+// checkImportPlan returns an error if a plan, as printed by `show -json`,
+// creates or destroys any resource.
+func checkImportPlan(planJSON string) error {
+	var plan struct {
+		ResourceChanges []struct {
+			Address string `json:"address"`
+			Change  struct {
+				Actions []string `json:"actions"`
+			} `json:"change"`
+		} `json:"resource_changes"`
+	}
+	if err := json.Unmarshal([]byte(planJSON), &plan); err != nil {
+		return fmt.Errorf("failed to parse import plan: %w", err)
+	}
+
+	var unsafe []string
+	for _, rc := range plan.ResourceChanges {
+		action := mapTFAction(rc.Change.Actions)
+		if action == iac.ActionCreate || action == iac.ActionDelete || action == iac.ActionReplace {
+			unsafe = append(unsafe, fmt.Sprintf("%s (%s)", rc.Address, action))
+		}
+	}
+	if len(unsafe) > 0 {
+		return fmt.Errorf("import would change more than the imported resource: %s; check the ID and address", strings.Join(unsafe, ", "))
+	}
+	return nil
+}
+
 func (p *Plugin) init(ctx context.Context, workDir string, opts iac.RunOptions) error {
 	// Check if already initialized
 	if _, err := os.Stat(filepath.Join(workDir, ".terraform")); err == nil {
@@ -408,6 +586,9 @@ func (p *Plugin) runTF(ctx context.Context, workDir string, args []string, opts
 	cmd := exec.CommandContext(ctx, p.binaryPath, args...)
 	cmd.Dir = workDir
 
+	// Interrupt rather than kill on cancellation so that state is written
+	iac.PrepareCommand(cmd)
+
 	// Set up environment
 	cmd.Env = os.Environ()
 	for k, v := range opts.Environment {
@@ -438,5 +619,8 @@ func (p *Plugin) runTF(ctx context.Context, workDir string, args []string, opts
 	return stdout.String(), nil
 }
 
-// Ensure we implement the Plugin interface
-var _ iac.Plugin = (*Plugin)(nil)
+// Ensure we implement the Plugin and Importer interfaces
+var (
+	_ iac.Plugin   = (*Plugin)(nil)
+	_ iac.Importer = (*Plugin)(nil)
+)
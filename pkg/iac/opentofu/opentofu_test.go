@@ -4,6 +4,7 @@ import (
 	"context"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/davidthor/arcctl/pkg/iac"
@@ -517,3 +518,101 @@ func TestPlugin_Init_AlreadyInitialized(t *testing.T) {
 		t.Errorf("expected no error for already initialized project, got: %v", err)
 	}
 }
+
+func TestFindResourceAddress(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	if _, err := findResourceAddress(tmpDir); err == nil {
+		t.Error("expected an error for a module without resources")
+	}
+
+	main := `variable "name" {}
+
+resource "aws_db_instance" "main" {
+  identifier = var.name
+}
+
+output "host" {
+  value = aws_db_instance.main.address
+}
+`
+	if err := os.WriteFile(filepath.Join(tmpDir, "main.tf"), []byte(main), 0644); err != nil {
+		t.Fatalf("failed to write main.tf: %v", err)
+	}
+	// A leftover import block isn't a resource declaration
+	if err := os.WriteFile(filepath.Join(tmpDir, importFile), []byte(importBlock("aws_db_instance.main", "db-1")), 0644); err != nil {
+		t.Fatalf("failed to write import block: %v", err)
+	}
+
+	address, err := findResourceAddress(tmpDir)
+	if err != nil {
+		t.Fatalf("findResourceAddress failed: %v", err)
+	}
+	if address != "aws_db_instance.main" {
+		t.Errorf("expected aws_db_instance.main, got %q", address)
+	}
+
+	extra := `resource "aws_db_parameter_group" "main" {}`
+	if err := os.WriteFile(filepath.Join(tmpDir, "params.tf"), []byte(extra), 0644); err != nil {
+		t.Fatalf("failed to write params.tf: %v", err)
+	}
+	_, err = findResourceAddress(tmpDir)
+	if err == nil || !strings.Contains(err.Error(), "aws_db_instance.main, aws_db_parameter_group.main") {
+		t.Errorf("expected an error listing both resources, got %v", err)
+	}
+}
+
+func TestImportBlock(t *testing.T) {
+	got := importBlock("aws_s3_bucket.this", `my-bucket-${x}"`)
+	want := "import {\n  to = aws_s3_bucket.this\n  id = \"my-bucket-$${x}\\\"\"\n}\n"
+	if got != want {
+		t.Errorf("importBlock() = %q, want %q", got, want)
+	}
+}
+
+func TestCheckImportPlan(t *testing.T) {
+	tests := []struct {
+		name    string
+		plan    string
+		wantErr string
+	}{
+		{
+			name: "import only",
+			plan: `{"resource_changes":[{"address":"aws_s3_bucket.this","change":{"actions":["no-op"],"importing":{"id":"my-bucket"}}}]}`,
+		},
+		{
+			name: "import with update",
+			plan: `{"resource_changes":[{"address":"aws_s3_bucket.this","change":{"actions":["update"]}}]}`,
+		},
+		{
+			name:    "creates a resource",
+			plan:    `{"resource_changes":[{"address":"aws_s3_bucket.this","change":{"actions":["no-op"]}},{"address":"aws_s3_bucket_policy.this","change":{"actions":["create"]}}]}`,
+			wantErr: "aws_s3_bucket_policy.this (create)",
+		},
+		{
+			name:    "replaces a resource",
+			plan:    `{"resource_changes":[{"address":"aws_s3_bucket.this","change":{"actions":["delete","create"]}}]}`,
+			wantErr: "aws_s3_bucket.this (replace)",
+		},
+		{
+			name:    "invalid JSON",
+			plan:    `not json`,
+			wantErr: "failed to parse import plan",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkImportPlan(tt.plan)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
@@ -4,6 +4,7 @@ package iac
 import (
 	"context"
 	"io"
+	"sort"
 )
 
 // Plugin defines the interface for IaC framework plugins.
@@ -14,7 +15,9 @@ type Plugin interface {
 	// Preview generates a preview of changes without applying
 	Preview(ctx context.Context, opts RunOptions) (*PreviewResult, error)
 
-	// Apply applies the module and returns outputs
+	// Apply applies the module and returns outputs. If the apply fails after
+	// changing infrastructure, the result may still carry the partial state
+	// alongside the error.
 	Apply(ctx context.Context, opts RunOptions) (*ApplyResult, error)
 
 	// Destroy destroys resources created by the module
@@ -24,6 +27,27 @@ type Plugin interface {
 	Refresh(ctx context.Context, opts RunOptions) (*RefreshResult, error)
 }
 
+// Importer is implemented by plugins that can adopt existing infrastructure
+// into a module's state instead of creating it.
+type Importer interface {
+	// Import runs the module in import mode, binding the resource at
+	// imp.Address to the existing infrastructure identified by imp.ID, and
+	// returns the module's outputs and state. It fails rather than create,
+	// replace, or destroy infrastructure.
+	Import(ctx context.Context, opts RunOptions, imp ImportOptions) (*ApplyResult, error)
+}
+
+// ImportOptions identifies the existing infrastructure to import.
+type ImportOptions struct {
+	// ID is the provider's identifier for the existing resource (e.g., a
+	// database instance name, bucket name, or container name)
+	ID string
+
+	// Address is the resource within the module to import into. It can be
+	// left empty when the module declares a single importable resource.
+	Address string
+}
+
 // RunOptions configures a plugin execution.
 type RunOptions struct {
 	// ModuleSource is the OCI image reference or local path to the module
@@ -114,6 +138,18 @@ type ApplyResult struct {
 	PartialError error
 }
 
+// SensitiveOutputs returns the sorted names of the outputs marked sensitive.
+func (r *ApplyResult) SensitiveOutputs() []string {
+	var names []string
+	for name, out := range r.Outputs {
+		if out.Sensitive {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
 // OutputValue represents a module output.
 type OutputValue struct {
 	Value     interface{}
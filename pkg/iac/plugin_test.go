@@ -277,6 +277,21 @@ func TestApplyResult(t *testing.T) {
 	}
 }
 
+func TestApplyResult_SensitiveOutputs(t *testing.T) {
+	result := &ApplyResult{
+		Outputs: map[string]OutputValue{
+			"url":      {Value: "postgres://db", Sensitive: true},
+			"host":     {Value: "db"},
+			"password": {Value: "secret", Sensitive: true},
+		},
+	}
+
+	names := result.SensitiveOutputs()
+	if len(names) != 2 || names[0] != "password" || names[1] != "url" {
+		t.Errorf("SensitiveOutputs: got %v, want [password url]", names)
+	}
+}
+
 func TestApplyResult_WithPartialError(t *testing.T) {
 	result := ApplyResult{
 		Outputs: map[string]OutputValue{
@@ -213,6 +213,167 @@ func (p *Plugin) Refresh(ctx context.Context, opts iac.RunOptions) (*iac.Refresh
 	}, nil
 }
 
+// Import adopts an existing resource with `pulumi import`, then previews the
+// program and updates the stack so its outputs are recorded. The address is
+// written as <type>::<name>, e.g. aws:s3/bucket:Bucket::bucket, and must
+// match the resource the program declares. If the preview would create or
+// destroy anything, the imported resource is removed from the stack again
+// and the import fails.
+func (p *Plugin) Import(ctx context.Context, opts iac.RunOptions, imp iac.ImportOptions) (*iac.ApplyResult, error) {
+	workDir := opts.WorkDir
+	if workDir == "" {
+		workDir = opts.ModuleSource
+	}
+
+	resourceType, resourceName, err := parseImportAddress(imp.Address)
+	if err != nil {
+		return nil, err
+	}
+
+	stackName := getStackName(opts.Environment)
+
+	// Write Pulumi config from inputs
+	if err := p.writeConfig(workDir, stackName, opts.Inputs); err != nil {
+		return nil, fmt.Errorf("failed to write config: %w", err)
+	}
+
+	// Initialize stack if needed
+	if err := p.ensureStack(ctx, workDir, stackName, opts); err != nil {
+		return nil, fmt.Errorf("failed to ensure stack: %w", err)
+	}
+
+	args := []string{
+		"import", resourceType, resourceName, imp.ID,
+		"--yes",
+		"--stack", stackName,
+		"--generate-code=false",
+		"--protect=false",
+		"--non-interactive",
+	}
+	output, err := p.runPulumi(ctx, workDir, args, opts)
+	if err != nil {
+		return nil, fmt.Errorf("pulumi import failed: %w\nOutput: %s", err, output)
+	}
+
+	previewArgs := []string{
+		"preview",
+		"--stack", stackName,
+		"--json",
+		"--non-interactive",
+	}
+	output, err = p.runPulumi(ctx, workDir, previewArgs, opts)
+	if err != nil {
+		err = fmt.Errorf("pulumi preview failed: %w", err)
+	} else {
+		err = checkImportPreview(output)
+	}
+	if err != nil {
+		p.forgetImport(ctx, workDir, stackName, resourceType, resourceName, opts)
+		return nil, err
+	}
+
+	upArgs := []string{
+		"up",
+		"--yes",
+		"--stack", stackName,
+		"--json",
+		"--non-interactive",
+	}
+	output, err = p.runPulumi(ctx, workDir, upArgs, opts)
+	if err != nil {
+		return nil, fmt.Errorf("pulumi up failed: %w\nOutput: %s", err, output)
+	}
+
+	// Get outputs
+	outputs, err := p.getOutputs(ctx, workDir, stackName, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get outputs: %w", err)
+	}
+
+	// Export state
+	stateBytes, err := p.exportState(ctx, workDir, stackName, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to export state: %w", err)
+	}
+
+	return &iac.ApplyResult{
+		Outputs: outputs,
+		State:   stateBytes,
+	}, nil
+}
+
+// forgetImport removes an imported resource from the stack without deleting
+// it, after an import that can't go ahead.
+func (p *Plugin) forgetImport(ctx context.Context, workDir, stackName, resourceType, resourceName string, opts iac.RunOptions) {
+	exported, err := p.exportState(ctx, workDir, stackName, opts)
+	if err != nil {
+		return
+	}
+	urn := findURN(exported, resourceType, resourceName)
+	if urn == "" {
+		return
+	}
+	args := []string{"state", "delete", urn, "--yes", "--stack", stackName}
+	_, _ = p.runPulumi(ctx, workDir, args, opts)
+}
+
+// parseImportAddress splits an import address of the form <type>::<name>.
+func parseImportAddress(address string) (string, string, error) {
+	i := strings.LastIndex(address, "::")
+	if i <= 0 || i+2 == len(address) {
+		return "", "", fmt.Errorf("invalid import address %q: expected <type>::<name>, e.g. aws:s3/bucket:Bucket::bucket", address)
+	}
+	return address[:i], address[i+2:], nil
+}
+
+// checkImportPreview returns an error if a preview after an import would
+// create or destroy any resource.
+func checkImportPreview(output string) error {
+	var preview struct {
+		Steps []struct {
+			Op  string `json:"op"`
+			URN string `json:"urn"`
+		} `json:"steps"`
+	}
+	if err := json.Unmarshal([]byte(output), &preview); err != nil {
+		return fmt.Errorf("failed to parse preview: %w", err)
+	}
+
+	var unsafe []string
+	for _, step := range preview.Steps {
+		action := mapPulumiAction(step.Op)
+		if action == iac.ActionCreate || action == iac.ActionDelete || action == iac.ActionReplace {
+			unsafe = append(unsafe, fmt.Sprintf("%s (%s)", step.URN, action))
+		}
+	}
+	if len(unsafe) > 0 {
+		return fmt.Errorf("import would change more than the imported resource: %s; check the ID and address", strings.Join(unsafe, ", "))
+	}
+	return nil
+}
+
+// findURN returns the URN of the resource with the given type and name in an
+// exported stack.
+func findURN(exported []byte, resourceType, resourceName string) string {
+	var stack struct {
+		Deployment struct {
+			Resources []struct {
+				URN  string `json:"urn"`
+				Type string `json:"type"`
+			} `json:"resources"`
+		} `json:"deployment"`
+	}
+	if err := json.Unmarshal(exported, &stack); err != nil {
+		return ""
+	}
+	for _, r := range stack.Deployment.Resources {
+		if r.Type == resourceType && strings.HasSuffix(r.URN, "::"+resourceName) {
+			return r.URN
+		}
+	}
+	return ""
+}
+
 func (p *Plugin) ensureStack(ctx context.Context, workDir, stackName string, opts iac.RunOptions) error {
 	// Check if stack exists
 	listArgs := []string{"stack", "ls", "--json"}
@@ -383,6 +544,9 @@ func (p *Plugin) runPulumi(ctx context.Context, workDir string, args []string, o
 	cmd := exec.CommandContext(ctx, p.pulumiPath, args...)
 	cmd.Dir = workDir
 
+	// Interrupt rather than kill on cancellation so that state is written
+	iac.PrepareCommand(cmd)
+
 	// Set up environment
 	cmd.Env = os.Environ()
 	for k, v := range opts.Environment {
@@ -434,5 +598,8 @@ func hasEnv(env []string, key string) bool {
 	return false
 }
 
-// Ensure we implement the Plugin interface
-var _ iac.Plugin = (*Plugin)(nil)
+// Ensure we implement the Plugin and Importer interfaces
+var (
+	_ iac.Plugin   = (*Plugin)(nil)
+	_ iac.Importer = (*Plugin)(nil)
+)
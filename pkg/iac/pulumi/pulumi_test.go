@@ -3,6 +3,7 @@ package pulumi
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/davidthor/arcctl/pkg/iac"
@@ -430,3 +431,50 @@ func TestYamlEncoder_Marshal(t *testing.T) {
 		t.Error("expected non-empty result")
 	}
 }
+
+func TestParseImportAddress(t *testing.T) {
+	resourceType, name, err := parseImportAddress("aws:s3/bucket:Bucket::bucket")
+	if err != nil {
+		t.Fatalf("parseImportAddress failed: %v", err)
+	}
+	if resourceType != "aws:s3/bucket:Bucket" || name != "bucket" {
+		t.Errorf("got %q, %q", resourceType, name)
+	}
+
+	for _, address := range []string{"", "bucket", "::bucket", "aws:s3/bucket:Bucket::"} {
+		if _, _, err := parseImportAddress(address); err == nil {
+			t.Errorf("expected an error for %q", address)
+		}
+	}
+}
+
+func TestCheckImportPreview(t *testing.T) {
+	same := `{"steps":[{"op":"same","urn":"urn:pulumi:dev::app::aws:s3/bucket:Bucket::bucket"},{"op":"update","urn":"urn:pulumi:dev::app::pulumi:pulumi:Stack::app-dev"}]}`
+	if err := checkImportPreview(same); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	create := `{"steps":[{"op":"create","urn":"urn:pulumi:dev::app::aws:s3/bucketPolicy:BucketPolicy::policy"}]}`
+	err := checkImportPreview(create)
+	if err == nil || !strings.Contains(err.Error(), "BucketPolicy::policy (create)") {
+		t.Errorf("expected an error for a created resource, got %v", err)
+	}
+
+	if err := checkImportPreview("not json"); err == nil {
+		t.Error("expected an error for invalid JSON")
+	}
+}
+
+func TestFindURN(t *testing.T) {
+	exported := []byte(`{"version":3,"deployment":{"resources":[
+		{"urn":"urn:pulumi:dev::app::pulumi:pulumi:Stack::app-dev","type":"pulumi:pulumi:Stack"},
+		{"urn":"urn:pulumi:dev::app::aws:s3/bucket:Bucket::bucket","type":"aws:s3/bucket:Bucket"}
+	]}}`)
+
+	if urn := findURN(exported, "aws:s3/bucket:Bucket", "bucket"); urn != "urn:pulumi:dev::app::aws:s3/bucket:Bucket::bucket" {
+		t.Errorf("unexpected URN %q", urn)
+	}
+	if urn := findURN(exported, "aws:s3/bucket:Bucket", "other"); urn != "" {
+		t.Errorf("expected no URN, got %q", urn)
+	}
+}
@@ -141,6 +141,21 @@ func (c *Client) Exists(ctx context.Context, reference string) (bool, error) {
 	return true, nil
 }
 
+// Digest returns the manifest digest an artifact reference resolves to.
+func (c *Client) Digest(ctx context.Context, reference string) (string, error) {
+	ref, err := name.ParseReference(reference)
+	if err != nil {
+		return "", fmt.Errorf("invalid reference: %w", err)
+	}
+
+	desc, err := remote.Head(ref, remote.WithAuthFromKeychain(c.auth), remote.WithContext(ctx))
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s: %w", reference, err)
+	}
+
+	return desc.Digest.String(), nil
+}
+
 // Tag adds a new tag to an existing artifact.
 func (c *Client) Tag(ctx context.Context, srcRef, destRef string) error {
 	src, err := name.ParseReference(srcRef)
@@ -2,6 +2,8 @@
 package datacenter
 
 import (
+	"time"
+
 	"github.com/davidthor/arcctl/pkg/schema/datacenter/internal"
 )
 
@@ -16,6 +18,9 @@ type Datacenter interface {
 	// Components (datacenter-level component declarations)
 	Components() []DatacenterComponent
 
+	// Policy rules checked against every resource before planning
+	Policies() []Policy
+
 	// Environment configuration
 	Environment() Environment
 
@@ -41,6 +46,26 @@ type DatacenterComponent interface {
 	Variables() map[string]string
 }
 
+// Policy represents a rule that every resource of the listed types must
+// satisfy before a deploy is planned.
+type Policy interface {
+	Name() string
+
+	// Resources lists the resource types the policy applies to; empty means
+	// every resource
+	Resources() []string
+
+	// Condition is an HCL expression that must be true for each resource
+	Condition() string
+
+	// Message is an HCL template describing a violation
+	Message() string
+
+	// Severity is "error" for violations that block deploys or "warn" for
+	// violations that are only shown in the plan
+	Severity() string
+}
+
 // Variable represents a datacenter variable.
 type Variable interface {
 	Name() string
@@ -61,6 +86,26 @@ type Module interface {
 	Environment() map[string]string
 	When() string
 	Volumes() []VolumeMount
+
+	// Timeout limits each apply or destroy attempt (0 means no limit)
+	Timeout() time.Duration
+
+	// Retry returns the policy for retrying failed applies and destroys, or
+	// nil if they are not retried
+	Retry() RetryPolicy
+}
+
+// RetryPolicy describes how failed module runs are retried.
+type RetryPolicy interface {
+	// Attempts is the total number of attempts, including the first
+	Attempts() int
+
+	// Backoff is the delay before the first retry; it doubles after each retry
+	Backoff() time.Duration
+
+	// On lists regular expressions matched against the error; only matching
+	// errors are retried. Empty means any error is retried.
+	On() []string
 }
 
 // VolumeMount represents a volume mount.
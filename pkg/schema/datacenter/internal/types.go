@@ -1,6 +1,8 @@
 // Package internal contains the canonical internal representation for datacenters.
 package internal
 
+import "time"
+
 // InternalDatacenter is the canonical internal representation.
 type InternalDatacenter struct {
 	// Variables
@@ -14,6 +16,9 @@ type InternalDatacenter struct {
 	// as dependencies by other components.
 	Components []InternalDatacenterComponent
 
+	// Policy rules checked against every resource before planning
+	Policies []InternalPolicy
+
 	// Environment configuration
 	Environment InternalEnvironment
 
@@ -31,6 +36,15 @@ type InternalDatacenterComponent struct {
 	Variables map[string]string // HCL expression strings (evaluated at runtime with datacenter variables)
 }
 
+// InternalPolicy represents a policy rule that resources must satisfy.
+type InternalPolicy struct {
+	Name      string
+	Resources []string // Resource types the policy applies to (empty means all)
+	Condition string   // HCL expression that must be true for each resource
+	Message   string   // HCL template describing the violation
+	Severity  string   // "error" blocks deploys, "warn" is shown in the plan
+}
+
 // InternalVariable represents a datacenter variable.
 type InternalVariable struct {
 	Name        string
@@ -57,6 +71,17 @@ type InternalModule struct {
 
 	// Volume mounts
 	Volumes []InternalVolumeMount
+
+	// Failure handling for applies and destroys
+	Timeout time.Duration        // Per-attempt limit (0 means no limit)
+	Retry   *InternalRetryPolicy // nil if failures are not retried
+}
+
+// InternalRetryPolicy describes how failed module runs are retried.
+type InternalRetryPolicy struct {
+	Attempts int           // Total attempts, including the first
+	Backoff  time.Duration // Delay before the first retry, doubled after each retry
+	On       []string      // Only retry errors matching one of these regexes (empty means any error)
 }
 
 // InternalVolumeMount represents a volume mount for module execution.
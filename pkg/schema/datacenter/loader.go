@@ -3,6 +3,7 @@ package datacenter
 import (
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/davidthor/arcctl/pkg/errors"
 	"github.com/davidthor/arcctl/pkg/schema/datacenter/internal"
@@ -128,6 +129,14 @@ func (d *datacenterWrapper) Components() []DatacenterComponent {
 	return result
 }
 
+func (d *datacenterWrapper) Policies() []Policy {
+	result := make([]Policy, len(d.dc.Policies))
+	for i := range d.dc.Policies {
+		result[i] = &policyWrapper{p: &d.dc.Policies[i]}
+	}
+	return result
+}
+
 func (d *datacenterWrapper) Environment() Environment {
 	return &environmentWrapper{e: &d.dc.Environment}
 }
@@ -156,6 +165,17 @@ func (v *variableWrapper) Default() interface{} { return v.v.Default }
 func (v *variableWrapper) Required() bool      { return v.v.Required }
 func (v *variableWrapper) Sensitive() bool     { return v.v.Sensitive }
 
+// policyWrapper implements Policy interface.
+type policyWrapper struct {
+	p *internal.InternalPolicy
+}
+
+func (p *policyWrapper) Name() string        { return p.p.Name }
+func (p *policyWrapper) Resources() []string { return p.p.Resources }
+func (p *policyWrapper) Condition() string   { return p.p.Condition }
+func (p *policyWrapper) Message() string     { return p.p.Message }
+func (p *policyWrapper) Severity() string    { return p.p.Severity }
+
 // datacenterComponentWrapper implements DatacenterComponent interface.
 type datacenterComponentWrapper struct {
 	c *internal.InternalDatacenterComponent
@@ -186,6 +206,24 @@ func (m *moduleWrapper) Volumes() []VolumeMount {
 	return result
 }
 
+func (m *moduleWrapper) Timeout() time.Duration { return m.m.Timeout }
+
+func (m *moduleWrapper) Retry() RetryPolicy {
+	if m.m.Retry == nil {
+		return nil
+	}
+	return &retryPolicyWrapper{r: m.m.Retry}
+}
+
+// retryPolicyWrapper implements RetryPolicy interface.
+type retryPolicyWrapper struct {
+	r *internal.InternalRetryPolicy
+}
+
+func (r *retryPolicyWrapper) Attempts() int          { return r.r.Attempts }
+func (r *retryPolicyWrapper) Backoff() time.Duration { return r.r.Backoff }
+func (r *retryPolicyWrapper) On() []string           { return r.r.On }
+
 // volumeMountWrapper implements VolumeMount interface.
 type volumeMountWrapper struct {
 	v *internal.InternalVolumeMount
@@ -3,6 +3,9 @@ package v1
 import (
 	"fmt"
 	"os"
+	"regexp"
+	"strings"
+	"time"
 
 	"github.com/hashicorp/hcl/v2"
 	"github.com/hashicorp/hcl/v2/hclparse"
@@ -74,6 +77,7 @@ func (p *Parser) ParseBytes(data []byte, filename string) (*SchemaV1, hcl.Diagno
 			{Type: "variable", LabelNames: []string{"name"}},
 			{Type: "module", LabelNames: []string{"name"}},
 			{Type: "component", LabelNames: []string{"name"}},
+			{Type: "policy", LabelNames: []string{"name"}},
 			{Type: "environment"},
 		},
 	}
@@ -120,6 +124,24 @@ func (p *Parser) ParseBytes(data []byte, filename string) (*SchemaV1, hcl.Diagno
 		}
 	}
 
+	// Parse policies
+	policyNames := make(map[string]bool)
+	for _, block := range content.Blocks.OfType("policy") {
+		policy, blockDiags := p.parsePolicy(block)
+		diags = append(diags, blockDiags...)
+		if policyNames[policy.Name] {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Duplicate policy",
+				Detail:   fmt.Sprintf("A policy named %q is already defined; policy names must be unique.", policy.Name),
+				Subject:  block.DefRange.Ptr(),
+			})
+			continue
+		}
+		policyNames[policy.Name] = true
+		schema.Policies = append(schema.Policies, *policy)
+	}
+
 	// Parse environment block
 	for _, block := range content.Blocks.OfType("environment") {
 		env, blockDiags := p.parseEnvironment(block)
@@ -199,10 +221,12 @@ func (p *Parser) parseModule(block *hcl.Block) (*ModuleBlockV1, hcl.Diagnostics)
 			{Name: "when"},
 			{Name: "environment"},
 			{Name: "inputs"},
+			{Name: "timeout"},
 		},
 		Blocks: []hcl.BlockHeaderSchema{
 			{Type: "inputs"},
 			{Type: "volume"},
+			{Type: "retry"},
 		},
 	}
 
@@ -293,9 +317,122 @@ func (p *Parser) parseModule(block *hcl.Block) (*ModuleBlockV1, hcl.Diagnostics)
 		}
 	}
 
+	if attr, ok := content.Attributes["timeout"]; ok {
+		timeout, durDiags := parseDuration(attr, hclCtx)
+		diags = append(diags, durDiags...)
+		module.Timeout = timeout
+	}
+
+	if retryBlocks := content.Blocks.OfType("retry"); len(retryBlocks) > 0 {
+		if len(retryBlocks) > 1 {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Duplicate retry block",
+				Detail:   fmt.Sprintf("Module %q has more than one retry block; only one is allowed.", module.Name),
+				Subject:  &retryBlocks[1].DefRange,
+			})
+		}
+		retry, retryDiags := p.parseRetry(retryBlocks[0])
+		diags = append(diags, retryDiags...)
+		module.Retry = retry
+	}
+
 	return module, diags
 }
 
+func (p *Parser) parseRetry(block *hcl.Block) (*RetryBlockV1, hcl.Diagnostics) {
+	var diags hcl.Diagnostics
+	hclCtx := p.getHCLContext()
+
+	retrySchema := &hcl.BodySchema{
+		Attributes: []hcl.AttributeSchema{
+			{Name: "attempts"},
+			{Name: "backoff"},
+			{Name: "on"},
+		},
+	}
+
+	content, moreDiags := block.Body.Content(retrySchema)
+	diags = append(diags, moreDiags...)
+
+	retry := &RetryBlockV1{}
+
+	if attr, ok := content.Attributes["attempts"]; ok {
+		val, valDiags := attr.Expr.Value(hclCtx)
+		diags = append(diags, valDiags...)
+		if !valDiags.HasErrors() {
+			var attempts int64
+			if val.Type() == cty.Number {
+				attempts, _ = val.AsBigFloat().Int64()
+			}
+			if attempts < 1 {
+				diags = append(diags, &hcl.Diagnostic{
+					Severity: hcl.DiagError,
+					Summary:  "Invalid retry attempts",
+					Detail:   "attempts must be a number of at least 1.",
+					Subject:  attr.Expr.Range().Ptr(),
+				})
+			} else {
+				retry.Attempts = int(attempts)
+			}
+		}
+	}
+
+	if attr, ok := content.Attributes["backoff"]; ok {
+		backoff, durDiags := parseDuration(attr, hclCtx)
+		diags = append(diags, durDiags...)
+		retry.Backoff = backoff
+	}
+
+	if attr, ok := content.Attributes["on"]; ok {
+		val, valDiags := attr.Expr.Value(hclCtx)
+		diags = append(diags, valDiags...)
+		if !valDiags.HasErrors() && (val.Type().IsListType() || val.Type().IsTupleType()) {
+			for _, v := range val.AsValueSlice() {
+				if v.Type() != cty.String {
+					continue
+				}
+				pattern := v.AsString()
+				if _, err := regexp.Compile(pattern); err != nil {
+					diags = append(diags, &hcl.Diagnostic{
+						Severity: hcl.DiagError,
+						Summary:  "Invalid retry pattern",
+						Detail:   fmt.Sprintf("%q is not a valid regular expression: %s", pattern, err),
+						Subject:  attr.Expr.Range().Ptr(),
+					})
+					continue
+				}
+				retry.On = append(retry.On, pattern)
+			}
+		}
+	}
+
+	return retry, diags
+}
+
+// parseDuration evaluates an attribute that must hold a positive duration
+// such as "30s" or "10m".
+func parseDuration(attr *hcl.Attribute, hclCtx *hcl.EvalContext) (string, hcl.Diagnostics) {
+	val, diags := attr.Expr.Value(hclCtx)
+	if diags.HasErrors() {
+		return "", diags
+	}
+
+	var value string
+	if val.Type() == cty.String && val.IsKnown() && !val.IsNull() {
+		value = val.AsString()
+	}
+	if d, err := time.ParseDuration(value); err != nil || d <= 0 {
+		return "", append(diags, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  fmt.Sprintf("Invalid %s", attr.Name),
+			Detail:   fmt.Sprintf("%s must be a positive duration such as \"30s\" or \"10m\".", attr.Name),
+			Subject:  attr.Expr.Range().Ptr(),
+		})
+	}
+	return value, diags
+}
+
 func (p *Parser) parseVolume(block *hcl.Block) (*VolumeBlockV1, hcl.Diagnostics) {
 	var diags hcl.Diagnostics
 	hclCtx := p.getHCLContext()
@@ -460,6 +597,109 @@ func (p *Parser) parseComponent(block *hcl.Block) (*ComponentBlockV1, hcl.Diagno
 	return comp, diags
 }
 
+// policyResourceTypes are the resource types a policy can be limited to.
+var policyResourceTypes = map[string]bool{
+	"database":      true,
+	"task":          true,
+	"bucket":        true,
+	"encryptionKey": true,
+	"smtp":          true,
+	"deployment":    true,
+	"function":      true,
+	"service":       true,
+	"route":         true,
+	"cronjob":       true,
+	"secret":        true,
+	"dockerBuild":   true,
+	"observability": true,
+}
+
+func (p *Parser) parsePolicy(block *hcl.Block) (*PolicyBlockV1, hcl.Diagnostics) {
+	var diags hcl.Diagnostics
+	hclCtx := p.getHCLContext()
+
+	policySchema := &hcl.BodySchema{
+		Attributes: []hcl.AttributeSchema{
+			{Name: "resources"},
+			{Name: "condition", Required: true},
+			{Name: "message", Required: true},
+			{Name: "severity"},
+		},
+	}
+
+	content, moreDiags := block.Body.Content(policySchema)
+	diags = append(diags, moreDiags...)
+
+	policy := &PolicyBlockV1{
+		Name:     block.Labels[0],
+		Severity: "error",
+	}
+
+	if attr, ok := content.Attributes["resources"]; ok {
+		val, valDiags := attr.Expr.Value(hclCtx)
+		diags = append(diags, valDiags...)
+		if !valDiags.HasErrors() && (val.Type().IsListType() || val.Type().IsTupleType()) {
+			for _, v := range val.AsValueSlice() {
+				var resourceType string
+				if v.Type() == cty.String && !v.IsNull() {
+					resourceType = v.AsString()
+				}
+				if !policyResourceTypes[resourceType] {
+					diags = append(diags, &hcl.Diagnostic{
+						Severity: hcl.DiagError,
+						Summary:  "Invalid policy resource type",
+						Detail:   fmt.Sprintf("Policy %q lists %q, which is not a resource type (e.g. \"deployment\" or \"bucket\").", policy.Name, resourceType),
+						Subject:  attr.Expr.Range().Ptr(),
+					})
+					continue
+				}
+				policy.Resources = append(policy.Resources, resourceType)
+			}
+		}
+	}
+
+	// The condition and message usually reference the resource, so their
+	// source text is kept for runtime evaluation
+	if attr, ok := content.Attributes["condition"]; ok {
+		policy.ConditionExpr = attr.Expr
+		policy.Condition = p.exprSource(attr.Expr)
+	}
+
+	if attr, ok := content.Attributes["message"]; ok {
+		policy.MessageExpr = attr.Expr
+		val, valDiags := attr.Expr.Value(hclCtx)
+		if len(attr.Expr.Variables()) == 0 && !valDiags.HasErrors() && val.Type() == cty.String {
+			policy.Message = val.AsString()
+		} else {
+			// Store the template source text, without its quotes
+			policy.Message = strings.TrimSuffix(strings.TrimPrefix(p.exprSource(attr.Expr), `"`), `"`)
+		}
+	}
+
+	if attr, ok := content.Attributes["severity"]; ok {
+		val, valDiags := attr.Expr.Value(hclCtx)
+		diags = append(diags, valDiags...)
+		if !valDiags.HasErrors() {
+			var severity string
+			if val.Type() == cty.String && !val.IsNull() {
+				severity = val.AsString()
+			}
+			if severity != "error" && severity != "warn" {
+				diags = append(diags, &hcl.Diagnostic{
+					Severity: hcl.DiagError,
+					Summary:  "Invalid policy severity",
+					Detail:   fmt.Sprintf("Policy %q has severity %q; it must be \"error\" or \"warn\".", policy.Name, severity),
+					Subject:  attr.Expr.Range().Ptr(),
+				})
+			} else {
+				policy.Severity = severity
+			}
+		}
+	}
+
+	return policy, diags
+}
+
 func (p *Parser) parseHook(block *hcl.Block) (*HookBlockV1, hcl.Diagnostics) {
 	var diags hcl.Diagnostics
 	hclCtx := p.getHCLContext()
@@ -486,9 +726,11 @@ func (p *Parser) parseHook(block *hcl.Block) (*HookBlockV1, hcl.Diagnostics) {
 	if attr, ok := content.Attributes["when"]; ok {
 		// Store raw expression for runtime evaluation
 		hook.WhenExpr = attr.Expr
-		// Try to evaluate if possible (may fail if references node.inputs)
-		val, valDiags := attr.Expr.Value(hclCtx)
-		if !valDiags.HasErrors() {
+		// Conditions on the node can only be evaluated at runtime, so their
+		// source text is kept. Others are evaluated now if possible.
+		if referencesNode(attr.Expr) {
+			hook.When = p.exprSource(attr.Expr)
+		} else if val, valDiags := attr.Expr.Value(hclCtx); !valDiags.HasErrors() {
 			// When can be a boolean or string expression
 			switch val.Type() {
 			case cty.Bool:
@@ -511,14 +753,8 @@ func (p *Parser) parseHook(block *hcl.Block) (*HookBlockV1, hcl.Diagnostics) {
 		if !valDiags.HasErrors() && val.Type() == cty.String {
 			hook.Error = val.AsString()
 		} else {
-			// Store the raw expression source text for runtime evaluation
-			rng := attr.Expr.Range()
-			if rng.Filename != "" {
-				data, err := os.ReadFile(rng.Filename)
-				if err == nil && rng.Start.Byte < len(data) && rng.End.Byte <= len(data) {
-					hook.Error = string(data[rng.Start.Byte:rng.End.Byte])
-				}
-			}
+			// Store the template source text, without its quotes, for runtime evaluation
+			hook.Error = strings.TrimSuffix(strings.TrimPrefix(p.exprSource(attr.Expr), `"`), `"`)
 		}
 	}
 
@@ -570,3 +806,30 @@ func (p *Parser) parseHook(block *hcl.Block) (*HookBlockV1, hcl.Diagnostics) {
 
 	return hook, diags
 }
+
+// exprSource returns the source text of an expression parsed by p, or an
+// empty string if it isn't available.
+func (p *Parser) exprSource(expr hcl.Expression) string {
+	rng := expr.Range()
+	var data []byte
+	if file, ok := p.parser.Files()[rng.Filename]; ok {
+		data = file.Bytes
+	} else if b, err := os.ReadFile(rng.Filename); err == nil {
+		data = b
+	}
+	if rng.Start.Byte < len(data) && rng.End.Byte <= len(data) {
+		return string(data[rng.Start.Byte:rng.End.Byte])
+	}
+	return ""
+}
+
+// referencesNode reports whether an expression refers to the node a hook is
+// evaluated for (e.g. node.inputs.type).
+func referencesNode(expr hcl.Expression) bool {
+	for _, traversal := range expr.Variables() {
+		if traversal.RootName() == "node" {
+			return true
+		}
+	}
+	return false
+}
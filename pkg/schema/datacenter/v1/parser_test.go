@@ -242,6 +242,45 @@ environment {
 	}
 }
 
+func TestParser_HookNodeConditionsKeptForRuntime(t *testing.T) {
+	parser := NewParser()
+
+	hcl := `
+environment {
+  database {
+    when  = element(split(":", node.inputs.type), 0) == "mongodb"
+    error = "Unsupported type: ${node.inputs.type}"
+  }
+
+  database {
+    when = node.inputs.type != null
+    module "pg" {
+      build = "./modules/pg"
+    }
+  }
+}
+`
+
+	schema, _, err := parser.ParseBytes([]byte(hcl), "test.hcl")
+	if err != nil {
+		t.Fatalf("failed to parse: %v", err)
+	}
+
+	hooks := schema.Environment.DatabaseHooks
+	if len(hooks) != 2 {
+		t.Fatalf("expected 2 database hooks, got %d", len(hooks))
+	}
+	if hooks[0].When != `element(split(":", node.inputs.type), 0) == "mongodb"` {
+		t.Errorf("expected when source text, got %q", hooks[0].When)
+	}
+	if hooks[0].Error != "Unsupported type: ${node.inputs.type}" {
+		t.Errorf("expected error template without quotes, got %q", hooks[0].Error)
+	}
+	if hooks[1].When != "node.inputs.type != null" {
+		t.Errorf("expected when source text, got %q", hooks[1].When)
+	}
+}
+
 func TestParser_HookErrorMutualExclusivity_ErrorAndModule(t *testing.T) {
 	parser := NewParser()
 
@@ -409,3 +448,156 @@ environment {
 		}
 	}
 }
+
+func TestParser_ModuleRetryAndTimeout(t *testing.T) {
+	parser := NewParser()
+
+	hcl := `
+environment {
+  database {
+    module "postgres" {
+      plugin  = "pulumi"
+      build   = "./modules/rds"
+      timeout = "20m"
+
+      retry {
+        attempts = 4
+        backoff  = "10s"
+        on       = ["Throttling", "(?i)rate exceeded"]
+      }
+    }
+  }
+}
+`
+
+	schema, diags, err := parser.ParseBytes([]byte(hcl), "test.hcl")
+	if err != nil {
+		t.Fatalf("failed to parse: %v", err)
+	}
+	if diags.HasErrors() {
+		t.Fatalf("unexpected diagnostics: %s", diags.Error())
+	}
+
+	module := schema.Environment.DatabaseHooks[0].Modules[0]
+	if module.Timeout != "20m" {
+		t.Errorf("expected timeout 20m, got %q", module.Timeout)
+	}
+	if module.Retry == nil {
+		t.Fatal("expected retry block")
+	}
+	if module.Retry.Attempts != 4 || module.Retry.Backoff != "10s" {
+		t.Errorf("unexpected retry policy: %+v", module.Retry)
+	}
+	if len(module.Retry.On) != 2 || module.Retry.On[1] != "(?i)rate exceeded" {
+		t.Errorf("unexpected retry patterns: %v", module.Retry.On)
+	}
+}
+
+func TestParser_ModuleRetryAndTimeout_Invalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		module string
+	}{
+		{"timeout not a duration", `timeout = "soon"`},
+		{"timeout not a string", `timeout = 30`},
+		{"zero attempts", `retry { attempts = 0 }`},
+		{"invalid backoff", `retry { backoff = "-5s" }`},
+		{"invalid pattern", `retry { on = ["(unclosed"] }`},
+		{"duplicate retry", "retry {}\nretry {}"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hcl := `
+environment {
+  deployment {
+    module "container" {
+      build = "./modules/container"
+      ` + tt.module + `
+    }
+  }
+}
+`
+			_, diags, _ := NewParser().ParseBytes([]byte(hcl), "test.hcl")
+			if !diags.HasErrors() {
+				t.Error("expected error diagnostic")
+			}
+		})
+	}
+}
+
+func TestParser_Policies(t *testing.T) {
+	hcl := `
+policy "no-public-buckets" {
+  resources = ["bucket"]
+  condition = !(environment.name == "production" && node.inputs.public)
+  message   = "Bucket ${node.name} may not be public in production"
+}
+
+policy "memory-required" {
+  condition = node.inputs.memory != ""
+  message   = "Deployments must set memory"
+  severity  = "warn"
+}
+`
+
+	schema, diags, err := NewParser().ParseBytes([]byte(hcl), "test.hcl")
+	if err != nil {
+		t.Fatalf("failed to parse: %v", err)
+	}
+	if diags.HasErrors() {
+		t.Fatalf("unexpected diagnostics: %s", diags.Error())
+	}
+
+	if len(schema.Policies) != 2 {
+		t.Fatalf("expected 2 policies, got %d", len(schema.Policies))
+	}
+
+	p := schema.Policies[0]
+	if p.Name != "no-public-buckets" || len(p.Resources) != 1 || p.Resources[0] != "bucket" {
+		t.Errorf("unexpected policy: %+v", p)
+	}
+	if p.Condition != `!(environment.name == "production" && node.inputs.public)` {
+		t.Errorf("expected condition source text, got %q", p.Condition)
+	}
+	if p.Message != "Bucket ${node.name} may not be public in production" {
+		t.Errorf("expected message template without quotes, got %q", p.Message)
+	}
+	if p.Severity != "error" {
+		t.Errorf("expected default severity 'error', got %q", p.Severity)
+	}
+
+	p = schema.Policies[1]
+	if len(p.Resources) != 0 || p.Severity != "warn" || p.Message != "Deployments must set memory" {
+		t.Errorf("unexpected policy: %+v", p)
+	}
+}
+
+func TestParser_Policies_Invalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy string
+	}{
+		{"missing condition", `policy "p" { message = "m" }`},
+		{"missing message", `policy "p" { condition = true }`},
+		{"invalid severity", `policy "p" { condition = true
+  message = "m"
+  severity = "info" }`},
+		{"invalid resource type", `policy "p" { condition = true
+  message = "m"
+  resources = ["database", "vm"] }`},
+		{"duplicate name", `policy "p" { condition = true
+  message = "m" }
+policy "p" { condition = true
+  message = "m" }`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, diags, _ := NewParser().ParseBytes([]byte(tt.policy), "test.hcl")
+			if !diags.HasErrors() {
+				t.Error("expected error diagnostic")
+			}
+		})
+	}
+}
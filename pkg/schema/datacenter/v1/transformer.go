@@ -2,12 +2,19 @@ package v1
 
 import (
 	"os"
+	"time"
 
 	"github.com/davidthor/arcctl/pkg/schema/datacenter/internal"
 	"github.com/hashicorp/hcl/v2"
 	"github.com/zclconf/go-cty/cty"
 )
 
+// Defaults for retry blocks that leave attempts or backoff unset.
+const (
+	defaultRetryAttempts = 3
+	defaultRetryBackoff  = 5 * time.Second
+)
+
 // Transformer converts v1 schema to internal representation.
 type Transformer struct{}
 
@@ -40,6 +47,17 @@ func (t *Transformer) Transform(v1 *SchemaV1) (*internal.InternalDatacenter, err
 		dc.Components = append(dc.Components, ic)
 	}
 
+	// Transform policies
+	for _, p := range v1.Policies {
+		dc.Policies = append(dc.Policies, internal.InternalPolicy{
+			Name:      p.Name,
+			Resources: p.Resources,
+			Condition: p.Condition,
+			Message:   p.Message,
+			Severity:  p.Severity,
+		})
+	}
+
 	// Transform environment
 	if v1.Environment != nil {
 		dc.Environment = t.transformEnvironment(v1.Environment)
@@ -143,6 +161,25 @@ func (t *Transformer) transformModule(m ModuleBlockV1) internal.InternalModule {
 		})
 	}
 
+	// Durations were validated by the parser
+	if m.Timeout != "" {
+		im.Timeout, _ = time.ParseDuration(m.Timeout)
+	}
+	if m.Retry != nil {
+		retry := &internal.InternalRetryPolicy{
+			Attempts: m.Retry.Attempts,
+			Backoff:  defaultRetryBackoff,
+			On:       m.Retry.On,
+		}
+		if retry.Attempts == 0 {
+			retry.Attempts = defaultRetryAttempts
+		}
+		if m.Retry.Backoff != "" {
+			retry.Backoff, _ = time.ParseDuration(m.Retry.Backoff)
+		}
+		im.Retry = retry
+	}
+
 	return im
 }
 
@@ -196,6 +233,14 @@ func (t *Transformer) transformHooks(hooks []HookBlockV1) []internal.InternalHoo
 				for k, v := range val.AsValueMap() {
 					ih.Outputs[k] = ctyValueToString(v)
 				}
+			} else if pairs, diags := hcl.ExprMap(h.OutputsExpr); !diags.HasErrors() {
+				// Outputs that reference module outputs can't be evaluated
+				// yet, so keep each one's expression text
+				for _, pair := range pairs {
+					if key := hcl.ExprAsKeyword(pair.Key); key != "" {
+						ih.Outputs[key] = exprToString(pair.Value)
+					}
+				}
 			}
 		} else if h.OutputsAttrs != nil {
 			// Block syntax: outputs { ... }
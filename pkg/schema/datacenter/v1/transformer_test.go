@@ -4,6 +4,7 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/hashicorp/hcl/v2"
 	"github.com/hashicorp/hcl/v2/hclsyntax"
@@ -122,3 +123,48 @@ func TestCtyValueToString_Types(t *testing.T) {
 		})
 	}
 }
+
+func TestTransformModule_RetryDefaults(t *testing.T) {
+	transformer := NewTransformer()
+
+	im := transformer.transformModule(ModuleBlockV1{
+		Name:    "postgres",
+		Timeout: "90s",
+		Retry:   &RetryBlockV1{On: []string{"Throttling"}},
+	})
+	if im.Timeout != 90*time.Second {
+		t.Errorf("expected 90s timeout, got %s", im.Timeout)
+	}
+	if im.Retry == nil {
+		t.Fatal("expected retry policy")
+	}
+	if im.Retry.Attempts != defaultRetryAttempts || im.Retry.Backoff != defaultRetryBackoff {
+		t.Errorf("expected default attempts and backoff, got %+v", im.Retry)
+	}
+
+	im = transformer.transformModule(ModuleBlockV1{Name: "postgres"})
+	if im.Timeout != 0 || im.Retry != nil {
+		t.Errorf("expected no timeout or retry policy, got %s %+v", im.Timeout, im.Retry)
+	}
+}
+
+func TestTransformHooks_OutputsReferencingModules(t *testing.T) {
+	transformer := NewTransformer()
+
+	hooks := transformer.transformHooks([]HookBlockV1{
+		{OutputsExpr: parseExpr(t, `{ url = module.db.url, port = 5432 }`)},
+	})
+	if len(hooks) != 1 {
+		t.Fatalf("expected 1 hook, got %d", len(hooks))
+	}
+	outputs := hooks[0].Outputs
+	if len(outputs) != 2 {
+		t.Fatalf("expected outputs url and port, got %v", outputs)
+	}
+	if _, ok := outputs["url"]; !ok {
+		t.Errorf("expected url output, got %v", outputs)
+	}
+	if outputs["port"] != "5432" {
+		t.Errorf("expected literal port output, got %q", outputs["port"])
+	}
+}
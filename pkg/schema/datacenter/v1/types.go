@@ -12,9 +12,22 @@ type SchemaV1 struct {
 	Variables   []VariableBlockV1   `hcl:"variable,block"`
 	Modules     []ModuleBlockV1     `hcl:"module,block"`
 	Components  []ComponentBlockV1  `hcl:"-"` // Parsed manually from HCL
+	Policies    []PolicyBlockV1     `hcl:"policy,block"`
 	Environment *EnvironmentBlockV1 `hcl:"environment,block"`
 }
 
+// PolicyBlockV1 represents a policy rule checked against every resource
+// before a deploy is planned.
+type PolicyBlockV1 struct {
+	Name          string         `hcl:"name,label"`
+	Resources     []string       `hcl:"resources,optional"` // Resource types the policy applies to (empty means all)
+	Condition     string         `hcl:"-"`                  // Source text of the condition, evaluated at runtime
+	ConditionExpr hcl.Expression `hcl:"-"`                  // Raw condition expression; resources must satisfy it
+	Message       string         `hcl:"-"`                  // Source text of the message template
+	MessageExpr   hcl.Expression `hcl:"-"`                  // Raw message expression for runtime interpolation
+	Severity      string         `hcl:"severity,optional"`  // "error" (default) or "warn"
+}
+
 // ComponentBlockV1 represents a datacenter-level component declaration.
 // These components are deployed into environments on-demand when needed as dependencies.
 type ComponentBlockV1 struct {
@@ -46,9 +59,18 @@ type ModuleBlockV1 struct {
 	When            string               `hcl:"when,optional"`
 	WhenExpr        hcl.Expression       `hcl:"-"`             // Raw when expression for runtime evaluation
 	Volumes         []VolumeBlockV1      `hcl:"volume,block"`
+	Timeout         string               `hcl:"timeout,optional"` // Per-attempt limit, e.g. "10m"
+	Retry           *RetryBlockV1        `hcl:"retry,block"`
 	Remain          hcl.Body             `hcl:",remain"`
 }
 
+// RetryBlockV1 represents a module's retry policy for failed applies and destroys.
+type RetryBlockV1 struct {
+	Attempts int      `hcl:"attempts,optional"` // Total attempts, including the first
+	Backoff  string   `hcl:"backoff,optional"`  // Delay before the first retry, doubled after each retry
+	On       []string `hcl:"on,optional"`       // Only retry errors matching one of these regexes
+}
+
 // VolumeBlockV1 represents a volume mount block.
 type VolumeBlockV1 struct {
 	HostPath  string `hcl:"host_path"`
@@ -0,0 +1,68 @@
+package secrets
+
+import (
+	"context"
+	"strings"
+)
+
+// referenceSchemes maps secret reference URI schemes to provider names.
+var referenceSchemes = map[string]string{
+	"vault":  "vault",
+	"aws-sm": "aws",
+	"env":    "env",
+}
+
+// ParseReference parses a secret reference URI and returns the provider name
+// and the provider-specific key. Supported forms:
+//
+//	vault://path/to/secret#field   -> vault provider, key "path/to/secret#field"
+//	aws-sm://secret-name#key       -> aws provider, key "secret-name#key"
+//	env://NAME                     -> env provider, key "NAME"
+//
+// ok is false if the value is not a secret reference.
+func ParseReference(value string) (provider, key string, ok bool) {
+	scheme, rest, found := strings.Cut(value, "://")
+	if !found || rest == "" {
+		return "", "", false
+	}
+
+	provider, ok = referenceSchemes[scheme]
+	if !ok {
+		return "", "", false
+	}
+	return provider, rest, true
+}
+
+// IsReference returns true if the value is a secret reference URI.
+func IsReference(value string) bool {
+	_, _, ok := ParseReference(value)
+	return ok
+}
+
+// Config configures the providers of a manager created with
+// NewManagerFromConfig.
+type Config struct {
+	// Vault configures the vault:// provider
+	Vault VaultConfig
+
+	// AWS configures the aws-sm:// provider
+	AWS AWSConfig
+}
+
+// NewManagerFromConfig creates a manager with the environment provider and
+// the Vault and AWS Secrets Manager providers. The Vault and AWS providers are
+// only initialized when a secret is first requested from them, so missing
+// credentials are only an error for configurations that actually reference
+// those providers.
+func NewManagerFromConfig(cfg Config) *Manager {
+	m := DefaultManager()
+
+	m.RegisterProviderFactory("vault", func(ctx context.Context) (Provider, error) {
+		return NewVaultProvider(cfg.Vault)
+	})
+	m.RegisterProviderFactory("aws", func(ctx context.Context) (Provider, error) {
+		return NewAWSProvider(ctx, cfg.AWS)
+	})
+
+	return m
+}
@@ -0,0 +1,105 @@
+package secrets
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestParseReference(t *testing.T) {
+	tests := []struct {
+		value    string
+		provider string
+		key      string
+		ok       bool
+	}{
+		{"vault://apps/api#password", "vault", "apps/api#password", true},
+		{"aws-sm://prod/db#url", "aws", "prod/db#url", true},
+		{"env://API_KEY", "env", "API_KEY", true},
+		{"https://example.com", "", "", false},
+		{"vault://", "", "", false},
+		{"plain-value", "", "", false},
+	}
+
+	for _, tt := range tests {
+		provider, key, ok := ParseReference(tt.value)
+		if ok != tt.ok || provider != tt.provider || key != tt.key {
+			t.Errorf("ParseReference(%q) = (%q, %q, %v), want (%q, %q, %v)",
+				tt.value, provider, key, ok, tt.provider, tt.key, tt.ok)
+		}
+	}
+}
+
+func TestManager_ResolveSecrets_References(t *testing.T) {
+	m := NewManager()
+	m.RegisterProvider(NewStaticProvider("vault", map[string]string{"apps/api#password": "s3cret"}))
+
+	result, err := m.ResolveSecrets(context.Background(), map[string]interface{}{
+		"password": "vault://apps/api#password",
+		"url":      "https://example.com",
+	})
+	if err != nil {
+		t.Fatalf("ResolveSecrets failed: %v", err)
+	}
+
+	if result["password"] != "s3cret" {
+		t.Errorf("password: got %v", result["password"])
+	}
+	if result["url"] != "https://example.com" {
+		t.Errorf("url should be unchanged, got %v", result["url"])
+	}
+
+	_, err = m.ResolveSecrets(context.Background(), map[string]interface{}{
+		"missing": "vault://apps/other#password",
+	})
+	if err == nil {
+		t.Error("expected error for unresolvable reference")
+	}
+}
+
+func TestManager_RegisterProviderFactory(t *testing.T) {
+	m := NewManager()
+
+	calls := 0
+	m.RegisterProviderFactory("vault", func(ctx context.Context) (Provider, error) {
+		calls++
+		return NewStaticProvider("vault", map[string]string{"a": "1", "b": "2"}), nil
+	})
+
+	if calls != 0 {
+		t.Fatal("factory should not be called until the provider is used")
+	}
+
+	for _, key := range []string{"a", "b"} {
+		if _, err := m.GetFromProvider(context.Background(), "vault", key); err != nil {
+			t.Fatalf("GetFromProvider(%q) failed: %v", key, err)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("factory called %d times, want 1", calls)
+	}
+}
+
+func TestManager_RegisterProviderFactory_Error(t *testing.T) {
+	m := NewManager()
+	m.RegisterProviderFactory("vault", func(ctx context.Context) (Provider, error) {
+		return nil, errors.New("vault address required")
+	})
+
+	if _, err := m.GetFromProvider(context.Background(), "vault", "a"); err == nil {
+		t.Error("expected factory error to be returned")
+	}
+}
+
+func TestNewManagerFromConfig(t *testing.T) {
+	m := NewManagerFromConfig(Config{})
+
+	if _, ok := m.providers["env"]; !ok {
+		t.Error("env provider not registered")
+	}
+	for _, name := range []string{"vault", "aws"} {
+		if _, ok := m.factories[name]; !ok {
+			t.Errorf("%s provider factory not registered", name)
+		}
+	}
+}
@@ -30,10 +30,14 @@ type Provider interface {
 	Delete(ctx context.Context, key string) error
 }
 
+// ProviderFactory creates a provider on first use.
+type ProviderFactory func(ctx context.Context) (Provider, error)
+
 // Manager manages multiple secret providers.
 type Manager struct {
 	mu        sync.RWMutex
 	providers map[string]Provider
+	factories map[string]ProviderFactory
 	priority  []string
 	cache     *secretCache
 }
@@ -42,6 +46,7 @@ type Manager struct {
 func NewManager() *Manager {
 	return &Manager{
 		providers: make(map[string]Provider),
+		factories: make(map[string]ProviderFactory),
 		priority:  []string{},
 		cache:     newSecretCache(),
 	}
@@ -56,6 +61,16 @@ func (m *Manager) RegisterProvider(p Provider) {
 	m.priority = append(m.priority, p.Name())
 }
 
+// RegisterProviderFactory registers a provider that is created the first time
+// a secret is requested from it by name. This avoids connecting to (or
+// requiring credentials for) providers that are never used.
+func (m *Manager) RegisterProviderFactory(name string, factory ProviderFactory) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.factories[name] = factory
+}
+
 // SetPriority sets the provider lookup priority.
 func (m *Manager) SetPriority(providers []string) {
 	m.mu.Lock()
@@ -97,15 +112,53 @@ func (m *Manager) Get(ctx context.Context, key string) (string, error) {
 
 // GetFromProvider retrieves a secret from a specific provider.
 func (m *Manager) GetFromProvider(ctx context.Context, providerName, key string) (string, error) {
+	cacheKey := providerName + ":" + key
+	if value, ok := m.cache.get(cacheKey); ok {
+		return value, nil
+	}
+
+	provider, err := m.provider(ctx, providerName)
+	if err != nil {
+		return "", err
+	}
+
+	value, err := provider.Get(ctx, key)
+	if err != nil {
+		return "", err
+	}
+	m.cache.set(cacheKey, value)
+	return value, nil
+}
+
+// provider returns a registered provider, creating it from its factory if it
+// has not been used yet.
+func (m *Manager) provider(ctx context.Context, name string) (Provider, error) {
 	m.mu.RLock()
-	provider, ok := m.providers[providerName]
+	provider, ok := m.providers[name]
+	factory, hasFactory := m.factories[name]
 	m.mu.RUnlock()
 
-	if !ok {
-		return "", fmt.Errorf("unknown provider: %s", providerName)
+	if ok {
+		return provider, nil
+	}
+	if !hasFactory {
+		return nil, fmt.Errorf("unknown provider: %s", name)
 	}
 
-	return provider.Get(ctx, key)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// Another caller may have created it while we waited for the lock
+	if provider, ok := m.providers[name]; ok {
+		return provider, nil
+	}
+
+	provider, err := factory(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize %s secret provider: %w", name, err)
+	}
+	m.providers[name] = provider
+	return provider, nil
 }
 
 // GetBatch retrieves multiple secrets.
@@ -133,7 +186,8 @@ func (m *Manager) GetBatch(ctx context.Context, keys []string) (map[string]strin
 }
 
 // ResolveSecrets resolves secret references in a map.
-// Format: ${secret:provider:key} or ${secret:key}
+// Format: ${secret:provider:key}, ${secret:key}, or a value that is entirely a
+// reference URI such as vault://path#field (see ParseReference).
 func (m *Manager) ResolveSecrets(ctx context.Context, data map[string]interface{}) (map[string]interface{}, error) {
 	result := make(map[string]interface{})
 
@@ -170,6 +224,15 @@ func (m *Manager) resolveValue(ctx context.Context, v interface{}) (interface{},
 }
 
 func (m *Manager) resolveString(ctx context.Context, s string) (string, error) {
+	// A value that is a reference URI is replaced entirely
+	if providerName, key, ok := ParseReference(s); ok {
+		value, err := m.GetFromProvider(ctx, providerName, key)
+		if err != nil {
+			return "", fmt.Errorf("failed to resolve secret reference %s: %w", s, err)
+		}
+		return value, nil
+	}
+
 	// Look for ${secret:...} patterns
 	if !strings.Contains(s, "${secret:") {
 		return s, nil
@@ -382,3 +445,22 @@ func (p *FileProvider) Delete(ctx context.Context, key string) error {
 	delete(p.secrets, key)
 	return nil
 }
+
+// StaticProvider provides a fixed set of secrets under a given provider name,
+// standing in for another provider, e.g. serving vault:// references in tests.
+type StaticProvider struct {
+	*FileProvider
+	name string
+}
+
+// NewStaticProvider creates a provider named name that provides secrets.
+func NewStaticProvider(name string, secrets map[string]string) *StaticProvider {
+	return &StaticProvider{
+		FileProvider: NewFileProvider(secrets),
+		name:         name,
+	}
+}
+
+func (p *StaticProvider) Name() string {
+	return p.name
+}
@@ -0,0 +1,508 @@
+package state
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/davidthor/arcctl/pkg/state/backend"
+)
+
+// ArchiveFormatVersion is the version of the state archive format written by
+// ExportDatacenter. Archives with a newer version are rejected on import.
+const ArchiveFormatVersion = 1
+
+// archiveManifestName is the name of the manifest entry in a state archive.
+const archiveManifestName = "manifest.json"
+
+// Kinds of state file recorded in an archive manifest.
+const (
+	ArchiveKindDatacenter          = "datacenter"
+	ArchiveKindDatacenterComponent = "datacenter_component"
+	ArchiveKindEnvironment         = "environment"
+	ArchiveKindHistory             = "environment_history"
+	ArchiveKindComponent           = "component"
+	ArchiveKindResource            = "resource"
+	ArchiveKindOther               = "other"
+)
+
+// ArchiveManifest describes the contents of a state archive.
+type ArchiveManifest struct {
+	FormatVersion int           `json:"format_version"`
+	Datacenter    string        `json:"datacenter"`
+	CreatedAt     time.Time     `json:"created_at"`
+	SourceBackend string        `json:"source_backend,omitempty"`
+	Files         []ArchiveFile `json:"files"`
+}
+
+// ArchiveFile is a state file in an archive.
+type ArchiveFile struct {
+	Path   string `json:"path"`
+	Kind   string `json:"kind"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// Count returns the number of files of the given kind in the archive.
+func (m *ArchiveManifest) Count(kind string) int {
+	n := 0
+	for _, f := range m.Files {
+		if f.Kind == kind {
+			n++
+		}
+	}
+	return n
+}
+
+// ArchiveOptions configures an export, import, or copy of datacenter state.
+type ArchiveOptions struct {
+	// Who is recorded on the locks taken while state is read or written.
+	Who string
+
+	// Overwrite allows importing into a backend that already has state for
+	// the datacenter. Files the archive doesn't contain are removed, so the
+	// datacenter's state matches the archive exactly.
+	Overwrite bool
+}
+
+// ErrDatacenterExists is returned when importing a datacenter that already
+// has state in the target backend without ArchiveOptions.Overwrite.
+var ErrDatacenterExists = errors.New("datacenter state already exists")
+
+// archiveEntry is a state file read from a backend or an archive.
+type archiveEntry struct {
+	file ArchiveFile
+	data []byte
+}
+
+// ExportDatacenter writes a gzipped tar archive of all state stored for a
+// datacenter to w: the datacenter itself, its components, and every
+// environment with its history, components, and resources. Files are copied
+// byte for byte, so encrypted values stay encrypted. The datacenter and its
+// environments are locked while the state is read.
+func ExportDatacenter(ctx context.Context, b backend.Backend, datacenter string, w io.Writer, opts ArchiveOptions) (*ArchiveManifest, error) {
+	manifest, entries, err := readDatacenterLocked(ctx, b, datacenter, opts)
+	if err != nil {
+		return nil, err
+	}
+	if err := writeArchive(w, manifest, entries); err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+// ImportArchive reads an archive written by ExportDatacenter from r, verifies
+// its format version and checksums, and writes its state to b. The
+// datacenter and its environments are locked in b while the state is written.
+func ImportArchive(ctx context.Context, b backend.Backend, r io.Reader, opts ArchiveOptions) (*ArchiveManifest, error) {
+	manifest, entries, err := readArchive(r)
+	if err != nil {
+		return nil, err
+	}
+	if err := writeDatacenterLocked(ctx, b, manifest, entries, opts); err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+// CopyDatacenter copies all state stored for a datacenter from one backend
+// to another, holding locks on the datacenter and its environments in both.
+func CopyDatacenter(ctx context.Context, from, to backend.Backend, datacenter string, opts ArchiveOptions) (*ArchiveManifest, error) {
+	envs, err := listArchiveEnvironments(ctx, from, datacenter)
+	if err != nil {
+		return nil, err
+	}
+	release, err := lockDatacenter(ctx, from, datacenter, envs, "export", opts.Who)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	manifest, entries, err := readDatacenter(ctx, from, datacenter)
+	if err != nil {
+		return nil, err
+	}
+	if err := writeDatacenterLocked(ctx, to, manifest, entries, opts); err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+// readDatacenterLocked reads a datacenter's state files while holding locks
+// on the datacenter and its environments.
+func readDatacenterLocked(ctx context.Context, b backend.Backend, datacenter string, opts ArchiveOptions) (*ArchiveManifest, []archiveEntry, error) {
+	envs, err := listArchiveEnvironments(ctx, b, datacenter)
+	if err != nil {
+		return nil, nil, err
+	}
+	release, err := lockDatacenter(ctx, b, datacenter, envs, "export", opts.Who)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer release()
+
+	return readDatacenter(ctx, b, datacenter)
+}
+
+// readDatacenter reads every state file stored under a datacenter, skipping
+// lock files.
+func readDatacenter(ctx context.Context, b backend.Backend, datacenter string) (*ArchiveManifest, []archiveEntry, error) {
+	paths, err := listDatacenterFiles(ctx, b, datacenter)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(paths) == 0 {
+		return nil, nil, fmt.Errorf("no state found for datacenter %q", datacenter)
+	}
+
+	manifest := &ArchiveManifest{
+		FormatVersion: ArchiveFormatVersion,
+		Datacenter:    datacenter,
+		CreatedAt:     time.Now().UTC(),
+		SourceBackend: b.Type(),
+	}
+
+	entries := make([]archiveEntry, 0, len(paths))
+	for _, p := range paths {
+		data, err := readFile(ctx, b, p)
+		if errors.Is(err, backend.ErrNotFound) {
+			continue // Deleted while listing
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+
+		sum := sha256.Sum256(data)
+		file := ArchiveFile{
+			Path:   p,
+			Kind:   archiveKind(p),
+			Size:   int64(len(data)),
+			SHA256: hex.EncodeToString(sum[:]),
+		}
+		manifest.Files = append(manifest.Files, file)
+		entries = append(entries, archiveEntry{file: file, data: data})
+	}
+
+	return manifest, entries, nil
+}
+
+// writeDatacenterLocked writes archived state files to b while holding locks
+// on the datacenter and its environments, both those in the archive and those
+// already stored in b. Each file is written only if it hasn't changed since
+// the locks were taken, so that a process writing without a lock isn't
+// silently overwritten.
+func writeDatacenterLocked(ctx context.Context, b backend.Backend, manifest *ArchiveManifest, entries []archiveEntry, opts ArchiveOptions) error {
+	datacenter := manifest.Datacenter
+
+	existing, err := listDatacenterFiles(ctx, b, datacenter)
+	if err != nil {
+		return err
+	}
+	if len(existing) > 0 && !opts.Overwrite {
+		return fmt.Errorf("%w: %s", ErrDatacenterExists, datacenter)
+	}
+
+	envSet := make(map[string]bool)
+	for _, p := range existing {
+		if env := archiveEnvironment(p); env != "" {
+			envSet[env] = true
+		}
+	}
+	for _, e := range entries {
+		if env := archiveEnvironment(e.file.Path); env != "" {
+			envSet[env] = true
+		}
+	}
+	release, err := lockDatacenter(ctx, b, datacenter, sortedKeys(envSet), "import", opts.Who)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	versions, err := readVersions(ctx, b, existing)
+	if err != nil {
+		return err
+	}
+
+	imported := make(map[string]bool, len(entries))
+	for _, e := range entries {
+		_, err := b.WriteIfVersion(ctx, e.file.Path, bytes.NewReader(e.data), versions[e.file.Path])
+		if errors.Is(err, backend.ErrConflict) {
+			return fmt.Errorf("%w: %s was saved while the archive was being written; re-run the command", ErrStateConflict, e.file.Path)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to write %s: %w", e.file.Path, err)
+		}
+		imported[e.file.Path] = true
+	}
+
+	// Remove state the archive doesn't have, e.g. environments created
+	// after the backup was taken
+	for _, p := range existing {
+		if imported[p] {
+			continue
+		}
+		if err := b.Delete(ctx, p); err != nil {
+			return fmt.Errorf("failed to delete %s: %w", p, err)
+		}
+	}
+
+	return nil
+}
+
+// writeArchive writes the manifest followed by each state file as a gzipped
+// tar stream.
+func writeArchive(w io.Writer, manifest *ArchiveManifest, entries []archiveEntry) error {
+	manifestData, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode manifest: %w", err)
+	}
+
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+
+	write := func(name string, data []byte) error {
+		hdr := &tar.Header{
+			Name:    name,
+			Mode:    0644,
+			Size:    int64(len(data)),
+			ModTime: manifest.CreatedAt,
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return fmt.Errorf("failed to write archive entry %s: %w", name, err)
+		}
+		if _, err := tw.Write(data); err != nil {
+			return fmt.Errorf("failed to write archive entry %s: %w", name, err)
+		}
+		return nil
+	}
+
+	if err := write(archiveManifestName, manifestData); err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if err := write(e.file.Path, e.data); err != nil {
+			return err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return fmt.Errorf("failed to write archive: %w", err)
+	}
+	if err := gz.Close(); err != nil {
+		return fmt.Errorf("failed to write archive: %w", err)
+	}
+	return nil
+}
+
+// readArchive reads and verifies an archive written by writeArchive. Every
+// file listed in the manifest must be present with a matching checksum, and
+// no file may be stored outside the manifest's datacenter.
+func readArchive(r io.Reader) (*ArchiveManifest, []archiveEntry, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read archive: %w", err)
+	}
+	defer gz.Close()
+
+	var manifest *ArchiveManifest
+	contents := make(map[string][]byte)
+
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read archive: %w", err)
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read archive entry %s: %w", hdr.Name, err)
+		}
+
+		if hdr.Name == archiveManifestName {
+			manifest = &ArchiveManifest{}
+			if err := json.Unmarshal(data, manifest); err != nil {
+				return nil, nil, fmt.Errorf("failed to decode manifest: %w", err)
+			}
+			continue
+		}
+		contents[hdr.Name] = data
+	}
+
+	if manifest == nil {
+		return nil, nil, fmt.Errorf("archive has no %s", archiveManifestName)
+	}
+	if manifest.FormatVersion < 1 || manifest.FormatVersion > ArchiveFormatVersion {
+		return nil, nil, fmt.Errorf("unsupported archive format version %d (this version of cldctl supports %d)", manifest.FormatVersion, ArchiveFormatVersion)
+	}
+	if manifest.Datacenter == "" || strings.ContainsAny(manifest.Datacenter, "/\\") || manifest.Datacenter == ".." {
+		return nil, nil, fmt.Errorf("archive has an invalid datacenter name %q", manifest.Datacenter)
+	}
+
+	prefix := datacenterPrefix(manifest.Datacenter)
+	entries := make([]archiveEntry, 0, len(manifest.Files))
+	for _, f := range manifest.Files {
+		if path.Clean(f.Path) != f.Path || !strings.HasPrefix(f.Path, prefix) {
+			return nil, nil, fmt.Errorf("archive file %s is outside datacenter %s", f.Path, manifest.Datacenter)
+		}
+		data, ok := contents[f.Path]
+		if !ok {
+			return nil, nil, fmt.Errorf("archive is missing %s", f.Path)
+		}
+		sum := sha256.Sum256(data)
+		if hex.EncodeToString(sum[:]) != f.SHA256 {
+			return nil, nil, fmt.Errorf("checksum mismatch for %s", f.Path)
+		}
+		delete(contents, f.Path)
+		entries = append(entries, archiveEntry{file: f, data: data})
+	}
+	for name := range contents {
+		return nil, nil, fmt.Errorf("archive file %s is not listed in the manifest", name)
+	}
+
+	return manifest, entries, nil
+}
+
+// lockDatacenter locks a datacenter and then each of its environments for an
+// archive operation. The returned function releases the locks.
+func lockDatacenter(ctx context.Context, b backend.Backend, datacenter string, envs []string, operation, who string) (func(), error) {
+	scopes := make([]LockScope, 0, len(envs)+1)
+	scopes = append(scopes, LockScope{Datacenter: datacenter, Operation: operation, Who: who})
+	for _, env := range envs {
+		scopes = append(scopes, LockScope{Datacenter: datacenter, Environment: env, Operation: operation, Who: who})
+	}
+
+	return LockAll(ctx, func(ctx context.Context, scope LockScope) (backend.Lock, error) {
+		return lockScope(ctx, b, scope)
+	}, scopes...)
+}
+
+// readVersions returns the backend's version token for each of the given
+// paths. Paths that no longer exist are left out.
+func readVersions(ctx context.Context, b backend.Backend, paths []string) (map[string]string, error) {
+	versions := make(map[string]string, len(paths))
+	for _, p := range paths {
+		reader, version, err := b.ReadVersion(ctx, p)
+		if errors.Is(err, backend.ErrNotFound) {
+			continue // Deleted while listing
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", p, err)
+		}
+		reader.Close()
+		versions[p] = version
+	}
+	return versions, nil
+}
+
+// listDatacenterFiles returns the sorted paths of the state files stored under
+// a datacenter, excluding locks.
+func listDatacenterFiles(ctx context.Context, b backend.Backend, datacenter string) ([]string, error) {
+	paths, err := b.List(ctx, datacenterPrefix(datacenter))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list state for datacenter %s: %w", datacenter, err)
+	}
+
+	var files []string
+	for _, p := range paths {
+		if strings.HasSuffix(p, ".lock") {
+			continue
+		}
+		files = append(files, p)
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// listArchiveEnvironments returns the names of the environments with state
+// stored under a datacenter.
+func listArchiveEnvironments(ctx context.Context, b backend.Backend, datacenter string) ([]string, error) {
+	paths, err := listDatacenterFiles(ctx, b, datacenter)
+	if err != nil {
+		return nil, err
+	}
+
+	envSet := make(map[string]bool)
+	for _, p := range paths {
+		if env := archiveEnvironment(p); env != "" {
+			envSet[env] = true
+		}
+	}
+	return sortedKeys(envSet), nil
+}
+
+// archiveEnvironment returns the environment a state file belongs to, or ""
+// for datacenter-level files.
+func archiveEnvironment(p string) string {
+	parts := splitPath(p)
+	if len(parts) >= 5 && parts[0] == "datacenters" && parts[2] == "environments" {
+		return parts[3]
+	}
+	return ""
+}
+
+// archiveKind classifies a state file by its path.
+func archiveKind(p string) string {
+	parts := splitPath(p)
+	switch {
+	case len(parts) == 3 && parts[2] == "datacenter.state.json":
+		return ArchiveKindDatacenter
+	case len(parts) == 4 && parts[2] == "components":
+		return ArchiveKindDatacenterComponent
+	case len(parts) == 5 && parts[2] == "environments" && parts[4] == "environment.state.json":
+		return ArchiveKindEnvironment
+	case len(parts) == 6 && parts[2] == "environments" && parts[4] == "history":
+		return ArchiveKindHistory
+	case len(parts) == 7 && parts[4] == "components" && parts[6] == "component.state.json":
+		return ArchiveKindComponent
+	case len(parts) == 8 && parts[4] == "components" && parts[6] == "resources":
+		return ArchiveKindResource
+	default:
+		return ArchiveKindOther
+	}
+}
+
+func datacenterPrefix(datacenter string) string {
+	return path.Join("datacenters", datacenter) + "/"
+}
+
+func readFile(ctx context.Context, b backend.Backend, p string) ([]byte, error) {
+	reader, err := b.Read(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", p, err)
+	}
+	return data, nil
+}
+
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for k := range set {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
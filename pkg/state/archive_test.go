@@ -0,0 +1,369 @@
+package state
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/davidthor/arcctl/pkg/state/backend"
+	"github.com/davidthor/arcctl/pkg/state/backend/local"
+	"github.com/davidthor/arcctl/pkg/state/types"
+)
+
+func newArchiveTestBackend(t *testing.T) backend.Backend {
+	t.Helper()
+	b, err := local.NewBackend(map[string]string{"path": t.TempDir()})
+	if err != nil {
+		t.Fatalf("Failed to create backend: %v", err)
+	}
+	return b
+}
+
+// seedArchiveState saves a datacenter with one component, an environment with
+// a history snapshot, and a component with one resource.
+func seedArchiveState(t *testing.T, m Manager) {
+	t.Helper()
+	ctx := context.Background()
+
+	if err := m.SaveDatacenter(ctx, &types.DatacenterState{Name: "dc1", Variables: map[string]string{"region": "us-east-1"}}); err != nil {
+		t.Fatalf("SaveDatacenter failed: %v", err)
+	}
+	if err := m.SaveDatacenterComponent(ctx, "dc1", &types.DatacenterComponentConfig{Name: "otel"}); err != nil {
+		t.Fatalf("SaveDatacenterComponent failed: %v", err)
+	}
+	if err := m.SaveEnvironment(ctx, "dc1", &types.EnvironmentState{Name: "production", Datacenter: "dc1"}); err != nil {
+		t.Fatalf("SaveEnvironment failed: %v", err)
+	}
+	if _, err := m.SnapshotEnvironment(ctx, "dc1", "production", types.EnvironmentSnapshotInfo{Operation: "deploy"}); err != nil {
+		t.Fatalf("SnapshotEnvironment failed: %v", err)
+	}
+	if err := m.SaveComponent(ctx, "dc1", "production", &types.ComponentState{Name: "api"}); err != nil {
+		t.Fatalf("SaveComponent failed: %v", err)
+	}
+	if err := m.SaveResource(ctx, "dc1", "production", "api", &types.ResourceState{Component: "api", Name: "main", Type: "database"}); err != nil {
+		t.Fatalf("SaveResource failed: %v", err)
+	}
+
+	// State for other datacenters isn't exported
+	if err := m.SaveDatacenter(ctx, &types.DatacenterState{Name: "dc2"}); err != nil {
+		t.Fatalf("SaveDatacenter failed: %v", err)
+	}
+}
+
+func TestExportImport(t *testing.T) {
+	ctx := context.Background()
+	src := newArchiveTestBackend(t)
+	seedArchiveState(t, NewManager(src))
+
+	var buf bytes.Buffer
+	manifest, err := ExportDatacenter(ctx, src, "dc1", &buf, ArchiveOptions{Who: "alice"})
+	if err != nil {
+		t.Fatalf("ExportDatacenter failed: %v", err)
+	}
+
+	if manifest.FormatVersion != ArchiveFormatVersion || manifest.Datacenter != "dc1" || manifest.SourceBackend != "local" {
+		t.Errorf("unexpected manifest: %+v", manifest)
+	}
+	counts := map[string]int{
+		ArchiveKindDatacenter:          1,
+		ArchiveKindDatacenterComponent: 1,
+		ArchiveKindEnvironment:         1,
+		ArchiveKindHistory:             1,
+		ArchiveKindComponent:           1,
+		ArchiveKindResource:            1,
+		ArchiveKindOther:               0,
+	}
+	for kind, want := range counts {
+		if got := manifest.Count(kind); got != want {
+			t.Errorf("Count(%s) = %d, want %d", kind, got, want)
+		}
+	}
+
+	// Export releases its locks
+	locks, _ := NewManager(src).ListLocks(ctx, "dc1")
+	if len(locks) != 0 {
+		t.Errorf("expected no locks after export, got %+v", locks)
+	}
+
+	dst := newArchiveTestBackend(t)
+	if _, err := ImportArchive(ctx, dst, bytes.NewReader(buf.Bytes()), ArchiveOptions{}); err != nil {
+		t.Fatalf("ImportArchive failed: %v", err)
+	}
+
+	// Files are copied byte for byte
+	for _, f := range manifest.Files {
+		want, _ := readFile(ctx, src, f.Path)
+		got, err := readFile(ctx, dst, f.Path)
+		if err != nil {
+			t.Fatalf("imported state missing %s: %v", f.Path, err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("%s differs after import", f.Path)
+		}
+	}
+	resource, err := NewManager(dst).GetResource(ctx, "dc1", "production", "api", "database.main")
+	if err != nil || resource.Type != "database" {
+		t.Errorf("expected the resource to be readable after import, got %+v, %v", resource, err)
+	}
+	if exists, _ := dst.Exists(ctx, datacenterPath("dc2")); exists {
+		t.Error("expected other datacenters not to be exported")
+	}
+
+	// Existing state isn't replaced without Overwrite
+	_, err = ImportArchive(ctx, dst, bytes.NewReader(buf.Bytes()), ArchiveOptions{})
+	if !errors.Is(err, ErrDatacenterExists) {
+		t.Fatalf("expected ErrDatacenterExists, got %v", err)
+	}
+
+	// Overwrite removes state the archive doesn't have
+	if err := NewManager(dst).SaveEnvironment(ctx, "dc1", &types.EnvironmentState{Name: "staging", Datacenter: "dc1"}); err != nil {
+		t.Fatalf("SaveEnvironment failed: %v", err)
+	}
+	if _, err := ImportArchive(ctx, dst, bytes.NewReader(buf.Bytes()), ArchiveOptions{Overwrite: true}); err != nil {
+		t.Fatalf("ImportArchive with Overwrite failed: %v", err)
+	}
+	if exists, _ := dst.Exists(ctx, environmentPath("dc1", "staging")); exists {
+		t.Error("expected the staging environment to be removed by the overwrite")
+	}
+}
+
+func TestExportDatacenter_NotFound(t *testing.T) {
+	_, err := ExportDatacenter(context.Background(), newArchiveTestBackend(t), "missing", io.Discard, ArchiveOptions{})
+	if err == nil || !strings.Contains(err.Error(), "no state found") {
+		t.Errorf("expected a not found error, got %v", err)
+	}
+}
+
+func TestExportDatacenter_Locked(t *testing.T) {
+	ctx := context.Background()
+	src := newArchiveTestBackend(t)
+	m := NewManager(src)
+	seedArchiveState(t, m)
+
+	lock, err := m.Lock(ctx, LockScope{Datacenter: "dc1", Environment: "production", Operation: "deploy"})
+	if err != nil {
+		t.Fatalf("Lock failed: %v", err)
+	}
+	defer func() { _ = lock.Unlock(ctx) }()
+
+	_, err = ExportDatacenter(ctx, src, "dc1", io.Discard, ArchiveOptions{})
+	if !errors.Is(err, backend.ErrLocked) {
+		t.Errorf("expected ErrLocked, got %v", err)
+	}
+}
+
+func TestImportArchive_Invalid(t *testing.T) {
+	ctx := context.Background()
+	src := newArchiveTestBackend(t)
+	seedArchiveState(t, NewManager(src))
+
+	var buf bytes.Buffer
+	if _, err := ExportDatacenter(ctx, src, "dc1", &buf, ArchiveOptions{}); err != nil {
+		t.Fatalf("ExportDatacenter failed: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		modify  func(manifest *ArchiveManifest, files map[string][]byte)
+		wantErr string
+	}{
+		{
+			name: "tampered file",
+			modify: func(m *ArchiveManifest, files map[string][]byte) {
+				files[datacenterPath("dc1")] = []byte(`{"name":"dc1"}`)
+			},
+			wantErr: "checksum mismatch",
+		},
+		{
+			name: "newer format version",
+			modify: func(m *ArchiveManifest, files map[string][]byte) {
+				m.FormatVersion = ArchiveFormatVersion + 1
+			},
+			wantErr: "unsupported archive format version",
+		},
+		{
+			name: "missing file",
+			modify: func(m *ArchiveManifest, files map[string][]byte) {
+				delete(files, datacenterPath("dc1"))
+			},
+			wantErr: "archive is missing",
+		},
+		{
+			name: "file outside datacenter",
+			modify: func(m *ArchiveManifest, files map[string][]byte) {
+				m.Files[0].Path = "datacenters/dc2/datacenter.state.json"
+				files[m.Files[0].Path] = files[datacenterPath("dc1")]
+			},
+			wantErr: "outside datacenter",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			manifest, files := unpackTestArchive(t, buf.Bytes())
+			tt.modify(manifest, files)
+
+			_, err := ImportArchive(ctx, newArchiveTestBackend(t), repackTestArchive(t, manifest, files), ArchiveOptions{})
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestCopyDatacenter(t *testing.T) {
+	ctx := context.Background()
+	src := newArchiveTestBackend(t)
+	seedArchiveState(t, NewManager(src))
+	dst := newArchiveTestBackend(t)
+
+	manifest, err := CopyDatacenter(ctx, src, dst, "dc1", ArchiveOptions{Who: "alice"})
+	if err != nil {
+		t.Fatalf("CopyDatacenter failed: %v", err)
+	}
+	if len(manifest.Files) != 6 {
+		t.Errorf("expected 6 files copied, got %d", len(manifest.Files))
+	}
+
+	env, err := NewManager(dst).GetEnvironment(ctx, "dc1", "production")
+	if err != nil || env.Name != "production" {
+		t.Errorf("expected the environment in the target, got %+v, %v", env, err)
+	}
+
+	// The target's environments are locked during the copy
+	lock, err := NewManager(dst).Lock(ctx, LockScope{Datacenter: "dc1", Environment: "production", Operation: "deploy"})
+	if err != nil {
+		t.Fatalf("Lock failed: %v", err)
+	}
+	defer func() { _ = lock.Unlock(ctx) }()
+
+	_, err = CopyDatacenter(ctx, src, dst, "dc1", ArchiveOptions{Overwrite: true})
+	if !errors.Is(err, backend.ErrLocked) {
+		t.Errorf("expected ErrLocked, got %v", err)
+	}
+
+	// Locks taken on the source are released after a failed copy
+	locks, _ := NewManager(src).ListLocks(ctx, "dc1")
+	if len(locks) != 0 {
+		t.Errorf("expected no source locks after the copy, got %+v", locks)
+	}
+}
+
+func TestCopyDatacenter_DatacenterLocked(t *testing.T) {
+	ctx := context.Background()
+	src := newArchiveTestBackend(t)
+	seedArchiveState(t, NewManager(src))
+	dst := newArchiveTestBackend(t)
+
+	// The datacenter is locked even where it has no environments yet
+	lock, err := NewManager(dst).Lock(ctx, LockScope{Datacenter: "dc1", Operation: "import"})
+	if err != nil {
+		t.Fatalf("Lock failed: %v", err)
+	}
+	defer func() { _ = lock.Unlock(ctx) }()
+
+	_, err = CopyDatacenter(ctx, src, dst, "dc1", ArchiveOptions{})
+	if !errors.Is(err, backend.ErrLocked) || !strings.Contains(err.Error(), "failed to lock datacenter dc1") {
+		t.Errorf("expected the datacenter lock to be held, got %v", err)
+	}
+	if files, _ := listDatacenterFiles(ctx, dst, "dc1"); len(files) != 0 {
+		t.Errorf("expected nothing to be written, got %v", files)
+	}
+}
+
+// racingBackend saves a datacenter the first time a file is written through
+// it, as a process that doesn't take the archive locks would.
+type racingBackend struct {
+	backend.Backend
+	raced bool
+}
+
+func (b *racingBackend) WriteIfVersion(ctx context.Context, p string, data io.Reader, version string) (string, error) {
+	if !b.raced {
+		b.raced = true
+		_ = b.Backend.Write(ctx, datacenterPath("dc1"), strings.NewReader(`{"name": "dc1", "serial": 9}`))
+	}
+	return b.Backend.WriteIfVersion(ctx, p, data, version)
+}
+
+func TestCopyDatacenter_ConcurrentWrite(t *testing.T) {
+	ctx := context.Background()
+	src := newArchiveTestBackend(t)
+	seedArchiveState(t, NewManager(src))
+	dst := newArchiveTestBackend(t)
+	seedArchiveState(t, NewManager(dst))
+
+	_, err := CopyDatacenter(ctx, src, &racingBackend{Backend: dst}, "dc1", ArchiveOptions{Overwrite: true})
+	if !errors.Is(err, ErrStateConflict) {
+		t.Fatalf("expected ErrStateConflict, got %v", err)
+	}
+	if !strings.Contains(readRaw(t, dst, datacenterPath("dc1")), `"serial": 9`) {
+		t.Error("expected the concurrently saved datacenter to be kept")
+	}
+}
+
+func TestArchiveKind(t *testing.T) {
+	tests := map[string]string{
+		"datacenters/dc/datacenter.state.json":                                         ArchiveKindDatacenter,
+		"datacenters/dc/components/otel.state.json":                                    ArchiveKindDatacenterComponent,
+		"datacenters/dc/environments/prod/environment.state.json":                      ArchiveKindEnvironment,
+		"datacenters/dc/environments/prod/history/3.state.json":                        ArchiveKindHistory,
+		"datacenters/dc/environments/prod/components/api/component.state.json":         ArchiveKindComponent,
+		"datacenters/dc/environments/prod/components/api/resources/db.main.state.json": ArchiveKindResource,
+		"datacenters/dc/notes.txt":                                                     ArchiveKindOther,
+	}
+	for p, want := range tests {
+		if got := archiveKind(p); got != want {
+			t.Errorf("archiveKind(%q) = %q, want %q", p, got, want)
+		}
+	}
+}
+
+func unpackTestArchive(t *testing.T, data []byte) (*ArchiveManifest, map[string][]byte) {
+	t.Helper()
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("failed to open archive: %v", err)
+	}
+	tr := tar.NewReader(gz)
+
+	var manifest ArchiveManifest
+	files := make(map[string][]byte)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("failed to read archive: %v", err)
+		}
+		content, _ := io.ReadAll(tr)
+		if hdr.Name == archiveManifestName {
+			if err := json.Unmarshal(content, &manifest); err != nil {
+				t.Fatalf("failed to decode manifest: %v", err)
+			}
+			continue
+		}
+		files[hdr.Name] = content
+	}
+	return &manifest, files
+}
+
+func repackTestArchive(t *testing.T, manifest *ArchiveManifest, files map[string][]byte) io.Reader {
+	t.Helper()
+	var entries []archiveEntry
+	for name, data := range files {
+		entries = append(entries, archiveEntry{file: ArchiveFile{Path: name}, data: data})
+	}
+	var buf bytes.Buffer
+	if err := writeArchive(&buf, manifest, entries); err != nil {
+		t.Fatalf("failed to write archive: %v", err)
+	}
+	return &buf
+}
@@ -141,6 +141,59 @@ func (b *Backend) Write(ctx context.Context, statePath string, data io.Reader) e
 	return nil
 }
 
+func (b *Backend) ReadVersion(ctx context.Context, statePath string) (io.ReadCloser, string, error) {
+	blobPath := b.fullPath(statePath)
+
+	resp, err := b.client.DownloadStream(ctx, b.containerName, blobPath, nil)
+	if err != nil {
+		if bloberror.HasCode(err, bloberror.BlobNotFound) {
+			return nil, "", backend.ErrNotFound
+		}
+		return nil, "", fmt.Errorf("failed to read state from azure://%s/%s: %w", b.containerName, blobPath, err)
+	}
+
+	var version string
+	if resp.ETag != nil {
+		version = string(*resp.ETag)
+	}
+	return resp.Body, version, nil
+}
+
+func (b *Backend) WriteIfVersion(ctx context.Context, statePath string, data io.Reader, version string) (string, error) {
+	blobPath := b.fullPath(statePath)
+
+	content, err := io.ReadAll(data)
+	if err != nil {
+		return "", fmt.Errorf("failed to read data: %w", err)
+	}
+
+	conds := &blob.ModifiedAccessConditions{}
+	if version == "" {
+		conds.IfNoneMatch = toPtr(azcore.ETagAny)
+	} else {
+		conds.IfMatch = toPtr(azcore.ETag(version))
+	}
+
+	resp, err := b.client.UploadBuffer(ctx, b.containerName, blobPath, content, &azblob.UploadBufferOptions{
+		HTTPHeaders: &blob.HTTPHeaders{
+			BlobContentType: toPtr("application/json"),
+		},
+		AccessConditions: &blob.AccessConditions{ModifiedAccessConditions: conds},
+	})
+	if err != nil {
+		if bloberror.HasCode(err, bloberror.ConditionNotMet, bloberror.BlobAlreadyExists) {
+			return "", backend.ErrConflict
+		}
+		return "", fmt.Errorf("failed to write state to azure://%s/%s: %w", b.containerName, blobPath, err)
+	}
+
+	var written string
+	if resp.ETag != nil {
+		written = string(*resp.ETag)
+	}
+	return written, nil
+}
+
 func (b *Backend) Delete(ctx context.Context, statePath string) error {
 	blobPath := b.fullPath(statePath)
 
@@ -209,8 +262,9 @@ func (b *Backend) Lock(ctx context.Context, statePath string, info backend.LockI
 	// Check for existing lock
 	existingLock, err := b.readLock(ctx, lockPath)
 	if err == nil {
-		// Check if lock is stale (older than 1 hour)
-		if time.Since(existingLock.Created) < time.Hour {
+		// Expired locks were left behind by a process that exited without
+		// unlocking, so they are taken over
+		if !existingLock.Expired(time.Now()) {
 			return nil, &backend.LockError{
 				Info: existingLock,
 				Err:  backend.ErrLocked,
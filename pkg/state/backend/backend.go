@@ -14,6 +14,10 @@ var ErrNotFound = errors.New("state not found")
 // ErrLocked is returned when state is already locked.
 var ErrLocked = errors.New("state is locked")
 
+// ErrConflict is returned by WriteIfVersion when the stored data no longer
+// matches the expected version.
+var ErrConflict = errors.New("state was modified concurrently")
+
 // Backend defines the interface for state storage backends.
 type Backend interface {
 	// Type returns the backend type identifier (e.g., "s3", "local", "gcs")
@@ -27,6 +31,18 @@ type Backend interface {
 	// Creates parent directories/prefixes as needed.
 	Write(ctx context.Context, path string, data io.Reader) error
 
+	// ReadVersion reads state data from the given path along with an opaque
+	// version token identifying the stored revision (e.g., an ETag).
+	// Returns ErrNotFound if the path doesn't exist.
+	ReadVersion(ctx context.Context, path string) (io.ReadCloser, string, error)
+
+	// WriteIfVersion writes state data to the given path only if the stored
+	// revision still matches version, as returned by ReadVersion. An empty
+	// version requires that the path doesn't exist yet. Returns the version
+	// token of the revision written.
+	// Returns ErrConflict if the stored revision has changed.
+	WriteIfVersion(ctx context.Context, path string, data io.Reader, version string) (string, error)
+
 	// Delete removes state data at the given path.
 	// Returns nil if path doesn't exist (idempotent).
 	Delete(ctx context.Context, path string) error
@@ -43,6 +59,14 @@ type Backend interface {
 	Lock(ctx context.Context, path string, info LockInfo) (Lock, error)
 }
 
+// ForceUnlocker is implemented by backends whose locks aren't released by
+// deleting the lock file, such as locks held by a database session.
+type ForceUnlocker interface {
+	// ForceUnlock releases the lock on the given path regardless of which
+	// process holds it.
+	ForceUnlock(ctx context.Context, path string) error
+}
+
 // Lock represents an acquired lock.
 type Lock interface {
 	// ID returns the lock identifier.
@@ -65,6 +89,19 @@ type LockInfo struct {
 	Expires   time.Time `json:"expires,omitempty"` // Optional expiration
 }
 
+// DefaultLockTimeout is how long a lock without an expiration is held before
+// it is considered stale.
+const DefaultLockTimeout = time.Hour
+
+// Expired returns true if the lock has passed its expiration time or, if it
+// has none, is older than DefaultLockTimeout.
+func (i LockInfo) Expired(now time.Time) bool {
+	if !i.Expires.IsZero() {
+		return now.After(i.Expires)
+	}
+	return now.Sub(i.Created) > DefaultLockTimeout
+}
+
 // LockError is returned when locking fails because state is already locked.
 type LockError struct {
 	Info LockInfo
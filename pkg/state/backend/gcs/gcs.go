@@ -8,13 +8,16 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"net/http"
 	"path"
+	"strconv"
 	"strings"
 	"time"
 
 	"cloud.google.com/go/storage"
 	"github.com/davidthor/arcctl/pkg/state/backend"
 	"github.com/google/uuid"
+	"google.golang.org/api/googleapi"
 	"google.golang.org/api/iterator"
 	"google.golang.org/api/option"
 )
@@ -111,6 +114,62 @@ func (b *Backend) Write(ctx context.Context, statePath string, data io.Reader) e
 	return nil
 }
 
+func (b *Backend) ReadVersion(ctx context.Context, statePath string) (io.ReadCloser, string, error) {
+	objectPath := b.fullPath(statePath)
+
+	reader, err := b.client.Bucket(b.bucket).Object(objectPath).NewReader(ctx)
+	if err != nil {
+		if errors.Is(err, storage.ErrObjectNotExist) {
+			return nil, "", backend.ErrNotFound
+		}
+		return nil, "", fmt.Errorf("failed to read state from gs://%s/%s: %w", b.bucket, objectPath, err)
+	}
+
+	return reader, strconv.FormatInt(reader.Attrs.Generation, 10), nil
+}
+
+func (b *Backend) WriteIfVersion(ctx context.Context, statePath string, data io.Reader, version string) (string, error) {
+	objectPath := b.fullPath(statePath)
+
+	content, err := io.ReadAll(data)
+	if err != nil {
+		return "", fmt.Errorf("failed to read data: %w", err)
+	}
+
+	conds := storage.Conditions{DoesNotExist: true}
+	if version != "" {
+		generation, err := strconv.ParseInt(version, 10, 64)
+		if err != nil {
+			return "", fmt.Errorf("invalid object generation %q: %w", version, err)
+		}
+		conds = storage.Conditions{GenerationMatch: generation}
+	}
+
+	writer := b.client.Bucket(b.bucket).Object(objectPath).If(conds).NewWriter(ctx)
+	writer.ContentType = "application/json"
+
+	if _, err := writer.Write(content); err != nil {
+		writer.Close()
+		return "", b.conditionalWriteError(objectPath, err)
+	}
+
+	if err := writer.Close(); err != nil {
+		return "", b.conditionalWriteError(objectPath, err)
+	}
+
+	return strconv.FormatInt(writer.Attrs().Generation, 10), nil
+}
+
+// conditionalWriteError maps a failed generation precondition to
+// backend.ErrConflict.
+func (b *Backend) conditionalWriteError(objectPath string, err error) error {
+	var apiErr *googleapi.Error
+	if errors.As(err, &apiErr) && apiErr.Code == http.StatusPreconditionFailed {
+		return backend.ErrConflict
+	}
+	return fmt.Errorf("failed to write state to gs://%s/%s: %w", b.bucket, objectPath, err)
+}
+
 func (b *Backend) Delete(ctx context.Context, statePath string) error {
 	objectPath := b.fullPath(statePath)
 
@@ -174,8 +233,9 @@ func (b *Backend) Lock(ctx context.Context, statePath string, info backend.LockI
 	// Check for existing lock
 	existingLock, err := b.readLock(ctx, lockPath)
 	if err == nil {
-		// Check if lock is stale (older than 1 hour)
-		if time.Since(existingLock.Created) < time.Hour {
+		// Expired locks were left behind by a process that exited without
+		// unlocking, so they are taken over
+		if !existingLock.Expired(time.Now()) {
 			return nil, &backend.LockError{
 				Info: existingLock,
 				Err:  backend.ErrLocked,
@@ -2,8 +2,12 @@
 package local
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"os"
@@ -102,6 +106,99 @@ func (b *Backend) Write(ctx context.Context, path string, data io.Reader) error
 	return nil
 }
 
+// casMarkerTimeout bounds how long WriteIfVersion waits for another writer's
+// compare-and-swap on the same file. Markers older than this are considered
+// abandoned by a crashed process and are removed.
+const casMarkerTimeout = 30 * time.Second
+
+func (b *Backend) ReadVersion(ctx context.Context, path string) (io.ReadCloser, string, error) {
+	fullPath := b.fullPath(path)
+
+	data, err := os.ReadFile(fullPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, "", backend.ErrNotFound
+		}
+		return nil, "", fmt.Errorf("failed to read %s: %w", fullPath, err)
+	}
+
+	return io.NopCloser(bytes.NewReader(data)), contentVersion(data), nil
+}
+
+func (b *Backend) WriteIfVersion(ctx context.Context, path string, data io.Reader, version string) (string, error) {
+	fullPath := b.fullPath(path)
+
+	dir := filepath.Dir(fullPath)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create directory %s: %w", dir, err)
+	}
+
+	// Hold an exclusive marker file while comparing and renaming so that
+	// concurrent processes can't both pass the version check
+	release, err := acquireMarker(ctx, fullPath+".cas")
+	if err != nil {
+		return "", err
+	}
+	defer release()
+
+	current, err := os.ReadFile(fullPath)
+	switch {
+	case os.IsNotExist(err):
+		if version != "" {
+			return "", backend.ErrConflict
+		}
+	case err != nil:
+		return "", fmt.Errorf("failed to read %s: %w", fullPath, err)
+	case contentVersion(current) != version:
+		return "", backend.ErrConflict
+	}
+
+	content, err := io.ReadAll(data)
+	if err != nil {
+		return "", fmt.Errorf("failed to read data: %w", err)
+	}
+	if err := b.Write(ctx, path, bytes.NewReader(content)); err != nil {
+		return "", err
+	}
+	return contentVersion(content), nil
+}
+
+// acquireMarker creates path exclusively, waiting for any other holder to
+// release it, and returns a function that removes it.
+func acquireMarker(ctx context.Context, path string) (func(), error) {
+	deadline := time.Now().Add(casMarkerTimeout)
+	for {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err == nil {
+			f.Close()
+			return func() { os.Remove(path) }, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, fmt.Errorf("failed to create %s: %w", path, err)
+		}
+
+		if info, statErr := os.Stat(path); statErr == nil && time.Since(info.ModTime()) > casMarkerTimeout {
+			os.Remove(path)
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("timed out waiting for concurrent write to %s", path)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
+
+// contentVersion returns the version token for the given file contents.
+func contentVersion(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
 func (b *Backend) Delete(ctx context.Context, path string) error {
 	fullPath := b.fullPath(path)
 
@@ -162,7 +259,7 @@ func (b *Backend) Lock(ctx context.Context, path string, info backend.LockInfo)
 	lockPath := path + ".lock"
 
 	// Check if already locked
-	if existing, ok := b.locks[lockPath]; ok {
+	if existing, ok := b.locks[lockPath]; ok && !existing.info.Expired(time.Now()) {
 		return nil, &backend.LockError{
 			Info: existing.info,
 			Err:  backend.ErrLocked,
@@ -174,8 +271,9 @@ func (b *Backend) Lock(ctx context.Context, path string, info backend.LockInfo)
 	if data, err := os.ReadFile(lockFilePath); err == nil {
 		var existingInfo backend.LockInfo
 		if err := json.Unmarshal(data, &existingInfo); err == nil {
-			// Check if lock is stale (older than 1 hour)
-			if time.Since(existingInfo.Created) < time.Hour {
+			// Expired locks were left behind by a process that exited without
+			// unlocking, so they are taken over
+			if !existingInfo.Expired(time.Now()) {
 				return nil, &backend.LockError{
 					Info: existingInfo,
 					Err:  backend.ErrLocked,
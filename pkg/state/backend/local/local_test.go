@@ -3,10 +3,13 @@ package local
 import (
 	"bytes"
 	"context"
+	"errors"
+	"fmt"
 	"io"
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/davidthor/arcctl/pkg/state/backend"
 )
@@ -252,3 +255,111 @@ func TestBackend_AtomicWrite(t *testing.T) {
 		t.Errorf("expected %s, got %s", expected, data)
 	}
 }
+
+func TestBackend_WriteIfVersion(t *testing.T) {
+	tmpDir := t.TempDir()
+	b, _ := NewBackend(map[string]string{"path": tmpDir})
+
+	ctx := context.Background()
+	testPath := "test/state.json"
+
+	// Empty version creates the file only if it doesn't exist
+	if _, err := b.WriteIfVersion(ctx, testPath, bytes.NewReader([]byte(`{"serial": 1}`)), ""); err != nil {
+		t.Fatalf("create failed: %v", err)
+	}
+	_, err := b.WriteIfVersion(ctx, testPath, bytes.NewReader([]byte(`{"serial": 1}`)), "")
+	if !errors.Is(err, backend.ErrConflict) {
+		t.Fatalf("expected ErrConflict creating existing file, got %v", err)
+	}
+
+	reader, version, err := b.ReadVersion(ctx, testPath)
+	if err != nil {
+		t.Fatalf("read version failed: %v", err)
+	}
+	reader.Close()
+
+	// Matching version succeeds
+	written, err := b.WriteIfVersion(ctx, testPath, bytes.NewReader([]byte(`{"serial": 2}`)), version)
+	if err != nil {
+		t.Fatalf("conditional write failed: %v", err)
+	}
+	reader, current, _ := b.ReadVersion(ctx, testPath)
+	reader.Close()
+	if written == "" || written != current {
+		t.Errorf("expected the version written to be returned, got %q, want %q", written, current)
+	}
+
+	// The old version is now stale
+	_, err = b.WriteIfVersion(ctx, testPath, bytes.NewReader([]byte(`{"serial": 3}`)), version)
+	if !errors.Is(err, backend.ErrConflict) {
+		t.Fatalf("expected ErrConflict for stale version, got %v", err)
+	}
+
+	reader, _ = b.Read(ctx, testPath)
+	data, _ := io.ReadAll(reader)
+	reader.Close()
+	if string(data) != `{"serial": 2}` {
+		t.Errorf("expected stale write to be rejected, got %s", data)
+	}
+
+	// The marker file is removed after each write
+	if _, err := os.Stat(filepath.Join(tmpDir, testPath+".cas")); !os.IsNotExist(err) {
+		t.Errorf("expected marker file to be removed, got %v", err)
+	}
+}
+
+func TestBackend_WriteIfVersionConcurrent(t *testing.T) {
+	tmpDir := t.TempDir()
+	b, _ := NewBackend(map[string]string{"path": tmpDir})
+
+	ctx := context.Background()
+	testPath := "test/state.json"
+	_ = b.Write(ctx, testPath, bytes.NewReader([]byte(`{"serial": 1}`)))
+
+	reader, version, _ := b.ReadVersion(ctx, testPath)
+	reader.Close()
+
+	// Writers racing from the same version: exactly one may win
+	const writers = 8
+	errs := make(chan error, writers)
+	for i := 0; i < writers; i++ {
+		go func(i int) {
+			data := []byte(fmt.Sprintf(`{"serial": 2, "writer": %d}`, i))
+			_, err := b.WriteIfVersion(ctx, testPath, bytes.NewReader(data), version)
+			errs <- err
+		}(i)
+	}
+
+	succeeded := 0
+	for i := 0; i < writers; i++ {
+		err := <-errs
+		switch {
+		case err == nil:
+			succeeded++
+		case !errors.Is(err, backend.ErrConflict):
+			t.Errorf("unexpected error: %v", err)
+		}
+	}
+	if succeeded != 1 {
+		t.Errorf("expected exactly one successful write, got %d", succeeded)
+	}
+}
+
+func TestBackend_WriteIfVersionStaleMarker(t *testing.T) {
+	tmpDir := t.TempDir()
+	b, _ := NewBackend(map[string]string{"path": tmpDir})
+
+	ctx := context.Background()
+	testPath := "test/state.json"
+
+	// A marker left behind by a crashed process is ignored once it expires
+	marker := filepath.Join(tmpDir, testPath+".cas")
+	_ = os.MkdirAll(filepath.Dir(marker), 0755)
+	_ = os.WriteFile(marker, nil, 0644)
+	old := time.Now().Add(-2 * casMarkerTimeout)
+	_ = os.Chtimes(marker, old, old)
+
+	if _, err := b.WriteIfVersion(ctx, testPath, bytes.NewReader([]byte(`{}`)), ""); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+}
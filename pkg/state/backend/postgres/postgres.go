@@ -0,0 +1,394 @@
+// Package postgres implements a PostgreSQL state backend.
+package postgres
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/davidthor/arcctl/pkg/state/backend"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+func init() {
+	backend.Register("postgres", NewBackend)
+}
+
+const (
+	defaultSchema = "public"
+	defaultTable  = "cldctl_state"
+)
+
+// Backend implements the state backend interface for PostgreSQL.
+//
+// State documents are stored as rows in a single table keyed by path. Locks
+// are session-level advisory locks held on a dedicated connection, so they
+// are released by the server as soon as the holding process exits or loses
+// its connection. Lock metadata is stored alongside state as a "<path>.lock"
+// row so that it can be listed and inspected.
+type Backend struct {
+	pool  *pgxpool.Pool
+	table string // Quoted schema-qualified table name
+
+	schema            string
+	tableName         string
+	skipTableCreation bool
+
+	initMu      sync.Mutex
+	initialized bool
+}
+
+// NewBackend creates a new PostgreSQL backend.
+func NewBackend(cfg map[string]string) (backend.Backend, error) {
+	// An empty connection string falls back to the standard PG* environment
+	// variables (PGHOST, PGUSER, PGPASSWORD, PGDATABASE, ...)
+	poolCfg, err := pgxpool.ParseConfig(cfg["connection_string"])
+	if err != nil {
+		return nil, fmt.Errorf("invalid postgres connection_string: %w", err)
+	}
+
+	schema := cfg["schema"]
+	if schema == "" {
+		schema = defaultSchema
+	}
+	tableName := cfg["table"]
+	if tableName == "" {
+		tableName = defaultTable
+	}
+
+	// The pool connects lazily, so no connection is made until state is used
+	pool, err := pgxpool.NewWithConfig(context.Background(), poolCfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create postgres connection pool: %w", err)
+	}
+
+	return &Backend{
+		pool:              pool,
+		table:             pgx.Identifier{schema, tableName}.Sanitize(),
+		schema:            schema,
+		tableName:         tableName,
+		skipTableCreation: cfg["skip_table_creation"] == "true",
+	}, nil
+}
+
+func (b *Backend) Type() string {
+	return "postgres"
+}
+
+// Close closes the connection pool.
+func (b *Backend) Close() error {
+	b.pool.Close()
+	return nil
+}
+
+func (b *Backend) Read(ctx context.Context, path string) (io.ReadCloser, error) {
+	reader, _, err := b.ReadVersion(ctx, path)
+	return reader, err
+}
+
+func (b *Backend) Write(ctx context.Context, path string, data io.Reader) error {
+	if err := b.ensureTable(ctx); err != nil {
+		return err
+	}
+
+	content, err := io.ReadAll(data)
+	if err != nil {
+		return fmt.Errorf("failed to read data: %w", err)
+	}
+
+	_, err = b.pool.Exec(ctx, `
+		INSERT INTO `+b.table+` AS s (path, data, version, updated_at)
+		VALUES ($1, $2, 1, now())
+		ON CONFLICT (path) DO UPDATE
+		SET data = EXCLUDED.data, version = s.version + 1, updated_at = now()`,
+		path, content)
+	if err != nil {
+		return fmt.Errorf("failed to write state %s: %w", path, err)
+	}
+
+	return nil
+}
+
+func (b *Backend) ReadVersion(ctx context.Context, path string) (io.ReadCloser, string, error) {
+	if err := b.ensureTable(ctx); err != nil {
+		return nil, "", err
+	}
+
+	var data []byte
+	var version int64
+	err := b.pool.QueryRow(ctx, `SELECT data, version FROM `+b.table+` WHERE path = $1`, path).Scan(&data, &version)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, "", backend.ErrNotFound
+		}
+		return nil, "", fmt.Errorf("failed to read state %s: %w", path, err)
+	}
+
+	return io.NopCloser(bytes.NewReader(data)), strconv.FormatInt(version, 10), nil
+}
+
+func (b *Backend) WriteIfVersion(ctx context.Context, path string, data io.Reader, version string) (string, error) {
+	if err := b.ensureTable(ctx); err != nil {
+		return "", err
+	}
+
+	content, err := io.ReadAll(data)
+	if err != nil {
+		return "", fmt.Errorf("failed to read data: %w", err)
+	}
+
+	var query string
+	args := []interface{}{path, content}
+	if version == "" {
+		query = `INSERT INTO ` + b.table + ` (path, data, version, updated_at)
+			VALUES ($1, $2, 1, now())
+			ON CONFLICT (path) DO NOTHING
+			RETURNING version`
+	} else {
+		expected, err := strconv.ParseInt(version, 10, 64)
+		if err != nil {
+			return "", fmt.Errorf("invalid state version %q: %w", version, err)
+		}
+		query = `UPDATE ` + b.table + `
+			SET data = $2, version = version + 1, updated_at = now()
+			WHERE path = $1 AND version = $3
+			RETURNING version`
+		args = append(args, expected)
+	}
+
+	var written int64
+	if err := b.pool.QueryRow(ctx, query, args...).Scan(&written); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", backend.ErrConflict
+		}
+		return "", fmt.Errorf("failed to write state %s: %w", path, err)
+	}
+
+	return strconv.FormatInt(written, 10), nil
+}
+
+func (b *Backend) Delete(ctx context.Context, path string) error {
+	if err := b.ensureTable(ctx); err != nil {
+		return err
+	}
+
+	if _, err := b.pool.Exec(ctx, `DELETE FROM `+b.table+` WHERE path = $1`, path); err != nil {
+		return fmt.Errorf("failed to delete state %s: %w", path, err)
+	}
+
+	return nil
+}
+
+func (b *Backend) List(ctx context.Context, prefix string) ([]string, error) {
+	if err := b.ensureTable(ctx); err != nil {
+		return nil, err
+	}
+
+	rows, err := b.pool.Query(ctx, `SELECT path FROM `+b.table+` WHERE path LIKE $1 ESCAPE '\' ORDER BY path`,
+		escapeLike(prefix)+"%")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s: %w", prefix, err)
+	}
+
+	paths, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s: %w", prefix, err)
+	}
+
+	return paths, nil
+}
+
+func (b *Backend) Exists(ctx context.Context, path string) (bool, error) {
+	if err := b.ensureTable(ctx); err != nil {
+		return false, err
+	}
+
+	var exists bool
+	err := b.pool.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM `+b.table+` WHERE path = $1)`, path).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("failed to check existence: %w", err)
+	}
+
+	return exists, nil
+}
+
+func (b *Backend) Lock(ctx context.Context, path string, info backend.LockInfo) (backend.Lock, error) {
+	if err := b.ensureTable(ctx); err != nil {
+		return nil, err
+	}
+
+	// Advisory locks belong to the session that took them, so hold a
+	// connection for as long as the lock is held
+	conn, err := b.pool.Acquire(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to acquire connection: %w", err)
+	}
+
+	key := b.lockKey(path)
+	var acquired bool
+	if err := conn.QueryRow(ctx, `SELECT pg_try_advisory_lock($1)`, key).Scan(&acquired); err != nil {
+		conn.Release()
+		return nil, fmt.Errorf("failed to acquire lock: %w", err)
+	}
+
+	lockPath := path + ".lock"
+	if !acquired {
+		conn.Release()
+
+		existing, err := b.readLock(ctx, lockPath)
+		if err != nil {
+			existing = backend.LockInfo{Path: path}
+		}
+		return nil, &backend.LockError{
+			Info: existing,
+			Err:  backend.ErrLocked,
+		}
+	}
+
+	// Any lock metadata left here belonged to a session that has since ended
+	// without unlocking, so it is simply replaced
+	info.ID = uuid.New().String()
+	info.Path = path
+	info.Created = time.Now()
+
+	lockData, err := json.Marshal(info)
+	if err != nil {
+		b.releaseAdvisoryLock(conn, key)
+		return nil, fmt.Errorf("failed to marshal lock info: %w", err)
+	}
+
+	if err := b.Write(ctx, lockPath, bytes.NewReader(lockData)); err != nil {
+		b.releaseAdvisoryLock(conn, key)
+		return nil, fmt.Errorf("failed to create lock: %w", err)
+	}
+
+	return &postgresLock{
+		backend: b,
+		conn:    conn,
+		key:     key,
+		path:    lockPath,
+		info:    info,
+	}, nil
+}
+
+func (b *Backend) readLock(ctx context.Context, lockPath string) (backend.LockInfo, error) {
+	reader, err := b.Read(ctx, lockPath)
+	if err != nil {
+		return backend.LockInfo{}, err
+	}
+	defer reader.Close()
+
+	var info backend.LockInfo
+	if err := json.NewDecoder(reader).Decode(&info); err != nil {
+		return backend.LockInfo{}, err
+	}
+
+	return info, nil
+}
+
+// ForceUnlock releases the lock on path held by another process. The advisory
+// lock belongs to the holder's session, so that session is terminated, which
+// also fails any state writes it has in flight.
+func (b *Backend) ForceUnlock(ctx context.Context, path string) error {
+	if err := b.ensureTable(ctx); err != nil {
+		return err
+	}
+
+	// A bigint advisory lock key is reported in pg_locks split across classid
+	// (high 32 bits) and objid (low 32 bits), with objsubid 1
+	key := uint64(b.lockKey(path))
+	_, err := b.pool.Exec(ctx, `
+		SELECT pg_terminate_backend(pid) FROM pg_locks
+		WHERE locktype = 'advisory' AND granted AND database = (SELECT oid FROM pg_database WHERE datname = current_database())
+		AND classid::bigint = $1 AND objid::bigint = $2 AND objsubid = 1
+		AND pid <> pg_backend_pid()`,
+		int64(key>>32), int64(key&0xffffffff))
+	if err != nil {
+		return fmt.Errorf("failed to release lock on %s: %w", path, err)
+	}
+
+	return b.Delete(ctx, path+".lock")
+}
+
+func (b *Backend) releaseAdvisoryLock(conn *pgxpool.Conn, key int64) {
+	_, _ = conn.Exec(context.Background(), `SELECT pg_advisory_unlock($1)`, key)
+	conn.Release()
+}
+
+// lockKey derives the advisory lock key for a state path. The table name is
+// included so that backends sharing a database don't contend for each
+// other's locks.
+func (b *Backend) lockKey(path string) int64 {
+	h := fnv.New64a()
+	h.Write([]byte(b.schema + "." + b.tableName + "/" + path))
+	return int64(h.Sum64())
+}
+
+// ensureTable creates the state table on first use unless skip_table_creation is set.
+func (b *Backend) ensureTable(ctx context.Context) error {
+	b.initMu.Lock()
+	defer b.initMu.Unlock()
+
+	if b.initialized || b.skipTableCreation {
+		return nil
+	}
+
+	_, err := b.pool.Exec(ctx, `
+		CREATE SCHEMA IF NOT EXISTS `+pgx.Identifier{b.schema}.Sanitize()+`;
+		CREATE TABLE IF NOT EXISTS `+b.table+` (
+			path       TEXT PRIMARY KEY,
+			data       BYTEA NOT NULL,
+			version    BIGINT NOT NULL,
+			updated_at TIMESTAMPTZ NOT NULL
+		)`)
+	if err != nil {
+		return fmt.Errorf("failed to create state table %s: %w", b.table, err)
+	}
+
+	b.initialized = true
+	return nil
+}
+
+// escapeLike escapes the LIKE wildcards in s.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// postgresLock implements the Lock interface for PostgreSQL.
+type postgresLock struct {
+	backend *Backend
+	conn    *pgxpool.Conn
+	key     int64
+	path    string
+	info    backend.LockInfo
+}
+
+func (l *postgresLock) ID() string {
+	return l.info.ID
+}
+
+func (l *postgresLock) Unlock(ctx context.Context) error {
+	// Only remove the metadata if it is still ours; it may have been replaced
+	// after a force unlock
+	_, err := l.backend.pool.Exec(ctx, `DELETE FROM `+l.backend.table+` WHERE path = $1 AND convert_from(data, 'UTF8')::jsonb->>'id' = $2`,
+		l.path, l.info.ID)
+	l.backend.releaseAdvisoryLock(l.conn, l.key)
+	if err != nil {
+		return fmt.Errorf("failed to release lock: %w", err)
+	}
+	return nil
+}
+
+func (l *postgresLock) Info() backend.LockInfo {
+	return l.info
+}
@@ -18,6 +18,7 @@ import (
 	"github.com/aws/aws-sdk-go-v2/credentials"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
 	"github.com/google/uuid"
 )
 
@@ -127,6 +128,61 @@ func (b *Backend) Write(ctx context.Context, statePath string, data io.Reader) e
 	return nil
 }
 
+func (b *Backend) ReadVersion(ctx context.Context, statePath string) (io.ReadCloser, string, error) {
+	key := b.fullPath(statePath)
+
+	output, err := b.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: &b.bucket,
+		Key:    &key,
+	})
+	if err != nil {
+		var nsk *types.NoSuchKey
+		if ok := errors.As(err, &nsk); ok {
+			return nil, "", backend.ErrNotFound
+		}
+		return nil, "", fmt.Errorf("failed to read state from s3://%s/%s: %w", b.bucket, key, err)
+	}
+
+	return output.Body, aws.ToString(output.ETag), nil
+}
+
+func (b *Backend) WriteIfVersion(ctx context.Context, statePath string, data io.Reader, version string) (string, error) {
+	key := b.fullPath(statePath)
+
+	content, err := io.ReadAll(data)
+	if err != nil {
+		return "", fmt.Errorf("failed to read data: %w", err)
+	}
+
+	input := &s3.PutObjectInput{
+		Bucket:      &b.bucket,
+		Key:         &key,
+		Body:        bytes.NewReader(content),
+		ContentType: aws.String("application/json"),
+	}
+	if version == "" {
+		input.IfNoneMatch = aws.String("*")
+	} else {
+		input.IfMatch = aws.String(version)
+	}
+
+	output, err := b.client.PutObject(ctx, input)
+	if err != nil {
+		// S3 rejects failed preconditions with 412, and concurrent conditional
+		// writes to the same key with 409
+		var apiErr smithy.APIError
+		if errors.As(err, &apiErr) {
+			switch apiErr.ErrorCode() {
+			case "PreconditionFailed", "ConditionalRequestConflict":
+				return "", backend.ErrConflict
+			}
+		}
+		return "", fmt.Errorf("failed to write state to s3://%s/%s: %w", b.bucket, key, err)
+	}
+
+	return aws.ToString(output.ETag), nil
+}
+
 func (b *Backend) Delete(ctx context.Context, statePath string) error {
 	key := b.fullPath(statePath)
 
@@ -200,8 +256,9 @@ func (b *Backend) Lock(ctx context.Context, statePath string, info backend.LockI
 	// Check for existing lock
 	existingLock, err := b.readLock(ctx, lockKey)
 	if err == nil {
-		// Check if lock is stale (older than 1 hour)
-		if time.Since(existingLock.Created) < time.Hour {
+		// Expired locks were left behind by a process that exited without
+		// unlocking, so they are taken over
+		if !existingLock.Expired(time.Now()) {
 			return nil, &backend.LockError{
 				Info: existingLock,
 				Err:  backend.ErrLocked,
@@ -2,7 +2,11 @@ package s3
 
 import (
 	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"io"
 	"net/http"
 	"net/http/httptest"
@@ -75,6 +79,7 @@ func (m *mockS3Server) handleGet(w http.ResponseWriter, key string) {
 		return
 	}
 	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", objectETag(data))
 	_, _ = w.Write(data)
 }
 
@@ -84,10 +89,27 @@ func (m *mockS3Server) handlePut(w http.ResponseWriter, r *http.Request, key str
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+
+	// Conditional writes
+	existing, exists := m.objects[key]
+	ifMatch := r.Header.Get("If-Match")
+	ifNoneMatch := r.Header.Get("If-None-Match")
+	if (ifMatch != "" && (!exists || objectETag(existing) != ifMatch)) || (ifNoneMatch == "*" && exists) {
+		w.WriteHeader(http.StatusPreconditionFailed)
+		_, _ = w.Write([]byte(`<?xml version="1.0" encoding="UTF-8"?><Error><Code>PreconditionFailed</Code></Error>`))
+		return
+	}
+
 	m.objects[key] = data
+	w.Header().Set("ETag", objectETag(data))
 	w.WriteHeader(http.StatusOK)
 }
 
+func objectETag(data []byte) string {
+	sum := md5.Sum(data)
+	return `"` + hex.EncodeToString(sum[:]) + `"`
+}
+
 func (m *mockS3Server) handleDelete(w http.ResponseWriter, key string) {
 	delete(m.objects, key)
 	w.WriteHeader(http.StatusNoContent)
@@ -610,3 +632,63 @@ func TestLockInfo_WithExpiration(t *testing.T) {
 		t.Error("expected Expires to be set")
 	}
 }
+
+func TestBackend_WriteIfVersion(t *testing.T) {
+	mock := newMockS3Server()
+	server := httptest.NewServer(mock)
+	defer server.Close()
+
+	b, err := NewBackend(map[string]string{
+		"bucket":           "test-bucket",
+		"endpoint":         server.URL,
+		"access_key":       "test-key",
+		"secret_key":       "test-secret",
+		"force_path_style": "true",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ctx := context.Background()
+	testPath := "test/state.json"
+
+	// Empty version creates the object only if it doesn't exist
+	if _, err := b.WriteIfVersion(ctx, testPath, bytes.NewReader([]byte(`{"serial": 1}`)), ""); err != nil {
+		t.Fatalf("create failed: %v", err)
+	}
+	_, err = b.WriteIfVersion(ctx, testPath, bytes.NewReader([]byte(`{"serial": 1}`)), "")
+	if !errors.Is(err, backend.ErrConflict) {
+		t.Fatalf("expected ErrConflict creating existing object, got %v", err)
+	}
+
+	reader, version, err := b.ReadVersion(ctx, testPath)
+	if err != nil {
+		t.Fatalf("read version failed: %v", err)
+	}
+	reader.Close()
+	if version == "" {
+		t.Fatal("expected ETag version")
+	}
+
+	written, err := b.WriteIfVersion(ctx, testPath, bytes.NewReader([]byte(`{"serial": 2}`)), version)
+	if err != nil {
+		t.Fatalf("conditional write failed: %v", err)
+	}
+	reader, current, _ := b.ReadVersion(ctx, testPath)
+	reader.Close()
+	if written == "" || written != current {
+		t.Errorf("expected the version written to be returned, got %q, want %q", written, current)
+	}
+
+	_, err = b.WriteIfVersion(ctx, testPath, bytes.NewReader([]byte(`{"serial": 3}`)), version)
+	if !errors.Is(err, backend.ErrConflict) {
+		t.Fatalf("expected ErrConflict for stale version, got %v", err)
+	}
+
+	reader, _ = b.Read(ctx, testPath)
+	data, _ := io.ReadAll(reader)
+	reader.Close()
+	if string(data) != `{"serial": 2}` {
+		t.Errorf("expected stale write to be rejected, got %s", data)
+	}
+}
@@ -0,0 +1,33 @@
+package encryption
+
+// Config selects the key provider used to encrypt state.
+type Config struct {
+	// Key is a base64-encoded 256-bit key for the local key provider
+	Key string
+
+	// VaultTransit configures the Vault Transit key provider. It is used
+	// when VaultTransit.KeyName is set.
+	VaultTransit VaultTransitConfig
+}
+
+// NewFromConfig creates an envelope Encrypter from the configuration.
+// Returns nil if no key provider is configured, in which case the state
+// manager refuses to write sensitive state unless plain text is allowed.
+func NewFromConfig(cfg Config) (Encrypter, error) {
+	switch {
+	case cfg.VaultTransit.KeyName != "":
+		provider, err := NewVaultTransitKeyProvider(cfg.VaultTransit)
+		if err != nil {
+			return nil, err
+		}
+		return NewEnvelopeEncrypter(provider), nil
+	case cfg.Key != "":
+		provider, err := NewLocalKeyProvider(cfg.Key)
+		if err != nil {
+			return nil, err
+		}
+		return NewEnvelopeEncrypter(provider), nil
+	default:
+		return nil, nil
+	}
+}
@@ -0,0 +1,216 @@
+// Package encryption provides envelope encryption for sensitive state values.
+//
+// Each value is encrypted with AES-256-GCM using a data encryption key (DEK).
+// The DEK is wrapped by a KeyProvider (a local key or a KMS-compatible
+// service such as Vault Transit) and stored alongside the ciphertext, so the
+// key-encryption key never needs to leave the provider.
+package encryption
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// prefix marks a value as an encrypted envelope.
+const prefix = "cldctl:enc:v1:"
+
+// ErrNoEncrypter is returned when encrypted state is read without a
+// configured encryption key.
+var ErrNoEncrypter = errors.New("state contains encrypted values but no state encryption key is configured")
+
+// ErrKeyRequired is returned when sensitive state is written without a
+// configured encryption key.
+var ErrKeyRequired = errors.New("state contains sensitive values but no state encryption key is configured; set CLDCTL_STATE_ENCRYPTION_KEY or configure a Vault Transit key")
+
+// Encrypter encrypts and decrypts sensitive state values.
+type Encrypter interface {
+	// Encrypt returns an encrypted envelope for the plaintext.
+	Encrypt(ctx context.Context, plaintext []byte) ([]byte, error)
+
+	// Decrypt returns the plaintext of an envelope created by Encrypt.
+	Decrypt(ctx context.Context, ciphertext []byte) ([]byte, error)
+}
+
+// KeyProvider wraps and unwraps data encryption keys.
+type KeyProvider interface {
+	// Name returns the provider identifier (e.g., "local", "vault-transit")
+	Name() string
+
+	// WrapKey encrypts a data encryption key.
+	WrapKey(ctx context.Context, key []byte) ([]byte, error)
+
+	// UnwrapKey decrypts a data encryption key returned by WrapKey.
+	UnwrapKey(ctx context.Context, wrapped []byte) ([]byte, error)
+}
+
+// IsEncrypted returns true if data is an envelope created by an Encrypter.
+func IsEncrypted(data []byte) bool {
+	return bytes.HasPrefix(data, []byte(prefix))
+}
+
+// envelope is the serialized form of an encrypted value.
+type envelope struct {
+	Provider   string `json:"provider"`
+	WrappedKey []byte `json:"key"`
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"data"`
+}
+
+// envelopeEncrypter implements Encrypter using a KeyProvider.
+type envelopeEncrypter struct {
+	provider KeyProvider
+
+	mu         sync.Mutex
+	key        []byte            // DEK used for new values
+	wrappedKey []byte            // key, wrapped by the provider
+	keys       map[string][]byte // unwrapped DEKs keyed by wrapped key
+}
+
+// NewEnvelopeEncrypter creates an Encrypter that protects its data keys with
+// the given key provider. A single data key is generated per encrypter and
+// unwrapped keys are cached, so the provider is called once per key rather
+// than once per value.
+func NewEnvelopeEncrypter(provider KeyProvider) Encrypter {
+	return &envelopeEncrypter{
+		provider: provider,
+		keys:     make(map[string][]byte),
+	}
+}
+
+func (e *envelopeEncrypter) Encrypt(ctx context.Context, plaintext []byte) ([]byte, error) {
+	key, wrappedKey, err := e.dataKey(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce, ciphertext, err := seal(key, plaintext)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := json.Marshal(envelope{
+		Provider:   e.provider.Name(),
+		WrappedKey: wrappedKey,
+		Nonce:      nonce,
+		Ciphertext: ciphertext,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return []byte(prefix + base64.StdEncoding.EncodeToString(data)), nil
+}
+
+func (e *envelopeEncrypter) Decrypt(ctx context.Context, ciphertext []byte) ([]byte, error) {
+	if !IsEncrypted(ciphertext) {
+		return nil, fmt.Errorf("value is not encrypted")
+	}
+
+	data, err := base64.StdEncoding.DecodeString(string(ciphertext[len(prefix):]))
+	if err != nil {
+		return nil, fmt.Errorf("invalid encrypted value: %w", err)
+	}
+
+	var env envelope
+	if err := json.Unmarshal(data, &env); err != nil {
+		return nil, fmt.Errorf("invalid encrypted value: %w", err)
+	}
+	if env.Provider != e.provider.Name() {
+		return nil, fmt.Errorf("value was encrypted with key provider %q, but %q is configured", env.Provider, e.provider.Name())
+	}
+
+	key, err := e.unwrap(ctx, env.WrappedKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return open(key, env.Nonce, env.Ciphertext)
+}
+
+// dataKey returns the DEK for new values, generating and wrapping it on first use.
+func (e *envelopeEncrypter) dataKey(ctx context.Context) ([]byte, []byte, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.key != nil {
+		return e.key, e.wrappedKey, nil
+	}
+
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, nil, fmt.Errorf("failed to generate data key: %w", err)
+	}
+
+	wrappedKey, err := e.provider.WrapKey(ctx, key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to wrap data key: %w", err)
+	}
+
+	e.key = key
+	e.wrappedKey = wrappedKey
+	e.keys[string(wrappedKey)] = key
+	return key, wrappedKey, nil
+}
+
+// unwrap returns the DEK for a wrapped key, caching the result.
+func (e *envelopeEncrypter) unwrap(ctx context.Context, wrappedKey []byte) ([]byte, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if key, ok := e.keys[string(wrappedKey)]; ok {
+		return key, nil
+	}
+
+	key, err := e.provider.UnwrapKey(ctx, wrappedKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unwrap data key: %w", err)
+	}
+
+	e.keys[string(wrappedKey)] = key
+	return key, nil
+}
+
+// seal encrypts plaintext with AES-GCM and returns the nonce and ciphertext.
+func seal(key, plaintext []byte) ([]byte, []byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	return nonce, gcm.Seal(nil, nonce, plaintext, nil), nil
+}
+
+// open decrypts ciphertext created by seal.
+func open(key, nonce, ciphertext []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt value: %w", err)
+	}
+	return plaintext, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid encryption key: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
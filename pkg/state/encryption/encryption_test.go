@@ -0,0 +1,142 @@
+package encryption
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const testKey = "MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY=" // 32 bytes
+
+func newTestEncrypter(t *testing.T) Encrypter {
+	t.Helper()
+	provider, err := NewLocalKeyProvider(testKey)
+	if err != nil {
+		t.Fatalf("NewLocalKeyProvider failed: %v", err)
+	}
+	return NewEnvelopeEncrypter(provider)
+}
+
+func TestEnvelopeEncrypter_RoundTrip(t *testing.T) {
+	ctx := context.Background()
+	enc := newTestEncrypter(t)
+
+	ciphertext, err := enc.Encrypt(ctx, []byte("s3cret"))
+	if err != nil {
+		t.Fatalf("Encrypt failed: %v", err)
+	}
+	if !IsEncrypted(ciphertext) {
+		t.Errorf("expected envelope prefix, got %q", ciphertext)
+	}
+	if strings.Contains(string(ciphertext), "s3cret") {
+		t.Error("ciphertext contains plaintext")
+	}
+
+	plaintext, err := enc.Decrypt(ctx, ciphertext)
+	if err != nil {
+		t.Fatalf("Decrypt failed: %v", err)
+	}
+	if string(plaintext) != "s3cret" {
+		t.Errorf("got %q, want %q", plaintext, "s3cret")
+	}
+
+	// A new encrypter with the same key can decrypt values from another
+	plaintext, err = newTestEncrypter(t).Decrypt(ctx, ciphertext)
+	if err != nil {
+		t.Fatalf("Decrypt with new encrypter failed: %v", err)
+	}
+	if string(plaintext) != "s3cret" {
+		t.Errorf("got %q, want %q", plaintext, "s3cret")
+	}
+}
+
+func TestEnvelopeEncrypter_WrongKey(t *testing.T) {
+	ctx := context.Background()
+
+	ciphertext, err := newTestEncrypter(t).Encrypt(ctx, []byte("s3cret"))
+	if err != nil {
+		t.Fatalf("Encrypt failed: %v", err)
+	}
+
+	other, err := NewLocalKeyProvider(base64.StdEncoding.EncodeToString([]byte("fedcba9876543210fedcba9876543210")))
+	if err != nil {
+		t.Fatalf("NewLocalKeyProvider failed: %v", err)
+	}
+	if _, err := NewEnvelopeEncrypter(other).Decrypt(ctx, ciphertext); err == nil {
+		t.Error("expected error decrypting with a different key")
+	}
+}
+
+func TestNewLocalKeyProvider_InvalidKey(t *testing.T) {
+	if _, err := NewLocalKeyProvider("not base64!"); err == nil {
+		t.Error("expected error for non-base64 key")
+	}
+	if _, err := NewLocalKeyProvider(base64.StdEncoding.EncodeToString([]byte("short"))); err == nil {
+		t.Error("expected error for short key")
+	}
+}
+
+func TestVaultTransitKeyProvider(t *testing.T) {
+	// Fake transit engine that "encrypts" by prefixing the plaintext
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "test-token" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		var body map[string]string
+		_ = json.NewDecoder(r.Body).Decode(&body)
+
+		switch r.URL.Path {
+		case "/v1/transit/encrypt/state":
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"data": map[string]string{"ciphertext": "vault:v1:" + body["plaintext"]},
+			})
+		case "/v1/transit/decrypt/state":
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"data": map[string]string{"plaintext": strings.TrimPrefix(body["ciphertext"], "vault:v1:")},
+			})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	enc, err := NewFromConfig(Config{
+		VaultTransit: VaultTransitConfig{
+			Address: server.URL,
+			Token:   "test-token",
+			KeyName: "state",
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewFromConfig failed: %v", err)
+	}
+
+	ctx := context.Background()
+	ciphertext, err := enc.Encrypt(ctx, []byte("s3cret"))
+	if err != nil {
+		t.Fatalf("Encrypt failed: %v", err)
+	}
+	plaintext, err := enc.Decrypt(ctx, ciphertext)
+	if err != nil {
+		t.Fatalf("Decrypt failed: %v", err)
+	}
+	if string(plaintext) != "s3cret" {
+		t.Errorf("got %q, want %q", plaintext, "s3cret")
+	}
+}
+
+func TestNewFromConfig_Unconfigured(t *testing.T) {
+	enc, err := NewFromConfig(Config{})
+	if err != nil {
+		t.Fatalf("NewFromConfig failed: %v", err)
+	}
+	if enc != nil {
+		t.Error("expected nil encrypter when no key provider is configured")
+	}
+}
@@ -0,0 +1,48 @@
+package encryption
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+)
+
+// LocalKeyProvider wraps data keys with a locally held 256-bit key.
+type LocalKeyProvider struct {
+	key []byte
+}
+
+// NewLocalKeyProvider creates a key provider from a base64-encoded 256-bit key
+// (e.g., the output of `openssl rand -base64 32`).
+func NewLocalKeyProvider(encodedKey string) (*LocalKeyProvider, error) {
+	key, err := base64.StdEncoding.DecodeString(encodedKey)
+	if err != nil {
+		return nil, fmt.Errorf("state encryption key must be base64 encoded: %w", err)
+	}
+	if len(key) != 32 {
+		return nil, fmt.Errorf("state encryption key must be 32 bytes, got %d", len(key))
+	}
+	return &LocalKeyProvider{key: key}, nil
+}
+
+func (p *LocalKeyProvider) Name() string {
+	return "local"
+}
+
+func (p *LocalKeyProvider) WrapKey(ctx context.Context, key []byte) ([]byte, error) {
+	nonce, ciphertext, err := seal(p.key, key)
+	if err != nil {
+		return nil, err
+	}
+	return append(nonce, ciphertext...), nil
+}
+
+func (p *LocalKeyProvider) UnwrapKey(ctx context.Context, wrapped []byte) ([]byte, error) {
+	gcm, err := newGCM(p.key)
+	if err != nil {
+		return nil, err
+	}
+	if len(wrapped) < gcm.NonceSize() {
+		return nil, fmt.Errorf("invalid wrapped key")
+	}
+	return open(p.key, wrapped[:gcm.NonceSize()], wrapped[gcm.NonceSize():])
+}
@@ -0,0 +1,152 @@
+package encryption
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// VaultTransitConfig configures the Vault Transit key provider.
+type VaultTransitConfig struct {
+	// Address is the Vault server address (default: VAULT_ADDR)
+	Address string
+
+	// Token is the authentication token (default: VAULT_TOKEN)
+	Token string
+
+	// Namespace is the Vault namespace (Enterprise feature)
+	Namespace string
+
+	// MountPath is the transit secrets engine mount path (default: "transit")
+	MountPath string
+
+	// KeyName is the name of the transit key used to wrap data keys
+	KeyName string
+}
+
+// VaultTransitKeyProvider wraps data keys with a HashiCorp Vault Transit key.
+type VaultTransitKeyProvider struct {
+	address   string
+	token     string
+	namespace string
+	mountPath string
+	keyName   string
+	client    *http.Client
+}
+
+// NewVaultTransitKeyProvider creates a new Vault Transit key provider.
+func NewVaultTransitKeyProvider(cfg VaultTransitConfig) (*VaultTransitKeyProvider, error) {
+	if cfg.KeyName == "" {
+		return nil, fmt.Errorf("vault transit key name required")
+	}
+
+	address := cfg.Address
+	if address == "" {
+		address = os.Getenv("VAULT_ADDR")
+	}
+	if address == "" {
+		return nil, fmt.Errorf("vault address required")
+	}
+
+	token := cfg.Token
+	if token == "" {
+		token = os.Getenv("VAULT_TOKEN")
+	}
+	if token == "" {
+		return nil, fmt.Errorf("vault token required")
+	}
+
+	namespace := cfg.Namespace
+	if namespace == "" {
+		namespace = os.Getenv("VAULT_NAMESPACE")
+	}
+
+	mountPath := cfg.MountPath
+	if mountPath == "" {
+		mountPath = "transit"
+	}
+
+	return &VaultTransitKeyProvider{
+		address:   strings.TrimSuffix(address, "/"),
+		token:     token,
+		namespace: namespace,
+		mountPath: mountPath,
+		keyName:   cfg.KeyName,
+		client: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+	}, nil
+}
+
+func (p *VaultTransitKeyProvider) Name() string {
+	return "vault-transit"
+}
+
+func (p *VaultTransitKeyProvider) WrapKey(ctx context.Context, key []byte) ([]byte, error) {
+	var result struct {
+		Data struct {
+			Ciphertext string `json:"ciphertext"`
+		} `json:"data"`
+	}
+	body := map[string]string{"plaintext": base64.StdEncoding.EncodeToString(key)}
+	if err := p.call(ctx, "encrypt", body, &result); err != nil {
+		return nil, err
+	}
+	return []byte(result.Data.Ciphertext), nil
+}
+
+func (p *VaultTransitKeyProvider) UnwrapKey(ctx context.Context, wrapped []byte) ([]byte, error) {
+	var result struct {
+		Data struct {
+			Plaintext string `json:"plaintext"`
+		} `json:"data"`
+	}
+	body := map[string]string{"ciphertext": string(wrapped)}
+	if err := p.call(ctx, "decrypt", body, &result); err != nil {
+		return nil, err
+	}
+	return base64.StdEncoding.DecodeString(result.Data.Plaintext)
+}
+
+// call invokes a transit operation (encrypt or decrypt) for the configured key.
+func (p *VaultTransitKeyProvider) call(ctx context.Context, operation string, body interface{}, result interface{}) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	url := fmt.Sprintf("%s/v1/%s/%s/%s", p.address, p.mountPath, operation, p.keyName)
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("X-Vault-Token", p.token)
+	req.Header.Set("Content-Type", "application/json")
+	if p.namespace != "" {
+		req.Header.Set("X-Vault-Namespace", p.namespace)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("vault request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("vault error: %s", string(respBody))
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+		return fmt.Errorf("failed to parse vault response: %w", err)
+	}
+	return nil
+}
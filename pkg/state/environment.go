@@ -0,0 +1,740 @@
+package state
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/davidthor/arcctl/pkg/state/backend"
+	"github.com/davidthor/arcctl/pkg/state/types"
+)
+
+// Environment persistence
+//
+// An environment's components and resources are each stored in their own
+// document, and the environment document holds an index of them. Saving an
+// environment writes the environment document and only those component and
+// resource documents that changed since the manager last read or wrote them,
+// so that a status change doesn't rewrite every resource's IaC state.
+//
+// A component's document also summarizes its resources, everything but their
+// IaC state, so reading an environment reads one document per component. A
+// resource's document, and with it its IaC state, is read when the IaC state
+// is first used (see types.ResourceState.LoadIaCState).
+//
+// Component and resource documents carry their own serial and are written
+// conditionally, like the environment document, so that concurrent writers
+// can't overwrite each other's resource state. The condition is the backend's
+// version token for the revision last read or written, which for a resource
+// is recorded in its summary, so documents aren't read again before they are
+// written. They are written before the environment document, and documents
+// that are no longer indexed are removed only once it has been written.
+
+// environmentDocument is the stored form of an environment.
+type environmentDocument struct {
+	*types.EnvironmentState
+
+	// Components holds the components of environments stored before the
+	// index, which kept them inline; upgrading the document indexes them. It
+	// shadows EnvironmentState.Components.
+	Components map[string]*types.ComponentState `json:"components,omitempty"`
+
+	// Index lists the keys of each component's resources by component name
+	Index map[string][]string `json:"index,omitempty"`
+}
+
+// componentDocument is the stored form of a component. Its resources are
+// stored in their own documents.
+type componentDocument struct {
+	*types.ComponentState
+
+	// Resources holds the resources of components stored with them inline.
+	// It shadows ComponentState.Resources.
+	Resources map[string]*types.ResourceState `json:"resources,omitempty"`
+
+	// Summaries holds the component's resources without their IaC state.
+	// Components stored before resources were summarized don't have it, and
+	// their resources are read from their documents.
+	Summaries map[string]*resourceSummary `json:"summaries,omitempty"`
+
+	// Serial is incremented each time the document is written
+	Serial int64 `json:"serial,omitempty"`
+}
+
+// resourceSummary is a resource as summarized in its component's document.
+type resourceSummary struct {
+	*types.ResourceState
+
+	// HasIaCState is set if the resource's document holds IaC state
+	HasIaCState bool `json:"has_iac_state,omitempty"`
+
+	// Serial and Version identify the revision of the resource's document
+	// that is summarized
+	Serial  int64  `json:"serial,omitempty"`
+	Version string `json:"version,omitempty"`
+}
+
+// resourceDocument is the stored form of a resource.
+type resourceDocument struct {
+	*types.ResourceState
+
+	// Serial is incremented each time the document is written
+	Serial int64 `json:"serial,omitempty"`
+}
+
+// storedIndex is the part of a stored environment document that saving it
+// again needs.
+type storedIndex struct {
+	FormatVersion int                 `json:"format_version"`
+	Index         map[string][]string `json:"index"`
+}
+
+// indexed reports whether the index lists the resource key of a component.
+func (s *storedIndex) indexed(component, key string) bool {
+	keys := s.Index[component]
+	i := sort.SearchStrings(keys, key)
+	return i < len(keys) && keys[i] == key
+}
+
+// cachedDocument is what the manager knows of a component or resource
+// document it last read or wrote: a digest of the unsealed document, since
+// sealing isn't deterministic, the serial it was stored with, and the
+// backend's version token for the revision.
+type cachedDocument struct {
+	digest  [sha256.Size]byte
+	serial  int64
+	version string
+}
+
+// documentCache records the component and resource documents the manager
+// last read or wrote.
+type documentCache struct {
+	mu   sync.Mutex
+	docs map[string]cachedDocument
+}
+
+func (c *documentCache) lookup(p string) (cachedDocument, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	doc, ok := c.docs[p]
+	return doc, ok
+}
+
+func (c *documentCache) store(p string, doc cachedDocument) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.docs == nil {
+		c.docs = make(map[string]cachedDocument)
+	}
+	c.docs[p] = doc
+}
+
+// forget drops the documents at p and under it.
+func (c *documentCache) forget(p string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for cached := range c.docs {
+		if cached == p || strings.HasPrefix(cached, p+"/") {
+			delete(c.docs, cached)
+		}
+	}
+}
+
+func documentDigest(doc interface{}) ([sha256.Size]byte, error) {
+	content, err := json.Marshal(doc)
+	if err != nil {
+		return [sha256.Size]byte{}, fmt.Errorf("failed to encode JSON: %w", err)
+	}
+	return sha256.Sum256(content), nil
+}
+
+// resourceDigest digests a resource's summary and its IaC state separately,
+// so that the digest of a resource read without its IaC state can be
+// completed once the state is read.
+func resourceDigest(res *types.ResourceState) ([sha256.Size]byte, error) {
+	summary, err := summaryContent(res)
+	if err != nil {
+		return [sha256.Size]byte{}, err
+	}
+	return digestWithIaCState(summary, res.IaCState), nil
+}
+
+func summaryContent(res *types.ResourceState) ([]byte, error) {
+	summary := *res
+	summary.IaCState = nil
+	content, err := json.Marshal(&summary)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode JSON: %w", err)
+	}
+	return content, nil
+}
+
+func digestWithIaCState(summary, iacState []byte) [sha256.Size]byte {
+	h := sha256.New()
+	h.Write(summary)
+	h.Write(iacState)
+	var digest [sha256.Size]byte
+	copy(digest[:], h.Sum(nil))
+	return digest
+}
+
+// loadAttempts is how many times an environment is read when a document it
+// indexes is missing, since a concurrent save may have removed the document
+// after replacing the index.
+const loadAttempts = 3
+
+// missingDocumentError is returned when an environment's index lists a
+// component or resource whose document doesn't exist.
+type missingDocumentError struct {
+	environment string
+	path        string
+	serial      int64
+}
+
+func (e *missingDocumentError) Error() string {
+	return fmt.Sprintf("environment %s lists %s in its index but the document doesn't exist; the state is incomplete and should be restored from a backup (see `cldctl state import`)",
+		e.environment, e.path)
+}
+
+// loadEnvironment reads an environment along with its indexed components and
+// resources. The resources are read from their components' summaries, and
+// their documents are read when their IaC state is first used; callers that
+// need a single component read it with GetComponent.
+func (m *manager) loadEnvironment(ctx context.Context, datacenter, name string) (*types.EnvironmentState, error) {
+	for attempt := 1; ; attempt++ {
+		state, err := m.readEnvironment(ctx, datacenter, name)
+		var missing *missingDocumentError
+		if !errors.As(err, &missing) || attempt == loadAttempts {
+			return state, err
+		}
+
+		// Read again only if the environment was saved since
+		current, _, err := readStateVersion(ctx, m.backend, environmentPath(datacenter, name))
+		if err != nil {
+			return nil, err
+		}
+		if current.Serial == missing.serial {
+			return nil, missing
+		}
+	}
+}
+
+// readEnvironment reads an environment and the component documents its
+// index lists.
+func (m *manager) readEnvironment(ctx context.Context, datacenter, name string) (*types.EnvironmentState, error) {
+	doc, err := readJSON[environmentDocument](ctx, m.backend, environmentPath(datacenter, name))
+	if err != nil {
+		return nil, err
+	}
+	state := doc.EnvironmentState
+	if state == nil {
+		state = &types.EnvironmentState{}
+	}
+	missing := func(p string) error {
+		return &missingDocumentError{environment: name, path: p, serial: state.Serial}
+	}
+
+	if len(doc.Index) > 0 {
+		state.Components = make(map[string]*types.ComponentState, len(doc.Index))
+	}
+	reads := make(map[string]*componentRead, len(doc.Index))
+	for compName, keys := range doc.Index {
+		if inline := doc.Components[compName]; inline != nil {
+			// Not cached, so that the next save moves it to its own documents
+			state.Components[compName] = inline
+			continue
+		}
+
+		read, err := m.readComponent(ctx, datacenter, name, compName, keys, missing)
+		if err != nil {
+			return nil, err
+		}
+		state.Components[compName] = read.state
+		reads[compName] = read
+	}
+
+	if err := m.openEnvironment(ctx, state); err != nil {
+		return nil, err
+	}
+
+	// Remember what was read so that saving the environment again only
+	// writes the documents that change, and only if they haven't changed
+	// since
+	for compName, read := range reads {
+		if err := m.cacheComponent(ctx, datacenter, name, compName, read); err != nil {
+			return nil, err
+		}
+	}
+
+	return state, nil
+}
+
+// componentRead is a component as read from its documents, along with the
+// revisions of the documents read.
+type componentRead struct {
+	state *types.ComponentState
+
+	// revisions holds the serial and version of the component's document
+	// and those of its resources, by path
+	revisions map[string]cachedDocument
+
+	// summarized lists the paths of the resources read from their summary
+	// whose IaC state is yet to be read
+	summarized map[string]bool
+
+	// inline is set for a component stored with its resources inline, and
+	// legacy for one stored before its resources were summarized. Neither
+	// is cached as is, so that the next save stores them in the current
+	// form.
+	inline, legacy bool
+}
+
+// readComponent reads a component's document and its resources with the
+// given keys: those the document summarizes from their summary, and others
+// from their documents. missing returns the error for a resource document
+// that doesn't exist, or nil to leave the resource out.
+func (m *manager) readComponent(ctx context.Context, datacenter, env, name string, keys []string, missing func(p string) error) (*componentRead, error) {
+	p := componentPath(datacenter, env, name)
+	doc, version, err := readJSONVersion[componentDocument](ctx, m.backend, p)
+	if errors.Is(err, backend.ErrNotFound) {
+		if err := missing(p); err != nil {
+			return nil, err
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	state := doc.ComponentState
+	if state == nil {
+		state = &types.ComponentState{}
+	}
+	read := &componentRead{
+		state:      state,
+		revisions:  map[string]cachedDocument{p: {serial: doc.Serial, version: version}},
+		summarized: make(map[string]bool),
+		inline:     doc.Resources != nil,
+		legacy:     doc.Summaries == nil && len(keys) > 0,
+	}
+	state.Resources = doc.Resources
+	if state.Resources == nil {
+		state.Resources = make(map[string]*types.ResourceState, len(keys))
+	}
+
+	for _, key := range keys {
+		if state.Resources[key] != nil {
+			continue // Stored inline
+		}
+		rp := resourcePath(datacenter, env, name, key)
+		if summary := doc.Summaries[key]; summary != nil && summary.ResourceState != nil {
+			state.Resources[key] = summary.ResourceState
+			read.revisions[rp] = cachedDocument{serial: summary.Serial, version: summary.Version}
+			read.summarized[rp] = summary.HasIaCState
+			continue
+		}
+
+		res, version, err := readJSONVersion[resourceDocument](ctx, m.backend, rp)
+		if errors.Is(err, backend.ErrNotFound) {
+			if err := missing(rp); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if res.ResourceState == nil {
+			res.ResourceState = &types.ResourceState{}
+		}
+		state.Resources[key] = res.ResourceState
+		read.revisions[rp] = cachedDocument{serial: res.Serial, version: version}
+	}
+	return read, nil
+}
+
+// storeEnvironment saves an environment, writing the component and resource
+// documents that changed, then the environment document with the new index,
+// and finally removing the documents of components and resources that are no
+// longer in the environment.
+func (m *manager) storeEnvironment(ctx context.Context, datacenter string, state *types.EnvironmentState) error {
+	p := environmentPath(datacenter, state.Name)
+
+	// Check the stored format before anything is written, since the
+	// component and resource documents are written first
+	var stored storedIndex
+	data, err := readFile(ctx, m.backend, p)
+	switch {
+	case errors.Is(err, backend.ErrNotFound):
+	case err != nil:
+		return err
+	default:
+		if err := json.Unmarshal(data, &stored); err != nil {
+			return fmt.Errorf("failed to decode JSON: %w", err)
+		}
+		if err := checkFormat(stored.FormatVersion, p); err != nil {
+			return err
+		}
+	}
+
+	index := make(map[string][]string, len(state.Components))
+	for compName, comp := range state.Components {
+		if comp == nil {
+			continue
+		}
+		keys, err := m.writeComponent(ctx, datacenter, state.Name, compName, comp, &stored)
+		if err != nil {
+			return err
+		}
+		index[compName] = keys
+	}
+
+	record := *state
+	record.Components = nil
+	sealed, err := m.sealEnvironment(ctx, &record)
+	if err != nil {
+		return err
+	}
+	envRecord := *sealed
+	doc := &environmentDocument{EnvironmentState: &envRecord}
+	if len(index) > 0 {
+		doc.Index = index
+	}
+	if err := writeVersionedJSON(ctx, m.backend, p, "environment "+state.Name, doc, &envRecord.Serial, &envRecord.Lineage); err != nil {
+		return err
+	}
+	state.Serial = envRecord.Serial
+	state.Lineage = envRecord.Lineage
+
+	for compName, keys := range stored.Index {
+		kept, ok := index[compName]
+		if !ok {
+			if err := m.removeComponent(ctx, datacenter, state.Name, compName); err != nil {
+				return err
+			}
+			continue
+		}
+		for _, key := range keys {
+			if i := sort.SearchStrings(kept, key); i < len(kept) && kept[i] == key {
+				continue
+			}
+			rp := resourcePath(datacenter, state.Name, compName, key)
+			if err := m.backend.Delete(ctx, rp); err != nil {
+				return fmt.Errorf("failed to delete %s: %w", rp, err)
+			}
+			m.documents.forget(rp)
+		}
+	}
+
+	return nil
+}
+
+// writeComponent writes a component's document and its resources' documents
+// that changed since they were last read or written, and returns the sorted
+// keys of its resources. stored is the index of the environment as stored,
+// which tells documents another process saved from ones a failed save left
+// behind.
+func (m *manager) writeComponent(ctx context.Context, datacenter, env, name string, comp *types.ComponentState, stored *storedIndex) ([]string, error) {
+	keys := make([]string, 0, len(comp.Resources))
+	for key, res := range comp.Resources {
+		if res == nil {
+			continue
+		}
+		keys = append(keys, key)
+		if err := m.writeResource(ctx, datacenter, env, name, key, res, stored.indexed(name, key)); err != nil {
+			return nil, err
+		}
+	}
+	sort.Strings(keys)
+
+	// The summaries record the resources' revisions, so the document
+	// changes whenever one of its resources is written
+	p := componentPath(datacenter, env, name)
+	doc := m.componentDocument(datacenter, env, name, comp)
+	digest, err := documentDigest(doc)
+	if err != nil {
+		return nil, err
+	}
+	if cached, ok := m.documents.lookup(p); ok && cached.digest == digest {
+		return keys, nil
+	}
+	record := *comp
+	record.Resources = nil
+	sealed, err := m.sealComponent(ctx, &record)
+	if err != nil {
+		return nil, err
+	}
+	summaries, err := m.sealSummaries(ctx, doc.Summaries)
+	if err != nil {
+		return nil, err
+	}
+	_, indexed := stored.Index[name]
+	sealedDoc := &componentDocument{ComponentState: sealed, Summaries: summaries}
+	version, err := m.writeDocumentIfUnchanged(ctx, p, "component "+name, sealedDoc, &sealedDoc.Serial, indexed)
+	if err != nil {
+		return nil, err
+	}
+	m.documents.store(p, cachedDocument{digest: digest, serial: sealedDoc.Serial, version: version})
+
+	return keys, nil
+}
+
+// sealSummaries returns a copy of summaries with the resources sealed.
+func (m *manager) sealSummaries(ctx context.Context, summaries map[string]*resourceSummary) (map[string]*resourceSummary, error) {
+	if m.plaintext() || summaries == nil {
+		return summaries, nil
+	}
+	sealed := make(map[string]*resourceSummary, len(summaries))
+	for key, summary := range summaries {
+		res, err := m.sealResource(ctx, summary.ResourceState)
+		if err != nil {
+			return nil, err
+		}
+		sealedSummary := *summary
+		sealedSummary.ResourceState = res
+		sealed[key] = &sealedSummary
+	}
+	return sealed, nil
+}
+
+// writeResource writes a resource's document if it changed since it was last
+// read or written.
+func (m *manager) writeResource(ctx context.Context, datacenter, env, component, key string, res *types.ResourceState, indexed bool) error {
+	p := resourcePath(datacenter, env, component, key)
+	digest, err := resourceDigest(res)
+	if err != nil {
+		return err
+	}
+	if cached, ok := m.documents.lookup(p); ok && cached.digest == digest {
+		return nil
+	}
+
+	// The document holds the IaC state, so a resource read without it reads
+	// it before being written, and may turn out unchanged
+	if res.IaCStateDeferred() {
+		if _, err := res.LoadIaCState(); err != nil {
+			return err
+		}
+		if digest, err = resourceDigest(res); err != nil {
+			return err
+		}
+		if cached, ok := m.documents.lookup(p); ok && cached.digest == digest {
+			return nil
+		}
+	}
+	return m.storeResource(ctx, datacenter, env, component, key, res, digest, indexed)
+}
+
+// storeResource writes a resource's document whose digest is given.
+func (m *manager) storeResource(ctx context.Context, datacenter, env, component, key string, res *types.ResourceState, digest [sha256.Size]byte, indexed bool) error {
+	p := resourcePath(datacenter, env, component, key)
+	sealed, err := m.sealResource(ctx, res)
+	if err != nil {
+		return err
+	}
+	doc := &resourceDocument{ResourceState: sealed}
+	version, err := m.writeDocumentIfUnchanged(ctx, p, "resource "+component+"/"+key, doc, &doc.Serial, indexed)
+	if err != nil {
+		return err
+	}
+	m.documents.store(p, cachedDocument{digest: digest, serial: doc.Serial, version: version})
+	return nil
+}
+
+// writeDocumentIfUnchanged writes a component or resource document, refusing
+// to overwrite changes saved since the manager last read or wrote it, and
+// returns the version token of the revision written. serial points into doc
+// and is set to the serial written.
+//
+// A document the manager knows of is written on the condition that it is
+// still at the revision last read or written, without reading it again. If
+// it isn't, as is also the case for a document copied from another backend,
+// whose version tokens differ, it is read and written only if its serial is
+// still the one last read or written.
+//
+// A document the manager hasn't read is written only if it doesn't exist or
+// isn't indexed, since an indexed document was saved by another process. One
+// that exists without being indexed was left behind by a save that failed
+// before writing its environment, and is replaced.
+func (m *manager) writeDocumentIfUnchanged(ctx context.Context, p, desc string, doc interface{}, serial *int64, indexed bool) (string, error) {
+	cached, known := m.documents.lookup(p)
+	*serial = cached.serial + 1
+	written, err := writeIfVersion(ctx, m.backend, p, desc, doc, cached.version)
+	if !errors.Is(err, ErrStateConflict) {
+		return written, err
+	}
+	if !known && indexed {
+		return "", fmt.Errorf("%w: %s was saved by another process; re-run the command to pick up the latest state", ErrStateConflict, desc)
+	}
+
+	stored, version, err := readStateVersion(ctx, m.backend, p)
+	if err != nil {
+		return "", err
+	}
+	if err := checkFormat(stored.FormatVersion, p); err != nil {
+		return "", err
+	}
+	switch {
+	case known && version == "":
+		return "", fmt.Errorf("%w: %s was deleted after it was read; re-run the command to pick up the latest state", ErrStateConflict, desc)
+	case known && stored.Serial != cached.serial:
+		return "", fmt.Errorf("%w: %s is at serial %d but serial %d was read; re-run the command to pick up the latest state",
+			ErrStateConflict, desc, stored.Serial, cached.serial)
+	}
+
+	*serial = stored.Serial + 1
+	return writeIfVersion(ctx, m.backend, p, desc, doc, version)
+}
+
+// cacheComponent records the documents of a component that was read and
+// opened, and defers reading the IaC state of the resources read from their
+// summary.
+func (m *manager) cacheComponent(ctx context.Context, datacenter, env, name string, read *componentRead) error {
+	p := componentPath(datacenter, env, name)
+	if read.inline {
+		// Stored with its resources inline: only the revision is recorded,
+		// so that the next save writes the resource documents
+		m.documents.store(p, read.revisions[p])
+		return nil
+	}
+
+	for key, res := range read.state.Resources {
+		rp := resourcePath(datacenter, env, name, key)
+		summary, err := summaryContent(res)
+		if err != nil {
+			return err
+		}
+		cached := read.revisions[rp]
+		cached.digest = digestWithIaCState(summary, res.IaCState)
+		m.documents.store(rp, cached)
+		if read.summarized[rp] {
+			m.deferIaCState(ctx, rp, res, summary, cached.version)
+		}
+	}
+
+	cached := read.revisions[p]
+	if !read.legacy {
+		digest, err := documentDigest(m.componentDocument(datacenter, env, name, read.state))
+		if err != nil {
+			return err
+		}
+		cached.digest = digest
+	}
+	m.documents.store(p, cached)
+	return nil
+}
+
+// deferIaCState sets a resource read from its summary to read its IaC state
+// from its document when first used. summary is the resource's summary as
+// read, which with the IaC state makes up the digest of the document read.
+func (m *manager) deferIaCState(ctx context.Context, p string, res *types.ResourceState, summary []byte, version string) {
+	ctx = context.WithoutCancel(ctx)
+	res.DeferIaCState(func() ([]byte, error) {
+		doc, stored, err := readJSONVersion[resourceDocument](ctx, m.backend, p)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the IaC state of %s: %w", p, err)
+		}
+		if doc.ResourceState == nil {
+			return nil, nil
+		}
+		iacState, err := m.openIaCState(ctx, doc.IaCState)
+		if err != nil {
+			return nil, err
+		}
+
+		// Complete the digest, so that reading the IaC state alone doesn't
+		// make the resource look changed. A document saved since the
+		// summary was written keeps the digest of the summary, and writing
+		// the resource then reports the conflict.
+		if cached, ok := m.documents.lookup(p); ok && stored == version && cached.version == version {
+			cached.digest = digestWithIaCState(summary, iacState)
+			m.documents.store(p, cached)
+		}
+		return iacState, nil
+	})
+}
+
+// componentDocument returns the document stored for a component, with its
+// resources summarized as of the revisions of their documents the manager
+// last read or wrote.
+func (m *manager) componentDocument(datacenter, env, name string, comp *types.ComponentState) *componentDocument {
+	doc := &componentDocument{ComponentState: comp}
+	if len(comp.Resources) == 0 {
+		return doc
+	}
+	doc.Summaries = make(map[string]*resourceSummary, len(comp.Resources))
+	for key, res := range comp.Resources {
+		if res == nil {
+			continue
+		}
+		summary := *res
+		summary.IaCState = nil
+		cached, _ := m.documents.lookup(resourcePath(datacenter, env, name, key))
+		doc.Summaries[key] = &resourceSummary{
+			ResourceState: &summary,
+			HasIaCState:   len(res.IaCState) > 0 || res.IaCStateDeferred(),
+			Serial:        cached.serial,
+			Version:       cached.version,
+		}
+	}
+	return doc
+}
+
+// loadIaCStates reads the IaC state of the resources of an environment that
+// were read without it.
+func loadIaCStates(state *types.EnvironmentState) error {
+	for _, comp := range state.Components {
+		if comp == nil {
+			continue
+		}
+		for _, res := range comp.Resources {
+			if res == nil {
+				continue
+			}
+			if _, err := res.LoadIaCState(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// removeComponent deletes a component's document and its resources'
+// documents.
+func (m *manager) removeComponent(ctx context.Context, datacenter, env, name string) error {
+	dir := path.Dir(componentPath(datacenter, env, name))
+	paths, err := m.backend.List(ctx, dir+"/")
+	if err != nil {
+		return err
+	}
+	for _, p := range paths {
+		if err := m.backend.Delete(ctx, p); err != nil {
+			return fmt.Errorf("failed to delete %s: %w", p, err)
+		}
+	}
+	m.documents.forget(dir)
+	return nil
+}
+
+// componentResourceKeys lists the keys of the resource documents stored for
+// a component.
+func (m *manager) componentResourceKeys(ctx context.Context, datacenter, env, name string) ([]string, error) {
+	dir := path.Dir(resourcePath(datacenter, env, name, "_"))
+	paths, err := m.backend.List(ctx, dir+"/")
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]string, 0, len(paths))
+	for _, p := range paths {
+		if key := strings.TrimSuffix(path.Base(p), ".state.json"); key != path.Base(p) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
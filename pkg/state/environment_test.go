@@ -0,0 +1,479 @@
+package state
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/davidthor/arcctl/pkg/state/backend"
+	"github.com/davidthor/arcctl/pkg/state/backend/local"
+	"github.com/davidthor/arcctl/pkg/state/encryption"
+	"github.com/davidthor/arcctl/pkg/state/types"
+)
+
+// countingBackend records the paths read and written through it.
+type countingBackend struct {
+	backend.Backend
+
+	mu     sync.Mutex
+	reads  []string
+	writes []string
+}
+
+func (b *countingBackend) Read(ctx context.Context, p string) (io.ReadCloser, error) {
+	b.mu.Lock()
+	b.reads = append(b.reads, p)
+	b.mu.Unlock()
+	return b.Backend.Read(ctx, p)
+}
+
+func (b *countingBackend) ReadVersion(ctx context.Context, p string) (io.ReadCloser, string, error) {
+	b.mu.Lock()
+	b.reads = append(b.reads, p)
+	b.mu.Unlock()
+	return b.Backend.ReadVersion(ctx, p)
+}
+
+func (b *countingBackend) Write(ctx context.Context, p string, data io.Reader) error {
+	b.mu.Lock()
+	b.writes = append(b.writes, p)
+	b.mu.Unlock()
+	return b.Backend.Write(ctx, p, data)
+}
+
+func (b *countingBackend) WriteIfVersion(ctx context.Context, p string, data io.Reader, version string) (string, error) {
+	b.mu.Lock()
+	b.writes = append(b.writes, p)
+	b.mu.Unlock()
+	return b.Backend.WriteIfVersion(ctx, p, data, version)
+}
+
+func (b *countingBackend) reset() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.reads, b.writes = nil, nil
+}
+
+func newCountingManager(t *testing.T) (Manager, *countingBackend) {
+	t.Helper()
+	local, err := local.NewBackend(map[string]string{"path": t.TempDir()})
+	if err != nil {
+		t.Fatalf("Failed to create backend: %v", err)
+	}
+	b := &countingBackend{Backend: local}
+	return NewManager(b, WithPlaintextState()), b
+}
+
+func persistenceTestEnvironment() *types.EnvironmentState {
+	return &types.EnvironmentState{
+		Name:       "production",
+		Datacenter: "dc",
+		Status:     types.EnvironmentStatusReady,
+		Components: map[string]*types.ComponentState{
+			"api": {
+				Name:   "api",
+				Status: types.ResourceStatusReady,
+				Resources: map[string]*types.ResourceState{
+					"database.main":  {Name: "main", Type: "database", IaCState: []byte(`{"large":"state"}`)},
+					"deployment.api": {Name: "api", Type: "deployment", IaCState: []byte(`{"large":"state"}`)},
+				},
+			},
+			"web": {
+				Name:   "web",
+				Status: types.ResourceStatusReady,
+				Resources: map[string]*types.ResourceState{
+					"deployment.web": {Name: "web", Type: "deployment"},
+				},
+			},
+		},
+	}
+}
+
+func TestSaveEnvironment_Documents(t *testing.T) {
+	m, b := newCountingManager(t)
+	ctx := context.Background()
+
+	if err := m.SaveEnvironment(ctx, "dc", persistenceTestEnvironment()); err != nil {
+		t.Fatalf("SaveEnvironment failed: %v", err)
+	}
+
+	raw := readRaw(t, b, environmentPath("dc", "production"))
+	if strings.Contains(raw, "iac_state") || strings.Contains(raw, `"components"`) {
+		t.Errorf("expected the environment document to hold only an index, got:\n%s", raw)
+	}
+	if !strings.Contains(raw, `"index"`) || !strings.Contains(raw, `"database.main"`) {
+		t.Errorf("expected the environment document to index resources, got:\n%s", raw)
+	}
+	if strings.Contains(readRaw(t, b, componentPath("dc", "production", "api")), `"iac_state"`) {
+		t.Error("expected the component document not to hold its resources' IaC state")
+	}
+	if !strings.Contains(readRaw(t, b, resourcePath("dc", "production", "api", "database.main")), "iac_state") {
+		t.Error("expected the resource document to hold its IaC state")
+	}
+
+	env, err := m.GetEnvironment(ctx, "dc", "production")
+	if err != nil {
+		t.Fatalf("GetEnvironment failed: %v", err)
+	}
+	if len(env.Components) != 2 || len(env.Components["api"].Resources) != 2 || len(env.Components["web"].Resources) != 1 {
+		t.Fatalf("unexpected environment read back: %+v", env.Components)
+	}
+	if iacState, err := env.Components["api"].Resources["database.main"].LoadIaCState(); err != nil || string(iacState) != `{"large":"state"}` {
+		t.Errorf("IaCState: got %s, %v", iacState, err)
+	}
+}
+
+func TestSaveEnvironment_WritesChangedDocuments(t *testing.T) {
+	m, b := newCountingManager(t)
+	ctx := context.Background()
+
+	_ = m.SaveEnvironment(ctx, "dc", persistenceTestEnvironment())
+	env, err := m.GetEnvironment(ctx, "dc", "production")
+	if err != nil {
+		t.Fatalf("GetEnvironment failed: %v", err)
+	}
+
+	// Changing one resource writes only that resource, the component that
+	// summarizes it and the environment
+	b.reset()
+	env.Components["api"].Resources["database.main"].Status = types.ResourceStatusFailed
+	if err := m.SaveEnvironment(ctx, "dc", env); err != nil {
+		t.Fatalf("SaveEnvironment failed: %v", err)
+	}
+	want := []string{
+		resourcePath("dc", "production", "api", "database.main"),
+		componentPath("dc", "production", "api"),
+		environmentPath("dc", "production"),
+	}
+	if strings.Join(b.writes, ",") != strings.Join(want, ",") {
+		t.Errorf("expected writes %v, got %v", want, b.writes)
+	}
+
+	// Saving again without changes writes only the environment
+	b.reset()
+	if err := m.SaveEnvironment(ctx, "dc", env); err != nil {
+		t.Fatalf("SaveEnvironment failed: %v", err)
+	}
+	if len(b.writes) != 1 || b.writes[0] != environmentPath("dc", "production") {
+		t.Errorf("expected only the environment to be written, got %v", b.writes)
+	}
+
+	// A new resource is written and added to the index
+	b.reset()
+	env.Components["web"].Resources["bucket.assets"] = &types.ResourceState{Name: "assets", Type: "bucket"}
+	if err := m.SaveEnvironment(ctx, "dc", env); err != nil {
+		t.Fatalf("SaveEnvironment failed: %v", err)
+	}
+	want = []string{
+		resourcePath("dc", "production", "web", "bucket.assets"),
+		componentPath("dc", "production", "web"),
+		environmentPath("dc", "production"),
+	}
+	if strings.Join(b.writes, ",") != strings.Join(want, ",") {
+		t.Errorf("expected writes %v, got %v", want, b.writes)
+	}
+	stored, _ := NewManager(b.Backend).GetEnvironment(ctx, "dc", "production")
+	if stored.Components["web"].Resources["bucket.assets"] == nil {
+		t.Error("expected the new resource to be read back")
+	}
+}
+
+func TestSaveEnvironment_EncryptedUnchanged(t *testing.T) {
+	local, _ := local.NewBackend(map[string]string{"path": t.TempDir()})
+	b := &countingBackend{Backend: local}
+	provider, err := encryption.NewLocalKeyProvider("MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY=")
+	if err != nil {
+		t.Fatalf("Failed to create key provider: %v", err)
+	}
+	m := NewManager(b, WithEncrypter(encryption.NewEnvelopeEncrypter(provider)))
+	ctx := context.Background()
+
+	env := sensitiveTestEnvironment()
+	_ = m.SaveEnvironment(ctx, "aws-us-east", env)
+
+	// Sealed documents differ on every write, so unchanged documents are
+	// detected before sealing
+	b.reset()
+	if err := m.SaveEnvironment(ctx, "aws-us-east", env); err != nil {
+		t.Fatalf("SaveEnvironment failed: %v", err)
+	}
+	if len(b.writes) != 1 {
+		t.Errorf("expected only the environment to be written, got %v", b.writes)
+	}
+}
+
+func TestSaveEnvironment_RemovesDocuments(t *testing.T) {
+	m, b := newCountingManager(t)
+	ctx := context.Background()
+
+	env := persistenceTestEnvironment()
+	_ = m.SaveEnvironment(ctx, "dc", env)
+
+	delete(env.Components["api"].Resources, "deployment.api")
+	delete(env.Components, "web")
+	if err := m.SaveEnvironment(ctx, "dc", env); err != nil {
+		t.Fatalf("SaveEnvironment failed: %v", err)
+	}
+
+	for _, p := range []string{
+		resourcePath("dc", "production", "api", "deployment.api"),
+		componentPath("dc", "production", "web"),
+		resourcePath("dc", "production", "web", "deployment.web"),
+	} {
+		if exists, _ := b.Exists(ctx, p); exists {
+			t.Errorf("expected %s to be removed", p)
+		}
+	}
+	if exists, _ := b.Exists(ctx, resourcePath("dc", "production", "api", "database.main")); !exists {
+		t.Error("expected the remaining resource to be kept")
+	}
+
+	// A removed component that comes back is written again
+	env.Components["web"] = persistenceTestEnvironment().Components["web"]
+	if err := m.SaveEnvironment(ctx, "dc", env); err != nil {
+		t.Fatalf("SaveEnvironment failed: %v", err)
+	}
+	if exists, _ := b.Exists(ctx, resourcePath("dc", "production", "web", "deployment.web")); !exists {
+		t.Error("expected the restored component's resource to be written")
+	}
+}
+
+func TestGetEnvironment_InlineComponents(t *testing.T) {
+	m, b := newCountingManager(t)
+	ctx := context.Background()
+
+	// Environments stored before the index held their components inline
+	legacy := `{"format_version": 1, "name": "production", "datacenter": "dc", "serial": 3,
+		"components": {"api": {"name": "api", "resources": {"database.main": {"name": "main", "type": "database", "status": "ready"}}}}}`
+	_ = b.Write(ctx, environmentPath("dc", "production"), strings.NewReader(legacy))
+
+	env, err := m.GetEnvironment(ctx, "dc", "production")
+	if err != nil {
+		t.Fatalf("GetEnvironment failed: %v", err)
+	}
+	if env.Components["api"].Resources["database.main"].Status != types.ResourceStatusReady {
+		t.Fatalf("unexpected environment: %+v", env.Components)
+	}
+
+	// and are moved to their own documents when saved
+	if err := m.SaveEnvironment(ctx, "dc", env); err != nil {
+		t.Fatalf("SaveEnvironment failed: %v", err)
+	}
+	if strings.Contains(readRaw(t, b, environmentPath("dc", "production")), `"components"`) {
+		t.Error("expected the components to be moved out of the environment document")
+	}
+	res, err := m.GetResource(ctx, "dc", "production", "api", "database.main")
+	if err != nil || res.Status != types.ResourceStatusReady {
+		t.Errorf("expected the resource document to be written, got %+v, %v", res, err)
+	}
+}
+
+func TestListEnvironments_ReadsOnlyEnvironments(t *testing.T) {
+	m, b := newCountingManager(t)
+	ctx := context.Background()
+
+	_ = m.SaveEnvironment(ctx, "dc", persistenceTestEnvironment())
+
+	b.reset()
+	refs, err := m.ListEnvironments(ctx, "dc")
+	if err != nil {
+		t.Fatalf("ListEnvironments failed: %v", err)
+	}
+	if len(refs) != 1 || refs[0].Name != "production" {
+		t.Fatalf("unexpected refs: %+v", refs)
+	}
+	if len(b.reads) != 1 || b.reads[0] != environmentPath("dc", "production") {
+		t.Errorf("expected only the environment document to be read, got %v", b.reads)
+	}
+}
+
+func TestGetComponent_ReadsOnlyComponent(t *testing.T) {
+	m, b := newCountingManager(t)
+	ctx := context.Background()
+
+	_ = m.SaveEnvironment(ctx, "dc", persistenceTestEnvironment())
+
+	b.reset()
+	comp, err := m.GetComponent(ctx, "dc", "production", "web")
+	if err != nil {
+		t.Fatalf("GetComponent failed: %v", err)
+	}
+	if comp.Name != "web" || len(comp.Resources) != 1 || comp.Resources["deployment.web"] == nil {
+		t.Errorf("unexpected component: %+v", comp)
+	}
+	for _, p := range b.reads {
+		if strings.Contains(p, "/components/api/") || p == environmentPath("dc", "production") {
+			t.Errorf("expected only the web component's documents to be read, got %v", b.reads)
+			break
+		}
+	}
+}
+
+func TestSaveEnvironment_ConflictingResourceWrites(t *testing.T) {
+	b, _ := local.NewBackend(map[string]string{"path": t.TempDir()})
+	ctx := context.Background()
+	_ = NewManager(b, WithPlaintextState()).SaveEnvironment(ctx, "dc", persistenceTestEnvironment())
+
+	first, second := NewManager(b, WithPlaintextState()), NewManager(b, WithPlaintextState())
+	firstEnv, err := first.GetEnvironment(ctx, "dc", "production")
+	if err != nil {
+		t.Fatalf("GetEnvironment failed: %v", err)
+	}
+	secondEnv, err := second.GetEnvironment(ctx, "dc", "production")
+	if err != nil {
+		t.Fatalf("GetEnvironment failed: %v", err)
+	}
+
+	firstEnv.Components["api"].Resources["database.main"].IaCState = []byte(`{"first":true}`)
+	if err := first.SaveEnvironment(ctx, "dc", firstEnv); err != nil {
+		t.Fatalf("SaveEnvironment failed: %v", err)
+	}
+
+	// The second writer's change to the same resource is refused rather than
+	// overwriting the first's
+	secondEnv.Components["api"].Resources["database.main"].IaCState = []byte(`{"second":true}`)
+	if err := second.SaveEnvironment(ctx, "dc", secondEnv); !errors.Is(err, ErrStateConflict) {
+		t.Fatalf("expected ErrStateConflict, got %v", err)
+	}
+	stored, err := NewManager(b).GetEnvironment(ctx, "dc", "production")
+	if err != nil {
+		t.Fatalf("GetEnvironment failed: %v", err)
+	}
+	if got, err := stored.Components["api"].Resources["database.main"].LoadIaCState(); err != nil || string(got) != `{"first":true}` {
+		t.Errorf("expected the first writer's IaC state to be kept, got %s, %v", got, err)
+	}
+}
+
+func TestSaveEnvironment_ConflictingNewResources(t *testing.T) {
+	b, _ := local.NewBackend(map[string]string{"path": t.TempDir()})
+	ctx := context.Background()
+	_ = NewManager(b, WithPlaintextState()).SaveEnvironment(ctx, "dc", persistenceTestEnvironment())
+
+	first, second := NewManager(b, WithPlaintextState()), NewManager(b, WithPlaintextState())
+	firstEnv, _ := first.GetEnvironment(ctx, "dc", "production")
+	secondEnv, _ := second.GetEnvironment(ctx, "dc", "production")
+
+	firstEnv.Components["web"].Resources["bucket.assets"] = &types.ResourceState{Name: "assets", Type: "bucket", IaCState: []byte(`{"first":true}`)}
+	if err := first.SaveEnvironment(ctx, "dc", firstEnv); err != nil {
+		t.Fatalf("SaveEnvironment failed: %v", err)
+	}
+
+	secondEnv.Components["web"].Resources["bucket.assets"] = &types.ResourceState{Name: "assets", Type: "bucket", IaCState: []byte(`{"second":true}`)}
+	if err := second.SaveEnvironment(ctx, "dc", secondEnv); !errors.Is(err, ErrStateConflict) {
+		t.Fatalf("expected ErrStateConflict, got %v", err)
+	}
+	res, err := NewManager(b).GetResource(ctx, "dc", "production", "web", "bucket.assets")
+	if err != nil || string(res.IaCState) != `{"first":true}` {
+		t.Errorf("expected the first writer's resource to be kept, got %+v, %v", res, err)
+	}
+}
+
+func TestSaveEnvironment_ReplacesUnindexedDocuments(t *testing.T) {
+	m, b := newCountingManager(t)
+	ctx := context.Background()
+	_ = m.SaveEnvironment(ctx, "dc", persistenceTestEnvironment())
+
+	// A save that failed before writing the environment leaves documents
+	// that aren't indexed
+	orphan := resourcePath("dc", "production", "web", "bucket.assets")
+	_ = b.Write(ctx, orphan, strings.NewReader(`{"format_version": 2, "name": "assets", "type": "bucket", "serial": 1}`))
+
+	env, err := NewManager(b).GetEnvironment(ctx, "dc", "production")
+	if err != nil {
+		t.Fatalf("GetEnvironment failed: %v", err)
+	}
+	if env.Components["web"].Resources["bucket.assets"] != nil {
+		t.Error("expected the unindexed resource not to be read")
+	}
+
+	env.Components["web"].Resources["bucket.assets"] = &types.ResourceState{Name: "assets", Type: "bucket", Status: types.ResourceStatusReady}
+	if err := m.SaveEnvironment(ctx, "dc", env); err != nil {
+		t.Fatalf("SaveEnvironment failed: %v", err)
+	}
+	res, err := m.GetResource(ctx, "dc", "production", "web", "bucket.assets")
+	if err != nil || res.Status != types.ResourceStatusReady {
+		t.Errorf("expected the unindexed document to be replaced, got %+v, %v", res, err)
+	}
+}
+
+func TestGetEnvironment_MissingDocument(t *testing.T) {
+	m, b := newCountingManager(t)
+	ctx := context.Background()
+	_ = m.SaveEnvironment(ctx, "dc", persistenceTestEnvironment())
+
+	_ = b.Delete(ctx, componentPath("dc", "production", "api"))
+
+	_, err := NewManager(b).GetEnvironment(ctx, "dc", "production")
+	if err == nil || !strings.Contains(err.Error(), "api/component.state.json") {
+		t.Errorf("expected an error naming the missing document, got %v", err)
+	}
+	if errors.Is(err, backend.ErrNotFound) {
+		t.Error("expected a missing document not to be reported as a missing environment")
+	}
+}
+
+func TestGetEnvironment_MissingResourceDocument(t *testing.T) {
+	m, b := newCountingManager(t)
+	ctx := context.Background()
+	_ = m.SaveEnvironment(ctx, "dc", persistenceTestEnvironment())
+
+	_ = b.Delete(ctx, resourcePath("dc", "production", "api", "database.main"))
+
+	// The resource is read from its summary, and the missing document is
+	// reported when its IaC state is used
+	env, err := NewManager(b).GetEnvironment(ctx, "dc", "production")
+	if err != nil {
+		t.Fatalf("GetEnvironment failed: %v", err)
+	}
+	_, err = env.Components["api"].Resources["database.main"].LoadIaCState()
+	if err == nil || !strings.Contains(err.Error(), "database.main.state.json") {
+		t.Errorf("expected an error naming the missing document, got %v", err)
+	}
+}
+
+func TestGetEnvironment_ReadsIaCStateOnFirstUse(t *testing.T) {
+	m, b := newCountingManager(t)
+	ctx := context.Background()
+	_ = m.SaveEnvironment(ctx, "dc", persistenceTestEnvironment())
+
+	b.reset()
+	reader := NewManager(b, WithPlaintextState())
+	env, err := reader.GetEnvironment(ctx, "dc", "production")
+	if err != nil {
+		t.Fatalf("GetEnvironment failed: %v", err)
+	}
+	for _, p := range b.reads {
+		if strings.Contains(p, "/resources/") {
+			t.Fatalf("expected no resource documents to be read, got %v", b.reads)
+		}
+	}
+	res := env.Components["api"].Resources["database.main"]
+	if res.Name != "main" || !res.IaCStateDeferred() {
+		t.Fatalf("expected the resource to be read from its summary, got %+v", res)
+	}
+
+	iacState, err := res.LoadIaCState()
+	if err != nil || string(iacState) != `{"large":"state"}` {
+		t.Fatalf("LoadIaCState: got %s, %v", iacState, err)
+	}
+	if want := resourcePath("dc", "production", "api", "database.main"); b.reads[len(b.reads)-1] != want {
+		t.Errorf("expected %s to be read, got %v", want, b.reads)
+	}
+
+	// Saving a change writes against the version read, without reading
+	// the documents again
+	b.reset()
+	res.Status = types.ResourceStatusFailed
+	if err := reader.SaveEnvironment(ctx, "dc", env); err != nil {
+		t.Fatalf("SaveEnvironment failed: %v", err)
+	}
+	for _, p := range b.reads {
+		if strings.Contains(p, "/components/") {
+			t.Errorf("expected no component or resource documents to be read, got %v", b.reads)
+			break
+		}
+	}
+}
@@ -0,0 +1,189 @@
+package state
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/davidthor/arcctl/pkg/state/backend"
+)
+
+// FormatVersion is the version of the state document format written by this
+// build of cldctl. It is recorded as format_version at the top of every state
+// document. Documents with an older version are upgraded when they are read;
+// documents with a newer version can be read but not overwritten.
+const FormatVersion = 2
+
+// ErrNewerFormat is returned when writing over state that a newer version of
+// cldctl wrote.
+var ErrNewerFormat = errors.New("state was written by a newer version of cldctl")
+
+// stateUpgrade upgrades a decoded state document by one format version in
+// place. kind is the document's kind (one of the ArchiveKind constants).
+// Environment history snapshots hold an environment document under "state",
+// which an upgrade of environments must also upgrade.
+type stateUpgrade func(kind string, doc map[string]interface{}) error
+
+// stateUpgrades holds the registered upgrades in order: stateUpgrades[v]
+// upgrades a document from format version v to v+1. Changing the layout of a
+// state document means incrementing FormatVersion and appending an upgrade
+// from the previous version.
+var stateUpgrades = []stateUpgrade{
+	// 0 -> 1: documents written before format versions were recorded. The
+	// layout is unchanged.
+	func(kind string, doc map[string]interface{}) error { return nil },
+
+	// 1 -> 2: environments held their components and resources inline rather
+	// than in their own documents.
+	indexInlineComponents,
+}
+
+// indexInlineComponents upgrades an environment that holds its components
+// inline by indexing them, as environments stored in their own documents are.
+// The inline components are read from the environment document until the
+// next save moves them to their own documents. Snapshots keep their
+// components inline and are left as they are.
+func indexInlineComponents(kind string, doc map[string]interface{}) error {
+	if kind != ArchiveKindEnvironment {
+		return nil
+	}
+	components, ok := doc["components"].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+
+	index := make(map[string]interface{}, len(components))
+	for name, comp := range components {
+		keys := []string{}
+		if comp, ok := comp.(map[string]interface{}); ok {
+			resources, _ := comp["resources"].(map[string]interface{})
+			for key := range resources {
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+		index[name] = keys
+	}
+	doc["index"] = index
+	return nil
+}
+
+// formatHeader is the part of a state document that records its format.
+type formatHeader struct {
+	FormatVersion int `json:"format_version"`
+}
+
+// upgradeDocument decodes a state document of the given kind, applying the
+// upgrades from its format version to FormatVersion, and returns it
+// re-encoded. Documents already at FormatVersion or newer are returned as
+// they are.
+func upgradeDocument(kind string, data []byte) ([]byte, error) {
+	var header formatHeader
+	if err := json.Unmarshal(data, &header); err != nil {
+		return nil, fmt.Errorf("failed to decode JSON: %w", err)
+	}
+	if header.FormatVersion >= FormatVersion {
+		return data, nil
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var doc map[string]interface{}
+	if err := dec.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("failed to decode JSON: %w", err)
+	}
+
+	for v := header.FormatVersion; v < FormatVersion; v++ {
+		if err := stateUpgrades[v](kind, doc); err != nil {
+			return nil, fmt.Errorf("failed to upgrade %s state from format version %d: %w", kind, v, err)
+		}
+	}
+	doc["format_version"] = FormatVersion
+
+	return json.Marshal(doc)
+}
+
+// encodeDocument encodes a state document, recording FormatVersion as its
+// first field.
+func encodeDocument(doc interface{}) ([]byte, error) {
+	content, err := json.Marshal(doc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode JSON: %w", err)
+	}
+	if len(content) < 2 || content[0] != '{' {
+		return nil, fmt.Errorf("failed to encode JSON: state document must be an object")
+	}
+
+	var stamped bytes.Buffer
+	fmt.Fprintf(&stamped, `{"format_version":%d`, FormatVersion)
+	if len(content) > 2 {
+		stamped.WriteByte(',')
+	}
+	stamped.Write(content[1:])
+
+	var indented bytes.Buffer
+	if err := json.Indent(&indented, stamped.Bytes(), "", "  "); err != nil {
+		return nil, fmt.Errorf("failed to encode JSON: %w", err)
+	}
+	return indented.Bytes(), nil
+}
+
+// checkFormat returns ErrNewerFormat if a stored document was written by a
+// newer version of cldctl, so that writing over it would lose data.
+func checkFormat(version int, p string) error {
+	if version > FormatVersion {
+		return fmt.Errorf("%w: %s has format version %d but this version supports up to %d; upgrade cldctl to change it",
+			ErrNewerFormat, p, version, FormatVersion)
+	}
+	return nil
+}
+
+// checkStoredFormat checks that the document stored at p, if any, can be
+// overwritten, and returns the backend's version token for the revision it
+// checked. The token is empty if the document doesn't exist.
+func checkStoredFormat(ctx context.Context, b backend.Backend, p string) (string, error) {
+	reader, version, err := b.ReadVersion(ctx, p)
+	if errors.Is(err, backend.ErrNotFound) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	defer reader.Close()
+
+	formatVersion, err := readFormatVersion(reader)
+	if err != nil {
+		return version, nil // Unreadable state is replaced, as before versioning
+	}
+	return version, checkFormat(formatVersion, p)
+}
+
+// readFormatVersion decodes the format version at the start of a state
+// document without decoding the rest of it. encodeDocument records
+// format_version as the first field, so a document that starts with any other
+// field predates format versions.
+func readFormatVersion(r io.Reader) (int, error) {
+	dec := json.NewDecoder(r)
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return 0, fmt.Errorf("failed to decode JSON: state document must be an object")
+	}
+	if !dec.More() {
+		return 0, nil
+	}
+	key, err := dec.Token()
+	if err != nil {
+		return 0, fmt.Errorf("failed to decode JSON: %w", err)
+	}
+	if key != "format_version" {
+		return 0, nil
+	}
+	var version int
+	if err := dec.Decode(&version); err != nil {
+		return 0, fmt.Errorf("failed to decode JSON: %w", err)
+	}
+	return version, nil
+}
@@ -0,0 +1,264 @@
+package state
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/davidthor/arcctl/pkg/state/backend/local"
+	"github.com/davidthor/arcctl/pkg/state/types"
+)
+
+func TestStateUpgrades_CoverFormatVersion(t *testing.T) {
+	if len(stateUpgrades) != FormatVersion {
+		t.Fatalf("expected an upgrade to each format version up to %d, got %d upgrades", FormatVersion, len(stateUpgrades))
+	}
+}
+
+func TestEncodeDocument(t *testing.T) {
+	content, err := encodeDocument(&types.ComponentState{Name: "api"})
+	if err != nil {
+		t.Fatalf("encodeDocument failed: %v", err)
+	}
+	if !strings.HasPrefix(string(content), fmt.Sprintf("{\n  \"format_version\": %d,\n  \"name\": \"api\"", FormatVersion)) {
+		t.Errorf("expected format_version as the first field, got:\n%s", content)
+	}
+
+	content, err = encodeDocument(struct{}{})
+	if err != nil {
+		t.Fatalf("encodeDocument failed: %v", err)
+	}
+	if string(content) != fmt.Sprintf("{\n  \"format_version\": %d\n}", FormatVersion) {
+		t.Errorf("unexpected encoding of empty document: %s", content)
+	}
+
+	if _, err := encodeDocument([]string{"a"}); err == nil {
+		t.Error("expected an error encoding a document that isn't an object")
+	}
+}
+
+func TestUpgradeDocument(t *testing.T) {
+	original := stateUpgrades[0]
+	defer func() { stateUpgrades[0] = original }()
+
+	var kinds []string
+	stateUpgrades[0] = func(kind string, doc map[string]interface{}) error {
+		kinds = append(kinds, kind)
+		doc["status_reason"] = doc["reason"]
+		delete(doc, "reason")
+		return nil
+	}
+
+	data, err := upgradeDocument(ArchiveKindResource, []byte(`{"name": "main", "reason": "old", "attempt": 9007199254740993}`))
+	if err != nil {
+		t.Fatalf("upgradeDocument failed: %v", err)
+	}
+	if len(kinds) != 1 || kinds[0] != ArchiveKindResource {
+		t.Errorf("expected one upgrade of a resource, got %v", kinds)
+	}
+
+	var doc map[string]json.RawMessage
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatalf("failed to decode upgraded document: %v", err)
+	}
+	if string(doc["format_version"]) != strconv.Itoa(FormatVersion) || string(doc["status_reason"]) != `"old"` || doc["reason"] != nil {
+		t.Errorf("unexpected upgraded document: %s", data)
+	}
+	if string(doc["attempt"]) != "9007199254740993" {
+		t.Errorf("expected numbers to be preserved exactly, got %s", doc["attempt"])
+	}
+
+	// Documents already at the current version aren't touched
+	kinds = nil
+	current := []byte(fmt.Sprintf(`{"format_version": %d, "reason": "kept"}`, FormatVersion))
+	data, err = upgradeDocument(ArchiveKindResource, current)
+	if err != nil {
+		t.Fatalf("upgradeDocument failed: %v", err)
+	}
+	if len(kinds) != 0 || !bytes.Equal(data, current) {
+		t.Errorf("expected current document unchanged, got %s after upgrades %v", data, kinds)
+	}
+}
+
+func TestUpgradeDocument_Error(t *testing.T) {
+	original := stateUpgrades[0]
+	defer func() { stateUpgrades[0] = original }()
+
+	stateUpgrades[0] = func(kind string, doc map[string]interface{}) error {
+		return errors.New("unsupported layout")
+	}
+
+	b, _ := local.NewBackend(map[string]string{"path": t.TempDir()})
+	m := NewManager(b)
+	ctx := context.Background()
+	_ = b.Write(ctx, environmentPath("dc", "production"), strings.NewReader(`{"name": "production"}`))
+
+	_, err := m.GetEnvironment(ctx, "dc", "production")
+	if err == nil || !strings.Contains(err.Error(), "failed to upgrade environment state from format version 0: unsupported layout") {
+		t.Errorf("expected upgrade error, got %v", err)
+	}
+}
+
+func TestUpgradeDocument_IndexesInlineComponents(t *testing.T) {
+	legacy := `{"format_version": 1, "name": "production", "components": {
+		"api": {"name": "api", "resources": {"deployment.api": {}, "database.main": {}}},
+		"web": {"name": "web"}}}`
+
+	data, err := upgradeDocument(ArchiveKindEnvironment, []byte(legacy))
+	if err != nil {
+		t.Fatalf("upgradeDocument failed: %v", err)
+	}
+	var doc environmentDocument
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatalf("failed to decode upgraded document: %v", err)
+	}
+	if got := strings.Join(doc.Index["api"], ","); got != "database.main,deployment.api" {
+		t.Errorf("api index: got %q", got)
+	}
+	if keys, ok := doc.Index["web"]; !ok || len(keys) != 0 {
+		t.Errorf("expected web to be indexed without resources, got %v", doc.Index)
+	}
+	if doc.Components["api"] == nil {
+		t.Error("expected the inline components to be kept until the next save")
+	}
+
+	// Snapshots keep their components inline
+	snapshot := `{"format_version": 1, "version": 1, "state": {"name": "production", "components": {"api": {"name": "api"}}}}`
+	data, err = upgradeDocument(ArchiveKindHistory, []byte(snapshot))
+	if err != nil {
+		t.Fatalf("upgradeDocument failed: %v", err)
+	}
+	if strings.Contains(string(data), `"index"`) {
+		t.Errorf("expected snapshot to be left without an index, got %s", data)
+	}
+}
+
+func TestReadFormatVersion(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    int
+		wantErr bool
+	}{
+		{name: "current", data: `{"format_version": 2, "name": "api"}`, want: 2},
+		{name: "newer", data: `{"format_version": 99}`, want: 99},
+		{name: "before versioning", data: `{"name": "api", "format_version": 5}`, want: 0},
+		{name: "empty", data: `{}`, want: 0},
+		{name: "not an object", data: `["a"]`, wantErr: true},
+		{name: "not JSON", data: `garbage`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := readFormatVersion(strings.NewReader(tt.data))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("readFormatVersion() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("readFormatVersion() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatVersion_Written(t *testing.T) {
+	b, _ := local.NewBackend(map[string]string{"path": t.TempDir()})
+	m := NewManager(b)
+	ctx := context.Background()
+
+	_ = m.SaveDatacenter(ctx, &types.DatacenterState{Name: "dc"})
+	_ = m.SaveDatacenterComponent(ctx, "dc", &types.DatacenterComponentConfig{Name: "stripe"})
+	_ = m.SaveEnvironment(ctx, "dc", &types.EnvironmentState{Name: "production"})
+	_ = m.SaveComponent(ctx, "dc", "production", &types.ComponentState{Name: "api"})
+	_ = m.SaveResource(ctx, "dc", "production", "api", &types.ResourceState{Name: "main", Type: "database"})
+	_, _ = m.SnapshotEnvironment(ctx, "dc", "production", types.EnvironmentSnapshotInfo{Operation: "deploy"})
+
+	for _, p := range []string{
+		datacenterPath("dc"),
+		datacenterComponentPath("dc", "stripe"),
+		environmentPath("dc", "production"),
+		componentPath("dc", "production", "api"),
+		resourcePath("dc", "production", "api", "database.main"),
+		snapshotPath("dc", "production", 1),
+	} {
+		var header formatHeader
+		if err := json.Unmarshal([]byte(readRaw(t, b, p)), &header); err != nil {
+			t.Fatalf("failed to decode %s: %v", p, err)
+		}
+		if header.FormatVersion != FormatVersion {
+			t.Errorf("expected %s to have format version %d, got %d", p, FormatVersion, header.FormatVersion)
+		}
+	}
+}
+
+func TestFormatVersion_LegacyState(t *testing.T) {
+	b, _ := local.NewBackend(map[string]string{"path": t.TempDir()})
+	m := NewManager(b)
+	ctx := context.Background()
+
+	// State written before format versions existed is read as version 0
+	p := resourcePath("dc", "production", "api", "database.main")
+	_ = b.Write(ctx, p, strings.NewReader(`{"name": "main", "type": "database", "status": "ready"}`))
+
+	res, err := m.GetResource(ctx, "dc", "production", "api", "database.main")
+	if err != nil {
+		t.Fatalf("GetResource failed: %v", err)
+	}
+	if res.Name != "main" || res.Status != types.ResourceStatusReady {
+		t.Errorf("unexpected resource: %+v", res)
+	}
+
+	if err := m.SaveResource(ctx, "dc", "production", "api", res); err != nil {
+		t.Fatalf("SaveResource failed: %v", err)
+	}
+	if !strings.Contains(readRaw(t, b, p), fmt.Sprintf(`"format_version": %d`, FormatVersion)) {
+		t.Error("expected saved resource to record the format version")
+	}
+}
+
+func TestFormatVersion_NewerState(t *testing.T) {
+	b, _ := local.NewBackend(map[string]string{"path": t.TempDir()})
+	m := NewManager(b)
+	ctx := context.Background()
+
+	newer := `{"format_version": 99, "name": "production", "datacenter": "dc", "serial": 4, "future_field": true}`
+	_ = b.Write(ctx, environmentPath("dc", "production"), strings.NewReader(newer))
+	_ = b.Write(ctx, componentPath("dc", "production", "api"), strings.NewReader(`{"format_version": 99, "name": "api"}`))
+
+	// Newer state can still be read
+	env, err := m.GetEnvironment(ctx, "dc", "production")
+	if err != nil {
+		t.Fatalf("GetEnvironment failed: %v", err)
+	}
+	if env.Name != "production" || env.Serial != 4 {
+		t.Errorf("unexpected environment: %+v", env)
+	}
+
+	// but not overwritten
+	err = m.SaveEnvironment(ctx, "dc", env)
+	if !errors.Is(err, ErrNewerFormat) {
+		t.Fatalf("expected ErrNewerFormat saving environment, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "format version 99") {
+		t.Errorf("expected error to name the stored format version, got %v", err)
+	}
+	if readRaw(t, b, environmentPath("dc", "production")) != newer {
+		t.Error("expected newer environment state to be left as it was")
+	}
+
+	err = m.SaveComponent(ctx, "dc", "production", &types.ComponentState{Name: "api"})
+	if !errors.Is(err, ErrNewerFormat) {
+		t.Errorf("expected ErrNewerFormat saving component, got %v", err)
+	}
+
+	_ = b.Write(ctx, datacenterComponentPath("dc", "stripe"), strings.NewReader(`{"format_version": 99, "name": "stripe"}`))
+	err = m.SaveDatacenterComponent(ctx, "dc", &types.DatacenterComponentConfig{Name: "stripe"})
+	if !errors.Is(err, ErrNewerFormat) {
+		t.Errorf("expected ErrNewerFormat saving datacenter component, got %v", err)
+	}
+}
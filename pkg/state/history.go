@@ -0,0 +1,170 @@
+package state
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/davidthor/arcctl/pkg/state/backend"
+	"github.com/davidthor/arcctl/pkg/state/types"
+)
+
+// ErrSnapshotNotFound is returned when a requested state snapshot doesn't exist.
+var ErrSnapshotNotFound = errors.New("snapshot not found")
+
+// DefaultHistoryLimit is the number of snapshots kept in an environment's
+// history unless WithHistoryLimit says otherwise.
+const DefaultHistoryLimit = 100
+
+// snapshotAttempts bounds how many snapshot numbers SnapshotEnvironment tries
+// when other processes record snapshots of the same environment concurrently.
+const snapshotAttempts = 10
+
+// Environment history
+
+func (m *manager) SnapshotEnvironment(ctx context.Context, datacenter, name string, info types.EnvironmentSnapshotInfo) (*types.EnvironmentSnapshotInfo, error) {
+	current, err := m.GetEnvironment(ctx, datacenter, name)
+	if err != nil {
+		return nil, err
+	}
+	// A snapshot holds the whole state, IaC state included
+	if err := loadIaCStates(current); err != nil {
+		return nil, err
+	}
+
+	versions, err := m.historyVersions(ctx, datacenter, name)
+	if err != nil {
+		return nil, err
+	}
+	info.Version = 1
+	if len(versions) > 0 {
+		info.Version = versions[len(versions)-1] + 1
+	}
+	if info.CreatedAt.IsZero() {
+		info.CreatedAt = time.Now()
+	}
+
+	sealed, err := m.sealEnvironment(ctx, current)
+	if err != nil {
+		return nil, err
+	}
+
+	// Snapshots are only ever created, never overwritten, so a snapshot
+	// number taken by a concurrent process shows up as a conflict and the
+	// next number is tried instead.
+	for attempt := 1; ; attempt++ {
+		snapshot := &types.EnvironmentSnapshot{
+			EnvironmentSnapshotInfo: info,
+			State:                   sealed,
+		}
+		desc := fmt.Sprintf("snapshot %d of environment %s", info.Version, name)
+		_, err := writeIfVersion(ctx, m.backend, snapshotPath(datacenter, name, info.Version), desc, snapshot, "")
+		if err == nil {
+			break
+		}
+		if !errors.Is(err, ErrStateConflict) || attempt == snapshotAttempts {
+			return nil, err
+		}
+		info.Version++
+	}
+
+	if err := m.pruneHistory(ctx, datacenter, name, info.Version); err != nil {
+		return nil, err
+	}
+
+	return &info, nil
+}
+
+func (m *manager) ListEnvironmentHistory(ctx context.Context, datacenter, name string) ([]types.EnvironmentSnapshotInfo, error) {
+	versions, err := m.historyVersions(ctx, datacenter, name)
+	if err != nil {
+		return nil, err
+	}
+
+	history := make([]types.EnvironmentSnapshotInfo, 0, len(versions))
+	for _, version := range versions {
+		snapshot, err := readJSON[types.EnvironmentSnapshot](ctx, m.backend, snapshotPath(datacenter, name, version))
+		if err != nil {
+			continue // Skip snapshots that can't be read
+		}
+		// The file name is authoritative; older snapshots recorded their
+		// number as "serial"
+		snapshot.Version = version
+		history = append(history, snapshot.EnvironmentSnapshotInfo)
+	}
+
+	return history, nil
+}
+
+func (m *manager) GetEnvironmentSnapshot(ctx context.Context, datacenter, name string, version int) (*types.EnvironmentSnapshot, error) {
+	snapshot, err := readJSON[types.EnvironmentSnapshot](ctx, m.backend, snapshotPath(datacenter, name, version))
+	if err != nil {
+		if errors.Is(err, backend.ErrNotFound) {
+			return nil, fmt.Errorf("%w: version %d of environment %s", ErrSnapshotNotFound, version, name)
+		}
+		return nil, err
+	}
+	if snapshot.State == nil {
+		return nil, fmt.Errorf("snapshot %d of environment %s has no state", version, name)
+	}
+	snapshot.Version = version
+	if err := m.openEnvironment(ctx, snapshot.State); err != nil {
+		return nil, err
+	}
+	return snapshot, nil
+}
+
+// pruneHistory removes the snapshots of an environment that fall outside the
+// history limit, counting back from latest.
+func (m *manager) pruneHistory(ctx context.Context, datacenter, name string, latest int) error {
+	if m.historyLimit <= 0 {
+		return nil
+	}
+
+	versions, err := m.historyVersions(ctx, datacenter, name)
+	if err != nil {
+		return err
+	}
+	for _, version := range versions {
+		if version > latest-m.historyLimit {
+			break
+		}
+		if err := m.backend.Delete(ctx, snapshotPath(datacenter, name, version)); err != nil {
+			return fmt.Errorf("failed to remove snapshot %d of environment %s: %w", version, name, err)
+		}
+	}
+	return nil
+}
+
+// historyVersions returns the versions of an environment's snapshots in
+// ascending order.
+func (m *manager) historyVersions(ctx context.Context, datacenter, name string) ([]int, error) {
+	paths, err := m.backend.List(ctx, historyPrefix(datacenter, name))
+	if err != nil {
+		return nil, err
+	}
+
+	var versions []int
+	for _, p := range paths {
+		version, err := strconv.Atoi(strings.TrimSuffix(path.Base(p), ".state.json"))
+		if err != nil {
+			continue // Not a snapshot file
+		}
+		versions = append(versions, version)
+	}
+	sort.Ints(versions)
+	return versions, nil
+}
+
+func historyPrefix(dc, env string) string {
+	return path.Join("datacenters", dc, "environments", env, "history") + "/"
+}
+
+func snapshotPath(dc, env string, version int) string {
+	return path.Join("datacenters", dc, "environments", env, "history", strconv.Itoa(version)+".state.json")
+}
@@ -0,0 +1,200 @@
+package state
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/davidthor/arcctl/pkg/state/backend"
+	"github.com/davidthor/arcctl/pkg/state/backend/local"
+	"github.com/davidthor/arcctl/pkg/state/types"
+)
+
+// staleListBackend lists nothing, as a backend would when another process
+// writes between the listing and the write that follows it.
+type staleListBackend struct {
+	backend.Backend
+}
+
+func (b *staleListBackend) List(ctx context.Context, prefix string) ([]string, error) {
+	return nil, nil
+}
+
+func TestEnvironmentHistory(t *testing.T) {
+	m, cleanup := createTestManager(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	dc := "aws-us-east"
+
+	deploy := func(version string) {
+		t.Helper()
+		env := &types.EnvironmentState{
+			Name:       "production",
+			Datacenter: dc,
+			Components: map[string]*types.ComponentState{
+				"api": {Name: "api", Source: "ghcr.io/org/api:" + version},
+			},
+		}
+		if current, err := m.GetEnvironment(ctx, dc, "production"); err == nil {
+			env.Serial = current.Serial
+		}
+		if err := m.SaveEnvironment(ctx, dc, env); err != nil {
+			t.Fatalf("SaveEnvironment failed: %v", err)
+		}
+	}
+
+	deploy("v1")
+	first, err := m.SnapshotEnvironment(ctx, dc, "production", types.EnvironmentSnapshotInfo{
+		Operation: "deploy",
+		Who:       "alice",
+		Component: "api",
+	})
+	if err != nil {
+		t.Fatalf("SnapshotEnvironment failed: %v", err)
+	}
+	if first.Version != 1 || first.CreatedAt.IsZero() {
+		t.Errorf("first snapshot: got version %d, created %v", first.Version, first.CreatedAt)
+	}
+
+	deploy("v2")
+	second, err := m.SnapshotEnvironment(ctx, dc, "production", types.EnvironmentSnapshotInfo{Operation: "deploy"})
+	if err != nil {
+		t.Fatalf("SnapshotEnvironment failed: %v", err)
+	}
+	if second.Version != 2 {
+		t.Errorf("second snapshot: got version %d, want 2", second.Version)
+	}
+
+	history, err := m.ListEnvironmentHistory(ctx, dc, "production")
+	if err != nil {
+		t.Fatalf("ListEnvironmentHistory failed: %v", err)
+	}
+	if len(history) != 2 || history[0].Version != 1 || history[1].Version != 2 {
+		t.Fatalf("unexpected history: %+v", history)
+	}
+	if history[0].Who != "alice" || history[0].Component != "api" {
+		t.Errorf("unexpected metadata: %+v", history[0])
+	}
+
+	snapshot, err := m.GetEnvironmentSnapshot(ctx, dc, "production", 1)
+	if err != nil {
+		t.Fatalf("GetEnvironmentSnapshot failed: %v", err)
+	}
+	if got := snapshot.State.Components["api"].Source; got != "ghcr.io/org/api:v1" {
+		t.Errorf("snapshot source: got %q", got)
+	}
+
+	// Snapshots don't show up as environments
+	refs, err := m.ListEnvironments(ctx, dc)
+	if err != nil {
+		t.Fatalf("ListEnvironments failed: %v", err)
+	}
+	if len(refs) != 1 {
+		t.Errorf("expected 1 environment, got %d", len(refs))
+	}
+
+	if _, err := m.GetEnvironmentSnapshot(ctx, dc, "production", 3); !errors.Is(err, ErrSnapshotNotFound) {
+		t.Errorf("expected ErrSnapshotNotFound, got %v", err)
+	}
+}
+
+func TestEnvironmentHistory_EncryptedAtRest(t *testing.T) {
+	m, b := createEncryptedTestManager(t)
+	ctx := context.Background()
+
+	if err := m.SaveEnvironment(ctx, "aws-us-east", sensitiveTestEnvironment()); err != nil {
+		t.Fatalf("SaveEnvironment failed: %v", err)
+	}
+	if _, err := m.SnapshotEnvironment(ctx, "aws-us-east", "production", types.EnvironmentSnapshotInfo{Operation: "deploy"}); err != nil {
+		t.Fatalf("SnapshotEnvironment failed: %v", err)
+	}
+
+	if raw := readRaw(t, b, snapshotPath("aws-us-east", "production", 1)); strings.Contains(raw, "hunter2") {
+		t.Error("persisted snapshot contains sensitive output")
+	}
+
+	snapshot, err := m.GetEnvironmentSnapshot(ctx, "aws-us-east", "production", 1)
+	if err != nil {
+		t.Fatalf("GetEnvironmentSnapshot failed: %v", err)
+	}
+	if got := snapshot.State.Components["api"].Resources["database.main"].Outputs["password"]; got != "hunter2" {
+		t.Errorf("password: got %v", got)
+	}
+}
+
+func TestEnvironmentHistory_TakenVersion(t *testing.T) {
+	b, _ := local.NewBackend(map[string]string{"path": t.TempDir()})
+	m := NewManager(&staleListBackend{Backend: b})
+	ctx := context.Background()
+
+	if err := m.SaveEnvironment(ctx, "dc", &types.EnvironmentState{Name: "production"}); err != nil {
+		t.Fatalf("SaveEnvironment failed: %v", err)
+	}
+	taken := `{"version": 1, "operation": "deploy", "who": "bob", "state": {"name": "production"}}`
+	_ = b.Write(ctx, snapshotPath("dc", "production", 1), strings.NewReader(taken))
+
+	info, err := m.SnapshotEnvironment(ctx, "dc", "production", types.EnvironmentSnapshotInfo{Operation: "destroy"})
+	if err != nil {
+		t.Fatalf("SnapshotEnvironment failed: %v", err)
+	}
+	if info.Version != 2 {
+		t.Errorf("expected the next free version, got %d", info.Version)
+	}
+	if readRaw(t, b, snapshotPath("dc", "production", 1)) != taken {
+		t.Error("expected the existing snapshot to be left as it was")
+	}
+}
+
+func TestEnvironmentHistory_Retention(t *testing.T) {
+	b, _ := local.NewBackend(map[string]string{"path": t.TempDir()})
+	m := NewManager(b, WithHistoryLimit(2))
+	ctx := context.Background()
+
+	if err := m.SaveEnvironment(ctx, "dc", &types.EnvironmentState{Name: "production"}); err != nil {
+		t.Fatalf("SaveEnvironment failed: %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		if _, err := m.SnapshotEnvironment(ctx, "dc", "production", types.EnvironmentSnapshotInfo{Operation: "deploy"}); err != nil {
+			t.Fatalf("SnapshotEnvironment failed: %v", err)
+		}
+	}
+
+	history, err := m.ListEnvironmentHistory(ctx, "dc", "production")
+	if err != nil {
+		t.Fatalf("ListEnvironmentHistory failed: %v", err)
+	}
+	if len(history) != 2 || history[0].Version != 2 || history[1].Version != 3 {
+		t.Errorf("expected the two latest snapshots, got %+v", history)
+	}
+	if _, err := m.GetEnvironmentSnapshot(ctx, "dc", "production", 1); !errors.Is(err, ErrSnapshotNotFound) {
+		t.Errorf("expected the oldest snapshot to be removed, got %v", err)
+	}
+}
+
+func TestEnvironmentHistory_LegacySerial(t *testing.T) {
+	b, _ := local.NewBackend(map[string]string{"path": t.TempDir()})
+	m := NewManager(b)
+	ctx := context.Background()
+
+	// Snapshots used to record their number as "serial"
+	legacy := `{"serial": 4, "operation": "deploy", "state": {"name": "production", "serial": 9}}`
+	_ = b.Write(ctx, snapshotPath("dc", "production", 4), strings.NewReader(legacy))
+
+	history, err := m.ListEnvironmentHistory(ctx, "dc", "production")
+	if err != nil {
+		t.Fatalf("ListEnvironmentHistory failed: %v", err)
+	}
+	if len(history) != 1 || history[0].Version != 4 {
+		t.Errorf("unexpected history: %+v", history)
+	}
+
+	snapshot, err := m.GetEnvironmentSnapshot(ctx, "dc", "production", 4)
+	if err != nil {
+		t.Fatalf("GetEnvironmentSnapshot failed: %v", err)
+	}
+	if snapshot.Version != 4 || snapshot.State.Serial != 9 {
+		t.Errorf("unexpected snapshot: version %d, state serial %d", snapshot.Version, snapshot.State.Serial)
+	}
+}
@@ -0,0 +1,152 @@
+package state
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/davidthor/arcctl/pkg/state/backend"
+)
+
+// ErrLockNotFound is returned when a lock to be released doesn't exist.
+var ErrLockNotFound = errors.New("lock not found")
+
+// LockSetTTL bounds how long the locks taken by LockAll block other
+// operations if cldctl exits without releasing them.
+const LockSetTTL = time.Hour
+
+// LockAll takes a lock for each scope in order, using lock, with LockSetTTL
+// as the lock lifetime. If any lock can't be taken, the locks already held
+// are released. The returned function releases all of them.
+func LockAll(ctx context.Context, lock func(context.Context, LockScope) (backend.Lock, error), scopes ...LockScope) (func(), error) {
+	var locks []backend.Lock
+	release := func() {
+		for _, l := range locks {
+			if l != nil {
+				_ = l.Unlock(ctx)
+			}
+		}
+	}
+
+	for _, scope := range scopes {
+		scope.TTL = LockSetTTL
+		l, err := lock(ctx, scope)
+		if err != nil {
+			release()
+			if scope.Environment == "" {
+				return nil, fmt.Errorf("failed to lock datacenter %s: %w", scope.Datacenter, err)
+			}
+			return nil, fmt.Errorf("failed to lock environment %s: %w", scope.Environment, err)
+		}
+		locks = append(locks, l)
+	}
+
+	return release, nil
+}
+
+// Lock inspection
+
+func (m *manager) ListLocks(ctx context.Context, datacenter string) ([]backend.LockInfo, error) {
+	prefix := "datacenters/"
+	if datacenter != "" {
+		prefix = path.Join("datacenters", datacenter) + "/"
+	}
+	locks, err := m.readLocks(ctx, prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	infos := make([]backend.LockInfo, 0, len(locks))
+	for _, info := range locks {
+		infos = append(infos, info)
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Path < infos[j].Path })
+	return infos, nil
+}
+
+func (m *manager) ListEnvironmentLocks(ctx context.Context, datacenter, environment string) ([]backend.LockInfo, error) {
+	envLock := lockPath(LockScope{Datacenter: datacenter, Environment: environment})
+
+	locks, err := m.ListLocks(ctx, datacenter)
+	if err != nil {
+		return nil, err
+	}
+
+	// Both the environment lock and its component locks
+	var infos []backend.LockInfo
+	for _, info := range locks {
+		if info.Path == envLock || strings.HasPrefix(info.Path, envLock+"/") {
+			infos = append(infos, info)
+		}
+	}
+	return infos, nil
+}
+
+func (m *manager) ForceUnlock(ctx context.Context, lockID string) (*backend.LockInfo, error) {
+	locks, err := m.readLocks(ctx, "datacenters/")
+	if err != nil {
+		return nil, err
+	}
+
+	for file, info := range locks {
+		if info.ID != lockID {
+			continue
+		}
+		if unlocker, ok := m.backend.(backend.ForceUnlocker); ok {
+			err = unlocker.ForceUnlock(ctx, info.Path)
+		} else {
+			err = m.backend.Delete(ctx, file)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to remove lock %s: %w", lockID, err)
+		}
+		return &info, nil
+	}
+
+	return nil, fmt.Errorf("%w: %s", ErrLockNotFound, lockID)
+}
+
+// readLocks returns the locks held under prefix, keyed by the path of the
+// lock file.
+func (m *manager) readLocks(ctx context.Context, prefix string) (map[string]backend.LockInfo, error) {
+	paths, err := m.backend.List(ctx, prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	locks := make(map[string]backend.LockInfo)
+	for _, p := range paths {
+		if !strings.HasSuffix(p, ".lock") {
+			continue
+		}
+		info, err := readJSON[backend.LockInfo](ctx, m.backend, p)
+		if err != nil {
+			continue // Released while listing, or not a lock file
+		}
+		locks[p] = *info
+	}
+	return locks, nil
+}
+
+// ParseLockPath returns the datacenter, environment, and (for component locks)
+// component that a lock's path refers to. Datacenter locks have no
+// environment.
+func ParseLockPath(p string) (LockScope, bool) {
+	parts := splitPath(p)
+	if len(parts) == 3 && parts[0] == "datacenters" && parts[2] == "datacenter" {
+		return LockScope{Datacenter: parts[1]}, true
+	}
+	if len(parts) < 4 || len(parts) > 5 || parts[0] != "datacenters" || parts[2] != "environments" {
+		return LockScope{}, false
+	}
+
+	scope := LockScope{Datacenter: parts[1], Environment: parts[3]}
+	if len(parts) == 5 {
+		scope.Component = parts[4]
+	}
+	return scope, true
+}
@@ -0,0 +1,146 @@
+package state
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/davidthor/arcctl/pkg/state/backend"
+	"github.com/davidthor/arcctl/pkg/state/backend/local"
+)
+
+func TestListLocks(t *testing.T) {
+	m, cleanup := createTestManager(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	envLock, err := m.Lock(ctx, LockScope{Datacenter: "dc1", Environment: "production", Operation: "deploy", Who: "ci"})
+	if err != nil {
+		t.Fatalf("Lock failed: %v", err)
+	}
+	_, err = m.Lock(ctx, LockScope{Datacenter: "dc1", Environment: "production", Component: "api", Operation: "destroy", Who: "alice"})
+	if err != nil {
+		t.Fatalf("Lock failed: %v", err)
+	}
+	_, err = m.Lock(ctx, LockScope{Datacenter: "dc2", Environment: "staging", Operation: "deploy", Who: "bob"})
+	if err != nil {
+		t.Fatalf("Lock failed: %v", err)
+	}
+
+	all, err := m.ListLocks(ctx, "")
+	if err != nil {
+		t.Fatalf("ListLocks failed: %v", err)
+	}
+	if len(all) != 3 {
+		t.Fatalf("expected 3 locks, got %d: %+v", len(all), all)
+	}
+
+	dc1, _ := m.ListLocks(ctx, "dc1")
+	if len(dc1) != 2 {
+		t.Errorf("expected 2 locks in dc1, got %d", len(dc1))
+	}
+
+	env, err := m.ListEnvironmentLocks(ctx, "dc1", "production")
+	if err != nil {
+		t.Fatalf("ListEnvironmentLocks failed: %v", err)
+	}
+	if len(env) != 2 || env[0].ID != envLock.ID() || env[1].Who != "alice" {
+		t.Errorf("unexpected environment locks: %+v", env)
+	}
+
+	// Released locks are no longer listed
+	_ = envLock.Unlock(ctx)
+	env, _ = m.ListEnvironmentLocks(ctx, "dc1", "production")
+	if len(env) != 1 {
+		t.Errorf("expected 1 lock after unlock, got %d", len(env))
+	}
+}
+
+func TestForceUnlock(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+
+	// The lock is held by another process
+	holderBackend, _ := local.NewBackend(map[string]string{"path": dir})
+	holder := NewManager(holderBackend)
+	lock, err := holder.Lock(ctx, LockScope{Datacenter: "dc", Environment: "production", Operation: "deploy", Who: "ci"})
+	if err != nil {
+		t.Fatalf("Lock failed: %v", err)
+	}
+
+	b, _ := local.NewBackend(map[string]string{"path": dir})
+	m := NewManager(b)
+
+	_, err = m.Lock(ctx, LockScope{Datacenter: "dc", Environment: "production"})
+	if !errors.Is(err, backend.ErrLocked) {
+		t.Fatalf("expected ErrLocked, got %v", err)
+	}
+
+	info, err := m.ForceUnlock(ctx, lock.ID())
+	if err != nil {
+		t.Fatalf("ForceUnlock failed: %v", err)
+	}
+	if info.Who != "ci" || info.Operation != "deploy" {
+		t.Errorf("unexpected lock info: %+v", info)
+	}
+
+	if _, err := m.Lock(ctx, LockScope{Datacenter: "dc", Environment: "production"}); err != nil {
+		t.Errorf("expected lock to be available after force unlock, got %v", err)
+	}
+
+	_, err = m.ForceUnlock(ctx, "does-not-exist")
+	if !errors.Is(err, ErrLockNotFound) {
+		t.Errorf("expected ErrLockNotFound, got %v", err)
+	}
+}
+
+func TestLock_ExpiredTakeover(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+
+	holderBackend, _ := local.NewBackend(map[string]string{"path": dir})
+	holder := NewManager(holderBackend)
+	_, err := holder.Lock(ctx, LockScope{Datacenter: "dc", Environment: "production", Who: "ci", TTL: time.Millisecond})
+	if err != nil {
+		t.Fatalf("Lock failed: %v", err)
+	}
+	time.Sleep(5 * time.Millisecond)
+
+	b, _ := local.NewBackend(map[string]string{"path": dir})
+	m := NewManager(b)
+
+	locks, _ := m.ListLocks(ctx, "dc")
+	if len(locks) != 1 || !locks[0].Expired(time.Now()) {
+		t.Fatalf("expected one expired lock, got %+v", locks)
+	}
+
+	lock, err := m.Lock(ctx, LockScope{Datacenter: "dc", Environment: "production", Who: "alice"})
+	if err != nil {
+		t.Fatalf("expected expired lock to be taken over, got %v", err)
+	}
+	if lock.Info().Who != "alice" {
+		t.Errorf("expected new lock holder, got %q", lock.Info().Who)
+	}
+}
+
+func TestParseLockPath(t *testing.T) {
+	scope, ok := ParseLockPath("datacenters/dc/environments/production")
+	if !ok || scope.Datacenter != "dc" || scope.Environment != "production" || scope.Component != "" {
+		t.Errorf("unexpected environment scope: %+v", scope)
+	}
+
+	scope, ok = ParseLockPath("datacenters/dc/environments/production/api")
+	if !ok || scope.Component != "api" {
+		t.Errorf("unexpected component scope: %+v", scope)
+	}
+
+	scope, ok = ParseLockPath("datacenters/dc/datacenter")
+	if !ok || scope.Datacenter != "dc" || scope.Environment != "" {
+		t.Errorf("unexpected datacenter scope: %+v", scope)
+	}
+
+	if _, ok := ParseLockPath("datacenters/dc"); ok {
+		t.Error("expected path without a scope not to parse")
+	}
+}
@@ -2,14 +2,15 @@
 package state
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"path"
+	"time"
 
 	"github.com/davidthor/arcctl/pkg/state/backend"
+	"github.com/davidthor/arcctl/pkg/state/encryption"
 	"github.com/davidthor/arcctl/pkg/state/types"
 )
 
@@ -33,6 +34,11 @@ type Manager interface {
 	SaveEnvironment(ctx context.Context, datacenter string, state *types.EnvironmentState) error
 	DeleteEnvironment(ctx context.Context, datacenter, name string) error
 
+	// Environment history operations
+	SnapshotEnvironment(ctx context.Context, datacenter, name string, info types.EnvironmentSnapshotInfo) (*types.EnvironmentSnapshotInfo, error)
+	ListEnvironmentHistory(ctx context.Context, datacenter, name string) ([]types.EnvironmentSnapshotInfo, error)
+	GetEnvironmentSnapshot(ctx context.Context, datacenter, name string, version int) (*types.EnvironmentSnapshot, error)
+
 	// Component operations (environment-scoped)
 	GetComponent(ctx context.Context, dc, env, component string) (*types.ComponentState, error)
 	SaveComponent(ctx context.Context, dc, env string, state *types.ComponentState) error
@@ -45,6 +51,13 @@ type Manager interface {
 
 	// Locking
 	Lock(ctx context.Context, scope LockScope) (backend.Lock, error)
+	ListLocks(ctx context.Context, datacenter string) ([]backend.LockInfo, error)
+	ListEnvironmentLocks(ctx context.Context, datacenter, environment string) ([]backend.LockInfo, error)
+	ForceUnlock(ctx context.Context, lockID string) (*backend.LockInfo, error)
+
+	// Sensitive values held outside the state, such as in saved plans
+	SealSensitiveValue(ctx context.Context, v interface{}) (interface{}, error)
+	OpenSensitiveValue(ctx context.Context, v interface{}) (interface{}, error)
 
 	// Backend info
 	Backend() backend.Backend
@@ -57,25 +70,66 @@ type LockScope struct {
 	Component   string
 	Operation   string
 	Who         string
+
+	// TTL sets when the lock expires, after which another process may take it
+	// over. Locks without a TTL expire after backend.DefaultLockTimeout.
+	TTL time.Duration
 }
 
 // manager implements the Manager interface.
 type manager struct {
-	backend backend.Backend
+	backend        backend.Backend
+	encrypter      encryption.Encrypter
+	allowPlaintext bool
+	documents      documentCache
+	historyLimit   int
+}
+
+// Option configures a state manager.
+type Option func(*manager)
+
+// WithEncrypter encrypts sensitive variables, sensitive outputs, and IaC
+// state blobs at rest using the given encrypter.
+func WithEncrypter(enc encryption.Encrypter) Option {
+	return func(m *manager) {
+		m.encrypter = enc
+	}
+}
+
+// WithPlaintextState stores sensitive values and IaC state blobs in plain
+// text when no encrypter is configured. Without it, saving state that holds
+// them fails with encryption.ErrKeyRequired.
+func WithPlaintextState() Option {
+	return func(m *manager) {
+		m.allowPlaintext = true
+	}
+}
+
+// WithHistoryLimit sets how many snapshots are kept in each environment's
+// history. Older snapshots are removed as new ones are recorded; a limit of
+// zero or less keeps every snapshot. Defaults to DefaultHistoryLimit.
+func WithHistoryLimit(limit int) Option {
+	return func(m *manager) {
+		m.historyLimit = limit
+	}
 }
 
 // NewManager creates a new state manager with the given backend.
-func NewManager(b backend.Backend) Manager {
-	return &manager{backend: b}
+func NewManager(b backend.Backend, opts ...Option) Manager {
+	m := &manager{backend: b, historyLimit: DefaultHistoryLimit}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
 }
 
 // NewManagerFromConfig creates a new state manager from backend configuration.
-func NewManagerFromConfig(config backend.Config) (Manager, error) {
+func NewManagerFromConfig(config backend.Config, opts ...Option) (Manager, error) {
 	b, err := backend.Create(config)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create backend: %w", err)
 	}
-	return NewManager(b), nil
+	return NewManager(b, opts...), nil
 }
 
 func (m *manager) Backend() backend.Backend {
@@ -86,12 +140,30 @@ func (m *manager) Backend() backend.Backend {
 
 func (m *manager) GetDatacenter(ctx context.Context, name string) (*types.DatacenterState, error) {
 	p := datacenterPath(name)
-	return readJSON[types.DatacenterState](ctx, m.backend, p)
+	state, err := readJSON[types.DatacenterState](ctx, m.backend, p)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.openDatacenter(ctx, state); err != nil {
+		return nil, err
+	}
+	return state, nil
 }
 
 func (m *manager) SaveDatacenter(ctx context.Context, state *types.DatacenterState) error {
 	p := datacenterPath(state.Name)
-	return writeJSON(ctx, m.backend, p, state)
+	sealed, err := m.sealDatacenter(ctx, state)
+	if err != nil {
+		return err
+	}
+
+	record := *sealed
+	if err := writeVersionedJSON(ctx, m.backend, p, "datacenter "+state.Name, &record, &record.Serial, &record.Lineage); err != nil {
+		return err
+	}
+	state.Serial = record.Serial
+	state.Lineage = record.Lineage
+	return nil
 }
 
 func (m *manager) DeleteDatacenter(ctx context.Context, name string) error {
@@ -106,6 +178,7 @@ func (m *manager) DeleteDatacenter(ctx context.Context, name string) error {
 			return fmt.Errorf("failed to delete %s: %w", p, err)
 		}
 	}
+	m.documents.forget(path.Join("datacenters", name))
 
 	return nil
 }
@@ -143,7 +216,7 @@ func (m *manager) GetDatacenterComponent(ctx context.Context, dc, component stri
 
 func (m *manager) SaveDatacenterComponent(ctx context.Context, dc string, state *types.DatacenterComponentConfig) error {
 	p := datacenterComponentPath(dc, state.Name)
-	return writeJSON(ctx, m.backend, p, state)
+	return writeJSON(ctx, m.backend, p, "datacenter component "+dc+"/"+state.Name, state)
 }
 
 func (m *manager) DeleteDatacenterComponent(ctx context.Context, dc, component string) error {
@@ -189,12 +262,14 @@ func (m *manager) ListEnvironments(ctx context.Context, datacenter string) ([]ty
 		}
 	}
 
+	// Only the environment documents are read, not their components
 	var refs []types.EnvironmentRef
 	for name := range names {
-		state, err := m.GetEnvironment(ctx, datacenter, name)
-		if err != nil {
+		doc, err := readJSON[environmentDocument](ctx, m.backend, environmentPath(datacenter, name))
+		if err != nil || doc.EnvironmentState == nil {
 			continue // Skip environments that can't be read
 		}
+		state := doc.EnvironmentState
 		refs = append(refs, types.EnvironmentRef{
 			Name:       state.Name,
 			Datacenter: state.Datacenter,
@@ -207,13 +282,11 @@ func (m *manager) ListEnvironments(ctx context.Context, datacenter string) ([]ty
 }
 
 func (m *manager) GetEnvironment(ctx context.Context, datacenter, name string) (*types.EnvironmentState, error) {
-	p := environmentPath(datacenter, name)
-	return readJSON[types.EnvironmentState](ctx, m.backend, p)
+	return m.loadEnvironment(ctx, datacenter, name)
 }
 
 func (m *manager) SaveEnvironment(ctx context.Context, datacenter string, state *types.EnvironmentState) error {
-	p := environmentPath(datacenter, state.Name)
-	return writeJSON(ctx, m.backend, p, state)
+	return m.storeEnvironment(ctx, datacenter, state)
 }
 
 func (m *manager) DeleteEnvironment(ctx context.Context, datacenter, name string) error {
@@ -228,71 +301,138 @@ func (m *manager) DeleteEnvironment(ctx context.Context, datacenter, name string
 			return fmt.Errorf("failed to delete %s: %w", p, err)
 		}
 	}
+	m.documents.forget(path.Join("datacenters", datacenter, "environments", name))
 
 	return nil
 }
 
 // Component operations
 
+// GetComponent reads a component and the resources stored for it, without
+// reading the rest of its environment.
 func (m *manager) GetComponent(ctx context.Context, dc, env, component string) (*types.ComponentState, error) {
-	p := componentPath(dc, env, component)
-	return readJSON[types.ComponentState](ctx, m.backend, p)
-}
+	doc, err := readJSON[componentDocument](ctx, m.backend, componentPath(dc, env, component))
+	if err != nil {
+		return nil, err
+	}
 
-func (m *manager) SaveComponent(ctx context.Context, dc, env string, state *types.ComponentState) error {
-	p := componentPath(dc, env, state.Name)
-	return writeJSON(ctx, m.backend, p, state)
+	// The resources stored for the component are those it summarizes, or
+	// for components stored before it did, those with a document
+	var keys []string
+	if doc.Summaries != nil {
+		for key := range doc.Summaries {
+			keys = append(keys, key)
+		}
+	} else if keys, err = m.componentResourceKeys(ctx, dc, env, component); err != nil {
+		return nil, err
+	}
+
+	// Resources deleted while listing are left out
+	read, err := m.readComponent(ctx, dc, env, component, keys, func(string) error { return nil })
+	if err != nil {
+		return nil, err
+	}
+	if err := m.openComponent(ctx, read.state); err != nil {
+		return nil, err
+	}
+	if err := m.cacheComponent(ctx, dc, env, component, read); err != nil {
+		return nil, err
+	}
+	return read.state, nil
 }
 
-func (m *manager) DeleteComponent(ctx context.Context, dc, env, component string) error {
-	// Delete all state under the component
-	paths, err := m.backend.List(ctx, path.Join("datacenters", dc, "environments", env, "components", component))
+// SaveComponent writes a component and its resources, replacing the resources
+// stored for it. It doesn't add the component to its environment's index;
+// components are added to an environment by saving the environment.
+func (m *manager) SaveComponent(ctx context.Context, dc, env string, state *types.ComponentState) error {
+	stored, err := m.componentResourceKeys(ctx, dc, env, state.Name)
 	if err != nil {
 		return err
 	}
 
-	for _, p := range paths {
-		if err := m.backend.Delete(ctx, p); err != nil {
-			return fmt.Errorf("failed to delete %s: %w", p, err)
-		}
+	if _, err := m.writeComponent(ctx, dc, env, state.Name, state, &storedIndex{}); err != nil {
+		return err
 	}
 
+	for _, key := range stored {
+		if state.Resources[key] != nil {
+			continue
+		}
+		rp := resourcePath(dc, env, state.Name, key)
+		if err := m.backend.Delete(ctx, rp); err != nil {
+			return err
+		}
+		m.documents.forget(rp)
+	}
 	return nil
 }
 
+func (m *manager) DeleteComponent(ctx context.Context, dc, env, component string) error {
+	// Delete all state under the component
+	return m.removeComponent(ctx, dc, env, component)
+}
+
 // Resource operations
 
 func (m *manager) GetResource(ctx context.Context, dc, env, component, resource string) (*types.ResourceState, error) {
 	p := resourcePath(dc, env, component, resource)
-	return readJSON[types.ResourceState](ctx, m.backend, p)
+	doc, version, err := readJSONVersion[resourceDocument](ctx, m.backend, p)
+	if err != nil {
+		return nil, err
+	}
+	state := doc.ResourceState
+	if state == nil {
+		state = &types.ResourceState{}
+	}
+	if err := m.openResource(ctx, state); err != nil {
+		return nil, err
+	}
+	digest, err := resourceDigest(state)
+	if err != nil {
+		return nil, err
+	}
+	m.documents.store(p, cachedDocument{digest: digest, serial: doc.Serial, version: version})
+	return state, nil
 }
 
+// SaveResource writes a resource's document. The component's summary of the
+// resource is updated by saving the component or its environment.
 func (m *manager) SaveResource(ctx context.Context, dc, env, component string, state *types.ResourceState) error {
 	// Use type-qualified key for the file path to avoid collisions
 	key := state.Type + "." + state.Name
-	p := resourcePath(dc, env, component, key)
-	return writeJSON(ctx, m.backend, p, state)
+	if _, err := state.LoadIaCState(); err != nil {
+		return err
+	}
+	digest, err := resourceDigest(state)
+	if err != nil {
+		return err
+	}
+	return m.storeResource(ctx, dc, env, component, key, state, digest, false)
 }
 
 func (m *manager) DeleteResource(ctx context.Context, dc, env, component, resource string) error {
 	p := resourcePath(dc, env, component, resource)
+	m.documents.forget(p)
 	return m.backend.Delete(ctx, p)
 }
 
 // Locking
 
 func (m *manager) Lock(ctx context.Context, scope LockScope) (backend.Lock, error) {
-	lockPath := path.Join("datacenters", scope.Datacenter, "environments", scope.Environment)
-	if scope.Component != "" {
-		lockPath = path.Join(lockPath, scope.Component)
-	}
+	return lockScope(ctx, m.backend, scope)
+}
 
+// lockScope takes the lock for scope on b.
+func lockScope(ctx context.Context, b backend.Backend, scope LockScope) (backend.Lock, error) {
 	info := backend.LockInfo{
 		Who:       scope.Who,
 		Operation: scope.Operation,
 	}
+	if scope.TTL > 0 {
+		info.Expires = time.Now().Add(scope.TTL)
+	}
 
-	return m.backend.Lock(ctx, lockPath, info)
+	return b.Lock(ctx, lockPath(scope), info)
 }
 
 // Path helpers
@@ -317,6 +457,19 @@ func resourcePath(dc, env, component, resource string) string {
 	return path.Join("datacenters", dc, "environments", env, "components", component, "resources", resource+".state.json")
 }
 
+// lockPath returns the path locked for a scope. A scope without an
+// environment locks the datacenter itself.
+func lockPath(scope LockScope) string {
+	if scope.Environment == "" {
+		return path.Join("datacenters", scope.Datacenter, "datacenter")
+	}
+	p := path.Join("datacenters", scope.Datacenter, "environments", scope.Environment)
+	if scope.Component != "" {
+		p = path.Join(p, scope.Component)
+	}
+	return p
+}
+
 func splitPath(p string) []string {
 	var parts []string
 	for p != "" && p != "." && p != "/" {
@@ -331,29 +484,48 @@ func splitPath(p string) []string {
 
 // JSON helpers
 
+// readJSON reads the document at p, upgrading state documents written in an
+// older format to the current one.
 func readJSON[T any](ctx context.Context, b backend.Backend, p string) (*T, error) {
-	reader, err := b.Read(ctx, p)
+	result, _, err := readJSONVersion[T](ctx, b, p)
+	return result, err
+}
+
+// readJSONVersion reads the document at p like readJSON, along with the
+// backend's version token for it.
+func readJSONVersion[T any](ctx context.Context, b backend.Backend, p string) (*T, string, error) {
+	reader, version, err := b.ReadVersion(ctx, p)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 	defer reader.Close()
 
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read %s: %w", p, err)
+	}
+	if kind := archiveKind(p); kind != ArchiveKindOther {
+		if data, err = upgradeDocument(kind, data); err != nil {
+			return nil, "", err
+		}
+	}
+
 	var result T
-	if err := json.NewDecoder(reader).Decode(&result); err != nil {
-		return nil, fmt.Errorf("failed to decode JSON: %w", err)
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, "", fmt.Errorf("failed to decode JSON: %w", err)
 	}
 
-	return &result, nil
+	return &result, version, nil
 }
 
-func writeJSON(ctx context.Context, b backend.Backend, p string, data interface{}) error {
-	content, err := json.MarshalIndent(data, "", "  ")
+// writeJSON writes a state document to p in the current format, refusing to
+// overwrite a document written by a newer version of cldctl. The write is
+// conditional on the stored revision whose format was checked.
+func writeJSON(ctx context.Context, b backend.Backend, p, desc string, data interface{}) error {
+	version, err := checkStoredFormat(ctx, b, p)
 	if err != nil {
-		return fmt.Errorf("failed to encode JSON: %w", err)
+		return err
 	}
-
-	return b.Write(ctx, p, bytes.NewReader(content))
+	_, err = writeIfVersion(ctx, b, p, desc, data, version)
+	return err
 }
-
-// Ensure manager imports are used
-var _ io.Reader = (*bytes.Reader)(nil)
@@ -0,0 +1,500 @@
+package state
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/davidthor/arcctl/pkg/state/encryption"
+	"github.com/davidthor/arcctl/pkg/state/types"
+)
+
+// Sensitive state protection
+//
+// When the manager has an Encrypter, sensitive variables, sensitive inputs,
+// sensitive outputs, and raw IaC state blobs are encrypted before they are
+// written to the backend and decrypted when read. The seal functions never modify their
+// argument since callers keep using their in-memory state after saving.
+//
+// Without an Encrypter, sealing state that holds any of these values fails
+// with encryption.ErrKeyRequired, unless the manager was created with
+// WithPlaintextState.
+
+func (m *manager) sealDatacenter(ctx context.Context, s *types.DatacenterState) (*types.DatacenterState, error) {
+	if m.plaintext() {
+		return s, nil
+	}
+
+	sealed := *s
+	vars, err := m.sealVariables(ctx, s.Variables, s.SensitiveVariables)
+	if err != nil {
+		return nil, err
+	}
+	sealed.Variables = vars
+
+	modules, err := m.sealModules(ctx, s.Modules)
+	if err != nil {
+		return nil, err
+	}
+	sealed.Modules = modules
+
+	return &sealed, nil
+}
+
+func (m *manager) openDatacenter(ctx context.Context, s *types.DatacenterState) error {
+	if err := m.openVariables(ctx, s.Variables); err != nil {
+		return err
+	}
+	for _, mod := range s.Modules {
+		if err := m.openModule(ctx, mod); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *manager) sealEnvironment(ctx context.Context, s *types.EnvironmentState) (*types.EnvironmentState, error) {
+	if m.plaintext() {
+		return s, nil
+	}
+
+	sealed := *s
+	if s.Components != nil {
+		sealed.Components = make(map[string]*types.ComponentState, len(s.Components))
+		for name, comp := range s.Components {
+			sealedComp, err := m.sealComponent(ctx, comp)
+			if err != nil {
+				return nil, err
+			}
+			sealed.Components[name] = sealedComp
+		}
+	}
+
+	modules, err := m.sealModules(ctx, s.Modules)
+	if err != nil {
+		return nil, err
+	}
+	sealed.Modules = modules
+
+	return &sealed, nil
+}
+
+func (m *manager) openEnvironment(ctx context.Context, s *types.EnvironmentState) error {
+	for _, comp := range s.Components {
+		if err := m.openComponent(ctx, comp); err != nil {
+			return err
+		}
+	}
+	for _, mod := range s.Modules {
+		if err := m.openModule(ctx, mod); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *manager) sealComponent(ctx context.Context, s *types.ComponentState) (*types.ComponentState, error) {
+	if m.plaintext() || s == nil {
+		return s, nil
+	}
+
+	sealed := *s
+	vars, err := m.sealVariables(ctx, s.Variables, s.SensitiveVariables)
+	if err != nil {
+		return nil, err
+	}
+	sealed.Variables = vars
+
+	if s.Resources != nil {
+		sealed.Resources = make(map[string]*types.ResourceState, len(s.Resources))
+		for key, res := range s.Resources {
+			sealedRes, err := m.sealResource(ctx, res)
+			if err != nil {
+				return nil, err
+			}
+			sealed.Resources[key] = sealedRes
+		}
+	}
+
+	return &sealed, nil
+}
+
+func (m *manager) openComponent(ctx context.Context, s *types.ComponentState) error {
+	if s == nil {
+		return nil
+	}
+	if err := m.openVariables(ctx, s.Variables); err != nil {
+		return err
+	}
+	for _, res := range s.Resources {
+		if err := m.openResource(ctx, res); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *manager) sealResource(ctx context.Context, s *types.ResourceState) (*types.ResourceState, error) {
+	if m.plaintext() || s == nil {
+		return s, nil
+	}
+
+	sealed := *s
+	inputs, err := m.sealInputs(ctx, s.Inputs, s.SensitiveInputs)
+	if err != nil {
+		return nil, err
+	}
+	sealed.Inputs = inputs
+
+	outputs, err := m.sealOutputs(ctx, s.Outputs, s.SensitiveOutputs)
+	if err != nil {
+		return nil, err
+	}
+	sealed.Outputs = outputs
+
+	iacState, err := m.sealIaCState(ctx, s.IaCState)
+	if err != nil {
+		return nil, err
+	}
+	sealed.IaCState = iacState
+
+	return &sealed, nil
+}
+
+func (m *manager) openResource(ctx context.Context, s *types.ResourceState) error {
+	if s == nil {
+		return nil
+	}
+	if err := m.openInputs(ctx, s.Inputs); err != nil {
+		return err
+	}
+	if err := m.openOutputs(ctx, s.Outputs); err != nil {
+		return err
+	}
+	iacState, err := m.openIaCState(ctx, s.IaCState)
+	if err != nil {
+		return err
+	}
+	s.IaCState = iacState
+	return nil
+}
+
+func (m *manager) sealModules(ctx context.Context, modules map[string]*types.ModuleState) (map[string]*types.ModuleState, error) {
+	if modules == nil {
+		return nil, nil
+	}
+
+	sealed := make(map[string]*types.ModuleState, len(modules))
+	for name, mod := range modules {
+		if mod == nil {
+			sealed[name] = nil
+			continue
+		}
+
+		sealedMod := *mod
+		inputs, err := m.sealInputs(ctx, mod.Inputs, mod.SensitiveInputs)
+		if err != nil {
+			return nil, err
+		}
+		sealedMod.Inputs = inputs
+
+		outputs, err := m.sealOutputs(ctx, mod.Outputs, mod.SensitiveOutputs)
+		if err != nil {
+			return nil, err
+		}
+		sealedMod.Outputs = outputs
+
+		iacState, err := m.sealIaCState(ctx, mod.IaCState)
+		if err != nil {
+			return nil, err
+		}
+		sealedMod.IaCState = iacState
+
+		sealed[name] = &sealedMod
+	}
+	return sealed, nil
+}
+
+func (m *manager) openModule(ctx context.Context, s *types.ModuleState) error {
+	if s == nil {
+		return nil
+	}
+	if err := m.openInputs(ctx, s.Inputs); err != nil {
+		return err
+	}
+	if err := m.openOutputs(ctx, s.Outputs); err != nil {
+		return err
+	}
+	iacState, err := m.openIaCState(ctx, s.IaCState)
+	if err != nil {
+		return err
+	}
+	s.IaCState = iacState
+	return nil
+}
+
+// sealVariables returns a copy of vars with the sensitive values encrypted.
+func (m *manager) sealVariables(ctx context.Context, vars map[string]string, sensitive []string) (map[string]string, error) {
+	if len(vars) == 0 || len(sensitive) == 0 {
+		return vars, nil
+	}
+
+	sealed := make(map[string]string, len(vars))
+	for k, v := range vars {
+		sealed[k] = v
+	}
+	for _, name := range sensitive {
+		v, ok := vars[name]
+		if !ok || encryption.IsEncrypted([]byte(v)) {
+			continue
+		}
+		enc, err := m.encrypt(ctx, []byte(v))
+		if err != nil {
+			return nil, fmt.Errorf("failed to encrypt variable %s: %w", name, err)
+		}
+		sealed[name] = string(enc)
+	}
+	return sealed, nil
+}
+
+func (m *manager) openVariables(ctx context.Context, vars map[string]string) error {
+	for k, v := range vars {
+		if !encryption.IsEncrypted([]byte(v)) {
+			continue
+		}
+		plaintext, err := m.decrypt(ctx, []byte(v))
+		if err != nil {
+			return fmt.Errorf("failed to decrypt variable %s: %w", k, err)
+		}
+		vars[k] = string(plaintext)
+	}
+	return nil
+}
+
+// sealInputs returns a copy of inputs with the sensitive values encrypted.
+// A sensitive input is named either as a top-level input or as
+// "<input>.<key>" for a key of an input that is a map, in which case the map
+// is copied and only that key's value is encrypted. Values are JSON encoded
+// before encryption, as outputs are.
+func (m *manager) sealInputs(ctx context.Context, inputs map[string]interface{}, sensitive []string) (map[string]interface{}, error) {
+	if len(inputs) == 0 || len(sensitive) == 0 {
+		return inputs, nil
+	}
+
+	sealed := make(map[string]interface{}, len(inputs))
+	for k, v := range inputs {
+		sealed[k] = v
+	}
+	for _, name := range sensitive {
+		if v, ok := inputs[name]; ok {
+			enc, err := m.sealValue(ctx, v)
+			if err != nil {
+				return nil, fmt.Errorf("failed to encrypt input %s: %w", name, err)
+			}
+			sealed[name] = enc
+			continue
+		}
+
+		input, key, ok := strings.Cut(name, ".")
+		if !ok {
+			continue
+		}
+		values, ok := inputMap(sealed[input])
+		if !ok {
+			continue
+		}
+		v, ok := values[key]
+		if !ok {
+			continue
+		}
+		enc, err := m.sealValue(ctx, v)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encrypt input %s: %w", name, err)
+		}
+		copied := make(map[string]interface{}, len(values))
+		for k, v := range values {
+			copied[k] = v
+		}
+		copied[key] = enc
+		sealed[input] = copied
+	}
+	return sealed, nil
+}
+
+// inputMap returns an input that is a map as a map[string]interface{}.
+func inputMap(v interface{}) (map[string]interface{}, bool) {
+	switch values := v.(type) {
+	case map[string]interface{}:
+		return values, true
+	case map[string]string:
+		converted := make(map[string]interface{}, len(values))
+		for k, v := range values {
+			converted[k] = v
+		}
+		return converted, true
+	}
+	return nil, false
+}
+
+// openInputs decrypts the encrypted values of inputs, including those held
+// in inputs that are maps.
+func (m *manager) openInputs(ctx context.Context, inputs map[string]interface{}) error {
+	for k, v := range inputs {
+		if values, ok := v.(map[string]interface{}); ok {
+			for key, value := range values {
+				opened, err := m.openValue(ctx, value)
+				if err != nil {
+					return fmt.Errorf("failed to decrypt input %s.%s: %w", k, key, err)
+				}
+				values[key] = opened
+			}
+			continue
+		}
+		opened, err := m.openValue(ctx, v)
+		if err != nil {
+			return fmt.Errorf("failed to decrypt input %s: %w", k, err)
+		}
+		inputs[k] = opened
+	}
+	return nil
+}
+
+// SealSensitiveValue encrypts a sensitive value that is stored outside the
+// state, the way sensitive inputs are stored. Without an encrypter, the value
+// is returned as is if the manager stores state in plain text, and
+// encryption.ErrKeyRequired is returned otherwise.
+func (m *manager) SealSensitiveValue(ctx context.Context, v interface{}) (interface{}, error) {
+	if m.plaintext() {
+		return v, nil
+	}
+	return m.sealValue(ctx, v)
+}
+
+// OpenSensitiveValue decrypts a value sealed by SealSensitiveValue. Values
+// that aren't encrypted are returned as they are.
+func (m *manager) OpenSensitiveValue(ctx context.Context, v interface{}) (interface{}, error) {
+	return m.openValue(ctx, v)
+}
+
+// sealValue JSON encodes and encrypts a value.
+func (m *manager) sealValue(ctx context.Context, v interface{}) (string, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	enc, err := m.encrypt(ctx, data)
+	if err != nil {
+		return "", err
+	}
+	return string(enc), nil
+}
+
+// openValue decrypts and decodes a value sealed by sealValue. Values that
+// aren't encrypted are returned as they are.
+func (m *manager) openValue(ctx context.Context, v interface{}) (interface{}, error) {
+	s, ok := v.(string)
+	if !ok || !encryption.IsEncrypted([]byte(s)) {
+		return v, nil
+	}
+	data, err := m.decrypt(ctx, []byte(s))
+	if err != nil {
+		return nil, err
+	}
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return nil, fmt.Errorf("failed to decode value: %w", err)
+	}
+	return value, nil
+}
+
+// sealOutputs returns a copy of outputs with the sensitive values encrypted.
+// Values are JSON encoded before encryption so non-string outputs round-trip.
+func (m *manager) sealOutputs(ctx context.Context, outputs map[string]interface{}, sensitive []string) (map[string]interface{}, error) {
+	if len(outputs) == 0 || len(sensitive) == 0 {
+		return outputs, nil
+	}
+
+	sealed := make(map[string]interface{}, len(outputs))
+	for k, v := range outputs {
+		sealed[k] = v
+	}
+	for _, name := range sensitive {
+		v, ok := outputs[name]
+		if !ok {
+			continue
+		}
+		data, err := json.Marshal(v)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode output %s: %w", name, err)
+		}
+		enc, err := m.encrypt(ctx, data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encrypt output %s: %w", name, err)
+		}
+		sealed[name] = string(enc)
+	}
+	return sealed, nil
+}
+
+func (m *manager) openOutputs(ctx context.Context, outputs map[string]interface{}) error {
+	for k, v := range outputs {
+		s, ok := v.(string)
+		if !ok || !encryption.IsEncrypted([]byte(s)) {
+			continue
+		}
+		data, err := m.decrypt(ctx, []byte(s))
+		if err != nil {
+			return fmt.Errorf("failed to decrypt output %s: %w", k, err)
+		}
+		var value interface{}
+		if err := json.Unmarshal(data, &value); err != nil {
+			return fmt.Errorf("failed to decode output %s: %w", k, err)
+		}
+		outputs[k] = value
+	}
+	return nil
+}
+
+// sealIaCState encrypts a raw IaC state blob. Plugin state routinely holds
+// credentials, so blobs are always encrypted.
+func (m *manager) sealIaCState(ctx context.Context, data []byte) ([]byte, error) {
+	if len(data) == 0 || encryption.IsEncrypted(data) {
+		return data, nil
+	}
+	enc, err := m.encrypt(ctx, data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt IaC state: %w", err)
+	}
+	return enc, nil
+}
+
+func (m *manager) openIaCState(ctx context.Context, data []byte) ([]byte, error) {
+	if !encryption.IsEncrypted(data) {
+		return data, nil
+	}
+	plaintext, err := m.decrypt(ctx, data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt IaC state: %w", err)
+	}
+	return plaintext, nil
+}
+
+// plaintext reports whether state is written without sealing it.
+func (m *manager) plaintext() bool {
+	return m.encrypter == nil && m.allowPlaintext
+}
+
+func (m *manager) encrypt(ctx context.Context, data []byte) ([]byte, error) {
+	if m.encrypter == nil {
+		return nil, encryption.ErrKeyRequired
+	}
+	return m.encrypter.Encrypt(ctx, data)
+}
+
+func (m *manager) decrypt(ctx context.Context, data []byte) ([]byte, error) {
+	if m.encrypter == nil {
+		return nil, encryption.ErrNoEncrypter
+	}
+	return m.encrypter.Decrypt(ctx, data)
+}
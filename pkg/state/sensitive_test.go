@@ -0,0 +1,286 @@
+package state
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/davidthor/arcctl/pkg/state/backend"
+	"github.com/davidthor/arcctl/pkg/state/backend/local"
+	"github.com/davidthor/arcctl/pkg/state/encryption"
+	"github.com/davidthor/arcctl/pkg/state/types"
+)
+
+func createEncryptedTestManager(t *testing.T) (Manager, backend.Backend) {
+	t.Helper()
+
+	b, err := local.NewBackend(map[string]string{"path": t.TempDir()})
+	if err != nil {
+		t.Fatalf("Failed to create backend: %v", err)
+	}
+
+	provider, err := encryption.NewLocalKeyProvider("MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY=")
+	if err != nil {
+		t.Fatalf("Failed to create key provider: %v", err)
+	}
+
+	return NewManager(b, WithEncrypter(encryption.NewEnvelopeEncrypter(provider))), b
+}
+
+func readRaw(t *testing.T, b backend.Backend, p string) string {
+	t.Helper()
+	r, err := b.Read(context.Background(), p)
+	if err != nil {
+		t.Fatalf("Failed to read %s: %v", p, err)
+	}
+	defer r.Close()
+	data, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("Failed to read %s: %v", p, err)
+	}
+	return string(data)
+}
+
+func sensitiveTestEnvironment() *types.EnvironmentState {
+	return &types.EnvironmentState{
+		Name:       "production",
+		Datacenter: "aws-us-east",
+		Components: map[string]*types.ComponentState{
+			"api": {
+				Name:               "api",
+				Variables:          map[string]string{"log_level": "info", "api_key": "key-123"},
+				SensitiveVariables: []string{"api_key"},
+				Resources: map[string]*types.ResourceState{
+					"database.main": {
+						Name:             "main",
+						Type:             "database",
+						Outputs:          map[string]interface{}{"host": "db.internal", "password": "hunter2", "port": float64(5432)},
+						SensitiveOutputs: []string{"password"},
+						IaCState:         []byte(`{"password":"hunter2"}`),
+					},
+				},
+			},
+		},
+	}
+}
+
+func TestSensitiveState_EncryptedAtRest(t *testing.T) {
+	m, b := createEncryptedTestManager(t)
+	ctx := context.Background()
+
+	env := sensitiveTestEnvironment()
+	if err := m.SaveEnvironment(ctx, "aws-us-east", env); err != nil {
+		t.Fatalf("SaveEnvironment failed: %v", err)
+	}
+
+	// Components and resources are stored in their own documents
+	raw := readRaw(t, b, environmentPath("aws-us-east", "production")) +
+		readRaw(t, b, componentPath("aws-us-east", "production", "api")) +
+		readRaw(t, b, resourcePath("aws-us-east", "production", "api", "database.main"))
+	for _, secret := range []string{"hunter2", "key-123"} {
+		if strings.Contains(raw, secret) {
+			t.Errorf("persisted state contains %q", secret)
+		}
+	}
+	if !strings.Contains(raw, "db.internal") || !strings.Contains(raw, "info") {
+		t.Error("non-sensitive values should be stored in plain text")
+	}
+
+	// The caller's state must not be modified
+	res := env.Components["api"].Resources["database.main"]
+	if res.Outputs["password"] != "hunter2" || string(res.IaCState) != `{"password":"hunter2"}` {
+		t.Error("SaveEnvironment modified the in-memory state")
+	}
+
+	retrieved, err := m.GetEnvironment(ctx, "aws-us-east", "production")
+	if err != nil {
+		t.Fatalf("GetEnvironment failed: %v", err)
+	}
+	comp := retrieved.Components["api"]
+	if comp.Variables["api_key"] != "key-123" {
+		t.Errorf("api_key: got %q", comp.Variables["api_key"])
+	}
+	res = comp.Resources["database.main"]
+	if res.Outputs["password"] != "hunter2" {
+		t.Errorf("password: got %v", res.Outputs["password"])
+	}
+	if res.Outputs["port"] != float64(5432) {
+		t.Errorf("port: got %v", res.Outputs["port"])
+	}
+	if iacState, err := res.LoadIaCState(); err != nil || string(iacState) != `{"password":"hunter2"}` {
+		t.Errorf("IaCState: got %s, %v", iacState, err)
+	}
+}
+
+func TestSensitiveState_ResourceAndDatacenter(t *testing.T) {
+	m, b := createEncryptedTestManager(t)
+	ctx := context.Background()
+
+	res := sensitiveTestEnvironment().Components["api"].Resources["database.main"]
+	if err := m.SaveResource(ctx, "aws-us-east", "production", "api", res); err != nil {
+		t.Fatalf("SaveResource failed: %v", err)
+	}
+	if raw := readRaw(t, b, resourcePath("aws-us-east", "production", "api", "database.main")); strings.Contains(raw, "hunter2") {
+		t.Error("persisted resource contains sensitive output")
+	}
+	got, err := m.GetResource(ctx, "aws-us-east", "production", "api", "database.main")
+	if err != nil {
+		t.Fatalf("GetResource failed: %v", err)
+	}
+	if got.Outputs["password"] != "hunter2" {
+		t.Errorf("password: got %v", got.Outputs["password"])
+	}
+
+	dc := &types.DatacenterState{
+		Name:               "aws-us-east",
+		Variables:          map[string]string{"region": "us-east-1", "db_password": "hunter2"},
+		SensitiveVariables: []string{"db_password"},
+	}
+	if err := m.SaveDatacenter(ctx, dc); err != nil {
+		t.Fatalf("SaveDatacenter failed: %v", err)
+	}
+	if raw := readRaw(t, b, datacenterPath("aws-us-east")); strings.Contains(raw, "hunter2") {
+		t.Error("persisted datacenter contains sensitive variable")
+	}
+	gotDC, err := m.GetDatacenter(ctx, "aws-us-east")
+	if err != nil {
+		t.Fatalf("GetDatacenter failed: %v", err)
+	}
+	if gotDC.Variables["db_password"] != "hunter2" {
+		t.Errorf("db_password: got %q", gotDC.Variables["db_password"])
+	}
+}
+
+func TestSensitiveState_Inputs(t *testing.T) {
+	m, b := createEncryptedTestManager(t)
+	ctx := context.Background()
+
+	res := &types.ResourceState{
+		Name: "api",
+		Type: "deployment",
+		Inputs: map[string]interface{}{
+			"image":       "api:latest",
+			"command":     "serve --key key-123",
+			"environment": map[string]string{"DATABASE_URL": "postgres://app:hunter2@db", "LOG_LEVEL": "info"},
+		},
+		SensitiveInputs: []string{"command", "environment.DATABASE_URL"},
+	}
+	if err := m.SaveResource(ctx, "aws-us-east", "production", "api", res); err != nil {
+		t.Fatalf("SaveResource failed: %v", err)
+	}
+	raw := readRaw(t, b, resourcePath("aws-us-east", "production", "api", "deployment.api"))
+	for _, secret := range []string{"hunter2", "key-123"} {
+		if strings.Contains(raw, secret) {
+			t.Errorf("persisted resource contains %q", secret)
+		}
+	}
+	if !strings.Contains(raw, "api:latest") || !strings.Contains(raw, "LOG_LEVEL") {
+		t.Error("non-sensitive inputs should be stored in plain text")
+	}
+	if res.Inputs["environment"].(map[string]string)["DATABASE_URL"] != "postgres://app:hunter2@db" {
+		t.Error("SaveResource modified the in-memory state")
+	}
+
+	got, err := m.GetResource(ctx, "aws-us-east", "production", "api", "deployment.api")
+	if err != nil {
+		t.Fatalf("GetResource failed: %v", err)
+	}
+	if got.Inputs["command"] != "serve --key key-123" {
+		t.Errorf("command: got %v", got.Inputs["command"])
+	}
+	env, _ := got.Inputs["environment"].(map[string]interface{})
+	if env["DATABASE_URL"] != "postgres://app:hunter2@db" || env["LOG_LEVEL"] != "info" {
+		t.Errorf("environment: got %v", got.Inputs["environment"])
+	}
+
+	// Module inputs are sealed the same way
+	dc := &types.DatacenterState{
+		Name: "aws-us-east",
+		Modules: map[string]*types.ModuleState{
+			"vpc": {
+				Name:            "vpc",
+				Inputs:          map[string]interface{}{"region": "us-east-1", "token": "hunter2"},
+				SensitiveInputs: []string{"token"},
+			},
+		},
+	}
+	if err := m.SaveDatacenter(ctx, dc); err != nil {
+		t.Fatalf("SaveDatacenter failed: %v", err)
+	}
+	if raw := readRaw(t, b, datacenterPath("aws-us-east")); strings.Contains(raw, "hunter2") {
+		t.Error("persisted datacenter contains sensitive module input")
+	}
+	gotDC, err := m.GetDatacenter(ctx, "aws-us-east")
+	if err != nil {
+		t.Fatalf("GetDatacenter failed: %v", err)
+	}
+	if gotDC.Modules["vpc"].Inputs["token"] != "hunter2" {
+		t.Errorf("token: got %v", gotDC.Modules["vpc"].Inputs["token"])
+	}
+}
+
+func TestSensitiveState_WithoutEncrypter(t *testing.T) {
+	m, b := createEncryptedTestManager(t)
+	ctx := context.Background()
+
+	if err := m.SaveEnvironment(ctx, "aws-us-east", sensitiveTestEnvironment()); err != nil {
+		t.Fatalf("SaveEnvironment failed: %v", err)
+	}
+
+	// Reading encrypted state without a key must fail rather than return ciphertext
+	_, err := NewManager(b).GetEnvironment(ctx, "aws-us-east", "production")
+	if !errors.Is(err, encryption.ErrNoEncrypter) {
+		t.Errorf("expected ErrNoEncrypter, got %v", err)
+	}
+}
+
+func TestSensitiveState_WithoutKey(t *testing.T) {
+	b, _ := local.NewBackend(map[string]string{"path": t.TempDir()})
+	m := NewManager(b)
+	ctx := context.Background()
+
+	// Sensitive state isn't written in plain text without a key
+	err := m.SaveEnvironment(ctx, "aws-us-east", sensitiveTestEnvironment())
+	if !errors.Is(err, encryption.ErrKeyRequired) {
+		t.Fatalf("expected ErrKeyRequired, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "CLDCTL_STATE_ENCRYPTION_KEY") {
+		t.Errorf("expected the error to say how to configure a key, got %v", err)
+	}
+	paths, _ := b.List(ctx, "")
+	for _, p := range paths {
+		if raw := readRaw(t, b, p); strings.Contains(raw, "hunter2") || strings.Contains(raw, "key-123") {
+			t.Errorf("%s contains sensitive state:\n%s", p, raw)
+		}
+	}
+
+	dc := &types.DatacenterState{
+		Name:               "aws-us-east",
+		Variables:          map[string]string{"region": "us-east-1", "db_password": "hunter2"},
+		SensitiveVariables: []string{"db_password"},
+	}
+	if err := m.SaveDatacenter(ctx, dc); !errors.Is(err, encryption.ErrKeyRequired) {
+		t.Errorf("expected ErrKeyRequired, got %v", err)
+	}
+
+	// State without sensitive values doesn't need a key
+	dc.SensitiveVariables = nil
+	if err := m.SaveDatacenter(ctx, dc); err != nil {
+		t.Errorf("SaveDatacenter failed: %v", err)
+	}
+}
+
+func TestSensitiveState_PlaintextWithoutKey(t *testing.T) {
+	b, _ := local.NewBackend(map[string]string{"path": t.TempDir()})
+	m := NewManager(b, WithPlaintextState())
+	ctx := context.Background()
+
+	if err := m.SaveEnvironment(ctx, "aws-us-east", sensitiveTestEnvironment()); err != nil {
+		t.Fatalf("SaveEnvironment failed: %v", err)
+	}
+	if raw := readRaw(t, b, resourcePath("aws-us-east", "production", "api", "database.main")); !strings.Contains(raw, "hunter2") {
+		t.Error("expected the resource to be stored in plain text")
+	}
+}
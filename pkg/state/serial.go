@@ -0,0 +1,106 @@
+package state
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/davidthor/arcctl/pkg/state/backend"
+	"github.com/google/uuid"
+)
+
+// ErrStateConflict is returned when saving state that another process has
+// saved since it was read.
+var ErrStateConflict = errors.New("state was modified by another process")
+
+// stateVersion is the serial and lineage recorded in a state document.
+type stateVersion struct {
+	FormatVersion int    `json:"format_version"`
+	Serial        int64  `json:"serial"`
+	Lineage       string `json:"lineage"`
+}
+
+// writeVersionedJSON writes a state document that carries a serial and
+// lineage, refusing to overwrite changes saved since the caller read it or a
+// document written by a newer version of cldctl.
+//
+// serial and lineage point into doc and hold the values the caller read. The
+// write succeeds only if the stored document still has them, and is made
+// conditional on the stored revision so that a concurrent writer can't slip in
+// between the check and the write. On success they are updated to the values
+// that were stored: the serial is incremented and a new lineage is assigned
+// to documents that don't have one yet.
+func writeVersionedJSON(ctx context.Context, b backend.Backend, p, desc string, doc interface{}, serial *int64, lineage *string) error {
+	stored, version, err := readStateVersion(ctx, b, p)
+	if err != nil {
+		return err
+	}
+	if version == "" && *serial > 0 {
+		return fmt.Errorf("%w: %s was deleted after serial %d was read", ErrStateConflict, desc, *serial)
+	}
+
+	if err := checkFormat(stored.FormatVersion, p); err != nil {
+		return err
+	}
+	if stored.Serial != *serial {
+		return fmt.Errorf("%w: %s is at serial %d but serial %d was read; re-run the command to pick up the latest state",
+			ErrStateConflict, desc, stored.Serial, *serial)
+	}
+	if stored.Lineage != "" && *lineage != "" && stored.Lineage != *lineage {
+		return fmt.Errorf("%w: %s has lineage %s but lineage %s was read; it was replaced by different state",
+			ErrStateConflict, desc, stored.Lineage, *lineage)
+	}
+
+	*serial = stored.Serial + 1
+	switch {
+	case stored.Lineage != "":
+		*lineage = stored.Lineage
+	case *lineage == "":
+		*lineage = uuid.New().String()
+	}
+
+	_, err = writeIfVersion(ctx, b, p, desc, doc, version)
+	return err
+}
+
+// readStateVersion reads the format version, serial and lineage recorded in
+// the document at p, along with the backend's version token for it. The token
+// is empty if the document doesn't exist.
+func readStateVersion(ctx context.Context, b backend.Backend, p string) (stateVersion, string, error) {
+	var stored stateVersion
+
+	reader, version, err := b.ReadVersion(ctx, p)
+	if errors.Is(err, backend.ErrNotFound) {
+		return stored, "", nil
+	}
+	if err != nil {
+		return stored, "", err
+	}
+	defer reader.Close()
+
+	if err := json.NewDecoder(reader).Decode(&stored); err != nil {
+		return stored, "", fmt.Errorf("failed to decode JSON: %w", err)
+	}
+	return stored, version, nil
+}
+
+// writeIfVersion writes a state document to p in the current format, provided
+// the stored revision is still version as returned by readStateVersion. It
+// returns the version token of the revision written.
+func writeIfVersion(ctx context.Context, b backend.Backend, p, desc string, doc interface{}, version string) (string, error) {
+	content, err := encodeDocument(doc)
+	if err != nil {
+		return "", err
+	}
+
+	written, err := b.WriteIfVersion(ctx, p, bytes.NewReader(content), version)
+	if err != nil {
+		if errors.Is(err, backend.ErrConflict) {
+			return "", fmt.Errorf("%w: %s was saved concurrently; re-run the command to pick up the latest state", ErrStateConflict, desc)
+		}
+		return "", err
+	}
+	return written, nil
+}
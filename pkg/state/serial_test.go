@@ -0,0 +1,193 @@
+package state
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/davidthor/arcctl/pkg/state/backend/local"
+	"github.com/davidthor/arcctl/pkg/state/types"
+)
+
+func TestSaveEnvironment_Serial(t *testing.T) {
+	b, _ := local.NewBackend(map[string]string{"path": t.TempDir()})
+	m := NewManager(b)
+	ctx := context.Background()
+
+	env := &types.EnvironmentState{Name: "production", Datacenter: "dc"}
+	if err := m.SaveEnvironment(ctx, "dc", env); err != nil {
+		t.Fatalf("SaveEnvironment failed: %v", err)
+	}
+	if env.Serial != 1 || env.Lineage == "" {
+		t.Fatalf("expected serial 1 and a lineage, got %d %q", env.Serial, env.Lineage)
+	}
+	lineage := env.Lineage
+
+	// Saving the same in-memory state again continues the sequence
+	if err := m.SaveEnvironment(ctx, "dc", env); err != nil {
+		t.Fatalf("SaveEnvironment failed: %v", err)
+	}
+	if env.Serial != 2 || env.Lineage != lineage {
+		t.Errorf("expected serial 2 with same lineage, got %d %q", env.Serial, env.Lineage)
+	}
+
+	stored, err := m.GetEnvironment(ctx, "dc", "production")
+	if err != nil {
+		t.Fatalf("GetEnvironment failed: %v", err)
+	}
+	if stored.Serial != 2 || stored.Lineage != lineage {
+		t.Errorf("expected stored serial 2 with same lineage, got %d %q", stored.Serial, stored.Lineage)
+	}
+}
+
+func TestSaveEnvironment_StaleSerial(t *testing.T) {
+	b, _ := local.NewBackend(map[string]string{"path": t.TempDir()})
+	m := NewManager(b)
+	ctx := context.Background()
+
+	_ = m.SaveEnvironment(ctx, "dc", &types.EnvironmentState{Name: "production"})
+
+	// Two processes read the same state
+	first, _ := m.GetEnvironment(ctx, "dc", "production")
+	second, _ := m.GetEnvironment(ctx, "dc", "production")
+
+	first.Components = map[string]*types.ComponentState{"api": {Name: "api"}}
+	if err := m.SaveEnvironment(ctx, "dc", first); err != nil {
+		t.Fatalf("SaveEnvironment failed: %v", err)
+	}
+
+	// The second must not silently drop the first's component
+	second.Components = map[string]*types.ComponentState{"web": {Name: "web"}}
+	err := m.SaveEnvironment(ctx, "dc", second)
+	if !errors.Is(err, ErrStateConflict) {
+		t.Fatalf("expected ErrStateConflict, got %v", err)
+	}
+	if second.Serial != 1 {
+		t.Errorf("expected failed save to leave serial at 1, got %d", second.Serial)
+	}
+
+	stored, _ := m.GetEnvironment(ctx, "dc", "production")
+	if _, ok := stored.Components["api"]; !ok {
+		t.Error("expected first save to be preserved")
+	}
+
+	// A fresh state can't replace existing state either
+	err = m.SaveEnvironment(ctx, "dc", &types.EnvironmentState{Name: "production"})
+	if !errors.Is(err, ErrStateConflict) {
+		t.Errorf("expected ErrStateConflict for new state, got %v", err)
+	}
+}
+
+func TestSaveEnvironment_LineageMismatch(t *testing.T) {
+	b, _ := local.NewBackend(map[string]string{"path": t.TempDir()})
+	m := NewManager(b)
+	ctx := context.Background()
+
+	_ = m.SaveEnvironment(ctx, "dc", &types.EnvironmentState{Name: "production"})
+	env, _ := m.GetEnvironment(ctx, "dc", "production")
+	env.Lineage = "some-other-lineage"
+
+	err := m.SaveEnvironment(ctx, "dc", env)
+	if !errors.Is(err, ErrStateConflict) {
+		t.Fatalf("expected ErrStateConflict, got %v", err)
+	}
+}
+
+func TestSaveEnvironment_Deleted(t *testing.T) {
+	b, _ := local.NewBackend(map[string]string{"path": t.TempDir()})
+	m := NewManager(b)
+	ctx := context.Background()
+
+	env := &types.EnvironmentState{Name: "production"}
+	_ = m.SaveEnvironment(ctx, "dc", env)
+	_ = m.DeleteEnvironment(ctx, "dc", "production")
+
+	err := m.SaveEnvironment(ctx, "dc", env)
+	if !errors.Is(err, ErrStateConflict) {
+		t.Fatalf("expected ErrStateConflict, got %v", err)
+	}
+}
+
+func TestSaveEnvironment_LegacyState(t *testing.T) {
+	b, _ := local.NewBackend(map[string]string{"path": t.TempDir()})
+	m := NewManager(b)
+	ctx := context.Background()
+
+	// State written before serials existed has neither field
+	legacy := []byte(`{"name": "production", "datacenter": "dc"}`)
+	_ = b.Write(ctx, environmentPath("dc", "production"), bytes.NewReader(legacy))
+
+	env, err := m.GetEnvironment(ctx, "dc", "production")
+	if err != nil {
+		t.Fatalf("GetEnvironment failed: %v", err)
+	}
+	if env.Serial != 0 {
+		t.Errorf("expected serial 0 for legacy state, got %d", env.Serial)
+	}
+	if err := m.SaveEnvironment(ctx, "dc", env); err != nil {
+		t.Fatalf("SaveEnvironment failed: %v", err)
+	}
+	if env.Serial != 1 || env.Lineage == "" {
+		t.Errorf("expected serial 1 and a lineage, got %d %q", env.Serial, env.Lineage)
+	}
+}
+
+func TestSaveEnvironment_Concurrent(t *testing.T) {
+	b, _ := local.NewBackend(map[string]string{"path": t.TempDir()})
+	m := NewManager(b)
+	ctx := context.Background()
+
+	_ = m.SaveEnvironment(ctx, "dc", &types.EnvironmentState{Name: "production"})
+
+	// Processes that read the same serial race to save; only one may win
+	const writers = 8
+	var wg sync.WaitGroup
+	errs := make(chan error, writers)
+	for i := 0; i < writers; i++ {
+		env, _ := m.GetEnvironment(ctx, "dc", "production")
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- m.SaveEnvironment(ctx, "dc", env)
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	succeeded := 0
+	for err := range errs {
+		switch {
+		case err == nil:
+			succeeded++
+		case !errors.Is(err, ErrStateConflict):
+			t.Errorf("unexpected error: %v", err)
+		}
+	}
+	if succeeded != 1 {
+		t.Errorf("expected exactly one successful save, got %d", succeeded)
+	}
+}
+
+func TestSaveDatacenter_StaleSerial(t *testing.T) {
+	b, _ := local.NewBackend(map[string]string{"path": t.TempDir()})
+	m := NewManager(b)
+	ctx := context.Background()
+
+	dc := &types.DatacenterState{Name: "aws-us-east"}
+	if err := m.SaveDatacenter(ctx, dc); err != nil {
+		t.Fatalf("SaveDatacenter failed: %v", err)
+	}
+	if dc.Serial != 1 {
+		t.Errorf("expected serial 1, got %d", dc.Serial)
+	}
+
+	stale, _ := m.GetDatacenter(ctx, "aws-us-east")
+	_ = m.SaveDatacenter(ctx, dc)
+
+	err := m.SaveDatacenter(ctx, stale)
+	if !errors.Is(err, ErrStateConflict) {
+		t.Fatalf("expected ErrStateConflict, got %v", err)
+	}
+}
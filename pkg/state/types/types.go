@@ -14,9 +14,17 @@ type DatacenterState struct {
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
 
+	// Serial increases by one with every save and Lineage identifies the state
+	// across saves; together they detect concurrent modification
+	Serial  int64  `json:"serial"`
+	Lineage string `json:"lineage,omitempty"`
+
 	// Configuration
 	Variables map[string]string `json:"variables,omitempty"`
 
+	// SensitiveVariables lists the variables declared sensitive by the datacenter
+	SensitiveVariables []string `json:"sensitive_variables,omitempty"`
+
 	// Module states (datacenter-level modules)
 	Modules map[string]*ModuleState `json:"modules,omitempty"`
 }
@@ -39,6 +47,11 @@ type EnvironmentState struct {
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
 
+	// Serial increases by one with every save and Lineage identifies the state
+	// across saves; together they detect concurrent modification
+	Serial  int64  `json:"serial"`
+	Lineage string `json:"lineage,omitempty"`
+
 	// Status
 	Status       EnvironmentStatus `json:"status"`
 	StatusReason string            `json:"status_reason,omitempty"`
@@ -53,6 +66,33 @@ type EnvironmentState struct {
 	Modules map[string]*ModuleState `json:"modules,omitempty"`
 }
 
+// EnvironmentSnapshotInfo describes a numbered snapshot in an environment's
+// state history.
+type EnvironmentSnapshotInfo struct {
+	// Version is the snapshot number, starting at 1 and increasing by one
+	// with each snapshot of the environment. It is unrelated to the serial of
+	// the environment state the snapshot holds.
+	Version   int       `json:"version"`
+	CreatedAt time.Time `json:"created_at"`
+
+	// Operation that produced the snapshot (e.g., "deploy", "destroy", "rollback")
+	Operation string `json:"operation"`
+
+	// Who ran the operation
+	Who string `json:"who,omitempty"`
+
+	// Component the operation targeted, if any
+	Component string `json:"component,omitempty"`
+}
+
+// EnvironmentSnapshot is a copy of an environment's state recorded after an
+// operation changed it.
+type EnvironmentSnapshot struct {
+	EnvironmentSnapshotInfo
+
+	State *EnvironmentState `json:"state"`
+}
+
 // EnvironmentStatus represents the status of an environment.
 type EnvironmentStatus string
 
@@ -80,6 +120,9 @@ type ComponentState struct {
 	// Variables used for this deployment
 	Variables map[string]string `json:"variables,omitempty"`
 
+	// SensitiveVariables lists the variables declared sensitive by the component
+	SensitiveVariables []string `json:"sensitive_variables,omitempty"`
+
 	// Dependencies lists the names of other components this component depends on.
 	// Populated at deploy time from the component schema's dependency declarations.
 	Dependencies []string `json:"dependencies,omitempty"`
@@ -104,15 +147,32 @@ type ResourceState struct {
 	// Resource inputs (normalized from component)
 	Inputs map[string]interface{} `json:"inputs,omitempty"`
 
+	// SensitiveInputs lists the inputs that hold sensitive values, such as a
+	// sensitive variable or a dependency's sensitive output. An entry names a
+	// top-level input, or "<input>.<key>" for a key of an input that is a map.
+	SensitiveInputs []string `json:"sensitive_inputs,omitempty"`
+
 	// Resource outputs (from hook execution)
 	Outputs map[string]interface{} `json:"outputs,omitempty"`
 
-	// IaC state (serialized state from the plugin)
+	// SensitiveOutputs lists the outputs the IaC plugin marked as sensitive
+	SensitiveOutputs []string `json:"sensitive_outputs,omitempty"`
+
+	// IaC state (serialized state from the plugin). Resources read as part of
+	// an environment are read without it; use LoadIaCState to read it.
 	IaCState []byte `json:"iac_state,omitempty"`
 
+	// loadIaCState reads IaCState for a resource read without it
+	loadIaCState func() ([]byte, error)
+
 	// Status
 	Status       ResourceStatus `json:"status"`
 	StatusReason string         `json:"status_reason,omitempty"`
+
+	// Attempt counts the tries at applying the resource's latest change. It
+	// is 1 for a change that was applied first time and increases each time a
+	// failed or interrupted apply is retried.
+	Attempt int `json:"attempt,omitempty"`
 }
 
 // ResourceStatus represents the status of a resource.
@@ -139,9 +199,16 @@ type ModuleState struct {
 	// Inputs used for this execution
 	Inputs map[string]interface{} `json:"inputs,omitempty"`
 
+	// SensitiveInputs lists the inputs that reference a sensitive variable or
+	// a sensitive output of another module
+	SensitiveInputs []string `json:"sensitive_inputs,omitempty"`
+
 	// Outputs from the module
 	Outputs map[string]interface{} `json:"outputs,omitempty"`
 
+	// SensitiveOutputs lists the outputs the IaC plugin marked as sensitive
+	SensitiveOutputs []string `json:"sensitive_outputs,omitempty"`
+
 	// IaC state (serialized state from the plugin)
 	IaCState []byte `json:"iac_state,omitempty"`
 
@@ -160,6 +227,62 @@ const (
 	ModuleStatusFailed   ModuleStatus = "failed"
 )
 
+// IsSensitiveVariable returns true if the named datacenter variable is sensitive.
+func (s *DatacenterState) IsSensitiveVariable(name string) bool {
+	return contains(s.SensitiveVariables, name)
+}
+
+// IsSensitiveVariable returns true if the named component variable is sensitive.
+func (s *ComponentState) IsSensitiveVariable(name string) bool {
+	return contains(s.SensitiveVariables, name)
+}
+
+// IsSensitiveOutput returns true if the named resource output is sensitive.
+func (s *ResourceState) IsSensitiveOutput(name string) bool {
+	return contains(s.SensitiveOutputs, name)
+}
+
+// DeferIaCState records that the resource was read without its IaC state,
+// and that load reads it. It is called by the state manager.
+func (s *ResourceState) DeferIaCState(load func() ([]byte, error)) {
+	s.loadIaCState = load
+}
+
+// IaCStateDeferred returns true if the resource has IaC state that hasn't
+// been read.
+func (s *ResourceState) IaCStateDeferred() bool {
+	return s.loadIaCState != nil
+}
+
+// LoadIaCState returns the resource's IaC state, reading it first if the
+// resource was read without it. IaC state set on the resource in the meantime
+// takes precedence over the stored state.
+func (s *ResourceState) LoadIaCState() ([]byte, error) {
+	if s.loadIaCState != nil && s.IaCState == nil {
+		state, err := s.loadIaCState()
+		if err != nil {
+			return nil, err
+		}
+		s.IaCState = state
+	}
+	s.loadIaCState = nil
+	return s.IaCState, nil
+}
+
+// IsSensitiveOutput returns true if the named module output is sensitive.
+func (s *ModuleState) IsSensitiveOutput(name string) bool {
+	return contains(s.SensitiveOutputs, name)
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
 // EnvironmentRef is a lightweight reference to an environment.
 type EnvironmentRef struct {
 	Name       string    `json:"name"`